@@ -8,17 +8,34 @@ import (
 	"log"
 	"os"
 
+	"github.com/oliverandrich/go-webapp-template/internal/commands"
 	"github.com/oliverandrich/go-webapp-template/internal/config"
+	"github.com/oliverandrich/go-webapp-template/internal/handlers"
 	"github.com/oliverandrich/go-webapp-template/internal/server"
 	"github.com/urfave/cli/v3"
 )
 
+// Build information, set via -ldflags "-X main.version=..." (see justfile).
+var (
+	version   = "dev"
+	commit    = "unknown"
+	buildTime = "unknown"
+)
+
 func main() {
+	server.SetBuildInfo(handlers.BuildInfo{Version: version, Commit: commit, BuildTime: buildTime})
+
 	cmd := &cli.Command{
-		Name:   "app",
-		Usage:  "Start the web application",
-		Flags:  config.Flags(),
-		Action: server.Run,
+		Name:    "app",
+		Usage:   "Start the web application",
+		Version: version,
+		Flags:   config.Flags(),
+		Action:  server.Run,
+		Commands: []*cli.Command{
+			commands.CreateAdmin(),
+			commands.Config(),
+			commands.Backup(),
+		},
 	}
 
 	if err := cmd.Run(context.Background(), os.Args); err != nil {
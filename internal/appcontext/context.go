@@ -1,7 +1,8 @@
 // Copyright 2025 Oliver Andrich
 // Licensed under the EUPL-1.2
 
-// Package appcontext provides the custom Echo context and context keys.
+// Package appcontext provides the custom Echo context, the context keys and
+// typed accessors for the values stored under them.
 package appcontext
 
 import (
@@ -14,12 +15,20 @@ import (
 type (
 	// CSRFToken is the context key for the CSRF token.
 	CSRFToken struct{}
+	// CSPNonce is the context key for the per-request Content-Security-Policy nonce.
+	CSPNonce struct{}
 	// CSSPath is the context key for the CSS path.
 	CSSPath struct{}
 	// JSPath is the context key for the JS (htmx) path.
 	JSPath struct{}
+	// RequestID is the context key for the X-Request-ID of the current request.
+	RequestID struct{}
 	// User is the context key for the authenticated user.
 	User struct{}
+	// Impersonator is the context key for the administrator acting as User.
+	Impersonator struct{}
+	// PathPrefix is the context key for the path the app is mounted below.
+	PathPrefix struct{}
 )
 
 // Assets holds paths to static assets.
@@ -34,6 +43,8 @@ type Context struct {
 	Htmx   *htmx.Request
 	Assets *Assets
 	User   *models.User // nil if not authenticated
+
+	Impersonator *models.User // administrator acting as User, nil otherwise
 }
 
 // GetUser returns the authenticated user, or nil if not authenticated.
@@ -45,3 +56,8 @@ func (c *Context) GetUser() *models.User {
 func (c *Context) IsAuthenticated() bool {
 	return c.User != nil
 }
+
+// IsImpersonating returns true if an administrator is acting as the user.
+func (c *Context) IsImpersonating() bool {
+	return c.Impersonator != nil
+}
@@ -0,0 +1,102 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package appcontext
+
+import (
+	"context"
+
+	"github.com/oliverandrich/go-webapp-template/internal/models"
+)
+
+// Typed accessors for the values the middleware stores in context.Context.
+// They use the exported key types above, so code reading the keys directly
+// keeps working.
+
+// WithCSRFToken returns a copy of ctx carrying the CSRF token.
+func WithCSRFToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, CSRFToken{}, token)
+}
+
+// CSRFTokenFrom returns the CSRF token stored in ctx.
+func CSRFTokenFrom(ctx context.Context) (string, bool) {
+	token, ok := ctx.Value(CSRFToken{}).(string)
+	return token, ok
+}
+
+// WithCSPNonce returns a copy of ctx carrying the Content-Security-Policy nonce.
+func WithCSPNonce(ctx context.Context, nonce string) context.Context {
+	return context.WithValue(ctx, CSPNonce{}, nonce)
+}
+
+// CSPNonceFrom returns the Content-Security-Policy nonce stored in ctx.
+func CSPNonceFrom(ctx context.Context) (string, bool) {
+	nonce, ok := ctx.Value(CSPNonce{}).(string)
+	return nonce, ok
+}
+
+// WithAssets returns a copy of ctx carrying the CSS and JS asset paths.
+func WithAssets(ctx context.Context, assets *Assets) context.Context {
+	ctx = context.WithValue(ctx, CSSPath{}, assets.CSSPath)
+	return context.WithValue(ctx, JSPath{}, assets.JSPath)
+}
+
+// CSSPathFrom returns the CSS asset path stored in ctx.
+func CSSPathFrom(ctx context.Context) (string, bool) {
+	path, ok := ctx.Value(CSSPath{}).(string)
+	return path, ok
+}
+
+// JSPathFrom returns the JS asset path stored in ctx.
+func JSPathFrom(ctx context.Context) (string, bool) {
+	path, ok := ctx.Value(JSPath{}).(string)
+	return path, ok
+}
+
+// WithRequestID returns a copy of ctx carrying the request ID.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, RequestID{}, id)
+}
+
+// RequestIDFrom returns the request ID stored in ctx.
+func RequestIDFrom(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(RequestID{}).(string)
+	return id, ok
+}
+
+// WithUser returns a copy of ctx carrying the authenticated user.
+func WithUser(ctx context.Context, user *models.User) context.Context {
+	return context.WithValue(ctx, User{}, user)
+}
+
+// UserFrom returns the authenticated user stored in ctx. It reports false
+// when no user is stored or the stored user is nil.
+func UserFrom(ctx context.Context) (*models.User, bool) {
+	user, ok := ctx.Value(User{}).(*models.User)
+	return user, ok && user != nil
+}
+
+// WithImpersonator returns a copy of ctx carrying the administrator acting
+// as the user.
+func WithImpersonator(ctx context.Context, admin *models.User) context.Context {
+	return context.WithValue(ctx, Impersonator{}, admin)
+}
+
+// ImpersonatorFrom returns the impersonating administrator stored in ctx.
+func ImpersonatorFrom(ctx context.Context) (*models.User, bool) {
+	admin, ok := ctx.Value(Impersonator{}).(*models.User)
+	return admin, ok && admin != nil
+}
+
+// WithPathPrefix returns a copy of ctx carrying the path the app is mounted
+// below.
+func WithPathPrefix(ctx context.Context, prefix string) context.Context {
+	return context.WithValue(ctx, PathPrefix{}, prefix)
+}
+
+// PathPrefixFrom returns the path prefix stored in ctx; empty when the app
+// is mounted at the root.
+func PathPrefixFrom(ctx context.Context) string {
+	prefix, _ := ctx.Value(PathPrefix{}).(string)
+	return prefix
+}
@@ -0,0 +1,99 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package appcontext_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/oliverandrich/go-webapp-template/internal/appcontext"
+	"github.com/oliverandrich/go-webapp-template/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStringAccessors(t *testing.T) {
+	tests := []struct {
+		name string
+		with func(context.Context, string) context.Context
+		from func(context.Context) (string, bool)
+	}{
+		{"csrf token", appcontext.WithCSRFToken, appcontext.CSRFTokenFrom},
+		{"csp nonce", appcontext.WithCSPNonce, appcontext.CSPNonceFrom},
+		{"request id", appcontext.WithRequestID, appcontext.RequestIDFrom},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, ok := tt.from(tt.with(context.Background(), "value"))
+			assert.True(t, ok)
+			assert.Equal(t, "value", value)
+
+			value, ok = tt.from(context.Background())
+			assert.False(t, ok)
+			assert.Empty(t, value)
+		})
+	}
+}
+
+func TestPathPrefixAccessors(t *testing.T) {
+	assert.Equal(t, "/app", appcontext.PathPrefixFrom(appcontext.WithPathPrefix(context.Background(), "/app")))
+	assert.Empty(t, appcontext.PathPrefixFrom(context.Background()))
+}
+
+func TestAssetAccessors(t *testing.T) {
+	ctx := appcontext.WithAssets(context.Background(), &appcontext.Assets{
+		CSSPath: "/static/css/styles.abc.css",
+		JSPath:  "/static/js/htmx.abc.js",
+	})
+
+	css, ok := appcontext.CSSPathFrom(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, "/static/css/styles.abc.css", css)
+	js, ok := appcontext.JSPathFrom(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, "/static/js/htmx.abc.js", js)
+
+	_, ok = appcontext.CSSPathFrom(context.Background())
+	assert.False(t, ok)
+	_, ok = appcontext.JSPathFrom(context.Background())
+	assert.False(t, ok)
+}
+
+func TestUserAccessors(t *testing.T) {
+	user := &models.User{ID: 1, Username: "alice"}
+	admin := &models.User{ID: 2, Username: "admin", IsAdmin: true}
+	ctx := appcontext.WithImpersonator(appcontext.WithUser(context.Background(), user), admin)
+
+	got, ok := appcontext.UserFrom(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, user, got)
+	got, ok = appcontext.ImpersonatorFrom(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, admin, got)
+
+	_, ok = appcontext.UserFrom(context.Background())
+	assert.False(t, ok)
+	_, ok = appcontext.ImpersonatorFrom(context.Background())
+	assert.False(t, ok)
+}
+
+func TestUserFrom_NilUser(t *testing.T) {
+	ctx := appcontext.WithUser(context.Background(), nil)
+
+	user, ok := appcontext.UserFrom(ctx)
+
+	assert.False(t, ok)
+	assert.Nil(t, user)
+}
+
+func TestAccessors_ShareExportedKeys(t *testing.T) {
+	user := &models.User{ID: 1}
+	ctx := context.WithValue(context.Background(), appcontext.User{}, user)
+
+	got, ok := appcontext.UserFrom(ctx)
+
+	assert.True(t, ok)
+	assert.Same(t, user, got)
+	assert.Equal(t, "token", appcontext.WithCSRFToken(ctx, "token").Value(appcontext.CSRFToken{}))
+}
@@ -75,11 +75,18 @@ func JSPath() string {
 	return jsPath
 }
 
-// FileServer returns an http.Handler that serves embedded static files.
-func FileServer() http.Handler {
+// FS returns the embedded static files, rooted at the static directory.
+func FS() fs.FS {
 	sub, err := fs.Sub(staticFS, "static")
 	if err != nil {
 		panic("failed to create sub filesystem: " + err.Error())
 	}
-	return http.FileServer(http.FS(sub))
+	return sub
+}
+
+// FileServer returns an http.Handler that serves embedded static files
+// with ETags for conditional requests.
+func FileServer() http.Handler {
+	sub := FS()
+	return withETags(sub, http.FileServer(http.FS(sub)))
 }
@@ -8,7 +8,9 @@
 package assets
 
 import (
+	"io/fs"
 	"net/http"
+	"os"
 )
 
 // CSSPath returns the path to the main CSS file (unhashed in dev mode).
@@ -21,7 +23,16 @@ func JSPath() string {
 	return "/static/dist/app.js"
 }
 
-// FileServer returns an http.Handler that serves static files from the filesystem.
+// staticDir holds the static files, relative to the repository root.
+const staticDir = "internal/assets/static"
+
+// FS returns the static files on disk.
+func FS() fs.FS {
+	return os.DirFS(staticDir)
+}
+
+// FileServer returns an http.Handler that serves static files from the filesystem
+// with ETags for conditional requests.
 func FileServer() http.Handler {
-	return http.FileServer(http.Dir("internal/assets/static"))
+	return withETags(FS(), http.FileServer(http.Dir(staticDir)))
 }
@@ -0,0 +1,56 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package assets
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+)
+
+// withETags sets a strong ETag derived from the file content before handing
+// the request to next. http.FileServer then answers If-None-Match (and
+// If-Modified-Since where the filesystem has modification times) with 304.
+func withETags(fsys fs.FS, next http.Handler) http.Handler {
+	var cache sync.Map // name|size|mtime → ETag
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+		if tag, ok := fileETag(fsys, name, &cache); ok {
+			w.Header().Set("ETag", tag)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// fileETag returns the ETag for a regular file, hashing its content once per
+// size and modification time.
+func fileETag(fsys fs.FS, name string, cache *sync.Map) (string, bool) {
+	if name == "" {
+		return "", false
+	}
+	info, err := fs.Stat(fsys, name)
+	if err != nil || info.IsDir() {
+		return "", false
+	}
+
+	key := fmt.Sprintf("%s|%d|%d", name, info.Size(), info.ModTime().UnixNano())
+	if tag, ok := cache.Load(key); ok {
+		return tag.(string), true
+	}
+
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return "", false
+	}
+	sum := sha256.Sum256(data)
+	tag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	cache.Store(key, tag)
+	return tag, true
+}
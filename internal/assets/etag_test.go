@@ -0,0 +1,91 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package assets
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestFileServer() http.Handler {
+	fsys := fstest.MapFS{
+		"css/styles.css": {Data: []byte("body { color: red; }"), ModTime: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)},
+		"js/app.js":      {Data: []byte("console.log('hi');")},
+	}
+	return withETags(fsys, http.FileServer(http.FS(fsys)))
+}
+
+func TestWithETags_ConditionalGet(t *testing.T) {
+	h := newTestFileServer()
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/css/styles.css", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	etag := rec.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+	assert.Equal(t, "body { color: red; }", rec.Body.String())
+
+	req := httptest.NewRequest(http.MethodGet, "/css/styles.css", nil)
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+	assert.Empty(t, rec.Body.String())
+}
+
+func TestWithETags_StaleETag(t *testing.T) {
+	h := newTestFileServer()
+
+	req := httptest.NewRequest(http.MethodGet, "/js/app.js", nil)
+	req.Header.Set("If-None-Match", `"outdated"`)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotEqual(t, `"outdated"`, rec.Header().Get("ETag"))
+}
+
+func TestWithETags_IfModifiedSince(t *testing.T) {
+	h := newTestFileServer()
+
+	req := httptest.NewRequest(http.MethodGet, "/css/styles.css", nil)
+	req.Header.Set("If-Modified-Since", time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC).Format(http.TimeFormat))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+}
+
+func TestWithETags_Head(t *testing.T) {
+	h := newTestFileServer()
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodHead, "/js/app.js", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotEmpty(t, rec.Header().Get("ETag"))
+	assert.Empty(t, rec.Body.String())
+}
+
+func TestWithETags_StableAndContentBased(t *testing.T) {
+	h := newTestFileServer()
+
+	get := func(path string) string {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec.Header().Get("ETag")
+	}
+
+	assert.Equal(t, get("/css/styles.css"), get("/css/styles.css"))
+	assert.NotEqual(t, get("/css/styles.css"), get("/js/app.js"))
+	assert.Empty(t, get("/missing.css"))
+	assert.Empty(t, get("/css"))
+}
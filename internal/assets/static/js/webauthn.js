@@ -69,8 +69,10 @@ const WebAuthn = {
             body: body ? JSON.stringify(body) : undefined
         });
         if (!resp.ok) {
-            const err = await resp.json();
-            throw new Error(err.error || 'Request failed');
+            const body = await resp.json();
+            const err = new Error(body.error || 'Request failed');
+            err.body = body;
+            throw err;
         }
         return resp.json();
     }
@@ -0,0 +1,56 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+// Package clock provides an injectable time source so expiry logic can be
+// tested without sleeping.
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock returns the current time.
+type Clock interface {
+	Now() time.Time
+}
+
+// Real is a Clock backed by time.Now.
+type Real struct{}
+
+// Now returns the current wall-clock time.
+func (Real) Now() time.Time {
+	return time.Now()
+}
+
+// Fake is a Clock whose time only changes when told to. Safe for concurrent use.
+type Fake struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewFake creates a fake clock set to the given time.
+func NewFake(now time.Time) *Fake {
+	return &Fake{now: now}
+}
+
+// Now returns the fake clock's current time.
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// Advance moves the fake clock forward by d.
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+}
+
+// Set moves the fake clock to t.
+func (f *Fake) Set(t time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = t
+}
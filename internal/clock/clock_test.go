@@ -0,0 +1,33 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package clock_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/oliverandrich/go-webapp-template/internal/clock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReal_Now(t *testing.T) {
+	before := time.Now()
+	now := clock.Real{}.Now()
+
+	assert.False(t, now.Before(before))
+}
+
+func TestFake_AdvanceAndSet(t *testing.T) {
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	c := clock.NewFake(start)
+
+	assert.Equal(t, start, c.Now())
+
+	c.Advance(time.Hour)
+	assert.Equal(t, start.Add(time.Hour), c.Now())
+
+	later := start.Add(48 * time.Hour)
+	c.Set(later)
+	assert.Equal(t, later, c.Now())
+}
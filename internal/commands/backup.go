@@ -0,0 +1,96 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/oliverandrich/go-webapp-template/internal/config"
+	"github.com/oliverandrich/go-webapp-template/internal/database"
+	"github.com/urfave/cli/v3"
+	"github.com/vinovest/sqlx"
+)
+
+// Backup returns the backup subcommand.
+func Backup() *cli.Command {
+	return &cli.Command{
+		Name:      "backup",
+		Usage:     "Write a snapshot of the database to a file while the server keeps running",
+		ArgsUsage: "<path>",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "force",
+				Usage: "Replace an existing file at path",
+			},
+		},
+		Action: backup,
+	}
+}
+
+func backup(ctx context.Context, cmd *cli.Command) error {
+	if cmd.Args().Len() != 1 {
+		return errors.New("exactly one backup path is required")
+	}
+	target := cmd.Args().First()
+
+	cfg := config.NewFromCLI(cmd)
+	if database.IsInMemory(cfg.Database.DSN) {
+		return errors.New("in-memory databases cannot be backed up")
+	}
+
+	// Migrations run automatically in Open
+	db, err := database.Open(cfg.Database.DSN)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	if err = BackupDatabase(ctx, db, target, cmd.Bool("force")); err != nil {
+		return err
+	}
+
+	_, _ = fmt.Fprintf(cmd.Root().Writer, "Backup written to %s.\n", target)
+	return nil
+}
+
+// BackupDatabase snapshots db to target and verifies the copy before it
+// takes target's place, so a failed backup never leaves a broken file or
+// destroys the previous one. Missing parent directories are created; an
+// existing target is only replaced with force.
+func BackupDatabase(ctx context.Context, db *sqlx.DB, target string, force bool) error {
+	if _, err := os.Stat(target); err == nil && !force {
+		return fmt.Errorf("%s already exists, use --force to replace it", target)
+	} else if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	dir := filepath.Dir(target)
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	// VACUUM INTO accepts an existing file as long as it is empty
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(target)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to create backup file: %w", err)
+	}
+	_ = tmp.Close()
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if err = database.Backup(ctx, db, tmp.Name()); err != nil {
+		return fmt.Errorf("failed to back up database: %w", err)
+	}
+	if err = database.VerifyBackup(ctx, tmp.Name()); err != nil {
+		return fmt.Errorf("backup is not usable: %w", err)
+	}
+	if err = os.Rename(tmp.Name(), target); err != nil {
+		return fmt.Errorf("failed to move backup into place: %w", err)
+	}
+	return nil
+}
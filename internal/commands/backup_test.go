@@ -0,0 +1,89 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package commands_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/oliverandrich/go-webapp-template/internal/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vinovest/sqlx"
+)
+
+// newBackupSource creates a file database with one user and returns its DSN.
+func newBackupSource(t *testing.T) string {
+	t.Helper()
+	dsn := filepath.Join(t.TempDir(), "app.db")
+	db, err := database.Open(dsn)
+	require.NoError(t, err)
+	_, err = db.ExecContext(context.Background(), "INSERT INTO users (username) VALUES ('alice')")
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+	return dsn
+}
+
+func countUsers(t *testing.T, path string) int {
+	t.Helper()
+	db, err := sqlx.Open("sqlite", path)
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+	var n int
+	require.NoError(t, db.QueryRowContext(context.Background(), "SELECT count(*) FROM users").Scan(&n))
+	return n
+}
+
+func TestBackup_WritesQueryableCopy(t *testing.T) {
+	dsn := newBackupSource(t)
+	target := filepath.Join(t.TempDir(), "nested", "dir", "backup.db")
+
+	out, err := runApp(t, "--database-dsn", dsn, "backup", target)
+
+	require.NoError(t, err)
+	assert.Contains(t, out, "Backup written to "+target)
+	assert.Equal(t, 1, countUsers(t, target))
+
+	// No temporary files are left behind
+	entries, err := os.ReadDir(filepath.Dir(target))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestBackup_RefusesToOverwrite(t *testing.T) {
+	dsn := newBackupSource(t)
+	target := filepath.Join(t.TempDir(), "backup.db")
+	require.NoError(t, os.WriteFile(target, []byte("previous"), 0o600))
+
+	_, err := runApp(t, "--database-dsn", dsn, "backup", target)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "--force")
+	content, readErr := os.ReadFile(target)
+	require.NoError(t, readErr)
+	assert.Equal(t, "previous", string(content))
+}
+
+func TestBackup_ForceReplaces(t *testing.T) {
+	dsn := newBackupSource(t)
+	target := filepath.Join(t.TempDir(), "backup.db")
+	require.NoError(t, os.WriteFile(target, []byte("previous"), 0o600))
+
+	_, err := runApp(t, "--database-dsn", dsn, "backup", "--force", target)
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, countUsers(t, target))
+}
+
+func TestBackup_Errors(t *testing.T) {
+	_, err := runApp(t, "--database-dsn", newBackupSource(t), "backup")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "exactly one backup path")
+
+	_, err = runApp(t, "--database-dsn", ":memory:", "backup", filepath.Join(t.TempDir(), "backup.db"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "in-memory")
+}
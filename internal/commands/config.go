@@ -0,0 +1,54 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/BurntSushi/toml"
+	"github.com/oliverandrich/go-webapp-template/internal/config"
+	"github.com/urfave/cli/v3"
+)
+
+// Config returns the config subcommand, which prints the effective
+// configuration after merging flags, environment and config file.
+func Config() *cli.Command {
+	return &cli.Command{
+		Name:  "config",
+		Usage: "Print the effective configuration with secrets redacted and validate it",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "format",
+				Usage: "Output format: toml, json",
+				Value: "toml",
+			},
+		},
+		Action: dumpConfig,
+	}
+}
+
+func dumpConfig(_ context.Context, cmd *cli.Command) error {
+	cfg := config.NewFromCLI(cmd)
+
+	if err := writeConfig(cmd.Root().Writer, cfg.Redacted(), cmd.String("format")); err != nil {
+		return err
+	}
+	return cfg.Validate()
+}
+
+func writeConfig(w io.Writer, cfg *config.Config, format string) error {
+	switch format {
+	case "toml":
+		return toml.NewEncoder(w).Encode(cfg)
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(cfg)
+	default:
+		return fmt.Errorf("unknown format %q, expected toml or json", format)
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package commands_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testHashKey = "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+func TestConfig_TOMLRedactsSecrets(t *testing.T) {
+	out, err := runApp(t,
+		"--host", "example.com", "--port", "9090", "--tls-mode", "off",
+		"--session-hash-key", testHashKey, "--smtp-password", "hunter2",
+		"config")
+
+	require.NoError(t, err)
+	assert.Contains(t, out, `Host = "example.com"`)
+	assert.Contains(t, out, "Port = 9090")
+	assert.Contains(t, out, `HashKey = "[redacted]"`)
+	assert.Contains(t, out, `Password = "[redacted]"`)
+	assert.NotContains(t, out, testHashKey)
+	assert.NotContains(t, out, "hunter2")
+}
+
+func TestConfig_JSON(t *testing.T) {
+	out, err := runApp(t, "--port", "9090", "--webhook-secret", "s3cret", "config", "--format", "json")
+
+	require.NoError(t, err)
+	var cfg struct {
+		Server struct {
+			Host string
+			Port int
+		}
+		Webhook struct{ Secret string }
+	}
+	require.NoError(t, json.Unmarshal([]byte(out), &cfg))
+	assert.Equal(t, "localhost", cfg.Server.Host)
+	assert.Equal(t, 9090, cfg.Server.Port)
+	assert.Equal(t, "[redacted]", cfg.Webhook.Secret)
+}
+
+func TestConfig_InvalidFails(t *testing.T) {
+	out, err := runApp(t, "--port", "0", "config")
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "server.port")
+	assert.Contains(t, out, "Port = 0", "the configuration is printed even when invalid")
+}
+
+func TestConfig_UnknownFormat(t *testing.T) {
+	_, err := runApp(t, "config", "--format", "yaml")
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown format")
+}
@@ -0,0 +1,181 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+// Package commands contains CLI subcommands for administrative tasks.
+package commands
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/mail"
+	"strings"
+
+	"github.com/oliverandrich/go-webapp-template/internal/config"
+	"github.com/oliverandrich/go-webapp-template/internal/database"
+	"github.com/oliverandrich/go-webapp-template/internal/models"
+	"github.com/oliverandrich/go-webapp-template/internal/repository"
+	"github.com/oliverandrich/go-webapp-template/internal/services/auth"
+	"github.com/oliverandrich/go-webapp-template/internal/services/recovery"
+	"github.com/oliverandrich/go-webapp-template/internal/services/webhook"
+	"github.com/urfave/cli/v3"
+)
+
+// CreateAdmin returns the create-admin subcommand.
+func CreateAdmin() *cli.Command {
+	return &cli.Command{
+		Name:  "create-admin",
+		Usage: "Create an administrator account or promote an existing user",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "username",
+				Usage: "Username of the administrator (username mode)",
+			},
+			&cli.StringFlag{
+				Name:  "email",
+				Usage: "Email of the administrator (email mode)",
+			},
+		},
+		Action: createAdmin,
+	}
+}
+
+// AdminResult describes the outcome of EnsureAdmin.
+type AdminResult struct { //nolint:govet // fieldalignment not critical
+	User          *models.User
+	Created       bool     // true if a new user was created, false if an existing one was promoted
+	RecoveryCodes []string // one-time codes to sign in a newly created admin
+}
+
+func createAdmin(ctx context.Context, cmd *cli.Command) error {
+	// The username is checked as given, like in registration
+	username := cmd.String("username")
+	email := strings.TrimSpace(cmd.String("email"))
+
+	if (username == "") == (email == "") {
+		return errors.New("exactly one of --username or --email is required")
+	}
+	if email != "" {
+		if _, err := mail.ParseAddress(email); err != nil {
+			return fmt.Errorf("invalid email address %q", email)
+		}
+	}
+
+	cfg := config.NewFromCLI(cmd)
+	email = auth.NormalizeEmail(email, cfg.Auth.CanonicalizeGmail)
+
+	// Migrations run automatically in Open
+	db, err := database.Open(cfg.Database.DSN)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	result, err := EnsureAdmin(ctx, repository.New(db), recovery.NewService(), username, email, cfg.Auth.UsernameMaxLength)
+	if err != nil {
+		return err
+	}
+
+	webhooks := webhook.NewNotifier(&cfg.Webhook)
+	webhooks.Notify(webhook.EventAdminGranted, map[string]any{
+		"user_id": result.User.ID,
+		"created": result.Created,
+	})
+	// Only fails when the command is interrupted; the admin exists either way
+	_ = webhooks.Wait(ctx)
+
+	printAdminResult(cmd.Root().Writer, result)
+	return nil
+}
+
+// EnsureAdmin makes sure a user with the given username or email exists and
+// has administrator rights. A new user gets a fresh set of recovery codes so
+// they can sign in via /auth/recovery and register a passkey. A new username
+// must pass auth.ValidateUsername with usernameMaxLength, as in registration;
+// existing users are promoted whatever their name.
+func EnsureAdmin(ctx context.Context, repo *repository.Repository, rec *recovery.Service, username, email string, usernameMaxLength int) (*AdminResult, error) {
+	result := &AdminResult{}
+
+	err := repo.WithTx(ctx, func(tx *repository.Repository) error {
+		user, err := findUser(ctx, tx, username, email)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			if email == "" {
+				if err = auth.ValidateUsername(username, usernameMaxLength); err != nil {
+					return fmt.Errorf("invalid username %q: %w", username, err)
+				}
+			}
+			user, err = createUser(ctx, tx, username, email)
+			if err != nil {
+				return fmt.Errorf("failed to create user: %w", err)
+			}
+			result.Created = true
+		case err != nil:
+			return fmt.Errorf("failed to look up user: %w", err)
+		}
+
+		if err = tx.SetAdmin(ctx, user.ID, true); err != nil {
+			return fmt.Errorf("failed to grant admin rights: %w", err)
+		}
+		user.IsAdmin = true
+		result.User = user
+
+		if !result.Created {
+			return nil
+		}
+
+		codes, hashes, err := rec.GenerateCodes(recovery.CodeCount)
+		if err != nil {
+			return fmt.Errorf("failed to generate recovery codes: %w", err)
+		}
+		if err = tx.CreateRecoveryCodes(ctx, user.ID, hashes); err != nil {
+			return fmt.Errorf("failed to store recovery codes: %w", err)
+		}
+		result.RecoveryCodes = codes
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func findUser(ctx context.Context, repo *repository.Repository, username, email string) (*models.User, error) {
+	if email != "" {
+		return repo.GetUserByEmail(ctx, email)
+	}
+	return repo.GetUserByUsername(ctx, username)
+}
+
+func createUser(ctx context.Context, repo *repository.Repository, username, email string) (*models.User, error) {
+	if email == "" {
+		return repo.CreateUser(ctx, username)
+	}
+
+	user, err := repo.CreateUserWithEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	// The operator vouches for the address, no verification mail needed
+	if markErr := repo.MarkEmailVerified(ctx, user.ID); markErr != nil {
+		return nil, markErr
+	}
+	return repo.GetUserByID(ctx, user.ID)
+}
+
+func printAdminResult(w io.Writer, result *AdminResult) {
+	if !result.Created {
+		_, _ = fmt.Fprintf(w, "Promoted existing user %q to administrator.\n", result.User.Username)
+		return
+	}
+
+	_, _ = fmt.Fprintf(w, "Created administrator %q.\n\n", result.User.Username)
+	_, _ = fmt.Fprintln(w, "Sign in at /auth/recovery with one of these recovery codes and register a passkey:")
+	for _, code := range result.RecoveryCodes {
+		_, _ = fmt.Fprintf(w, "  %s\n", code)
+	}
+	_, _ = fmt.Fprintln(w, "\nEach code can only be used once. Store them somewhere safe.")
+}
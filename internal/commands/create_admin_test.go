@@ -0,0 +1,138 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package commands_test
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/oliverandrich/go-webapp-template/internal/commands"
+	"github.com/oliverandrich/go-webapp-template/internal/config"
+	"github.com/oliverandrich/go-webapp-template/internal/database"
+	"github.com/oliverandrich/go-webapp-template/internal/repository"
+	"github.com/oliverandrich/go-webapp-template/internal/services/auth"
+	"github.com/oliverandrich/go-webapp-template/internal/services/recovery"
+	"github.com/oliverandrich/go-webapp-template/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli/v3"
+)
+
+func runApp(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	var out bytes.Buffer
+	app := &cli.Command{
+		Name:     "app",
+		Flags:    config.Flags(),
+		Writer:   &out,
+		Commands: []*cli.Command{commands.CreateAdmin(), commands.Config(), commands.Backup()},
+	}
+	err := app.Run(context.Background(), append([]string{"app"}, args...))
+	return out.String(), err
+}
+
+func TestEnsureAdmin_CreatesUser(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	ctx := context.Background()
+
+	result, err := commands.EnsureAdmin(ctx, repo, recovery.NewService(), "", "admin@example.com", 0)
+
+	require.NoError(t, err)
+	assert.True(t, result.Created)
+	assert.Len(t, result.RecoveryCodes, recovery.CodeCount)
+
+	user, err := repo.GetUserByEmail(ctx, "admin@example.com")
+	require.NoError(t, err)
+	assert.True(t, user.IsAdmin)
+	assert.True(t, user.EmailVerified)
+
+	valid, err := repo.ValidateAndUseRecoveryCode(ctx, user.ID, recovery.NormalizeCode(result.RecoveryCodes[0]))
+	require.NoError(t, err)
+	assert.True(t, valid)
+}
+
+func TestEnsureAdmin_RejectsInvalidUsername(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	ctx := context.Background()
+
+	tests := map[string]error{
+		"al ice":      auth.ErrUsernameCharacters,
+		" alice":      auth.ErrUsernameWhitespace,
+		"toolongname": auth.ErrUsernameTooLong,
+	}
+	for username, want := range tests {
+		_, err := commands.EnsureAdmin(ctx, repo, recovery.NewService(), username, "", 8)
+
+		require.ErrorIs(t, err, want, username)
+	}
+
+	users, err := repo.SearchUsers(ctx, "", 0)
+	require.NoError(t, err)
+	assert.Empty(t, users)
+}
+
+func TestEnsureAdmin_PromotesExistingUser(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	ctx := context.Background()
+	existing := testutil.NewTestUser(t, repo, "alice")
+
+	result, err := commands.EnsureAdmin(ctx, repo, recovery.NewService(), "alice", "", 0)
+
+	require.NoError(t, err)
+	assert.False(t, result.Created)
+	assert.Empty(t, result.RecoveryCodes)
+	assert.Equal(t, existing.ID, result.User.ID)
+
+	user, err := repo.GetUserByID(ctx, existing.ID)
+	require.NoError(t, err)
+	assert.True(t, user.IsAdmin)
+
+	hasCodes, err := repo.HasRecoveryCodes(ctx, existing.ID)
+	require.NoError(t, err)
+	assert.False(t, hasCodes, "existing users keep their own recovery codes")
+}
+
+func TestCreateAdminCommand(t *testing.T) {
+	dsn := filepath.Join(t.TempDir(), "app.db")
+
+	out, err := runApp(t, "create-admin", "--database-dsn", dsn, "--username", "root")
+
+	require.NoError(t, err)
+	assert.Contains(t, out, `Created administrator "root"`)
+
+	db, err := database.Open(dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	user, err := repository.New(db).GetUserByUsername(context.Background(), "root")
+	require.NoError(t, err)
+	assert.True(t, user.IsAdmin)
+
+	// Running it again promotes (keeps) the existing user
+	out, err = runApp(t, "create-admin", "--database-dsn", dsn, "--username", "root")
+	require.NoError(t, err)
+	assert.Contains(t, out, `Promoted existing user "root"`)
+}
+
+func TestCreateAdminCommand_ValidationErrors(t *testing.T) {
+	dsn := filepath.Join(t.TempDir(), "app.db")
+
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"no identity", []string{}},
+		{"both identities", []string{"--username", "root", "--email", "root@example.com"}},
+		{"invalid email", []string{"--email", "not-an-email"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := append([]string{"create-admin", "--database-dsn", dsn}, tt.args...)
+			_, err := runApp(t, args...)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -5,6 +5,9 @@ package config
 
 import (
 	"fmt"
+	"net/netip"
+	"net/url"
+	"strconv"
 	"strings"
 
 	altsrc "github.com/urfave/cli-altsrc/v3"
@@ -23,110 +26,333 @@ type Config struct { //nolint:govet // fieldalignment not critical for config st
 	Session  SessionConfig
 	Auth     AuthConfig
 	SMTP     SMTPConfig
+	Webhook  WebhookConfig
+	CSP      CSPConfig
+	CSRF     CSRFConfig
+	I18n     I18nConfig
+	Tracing  TracingConfig
+
+	Robots      RobotsConfig
+	SecurityTxt SecurityTxtConfig
+}
+
+type RobotsConfig struct {
+	NoIndex bool   // Disallow all crawlers ("Disallow: /"), e.g. for staging
+	File    string // File served at /robots.txt (empty = built-in default)
+}
+
+type SecurityTxtConfig struct {
+	Contact            []string // Contact URIs (mailto:, https:, tel:); /.well-known/security.txt is only served when set
+	Expires            string   // RFC 3339 expiry (empty = one year from the request)
+	Encryption         string   // URI of the key for encrypted reports
+	Policy             string   // URI of the vulnerability disclosure policy
+	PreferredLanguages string   // Comma-separated language tags, e.g. "en, de"
+}
+
+type CSRFConfig struct {
+	CookieName     string   // CSRF cookie name
+	HeaderName     string   // Request header carrying the token (in addition to the csrf_token form field)
+	SameSite       string   // SameSite mode for the cookie: lax, strict, none (none requires HTTPS)
+	ExemptPrefixes []string // Path prefixes without CSRF checks, only for endpoints not authenticated by cookies
+}
+
+type I18nConfig struct {
+	DefaultLanguage string // Language used when Accept-Language matches no translation (en, de)
+}
+
+type CSPConfig struct { //nolint:govet // fieldalignment not critical
+	ReportOnly bool   // Send Content-Security-Policy-Report-Only instead of enforcing
+	ReportURI  string // Endpoint that receives CSP violation reports (optional)
+}
+
+type WebhookConfig struct {
+	URL    string // Endpoint receiving security event notifications (disabled when empty)
+	Secret string // Shared secret for the HMAC-SHA256 signature header
 }
 
-type AuthConfig struct {
+type TracingConfig struct { //nolint:govet // fieldalignment not critical
+	Enabled     bool   // Export OpenTelemetry spans for requests and database queries
+	Endpoint    string // OTLP/HTTP traces endpoint, e.g. "http://localhost:4318/v1/traces"
+	ServiceName string // service.name resource attribute
+	SampleRate  int    // Record 1 in N new traces (0 or 1 = record all)
+}
+
+type AuthConfig struct { //nolint:govet // fieldalignment not critical
 	UseEmail            bool // Use email instead of username for authentication
 	RequireVerification bool // Require email verification before login (default: true when UseEmail)
+	LockoutThreshold    int  // Failed logins per client within LockoutWindow that block recovery codes (0 = disabled)
+	LockoutWindow       int  // Lockout window in seconds
+	CanonicalizeGmail   bool // Collapse Gmail dots and +tags when storing and looking up emails
+
+	ChangePasswordURL string // Target of the /.well-known/change-password redirect
+	RegistrationLimit int    // Registrations a client IP may start per UTC day (0 = unlimited)
+	UsernameMaxLength int    // Maximum username length in characters (at most 64)
+
+	MagicLink        bool // Allow signing in with a single-use link sent by email (requires UseEmail)
+	MagicLinkTTL     int  // Seconds a sign-in link stays valid
+	MagicLinkLimit   int  // Sign-in links an address may request per hour (0 = unlimited)
+	MagicLinkIPLimit int  // Sign-in links a client IP may request per hour, for any address (0 = unlimited)
+
+	EnumerationSafe    bool // Answer registrations for taken emails like new ones and notify the owner by email (requires UseEmail)
+	AccountNoticeLimit int  // Account-exists emails an address may receive per hour (0 = unlimited)
 }
 
 type SMTPConfig struct { //nolint:govet // fieldalignment not critical
-	Host     string // SMTP server host
-	Port     int    // SMTP port (25, 465, 587)
-	Username string // SMTP username
-	Password string // SMTP password
-	From     string // Sender email address
-	FromName string // Sender name
-	TLS      bool   // Enable TLS (auto-detects implicit TLS on port 465, STARTTLS otherwise)
+	Host      string            // SMTP server host
+	Port      int               // SMTP port (25, 465, 587)
+	Username  string            // SMTP username
+	Password  string            // SMTP password
+	From      string            // Sender email address
+	FromName  string            // Sender name
+	FromNames map[string]string // Sender name per locale (e.g. "de"), overrides FromName
+	TLS       bool              // Enable TLS (auto-detects implicit TLS on port 465, STARTTLS otherwise)
+
+	IdleTimeout int // Seconds an idle SMTP connection is kept open for reuse (0 = close after each message)
+	TokenTTL    int // Seconds an email verification or email change link stays valid
 }
 
 type TLSConfig struct {
-	Mode     string // auto, acme, selfsigned, manual, off
-	CertDir  string // Directory for auto-generated certificates
-	Email    string // ACME email for Let's Encrypt
-	CertFile string // Path to certificate file (manual mode)
-	KeyFile  string // Path to private key file (manual mode)
+	Mode      string   // auto, acme, selfsigned, manual, off
+	CertDir   string   // Directory for auto-generated certificates
+	Email     string   // ACME email for Let's Encrypt
+	CertFile  string   // Path to certificate file (manual mode)
+	KeyFile   string   // Path to private key file (manual mode)
+	ExtraSANs []string // Additional DNS names or IPs for the self-signed certificate (also added to DNS-01 certificates)
+
+	AllowedHosts []string // Further hosts ACME (http-01) may issue certificates for; "*.example.com" admits single-label subdomains
+
+	ACMEDirectoryURL string // ACME directory (empty = Let's Encrypt production)
+	ACMEChallenge    string // http-01 (default) or dns-01
+	ACMEDNSProvider  string // DNS provider for dns-01: exec
+	ACMEDNSExec      string // Hook command of the exec DNS provider
+
+	HSTSMaxAge            int  // Strict-Transport-Security max-age in seconds (0 = no header)
+	HSTSIncludeSubdomains bool // Add includeSubDomains to the HSTS header
+	HSTSPreload           bool // Add preload to the HSTS header
 }
 
 type ServerConfig struct { //nolint:govet // fieldalignment not critical for config structs
-	Host        string
-	Port        int
-	BaseURL     string
-	MaxBodySize int // in MB
+	Host           string // Bind address, or "unix:/path/to.sock" to listen on a Unix socket
+	Port           int
+	BaseURL        string
+	Socket         string   // Unix socket path (overrides host/port when set, plain HTTP only)
+	MaxBodySize    int      // in MB
+	AuthBodySize   int      // in KB, stricter limit for the /auth and /api/auth routes (0 = MaxBodySize applies)
+	GzipLevel      int      // gzip compression level (1-9, -1 = default, 0 = disabled)
+	GzipMinSize    int      // Responses smaller than this many bytes are sent uncompressed
+	TrustedProxies []string // Proxy IPs/CIDRs whose forwarded client IP headers are trusted
+	Maintenance    bool     // Serve the maintenance page to everyone except administrators
+	Favicon        string   // File served at /favicon.ico (empty = favicon.ico from the static assets)
+	Manifest       string   // File served at /manifest.webmanifest (empty = manifest.webmanifest from the static assets)
+	IconMaxAge     int      // Cache max-age in seconds for the favicon, manifest and unhashed icons (0 = revalidate every time)
+	SPAFallback    bool     // Answer unknown GET paths outside /api, /auth and /static with static/index.html
+
+	RequestTimeout        int      // Seconds a request may take before it is answered with 503 (0 = no limit)
+	RequestTimeoutExclude []string // Path prefixes without a request timeout, e.g. for streaming endpoints
+	MaxInFlight           int      // Requests handled concurrently before further ones get 503 (0 = unlimited)
+	MaxInFlightExclude    []string // Path prefixes not counted towards MaxInFlight, e.g. for streaming endpoints
+	PathPrefix            string   // Path the app is mounted below behind a reverse proxy, e.g. "/app" (empty = root)
+
+	BodyLimits map[string]string // Max body size per path prefix, e.g. "/api/upload" -> "10m" (tightest match wins, MaxBodySize elsewhere)
 }
 
-type LogConfig struct {
+type LogConfig struct { //nolint:govet // fieldalignment not critical
 	Level  string // debug, info, warn, error
 	Format string // text, json
+
+	SampleRate    int // Log 1 in N fast successful requests (0 or 1 = log all)
+	SlowThreshold int // Requests slower than this many milliseconds are always logged
+
+	RedactKeys []string // Attribute keys whose values are replaced in log output
 }
 
 type DatabaseConfig struct {
-	DSN string
+	DSN                string
+	CheckpointInterval int // Seconds between WAL checkpoints (0 = disabled)
+	SlowQueryThreshold int // Queries slower than this many milliseconds are logged at warn level (0 = no query logging)
 }
 
 type WebAuthnConfig struct {
-	RPID          string // Relying Party ID (domain), e.g. "localhost"
-	RPOrigin      string // Relying Party Origin (full URL), e.g. "http://localhost:8080"
-	RPDisplayName string // Display name shown to users
+	RPID                  string // Relying Party ID (domain), e.g. "localhost"
+	RPOrigin              string // Relying Party Origin (full URL), e.g. "http://localhost:8080"
+	RPDisplayName         string // Display name shown to users
+	MaxCredentialsPerUser int    // Maximum passkeys per user (0 = unlimited)
+
+	Timeout                 int    // Ceremony timeout in milliseconds (0 = 2 minutes)
+	UserVerification        string // required, preferred, discouraged
+	AuthenticatorAttachment string // platform, cross-platform, any
 }
 
 type SessionConfig struct { //nolint:govet // fieldalignment not critical
-	CookieName string // Session cookie name
-	MaxAge     int    // Session max age in seconds
-	HashKey    string // 32-byte hex string for HMAC signing
-	BlockKey   string // 32-byte hex string for AES encryption (optional)
+	Backend           string // cookie (whole session in a signed cookie) or database (revocable server-side sessions)
+	CookieName        string // Session cookie name
+	MaxAge            int    // Session max age in seconds
+	RememberMeMaxAge  int    // Session max age in seconds when "remember me" is checked (0 = same as MaxAge)
+	HashKey           string // 32-byte hex string for HMAC signing
+	BlockKey          string // 32-byte hex string for AES encryption (optional)
+	URLSigningKey     string // 32-byte hex string for signed URLs (derived from HashKey if empty)
+	ExtendOnReauth    bool   // Extend the session deadline when the user re-asserts a passkey
+	AllowEphemeralKey bool   // Generate a random hash key when none is set (defaults to true only for a localhost base URL)
+
+	PreviousHashKeys  []string // Retired hash keys, newest first; cookies signed with them stay valid until they expire
+	PreviousBlockKeys []string // Block keys paired by position with PreviousHashKeys (empty = none were used)
 }
 
 func NewFromCLI(cmd *cli.Command) *Config {
 	cfg := &Config{
 		Server: ServerConfig{
-			Host:        cmd.String("host"),
-			Port:        int(cmd.Int("port")),
-			BaseURL:     cmd.String("base-url"),
-			MaxBodySize: int(cmd.Int("max-body-size")),
+			Host:           cmd.String("host"),
+			Port:           int(cmd.Int("port")),
+			BaseURL:        cmd.String("base-url"),
+			Socket:         cmd.String("socket"),
+			MaxBodySize:    int(cmd.Int("max-body-size")),
+			AuthBodySize:   int(cmd.Int("auth-body-size")),
+			BodyLimits:     cmd.StringMap("body-limits"),
+			GzipLevel:      int(cmd.Int("gzip-level")),
+			GzipMinSize:    int(cmd.Int("gzip-min-size")),
+			TrustedProxies: cmd.StringSlice("trusted-proxies"),
+			Maintenance:    cmd.Bool("maintenance"),
+			Favicon:        cmd.String("favicon"),
+			Manifest:       cmd.String("manifest"),
+			IconMaxAge:     int(cmd.Int("icon-max-age")),
+			SPAFallback:    cmd.Bool("spa-fallback"),
+
+			RequestTimeout:        int(cmd.Int("request-timeout")),
+			RequestTimeoutExclude: cmd.StringSlice("request-timeout-exclude"),
+			MaxInFlight:           int(cmd.Int("max-in-flight")),
+			MaxInFlightExclude:    cmd.StringSlice("max-in-flight-exclude"),
+			PathPrefix:            cmd.String("path-prefix"),
 		},
 		Log: LogConfig{
 			Level:  cmd.String("log-level"),
 			Format: cmd.String("log-format"),
+
+			SampleRate:    int(cmd.Int("log-sample-rate")),
+			SlowThreshold: int(cmd.Int("log-slow-threshold")),
+
+			RedactKeys: cmd.StringSlice("log-redact-keys"),
 		},
 		Database: DatabaseConfig{
-			DSN: cmd.String("database-dsn"),
+			DSN:                cmd.String("database-dsn"),
+			CheckpointInterval: int(cmd.Int("database-checkpoint-interval")),
+			SlowQueryThreshold: int(cmd.Int("database-slow-query-threshold")),
 		},
 		TLS: TLSConfig{
-			Mode:     cmd.String("tls-mode"),
-			CertDir:  cmd.String("tls-cert-dir"),
-			Email:    cmd.String("tls-email"),
-			CertFile: cmd.String("tls-cert-file"),
-			KeyFile:  cmd.String("tls-key-file"),
+			Mode:      cmd.String("tls-mode"),
+			CertDir:   cmd.String("tls-cert-dir"),
+			Email:     cmd.String("tls-email"),
+			CertFile:  cmd.String("tls-cert-file"),
+			KeyFile:   cmd.String("tls-key-file"),
+			ExtraSANs: cmd.StringSlice("tls-extra-sans"),
+
+			AllowedHosts: cmd.StringSlice("tls-allowed-hosts"),
+
+			ACMEDirectoryURL: cmd.String("tls-acme-directory-url"),
+			ACMEChallenge:    cmd.String("tls-acme-challenge"),
+			ACMEDNSProvider:  cmd.String("tls-acme-dns-provider"),
+			ACMEDNSExec:      cmd.String("tls-acme-dns-exec"),
+
+			HSTSMaxAge:            cmd.Int("tls-hsts-max-age"),
+			HSTSIncludeSubdomains: cmd.Bool("tls-hsts-include-subdomains"),
+			HSTSPreload:           cmd.Bool("tls-hsts-preload"),
 		},
 		WebAuthn: WebAuthnConfig{
-			RPID:          cmd.String("webauthn-rp-id"),
-			RPOrigin:      cmd.String("webauthn-rp-origin"),
-			RPDisplayName: cmd.String("webauthn-rp-display-name"),
+			RPID:                  cmd.String("webauthn-rp-id"),
+			RPOrigin:              cmd.String("webauthn-rp-origin"),
+			RPDisplayName:         cmd.String("webauthn-rp-display-name"),
+			MaxCredentialsPerUser: int(cmd.Int("webauthn-max-credentials")),
+
+			Timeout:                 int(cmd.Int("webauthn-timeout")),
+			UserVerification:        cmd.String("webauthn-user-verification"),
+			AuthenticatorAttachment: cmd.String("webauthn-authenticator-attachment"),
 		},
 		Session: SessionConfig{
-			CookieName: cmd.String("session-cookie-name"),
-			MaxAge:     int(cmd.Int("session-max-age")),
-			HashKey:    cmd.String("session-hash-key"),
-			BlockKey:   cmd.String("session-block-key"),
+			Backend:           cmd.String("session-backend"),
+			CookieName:        cmd.String("session-cookie-name"),
+			MaxAge:            int(cmd.Int("session-max-age")),
+			HashKey:           cmd.String("session-hash-key"),
+			URLSigningKey:     cmd.String("session-url-signing-key"),
+			BlockKey:          cmd.String("session-block-key"),
+			ExtendOnReauth:    cmd.Bool("extend-session-on-reauth"),
+			AllowEphemeralKey: cmd.Bool("session-allow-ephemeral-key"),
+			RememberMeMaxAge:  int(cmd.Int("session-remember-me-max-age")),
+
+			PreviousHashKeys:  cmd.StringSlice("session-previous-hash-keys"),
+			PreviousBlockKeys: cmd.StringSlice("session-previous-block-keys"),
 		},
 		Auth: AuthConfig{
 			UseEmail:            cmd.Bool("auth-use-email"),
 			RequireVerification: cmd.Bool("auth-require-verification"),
+			LockoutThreshold:    int(cmd.Int("auth-lockout-threshold")),
+			LockoutWindow:       int(cmd.Int("auth-lockout-window")),
+			CanonicalizeGmail:   cmd.Bool("auth-canonicalize-gmail"),
+			ChangePasswordURL:   cmd.String("auth-change-password-url"),
+			RegistrationLimit:   int(cmd.Int("auth-registration-limit")),
+			UsernameMaxLength:   int(cmd.Int("auth-username-max-length")),
+			MagicLink:           cmd.Bool("auth-magic-link"),
+			MagicLinkTTL:        int(cmd.Int("auth-magic-link-ttl")),
+			MagicLinkLimit:      int(cmd.Int("auth-magic-link-limit")),
+			MagicLinkIPLimit:    int(cmd.Int("auth-magic-link-ip-limit")),
+			EnumerationSafe:     cmd.Bool("auth-enumeration-safe"),
+			AccountNoticeLimit:  int(cmd.Int("auth-account-notice-limit")),
 		},
 		SMTP: SMTPConfig{
-			Host:     cmd.String("smtp-host"),
-			Port:     int(cmd.Int("smtp-port")),
-			Username: cmd.String("smtp-username"),
-			Password: cmd.String("smtp-password"),
-			From:     cmd.String("smtp-from"),
-			FromName: cmd.String("smtp-from-name"),
-			TLS:      cmd.Bool("smtp-tls"),
+			Host:      cmd.String("smtp-host"),
+			Port:      int(cmd.Int("smtp-port")),
+			Username:  cmd.String("smtp-username"),
+			Password:  cmd.String("smtp-password"),
+			From:      cmd.String("smtp-from"),
+			FromName:  cmd.String("smtp-from-name"),
+			FromNames: cmd.StringMap("smtp-from-names"),
+			TLS:       cmd.Bool("smtp-tls"),
+
+			IdleTimeout: int(cmd.Int("smtp-idle-timeout")),
+			TokenTTL:    int(cmd.Int("smtp-token-ttl")),
+		},
+		Webhook: WebhookConfig{
+			URL:    cmd.String("webhook-url"),
+			Secret: cmd.String("webhook-secret"),
+		},
+		CSP: CSPConfig{
+			ReportOnly: cmd.Bool("csp-report-only"),
+			ReportURI:  cmd.String("csp-report-uri"),
+		},
+		CSRF: CSRFConfig{
+			CookieName:     cmd.String("csrf-cookie-name"),
+			HeaderName:     cmd.String("csrf-header-name"),
+			SameSite:       cmd.String("csrf-same-site"),
+			ExemptPrefixes: cmd.StringSlice("csrf-exempt-prefixes"),
+		},
+		I18n: I18nConfig{
+			DefaultLanguage: cmd.String("default-language"),
+		},
+		Tracing: TracingConfig{
+			Enabled:     cmd.Bool("tracing-enabled"),
+			Endpoint:    cmd.String("tracing-endpoint"),
+			ServiceName: cmd.String("tracing-service-name"),
+			SampleRate:  int(cmd.Int("tracing-sample-rate")),
+		},
+		Robots: RobotsConfig{
+			NoIndex: cmd.Bool("robots-no-index"),
+			File:    cmd.String("robots-file"),
+		},
+		SecurityTxt: SecurityTxtConfig{
+			Contact:            cmd.StringSlice("security-txt-contact"),
+			Expires:            cmd.String("security-txt-expires"),
+			Encryption:         cmd.String("security-txt-encryption"),
+			Policy:             cmd.String("security-txt-policy"),
+			PreferredLanguages: cmd.String("security-txt-preferred-languages"),
 		},
 	}
 
 	if cfg.Server.BaseURL == "" {
-		cfg.Server.BaseURL = buildBaseURL(cfg)
+		cfg.Server.BaseURL = buildBaseURL(cfg) + cfg.Server.PathPrefix
+	}
+
+	// A random session key logs everyone out on restart, so it's only the
+	// default for local development
+	if !cmd.IsSet("session-allow-ephemeral-key") {
+		cfg.Session.AllowEphemeralKey = baseURLIsLocalhost(cfg.Server.BaseURL)
 	}
 
 	// Apply WebAuthn defaults based on BaseURL
@@ -141,9 +367,9 @@ func applyWebAuthnDefaults(cfg *Config) {
 	if cfg.WebAuthn.RPID == "" {
 		cfg.WebAuthn.RPID = cfg.Server.Host
 	}
-	// Use BaseURL as RPOrigin
+	// Use BaseURL as RPOrigin; origins have no path, e.g. a path prefix
 	if cfg.WebAuthn.RPOrigin == "" {
-		cfg.WebAuthn.RPOrigin = cfg.Server.BaseURL
+		cfg.WebAuthn.RPOrigin = baseURLOrigin(cfg.Server.BaseURL)
 	}
 	// Default display name
 	if cfg.WebAuthn.RPDisplayName == "" {
@@ -151,6 +377,16 @@ func applyWebAuthnDefaults(cfg *Config) {
 	}
 }
 
+// baseURLOrigin strips the path from a base URL mounted below a path prefix.
+// Other base URLs are returned unchanged.
+func baseURLOrigin(baseURL string) string {
+	u, err := url.Parse(baseURL)
+	if err != nil || strings.Trim(u.Path, "/") == "" {
+		return baseURL
+	}
+	return u.Scheme + "://" + u.Host
+}
+
 func buildBaseURL(cfg *Config) string {
 	host := cfg.Server.Host
 	port := cfg.Server.Port
@@ -164,8 +400,8 @@ func buildBaseURL(cfg *Config) string {
 		scheme = "https"
 	}
 
-	// ACME mode always uses port 443
-	if mode == "acme" {
+	// ACME mode always uses port 443, unless certificates come via DNS-01
+	if mode == "acme" && !cfg.TLS.UsesDNSChallenge() {
 		return fmt.Sprintf("https://%s", host)
 	}
 
@@ -187,6 +423,90 @@ func shouldUseTLS(mode, host string) bool {
 	}
 }
 
+// UsesDNSChallenge reports whether ACME certificates are obtained via the
+// DNS-01 challenge, which needs neither port 80 nor port 443.
+func (c *TLSConfig) UsesDNSChallenge() bool {
+	return strings.EqualFold(c.ACMEChallenge, "dns-01")
+}
+
+// TrustedProxyPrefixes parses TrustedProxies. Plain IPs are treated as
+// single-address prefixes.
+func (c *ServerConfig) TrustedProxyPrefixes() ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(c.TrustedProxies))
+	for _, value := range c.TrustedProxies {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		if strings.Contains(value, "/") {
+			prefix, err := netip.ParsePrefix(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: %w", value, err)
+			}
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", value, err)
+		}
+		addr = addr.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return prefixes, nil
+}
+
+// BodyLimitBytes parses BodyLimits into byte counts per path prefix.
+// Prefixes must start with "/".
+func (c *ServerConfig) BodyLimitBytes() (map[string]int64, error) {
+	limits := make(map[string]int64, len(c.BodyLimits))
+	for prefix, size := range c.BodyLimits {
+		prefix = strings.TrimSpace(prefix)
+		if !strings.HasPrefix(prefix, "/") {
+			return nil, fmt.Errorf("body limit prefix %q must start with /", prefix)
+		}
+		n, err := ParseByteSize(size)
+		if err != nil {
+			return nil, fmt.Errorf("invalid body limit for %s: %w", prefix, err)
+		}
+		limits[prefix] = n
+	}
+	return limits, nil
+}
+
+// ParseByteSize parses a positive size such as "512", "16k", "10m" or "1g"
+// into bytes. The suffixes are case-insensitive and powers of 1024.
+func ParseByteSize(s string) (int64, error) {
+	value := strings.ToLower(strings.TrimSpace(s))
+	shift := 0
+	switch {
+	case strings.HasSuffix(value, "k"):
+		shift = 10
+	case strings.HasSuffix(value, "m"):
+		shift = 20
+	case strings.HasSuffix(value, "g"):
+		shift = 30
+	}
+	if shift > 0 {
+		value = value[:len(value)-1]
+	}
+
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("size must be a positive number with an optional k, m or g suffix, got %q", s)
+	}
+	if n > (1<<62)>>shift {
+		return 0, fmt.Errorf("size %q is too large", s)
+	}
+	return n << shift, nil
+}
+
+// baseURLIsLocalhost reports whether the base URL points to localhost.
+func baseURLIsLocalhost(baseURL string) bool {
+	u, err := url.Parse(baseURL)
+	return err == nil && u.Host != "" && IsLocalhost(u.Hostname())
+}
+
 // IsLocalhost checks if the host is a localhost address.
 func IsLocalhost(host string) bool {
 	switch host {
@@ -216,12 +536,97 @@ func Flags() []cli.Flag {
 			Usage:   "Base URL for the application",
 			Sources: cli.NewValueSourceChain(cli.EnvVar("BASE_URL"), toml.TOML("server.base_url", configFile)),
 		},
+		&cli.StringFlag{
+			Name:    "socket",
+			Usage:   "Listen on a Unix domain socket instead of host/port (requires tls-mode off)",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("SOCKET"), toml.TOML("server.socket", configFile)),
+		},
 		&cli.IntFlag{
 			Name:    "max-body-size",
 			Value:   1,
 			Usage:   "Maximum request body size in MB",
 			Sources: cli.NewValueSourceChain(cli.EnvVar("MAX_BODY_SIZE"), toml.TOML("server.max_body_size", configFile)),
 		},
+		&cli.IntFlag{
+			Name:    "auth-body-size",
+			Value:   16,
+			Usage:   "Maximum request body size for auth routes in KB (0 = use max-body-size)",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("AUTH_BODY_SIZE"), toml.TOML("server.auth_body_size", configFile)),
+		},
+		&cli.StringMapFlag{
+			Name:    "body-limits",
+			Usage:   "Maximum request body size per path prefix (e.g. /auth=16k,/api/upload=10m)",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("BODY_LIMITS"), toml.TOML("server.body_limits", configFile)),
+		},
+		&cli.IntFlag{
+			Name:    "gzip-level",
+			Value:   -1,
+			Usage:   "gzip compression level (1-9, -1 = default, 0 = disabled)",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("GZIP_LEVEL"), toml.TOML("server.gzip_level", configFile)),
+		},
+		&cli.IntFlag{
+			Name:    "gzip-min-size",
+			Value:   1024,
+			Usage:   "Minimum response size in bytes before gzip compression is applied",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("GZIP_MIN_SIZE"), toml.TOML("server.gzip_min_size", configFile)),
+		},
+		&cli.StringSliceFlag{
+			Name:    "trusted-proxies",
+			Usage:   "Reverse proxy IPs or CIDRs whose forwarded client IP headers are trusted (comma-separated)",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("TRUSTED_PROXIES"), toml.TOML("server.trusted_proxies", configFile)),
+		},
+		&cli.BoolFlag{
+			Name:    "maintenance",
+			Usage:   "Serve a maintenance page to everyone except administrators",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("MAINTENANCE_MODE"), toml.TOML("server.maintenance", configFile)),
+		},
+		&cli.StringFlag{
+			Name:    "favicon",
+			Usage:   "File served at /favicon.ico (default: favicon.ico from the static assets)",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("FAVICON"), toml.TOML("server.favicon", configFile)),
+		},
+		&cli.StringFlag{
+			Name:    "manifest",
+			Usage:   "Web app manifest served at /manifest.webmanifest (default: manifest.webmanifest from the static assets)",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("MANIFEST"), toml.TOML("server.manifest", configFile)),
+		},
+		&cli.IntFlag{
+			Name:    "icon-max-age",
+			Value:   86400, // 1 day
+			Usage:   "Cache max-age in seconds for the favicon, manifest and unhashed icons (0 = revalidate every time)",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("ICON_MAX_AGE"), toml.TOML("server.icon_max_age", configFile)),
+		},
+		&cli.BoolFlag{
+			Name:    "spa-fallback",
+			Usage:   "Serve static/index.html for unknown GET paths outside /api, /auth and /static",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("SPA_FALLBACK"), toml.TOML("server.spa_fallback", configFile)),
+		},
+		&cli.IntFlag{
+			Name:    "request-timeout",
+			Value:   30,
+			Usage:   "Seconds a request may take before it is answered with 503 (0 = no limit)",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("REQUEST_TIMEOUT"), toml.TOML("server.request_timeout", configFile)),
+		},
+		&cli.StringSliceFlag{
+			Name:    "request-timeout-exclude",
+			Usage:   "Path prefixes exempt from the request timeout, e.g. long-lived streams (comma-separated)",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("REQUEST_TIMEOUT_EXCLUDE"), toml.TOML("server.request_timeout_exclude", configFile)),
+		},
+		&cli.IntFlag{
+			Name:    "max-in-flight",
+			Usage:   "Requests handled concurrently before further ones are answered with 503 (0 = unlimited)",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("MAX_IN_FLIGHT"), toml.TOML("server.max_in_flight", configFile)),
+		},
+		&cli.StringSliceFlag{
+			Name:    "max-in-flight-exclude",
+			Usage:   "Path prefixes not counted towards max-in-flight, e.g. long-lived streams (comma-separated)",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("MAX_IN_FLIGHT_EXCLUDE"), toml.TOML("server.max_in_flight_exclude", configFile)),
+		},
+		&cli.StringFlag{
+			Name:    "path-prefix",
+			Usage:   "Path the app is mounted below behind a reverse proxy, e.g. /app (include it in base-url when setting that)",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("PATH_PREFIX"), toml.TOML("server.path_prefix", configFile)),
+		},
 		&cli.StringFlag{
 			Name:    "log-level",
 			Value:   "info",
@@ -234,12 +639,42 @@ func Flags() []cli.Flag {
 			Usage:   "Log format (text, json)",
 			Sources: cli.NewValueSourceChain(cli.EnvVar("LOG_FORMAT"), toml.TOML("log.format", configFile)),
 		},
+		&cli.IntFlag{
+			Name:    "log-sample-rate",
+			Value:   1,
+			Usage:   "Log only 1 in N successful requests faster than the slow threshold (errors are always logged)",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("LOG_SAMPLE_RATE"), toml.TOML("log.sample_rate", configFile)),
+		},
+		&cli.IntFlag{
+			Name:    "log-slow-threshold",
+			Value:   1000,
+			Usage:   "Requests slower than this many milliseconds are always logged",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("LOG_SLOW_THRESHOLD"), toml.TOML("log.slow_threshold", configFile)),
+		},
+		&cli.StringSliceFlag{
+			Name:    "log-redact-keys",
+			Value:   []string{"password", "token", "secret", "session_secret", "authorization", "cookie"},
+			Usage:   "Log attribute keys whose values are redacted (comma-separated)",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("LOG_REDACT_KEYS"), toml.TOML("log.redact_keys", configFile)),
+		},
 		&cli.StringFlag{
 			Name:    "database-dsn",
 			Value:   "./data/app.db",
 			Usage:   "Database DSN",
 			Sources: cli.NewValueSourceChain(cli.EnvVar("DATABASE_DSN"), toml.TOML("database.dsn", configFile)),
 		},
+		&cli.IntFlag{
+			Name:    "database-checkpoint-interval",
+			Value:   300,
+			Usage:   "Seconds between SQLite WAL checkpoints (0 = disabled)",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("DATABASE_CHECKPOINT_INTERVAL"), toml.TOML("database.checkpoint_interval", configFile)),
+		},
+		&cli.IntFlag{
+			Name:    "database-slow-query-threshold",
+			Value:   200,
+			Usage:   "Log queries slower than this many milliseconds at warn level, all others at debug level (0 = no query logging)",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("DATABASE_SLOW_QUERY_THRESHOLD"), toml.TOML("database.slow_query_threshold", configFile)),
+		},
 		&cli.StringFlag{
 			Name:    "tls-mode",
 			Value:   "auto",
@@ -267,6 +702,53 @@ func Flags() []cli.Flag {
 			Usage:   "Path to TLS private key file (manual mode)",
 			Sources: cli.NewValueSourceChain(cli.EnvVar("TLS_KEY_FILE"), toml.TOML("tls.key_file", configFile)),
 		},
+		&cli.StringSliceFlag{
+			Name:    "tls-extra-sans",
+			Usage:   "Additional DNS names or IPs for the self-signed certificate (comma-separated)",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("TLS_EXTRA_SANS"), toml.TOML("tls.extra_sans", configFile)),
+		},
+		&cli.StringSliceFlag{
+			Name:    "tls-allowed-hosts",
+			Usage:   "Additional hosts to obtain ACME certificates for (comma-separated, *.example.com admits its subdomains)",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("TLS_ALLOWED_HOSTS"), toml.TOML("tls.allowed_hosts", configFile)),
+		},
+		&cli.StringFlag{
+			Name:    "tls-acme-directory-url",
+			Usage:   "ACME directory URL, e.g. Let's Encrypt staging or an internal CA (default: Let's Encrypt production)",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("TLS_ACME_DIRECTORY_URL"), toml.TOML("tls.acme_directory_url", configFile)),
+		},
+		&cli.StringFlag{
+			Name:    "tls-acme-challenge",
+			Value:   "http-01",
+			Usage:   "ACME challenge type: http-01 or dns-01",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("TLS_ACME_CHALLENGE"), toml.TOML("tls.acme_challenge", configFile)),
+		},
+		&cli.StringFlag{
+			Name:    "tls-acme-dns-provider",
+			Value:   "exec",
+			Usage:   "DNS provider for the dns-01 challenge: exec",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("TLS_ACME_DNS_PROVIDER"), toml.TOML("tls.acme_dns_provider", configFile)),
+		},
+		&cli.StringFlag{
+			Name:    "tls-acme-dns-exec",
+			Usage:   "Hook command of the exec DNS provider, called as '<cmd> present|cleanup <fqdn> <value>'",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("TLS_ACME_DNS_EXEC"), toml.TOML("tls.acme_dns_exec", configFile)),
+		},
+		&cli.IntFlag{
+			Name:    "tls-hsts-max-age",
+			Usage:   "Strict-Transport-Security max-age in seconds, sent only when TLS is enabled (0 = disabled)",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("TLS_HSTS_MAX_AGE"), toml.TOML("tls.hsts_max_age", configFile)),
+		},
+		&cli.BoolFlag{
+			Name:    "tls-hsts-include-subdomains",
+			Usage:   "Apply HSTS to all subdomains",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("TLS_HSTS_INCLUDE_SUBDOMAINS"), toml.TOML("tls.hsts_include_subdomains", configFile)),
+		},
+		&cli.BoolFlag{
+			Name:    "tls-hsts-preload",
+			Usage:   "Mark the HSTS header as eligible for browser preload lists",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("TLS_HSTS_PRELOAD"), toml.TOML("tls.hsts_preload", configFile)),
+		},
 		// WebAuthn flags
 		&cli.StringFlag{
 			Name:    "webauthn-rp-id",
@@ -284,7 +766,37 @@ func Flags() []cli.Flag {
 			Usage:   "WebAuthn Relying Party display name",
 			Sources: cli.NewValueSourceChain(cli.EnvVar("WEBAUTHN_RP_DISPLAY_NAME"), toml.TOML("webauthn.rp_display_name", configFile)),
 		},
+		&cli.IntFlag{
+			Name:    "webauthn-max-credentials",
+			Value:   0,
+			Usage:   "Maximum number of passkeys per user (0 = unlimited)",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("WEBAUTHN_MAX_CREDENTIALS"), toml.TOML("webauthn.max_credentials", configFile)),
+		},
+		&cli.IntFlag{
+			Name:    "webauthn-timeout",
+			Value:   120000, // 2 minutes
+			Usage:   "Milliseconds a passkey registration or login may take",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("WEBAUTHN_TIMEOUT"), toml.TOML("webauthn.timeout", configFile)),
+		},
+		&cli.StringFlag{
+			Name:    "webauthn-user-verification",
+			Value:   "preferred",
+			Usage:   "User verification requirement (required, preferred, discouraged)",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("WEBAUTHN_USER_VERIFICATION"), toml.TOML("webauthn.user_verification", configFile)),
+		},
+		&cli.StringFlag{
+			Name:    "webauthn-authenticator-attachment",
+			Value:   "any",
+			Usage:   "Authenticators offered for new passkeys (platform, cross-platform, any)",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("WEBAUTHN_AUTHENTICATOR_ATTACHMENT"), toml.TOML("webauthn.authenticator_attachment", configFile)),
+		},
 		// Session flags
+		&cli.StringFlag{
+			Name:    "session-backend",
+			Value:   "cookie",
+			Usage:   "Where sessions are kept: cookie (signed cookie) or database (revocable server-side sessions)",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("SESSION_BACKEND"), toml.TOML("session.backend", configFile)),
+		},
 		&cli.StringFlag{
 			Name:    "session-cookie-name",
 			Value:   "_session",
@@ -297,6 +809,12 @@ func Flags() []cli.Flag {
 			Usage:   "Session max age in seconds",
 			Sources: cli.NewValueSourceChain(cli.EnvVar("SESSION_MAX_AGE"), toml.TOML("session.max_age", configFile)),
 		},
+		&cli.IntFlag{
+			Name:    "session-remember-me-max-age",
+			Value:   2592000, // 30 days in seconds
+			Usage:   "Session max age in seconds when \"remember me\" is checked at login",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("SESSION_REMEMBER_ME_MAX_AGE"), toml.TOML("session.remember_me_max_age", configFile)),
+		},
 		&cli.StringFlag{
 			Name:    "session-hash-key",
 			Usage:   "Session hash key (32-byte hex, auto-generated if empty in dev)",
@@ -307,6 +825,31 @@ func Flags() []cli.Flag {
 			Usage:   "Session block key for encryption (32-byte hex, optional)",
 			Sources: cli.NewValueSourceChain(cli.EnvVar("SESSION_BLOCK_KEY"), toml.TOML("session.block_key", configFile)),
 		},
+		&cli.StringSliceFlag{
+			Name:    "session-previous-hash-keys",
+			Usage:   "Retired session hash keys still accepted for existing cookies (comma-separated, newest first)",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("SESSION_PREVIOUS_HASH_KEYS"), toml.TOML("session.previous_hash_keys", configFile)),
+		},
+		&cli.StringSliceFlag{
+			Name:    "session-previous-block-keys",
+			Usage:   "Block keys belonging to the previous hash keys, in the same order (comma-separated)",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("SESSION_PREVIOUS_BLOCK_KEYS"), toml.TOML("session.previous_block_keys", configFile)),
+		},
+		&cli.StringFlag{
+			Name:    "session-url-signing-key",
+			Usage:   "Key for signed URLs (32-byte hex, derived from the session hash key if empty)",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("SESSION_URL_SIGNING_KEY"), toml.TOML("session.url_signing_key", configFile)),
+		},
+		&cli.BoolFlag{
+			Name:    "extend-session-on-reauth",
+			Usage:   "Extend the session deadline when the user re-asserts a passkey",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("SESSION_EXTEND_ON_REAUTH"), toml.TOML("session.extend_on_reauth", configFile)),
+		},
+		&cli.BoolFlag{
+			Name:    "session-allow-ephemeral-key",
+			Usage:   "Generate a random session hash key when none is configured (default: only for a localhost base URL)",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("SESSION_ALLOW_EPHEMERAL_KEY"), toml.TOML("session.allow_ephemeral_key", configFile)),
+		},
 		// Auth flags
 		&cli.BoolFlag{
 			Name:    "auth-use-email",
@@ -319,6 +862,75 @@ func Flags() []cli.Flag {
 			Usage:   "Require email verification before login (only when auth-use-email is enabled)",
 			Sources: cli.NewValueSourceChain(cli.EnvVar("AUTH_REQUIRE_VERIFICATION"), toml.TOML("auth.require_verification", configFile)),
 		},
+		&cli.IntFlag{
+			Name:    "auth-lockout-threshold",
+			Value:   5,
+			Usage:   "Failed logins from one client within the lockout window that temporarily block recovery code sign-in for the account (0 = disabled)",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("AUTH_LOCKOUT_THRESHOLD"), toml.TOML("auth.lockout_threshold", configFile)),
+		},
+		&cli.IntFlag{
+			Name:    "auth-lockout-window",
+			Value:   900, // 15 minutes
+			Usage:   "Lockout window in seconds",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("AUTH_LOCKOUT_WINDOW"), toml.TOML("auth.lockout_window", configFile)),
+		},
+		&cli.BoolFlag{
+			Name:    "auth-canonicalize-gmail",
+			Usage:   "Treat Gmail addresses that differ only in dots or +tags as the same email",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("AUTH_CANONICALIZE_GMAIL"), toml.TOML("auth.canonicalize_gmail", configFile)),
+		},
+		&cli.StringFlag{
+			Name:    "auth-change-password-url",
+			Value:   "/auth/credentials",
+			Usage:   "Page that /.well-known/change-password redirects to (path or absolute URL)",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("AUTH_CHANGE_PASSWORD_URL"), toml.TOML("auth.change_password_url", configFile)),
+		},
+		&cli.IntFlag{
+			Name:    "auth-registration-limit",
+			Value:   10,
+			Usage:   "Registrations a client IP may start per UTC day (0 = unlimited)",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("AUTH_REGISTRATION_LIMIT"), toml.TOML("auth.registration_limit", configFile)),
+		},
+		&cli.IntFlag{
+			Name:    "auth-username-max-length",
+			Value:   64,
+			Usage:   "Maximum username length in characters (at most 64)",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("AUTH_USERNAME_MAX_LENGTH"), toml.TOML("auth.username_max_length", configFile)),
+		},
+		&cli.BoolFlag{
+			Name:    "auth-magic-link",
+			Usage:   "Allow signing in with a single-use link sent by email (requires auth-use-email)",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("AUTH_MAGIC_LINK"), toml.TOML("auth.magic_link", configFile)),
+		},
+		&cli.IntFlag{
+			Name:    "auth-magic-link-ttl",
+			Value:   900, // 15 minutes
+			Usage:   "Seconds a sign-in link stays valid",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("AUTH_MAGIC_LINK_TTL"), toml.TOML("auth.magic_link_ttl", configFile)),
+		},
+		&cli.IntFlag{
+			Name:    "auth-magic-link-limit",
+			Value:   3,
+			Usage:   "Sign-in links an address may request per hour (0 = unlimited)",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("AUTH_MAGIC_LINK_LIMIT"), toml.TOML("auth.magic_link_limit", configFile)),
+		},
+		&cli.IntFlag{
+			Name:    "auth-magic-link-ip-limit",
+			Value:   10,
+			Usage:   "Sign-in links a client IP may request per hour, for any address (0 = unlimited)",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("AUTH_MAGIC_LINK_IP_LIMIT"), toml.TOML("auth.magic_link_ip_limit", configFile)),
+		},
+		&cli.BoolFlag{
+			Name:    "auth-enumeration-safe",
+			Usage:   "Answer registrations for taken emails like new ones and notify the owner by email (requires auth-use-email)",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("AUTH_ENUMERATION_SAFE"), toml.TOML("auth.enumeration_safe", configFile)),
+		},
+		&cli.IntFlag{
+			Name:    "auth-account-notice-limit",
+			Value:   3,
+			Usage:   "Account-exists emails an address may receive per hour with auth-enumeration-safe (0 = unlimited)",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("AUTH_ACCOUNT_NOTICE_LIMIT"), toml.TOML("auth.account_notice_limit", configFile)),
+		},
 		// SMTP flags
 		&cli.StringFlag{
 			Name:    "smtp-host",
@@ -351,11 +963,140 @@ func Flags() []cli.Flag {
 			Usage:   "Sender display name",
 			Sources: cli.NewValueSourceChain(cli.EnvVar("SMTP_FROM_NAME"), toml.TOML("smtp.from_name", configFile)),
 		},
+		&cli.StringMapFlag{
+			Name:    "smtp-from-names",
+			Usage:   "Sender display name per locale (e.g. de=Meine App,en=My App)",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("SMTP_FROM_NAMES"), toml.TOML("smtp.from_names", configFile)),
+		},
 		&cli.BoolFlag{
 			Name:    "smtp-tls",
 			Value:   true,
 			Usage:   "Enable TLS for SMTP (auto-detects implicit TLS on port 465, STARTTLS otherwise)",
 			Sources: cli.NewValueSourceChain(cli.EnvVar("SMTP_TLS"), toml.TOML("smtp.tls", configFile)),
 		},
+		&cli.IntFlag{
+			Name:    "smtp-idle-timeout",
+			Value:   30,
+			Usage:   "Seconds an idle SMTP connection is kept open for reuse (0 = close after each message)",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("SMTP_IDLE_TIMEOUT"), toml.TOML("smtp.idle_timeout", configFile)),
+		},
+		&cli.IntFlag{
+			Name:    "smtp-token-ttl",
+			Value:   86400,
+			Usage:   "Seconds an email verification or email change link stays valid (300 to 604800)",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("SMTP_TOKEN_TTL"), toml.TOML("smtp.token_ttl", configFile)),
+		},
+		// Webhook flags
+		&cli.StringFlag{
+			Name:    "webhook-url",
+			Usage:   "URL to POST security event notifications to (disabled when empty)",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("WEBHOOK_URL"), toml.TOML("webhook.url", configFile)),
+		},
+		&cli.StringFlag{
+			Name:    "webhook-secret",
+			Usage:   "Shared secret used to sign webhook payloads (HMAC-SHA256)",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("WEBHOOK_SECRET"), toml.TOML("webhook.secret", configFile)),
+		},
+		// Tracing flags
+		&cli.BoolFlag{
+			Name:    "tracing-enabled",
+			Usage:   "Export OpenTelemetry spans for requests and database queries (requires tracing-endpoint)",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("TRACING_ENABLED"), toml.TOML("tracing.enabled", configFile)),
+		},
+		&cli.StringFlag{
+			Name:    "tracing-endpoint",
+			Usage:   "OTLP/HTTP traces endpoint (e.g. http://localhost:4318/v1/traces)",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("TRACING_ENDPOINT"), toml.TOML("tracing.endpoint", configFile)),
+		},
+		&cli.StringFlag{
+			Name:    "tracing-service-name",
+			Value:   "app",
+			Usage:   "Service name reported with every span",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("TRACING_SERVICE_NAME"), toml.TOML("tracing.service_name", configFile)),
+		},
+		&cli.IntFlag{
+			Name:    "tracing-sample-rate",
+			Value:   1,
+			Usage:   "Record 1 in N new traces (0 or 1 = record all); traces started upstream follow the caller's decision",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("TRACING_SAMPLE_RATE"), toml.TOML("tracing.sample_rate", configFile)),
+		},
+		// CSP flags
+		&cli.BoolFlag{
+			Name:    "csp-report-only",
+			Usage:   "Only report Content-Security-Policy violations instead of enforcing the policy",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("CSP_REPORT_ONLY"), toml.TOML("csp.report_only", configFile)),
+		},
+		&cli.StringFlag{
+			Name:    "csp-report-uri",
+			Usage:   "URI that receives Content-Security-Policy violation reports",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("CSP_REPORT_URI"), toml.TOML("csp.report_uri", configFile)),
+		},
+		// CSRF flags
+		&cli.StringFlag{
+			Name:    "csrf-cookie-name",
+			Value:   "_csrf",
+			Usage:   "CSRF cookie name",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("CSRF_COOKIE_NAME"), toml.TOML("csrf.cookie_name", configFile)),
+		},
+		&cli.StringFlag{
+			Name:    "csrf-header-name",
+			Value:   "X-CSRF-Token",
+			Usage:   "Request header carrying the CSRF token (e.g. X-XSRF-TOKEN)",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("CSRF_HEADER_NAME"), toml.TOML("csrf.header_name", configFile)),
+		},
+		&cli.StringFlag{
+			Name:    "csrf-same-site",
+			Value:   "lax",
+			Usage:   "SameSite mode for the CSRF cookie (lax, strict, none)",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("CSRF_SAME_SITE"), toml.TOML("csrf.same_site", configFile)),
+		},
+		&cli.StringSliceFlag{
+			Name:    "csrf-exempt-prefixes",
+			Usage:   "Path prefixes without CSRF checks, e.g. /api/ with bearer tokens; never for cookie-authenticated routes (comma-separated)",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("CSRF_EXEMPT_PREFIXES"), toml.TOML("csrf.exempt_prefixes", configFile)),
+		},
+		// i18n flags
+		&cli.StringFlag{
+			Name:    "default-language",
+			Value:   "en",
+			Usage:   "Language used when Accept-Language matches no translation (en, de)",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("DEFAULT_LANGUAGE"), toml.TOML("i18n.default_language", configFile)),
+		},
+		// robots.txt and security.txt flags
+		&cli.BoolFlag{
+			Name:    "robots-no-index",
+			Usage:   "Disallow all crawlers in /robots.txt",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("ROBOTS_NO_INDEX"), toml.TOML("robots.no_index", configFile)),
+		},
+		&cli.StringFlag{
+			Name:    "robots-file",
+			Usage:   "File served at /robots.txt (default: built-in rules)",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("ROBOTS_FILE"), toml.TOML("robots.file", configFile)),
+		},
+		&cli.StringSliceFlag{
+			Name:    "security-txt-contact",
+			Usage:   "Security contact URIs for /.well-known/security.txt, e.g. mailto:security@example.com (comma-separated)",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("SECURITY_TXT_CONTACT"), toml.TOML("security_txt.contact", configFile)),
+		},
+		&cli.StringFlag{
+			Name:    "security-txt-expires",
+			Usage:   "Expiry of security.txt in RFC 3339 format (default: one year ahead)",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("SECURITY_TXT_EXPIRES"), toml.TOML("security_txt.expires", configFile)),
+		},
+		&cli.StringFlag{
+			Name:    "security-txt-encryption",
+			Usage:   "URI of the key for encrypted security reports",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("SECURITY_TXT_ENCRYPTION"), toml.TOML("security_txt.encryption", configFile)),
+		},
+		&cli.StringFlag{
+			Name:    "security-txt-policy",
+			Usage:   "URI of the vulnerability disclosure policy",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("SECURITY_TXT_POLICY"), toml.TOML("security_txt.policy", configFile)),
+		},
+		&cli.StringFlag{
+			Name:    "security-txt-preferred-languages",
+			Usage:   "Languages security reports may be written in, e.g. \"en, de\"",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("SECURITY_TXT_PREFERRED_LANGUAGES"), toml.TOML("security_txt.preferred_languages", configFile)),
+		},
 	}
 }
@@ -5,9 +5,11 @@ package config
 
 import (
 	"context"
+	"net/netip"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/urfave/cli/v3"
 )
 
@@ -105,6 +107,14 @@ func TestBuildBaseURL(t *testing.T) {
 			},
 			expected: "https://example.com",
 		},
+		{
+			name: "ACME with DNS-01 keeps configured port",
+			cfg: &Config{
+				Server: ServerConfig{Host: "example.com", Port: 8443},
+				TLS:    TLSConfig{Mode: "acme", ACMEChallenge: "dns-01"},
+			},
+			expected: "https://example.com:8443",
+		},
 		{
 			name: "localhost with auto TLS uses HTTP",
 			cfg: &Config{
@@ -139,6 +149,19 @@ func TestApplyWebAuthnDefaults(t *testing.T) {
 		assert.Equal(t, "Go Web App", cfg.WebAuthn.RPDisplayName)
 	})
 
+	t.Run("origin without path prefix", func(t *testing.T) {
+		cfg := &Config{
+			Server: ServerConfig{
+				Host:    "example.com",
+				BaseURL: "https://example.com/app",
+			},
+		}
+
+		applyWebAuthnDefaults(cfg)
+
+		assert.Equal(t, "https://example.com", cfg.WebAuthn.RPOrigin)
+	})
+
 	t.Run("does not override existing values", func(t *testing.T) {
 		cfg := &Config{
 			Server: ServerConfig{
@@ -207,6 +230,9 @@ func TestNewFromCLI(t *testing.T) {
 			assert.Equal(t, "localhost", cfg.WebAuthn.RPID)
 			assert.Equal(t, "Go Web App", cfg.WebAuthn.RPDisplayName)
 
+			// A localhost base URL may run with a random session key
+			assert.True(t, cfg.Session.AllowEphemeralKey)
+
 			return nil
 		},
 	}
@@ -229,6 +255,7 @@ func TestNewFromCLI_WithCustomValues(t *testing.T) {
 			assert.Equal(t, "https://example.com", cfg.Server.BaseURL)
 			assert.Equal(t, "debug", cfg.Log.Level)
 			assert.Equal(t, "./data/test.db", cfg.Database.DSN)
+			assert.False(t, cfg.Session.AllowEphemeralKey, "public base URLs need a session key")
 
 			return nil
 		},
@@ -246,3 +273,110 @@ func TestNewFromCLI_WithCustomValues(t *testing.T) {
 	err := app.Run(context.Background(), args)
 	assert.NoError(t, err)
 }
+
+func TestNewFromCLI_AllowEphemeralKeyOverride(t *testing.T) {
+	app := &cli.Command{
+		Name:  "test",
+		Flags: Flags(),
+		Action: func(_ context.Context, cmd *cli.Command) error {
+			assert.True(t, NewFromCLI(cmd).Session.AllowEphemeralKey)
+			return nil
+		},
+	}
+
+	args := []string{"test", "--base-url", "https://example.com", "--session-allow-ephemeral-key"}
+	assert.NoError(t, app.Run(context.Background(), args))
+}
+
+func TestBaseURLIsLocalhost(t *testing.T) {
+	assert.True(t, baseURLIsLocalhost("http://localhost:8080"))
+	assert.True(t, baseURLIsLocalhost("https://app.localhost"))
+	assert.True(t, baseURLIsLocalhost("http://127.0.0.1:8080"))
+	assert.True(t, baseURLIsLocalhost("http://[::1]:8080"))
+	assert.False(t, baseURLIsLocalhost("https://example.com"))
+	assert.False(t, baseURLIsLocalhost(""))
+}
+
+func TestTrustedProxyPrefixes(t *testing.T) {
+	cfg := ServerConfig{TrustedProxies: []string{"10.0.0.1", " 192.168.0.0/16 ", "", "fd00::/8", "10.1.2.3/8"}}
+
+	prefixes, err := cfg.TrustedProxyPrefixes()
+
+	require.NoError(t, err)
+	assert.Equal(t, []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.1/32"),
+		netip.MustParsePrefix("192.168.0.0/16"),
+		netip.MustParsePrefix("fd00::/8"),
+		netip.MustParsePrefix("10.0.0.0/8"),
+	}, prefixes)
+}
+
+func TestTrustedProxyPrefixes_Invalid(t *testing.T) {
+	cfg := ServerConfig{TrustedProxies: []string{"10.0.0.0/33"}}
+
+	_, err := cfg.TrustedProxyPrefixes()
+
+	require.Error(t, err)
+}
+
+func TestParseByteSize(t *testing.T) {
+	tests := map[string]int64{
+		"512":  512,
+		"16k":  16 << 10,
+		"16K":  16 << 10,
+		"10m":  10 << 20,
+		" 1g ": 1 << 30,
+	}
+	for input, want := range tests {
+		got, err := ParseByteSize(input)
+		require.NoError(t, err, input)
+		assert.Equal(t, want, got, input)
+	}
+
+	for _, input := range []string{"", "k", "0", "-1k", "1.5m", "10kb", "10t", "99999999999g"} {
+		_, err := ParseByteSize(input)
+		assert.Error(t, err, input)
+	}
+}
+
+func TestBodyLimitBytes(t *testing.T) {
+	cfg := ServerConfig{BodyLimits: map[string]string{"/auth": "16k", " /api/upload ": "10m"}}
+
+	limits, err := cfg.BodyLimitBytes()
+
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int64{"/auth": 16 << 10, "/api/upload": 10 << 20}, limits)
+}
+
+func TestBodyLimitBytes_Invalid(t *testing.T) {
+	for _, limits := range []map[string]string{
+		{"/auth": "16x"},
+		{"auth": "16k"},
+	} {
+		cfg := ServerConfig{BodyLimits: limits}
+
+		_, err := cfg.BodyLimitBytes()
+
+		require.Error(t, err)
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	cfg := &Config{
+		Session: SessionConfig{HashKey: "hash", URLSigningKey: "url"},
+		SMTP:    SMTPConfig{Host: "smtp.example.com", Password: "pw", FromNames: map[string]string{"de": "App"}},
+	}
+
+	out := cfg.Redacted()
+	out.SMTP.FromNames["en"] = "App"
+
+	assert.Equal(t, Redacted, out.Session.HashKey)
+	assert.Equal(t, Redacted, out.Session.URLSigningKey)
+	assert.Empty(t, out.Session.BlockKey, "unset secrets stay empty")
+	assert.Equal(t, Redacted, out.SMTP.Password)
+	assert.Equal(t, "smtp.example.com", out.SMTP.Host)
+
+	assert.Equal(t, "hash", cfg.Session.HashKey, "original is untouched")
+	assert.Equal(t, "pw", cfg.SMTP.Password)
+	assert.NotContains(t, cfg.SMTP.FromNames, "en")
+}
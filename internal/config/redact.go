@@ -0,0 +1,47 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package config
+
+import "maps"
+
+// Redacted is the placeholder that replaces secrets in Config.Redacted.
+const Redacted = "[redacted]"
+
+// Redacted returns a copy of the configuration with secrets (session keys,
+// SMTP password, webhook secret) replaced by a placeholder, safe to print or
+// log. Unset secrets stay empty so their absence remains visible.
+func (c *Config) Redacted() *Config {
+	out := *c
+	out.Server.TrustedProxies = append([]string(nil), c.Server.TrustedProxies...)
+	out.Server.RequestTimeoutExclude = append([]string(nil), c.Server.RequestTimeoutExclude...)
+	out.Server.MaxInFlightExclude = append([]string(nil), c.Server.MaxInFlightExclude...)
+	out.TLS.ExtraSANs = append([]string(nil), c.TLS.ExtraSANs...)
+	out.TLS.AllowedHosts = append([]string(nil), c.TLS.AllowedHosts...)
+	out.CSRF.ExemptPrefixes = append([]string(nil), c.CSRF.ExemptPrefixes...)
+	out.SMTP.FromNames = maps.Clone(c.SMTP.FromNames)
+
+	redact(&out.Session.HashKey)
+	redact(&out.Session.BlockKey)
+	redact(&out.Session.URLSigningKey)
+	out.Session.PreviousHashKeys = redactAll(c.Session.PreviousHashKeys)
+	out.Session.PreviousBlockKeys = redactAll(c.Session.PreviousBlockKeys)
+	redact(&out.SMTP.Password)
+	redact(&out.Webhook.Secret)
+	return &out
+}
+
+func redact(s *string) {
+	if *s != "" {
+		*s = Redacted
+	}
+}
+
+func redactAll(keys []string) []string {
+	out := make([]string, len(keys))
+	for i := range keys {
+		out[i] = keys[i]
+		redact(&out[i])
+	}
+	return out
+}
@@ -0,0 +1,300 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/oliverandrich/go-webapp-template/internal/i18n"
+)
+
+// hstsPreloadMinAge is the smallest max-age the browser preload lists accept.
+const hstsPreloadMinAge = 31536000
+
+var (
+	validLogLevels       = []string{"debug", "info", "warn", "error"}
+	validLogFormats      = []string{"text", "json"}
+	validTLSModes        = []string{"", "auto", "acme", "selfsigned", "manual", "off"}
+	validACMEChallenges  = []string{"", "http-01", "dns-01"}
+	validDNSProviders    = []string{"exec"}
+	validSameSite        = []string{"", "lax", "strict", "none"}
+	validContactSchemes  = []string{"mailto", "https", "tel"}
+	validSessionBackends = []string{"", "cookie", "database"}
+
+	validUserVerification         = []string{"", "required", "preferred", "discouraged"}
+	validAuthenticatorAttachments = []string{"", "platform", "cross-platform", "any"}
+)
+
+// Validate checks cross-field constraints that would otherwise only surface
+// as runtime failures. All problems are reported at once, one per line.
+func (c *Config) Validate() error {
+	var errs []error
+	add := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	// Server
+	if c.Server.Socket == "" && !strings.HasPrefix(c.Server.Host, "unix:") {
+		if c.Server.Port < 1 || c.Server.Port > 65535 {
+			add("server.port must be between 1 and 65535, got %d", c.Server.Port)
+		}
+	}
+	if c.Server.MaxBodySize <= 0 {
+		add("server.max_body_size must be positive, got %d", c.Server.MaxBodySize)
+	}
+	if c.Server.AuthBodySize < 0 {
+		add("server.auth_body_size must not be negative, got %d", c.Server.AuthBodySize)
+	}
+	if c.Server.GzipLevel < -1 || c.Server.GzipLevel > 9 {
+		add("server.gzip_level must be between -1 and 9, got %d", c.Server.GzipLevel)
+	}
+	if c.Server.GzipMinSize < 0 {
+		add("server.gzip_min_size must not be negative, got %d", c.Server.GzipMinSize)
+	}
+	if c.Server.IconMaxAge < 0 {
+		add("server.icon_max_age must not be negative, got %d", c.Server.IconMaxAge)
+	}
+	if c.Server.RequestTimeout < 0 {
+		add("server.request_timeout must not be negative, got %d", c.Server.RequestTimeout)
+	}
+	if c.Server.MaxInFlight < 0 {
+		add("server.max_in_flight must not be negative, got %d", c.Server.MaxInFlight)
+	}
+	if p := c.Server.PathPrefix; p != "" && !validPathPrefix(p) {
+		add("server.path_prefix must start with / and not end with /, e.g. /app, got %q", p)
+	}
+	if _, err := c.Server.TrustedProxyPrefixes(); err != nil {
+		add("server.trusted_proxies: %v", err)
+	}
+	if _, err := c.Server.BodyLimitBytes(); err != nil {
+		add("server.body_limits: %v", err)
+	}
+
+	// Logging
+	if !slices.Contains(validLogLevels, c.Log.Level) {
+		add("log.level must be one of %s, got %q", strings.Join(validLogLevels, ", "), c.Log.Level)
+	}
+	if !slices.Contains(validLogFormats, c.Log.Format) {
+		add("log.format must be one of %s, got %q", strings.Join(validLogFormats, ", "), c.Log.Format)
+	}
+	if c.Log.SampleRate < 0 {
+		add("log.sample_rate must not be negative, got %d", c.Log.SampleRate)
+	}
+	if c.Log.SlowThreshold < 0 {
+		add("log.slow_threshold must not be negative, got %d", c.Log.SlowThreshold)
+	}
+
+	// Database
+	if c.Database.CheckpointInterval < 0 {
+		add("database.checkpoint_interval must not be negative, got %d", c.Database.CheckpointInterval)
+	}
+	if c.Database.SlowQueryThreshold < 0 {
+		add("database.slow_query_threshold must not be negative, got %d", c.Database.SlowQueryThreshold)
+	}
+
+	// TLS
+	mode := strings.ToLower(c.TLS.Mode)
+	switch {
+	case !slices.Contains(validTLSModes, mode):
+		add("tls.mode must be one of auto, acme, selfsigned, manual, off, got %q", c.TLS.Mode)
+	case mode == "acme" && c.TLS.Email == "":
+		add("tls.email is required when tls.mode is acme")
+	case mode == "manual" && (c.TLS.CertFile == "" || c.TLS.KeyFile == ""):
+		add("tls.cert_file and tls.key_file are required when tls.mode is manual")
+	}
+	if c.TLS.ACMEDirectoryURL != "" {
+		if u, err := url.Parse(c.TLS.ACMEDirectoryURL); err != nil || u.Scheme != "https" || u.Host == "" {
+			add("tls.acme_directory_url must be an https URL, got %q", c.TLS.ACMEDirectoryURL)
+		}
+	}
+	for _, host := range c.TLS.AllowedHosts {
+		if !validAllowedHost(host) {
+			add("tls.allowed_hosts entries must be host names or *.<domain> patterns below a registrable domain, got %q", host)
+		}
+	}
+	switch challenge := strings.ToLower(c.TLS.ACMEChallenge); {
+	case !slices.Contains(validACMEChallenges, challenge):
+		add("tls.acme_challenge must be one of http-01, dns-01, got %q", c.TLS.ACMEChallenge)
+	case challenge == "dns-01" && !slices.Contains(validDNSProviders, strings.ToLower(c.TLS.ACMEDNSProvider)):
+		add("tls.acme_dns_provider must be one of %s, got %q", strings.Join(validDNSProviders, ", "), c.TLS.ACMEDNSProvider)
+	case challenge == "dns-01" && c.TLS.ACMEDNSExec == "":
+		add("tls.acme_dns_exec is required when tls.acme_challenge is dns-01")
+	}
+	switch {
+	case c.TLS.HSTSMaxAge < 0:
+		add("tls.hsts_max_age must not be negative, got %d", c.TLS.HSTSMaxAge)
+	case c.TLS.HSTSPreload && (c.TLS.HSTSMaxAge < hstsPreloadMinAge || !c.TLS.HSTSIncludeSubdomains):
+		add("tls.hsts_preload requires tls.hsts_max_age of at least %d and tls.hsts_include_subdomains", hstsPreloadMinAge)
+	}
+
+	// WebAuthn
+	if c.WebAuthn.MaxCredentialsPerUser < 0 {
+		add("webauthn.max_credentials must not be negative, got %d", c.WebAuthn.MaxCredentialsPerUser)
+	}
+	if c.WebAuthn.Timeout < 0 {
+		add("webauthn.timeout must not be negative, got %d", c.WebAuthn.Timeout)
+	}
+	if !slices.Contains(validUserVerification, c.WebAuthn.UserVerification) {
+		add("webauthn.user_verification must be one of required, preferred, discouraged, got %q", c.WebAuthn.UserVerification)
+	}
+	if !slices.Contains(validAuthenticatorAttachments, c.WebAuthn.AuthenticatorAttachment) {
+		add("webauthn.authenticator_attachment must be one of platform, cross-platform, any, got %q", c.WebAuthn.AuthenticatorAttachment)
+	}
+
+	// Session
+	if !slices.Contains(validSessionBackends, c.Session.Backend) {
+		add("session.backend must be one of cookie, database, got %q", c.Session.Backend)
+	}
+	if c.Session.MaxAge <= 0 {
+		add("session.max_age must be positive, got %d", c.Session.MaxAge)
+	}
+	if c.Session.RememberMeMaxAge < 0 {
+		add("session.remember_me_max_age must not be negative, got %d", c.Session.RememberMeMaxAge)
+	}
+	if len(c.Session.PreviousBlockKeys) > len(c.Session.PreviousHashKeys) {
+		add("session.previous_block_keys must not have more entries than session.previous_hash_keys")
+	}
+
+	// Lockout
+	if c.Auth.LockoutThreshold < 0 {
+		add("auth.lockout_threshold must not be negative, got %d", c.Auth.LockoutThreshold)
+	}
+	if c.Auth.LockoutThreshold > 0 && c.Auth.LockoutWindow <= 0 {
+		add("auth.lockout_window must be positive when auth.lockout_threshold is set, got %d", c.Auth.LockoutWindow)
+	}
+	if c.Auth.RegistrationLimit < 0 {
+		add("auth.registration_limit must not be negative, got %d", c.Auth.RegistrationLimit)
+	}
+	if c.Auth.UsernameMaxLength < 0 || c.Auth.UsernameMaxLength > 64 {
+		add("auth.username_max_length must be between 1 and 64, got %d", c.Auth.UsernameMaxLength)
+	}
+	if c.Auth.MagicLink && !c.Auth.UseEmail {
+		add("auth.magic_link requires auth.use_email")
+	}
+	if c.Auth.MagicLink && c.Auth.MagicLinkTTL <= 0 {
+		add("auth.magic_link_ttl must be positive when auth.magic_link is set, got %d", c.Auth.MagicLinkTTL)
+	}
+	if c.Auth.EnumerationSafe && !c.Auth.UseEmail {
+		add("auth.enumeration_safe requires auth.use_email")
+	}
+	// Without verification a real sign-up is signed in right away, which the
+	// decoy for a taken address cannot imitate
+	if c.Auth.EnumerationSafe && !c.Auth.RequireVerification {
+		add("auth.enumeration_safe requires auth.require_verification")
+	}
+	if c.Auth.AccountNoticeLimit < 0 {
+		add("auth.account_notice_limit must not be negative, got %d", c.Auth.AccountNoticeLimit)
+	}
+	if c.Auth.MagicLinkLimit < 0 {
+		add("auth.magic_link_limit must not be negative, got %d", c.Auth.MagicLinkLimit)
+	}
+	if c.Auth.MagicLinkIPLimit < 0 {
+		add("auth.magic_link_ip_limit must not be negative, got %d", c.Auth.MagicLinkIPLimit)
+	}
+	if c.Auth.ChangePasswordURL != "" && !validRedirectTarget(c.Auth.ChangePasswordURL) {
+		add("auth.change_password_url must be an absolute path or http(s) URL, got %q", c.Auth.ChangePasswordURL)
+	}
+
+	// Registration mode: email mode needs a working mail setup
+	if c.Auth.UseEmail {
+		if c.SMTP.Host == "" {
+			add("smtp.host is required when auth.use_email is enabled")
+		}
+		if c.SMTP.From == "" {
+			add("smtp.from is required when auth.use_email is enabled")
+		}
+		if c.SMTP.Port < 1 || c.SMTP.Port > 65535 {
+			add("smtp.port must be between 1 and 65535, got %d", c.SMTP.Port)
+		}
+		if c.SMTP.IdleTimeout < 0 {
+			add("smtp.idle_timeout must not be negative, got %d", c.SMTP.IdleTimeout)
+		}
+		// Zero keeps the 24 hour default
+		if c.SMTP.TokenTTL != 0 && (c.SMTP.TokenTTL < 300 || c.SMTP.TokenTTL > 604800) {
+			add("smtp.token_ttl must be between 300 (5 minutes) and 604800 (7 days), got %d", c.SMTP.TokenTTL)
+		}
+	}
+
+	// CSRF
+	if !slices.Contains(validSameSite, strings.ToLower(c.CSRF.SameSite)) {
+		add("csrf.same_site must be one of lax, strict, none, got %q", c.CSRF.SameSite)
+	}
+	// i18n
+	if lang := c.I18n.DefaultLanguage; lang != "" {
+		if _, ok := i18n.ParseSupported(lang); !ok {
+			add("i18n.default_language must be a supported language (en, de), got %q", lang)
+		}
+	}
+
+	// Tracing
+	if c.Tracing.Enabled {
+		if u, err := url.Parse(c.Tracing.Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			add("tracing.endpoint must be an http(s) URL when tracing.enabled is set, got %q", c.Tracing.Endpoint)
+		}
+	}
+	if c.Tracing.SampleRate < 0 {
+		add("tracing.sample_rate must not be negative, got %d", c.Tracing.SampleRate)
+	}
+
+	for _, prefix := range c.CSRF.ExemptPrefixes {
+		if prefix = strings.TrimSpace(prefix); prefix == "" || prefix == "/" || !strings.HasPrefix(prefix, "/") {
+			add("csrf.exempt_prefixes must be paths below /, got %q", prefix)
+		}
+	}
+
+	// security.txt
+	for _, contact := range c.SecurityTxt.Contact {
+		if u, err := url.Parse(contact); err != nil || !slices.Contains(validContactSchemes, u.Scheme) {
+			add("security_txt.contact must be a mailto:, https: or tel: URI, got %q", contact)
+		}
+	}
+	if c.SecurityTxt.Expires != "" {
+		if _, err := time.Parse(time.RFC3339, c.SecurityTxt.Expires); err != nil {
+			add("security_txt.expires must be an RFC 3339 time, got %q", c.SecurityTxt.Expires)
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("invalid configuration:\n%w", errors.Join(errs...))
+}
+
+// validAllowedHost reports whether host is a DNS name or a "*.<domain>"
+// pattern. Patterns need at least two labels after the wildcard, so "*.com"
+// cannot turn the server into a certificate issuer for a whole TLD.
+func validAllowedHost(host string) bool {
+	name, isPattern := strings.CutPrefix(strings.TrimSpace(host), "*.")
+	if name == "" || strings.Contains(name, "*") || net.ParseIP(name) != nil {
+		return false
+	}
+	labels := strings.Split(name, ".")
+	if isPattern && len(labels) < 2 {
+		return false
+	}
+	return !slices.Contains(labels, "")
+}
+
+// validRedirectTarget reports whether target is a local absolute path or an
+// absolute http(s) URL. Protocol-relative "//host" paths are rejected.
+func validRedirectTarget(target string) bool {
+	if strings.HasPrefix(target, "/") {
+		return !strings.HasPrefix(target, "//") && !strings.HasPrefix(target, "/\\")
+	}
+	u, err := url.Parse(target)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// validPathPrefix reports whether p is a clean absolute path other than "/",
+// without trailing slash, query or fragment.
+func validPathPrefix(p string) bool {
+	return strings.HasPrefix(p, "/") && path.Clean(p) == p && p != "/" && !strings.ContainsAny(p, "?#%")
+}
@@ -0,0 +1,139 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// validConfig returns a configuration that passes Validate.
+func validConfig() *Config {
+	return &Config{
+		Server:  ServerConfig{Host: "localhost", Port: 8080, MaxBodySize: 1},
+		Log:     LogConfig{Level: "info", Format: "text"},
+		TLS:     TLSConfig{Mode: "auto"},
+		Session: SessionConfig{MaxAge: 604800},
+		CSRF:    CSRFConfig{SameSite: "lax"},
+	}
+}
+
+func TestValidate_Valid(t *testing.T) {
+	cfg := validConfig()
+	cfg.Auth.UseEmail = true
+	cfg.SMTP = SMTPConfig{Host: "smtp.example.com", Port: 587, From: "noreply@example.com"}
+	cfg.TLS.AllowedHosts = []string{"www.example.com", "*.example.com"}
+
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestValidate_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(*Config)
+		wantErr string
+	}{
+		{"log level", func(c *Config) { c.Log.Level = "verbose" }, `log.level must be one of debug, info, warn, error, got "verbose"`},
+		{"log format", func(c *Config) { c.Log.Format = "xml" }, "log.format must be one of text, json"},
+		{"log sample rate", func(c *Config) { c.Log.SampleRate = -1 }, "log.sample_rate must not be negative"},
+		{"log slow threshold", func(c *Config) { c.Log.SlowThreshold = -1 }, "log.slow_threshold must not be negative"},
+		{"slow query threshold", func(c *Config) { c.Database.SlowQueryThreshold = -1 }, "database.slow_query_threshold must not be negative"},
+		{"tls mode", func(c *Config) { c.TLS.Mode = "letsencrypt" }, "tls.mode must be one of"},
+		{"acme without email", func(c *Config) { c.TLS.Mode = "acme" }, "tls.email is required"},
+		{"manual without files", func(c *Config) { c.TLS.Mode = "manual" }, "tls.cert_file and tls.key_file are required"},
+		{"icon max age", func(c *Config) { c.Server.IconMaxAge = -1 }, "server.icon_max_age must not be negative"},
+		{"request timeout", func(c *Config) { c.Server.RequestTimeout = -1 }, "server.request_timeout must not be negative"},
+		{"max in flight", func(c *Config) { c.Server.MaxInFlight = -1 }, "server.max_in_flight must not be negative"},
+		{"path prefix trailing slash", func(c *Config) { c.Server.PathPrefix = "/app/" }, "server.path_prefix must start with /"},
+		{"path prefix relative", func(c *Config) { c.Server.PathPrefix = "app" }, "server.path_prefix must start with /"},
+		{"acme directory url", func(c *Config) { c.TLS.ACMEDirectoryURL = "http://ca.internal/directory" }, "tls.acme_directory_url must be an https URL"},
+		{"acme challenge", func(c *Config) { c.TLS.ACMEChallenge = "tls-alpn-01" }, "tls.acme_challenge must be one of"},
+		{"allowed host tld pattern", func(c *Config) { c.TLS.AllowedHosts = []string{"*.com"} }, "tls.allowed_hosts entries must be"},
+		{"allowed host bare wildcard", func(c *Config) { c.TLS.AllowedHosts = []string{"*"} }, "tls.allowed_hosts entries must be"},
+		{"allowed host ip", func(c *Config) { c.TLS.AllowedHosts = []string{"10.0.0.1"} }, "tls.allowed_hosts entries must be"},
+		{"dns provider", func(c *Config) { c.TLS.ACMEChallenge = "dns-01"; c.TLS.ACMEDNSProvider = "route53" }, "tls.acme_dns_provider must be one of"},
+		{"dns-01 without hook", func(c *Config) { c.TLS.ACMEChallenge = "dns-01"; c.TLS.ACMEDNSProvider = "exec" }, "tls.acme_dns_exec is required"},
+		{"magic link without email", func(c *Config) { c.Auth.MagicLink = true; c.Auth.MagicLinkTTL = 900 }, "auth.magic_link requires auth.use_email"},
+		{"tracing without endpoint", func(c *Config) { c.Tracing.Enabled = true }, "tracing.endpoint must be an http(s) URL"},
+		{"tracing endpoint scheme", func(c *Config) { c.Tracing.Enabled = true; c.Tracing.Endpoint = "grpc://collector:4317" }, "tracing.endpoint must be an http(s) URL"},
+		{"tracing sample rate", func(c *Config) { c.Tracing.SampleRate = -1 }, "tracing.sample_rate must not be negative"},
+		{"enumeration safe without email", func(c *Config) { c.Auth.EnumerationSafe = true }, "auth.enumeration_safe requires auth.use_email"},
+		{"enumeration safe without verification", func(c *Config) { c.Auth.EnumerationSafe = true }, "auth.enumeration_safe requires auth.require_verification"},
+		{"negative account notice limit", func(c *Config) { c.Auth.AccountNoticeLimit = -1 }, "auth.account_notice_limit must not be negative"},
+		{"magic link ttl", func(c *Config) { c.Auth.MagicLink = true }, "auth.magic_link_ttl must be positive"},
+		{"negative magic link limit", func(c *Config) { c.Auth.MagicLinkLimit = -1 }, "auth.magic_link_limit must not be negative"},
+		{"negative magic link ip limit", func(c *Config) { c.Auth.MagicLinkIPLimit = -1 }, "auth.magic_link_ip_limit must not be negative"},
+		{"security.txt contact", func(c *Config) { c.SecurityTxt.Contact = []string{"security@example.com"} }, "security_txt.contact must be a mailto:"},
+		{"security.txt expires", func(c *Config) { c.SecurityTxt.Expires = "2026-01-01" }, "security_txt.expires must be an RFC 3339 time"},
+		{"negative hsts max-age", func(c *Config) { c.TLS.HSTSMaxAge = -1 }, "tls.hsts_max_age must not be negative"},
+		{"hsts preload too short", func(c *Config) {
+			c.TLS.HSTSPreload = true
+			c.TLS.HSTSIncludeSubdomains = true
+			c.TLS.HSTSMaxAge = 86400
+		}, "tls.hsts_preload requires"},
+		{"hsts preload without subdomains", func(c *Config) { c.TLS.HSTSPreload = true; c.TLS.HSTSMaxAge = 63072000 }, "tls.hsts_preload requires"},
+		{"port too high", func(c *Config) { c.Server.Port = 70000 }, "server.port must be between 1 and 65535"},
+		{"port zero", func(c *Config) { c.Server.Port = 0 }, "server.port must be between 1 and 65535"},
+		{"auth body size", func(c *Config) { c.Server.AuthBodySize = -1 }, "server.auth_body_size must not be negative"},
+		{"body limit size", func(c *Config) { c.Server.BodyLimits = map[string]string{"/api/upload": "10x"} }, "server.body_limits: invalid body limit for /api/upload"},
+		{"body limit prefix", func(c *Config) { c.Server.BodyLimits = map[string]string{"api": "10m"} }, "server.body_limits: body limit prefix \"api\" must start with /"},
+		{"gzip level", func(c *Config) { c.Server.GzipLevel = 10 }, "server.gzip_level must be between -1 and 9"},
+		{"trusted proxies", func(c *Config) { c.Server.TrustedProxies = []string{"10.0.0.0/8", "proxy.local"} }, `server.trusted_proxies: invalid trusted proxy "proxy.local"`},
+		{"gzip min size", func(c *Config) { c.Server.GzipMinSize = -1 }, "server.gzip_min_size must not be negative"},
+		{"session backend", func(c *Config) { c.Session.Backend = "redis" }, "session.backend must be one of cookie, database"},
+		{"session max age", func(c *Config) { c.Session.MaxAge = 0 }, "session.max_age must be positive"},
+		{"previous block keys", func(c *Config) { c.Session.PreviousBlockKeys = []string{"ab"} }, "session.previous_block_keys must not have more entries"},
+		{"email without smtp", func(c *Config) { c.Auth.UseEmail = true }, "smtp.host is required"},
+		{"smtp idle timeout", func(c *Config) { c.Auth.UseEmail = true; c.SMTP.IdleTimeout = -1 }, "smtp.idle_timeout must not be negative"},
+		{"smtp token ttl too short", func(c *Config) { c.Auth.UseEmail = true; c.SMTP.TokenTTL = 60 }, "smtp.token_ttl must be between 300"},
+		{"smtp token ttl too long", func(c *Config) { c.Auth.UseEmail = true; c.SMTP.TokenTTL = 8 * 86400 }, "smtp.token_ttl must be between 300"},
+		{"webauthn timeout", func(c *Config) { c.WebAuthn.Timeout = -1 }, "webauthn.timeout must not be negative"},
+		{"webauthn user verification", func(c *Config) { c.WebAuthn.UserVerification = "always" }, "webauthn.user_verification must be one of"},
+		{"webauthn attachment", func(c *Config) { c.WebAuthn.AuthenticatorAttachment = "usb" }, "webauthn.authenticator_attachment must be one of"},
+		{"registration limit", func(c *Config) { c.Auth.RegistrationLimit = -1 }, "auth.registration_limit must not be negative"},
+		{"username max length", func(c *Config) { c.Auth.UsernameMaxLength = 65 }, "auth.username_max_length must be between 1 and 64"},
+		{"change password url scheme", func(c *Config) { c.Auth.ChangePasswordURL = "javascript:alert(1)" }, "auth.change_password_url must be"},
+		{"change password url protocol relative", func(c *Config) { c.Auth.ChangePasswordURL = "//evil.example" }, "auth.change_password_url must be"},
+		{"csrf same site", func(c *Config) { c.CSRF.SameSite = "sometimes" }, "csrf.same_site must be one of"},
+		{"default language", func(c *Config) { c.I18n.DefaultLanguage = "fr" }, "i18n.default_language must be a supported language"},
+		{"csrf exempt root", func(c *Config) { c.CSRF.ExemptPrefixes = []string{"/"} }, "csrf.exempt_prefixes must be paths below /"},
+		{"csrf exempt relative", func(c *Config) { c.CSRF.ExemptPrefixes = []string{"api/"} }, "csrf.exempt_prefixes must be paths below /"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			tt.modify(cfg)
+
+			err := cfg.Validate()
+
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestValidate_AggregatesErrors(t *testing.T) {
+	cfg := validConfig()
+	cfg.Log.Level = "verbose"
+	cfg.Session.MaxAge = -1
+	cfg.Server.Port = 0
+
+	err := cfg.Validate()
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "log.level")
+	assert.Contains(t, err.Error(), "session.max_age")
+	assert.Contains(t, err.Error(), "server.port")
+}
+
+func TestValidate_SocketIgnoresPort(t *testing.T) {
+	cfg := validConfig()
+	cfg.Server.Port = 0
+	cfg.Server.Socket = "/run/app.sock"
+
+	assert.NoError(t, cfg.Validate())
+}
@@ -0,0 +1,49 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/vinovest/sqlx"
+)
+
+// Backup writes a consistent snapshot of db to path with VACUUM INTO. It
+// runs while the database is in use; writers wait only for the copy to
+// finish. The file at path must not exist or be empty.
+func Backup(ctx context.Context, db *sqlx.DB, path string) error {
+	_, err := db.ExecContext(ctx, "VACUUM INTO ?", path)
+	return err
+}
+
+// VerifyBackup opens the database file at path read-only and checks that it
+// passes an integrity check and contains the users table.
+func VerifyBackup(ctx context.Context, path string) error {
+	conn, err := sqlx.Open("sqlite", "file:"+(&url.URL{Path: path}).EscapedPath()+"?mode=ro")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = conn.Close() }()
+
+	var result string
+	if err = conn.QueryRowContext(ctx, "PRAGMA quick_check").Scan(&result); err != nil {
+		return fmt.Errorf("integrity check failed: %w", err)
+	}
+	if result != "ok" {
+		return fmt.Errorf("integrity check failed: %s", result)
+	}
+
+	var tables int
+	err = conn.QueryRowContext(ctx, "SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = 'users'").Scan(&tables)
+	if err != nil {
+		return err
+	}
+	if tables == 0 {
+		return errors.New("users table missing")
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package database_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/oliverandrich/go-webapp-template/internal/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vinovest/sqlx"
+)
+
+func TestBackup(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	db, err := database.Open(filepath.Join(dir, "app.db"))
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+	_, err = db.ExecContext(ctx, "INSERT INTO users (username) VALUES ('alice')")
+	require.NoError(t, err)
+
+	target := filepath.Join(dir, "backup.db")
+	require.NoError(t, database.Backup(ctx, db, target))
+	require.NoError(t, database.VerifyBackup(ctx, target))
+
+	backup, err := sqlx.Open("sqlite", target)
+	require.NoError(t, err)
+	defer func() { _ = backup.Close() }()
+	var username string
+	require.NoError(t, backup.QueryRowContext(ctx, "SELECT username FROM users").Scan(&username))
+	assert.Equal(t, "alice", username)
+}
+
+func TestVerifyBackup_NoUsersTable(t *testing.T) {
+	ctx := context.Background()
+	target := filepath.Join(t.TempDir(), "other.db")
+	db, err := sqlx.Open("sqlite", target)
+	require.NoError(t, err)
+	_, err = db.ExecContext(ctx, "CREATE TABLE notes (id INTEGER PRIMARY KEY)")
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	err = database.VerifyBackup(ctx, target)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "users table missing")
+}
+
+func TestVerifyBackup_NotADatabase(t *testing.T) {
+	target := filepath.Join(t.TempDir(), "garbage.db")
+	require.NoError(t, os.WriteFile(target, []byte("definitely not sqlite, but long enough to look like a header"), 0o600))
+
+	require.Error(t, database.VerifyBackup(context.Background(), target))
+}
@@ -0,0 +1,64 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package database
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/vinovest/sqlx"
+)
+
+// CheckpointResult holds the frame counts reported by PRAGMA wal_checkpoint.
+type CheckpointResult struct {
+	Busy         int // 1 if the checkpoint could not complete because of readers/writers
+	Log          int // Frames in the WAL file
+	Checkpointed int // Frames written back to the database file
+}
+
+// Checkpoint copies the WAL into the database file and truncates the WAL.
+func Checkpoint(ctx context.Context, db *sqlx.DB) (CheckpointResult, error) {
+	var res CheckpointResult
+	err := db.QueryRowContext(ctx, "PRAGMA wal_checkpoint(TRUNCATE)").Scan(&res.Busy, &res.Log, &res.Checkpointed)
+	return res, err
+}
+
+// StartCheckpointer runs Checkpoint every interval until ctx is cancelled.
+// It does nothing for in-memory databases or a non-positive interval. The
+// returned channel is closed once the checkpointer has stopped.
+func StartCheckpointer(ctx context.Context, db *sqlx.DB, dsn string, interval time.Duration) <-chan struct{} {
+	done := make(chan struct{})
+	if interval <= 0 || IsInMemory(dsn) {
+		close(done)
+		return done
+	}
+
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				res, err := Checkpoint(ctx, db)
+				if err != nil {
+					if ctx.Err() == nil {
+						slog.Error("wal checkpoint failed", "error", err)
+					}
+					continue
+				}
+				slog.Debug("wal checkpoint",
+					"busy", res.Busy,
+					"log", res.Log,
+					"checkpointed", res.Checkpointed,
+				)
+			}
+		}
+	}()
+	return done
+}
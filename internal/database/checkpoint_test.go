@@ -0,0 +1,73 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package database_test
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/oliverandrich/go-webapp-template/internal/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckpoint_FileDatabase(t *testing.T) {
+	dsn := filepath.Join(t.TempDir(), "app.db")
+	db, err := database.Open(dsn)
+	require.NoError(t, err)
+	defer func() {
+		_ = db.Close()
+	}()
+
+	ctx := context.Background()
+	for i := range 10 {
+		_, err = db.ExecContext(ctx, "INSERT INTO users (username) VALUES (?)", fmt.Sprintf("user%d", i))
+		require.NoError(t, err)
+	}
+
+	res, err := database.Checkpoint(ctx, db)
+
+	require.NoError(t, err)
+	assert.Equal(t, 0, res.Busy)
+	assert.Equal(t, res.Log, res.Checkpointed)
+}
+
+func TestStartCheckpointer_StopsOnCancel(t *testing.T) {
+	dsn := filepath.Join(t.TempDir(), "app.db")
+	db, err := database.Open(dsn)
+	require.NoError(t, err)
+	defer func() {
+		_ = db.Close()
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := database.StartCheckpointer(ctx, db, dsn, 10*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("checkpointer did not stop after cancel")
+	}
+}
+
+func TestStartCheckpointer_InMemoryIsNoop(t *testing.T) {
+	done := database.StartCheckpointer(context.Background(), nil, ":memory:", time.Millisecond)
+
+	select {
+	case <-done:
+	default:
+		t.Fatal("checkpointer should not run for in-memory databases")
+	}
+}
+
+func TestIsInMemory(t *testing.T) {
+	assert.True(t, database.IsInMemory(":memory:"))
+	assert.True(t, database.IsInMemory("file:test?mode=memory&cache=shared"))
+	assert.False(t, database.IsInMemory("./data/app.db"))
+}
@@ -21,7 +21,7 @@ func Open(dsn string) (*sqlx.DB, error) {
 	}
 
 	// Create directory for file-based databases
-	if !strings.HasPrefix(dsn, ":memory:") && !strings.Contains(dsn, "mode=memory") {
+	if !IsInMemory(dsn) {
 		dir := filepath.Dir(dsn)
 		if err := os.MkdirAll(dir, 0o750); err != nil {
 			return nil, err
@@ -58,6 +58,11 @@ func Open(dsn string) (*sqlx.DB, error) {
 	return conn, nil
 }
 
+// IsInMemory reports whether the DSN refers to an in-memory database.
+func IsInMemory(dsn string) bool {
+	return strings.HasPrefix(dsn, ":memory:") || strings.Contains(dsn, "mode=memory")
+}
+
 // addDefaultParams adds recommended SQLite parameters if not already present.
 func addDefaultParams(dsn string) string {
 	defaults := map[string]string{
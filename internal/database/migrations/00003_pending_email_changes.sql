@@ -0,0 +1,15 @@
+-- +goose Up
+
+-- Email address changes awaiting confirmation from the new address
+CREATE TABLE pending_email_changes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    new_email TEXT NOT NULL,
+    token_hash TEXT UNIQUE NOT NULL,
+    expires_at DATETIME NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_pending_email_changes_user_id ON pending_email_changes(user_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS pending_email_changes;
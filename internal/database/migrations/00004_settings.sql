@@ -0,0 +1,11 @@
+-- +goose Up
+
+-- Runtime settings that operators can change without a redeploy
+CREATE TABLE settings (
+    key TEXT PRIMARY KEY,
+    value TEXT NOT NULL,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS settings;
@@ -0,0 +1,12 @@
+-- +goose Up
+
+-- Failed login attempts, used for temporary account lockout
+CREATE TABLE failed_logins (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at DATETIME NOT NULL
+);
+CREATE INDEX idx_failed_logins_user_id_created_at ON failed_logins(user_id, created_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS failed_logins;
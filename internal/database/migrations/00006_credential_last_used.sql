@@ -0,0 +1,7 @@
+-- +goose Up
+
+-- Time a credential was last used to sign in
+ALTER TABLE credentials ADD COLUMN last_used_at DATETIME;
+
+-- +goose Down
+ALTER TABLE credentials DROP COLUMN last_used_at;
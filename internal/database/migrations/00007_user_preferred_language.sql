@@ -0,0 +1,7 @@
+-- +goose Up
+
+-- Language chosen by the user, preferred over Accept-Language when set
+ALTER TABLE users ADD COLUMN preferred_language TEXT;
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN preferred_language;
@@ -0,0 +1,7 @@
+-- +goose Up
+
+-- Bumped to invalidate all session cookies issued for the user
+ALTER TABLE users ADD COLUMN session_version INTEGER NOT NULL DEFAULT 0;
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN session_version;
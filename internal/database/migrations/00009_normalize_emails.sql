@@ -0,0 +1,19 @@
+-- +goose Up
+
+-- Emails are stored trimmed and lowercased. In email mode the username
+-- mirrors the email and follows it. Rows whose canonical address is already
+-- taken by another user are left as they are.
+UPDATE users SET
+    username = CASE WHEN username = email THEN lower(trim(email)) ELSE username END,
+    email = lower(trim(email))
+WHERE email IS NOT NULL
+  AND email != lower(trim(email))
+  AND NOT EXISTS (
+      SELECT 1 FROM users AS other
+      WHERE other.id != users.id
+        AND (other.email = lower(trim(users.email)) OR other.username = lower(trim(users.email)))
+  );
+
+-- +goose Down
+-- The original spelling is not kept, so there is nothing to restore.
+SELECT 1;
@@ -0,0 +1,15 @@
+-- +goose Up
+
+-- Security-relevant actions, e.g. an administrator impersonating a user.
+-- Events outlive the users involved.
+CREATE TABLE audit_events (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    actor_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    action TEXT NOT NULL,
+    target_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at DATETIME NOT NULL
+);
+CREATE INDEX idx_audit_events_created_at ON audit_events(created_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS audit_events;
@@ -0,0 +1,7 @@
+-- +goose Up
+
+-- Time of the user's last successful sign-in
+ALTER TABLE users ADD COLUMN last_login_at DATETIME;
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN last_login_at;
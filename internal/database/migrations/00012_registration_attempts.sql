@@ -0,0 +1,13 @@
+-- +goose Up
+
+-- Registrations started per client IP and UTC day, for the daily limit.
+-- Rows of past days are pruned when the first attempt of a new day is counted.
+CREATE TABLE registration_attempts (
+    ip TEXT NOT NULL,
+    day TEXT NOT NULL,
+    count INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (ip, day)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS registration_attempts;
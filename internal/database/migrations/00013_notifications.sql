@@ -0,0 +1,15 @@
+-- +goose Up
+
+-- In-app notifications; payload holds type-specific JSON
+CREATE TABLE notifications (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    type TEXT NOT NULL,
+    payload TEXT NOT NULL DEFAULT '{}',
+    read_at DATETIME,
+    created_at DATETIME NOT NULL
+);
+CREATE INDEX idx_notifications_user_unread ON notifications(user_id, read_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS notifications;
@@ -0,0 +1,8 @@
+-- +goose Up
+
+-- Soft-deleted users keep their row for audit history. Their username and
+-- email get a ":deleted:<id>" suffix, freeing the identifiers for new sign-ups.
+ALTER TABLE users ADD COLUMN deleted_at DATETIME;
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN deleted_at;
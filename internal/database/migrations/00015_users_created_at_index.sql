@@ -0,0 +1,7 @@
+-- +goose Up
+
+-- Keyset pagination walks users by (created_at, id)
+CREATE INDEX idx_users_created_at_id ON users(created_at, id);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_users_created_at_id;
@@ -0,0 +1,14 @@
+-- +goose Up
+
+-- Single-use tokens of email sign-in links; created_at drives the rate limit
+CREATE TABLE magic_link_tokens (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash TEXT UNIQUE NOT NULL,
+    expires_at DATETIME NOT NULL,
+    created_at DATETIME NOT NULL
+);
+CREATE INDEX idx_magic_link_tokens_user_id_created_at ON magic_link_tokens(user_id, created_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS magic_link_tokens;
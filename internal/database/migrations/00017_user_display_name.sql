@@ -0,0 +1,8 @@
+-- +goose Up
+
+-- Name shown in passkey prompts; defaults to the username
+ALTER TABLE users ADD COLUMN display_name TEXT NOT NULL DEFAULT '';
+UPDATE users SET display_name = username;
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN display_name;
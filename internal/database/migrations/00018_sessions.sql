@@ -0,0 +1,16 @@
+-- +goose Up
+
+-- Server-side sessions (session.backend = "database"); the cookie carries a
+-- random token whose hash is the id
+CREATE TABLE sessions (
+    id TEXT PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    data TEXT NOT NULL,
+    expires_at DATETIME NOT NULL,
+    created_at DATETIME NOT NULL
+);
+CREATE INDEX idx_sessions_user_id ON sessions(user_id);
+CREATE INDEX idx_sessions_expires_at ON sessions(expires_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS sessions;
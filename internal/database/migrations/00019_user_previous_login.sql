@@ -0,0 +1,7 @@
+-- +goose Up
+
+-- Sign-in before the last one, shown on the dashboard after signing in
+ALTER TABLE users ADD COLUMN previous_login_at DATETIME;
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN previous_login_at;
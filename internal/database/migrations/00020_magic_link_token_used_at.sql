@@ -0,0 +1,7 @@
+-- +goose Up
+
+-- Used sign-in links are kept until they expire, so the hourly limit counts them
+ALTER TABLE magic_link_tokens ADD COLUMN used_at DATETIME;
+
+-- +goose Down
+ALTER TABLE magic_link_tokens DROP COLUMN used_at;
@@ -0,0 +1,12 @@
+-- +goose Up
+
+-- Failed logins are counted per client, so attempts from one network cannot
+-- lock the account owner out everywhere
+ALTER TABLE failed_logins ADD COLUMN client TEXT NOT NULL DEFAULT '';
+DROP INDEX idx_failed_logins_user_id_created_at;
+CREATE INDEX idx_failed_logins_user_id_client_created_at ON failed_logins(user_id, client, created_at);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_failed_logins_user_id_client_created_at;
+ALTER TABLE failed_logins DROP COLUMN client;
+CREATE INDEX idx_failed_logins_user_id_created_at ON failed_logins(user_id, created_at);
@@ -0,0 +1,10 @@
+-- +goose Up
+
+-- Impersonation sessions are stored under the impersonated user; the
+-- administrator is kept as well, so invalidating their sessions ends them
+ALTER TABLE sessions ADD COLUMN impersonator_id INTEGER REFERENCES users(id) ON DELETE CASCADE;
+CREATE INDEX idx_sessions_impersonator_id ON sessions(impersonator_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_sessions_impersonator_id;
+ALTER TABLE sessions DROP COLUMN impersonator_id;
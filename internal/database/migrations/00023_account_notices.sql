@@ -0,0 +1,13 @@
+-- +goose Up
+
+-- "Account exists" emails sent for registrations of taken addresses
+-- (auth.enumeration_safe); created_at drives the rate limit
+CREATE TABLE account_notices (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at DATETIME NOT NULL
+);
+CREATE INDEX idx_account_notices_user_id_created_at ON account_notices(user_id, created_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS account_notices;
@@ -0,0 +1,13 @@
+-- +goose Up
+
+-- Sign-in link requests per client (IP or IPv6 /64), whether or not the
+-- address has an account; created_at drives the per-client rate limit
+CREATE TABLE magic_link_requests (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    client TEXT NOT NULL,
+    created_at DATETIME NOT NULL
+);
+CREATE INDEX idx_magic_link_requests_client_created_at ON magic_link_requests(client, created_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS magic_link_requests;
@@ -0,0 +1,300 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package handlers
+
+import (
+	"cmp"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/oliverandrich/go-webapp-template/internal/appcontext"
+	"github.com/oliverandrich/go-webapp-template/internal/models"
+	"github.com/oliverandrich/go-webapp-template/internal/pagination"
+	"github.com/oliverandrich/go-webapp-template/internal/repository"
+	"github.com/oliverandrich/go-webapp-template/internal/services/recovery"
+	"github.com/oliverandrich/go-webapp-template/internal/services/session"
+	"github.com/oliverandrich/go-webapp-template/internal/services/settings"
+)
+
+// AdminHandlers contains handlers for administrator-only endpoints.
+// Routes must be protected with RequireAuth and RequireAdmin, except
+// StopImpersonation, which runs as the impersonated user.
+type AdminHandlers struct {
+	settings *settings.Service
+	repo     *repository.Repository
+	sessions *session.Manager
+	store    session.Store
+	recovery *recovery.Service
+	cursors  *pagination.Codec // nil without a session manager
+}
+
+// NewAdmin creates a new AdminHandlers instance.
+func NewAdmin(s *settings.Service, repo *repository.Repository, sessions *session.Manager) *AdminHandlers {
+	h := &AdminHandlers{
+		settings: s,
+		repo:     repo,
+		sessions: sessions,
+		store:    session.NewCookieStore(sessions, repo),
+		recovery: recovery.NewService(),
+	}
+	if sessions != nil {
+		h.cursors = pagination.New(sessions.DeriveKey("pagination"))
+	}
+	return h
+}
+
+// SetSessionStore replaces the cookie-backed session store, e.g. with a
+// session.DBStore.
+func (h *AdminHandlers) SetSessionStore(s session.Store) {
+	h.store = s
+}
+
+// RegistrationRequest is the request body for changing the registration mode.
+type RegistrationRequest struct {
+	Mode string `json:"mode" form:"mode"`
+}
+
+// SetRegistration opens or closes registration at runtime.
+func (h *AdminHandlers) SetRegistration(c echo.Context) error {
+	var req RegistrationRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid request"})
+	}
+
+	if err := h.settings.SetRegistrationMode(c.Request().Context(), req.Mode); err != nil {
+		if errors.Is(err, settings.ErrInvalidRegistrationMode) {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "mode must be open or closed"})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to update registration mode"})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{"registration": h.settings.RegistrationMode()})
+}
+
+// MaintenanceRequest is the request body for switching maintenance mode.
+type MaintenanceRequest struct {
+	Enabled bool `json:"enabled" form:"enabled"`
+}
+
+// SetMaintenance switches maintenance mode on or off at runtime.
+func (h *AdminHandlers) SetMaintenance(c echo.Context) error {
+	var req MaintenanceRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid request"})
+	}
+
+	if err := h.settings.SetMaintenance(c.Request().Context(), req.Enabled); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to update maintenance mode"})
+	}
+
+	return c.JSON(http.StatusOK, map[string]bool{"maintenance": h.settings.MaintenanceEnabled()})
+}
+
+// UserListResponse is the response body of ListUsers.
+type UserListResponse struct {
+	Users []models.User `json:"users"`
+	Next  string        `json:"next,omitempty"` // ?after= cursor of the following page, empty on the last one
+}
+
+// ListUsers returns the users whose username or email starts with the ?q=
+// query parameter. Without a query all users are listed newest first, one
+// page at a time: the response carries the ?after= cursor of the next page.
+// ?limit= caps the result size, bounded by repository.MaxUserSearchLimit.
+func (h *AdminHandlers) ListUsers(c echo.Context) error {
+	limit := 0
+	if raw := c.QueryParam("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid limit"})
+		}
+		limit = n
+	}
+
+	query := c.QueryParam("q")
+	if query == "" && h.cursors != nil {
+		return h.listUserPage(c, limit)
+	}
+
+	users, err := h.repo.SearchUsers(c.Request().Context(), query, limit)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "database error"})
+	}
+	if users == nil {
+		users = []models.User{}
+	}
+	return c.JSON(http.StatusOK, UserListResponse{Users: users})
+}
+
+// listUserPage answers ListUsers without a query with the page following
+// the ?after= cursor, or the first page.
+func (h *AdminHandlers) listUserPage(c echo.Context, limit int) error {
+	var after *pagination.Cursor
+	if raw := c.QueryParam("after"); raw != "" {
+		cursor, err := h.cursors.Decode(raw)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid cursor"})
+		}
+		after = &cursor
+	}
+
+	users, next, err := h.repo.ListUsersAfter(c.Request().Context(), after, limit)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "database error"})
+	}
+	if users == nil {
+		users = []models.User{}
+	}
+	resp := UserListResponse{Users: users}
+	if next != nil {
+		resp.Next = h.cursors.Encode(*next)
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// LogoutAll signs the user in the :id path parameter out of every session,
+// e.g. after an account compromise. The action is written to the audit log.
+func (h *AdminHandlers) LogoutAll(c echo.Context) error {
+	cc, ok := c.(*appcontext.Context)
+	if !ok || !cc.IsAuthenticated() {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "not authenticated"})
+	}
+	admin := cc.GetUser()
+
+	targetID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid user id"})
+	}
+
+	ctx := c.Request().Context()
+	if _, err := h.repo.GetUserByID(ctx, targetID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "user not found"})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "database error"})
+	}
+
+	if err := h.store.Invalidate(ctx, targetID); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "database error"})
+	}
+	if err := h.repo.CreateAuditEvent(ctx, admin.ID, models.AuditSessionsRevoked, targetID); err != nil {
+		slog.Error("failed to record audit event", "error", err)
+	}
+
+	slog.Warn("sessions revoked", "admin_id", admin.ID, "user_id", targetID)
+
+	return c.JSON(http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// ResetRecoveryCodes replaces the recovery codes of the user in the :id path
+// parameter, e.g. for a user who lost their passkeys and codes. The new codes
+// are returned once, as a RecoveryCodesResponse or, with format=txt, as a
+// plain text attachment; only their hashes are stored. The action is written
+// to the audit log.
+func (h *AdminHandlers) ResetRecoveryCodes(c echo.Context) error {
+	cc, ok := c.(*appcontext.Context)
+	if !ok || !cc.IsAuthenticated() {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "not authenticated"})
+	}
+	admin := cc.GetUser()
+
+	format := c.FormValue("format")
+	if format != "" && format != "txt" && format != "json" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "format must be txt or json"})
+	}
+
+	targetID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid user id"})
+	}
+
+	ctx := c.Request().Context()
+	target, err := h.repo.GetUserByID(ctx, targetID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "user not found"})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "database error"})
+	}
+
+	codes, hashes, err := h.recovery.GenerateCodes(recovery.CodeCount)
+	if err != nil {
+		slog.Error("failed to generate recovery codes", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to generate codes"})
+	}
+
+	// Replace the codes and record the reset atomically, so a failure never
+	// leaves the user without codes
+	var hadCodes bool
+	err = h.repo.WithTx(ctx, func(tx *repository.Repository) error {
+		var txErr error
+		if hadCodes, txErr = tx.HasRecoveryCodes(ctx, target.ID); txErr != nil {
+			return txErr
+		}
+		if txErr = tx.DeleteRecoveryCodes(ctx, target.ID); txErr != nil {
+			return txErr
+		}
+		if txErr = tx.CreateRecoveryCodes(ctx, target.ID, hashes); txErr != nil {
+			return txErr
+		}
+		return tx.CreateAuditEvent(ctx, admin.ID, models.AuditRecoveryCodesReset, target.ID)
+	})
+	if err != nil {
+		slog.Error("failed to reset recovery codes", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to store codes"})
+	}
+
+	slog.Warn("recovery codes reset", "admin_id", admin.ID, "user_id", target.ID)
+
+	c.Response().Header().Set("Cache-Control", "no-store")
+
+	if format == "txt" {
+		body := strings.NewReader(strings.Join(codes, "\n") + "\n")
+		return ServeAttachment(c, "recovery-codes-"+target.Username+".txt", echo.MIMETextPlainCharsetUTF8, body)
+	}
+
+	return c.JSON(http.StatusOK, RecoveryCodesResponse{
+		Codes:               codes,
+		Count:               len(codes),
+		GeneratedAt:         time.Now().UTC(),
+		PreviousInvalidated: hadCodes,
+	})
+}
+
+// AuthenticatorStat is the number of credentials of one authenticator model.
+type AuthenticatorStat struct {
+	AAGUID string `json:"aaguid"`
+	Name   string `json:"name,omitempty"`
+	Count  int64  `json:"count"`
+}
+
+// AuthenticatorStatsResponse is the response body of AuthenticatorStats.
+type AuthenticatorStatsResponse struct {
+	Authenticators []AuthenticatorStat `json:"authenticators"`
+}
+
+// AuthenticatorStats reports how many registered passkeys each authenticator
+// model accounts for, most common first. Credentials without an AAGUID are
+// reported as repository.UnknownAAGUID.
+func (h *AdminHandlers) AuthenticatorStats(c echo.Context) error {
+	counts, err := h.repo.CountCredentialsByAAGUID(c.Request().Context())
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "database error"})
+	}
+
+	stats := make([]AuthenticatorStat, 0, len(counts))
+	for aaguid, n := range counts {
+		stats = append(stats, AuthenticatorStat{AAGUID: aaguid, Name: models.AuthenticatorNameFromHex(aaguid), Count: n})
+	}
+	slices.SortFunc(stats, func(a, b AuthenticatorStat) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.AAGUID, b.AAGUID))
+	})
+	return c.JSON(http.StatusOK, AuthenticatorStatsResponse{Authenticators: stats})
+}
@@ -0,0 +1,382 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/oliverandrich/go-webapp-template/internal/handlers"
+	"github.com/oliverandrich/go-webapp-template/internal/models"
+	"github.com/oliverandrich/go-webapp-template/internal/repository"
+	"github.com/oliverandrich/go-webapp-template/internal/services/recovery"
+	"github.com/oliverandrich/go-webapp-template/internal/services/settings"
+	"github.com/oliverandrich/go-webapp-template/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setRegistration posts a mode to the admin handler and returns the recorder.
+func setRegistration(t *testing.T, h *handlers.AdminHandlers, mode string) *httptest.ResponseRecorder {
+	t.Helper()
+	e := echo.New()
+	body := strings.NewReader(`{"mode":"` + mode + `"}`)
+	req := httptest.NewRequest(http.MethodPost, "/admin/settings/registration", body)
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+
+	require.NoError(t, h.SetRegistration(e.NewContext(req, rec)))
+	return rec
+}
+
+// registerBegin starts a username registration and returns the recorder.
+func registerBegin(t *testing.T, h *handlers.AuthHandlers, username string) *httptest.ResponseRecorder {
+	t.Helper()
+	e := echo.New()
+	body := strings.NewReader(`{"username":"` + username + `"}`)
+	req := httptest.NewRequest(http.MethodPost, "/auth/register/begin", body)
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+
+	require.NoError(t, h.RegisterBegin(e.NewContext(req, rec)))
+	return rec
+}
+
+func newTestSettings(t *testing.T, repo *repository.Repository) *settings.Service {
+	t.Helper()
+	svc, err := settings.NewService(context.Background(), repo)
+	require.NoError(t, err)
+	return svc
+}
+
+func TestSetRegistration_TogglesRegisterBegin(t *testing.T) {
+	auth, repo := newTestAuthHandlers(t)
+	svc := newTestSettings(t, repo)
+	auth.SetSettings(svc)
+	admin := handlers.NewAdmin(svc, repo, nil)
+
+	assert.Equal(t, http.StatusOK, registerBegin(t, auth, "before").Code)
+
+	rec := setRegistration(t, admin, settings.RegistrationClosed)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"registration":"closed"`)
+	assert.False(t, auth.IsRegistrationEnabled())
+
+	rec = registerBegin(t, auth, "during")
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Registration is currently closed")
+
+	rec = setRegistration(t, admin, settings.RegistrationOpen)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, http.StatusOK, registerBegin(t, auth, "after").Code)
+}
+
+func TestSetRegistration_InvalidMode(t *testing.T) {
+	_, repo := newTestAuthHandlers(t)
+	svc := newTestSettings(t, repo)
+	admin := handlers.NewAdmin(svc, repo, nil)
+
+	rec := setRegistration(t, admin, "sometimes")
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, settings.RegistrationOpen, svc.RegistrationMode())
+}
+
+func TestRegisterPage_RegistrationClosed(t *testing.T) {
+	auth, repo := newTestAuthHandlers(t)
+	svc := newTestSettings(t, repo)
+	require.NoError(t, svc.SetRegistrationMode(context.Background(), settings.RegistrationClosed))
+	auth.SetSettings(svc)
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/auth/register", nil)
+	rec := httptest.NewRecorder()
+
+	err := auth.RegisterPage(e.NewContext(req, rec))
+
+	var he *echo.HTTPError
+	require.ErrorAs(t, err, &he)
+	assert.Equal(t, http.StatusForbidden, he.Code)
+}
+
+func TestRegisterFinish_RegistrationClosed(t *testing.T) {
+	auth, repo := newTestAuthHandlers(t)
+	svc := newTestSettings(t, repo)
+	auth.SetSettings(svc)
+
+	// A ceremony started while registration was open cannot be finished
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/auth/register/begin", strings.NewReader(`{"username":"alice"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	require.NoError(t, auth.RegisterBegin(e.NewContext(req, rec)))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var begin struct {
+		UserID int64 `json:"user_id"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &begin))
+
+	require.NoError(t, svc.SetRegistrationMode(context.Background(), settings.RegistrationClosed))
+
+	req = httptest.NewRequest(http.MethodPost, "/auth/register/begin", strings.NewReader(`{"username":"bob"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	beginRec := httptest.NewRecorder()
+	require.NoError(t, auth.RegisterBegin(e.NewContext(req, beginRec)))
+
+	req = httptest.NewRequest(http.MethodPost, "/auth/register/finish?user_id="+strconv.FormatInt(begin.UserID, 10), strings.NewReader("{}"))
+	finishRec := httptest.NewRecorder()
+	require.NoError(t, auth.RegisterFinish(e.NewContext(req, finishRec)))
+
+	assert.Equal(t, http.StatusForbidden, finishRec.Code)
+	assert.Equal(t, beginRec.Code, finishRec.Code)
+	assert.JSONEq(t, beginRec.Body.String(), finishRec.Body.String())
+}
+
+func TestSetMaintenance(t *testing.T) {
+	_, repo := newTestAuthHandlers(t)
+	svc := newTestSettings(t, repo)
+	admin := handlers.NewAdmin(svc, repo, nil)
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/admin/settings/maintenance", strings.NewReader(`{"enabled":true}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+
+	require.NoError(t, admin.SetMaintenance(e.NewContext(req, rec)))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"maintenance":true}`, rec.Body.String())
+	assert.True(t, svc.MaintenanceEnabled())
+}
+
+// listUsers calls the admin user search with the given query string.
+func listUsers(t *testing.T, h *handlers.AdminHandlers, query string) *httptest.ResponseRecorder {
+	t.Helper()
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/admin/users?"+query, nil)
+	rec := httptest.NewRecorder()
+
+	require.NoError(t, h.ListUsers(e.NewContext(req, rec)))
+	return rec
+}
+
+func TestListUsers_Search(t *testing.T) {
+	_, repo := newTestAuthHandlers(t)
+	admin := handlers.NewAdmin(newTestSettings(t, repo), repo, nil)
+	testutil.NewTestUser(t, repo, "alice")
+	testutil.NewTestUser(t, repo, "bob")
+
+	rec := listUsers(t, admin, "q=AL")
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	var resp handlers.UserListResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Len(t, resp.Users, 1)
+	assert.Equal(t, "alice", resp.Users[0].Username)
+}
+
+func TestListUsers_NoMatchReturnsEmptyList(t *testing.T) {
+	_, repo := newTestAuthHandlers(t)
+	admin := handlers.NewAdmin(newTestSettings(t, repo), repo, nil)
+
+	rec := listUsers(t, admin, "q=nobody")
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"users":[]}`, rec.Body.String())
+}
+
+func TestListUsers_InvalidLimit(t *testing.T) {
+	_, repo := newTestAuthHandlers(t)
+	admin := handlers.NewAdmin(newTestSettings(t, repo), repo, nil)
+
+	rec := listUsers(t, admin, "limit=-1")
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestListUsers_Pages(t *testing.T) {
+	h, repo, _ := newTestAdminHandlers(t)
+	for i := range 5 {
+		testutil.NewTestUser(t, repo, "user"+strconv.Itoa(i))
+	}
+
+	var seen []string
+	query := "limit=2"
+	for range 5 {
+		rec := listUsers(t, h, query)
+		require.Equal(t, http.StatusOK, rec.Code)
+		var resp handlers.UserListResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		for _, u := range resp.Users {
+			seen = append(seen, u.Username)
+		}
+		if resp.Next == "" {
+			break
+		}
+		query = "limit=2&after=" + resp.Next
+	}
+
+	assert.Equal(t, []string{"user4", "user3", "user2", "user1", "user0"}, seen)
+}
+
+func TestListUsers_InvalidCursor(t *testing.T) {
+	h, _, _ := newTestAdminHandlers(t)
+
+	rec := listUsers(t, h, "after=forged")
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "invalid cursor")
+}
+
+// logoutAll calls LogoutAll as admin for the given target ID.
+func logoutAll(t *testing.T, h *handlers.AdminHandlers, admin *models.User, targetID string) *httptest.ResponseRecorder {
+	t.Helper()
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/admin/users/"+targetID+"/logout-all", nil)
+	rec := httptest.NewRecorder()
+	c := newTestContext(e, req, rec, admin)
+	c.SetParamNames("id")
+	c.SetParamValues(targetID)
+
+	require.NoError(t, h.LogoutAll(c))
+	return rec
+}
+
+func TestLogoutAll(t *testing.T) {
+	h, repo, _ := newTestAdminHandlers(t)
+	admin := newTestAdmin(t, repo)
+	target := testutil.NewTestUser(t, repo, "customer")
+
+	rec := logoutAll(t, h, admin, strconv.FormatInt(target.ID, 10))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	updated, err := repo.GetUserByID(context.Background(), target.ID)
+	require.NoError(t, err)
+	assert.Equal(t, target.SessionVersion+1, updated.SessionVersion)
+
+	events, err := repo.ListAuditEvents(context.Background(), 10)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, models.AuditSessionsRevoked, events[0].Action)
+	assert.Equal(t, admin.ID, *events[0].ActorID)
+	assert.Equal(t, target.ID, *events[0].TargetID)
+}
+
+func TestLogoutAll_Rejected(t *testing.T) {
+	h, repo, _ := newTestAdminHandlers(t)
+	admin := newTestAdmin(t, repo)
+
+	assert.Equal(t, http.StatusBadRequest, logoutAll(t, h, admin, "abc").Code)
+	assert.Equal(t, http.StatusNotFound, logoutAll(t, h, admin, "999").Code)
+}
+
+// resetRecoveryCodes calls ResetRecoveryCodes as admin for the given target ID.
+func resetRecoveryCodes(t *testing.T, h *handlers.AdminHandlers, admin *models.User, targetID, query string) *httptest.ResponseRecorder {
+	t.Helper()
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/admin/users/"+targetID+"/recovery-codes?"+query, nil)
+	rec := httptest.NewRecorder()
+	c := newTestContext(e, req, rec, admin)
+	c.SetParamNames("id")
+	c.SetParamValues(targetID)
+
+	require.NoError(t, h.ResetRecoveryCodes(c))
+	return rec
+}
+
+func TestResetRecoveryCodes(t *testing.T) {
+	h, repo, _ := newTestAdminHandlers(t)
+	ctx := context.Background()
+	admin := newTestAdmin(t, repo)
+	target := testutil.NewTestUser(t, repo, "customer")
+	oldCodes, oldHashes, err := recovery.NewService().GenerateCodes(recovery.CodeCount)
+	require.NoError(t, err)
+	require.NoError(t, repo.CreateRecoveryCodes(ctx, target.ID, oldHashes))
+
+	rec := resetRecoveryCodes(t, h, admin, strconv.FormatInt(target.ID, 10), "")
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "no-store", rec.Header().Get("Cache-Control"))
+	var resp handlers.RecoveryCodesResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Len(t, resp.Codes, recovery.CodeCount)
+	assert.True(t, resp.PreviousInvalidated)
+
+	valid, err := repo.ValidateAndUseRecoveryCode(ctx, target.ID, recovery.NormalizeCode(oldCodes[0]))
+	require.NoError(t, err)
+	assert.False(t, valid, "old codes must be invalidated")
+	valid, err = repo.ValidateAndUseRecoveryCode(ctx, target.ID, recovery.NormalizeCode(resp.Codes[0]))
+	require.NoError(t, err)
+	assert.True(t, valid, "new codes must validate")
+
+	events, err := repo.ListAuditEvents(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, models.AuditRecoveryCodesReset, events[0].Action)
+	assert.Equal(t, admin.ID, *events[0].ActorID)
+	assert.Equal(t, target.ID, *events[0].TargetID)
+}
+
+func TestResetRecoveryCodes_TextAttachment(t *testing.T) {
+	h, repo, _ := newTestAdminHandlers(t)
+	admin := newTestAdmin(t, repo)
+	target := testutil.NewTestUser(t, repo, "customer")
+
+	rec := resetRecoveryCodes(t, h, admin, strconv.FormatInt(target.ID, 10), "format=txt")
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Header().Get(echo.HeaderContentDisposition), "recovery-codes-customer.txt")
+	codes := strings.Fields(rec.Body.String())
+	require.Len(t, codes, recovery.CodeCount)
+	valid, err := repo.ValidateAndUseRecoveryCode(context.Background(), target.ID, recovery.NormalizeCode(codes[0]))
+	require.NoError(t, err)
+	assert.True(t, valid)
+}
+
+func TestResetRecoveryCodes_Rejected(t *testing.T) {
+	h, repo, _ := newTestAdminHandlers(t)
+	admin := newTestAdmin(t, repo)
+	target := strconv.FormatInt(testutil.NewTestUser(t, repo, "customer").ID, 10)
+
+	assert.Equal(t, http.StatusBadRequest, resetRecoveryCodes(t, h, admin, "abc", "").Code)
+	assert.Equal(t, http.StatusNotFound, resetRecoveryCodes(t, h, admin, "999", "").Code)
+	assert.Equal(t, http.StatusBadRequest, resetRecoveryCodes(t, h, admin, target, "format=pdf").Code)
+
+	events, err := repo.ListAuditEvents(context.Background(), 10)
+	require.NoError(t, err)
+	assert.Empty(t, events)
+}
+
+func TestAuthenticatorStats(t *testing.T) {
+	h, repo, _ := newTestAdminHandlers(t)
+	user := testutil.NewTestUser(t, repo, "customer")
+	icloud := []byte{0xfb, 0xfc, 0x30, 0x07, 0x15, 0x4e, 0x4e, 0xcc, 0x8c, 0x0b, 0x6e, 0x02, 0x05, 0x57, 0xd7, 0xbd}
+	for i, aaguid := range [][]byte{icloud, icloud, nil} {
+		require.NoError(t, repo.CreateCredential(context.Background(), &models.Credential{
+			UserID:       user.ID,
+			CredentialID: []byte{byte(i)},
+			PublicKey:    []byte("key"),
+			AAGUID:       aaguid,
+		}))
+	}
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/admin/analytics/authenticators", nil)
+	rec := httptest.NewRecorder()
+	require.NoError(t, h.AuthenticatorStats(e.NewContext(req, rec)))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	var resp handlers.AuthenticatorStatsResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, []handlers.AuthenticatorStat{
+		{AAGUID: "fbfc3007154e4ecc8c0b6e020557d7bd", Name: "iCloud Keychain", Count: 2},
+		{AAGUID: repository.UnknownAAGUID, Count: 1},
+	}, resp.Authenticators)
+}
@@ -0,0 +1,160 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/oliverandrich/go-webapp-template/internal/appcontext"
+	"github.com/oliverandrich/go-webapp-template/internal/htmx"
+	"github.com/oliverandrich/go-webapp-template/internal/i18n"
+	"github.com/oliverandrich/go-webapp-template/internal/services/recovery"
+)
+
+// APIErrorCode identifies an error returned by the JSON auth API.
+type APIErrorCode string
+
+// Error codes returned by the /api/auth endpoints.
+const (
+	ErrCodeInvalidRequest     APIErrorCode = "invalid_request"     // Malformed or incomplete request
+	ErrCodeUnauthenticated    APIErrorCode = "unauthenticated"     // No valid session
+	ErrCodeSessionExpired     APIErrorCode = "session_expired"     // WebAuthn login session missing or expired
+	ErrCodeLoginFailed        APIErrorCode = "login_failed"        // Passkey assertion could not be verified
+	ErrCodeInvalidCredentials APIErrorCode = "invalid_credentials" // Unknown username or wrong recovery code
+	ErrCodeEmailNotVerified   APIErrorCode = "email_not_verified"  // Email verification is still pending
+	ErrCodeTooManyAttempts    APIErrorCode = "too_many_attempts"   // Account temporarily locked, see Retry-After
+	ErrCodeInternal           APIErrorCode = "internal_error"      // Unexpected server-side failure
+)
+
+// authError describes a failed authentication step. The browser endpoints
+// render its message, the JSON API additionally exposes the code. Messages are
+// translation IDs, so clients get them in the request's language.
+type authError struct {
+	status     int
+	code       APIErrorCode
+	messageID  string
+	data       map[string]any // Template data for the message, may be nil
+	retryAfter int            // Seconds, sent as Retry-After when positive
+}
+
+func newAuthError(status int, code APIErrorCode, messageID string) *authError {
+	return &authError{status: status, code: code, messageID: messageID}
+}
+
+// message translates the error for the request context. A message without a
+// translation falls back to the generic message for its code, and from there
+// to the HTTP status text, so clients never see a raw translation ID.
+func (e *authError) message(ctx context.Context) string {
+	if msg := i18n.TData(ctx, e.messageID, e.data); msg != e.messageID {
+		return msg
+	}
+	codeID := "auth_error_" + string(e.code)
+	if msg := i18n.T(ctx, codeID); msg != codeID {
+		return msg
+	}
+	return http.StatusText(e.status)
+}
+
+// AuthThrottleEvent is the detail of the "authThrottle" htmx event sent with
+// throttled responses, so the UI can disable the form and show a countdown.
+type AuthThrottleEvent struct {
+	RetryAfterSeconds int `json:"retryAfterSeconds"`
+}
+
+// setThrottleHeaders sets Retry-After and the authThrottle HX-Trigger event
+// when the error carries a retry delay.
+func (e *authError) setThrottleHeaders(c echo.Context) {
+	if e.retryAfter <= 0 {
+		return
+	}
+	header := c.Response().Header()
+	header.Set("Retry-After", strconv.Itoa(e.retryAfter))
+	_ = htmx.TriggerEvent(header, "authThrottle", AuthThrottleEvent{RetryAfterSeconds: e.retryAfter})
+}
+
+// writeAuthError writes the error in the {"error": "message"} shape used by
+// the browser-facing auth endpoints.
+func writeAuthError(c echo.Context, e *authError) error {
+	e.setThrottleHeaders(c)
+	return c.JSON(e.status, map[string]string{"error": e.message(c.Request().Context())})
+}
+
+// writeAPIError writes the error as problem details with the translated
+// message as detail and the stable error code as extension member.
+func writeAPIError(c echo.Context, e *authError) error {
+	e.setThrottleHeaders(c)
+	return writeProblem(c, Problem{Status: e.status, Detail: e.message(c.Request().Context()), Code: e.code})
+}
+
+// APIAuthHandlers exposes the authentication flows as a JSON API for
+// single-page applications. They share the logic of AuthHandlers but never
+// render templates or redirect. CSRF protection applies as for the browser
+// endpoints; clients send the token in the X-CSRF-Token header.
+type APIAuthHandlers struct {
+	auth *AuthHandlers
+}
+
+// NewAPIAuth creates JSON API handlers backed by the given auth handlers.
+func NewAPIAuth(auth *AuthHandlers) *APIAuthHandlers {
+	return &APIAuthHandlers{auth: auth}
+}
+
+// LoginBegin starts a passkey login.
+// Returns {"publicKey": {...}, "session_id": "..."}.
+func (h *APIAuthHandlers) LoginBegin(c echo.Context) error {
+	publicKey, sessionID, aerr := h.auth.beginLogin()
+	if aerr != nil {
+		return writeAPIError(c, aerr)
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"publicKey":  publicKey,
+		"session_id": sessionID,
+	})
+}
+
+// LoginFinish completes a passkey login and sets the session cookie.
+// Returns {"user": {...}}.
+func (h *APIAuthHandlers) LoginFinish(c echo.Context) error {
+	user, aerr := h.auth.finishLogin(c)
+	if aerr != nil {
+		return writeAPIError(c, aerr)
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{"user": user})
+}
+
+// RecoveryLogin signs in with a recovery code and sets the session cookie.
+// Returns {"user": {...}, "remaining_codes": n, "low_codes": bool}.
+func (h *APIAuthHandlers) RecoveryLogin(c echo.Context) error {
+	user, remaining, aerr := h.auth.recoveryLogin(c)
+	if aerr != nil {
+		return writeAPIError(c, aerr)
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"user":            user,
+		"remaining_codes": remaining,
+		"low_codes":       remaining <= recovery.LowCount,
+	})
+}
+
+// Me returns the signed-in user as {"user": {...}}.
+func (h *APIAuthHandlers) Me(c echo.Context) error {
+	cc, ok := c.(*appcontext.Context)
+	if !ok || !cc.IsAuthenticated() {
+		return writeAPIError(c, newAuthError(http.StatusUnauthorized, ErrCodeUnauthenticated, "auth_error_unauthenticated"))
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{"user": cc.GetUser()})
+}
+
+// Logout ends the session and clears the session cookie. Returns {"status": "ok"}.
+func (h *APIAuthHandlers) Logout(c echo.Context) error {
+	c.SetCookie(h.auth.store.Clear(c.Request()))
+	return c.JSON(http.StatusOK, map[string]string{"status": "ok"})
+}
@@ -0,0 +1,211 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/oliverandrich/go-webapp-template/internal/handlers"
+	"github.com/oliverandrich/go-webapp-template/internal/i18n"
+	"github.com/oliverandrich/go-webapp-template/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/language"
+)
+
+// decodeAPIError asserts the status and problem details shape and returns
+// the decoded error code.
+func decodeAPIError(t *testing.T, rec *httptest.ResponseRecorder, status int) handlers.APIErrorCode {
+	t.Helper()
+	require.Equal(t, status, rec.Code)
+	assert.Equal(t, handlers.MIMEApplicationProblemJSON, rec.Header().Get(echo.HeaderContentType))
+	var body handlers.Problem
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, status, body.Status)
+	assert.NotEmpty(t, body.Detail)
+	return body.Code
+}
+
+func jsonRequest(method, target, body string) *http.Request {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	return req
+}
+
+func TestAPILoginBegin(t *testing.T) {
+	auth, _ := newTestAuthHandlers(t)
+	h := handlers.NewAPIAuth(auth)
+
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	require.NoError(t, h.LoginBegin(e.NewContext(jsonRequest(http.MethodPost, "/api/auth/login/begin", ""), rec)))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	var body map[string]any
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Contains(t, body, "publicKey")
+	assert.NotEmpty(t, body["session_id"])
+}
+
+func TestAPILoginFinish_Errors(t *testing.T) {
+	auth, _ := newTestAuthHandlers(t)
+	h := handlers.NewAPIAuth(auth)
+	e := echo.New()
+
+	// Start a real login session for the assertion failure case
+	rec := httptest.NewRecorder()
+	require.NoError(t, h.LoginBegin(e.NewContext(jsonRequest(http.MethodPost, "/api/auth/login/begin", ""), rec)))
+	var begin struct {
+		SessionID string `json:"session_id"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &begin))
+
+	tests := []struct {
+		name   string
+		target string
+		status int
+		code   handlers.APIErrorCode
+	}{
+		{"missing session id", "/api/auth/login/finish", http.StatusBadRequest, handlers.ErrCodeInvalidRequest},
+		{"unknown session", "/api/auth/login/finish?session_id=unknown", http.StatusBadRequest, handlers.ErrCodeSessionExpired},
+		{"invalid assertion", "/api/auth/login/finish?session_id=" + begin.SessionID, http.StatusUnauthorized, handlers.ErrCodeLoginFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			require.NoError(t, h.LoginFinish(e.NewContext(jsonRequest(http.MethodPost, tt.target, `{}`), rec)))
+
+			assert.Equal(t, tt.code, decodeAPIError(t, rec, tt.status))
+		})
+	}
+}
+
+func TestAPIRecoveryLogin_Success(t *testing.T) {
+	auth, repo := newTestAuthHandlers(t)
+	h := handlers.NewAPIAuth(auth)
+	user := testutil.NewTestUser(t, repo, "testuser")
+	codes := newTestRecoveryCodes(t, repo, user.ID)
+
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	body := `{"username":"testuser","code":"` + codes[0] + `"}`
+	require.NoError(t, h.RecoveryLogin(e.NewContext(jsonRequest(http.MethodPost, "/api/auth/recovery", body), rec)))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	var resp struct {
+		User struct {
+			ID       int64  `json:"id"`
+			Username string `json:"username"`
+		} `json:"user"`
+		RemainingCodes int64 `json:"remaining_codes"`
+		LowCodes       bool  `json:"low_codes"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, user.ID, resp.User.ID)
+	assert.Equal(t, "testuser", resp.User.Username)
+	assert.Equal(t, int64(1), resp.RemainingCodes)
+	assert.True(t, resp.LowCodes)
+	assert.NotEmpty(t, rec.Header().Get("Set-Cookie"))
+}
+
+func TestAPIRecoveryLogin_Errors(t *testing.T) {
+	auth, repo := newTestLockoutHandlers(t)
+	h := handlers.NewAPIAuth(auth)
+	user := testutil.NewTestUser(t, repo, "testuser")
+	newTestRecoveryCodes(t, repo, user.ID)
+	e := echo.New()
+
+	post := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		require.NoError(t, h.RecoveryLogin(e.NewContext(jsonRequest(http.MethodPost, "/api/auth/recovery", body), rec)))
+		return rec
+	}
+
+	assert.Equal(t, handlers.ErrCodeInvalidRequest, decodeAPIError(t, post(`{"username":"testuser"}`), http.StatusBadRequest))
+	assert.Equal(t, handlers.ErrCodeInvalidRequest, decodeAPIError(t, post(`not json`), http.StatusBadRequest))
+
+	for range 3 {
+		rec := post(`{"username":"testuser","code":"wrong"}`)
+		assert.Equal(t, handlers.ErrCodeInvalidCredentials, decodeAPIError(t, rec, http.StatusUnauthorized))
+	}
+
+	rec := post(`{"username":"testuser","code":"wrong"}`)
+	assert.Equal(t, handlers.ErrCodeTooManyAttempts, decodeAPIError(t, rec, http.StatusTooManyRequests))
+	assert.Equal(t, "900", rec.Header().Get("Retry-After"))
+	assert.JSONEq(t, `{"authThrottle":{"retryAfterSeconds":900}}`, rec.Header().Get("HX-Trigger"))
+}
+
+func TestAPIRecoveryLogin_GermanMessages(t *testing.T) {
+	auth, repo := newTestLockoutHandlers(t)
+	h := handlers.NewAPIAuth(auth)
+	user := testutil.NewTestUser(t, repo, "testuser")
+	newTestRecoveryCodes(t, repo, user.ID)
+	e := echo.New()
+
+	post := func(body string) handlers.Problem {
+		req := jsonRequest(http.MethodPost, "/api/auth/recovery", body)
+		req = req.WithContext(i18n.WithLocale(req.Context(), language.German))
+		rec := httptest.NewRecorder()
+		require.NoError(t, h.RecoveryLogin(e.NewContext(req, rec)))
+		var resp handlers.Problem
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		return resp
+	}
+
+	assert.Equal(t, "Benutzername und Recovery Code sind erforderlich.", post(`{"username":"testuser"}`).Detail)
+	for range 3 {
+		assert.Equal(t, "Ungültiger Benutzername oder Recovery Code.", post(`{"username":"testuser","code":"wrong"}`).Detail)
+	}
+	assert.Equal(t, "Zu viele fehlgeschlagene Versuche. Bitte versuche es in 15 Min. erneut.", post(`{"username":"testuser","code":"wrong"}`).Detail)
+}
+
+func TestAuthErrorMessage_Fallback(t *testing.T) {
+	ctx := i18n.WithLocale(context.Background(), language.German)
+
+	t.Run("unknown message falls back to code", func(t *testing.T) {
+		msg := handlers.AuthErrorMessage(ctx, http.StatusUnauthorized, handlers.ErrCodeLoginFailed, "auth_error_no_such_message")
+		assert.Equal(t, "Anmeldung fehlgeschlagen.", msg)
+	})
+
+	t.Run("unknown code falls back to status text", func(t *testing.T) {
+		msg := handlers.AuthErrorMessage(ctx, http.StatusConflict, handlers.APIErrorCode("no_such_code"), "auth_error_no_such_message")
+		assert.Equal(t, http.StatusText(http.StatusConflict), msg)
+	})
+}
+
+func TestAPIMe(t *testing.T) {
+	auth, repo := newTestAuthHandlers(t)
+	h := handlers.NewAPIAuth(auth)
+	user := testutil.NewTestUser(t, repo, "testuser")
+	e := echo.New()
+
+	rec := httptest.NewRecorder()
+	require.NoError(t, h.Me(newTestContext(e, httptest.NewRequest(http.MethodGet, "/api/auth/me", nil), rec, user)))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"username":"testuser"`)
+
+	rec = httptest.NewRecorder()
+	require.NoError(t, h.Me(newTestContext(e, httptest.NewRequest(http.MethodGet, "/api/auth/me", nil), rec, nil)))
+	assert.Equal(t, handlers.ErrCodeUnauthenticated, decodeAPIError(t, rec, http.StatusUnauthorized))
+}
+
+func TestAPILogout(t *testing.T) {
+	auth, _ := newTestAuthHandlers(t)
+	h := handlers.NewAPIAuth(auth)
+
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	require.NoError(t, h.Logout(e.NewContext(httptest.NewRequest(http.MethodPost, "/api/auth/logout", nil), rec)))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"status":"ok"}`, rec.Body.String())
+	assert.Contains(t, rec.Header().Get("Set-Cookie"), "Max-Age=0")
+}
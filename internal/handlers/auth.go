@@ -4,7 +4,9 @@
 package handlers
 
 import (
-	"encoding/binary"
+	"context"
+	"database/sql"
+	"errors"
 	"log/slog"
 	"net/http"
 	"strconv"
@@ -14,62 +16,203 @@ import (
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 	"github.com/oliverandrich/go-webapp-template/internal/appcontext"
+	"github.com/oliverandrich/go-webapp-template/internal/clock"
 	"github.com/oliverandrich/go-webapp-template/internal/config"
+	"github.com/oliverandrich/go-webapp-template/internal/i18n"
 	"github.com/oliverandrich/go-webapp-template/internal/models"
 	"github.com/oliverandrich/go-webapp-template/internal/repository"
+	"github.com/oliverandrich/go-webapp-template/internal/services/auth"
 	"github.com/oliverandrich/go-webapp-template/internal/services/email"
 	"github.com/oliverandrich/go-webapp-template/internal/services/recovery"
 	"github.com/oliverandrich/go-webapp-template/internal/services/session"
+	"github.com/oliverandrich/go-webapp-template/internal/services/settings"
 	"github.com/oliverandrich/go-webapp-template/internal/services/webauthn"
+	"github.com/oliverandrich/go-webapp-template/internal/services/webhook"
+	"github.com/oliverandrich/go-webapp-template/internal/signedurl"
 	authtpl "github.com/oliverandrich/go-webapp-template/internal/templates/auth"
 )
 
 // AuthHandlers contains handlers for authentication.
 type AuthHandlers struct {
-	repo     *repository.Repository
-	webauthn *webauthn.Service
-	sessions *session.Manager
-	recovery *recovery.Service
-	email    *email.Service // nil if email mode is disabled
-	authCfg  *config.AuthConfig
+	repo      *repository.Repository
+	webauthn  *webauthn.Service
+	sessions  *session.Manager
+	store     session.Store
+	recovery  *recovery.Service
+	email     *email.Service // nil if email mode is disabled
+	authCfg   *config.AuthConfig
+	webhooks  *webhook.Notifier // nil if webhooks are disabled
+	settings  *settings.Service // nil means registration is always open
+	decoys    *decoyLockout
+	downloads *recoveryDownloads
+	clock     clock.Clock
+	pages     *Renderer
+
+	magicLinks *signedurl.Signer // nil if sign-in links by email are disabled
 }
 
 // NewAuth creates a new AuthHandlers instance.
 // email service can be nil if email mode is disabled.
 func NewAuth(repo *repository.Repository, wa *webauthn.Service, sess *session.Manager, emailSvc *email.Service, authCfg *config.AuthConfig) *AuthHandlers {
 	return &AuthHandlers{
-		repo:     repo,
-		webauthn: wa,
-		sessions: sess,
-		recovery: recovery.NewService(),
-		email:    emailSvc,
-		authCfg:  authCfg,
+		repo:      repo,
+		webauthn:  wa,
+		sessions:  sess,
+		store:     session.NewCookieStore(sess, repo),
+		recovery:  recovery.NewService(),
+		email:     emailSvc,
+		authCfg:   authCfg,
+		decoys:    newDecoyLockout(),
+		downloads: newRecoveryDownloads(),
+		clock:     clock.Real{},
+		pages:     defaultRenderer,
 	}
 }
 
+// SetRenderer replaces the renderer used for the auth pages.
+func (h *AuthHandlers) SetRenderer(r *Renderer) {
+	h.pages = r
+}
+
+// SetSessionStore replaces the cookie-backed session store, e.g. with a
+// session.DBStore.
+func (h *AuthHandlers) SetSessionStore(s session.Store) {
+	h.store = s
+}
+
+// SetClock replaces the time source used for token expiry (for tests).
+func (h *AuthHandlers) SetClock(c clock.Clock) {
+	h.clock = c
+}
+
+// SetWebhooks sets the notifier used to report security events.
+func (h *AuthHandlers) SetWebhooks(n *webhook.Notifier) {
+	h.webhooks = n
+}
+
+// SetSettings sets the runtime settings consulted for the registration mode.
+func (h *AuthHandlers) SetSettings(s *settings.Service) {
+	h.settings = s
+}
+
+// IsRegistrationEnabled reports whether new users may currently register.
+func (h *AuthHandlers) IsRegistrationEnabled() bool {
+	return h.settings == nil || h.settings.IsRegistrationEnabled()
+}
+
 // UseEmailMode returns true if email-based authentication is enabled.
 func (h *AuthHandlers) UseEmailMode() bool {
 	return h.authCfg != nil && h.authCfg.UseEmail
 }
 
+// normalizeEmail returns the canonical form of an email address, used for
+// everything that is stored or looked up.
+func (h *AuthHandlers) normalizeEmail(email string) string {
+	return auth.NormalizeEmail(email, h.authCfg != nil && h.authCfg.CanonicalizeGmail)
+}
+
+// normalizeUsername canonicalizes a login name. In email mode the username
+// mirrors the email and is normalized like one.
+func (h *AuthHandlers) normalizeUsername(username string) string {
+	if h.UseEmailMode() {
+		return h.normalizeEmail(username)
+	}
+	return auth.NormalizeUsername(username)
+}
+
 // RegisterPage renders the registration page.
 func (h *AuthHandlers) RegisterPage(c echo.Context) error {
-	return Render(c, http.StatusOK, authtpl.Register(h.UseEmailMode()))
+	if !h.IsRegistrationEnabled() {
+		return echo.NewHTTPError(http.StatusForbidden, "registration is closed")
+	}
+	return h.pages.Page(c, http.StatusOK, "register_title", authtpl.Register(h.UseEmailMode()))
 }
 
 // RegisterBeginRequest is the request body for starting registration.
 type RegisterBeginRequest struct {
-	Username string `json:"username"`
-	Email    string `json:"email"`
+	Username    string `json:"username"`
+	Email       string `json:"email"`
+	DisplayName string `json:"display_name"` // Optional, defaults to the username
+}
+
+// usernameMessages maps auth.ValidateUsername errors to translation IDs.
+var usernameMessages = map[error]string{
+	auth.ErrUsernameEmpty:      "username_required",
+	auth.ErrUsernameTooLong:    "username_too_long",
+	auth.ErrUsernameWhitespace: "username_whitespace",
+	auth.ErrUsernameCharacters: "username_invalid_characters",
+}
+
+// usernameMaxLength returns the configured maximum username length, or
+// auth.MaxUsernameLength when it is unset or out of range.
+func (h *AuthHandlers) usernameMaxLength() int {
+	maxLength := h.authCfg.UsernameMaxLength
+	if maxLength <= 0 || maxLength > auth.MaxUsernameLength {
+		maxLength = auth.MaxUsernameLength
+	}
+	return maxLength
+}
+
+// validateUsername checks a username for registration against the
+// configured maximum length.
+func (h *AuthHandlers) validateUsername(username string) *authError {
+	maxLength := h.usernameMaxLength()
+	err := auth.ValidateUsername(username, maxLength)
+	if err == nil {
+		return nil
+	}
+	aerr := newAuthError(http.StatusBadRequest, ErrCodeInvalidRequest, usernameMessages[err])
+	aerr.data = map[string]any{"Max": maxLength}
+	return aerr
+}
+
+// usernameSuggestions is the number of alternatives offered when a username is taken.
+const usernameSuggestions = 3
+
+// suggestUsernames returns up to usernameSuggestions free variants of a
+// taken username that registration would accept. All candidates are checked
+// with one query, so fewer suggestions may be returned if most are taken.
+func (h *AuthHandlers) suggestUsernames(ctx context.Context, base string) ([]string, error) {
+	candidates := auth.UsernameCandidates(base, h.usernameMaxLength())
+	taken, err := h.repo.TakenUsernames(ctx, candidates)
+	if err != nil {
+		return nil, err
+	}
+
+	var suggestions []string
+	for _, candidate := range candidates {
+		if !taken[candidate] {
+			suggestions = append(suggestions, candidate)
+		}
+		if len(suggestions) == usernameSuggestions {
+			break
+		}
+	}
+	return suggestions, nil
 }
 
 // RegisterBegin starts the WebAuthn registration process.
 func (h *AuthHandlers) RegisterBegin(c echo.Context) error {
+	if !h.IsRegistrationEnabled() {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": i18n.T(c.Request().Context(), "registration_closed")})
+	}
+	if aerr := h.countRegistration(c); aerr != nil {
+		return writeAuthError(c, aerr)
+	}
+
 	var req RegisterBeginRequest
 	if err := c.Bind(&req); err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid request"})
 	}
 
+	// The username is validated as entered: surrounding whitespace is
+	// rejected rather than trimmed, so the stored name is what the user typed
+	req.Email = h.normalizeEmail(req.Email)
+	req.DisplayName = auth.NormalizeDisplayName(req.DisplayName)
+	if !auth.ValidDisplayName(req.DisplayName) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid display name"})
+	}
+
 	var user *models.User
 	var createErr error
 	ctx := c.Request().Context()
@@ -79,6 +222,9 @@ func (h *AuthHandlers) RegisterBegin(c echo.Context) error {
 		if req.Email == "" {
 			return c.JSON(http.StatusBadRequest, map[string]string{"error": "email is required"})
 		}
+		if !auth.ValidEmail(req.Email) {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid email address"})
+		}
 
 		// Check if email already exists
 		exists, err := h.repo.EmailExists(ctx, req.Email)
@@ -86,19 +232,22 @@ func (h *AuthHandlers) RegisterBegin(c echo.Context) error {
 			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "database error"})
 		}
 		if exists {
+			if h.authCfg.EnumerationSafe {
+				return h.registrationConflictNotice(c, req.Email, req.DisplayName)
+			}
 			return c.JSON(http.StatusConflict, map[string]string{"error": "email already registered"})
 		}
 
 		// Create user with email
-		user, createErr = h.repo.CreateUserWithEmail(ctx, req.Email)
+		user, createErr = h.repo.CreateUserWithEmailAndDisplayName(ctx, req.Email, req.DisplayName)
 		if createErr != nil {
 			slog.Error("failed to create user", "error", createErr, "email", req.Email)
 			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to create user"})
 		}
 	} else {
 		// Username mode: original behavior
-		if req.Username == "" {
-			return c.JSON(http.StatusBadRequest, map[string]string{"error": "username is required"})
+		if aerr := h.validateUsername(req.Username); aerr != nil {
+			return writeAuthError(c, aerr)
 		}
 
 		// Check if username already exists
@@ -107,11 +256,18 @@ func (h *AuthHandlers) RegisterBegin(c echo.Context) error {
 			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "database error"})
 		}
 		if exists {
-			return c.JSON(http.StatusConflict, map[string]string{"error": "username already taken"})
+			suggestions, suggestErr := h.suggestUsernames(ctx, req.Username)
+			if suggestErr != nil {
+				slog.Error("failed to suggest usernames", "error", suggestErr)
+			}
+			return c.JSON(http.StatusConflict, map[string]any{
+				"error":       "username already taken",
+				"suggestions": suggestions,
+			})
 		}
 
 		// Create user in database
-		user, createErr = h.repo.CreateUser(ctx, req.Username)
+		user, createErr = h.repo.CreateUserWithDisplayName(ctx, req.Username, req.DisplayName)
 		if createErr != nil {
 			slog.Error("failed to create user", "error", createErr, "username", req.Username)
 			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to create user"})
@@ -133,6 +289,96 @@ func (h *AuthHandlers) RegisterBegin(c echo.Context) error {
 	})
 }
 
+// accountNoticeWindow is the window AuthConfig.AccountNoticeLimit applies to.
+const accountNoticeWindow = time.Hour
+
+// registrationConflictNotice answers a registration for an email that
+// already has an account when auth.enumeration_safe is enabled. The client
+// gets a creation challenge of the same shape a new sign-up gets, for a user
+// ID that is reserved but never stored, and RegisterFinish answers it like a
+// new sign-up without keeping the credential. The owner gets an email
+// pointing them to the login page, at most auth.account_notice_limit per
+// hour; the answer is the same either way.
+func (h *AuthHandlers) registrationConflictNotice(c echo.Context, address, displayName string) error {
+	ctx := c.Request().Context()
+	owner, err := h.repo.GetUserByEmail(ctx, address)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "database error"})
+	}
+	notify := true
+	if limit := h.authCfg.AccountNoticeLimit; limit > 0 {
+		count, countErr := h.repo.CountRecentAccountNotices(ctx, owner.ID, accountNoticeWindow)
+		if countErr != nil {
+			slog.Error("failed to count account notices", "error", countErr)
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "database error"})
+		}
+		notify = count < int64(limit)
+	}
+	if notify {
+		if err = h.repo.RecordAccountNotice(ctx, owner.ID); err != nil {
+			slog.Error("failed to record account notice", "error", err)
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "database error"})
+		}
+	} else {
+		slog.Warn("account notice limit reached", "user_id", owner.ID)
+	}
+
+	id, err := h.repo.ReserveUserID(ctx)
+	if err != nil {
+		slog.Error("failed to reserve user id", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to create user"})
+	}
+	if displayName == "" {
+		displayName = address
+	}
+	decoy := &models.User{ID: id, Username: address, DisplayName: displayName, Email: &address}
+	options, sessionData, err := h.webauthn.WebAuthn().BeginRegistration(decoy)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to begin registration"})
+	}
+	h.webauthn.StoreRegistrationSession(id, sessionData)
+
+	if notify {
+		go func() {
+			if sendErr := h.email.SendAccountExists(context.WithoutCancel(ctx), address); sendErr != nil {
+				slog.Error("failed to send account exists email", "error", sendErr, "email", address)
+			}
+		}()
+	}
+	return c.JSON(http.StatusOK, map[string]any{
+		"publicKey": options.Response,
+		"user_id":   id,
+	})
+}
+
+// finishDecoyRegistration completes a ceremony started by
+// registrationConflictNotice. The attestation is verified like any other so
+// errors look the same, but the credential is discarded and the client is
+// sent to the "check your email" page a new sign-up ends on, with the same
+// recovery codes flash cookie; the codes are never stored.
+func (h *AuthHandlers) finishDecoyRegistration(c echo.Context, userID int64, sessionData *gowebauthn.SessionData) error {
+	if _, err := h.webauthn.WebAuthn().FinishRegistration(&models.User{ID: userID}, *sessionData, c.Request()); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "registration failed: " + err.Error()})
+	}
+
+	codes, _, err := h.recovery.GenerateCodes(recovery.CodeCount)
+	if err != nil {
+		slog.Error("failed to generate recovery codes", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to generate recovery codes"})
+	}
+	flashCookie, err := h.sessions.SetFlash(h.recoveryCodesFlash(userID, codes))
+	if err != nil {
+		slog.Error("failed to create flash cookie", "error", err)
+	} else {
+		c.SetCookie(flashCookie)
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"status":   "ok",
+		"redirect": "/auth/verify-pending",
+	})
+}
+
 // RegisterFinishRequest is the request body for finishing registration.
 type RegisterFinishRequest struct {
 	UserID int64 `json:"user_id"`
@@ -140,10 +386,16 @@ type RegisterFinishRequest struct {
 
 // RegisterFinish completes the WebAuthn registration process.
 func (h *AuthHandlers) RegisterFinish(c echo.Context) error {
+	if !h.IsRegistrationEnabled() {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": i18n.T(c.Request().Context(), "registration_closed")})
+	}
 	userID, err := strconv.ParseInt(c.QueryParam("user_id"), 10, 64)
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid user_id"})
 	}
+	if aerr := h.checkRegistrationLimit(c); aerr != nil {
+		return writeAuthError(c, aerr)
+	}
 
 	ctx := c.Request().Context()
 
@@ -156,6 +408,9 @@ func (h *AuthHandlers) RegisterFinish(c echo.Context) error {
 	// Get user from database
 	user, err := h.repo.GetUserByID(ctx, userID)
 	if err != nil {
+		if h.authCfg.EnumerationSafe {
+			return h.finishDecoyRegistration(c, userID, sessionData)
+		}
 		return c.JSON(http.StatusNotFound, map[string]string{"error": "user not found"})
 	}
 
@@ -218,7 +473,7 @@ func (h *AuthHandlers) RegisterFinish(c echo.Context) error {
 		}()
 
 		// Store codes in flash cookie for later display after verification
-		flashCookie, flashErr := h.sessions.SetFlash(&session.FlashData{RecoveryCodes: codes})
+		flashCookie, flashErr := h.sessions.SetFlash(h.recoveryCodesFlash(user.ID, codes))
 		if flashErr != nil {
 			slog.Error("failed to create flash cookie", "error", flashErr)
 		} else {
@@ -232,14 +487,14 @@ func (h *AuthHandlers) RegisterFinish(c echo.Context) error {
 	}
 
 	// Username mode or email already verified: create session immediately
-	sessionCookie, err := h.sessions.Create(user.ID, user.Username)
+	sessionCookie, err := h.newSession(ctx, user, dbCred.ID, h.sessions.Duration())
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to create session"})
 	}
 	c.SetCookie(sessionCookie)
 
 	// Store codes in flash cookie for display on next page
-	flashCookie, err := h.sessions.SetFlash(&session.FlashData{RecoveryCodes: codes})
+	flashCookie, err := h.sessions.SetFlash(h.recoveryCodesFlash(user.ID, codes))
 	if err != nil {
 		slog.Error("failed to create flash cookie", "error", err)
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to store recovery codes"})
@@ -254,94 +509,181 @@ func (h *AuthHandlers) RegisterFinish(c echo.Context) error {
 
 // LoginPage renders the login page.
 func (h *AuthHandlers) LoginPage(c echo.Context) error {
-	return Render(c, http.StatusOK, authtpl.Login())
+	return h.pages.Page(c, http.StatusOK, "login_title", authtpl.Login())
 }
 
 // LoginBegin starts the WebAuthn login process (usernameless/discoverable).
 func (h *AuthHandlers) LoginBegin(c echo.Context) error {
+	publicKey, sessionID, aerr := h.beginLogin()
+	if aerr != nil {
+		return writeAuthError(c, aerr)
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"publicKey":  publicKey,
+		"session_id": sessionID,
+	})
+}
+
+// beginLogin starts a discoverable login and returns the credential request
+// options together with the ID of the stored login session.
+func (h *AuthHandlers) beginLogin() (any, string, *authError) {
 	// Begin discoverable login (no user info needed)
 	options, sessionData, err := h.webauthn.WebAuthn().BeginDiscoverableLogin()
 	if err != nil {
 		slog.Error("failed to begin discoverable login", "error", err)
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to begin login"})
+		return nil, "", newAuthError(http.StatusInternalServerError, ErrCodeInternal, "auth_error_begin_login")
 	}
 
 	// Generate session ID for this login attempt
 	sessionID := uuid.New().String()
 	h.webauthn.StoreDiscoverableSession(sessionID, sessionData)
 
-	return c.JSON(http.StatusOK, map[string]any{
-		"publicKey":  options.Response,
-		"session_id": sessionID,
-	})
+	return options.Response, sessionID, nil
 }
 
 // LoginFinish completes the WebAuthn login process.
 func (h *AuthHandlers) LoginFinish(c echo.Context) error {
+	if _, aerr := h.finishLogin(c); aerr != nil {
+		if aerr.code == ErrCodeEmailNotVerified {
+			return c.JSON(aerr.status, map[string]any{
+				"error":    "email_not_verified",
+				"redirect": "/auth/verify-pending",
+			})
+		}
+		return writeAuthError(c, aerr)
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// finishLogin verifies the passkey assertion and sets the session cookie.
+func (h *AuthHandlers) finishLogin(c echo.Context) (*models.User, *authError) {
 	sessionID := c.QueryParam("session_id")
 	if sessionID == "" {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "session_id is required"})
+		return nil, newAuthError(http.StatusBadRequest, ErrCodeInvalidRequest, "auth_error_session_id_required")
 	}
 
 	// Get session data
 	sessionData, err := h.webauthn.GetDiscoverableSession(sessionID)
 	if err != nil {
 		slog.Error("failed to get discoverable session", "error", err, "session_id", sessionID)
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "login session expired"})
+		return nil, newAuthError(http.StatusBadRequest, ErrCodeSessionExpired, "auth_error_session_expired")
 	}
 
 	// Finish discoverable login with user handler
 	var foundUser *models.User
+	var ownsCredential bool
 	credential, finishErr := h.webauthn.WebAuthn().FinishDiscoverableLogin(
 		func(rawID, userHandle []byte) (gowebauthn.User, error) {
 			// userHandle contains the user ID we set during registration
 			slog.Debug("discoverable login callback", "rawID_len", len(rawID), "userHandle_len", len(userHandle))
-			if len(userHandle) < 8 {
-				slog.Error("invalid user handle length", "length", len(userHandle))
-				return nil, echo.NewHTTPError(http.StatusBadRequest, "invalid user handle")
-			}
-			userID := int64(binary.BigEndian.Uint64(userHandle)) //nolint:gosec // user IDs are always positive
-			slog.Debug("looking up user", "user_id", userID)
-			user, userErr := h.repo.GetUserByID(c.Request().Context(), userID)
+			user, userErr := h.repo.GetUserByWebAuthnID(c.Request().Context(), userHandle)
 			if userErr != nil {
-				slog.Error("failed to get user by ID", "error", userErr, "user_id", userID)
+				slog.Warn("failed to get user by webauthn handle", "error", userErr, "userHandle_len", len(userHandle))
 				return nil, userErr
 			}
 			foundUser = user
+			if cred, credErr := h.repo.GetCredentialByCredentialID(c.Request().Context(), rawID); credErr == nil {
+				ownsCredential = cred.UserID == user.ID
+			}
 			return user, nil
 		},
 		*sessionData,
 		c.Request(),
 	)
+	// A verified assertion is never rejected by the lockout, which only gates
+	// recovery codes. Failures only count against the account when the
+	// asserted passkey belongs to it; the user handle alone is chosen by the
+	// client.
+	client := clientKey(c.RealIP())
 	if finishErr != nil {
 		slog.Error("failed to finish discoverable login", "error", finishErr)
-		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "login failed"})
+		if foundUser != nil && ownsCredential {
+			h.recordFailedLogin(c.Request().Context(), foundUser.ID, client)
+		}
+		return nil, newAuthError(http.StatusUnauthorized, ErrCodeLoginFailed, "auth_error_login_failed")
 	}
+	h.resetFailedLogins(c.Request().Context(), foundUser.ID, client)
 
 	// Update sign count
 	_ = h.repo.UpdateCredentialSignCount(c.Request().Context(), credential.ID, credential.Authenticator.SignCount)
 
+	// Remember which passkey the session was established with
+	var credID int64
+	if dbCred, credErr := h.repo.GetCredentialByCredentialID(c.Request().Context(), credential.ID); credErr == nil {
+		credID = dbCred.ID
+		h.syncBackupState(c.Request().Context(), foundUser.ID, dbCred, credential)
+	}
+
 	// Check email verification in email mode
 	if h.UseEmailMode() && h.authCfg.RequireVerification && !foundUser.EmailVerified {
-		return c.JSON(http.StatusForbidden, map[string]any{
-			"error":    "email_not_verified",
-			"redirect": "/auth/verify-pending",
-		})
+		return nil, newAuthError(http.StatusForbidden, ErrCodeEmailNotVerified, "auth_error_email_not_verified")
 	}
 
-	// Create session cookie
-	cookie, err := h.sessions.Create(foundUser.ID, foundUser.Username)
+	// Refresh the existing session on re-assertion, otherwise create a new one
+	var cookie *http.Cookie
+	ctx := c.Request().Context()
+	if existing, _ := h.store.Parse(c.Request()); existing != nil && existing.UserID == foundUser.ID {
+		existing.Version = foundUser.SessionVersion
+		existing.CredentialID = credID
+		cookie, err = h.store.Save(ctx, h.sessions.Reauthenticated(existing))
+	} else if rememberMe(c) {
+		cookie, err = h.newSession(ctx, foundUser, credID, h.sessions.RememberMeDuration())
+	} else {
+		cookie, err = h.newSession(ctx, foundUser, credID, h.sessions.Duration())
+	}
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to create session"})
+		return nil, newAuthError(http.StatusInternalServerError, ErrCodeInternal, "auth_error_create_session")
 	}
 	c.SetCookie(cookie)
+	if err := h.repo.RecordLogin(c.Request().Context(), foundUser.ID); err != nil {
+		slog.Error("failed to record login", "error", err, "user_id", foundUser.ID)
+	}
 
-	return c.JSON(http.StatusOK, map[string]string{"status": "ok"})
+	return foundUser, nil
 }
 
-// Logout clears the session cookie.
+// syncBackupState stores backup flags that changed since the credential was
+// saved, e.g. a device-bound passkey that is now synced, and records the
+// change in the audit log. Failures are logged and don't fail the login.
+func (h *AuthHandlers) syncBackupState(ctx context.Context, userID int64, stored *models.Credential, asserted *gowebauthn.Credential) {
+	eligible, state := asserted.Flags.BackupEligible, asserted.Flags.BackupState
+	if stored.BackupEligible == eligible && stored.BackupState == state {
+		return
+	}
+	if err := h.repo.UpdateCredentialBackupState(ctx, stored.CredentialID, eligible, state); err != nil {
+		slog.Error("failed to update credential backup state", "error", err, "credential_id", stored.ID)
+		return
+	}
+	if err := h.repo.CreateAuditEvent(ctx, userID, models.AuditCredentialBackup, userID); err != nil {
+		slog.Error("failed to record audit event", "error", err)
+	}
+	slog.Info("credential backup state changed", "user_id", userID, "credential_id", stored.ID,
+		"backup_eligible", eligible, "backup_state", state)
+}
+
+// newSession issues a session cookie for user, bound to the user's current
+// session version. credID is the passkey used to sign in, 0 if none was.
+func (h *AuthHandlers) newSession(ctx context.Context, user *models.User, credID int64, d time.Duration) (*http.Cookie, error) {
+	return h.store.Create(ctx, session.Data{
+		UserID:       user.ID,
+		Username:     user.Username,
+		Version:      user.SessionVersion,
+		CredentialID: credID,
+	}, d)
+}
+
+// rememberMe reports whether the login request asked for a long-lived session.
+// The WebAuthn response occupies the body, so the flag travels in the query.
+func rememberMe(c echo.Context) bool {
+	v, _ := strconv.ParseBool(c.QueryParam("remember_me"))
+	return v
+}
+
+// Logout ends the session and clears the session cookie.
 func (h *AuthHandlers) Logout(c echo.Context) error {
-	c.SetCookie(h.sessions.Clear())
+	c.SetCookie(h.store.Clear(c.Request()))
 	return c.Redirect(http.StatusSeeOther, "/")
 }
 
@@ -353,12 +695,29 @@ func (h *AuthHandlers) CredentialsPage(c echo.Context) error {
 	}
 	user := cc.GetUser()
 
-	creds, err := h.repo.GetCredentialsByUserID(c.Request().Context(), user.ID)
+	creds, err := h.repo.GetCredentialSummaries(c.Request().Context(), user.ID)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to get credentials"})
 	}
 
-	return Render(c, http.StatusOK, authtpl.Credentials(creds))
+	return h.pages.Page(c, http.StatusOK, "credentials_title", authtpl.Credentials(creds))
+}
+
+// defaultChangePasswordURL is where ChangePassword redirects unless configured
+// otherwise. Passkeys and, in email mode, the account email are both managed
+// there, so the same page serves both auth modes.
+const defaultChangePasswordURL = "/auth/credentials"
+
+// ChangePassword answers the well-known change-password URL used by password
+// managers with a redirect to the page where users manage their sign-in
+// credentials. It does not require authentication; the target page sends
+// anonymous visitors on to the login page.
+func (h *AuthHandlers) ChangePassword(c echo.Context) error {
+	target := defaultChangePasswordURL
+	if h.authCfg != nil && h.authCfg.ChangePasswordURL != "" {
+		target = h.authCfg.ChangePasswordURL
+	}
+	return c.Redirect(http.StatusFound, target)
 }
 
 // AddCredentialBegin starts the process of adding a new credential.
@@ -367,8 +726,21 @@ func (h *AuthHandlers) AddCredentialBegin(c echo.Context) error {
 	if !ok || !cc.IsAuthenticated() {
 		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "not authenticated"})
 	}
+	if cc.IsImpersonating() {
+		return impersonationForbidden(c)
+	}
 	user := cc.GetUser()
 
+	reached, err := h.credentialLimitReached(c.Request().Context(), user.ID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to count credentials"})
+	}
+	if reached {
+		return c.JSON(http.StatusConflict, map[string]string{
+			"error": i18n.TData(c.Request().Context(), "error_max_credentials", map[string]any{"Max": h.webauthn.MaxCredentialsPerUser()}),
+		})
+	}
+
 	// Begin registration for existing user
 	options, sessionData, err := h.webauthn.WebAuthn().BeginRegistration(user)
 	if err != nil {
@@ -382,12 +754,29 @@ func (h *AuthHandlers) AddCredentialBegin(c echo.Context) error {
 	})
 }
 
+// credentialLimitReached reports whether the user already has the maximum
+// number of passkeys allowed by the configuration.
+func (h *AuthHandlers) credentialLimitReached(ctx context.Context, userID int64) (bool, error) {
+	limit := h.webauthn.MaxCredentialsPerUser()
+	if limit <= 0 {
+		return false, nil
+	}
+	count, err := h.repo.CountUserCredentials(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return count >= int64(limit), nil
+}
+
 // AddCredentialFinish completes adding a new credential.
 func (h *AuthHandlers) AddCredentialFinish(c echo.Context) error {
 	cc, ok := c.(*appcontext.Context)
 	if !ok || !cc.IsAuthenticated() {
 		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "not authenticated"})
 	}
+	if cc.IsImpersonating() {
+		return impersonationForbidden(c)
+	}
 	user := cc.GetUser()
 
 	// Get session data
@@ -419,6 +808,11 @@ func (h *AuthHandlers) AddCredentialFinish(c echo.Context) error {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to store credential"})
 	}
 
+	h.webhooks.Notify(webhook.EventCredentialAdded, map[string]any{
+		"user_id":       user.ID,
+		"credential_id": dbCred.ID,
+	})
+
 	return c.JSON(http.StatusOK, map[string]string{"status": "ok"})
 }
 
@@ -428,6 +822,9 @@ func (h *AuthHandlers) DeleteCredential(c echo.Context) error {
 	if !ok || !cc.IsAuthenticated() {
 		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "not authenticated"})
 	}
+	if cc.IsImpersonating() {
+		return impersonationForbidden(c)
+	}
 	user := cc.GetUser()
 
 	credID, err := strconv.ParseInt(c.Param("id"), 10, 64)
@@ -452,9 +849,71 @@ func (h *AuthHandlers) DeleteCredential(c echo.Context) error {
 	return c.JSON(http.StatusOK, map[string]string{"status": "ok"})
 }
 
+// RevokeOtherCredentials deletes all of the user's passkeys except the one
+// the current session was signed in with. Returns {"status": "ok", "removed": n}.
+func (h *AuthHandlers) RevokeOtherCredentials(c echo.Context) error {
+	cc, ok := c.(*appcontext.Context)
+	if !ok || !cc.IsAuthenticated() {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "not authenticated"})
+	}
+	if cc.IsImpersonating() {
+		return impersonationForbidden(c)
+	}
+	user := cc.GetUser()
+
+	// Sessions from recovery codes or email verification have no passkey to keep
+	current, err := h.store.Parse(c.Request())
+	if err != nil || current == nil || current.CredentialID == 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "sign in with a passkey to revoke the others"})
+	}
+
+	removed, err := h.repo.DeleteCredentialsExcept(c.Request().Context(), user.ID, current.CredentialID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to delete credentials"})
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{"status": "ok", "removed": removed})
+}
+
+// SignOutEverywhere invalidates all of the user's sessions. The current
+// session is renewed with the new session version and stays signed in.
+func (h *AuthHandlers) SignOutEverywhere(c echo.Context) error {
+	cc, ok := c.(*appcontext.Context)
+	if !ok || !cc.IsAuthenticated() {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "not authenticated"})
+	}
+	if cc.IsImpersonating() {
+		return impersonationForbidden(c)
+	}
+	user := cc.GetUser()
+
+	current, err := h.store.Parse(c.Request())
+	if err != nil || current == nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "not authenticated"})
+	}
+
+	ctx := c.Request().Context()
+	if err = h.store.Invalidate(ctx, user.ID); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "database error"})
+	}
+	updated, err := h.repo.GetUserByID(ctx, user.ID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "database error"})
+	}
+
+	current.Version = updated.SessionVersion
+	cookie, err := h.store.Renew(ctx, current)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to create session"})
+	}
+	c.SetCookie(cookie)
+
+	return c.JSON(http.StatusOK, map[string]string{"status": "ok"})
+}
+
 // RecoveryPage renders the recovery login page.
 func (h *AuthHandlers) RecoveryPage(c echo.Context) error {
-	return Render(c, http.StatusOK, authtpl.Recovery())
+	return h.pages.Page(c, http.StatusOK, "recovery_title", authtpl.Recovery())
 }
 
 // RecoveryLoginRequest is the request body for recovery login.
@@ -463,64 +922,114 @@ type RecoveryLoginRequest struct {
 	Code     string `json:"code" form:"code"`
 }
 
-// RecoveryLogin authenticates a user with a recovery code.
+// RecoveryLogin authenticates a user with a recovery code. The response
+// flags low_codes once recovery.LowCount or fewer unused codes are left, so
+// the page can ask the user to generate new ones.
 func (h *AuthHandlers) RecoveryLogin(c echo.Context) error {
+	_, remaining, aerr := h.recoveryLogin(c)
+	if aerr != nil {
+		return writeAuthError(c, aerr)
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"status":          "ok",
+		"remaining_codes": remaining,
+		"low_codes":       remaining <= recovery.LowCount,
+	})
+}
+
+// recoveryLogin validates a recovery code, sets the session cookie and returns
+// the user together with the number of unused codes left.
+func (h *AuthHandlers) recoveryLogin(c echo.Context) (*models.User, int64, *authError) {
 	var req RecoveryLoginRequest
 	if err := c.Bind(&req); err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid request"})
+		return nil, 0, newAuthError(http.StatusBadRequest, ErrCodeInvalidRequest, "auth_error_invalid_request")
 	}
+	req.Username = h.normalizeUsername(req.Username)
 
 	if req.Username == "" || req.Code == "" {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "username and code are required"})
+		return nil, 0, newAuthError(http.StatusBadRequest, ErrCodeInvalidRequest, "auth_error_recovery_fields_required")
 	}
 
+	ctx := c.Request().Context()
+	client := clientKey(c.RealIP())
+	invalid := newAuthError(http.StatusUnauthorized, ErrCodeInvalidCredentials, "auth_error_invalid_credentials")
+
 	// Find user
-	user, err := h.repo.GetUserByUsername(c.Request().Context(), req.Username)
+	user, err := h.repo.GetUserByUsername(ctx, req.Username)
 	if err != nil {
-		// Don't reveal if user exists or not
-		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "invalid username or recovery code"})
+		// Don't reveal if user exists or not, including through the lockout
+		if h.lockoutEnabled() {
+			now := h.clock.Now()
+			if h.decoys.count(req.Username, client, now, h.lockoutWindow()) >= h.authCfg.LockoutThreshold {
+				return nil, 0, h.lockoutError()
+			}
+			h.decoys.record(req.Username, client, now, h.lockoutWindow())
+		}
+		return nil, 0, invalid
+	}
+
+	// Check the lockout before touching the codes. It is kept per client, so
+	// guessing codes for a known username only locks out the guesser.
+	locked, err := h.isLockedOut(ctx, user.ID, client)
+	if err != nil {
+		return nil, 0, newAuthError(http.StatusInternalServerError, ErrCodeInternal, "auth_error_internal")
+	}
+	if locked {
+		return nil, 0, h.lockoutError()
 	}
 
 	// Normalize and validate recovery code
 	normalizedCode := recovery.NormalizeCode(req.Code)
-	valid, err := h.repo.ValidateAndUseRecoveryCode(c.Request().Context(), user.ID, normalizedCode)
+	valid, err := h.repo.ValidateAndUseRecoveryCode(ctx, user.ID, normalizedCode)
 	if err != nil {
 		slog.Error("failed to validate recovery code", "error", err)
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "validation error"})
+		return nil, 0, newAuthError(http.StatusInternalServerError, ErrCodeInternal, "auth_error_internal")
 	}
 	if !valid {
-		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "invalid username or recovery code"})
+		h.recordFailedLogin(ctx, user.ID, client)
+		return nil, 0, invalid
 	}
+	h.resetFailedLogins(ctx, user.ID, client)
 
 	// Create session cookie
-	cookie, err := h.sessions.Create(user.ID, user.Username)
+	cookie, err := h.newSession(ctx, user, 0, h.sessions.Duration())
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to create session"})
+		return nil, 0, newAuthError(http.StatusInternalServerError, ErrCodeInternal, "auth_error_create_session")
 	}
 	c.SetCookie(cookie)
+	if err := h.repo.RecordLogin(ctx, user.ID); err != nil {
+		slog.Error("failed to record login", "error", err, "user_id", user.ID)
+	}
 
 	// Get remaining codes count for warning
-	remaining, _ := h.repo.GetUnusedRecoveryCodeCount(c.Request().Context(), user.ID)
+	remaining, _ := h.repo.GetUnusedRecoveryCodeCount(ctx, user.ID)
 
-	return c.JSON(http.StatusOK, map[string]any{
-		"status":          "ok",
-		"remaining_codes": remaining,
-	})
+	return user, remaining, nil
+}
+
+// RecoveryCodesResponse is the JSON envelope returned to JSON clients after
+// regenerating recovery codes.
+type RecoveryCodesResponse struct {
+	GeneratedAt         time.Time `json:"generated_at"`
+	Codes               []string  `json:"codes"`
+	Count               int       `json:"count"`
+	PreviousInvalidated bool      `json:"previous_invalidated"`
+	DownloadToken       string    `json:"download_token,omitempty"` // For DownloadRecoveryCodes, valid for a few minutes
 }
 
 // RegenerateRecoveryCodes generates new recovery codes and invalidates old ones.
+// JSON clients (Accept: application/json) receive the codes directly in a
+// RecoveryCodesResponse; browser flows are redirected to the recovery codes page.
 func (h *AuthHandlers) RegenerateRecoveryCodes(c echo.Context) error {
 	cc, ok := c.(*appcontext.Context)
 	if !ok || !cc.IsAuthenticated() {
 		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "not authenticated"})
 	}
-	user := cc.GetUser()
-
-	// Delete old codes
-	if err := h.repo.DeleteRecoveryCodes(c.Request().Context(), user.ID); err != nil {
-		slog.Error("failed to delete old recovery codes", "error", err)
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to regenerate codes"})
+	if cc.IsImpersonating() {
+		return impersonationForbidden(c)
 	}
+	user := cc.GetUser()
 
 	// Generate new codes
 	codes, hashes, err := h.recovery.GenerateCodes(recovery.CodeCount)
@@ -529,14 +1038,44 @@ func (h *AuthHandlers) RegenerateRecoveryCodes(c echo.Context) error {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to generate codes"})
 	}
 
-	// Store new codes
-	if createErr := h.repo.CreateRecoveryCodes(c.Request().Context(), user.ID, hashes); createErr != nil {
-		slog.Error("failed to store recovery codes", "error", createErr)
+	// Replace old codes atomically so a failure never leaves the user without codes
+	var hadCodes bool
+	err = h.repo.WithTx(c.Request().Context(), func(tx *repository.Repository) error {
+		var txErr error
+		if hadCodes, txErr = tx.HasRecoveryCodes(c.Request().Context(), user.ID); txErr != nil {
+			return txErr
+		}
+		if txErr = tx.DeleteRecoveryCodes(c.Request().Context(), user.ID); txErr != nil {
+			return txErr
+		}
+		return tx.CreateRecoveryCodes(c.Request().Context(), user.ID, hashes)
+	})
+	if err != nil {
+		slog.Error("failed to replace recovery codes", "error", err)
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to store codes"})
 	}
 
+	h.webhooks.Notify(webhook.EventRecoveryCodesRegenerated, map[string]any{
+		"user_id": user.ID,
+		"count":   len(codes),
+	})
+
+	if WantsJSON(c) {
+		token, tokenErr := h.downloads.put(user.ID, codes, h.clock.Now())
+		if tokenErr != nil {
+			slog.Error("failed to create recovery codes download", "error", tokenErr)
+		}
+		return c.JSON(http.StatusOK, RecoveryCodesResponse{
+			DownloadToken:       token,
+			Codes:               codes,
+			Count:               len(codes),
+			GeneratedAt:         h.clock.Now().UTC(),
+			PreviousInvalidated: hadCodes,
+		})
+	}
+
 	// Store codes in flash cookie for display on next page
-	flashCookie, err := h.sessions.SetFlash(&session.FlashData{RecoveryCodes: codes})
+	flashCookie, err := h.sessions.SetFlash(h.recoveryCodesFlash(user.ID, codes))
 	if err != nil {
 		slog.Error("failed to create flash cookie", "error", err)
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to store codes"})
@@ -561,65 +1100,81 @@ func (h *AuthHandlers) RecoveryCodesPage(c echo.Context) error {
 	// Clear flash cookie
 	c.SetCookie(h.sessions.ClearFlash())
 
-	return Render(c, http.StatusOK, authtpl.RecoveryCodes(flash.RecoveryCodes))
+	return h.pages.Page(c, http.StatusOK, "recovery_codes_title", authtpl.RecoveryCodes(flash.RecoveryCodes, flash.DownloadToken))
+}
+
+// recoveryCodesFlash prepares the flash data for the recovery codes page,
+// including a token to download the codes. Without a token the page only
+// offers copying them.
+func (h *AuthHandlers) recoveryCodesFlash(userID int64, codes []string) *session.FlashData {
+	token, err := h.downloads.put(userID, codes, h.clock.Now())
+	if err != nil {
+		slog.Error("failed to create recovery codes download", "error", err)
+	}
+	return &session.FlashData{RecoveryCodes: codes, DownloadToken: token}
 }
 
 // VerifyPendingPage renders the "check your email" page.
 func (h *AuthHandlers) VerifyPendingPage(c echo.Context) error {
-	return Render(c, http.StatusOK, authtpl.VerifyPending())
+	return h.pages.Page(c, http.StatusOK, "verify_pending_title", authtpl.VerifyPending())
 }
 
 // VerifyEmail handles the email verification link.
 func (h *AuthHandlers) VerifyEmail(c echo.Context) error {
 	token := c.QueryParam("token")
 	if token == "" {
-		return Render(c, http.StatusBadRequest, authtpl.VerifyError("missing_token"))
+		return h.pages.Page(c, http.StatusBadRequest, "verify_error_title", authtpl.VerifyError("missing_token"))
 	}
 
 	ctx := c.Request().Context()
 
-	// Hash the token to look it up
-	tokenHash := email.HashToken(token)
-
-	// Find the token
-	verificationToken, err := h.repo.GetEmailVerificationToken(ctx, tokenHash)
-	if err != nil {
-		slog.Error("verification token not found", "error", err)
-		return Render(c, http.StatusBadRequest, authtpl.VerifyError("invalid_token"))
-	}
-
-	// Check if token is expired
-	if time.Now().After(verificationToken.ExpiresAt) {
-		// Delete expired token
-		_ = h.repo.DeleteEmailVerificationToken(ctx, verificationToken.ID)
-		return Render(c, http.StatusBadRequest, authtpl.VerifyError("token_expired"))
-	}
-
-	// Mark email as verified
-	if markErr := h.repo.MarkEmailVerified(ctx, verificationToken.UserID); markErr != nil {
-		slog.Error("failed to mark email as verified", "error", markErr)
-		return Render(c, http.StatusInternalServerError, authtpl.VerifyError("verification_failed"))
+	// Consuming the token, marking the user verified and removing their other
+	// tokens happen in one transaction, so a token works exactly once even
+	// when the link is opened twice at the same time.
+	var verificationToken *models.EmailVerificationToken
+	expired := false
+	err := h.repo.WithTx(ctx, func(tx *repository.Repository) error {
+		var txErr error
+		verificationToken, txErr = tx.ConsumeEmailVerificationToken(ctx, email.HashToken(token))
+		if txErr != nil {
+			return txErr
+		}
+		// The expired token stays deleted
+		if h.clock.Now().After(verificationToken.ExpiresAt) {
+			expired = true
+			return nil
+		}
+		if txErr = tx.MarkEmailVerified(ctx, verificationToken.UserID); txErr != nil {
+			return txErr
+		}
+		return tx.DeleteUserEmailVerificationTokens(ctx, verificationToken.UserID)
+	})
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return h.pages.Page(c, http.StatusBadRequest, "verify_error_title", authtpl.VerifyError("invalid_token"))
+	case err != nil:
+		slog.Error("failed to verify email", "error", err)
+		return h.pages.Page(c, http.StatusInternalServerError, "verify_error_title", authtpl.VerifyError("verification_failed"))
+	case expired:
+		return h.pages.Page(c, http.StatusBadRequest, "verify_error_title", authtpl.VerifyError("token_expired"))
 	}
 
-	// Delete all verification tokens for this user
-	_ = h.repo.DeleteUserEmailVerificationTokens(ctx, verificationToken.UserID)
-
 	// Get user for session creation
 	user, err := h.repo.GetUserByID(ctx, verificationToken.UserID)
 	if err != nil {
 		slog.Error("failed to get user after verification", "error", err)
-		return Render(c, http.StatusInternalServerError, authtpl.VerifyError("verification_failed"))
+		return h.pages.Page(c, http.StatusInternalServerError, "verify_error_title", authtpl.VerifyError("verification_failed"))
 	}
 
 	// Create session
-	sessionCookie, err := h.sessions.Create(user.ID, user.Username)
+	sessionCookie, err := h.newSession(ctx, user, 0, h.sessions.Duration())
 	if err != nil {
 		slog.Error("failed to create session after verification", "error", err)
-		return Render(c, http.StatusInternalServerError, authtpl.VerifyError("verification_failed"))
+		return h.pages.Page(c, http.StatusInternalServerError, "verify_error_title", authtpl.VerifyError("verification_failed"))
 	}
 	c.SetCookie(sessionCookie)
 
-	return Render(c, http.StatusOK, authtpl.VerifySuccess())
+	return h.pages.Page(c, http.StatusOK, "verify_success_title", authtpl.VerifySuccess())
 }
 
 // ResendVerificationRequest is the request body for resending verification email.
@@ -633,6 +1188,7 @@ func (h *AuthHandlers) ResendVerification(c echo.Context) error {
 	if err := c.Bind(&req); err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid request"})
 	}
+	req.Email = h.normalizeEmail(req.Email)
 
 	if req.Email == "" {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "email is required"})
@@ -677,3 +1233,135 @@ func (h *AuthHandlers) ResendVerification(c echo.Context) error {
 
 	return c.JSON(http.StatusOK, map[string]string{"status": "ok"})
 }
+
+// ChangeEmailRequest is the request body for starting an email change.
+type ChangeEmailRequest struct {
+	Email string `json:"email" form:"email"`
+}
+
+// ChangeEmailBegin starts an email change for the current user. The new
+// address only replaces the old one once the link sent to it is confirmed.
+func (h *AuthHandlers) ChangeEmailBegin(c echo.Context) error {
+	cc, ok := c.(*appcontext.Context)
+	if !ok || !cc.IsAuthenticated() {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "not authenticated"})
+	}
+	if cc.IsImpersonating() {
+		return impersonationForbidden(c)
+	}
+	user := cc.GetUser()
+
+	if h.email == nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "email is not enabled"})
+	}
+
+	var req ChangeEmailRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid request"})
+	}
+
+	newEmail := h.normalizeEmail(req.Email)
+	if newEmail == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "email is required"})
+	}
+	if !auth.ValidEmail(newEmail) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid email address"})
+	}
+
+	ctx := c.Request().Context()
+
+	exists, err := h.repo.EmailExists(ctx, newEmail)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "database error"})
+	}
+	if exists {
+		return c.JSON(http.StatusConflict, map[string]string{"error": "email already registered"})
+	}
+
+	plainToken, tokenHash, expiresAt, err := h.email.GenerateToken()
+	if err != nil {
+		slog.Error("failed to generate email change token", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to send confirmation email"})
+	}
+
+	// Only the most recent request stays valid
+	err = h.repo.WithTx(ctx, func(tx *repository.Repository) error {
+		if txErr := tx.DeleteUserPendingEmailChanges(ctx, user.ID); txErr != nil {
+			return txErr
+		}
+		return tx.CreatePendingEmailChange(ctx, user.ID, newEmail, tokenHash, expiresAt)
+	})
+	if err != nil {
+		slog.Error("failed to store email change", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to send confirmation email"})
+	}
+
+	// Send confirmation to the new address only (async)
+	go func() {
+		if sendErr := h.email.SendEmailChange(context.WithoutCancel(ctx), newEmail, plainToken); sendErr != nil {
+			slog.Error("failed to send email change confirmation", "error", sendErr, "email", newEmail)
+		}
+	}()
+
+	return c.JSON(http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// ChangeEmailConfirm applies a pending email change from the confirmation link.
+func (h *AuthHandlers) ChangeEmailConfirm(c echo.Context) error {
+	token := c.QueryParam("token")
+	if token == "" {
+		return h.pages.Page(c, http.StatusBadRequest, "verify_error_title", authtpl.VerifyError("missing_token"))
+	}
+
+	ctx := c.Request().Context()
+
+	// Looking up, checking and consuming the token share one transaction, so
+	// a token is applied at most once and the address can't be taken in between
+	var change *models.PendingEmailChange
+	failure := ""
+	err := h.repo.WithTx(ctx, func(tx *repository.Repository) error {
+		var txErr error
+		change, txErr = tx.GetPendingEmailChange(ctx, email.HashToken(token))
+		if txErr != nil {
+			failure = "invalid_token"
+			return txErr
+		}
+
+		if h.clock.Now().After(change.ExpiresAt) {
+			failure = "token_expired"
+			return tx.DeleteUserPendingEmailChanges(ctx, change.UserID)
+		}
+
+		// The address may have been taken since the change was requested
+		exists, txErr := tx.EmailExists(ctx, change.NewEmail)
+		if txErr != nil {
+			return txErr
+		}
+		if exists {
+			failure = "email_taken"
+			return tx.DeleteUserPendingEmailChanges(ctx, change.UserID)
+		}
+
+		if txErr = tx.UpdateUserEmail(ctx, change.UserID, change.NewEmail); txErr != nil {
+			return txErr
+		}
+		return tx.DeleteUserPendingEmailChanges(ctx, change.UserID)
+	})
+	switch {
+	case failure == "invalid_token":
+		return h.pages.Page(c, http.StatusBadRequest, "verify_error_title", authtpl.VerifyError("invalid_token"))
+	case err != nil:
+		slog.Error("failed to apply email change", "error", err)
+		return h.pages.Page(c, http.StatusInternalServerError, "verify_error_title", authtpl.VerifyError("verification_failed"))
+	case failure == "token_expired":
+		return h.pages.Page(c, http.StatusBadRequest, "verify_error_title", authtpl.VerifyError("token_expired"))
+	case failure == "email_taken":
+		return h.pages.Page(c, http.StatusConflict, "verify_error_title", authtpl.VerifyError("invalid_token"))
+	}
+
+	h.webhooks.Notify(webhook.EventEmailChanged, map[string]any{
+		"user_id": change.UserID,
+	})
+
+	return h.pages.Page(c, http.StatusOK, "verify_success_title", authtpl.VerifySuccess())
+}
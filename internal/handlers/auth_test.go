@@ -5,19 +5,24 @@ package handlers_test
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/oliverandrich/go-webapp-template/internal/appcontext"
+	"github.com/oliverandrich/go-webapp-template/internal/clock"
 	"github.com/oliverandrich/go-webapp-template/internal/config"
 	"github.com/oliverandrich/go-webapp-template/internal/handlers"
 	"github.com/oliverandrich/go-webapp-template/internal/i18n"
 	"github.com/oliverandrich/go-webapp-template/internal/models"
 	"github.com/oliverandrich/go-webapp-template/internal/repository"
+	"github.com/oliverandrich/go-webapp-template/internal/services/email"
 	"github.com/oliverandrich/go-webapp-template/internal/services/session"
 	"github.com/oliverandrich/go-webapp-template/internal/services/webauthn"
 	"github.com/oliverandrich/go-webapp-template/internal/testutil"
@@ -38,13 +43,24 @@ func newTestContext(e *echo.Echo, req *http.Request, rec *httptest.ResponseRecor
 const testHashKey = "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
 
 func newTestAuthHandlers(t *testing.T) (*handlers.AuthHandlers, *repository.Repository) {
+	t.Helper()
+	return newTestAuthHandlersWithMaxCredentials(t, 0)
+}
+
+func newTestAuthHandlersWithMaxCredentials(t *testing.T, maxCredentials int) (*handlers.AuthHandlers, *repository.Repository) {
+	t.Helper()
+	return newTestAuthHandlersWithConfig(t, maxCredentials, &config.AuthConfig{UseEmail: false})
+}
+
+func newTestAuthHandlersWithConfig(t *testing.T, maxCredentials int, authCfg *config.AuthConfig) (*handlers.AuthHandlers, *repository.Repository) {
 	t.Helper()
 	_, repo := testutil.NewTestDB(t)
 
 	waSvc, err := webauthn.NewService(&config.WebAuthnConfig{
-		RPID:          "localhost",
-		RPOrigin:      "http://localhost:8080",
-		RPDisplayName: "Test App",
+		RPID:                  "localhost",
+		RPOrigin:              "http://localhost:8080",
+		RPDisplayName:         "Test App",
+		MaxCredentialsPerUser: maxCredentials,
 	})
 	require.NoError(t, err)
 
@@ -55,8 +71,8 @@ func newTestAuthHandlers(t *testing.T) (*handlers.AuthHandlers, *repository.Repo
 	}, false)
 	require.NoError(t, err)
 
-	// Use nil email service and default auth config (username mode)
-	h := handlers.NewAuth(repo, waSvc, sessMgr, nil, &config.AuthConfig{UseEmail: false})
+	// Use nil email service
+	h := handlers.NewAuth(repo, waSvc, sessMgr, nil, authCfg)
 	return h, repo
 }
 
@@ -114,7 +130,39 @@ func TestRegisterBegin_MissingUsername(t *testing.T) {
 
 	require.NoError(t, err)
 	assert.Equal(t, http.StatusBadRequest, rec.Code)
-	assert.Contains(t, rec.Body.String(), "username is required")
+	assert.Contains(t, rec.Body.String(), "Username is required.")
+}
+
+func TestRegisterBegin_InvalidUsername(t *testing.T) {
+	h, repo := newTestAuthHandlersWithConfig(t, 0, &config.AuthConfig{UsernameMaxLength: 16})
+
+	tests := map[string]string{
+		strings.Repeat("a", 17): "Username must be at most 16 characters long.",
+		"bad\\u0007name":        "Username may only contain letters, digits, dots, underscores and hyphens.", // JSON escape of a control character
+		"alice smith":           "Username may only contain letters, digits, dots, underscores and hyphens.",
+		" alice":                "Username must not start or end with a space.",
+		"alice\\t":              "Username must not start or end with a space.", // JSON escape of a tab
+	}
+	for username, want := range tests {
+		rec := registerBegin(t, h, username)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code, username)
+		var resp map[string]string
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		assert.Equal(t, want, resp["error"], username)
+	}
+
+	users, err := repo.SearchUsers(context.Background(), "", 0)
+	require.NoError(t, err)
+	assert.Empty(t, users)
+}
+
+func TestRegisterBegin_UsernameAtMaxLength(t *testing.T) {
+	h, _ := newTestAuthHandlersWithConfig(t, 0, &config.AuthConfig{UsernameMaxLength: 16})
+
+	rec := registerBegin(t, h, strings.Repeat("a", 16))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
 }
 
 func TestRegisterBegin_UsernameExists(t *testing.T) {
@@ -138,6 +186,52 @@ func TestRegisterBegin_UsernameExists(t *testing.T) {
 	assert.Contains(t, rec.Body.String(), "username already taken")
 }
 
+func TestRegisterBegin_UsernameExistsSuggestions(t *testing.T) {
+	h, repo := newTestAuthHandlers(t)
+
+	testutil.NewTestUser(t, repo, "alice")
+	testutil.NewTestUser(t, repo, "alice2")
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/auth/register/begin", strings.NewReader(`{"username":"alice"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	err := h.RegisterBegin(c)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	var resp struct {
+		Error       string   `json:"error"`
+		Suggestions []string `json:"suggestions"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "username already taken", resp.Error)
+	assert.Equal(t, []string{"alice_", "alice3", "alice_2"}, resp.Suggestions)
+}
+
+func TestRegisterBegin_SuggestionsRespectMaxLength(t *testing.T) {
+	h, repo := newTestAuthHandlersWithConfig(t, 0, &config.AuthConfig{UsernameMaxLength: 6})
+
+	testutil.NewTestUser(t, repo, "alice")
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/auth/register/begin", strings.NewReader(`{"username":"alice"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+
+	require.NoError(t, h.RegisterBegin(e.NewContext(req, rec)))
+
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	var resp struct {
+		Suggestions []string `json:"suggestions"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	// Every suggestion can be registered as is
+	assert.Equal(t, []string{"alice2", "alice_", "alice3"}, resp.Suggestions)
+}
+
 func TestRegisterFinish_InvalidUserID(t *testing.T) {
 	h, _ := newTestAuthHandlers(t)
 
@@ -217,7 +311,7 @@ func TestLoginFinish_MissingSessionID(t *testing.T) {
 
 	require.NoError(t, err)
 	assert.Equal(t, http.StatusBadRequest, rec.Code)
-	assert.Contains(t, rec.Body.String(), "session_id is required")
+	assert.Contains(t, rec.Body.String(), "The session_id parameter is required.")
 }
 
 func TestLoginFinish_SessionExpired(t *testing.T) {
@@ -232,7 +326,7 @@ func TestLoginFinish_SessionExpired(t *testing.T) {
 
 	require.NoError(t, err)
 	assert.Equal(t, http.StatusBadRequest, rec.Code)
-	assert.Contains(t, rec.Body.String(), "login session expired")
+	assert.Contains(t, rec.Body.String(), "The login session has expired.")
 }
 
 func TestLogout(t *testing.T) {
@@ -291,6 +385,40 @@ func TestCredentialsPage_Authenticated(t *testing.T) {
 	assert.Contains(t, rec.Body.String(), "<!doctype html>")
 }
 
+func TestChangePassword_Redirect(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      *config.AuthConfig
+		wantPath string
+	}{
+		{"username mode", &config.AuthConfig{UseEmail: false}, "/auth/credentials"},
+		{"email mode", &config.AuthConfig{UseEmail: true, RequireVerification: true}, "/auth/credentials"},
+		{"configured target", &config.AuthConfig{UseEmail: true, ChangePasswordURL: "/account/security"}, "/account/security"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var h *handlers.AuthHandlers
+			if tt.cfg.UseEmail {
+				h, _ = newTestEmailAuthHandlersWithConfig(t, tt.cfg)
+			} else {
+				h, _ = newTestAuthHandlersWithConfig(t, 0, tt.cfg)
+			}
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/.well-known/change-password", nil)
+			rec := httptest.NewRecorder()
+			c := newTestContext(e, req, rec, nil)
+
+			err := h.ChangePassword(c)
+
+			require.NoError(t, err)
+			assert.Equal(t, http.StatusFound, rec.Code)
+			assert.Equal(t, tt.wantPath, rec.Header().Get("Location"))
+		})
+	}
+}
+
 func TestAddCredentialBegin_Unauthenticated(t *testing.T) {
 	h, _ := newTestAuthHandlers(t)
 
@@ -323,6 +451,43 @@ func TestAddCredentialBegin_Authenticated(t *testing.T) {
 	assert.Contains(t, rec.Body.String(), "publicKey")
 }
 
+func TestAddCredentialBegin_BelowLimit(t *testing.T) {
+	h, repo := newTestAuthHandlersWithMaxCredentials(t, 2)
+
+	user := testutil.NewTestUser(t, repo, "testuser")
+	testutil.NewTestCredential(t, repo, user.ID, "cred-1")
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/auth/credentials/begin", nil)
+	rec := httptest.NewRecorder()
+	c := newTestContext(e, req, rec, user)
+
+	err := h.AddCredentialBegin(c)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "publicKey")
+}
+
+func TestAddCredentialBegin_AtLimit(t *testing.T) {
+	h, repo := newTestAuthHandlersWithMaxCredentials(t, 2)
+
+	user := testutil.NewTestUser(t, repo, "testuser")
+	testutil.NewTestCredential(t, repo, user.ID, "cred-1")
+	testutil.NewTestCredential(t, repo, user.ID, "cred-2")
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/auth/credentials/begin", nil)
+	rec := httptest.NewRecorder()
+	c := newTestContext(e, req, rec, user)
+
+	err := h.AddCredentialBegin(c)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Contains(t, rec.Body.String(), "maximum of 2 passkeys")
+}
+
 func TestAddCredentialFinish_Unauthenticated(t *testing.T) {
 	h, _ := newTestAuthHandlers(t)
 
@@ -507,7 +672,7 @@ func TestLoginFinish_EmptySessionID(t *testing.T) {
 
 	require.NoError(t, err)
 	assert.Equal(t, http.StatusBadRequest, rec.Code)
-	assert.Contains(t, rec.Body.String(), "session_id is required")
+	assert.Contains(t, rec.Body.String(), "The session_id parameter is required.")
 }
 
 func TestAddCredentialBegin_Success(t *testing.T) {
@@ -550,8 +715,21 @@ func TestCredentialsPage_NoCredentials(t *testing.T) {
 // Email mode tests
 
 func newTestEmailAuthHandlers(t *testing.T) (*handlers.AuthHandlers, *repository.Repository) {
+	t.Helper()
+	return newTestEmailAuthHandlersWithConfig(t, &config.AuthConfig{
+		UseEmail:            true,
+		RequireVerification: true,
+	})
+}
+
+func newTestEmailAuthHandlersWithConfig(t *testing.T, authCfg *config.AuthConfig) (*handlers.AuthHandlers, *repository.Repository) {
 	t.Helper()
 	_, repo := testutil.NewTestDB(t)
+	return newTestEmailAuthHandlersWithRepo(t, repo, authCfg), repo
+}
+
+func newTestEmailAuthHandlersWithRepo(t *testing.T, repo *repository.Repository, authCfg *config.AuthConfig) *handlers.AuthHandlers {
+	t.Helper()
 
 	waSvc, err := webauthn.NewService(&config.WebAuthnConfig{
 		RPID:          "localhost",
@@ -568,11 +746,7 @@ func newTestEmailAuthHandlers(t *testing.T) (*handlers.AuthHandlers, *repository
 	require.NoError(t, err)
 
 	// Email mode enabled, but without email service (for unit testing handlers)
-	h := handlers.NewAuth(repo, waSvc, sessMgr, nil, &config.AuthConfig{
-		UseEmail:            true,
-		RequireVerification: true,
-	})
-	return h, repo
+	return handlers.NewAuth(repo, waSvc, sessMgr, nil, authCfg)
 }
 
 func TestUseEmailMode_Enabled(t *testing.T) {
@@ -620,6 +794,24 @@ func TestRegisterBegin_EmailMode_MissingEmail(t *testing.T) {
 	assert.Contains(t, rec.Body.String(), "email is required")
 }
 
+func TestRegisterBegin_EmailMode_InvalidEmail(t *testing.T) {
+	h, repo := newTestEmailAuthHandlers(t)
+
+	e := echo.New()
+	body := strings.NewReader(`{"email":"Alice <alice@example.com>"}`)
+	req := httptest.NewRequest(http.MethodPost, "/auth/register/begin", body)
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+
+	require.NoError(t, h.RegisterBegin(e.NewContext(req, rec)))
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "invalid email address")
+	users, err := repo.SearchUsers(context.Background(), "", 0)
+	require.NoError(t, err)
+	assert.Empty(t, users)
+}
+
 func TestRegisterBegin_EmailMode_EmailExists(t *testing.T) {
 	h, repo := newTestEmailAuthHandlers(t)
 
@@ -641,6 +833,64 @@ func TestRegisterBegin_EmailMode_EmailExists(t *testing.T) {
 	assert.Contains(t, rec.Body.String(), "email already registered")
 }
 
+func registerWithEmail(t *testing.T, h *handlers.AuthHandlers, email string) *httptest.ResponseRecorder {
+	t.Helper()
+	e := echo.New()
+	body := strings.NewReader(`{"email":"` + email + `"}`)
+	req := httptest.NewRequest(http.MethodPost, "/auth/register/begin", body)
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+
+	require.NoError(t, h.RegisterBegin(e.NewContext(req, rec)))
+	return rec
+}
+
+func TestRegisterBegin_EmailMode_StoresCanonicalEmail(t *testing.T) {
+	h, repo := newTestEmailAuthHandlers(t)
+
+	rec := registerWithEmail(t, h, "  Test@Example.COM ")
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	user, err := repo.GetUserByEmail(context.Background(), "test@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "test@example.com", user.Username)
+}
+
+func TestRegisterBegin_EmailMode_EmailExistsCaseInsensitive(t *testing.T) {
+	h, repo := newTestEmailAuthHandlers(t)
+	_, err := repo.CreateUserWithEmail(context.Background(), "test@example.com")
+	require.NoError(t, err)
+
+	rec := registerWithEmail(t, h, "Test@Example.com")
+
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Contains(t, rec.Body.String(), "email already registered")
+}
+
+func TestRegisterBegin_EmailMode_GmailAliases(t *testing.T) {
+	t.Run("distinct by default", func(t *testing.T) {
+		h, repo := newTestEmailAuthHandlers(t)
+		_, err := repo.CreateUserWithEmail(context.Background(), "johndoe@gmail.com")
+		require.NoError(t, err)
+
+		rec := registerWithEmail(t, h, "John.Doe+news@gmail.com")
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("collapsed when enabled", func(t *testing.T) {
+		h, repo := newTestEmailAuthHandlersWithConfig(t, &config.AuthConfig{
+			UseEmail:            true,
+			RequireVerification: true,
+			CanonicalizeGmail:   true,
+		})
+		_, err := repo.CreateUserWithEmail(context.Background(), "johndoe@gmail.com")
+		require.NoError(t, err)
+
+		rec := registerWithEmail(t, h, "John.Doe+news@googlemail.com")
+		assert.Equal(t, http.StatusConflict, rec.Code)
+	})
+}
+
 func TestRegisterBegin_EmailMode_EmailOnly(t *testing.T) {
 	h, _ := newTestEmailAuthHandlers(t)
 
@@ -709,6 +959,88 @@ func TestVerifyEmail_InvalidToken(t *testing.T) {
 	assert.Contains(t, rec.Body.String(), "<!doctype html>")
 }
 
+func TestVerifyEmail_TokenExpiry(t *testing.T) {
+	h, repo := newTestEmailAuthHandlers(t)
+	ctx := context.Background()
+	user, err := repo.CreateUserWithEmail(ctx, "user@example.com")
+	require.NoError(t, err)
+
+	now := time.Now()
+	fake := clock.NewFake(now)
+	h.SetClock(fake)
+	require.NoError(t, repo.CreateEmailVerificationToken(ctx, user.ID, email.HashToken("verify-token"), now.Add(24*time.Hour)))
+
+	verify := func() *httptest.ResponseRecorder {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/auth/verify-email?token=verify-token", nil)
+		req = req.WithContext(i18n.WithLocale(req.Context(), language.English))
+		rec := httptest.NewRecorder()
+		require.NoError(t, h.VerifyEmail(e.NewContext(req, rec)))
+		return rec
+	}
+
+	// One second past the deadline the token is rejected and removed
+	fake.Advance(24*time.Hour + time.Second)
+	rec := verify()
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "expired")
+
+	_, err = repo.GetEmailVerificationToken(ctx, email.HashToken("verify-token"))
+	assert.Error(t, err)
+}
+
+func TestVerifyEmail_ValidToken(t *testing.T) {
+	h, repo := newTestEmailAuthHandlers(t)
+	ctx := context.Background()
+	user, err := repo.CreateUserWithEmail(ctx, "user@example.com")
+	require.NoError(t, err)
+
+	now := time.Now()
+	h.SetClock(clock.NewFake(now.Add(24*time.Hour - time.Second)))
+	require.NoError(t, repo.CreateEmailVerificationToken(ctx, user.ID, email.HashToken("verify-token"), now.Add(24*time.Hour)))
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/auth/verify-email?token=verify-token", nil)
+	req = req.WithContext(i18n.WithLocale(req.Context(), language.English))
+	rec := httptest.NewRecorder()
+
+	require.NoError(t, h.VerifyEmail(e.NewContext(req, rec)))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	verified, err := repo.GetUserByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.True(t, verified.EmailVerified)
+}
+
+func TestVerifyEmail_TokenIsSingleUse(t *testing.T) {
+	_, repo := testutil.NewTestFileDB(t)
+	h := newTestEmailAuthHandlersWithRepo(t, repo, &config.AuthConfig{UseEmail: true, RequireVerification: true})
+	ctx := context.Background()
+	user, err := repo.CreateUserWithEmail(ctx, "user@example.com")
+	require.NoError(t, err)
+	require.NoError(t, repo.CreateEmailVerificationToken(ctx, user.ID, email.HashToken("verify-token"), time.Now().Add(time.Hour)))
+
+	const attempts = 2
+	codes := make([]int, attempts)
+	var wg sync.WaitGroup
+	for i := range attempts {
+		wg.Go(func() {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/auth/verify-email?token=verify-token", nil)
+			req = req.WithContext(i18n.WithLocale(req.Context(), language.English))
+			rec := httptest.NewRecorder()
+			assert.NoError(t, h.VerifyEmail(e.NewContext(req, rec)))
+			codes[i] = rec.Code
+		})
+	}
+	wg.Wait()
+
+	assert.ElementsMatch(t, []int{http.StatusOK, http.StatusBadRequest}, codes)
+	verified, err := repo.GetUserByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.True(t, verified.EmailVerified)
+}
+
 func TestResendVerification_MissingEmail(t *testing.T) {
 	h, _ := newTestEmailAuthHandlers(t)
 
@@ -742,3 +1074,371 @@ func TestResendVerification_NonexistentEmail(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, http.StatusOK, rec.Code)
 }
+
+func TestRegenerateRecoveryCodes_JSONEnvelope(t *testing.T) {
+	h, repo := newTestAuthHandlers(t)
+
+	user := testutil.NewTestUser(t, repo, "testuser")
+	require.NoError(t, repo.CreateRecoveryCodes(context.Background(), user.ID, []string{"old-hash-1", "old-hash-2"}))
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/auth/credentials/recovery-codes", nil)
+	req.Header.Set(echo.HeaderAccept, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := newTestContext(e, req, rec, user)
+
+	err := h.RegenerateRecoveryCodes(c)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var resp handlers.RecoveryCodesResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Len(t, resp.Codes, 8)
+	assert.Equal(t, 8, resp.Count)
+	assert.True(t, resp.PreviousInvalidated)
+	assert.False(t, resp.GeneratedAt.IsZero())
+
+	// Raw shape uses the documented keys
+	var raw map[string]any
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &raw))
+	assert.Contains(t, raw, "codes")
+	assert.Contains(t, raw, "count")
+	assert.Contains(t, raw, "generated_at")
+	assert.Contains(t, raw, "previous_invalidated")
+
+	// Old codes were replaced by the new set
+	count, err := repo.GetUnusedRecoveryCodeCount(context.Background(), user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(8), count)
+
+	// No flash cookie for JSON clients
+	assert.Empty(t, rec.Header().Get("Set-Cookie"))
+}
+
+func TestRegenerateRecoveryCodes_BrowserRedirect(t *testing.T) {
+	h, repo := newTestAuthHandlers(t)
+
+	user := testutil.NewTestUser(t, repo, "testuser")
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/auth/credentials/recovery-codes", nil)
+	rec := httptest.NewRecorder()
+	c := newTestContext(e, req, rec, user)
+
+	err := h.RegenerateRecoveryCodes(c)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "/auth/recovery-codes")
+	assert.Contains(t, rec.Header().Get("Set-Cookie"), "flash=")
+}
+
+func newTestEmailChangeHandlers(t *testing.T) (*handlers.AuthHandlers, *repository.Repository) {
+	t.Helper()
+	_, repo := testutil.NewTestDB(t)
+
+	waSvc, err := webauthn.NewService(&config.WebAuthnConfig{
+		RPID:          "localhost",
+		RPOrigin:      "http://localhost:8080",
+		RPDisplayName: "Test App",
+	})
+	require.NoError(t, err)
+
+	sessMgr, err := session.NewManager(&config.SessionConfig{
+		CookieName: "_test_session",
+		MaxAge:     3600,
+		HashKey:    testHashKey,
+	}, false)
+	require.NoError(t, err)
+
+	// Unreachable SMTP server; sending happens asynchronously and just logs the failure
+	emailSvc, err := email.NewService(&config.SMTPConfig{Host: "127.0.0.1", Port: 1, From: "noreply@example.com"}, "http://localhost:8080")
+	require.NoError(t, err)
+
+	h := handlers.NewAuth(repo, waSvc, sessMgr, emailSvc, &config.AuthConfig{UseEmail: true})
+	return h, repo
+}
+
+func TestChangeEmailBegin_Success(t *testing.T) {
+	h, repo := newTestEmailChangeHandlers(t)
+	user, err := repo.CreateUserWithEmail(context.Background(), "old@example.com")
+	require.NoError(t, err)
+
+	e := echo.New()
+	body := strings.NewReader(`{"email":"new@example.com"}`)
+	req := httptest.NewRequest(http.MethodPost, "/auth/email/change", body)
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := newTestContext(e, req, rec, user)
+
+	err = h.ChangeEmailBegin(c)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// Email is unchanged until confirmed
+	unchanged, err := repo.GetUserByID(context.Background(), user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "old@example.com", *unchanged.Email)
+}
+
+func TestChangeEmailBegin_DuplicateEmail(t *testing.T) {
+	h, repo := newTestEmailChangeHandlers(t)
+	ctx := context.Background()
+	user, err := repo.CreateUserWithEmail(ctx, "old@example.com")
+	require.NoError(t, err)
+	_, err = repo.CreateUserWithEmail(ctx, "taken@example.com")
+	require.NoError(t, err)
+
+	e := echo.New()
+	body := strings.NewReader(`{"email":"taken@example.com"}`)
+	req := httptest.NewRequest(http.MethodPost, "/auth/email/change", body)
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := newTestContext(e, req, rec, user)
+
+	err = h.ChangeEmailBegin(c)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Contains(t, rec.Body.String(), "email already registered")
+}
+
+func TestChangeEmailBegin_DuplicateEmailCaseInsensitive(t *testing.T) {
+	h, repo := newTestEmailChangeHandlers(t)
+	ctx := context.Background()
+	user, err := repo.CreateUserWithEmail(ctx, "old@example.com")
+	require.NoError(t, err)
+	_, err = repo.CreateUserWithEmail(ctx, "taken@example.com")
+	require.NoError(t, err)
+
+	e := echo.New()
+	body := strings.NewReader(`{"email":" Taken@Example.com "}`)
+	req := httptest.NewRequest(http.MethodPost, "/auth/email/change", body)
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := newTestContext(e, req, rec, user)
+
+	err = h.ChangeEmailBegin(c)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+}
+
+func TestChangeEmailBegin_InvalidEmail(t *testing.T) {
+	h, repo := newTestEmailChangeHandlers(t)
+	user, err := repo.CreateUserWithEmail(context.Background(), "old@example.com")
+	require.NoError(t, err)
+
+	for _, address := range []string{"not-an-email", "new@example.com, other@example.com"} {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/auth/email/change", strings.NewReader(`{"email":"`+address+`"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+
+		require.NoError(t, h.ChangeEmailBegin(newTestContext(e, req, rec, user)))
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code, address)
+		assert.Contains(t, rec.Body.String(), "invalid email address", address)
+	}
+}
+
+func TestChangeEmailBegin_NotAuthenticated(t *testing.T) {
+	h, _ := newTestEmailChangeHandlers(t)
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/auth/email/change", strings.NewReader(`{"email":"new@example.com"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := newTestContext(e, req, rec, nil)
+
+	err := h.ChangeEmailBegin(c)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestChangeEmailConfirm_Success(t *testing.T) {
+	h, repo := newTestEmailChangeHandlers(t)
+	ctx := context.Background()
+	user, err := repo.CreateUserWithEmail(ctx, "old@example.com")
+	require.NoError(t, err)
+	require.NoError(t, repo.CreatePendingEmailChange(ctx, user.ID, "new@example.com", email.HashToken("change-token"), time.Now().Add(time.Hour)))
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/auth/email/confirm?token=change-token", nil)
+	req = req.WithContext(i18n.WithLocale(req.Context(), language.English))
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	err = h.ChangeEmailConfirm(c)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	updated, err := repo.GetUserByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "new@example.com", *updated.Email)
+	assert.True(t, updated.EmailVerified)
+
+	// The token is single use
+	_, err = repo.GetPendingEmailChange(ctx, email.HashToken("change-token"))
+	assert.Error(t, err)
+}
+
+func TestChangeEmailConfirm_ExpiredToken(t *testing.T) {
+	h, repo := newTestEmailChangeHandlers(t)
+	ctx := context.Background()
+	user, err := repo.CreateUserWithEmail(ctx, "old@example.com")
+	require.NoError(t, err)
+	now := time.Now()
+	require.NoError(t, repo.CreatePendingEmailChange(ctx, user.ID, "new@example.com", email.HashToken("old-token"), now.Add(time.Hour)))
+	h.SetClock(clock.NewFake(now.Add(time.Hour + time.Second)))
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/auth/email/confirm?token=old-token", nil)
+	req = req.WithContext(i18n.WithLocale(req.Context(), language.English))
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	err = h.ChangeEmailConfirm(c)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	unchanged, err := repo.GetUserByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "old@example.com", *unchanged.Email)
+}
+
+func TestChangeEmailConfirm_InvalidToken(t *testing.T) {
+	h, _ := newTestEmailChangeHandlers(t)
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/auth/email/confirm?token=bogus", nil)
+	req = req.WithContext(i18n.WithLocale(req.Context(), language.English))
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	err := h.ChangeEmailConfirm(c)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+// newTestSessionManager returns a session manager that reads and writes the
+// cookies of the handlers created by newTestAuthHandlers.
+func newTestSessionManager(t *testing.T) *session.Manager {
+	t.Helper()
+	sessMgr, err := session.NewManager(&config.SessionConfig{
+		CookieName: "_test_session",
+		MaxAge:     3600,
+		HashKey:    testHashKey,
+	}, false)
+	require.NoError(t, err)
+	return sessMgr
+}
+
+func TestRevokeOtherCredentials_KeepsCurrent(t *testing.T) {
+	h, repo := newTestAuthHandlers(t)
+
+	user := testutil.NewTestUser(t, repo, "testuser")
+	testutil.NewTestCredential(t, repo, user.ID, "cred-1")
+	current := testutil.NewTestCredential(t, repo, user.ID, "cred-2")
+	testutil.NewTestCredential(t, repo, user.ID, "cred-3")
+
+	cookie, err := newTestSessionManager(t).Issue(session.Data{UserID: user.ID, Username: user.Username, CredentialID: current.ID}, time.Hour)
+	require.NoError(t, err)
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/auth/credentials/revoke-others", nil)
+	req.AddCookie(cookie)
+	rec := httptest.NewRecorder()
+	c := newTestContext(e, req, rec, user)
+
+	err = h.RevokeOtherCredentials(c)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"status":"ok","removed":2}`, rec.Body.String())
+
+	creds, err := repo.GetCredentialsByUserID(context.Background(), user.ID)
+	require.NoError(t, err)
+	require.Len(t, creds, 1)
+	assert.Equal(t, current.ID, creds[0].ID)
+}
+
+func TestRevokeOtherCredentials_NoPasskeySession(t *testing.T) {
+	h, repo := newTestAuthHandlers(t)
+
+	user := testutil.NewTestUser(t, repo, "testuser")
+	testutil.NewTestCredential(t, repo, user.ID, "cred-1")
+	testutil.NewTestCredential(t, repo, user.ID, "cred-2")
+
+	// Recovery code sessions carry no credential
+	cookie, err := newTestSessionManager(t).Create(user.ID, user.Username)
+	require.NoError(t, err)
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/auth/credentials/revoke-others", nil)
+	req.AddCookie(cookie)
+	rec := httptest.NewRecorder()
+	c := newTestContext(e, req, rec, user)
+
+	err = h.RevokeOtherCredentials(c)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	count, err := repo.CountUserCredentials(context.Background(), user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+}
+
+func TestRevokeOtherCredentials_Unauthenticated(t *testing.T) {
+	h, _ := newTestAuthHandlers(t)
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/auth/credentials/revoke-others", nil)
+	rec := httptest.NewRecorder()
+	c := newTestContext(e, req, rec, nil)
+
+	err := h.RevokeOtherCredentials(c)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestSignOutEverywhere(t *testing.T) {
+	h, repo := newTestAuthHandlers(t)
+	sessMgr := newTestSessionManager(t)
+
+	user := testutil.NewTestUser(t, repo, "testuser")
+	cookie, err := sessMgr.Create(user.ID, user.Username)
+	require.NoError(t, err)
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/auth/sessions/revoke", nil)
+	req.AddCookie(cookie)
+	rec := httptest.NewRecorder()
+	c := newTestContext(e, req, rec, user)
+
+	err = h.SignOutEverywhere(c)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	updated, err := repo.GetUserByID(context.Background(), user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, updated.SessionVersion)
+
+	// The current session is reissued with the new version
+	cookies := rec.Result().Cookies()
+	require.Len(t, cookies, 1)
+	next := httptest.NewRequest(http.MethodGet, "/", nil)
+	next.AddCookie(cookies[0])
+	data, err := sessMgr.Parse(next)
+	require.NoError(t, err)
+	require.NotNil(t, data)
+	assert.Equal(t, 1, data.Version)
+}
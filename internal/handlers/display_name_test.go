@@ -0,0 +1,145 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/oliverandrich/go-webapp-template/internal/handlers"
+	"github.com/oliverandrich/go-webapp-template/internal/models"
+	"github.com/oliverandrich/go-webapp-template/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// registerBegin starts a registration and returns the recorder.
+func registerBeginJSON(t *testing.T, h *handlers.AuthHandlers, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/auth/register/begin", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	require.NoError(t, h.RegisterBegin(e.NewContext(req, rec)))
+	return rec
+}
+
+// webAuthnUser returns the user entity of a RegisterBegin response.
+func webAuthnUser(t *testing.T, rec *httptest.ResponseRecorder) (name, displayName string) {
+	t.Helper()
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var resp struct {
+		PublicKey struct {
+			User struct {
+				Name        string `json:"name"`
+				DisplayName string `json:"displayName"`
+			} `json:"user"`
+		} `json:"publicKey"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	return resp.PublicKey.User.Name, resp.PublicKey.User.DisplayName
+}
+
+func TestRegisterBegin_DisplayName(t *testing.T) {
+	h, repo := newTestAuthHandlers(t)
+
+	rec := registerBeginJSON(t, h, `{"username":"alice","display_name":"  Alice   Smith "}`)
+
+	name, displayName := webAuthnUser(t, rec)
+	assert.Equal(t, "alice", name)
+	assert.Equal(t, "Alice Smith", displayName)
+	user, err := repo.GetUserByUsername(context.Background(), "alice")
+	require.NoError(t, err)
+	assert.Equal(t, "Alice Smith", user.DisplayName)
+}
+
+func TestRegisterBegin_DisplayNameDefaultsToUsername(t *testing.T) {
+	h, _ := newTestAuthHandlers(t)
+
+	_, displayName := webAuthnUser(t, registerBeginJSON(t, h, `{"username":"alice"}`))
+
+	assert.Equal(t, "alice", displayName)
+}
+
+func TestRegisterBegin_EmailMode_DisplayName(t *testing.T) {
+	h, _ := newTestEmailAuthHandlers(t)
+
+	_, displayName := webAuthnUser(t, registerBeginJSON(t, h, `{"email":"alice@example.com","display_name":"Alice"}`))
+	assert.Equal(t, "Alice", displayName)
+
+	_, displayName = webAuthnUser(t, registerBeginJSON(t, h, `{"email":"bob@example.com"}`))
+	assert.Equal(t, "bob@example.com", displayName)
+}
+
+func TestRegisterBegin_InvalidDisplayName(t *testing.T) {
+	h, repo := newTestAuthHandlers(t)
+
+	rec := registerBeginJSON(t, h, `{"username":"alice","display_name":"`+strings.Repeat("x", 65)+`"}`)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "invalid display name")
+	exists, err := repo.UserExists(context.Background(), "alice")
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func setDisplayName(t *testing.T, h *handlers.Handlers, user *models.User, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/settings/display-name", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	require.NoError(t, h.SetDisplayName(newTestContext(e, req, rec, user)))
+	return rec
+}
+
+func TestSetDisplayName(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	h := handlers.New(repo)
+	user := testutil.NewTestUser(t, repo, "alice")
+
+	rec := setDisplayName(t, h, user, `{"display_name":" Alice Smith "}`)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"display_name":"Alice Smith"}`, rec.Body.String())
+	updated, err := repo.GetUserByID(context.Background(), user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Alice Smith", updated.WebAuthnDisplayName())
+}
+
+func TestSetDisplayName_ResetToUsername(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	h := handlers.New(repo)
+	user := testutil.NewTestUser(t, repo, "alice")
+	require.NoError(t, repo.SetDisplayName(context.Background(), user.ID, "Alice Smith"))
+
+	rec := setDisplayName(t, h, user, `{"display_name":""}`)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"display_name":"alice"}`, rec.Body.String())
+}
+
+func TestSetDisplayName_Invalid(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	h := handlers.New(repo)
+	user := testutil.NewTestUser(t, repo, "alice")
+
+	rec := setDisplayName(t, h, user, `{"display_name":"Alice\u0007"}`)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestSetDisplayName_Unauthenticated(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	h := handlers.New(repo)
+
+	rec := setDisplayName(t, h, nil, `{"display_name":"Alice"}`)
+
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
@@ -0,0 +1,213 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package handlers_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/oliverandrich/go-webapp-template/internal/config"
+	"github.com/oliverandrich/go-webapp-template/internal/handlers"
+	"github.com/oliverandrich/go-webapp-template/internal/i18n"
+	"github.com/oliverandrich/go-webapp-template/internal/repository"
+	"github.com/oliverandrich/go-webapp-template/internal/services/email"
+	"github.com/oliverandrich/go-webapp-template/internal/services/session"
+	"github.com/oliverandrich/go-webapp-template/internal/services/webauthn"
+	"github.com/oliverandrich/go-webapp-template/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wneessen/go-mail"
+)
+
+// newTestEnumerationHandlers returns email mode handlers with a working
+// email service. Recipients of sent messages arrive on the returned channel.
+func newTestEnumerationHandlers(t *testing.T, enumerationSafe bool) (*handlers.AuthHandlers, *repository.Repository, <-chan string) {
+	t.Helper()
+	return newTestEnumerationHandlersWithLimit(t, enumerationSafe, 0)
+}
+
+// newTestEnumerationHandlersWithLimit is newTestEnumerationHandlers with
+// auth.account_notice_limit set to limit.
+func newTestEnumerationHandlersWithLimit(t *testing.T, enumerationSafe bool, limit int) (*handlers.AuthHandlers, *repository.Repository, <-chan string) {
+	t.Helper()
+	require.NoError(t, i18n.Init())
+	_, repo := testutil.NewTestDB(t)
+
+	waSvc, err := webauthn.NewService(&config.WebAuthnConfig{
+		RPID:          "localhost",
+		RPOrigin:      "http://localhost:8080",
+		RPDisplayName: "Test App",
+	})
+	require.NoError(t, err)
+	sessMgr, err := session.NewManager(&config.SessionConfig{
+		CookieName: "_test_session",
+		MaxAge:     3600,
+		HashKey:    testHashKey,
+	}, false)
+	require.NoError(t, err)
+
+	emailSvc, err := email.NewService(&config.SMTPConfig{Host: "127.0.0.1", Port: 1, From: "noreply@example.com"}, "http://localhost:8080")
+	require.NoError(t, err)
+	recipients := make(chan string, 10)
+	emailSvc.SetSendFunc(func(msg *mail.Msg) error {
+		to, toErr := msg.GetRecipients()
+		require.NoError(t, toErr)
+		for _, addr := range to {
+			recipients <- addr
+		}
+		return nil
+	})
+
+	h := handlers.NewAuth(repo, waSvc, sessMgr, emailSvc, &config.AuthConfig{
+		UseEmail:            true,
+		RequireVerification: true,
+		EnumerationSafe:     enumerationSafe,
+		AccountNoticeLimit:  limit,
+	})
+	return h, repo, recipients
+}
+
+// jsonShape reduces a decoded JSON value to its structure: object keys and
+// value kinds, without the values.
+func jsonShape(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		shape := make(map[string]any, len(v))
+		for k, val := range v {
+			shape[k] = jsonShape(val)
+		}
+		return shape
+	case []any:
+		shape := make([]any, len(v))
+		for i, val := range v {
+			shape[i] = jsonShape(val)
+		}
+		return shape
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+func decodeJSON(t *testing.T, rec *httptest.ResponseRecorder) map[string]any {
+	t.Helper()
+	var resp map[string]any
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	return resp
+}
+
+func registerFinish(t *testing.T, h *handlers.AuthHandlers, userID any) *httptest.ResponseRecorder {
+	t.Helper()
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/auth/register/finish?user_id=%v", userID), strings.NewReader("{}"))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	require.NoError(t, h.RegisterFinish(e.NewContext(req, rec)))
+	return rec
+}
+
+func TestRegisterBegin_EnumerationSafe_TakenEmail(t *testing.T) {
+	h, repo, recipients := newTestEnumerationHandlers(t, true)
+	_, err := repo.CreateUserWithEmail(context.Background(), "taken@example.com")
+	require.NoError(t, err)
+
+	rec := registerWithEmail(t, h, "Taken@Example.com")
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	resp := decodeJSON(t, rec)
+	assert.Contains(t, resp, "publicKey")
+	assert.NotContains(t, resp, "redirect")
+
+	// No account was created for the challenge
+	_, err = repo.GetUserByID(context.Background(), int64(resp["user_id"].(float64)))
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	select {
+	case to := <-recipients:
+		assert.Equal(t, "<taken@example.com>", to)
+	case <-time.After(5 * time.Second):
+		t.Fatal("owner was not notified")
+	}
+}
+
+func TestRegisterBegin_EnumerationSafe_AccountNoticeLimit(t *testing.T) {
+	h, repo, recipients := newTestEnumerationHandlersWithLimit(t, true, 1)
+	_, err := repo.CreateUserWithEmail(context.Background(), "taken@example.com")
+	require.NoError(t, err)
+
+	first := registerWithEmail(t, h, "taken@example.com")
+	second := registerWithEmail(t, h, "taken@example.com")
+
+	// Both attempts get a challenge, but only the first one sends a mail
+	require.Equal(t, http.StatusOK, first.Code)
+	require.Equal(t, http.StatusOK, second.Code)
+	assert.Equal(t, jsonShape(decodeJSON(t, first)), jsonShape(decodeJSON(t, second)))
+	select {
+	case to := <-recipients:
+		assert.Equal(t, "<taken@example.com>", to)
+	case <-time.After(5 * time.Second):
+		t.Fatal("owner was not notified")
+	}
+	select {
+	case to := <-recipients:
+		t.Fatalf("unexpected second notice to %s", to)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestRegisterBegin_EnumerationSafe_NewEmail(t *testing.T) {
+	h, _, recipients := newTestEnumerationHandlers(t, true)
+
+	rec := registerWithEmail(t, h, "new@example.com")
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "publicKey")
+	assert.Empty(t, recipients)
+}
+
+func TestRegisterBegin_EnumerationSafe_IndistinguishableResponses(t *testing.T) {
+	h, repo, _ := newTestEnumerationHandlers(t, true)
+	_, err := repo.CreateUserWithEmail(context.Background(), "taken@example.com")
+	require.NoError(t, err)
+
+	takenRec := registerWithEmail(t, h, "taken@example.com")
+	newRec := registerWithEmail(t, h, "fresh@example.com")
+
+	require.Equal(t, newRec.Code, takenRec.Code)
+	taken, fresh := decodeJSON(t, takenRec), decodeJSON(t, newRec)
+	assert.Equal(t, jsonShape(fresh), jsonShape(taken))
+
+	// User IDs come from the same sequence, so they don't stand out either
+	assert.Equal(t, taken["user_id"].(float64)+1, fresh["user_id"].(float64))
+	takenUser := taken["publicKey"].(map[string]any)["user"].(map[string]any)
+	freshUser := fresh["publicKey"].(map[string]any)["user"].(map[string]any)
+	assert.Equal(t, "taken@example.com", takenUser["name"])
+	assert.Equal(t, "fresh@example.com", freshUser["name"])
+
+	// Finishing with an invalid attestation fails the same way for both
+	takenFinish := registerFinish(t, h, taken["user_id"])
+	freshFinish := registerFinish(t, h, fresh["user_id"])
+	assert.Equal(t, http.StatusBadRequest, takenFinish.Code)
+	assert.Equal(t, freshFinish.Code, takenFinish.Code)
+	assert.Equal(t, freshFinish.Body.String(), takenFinish.Body.String())
+}
+
+func TestRegisterBegin_EnumerationSafeDisabled_TakenEmail(t *testing.T) {
+	h, repo, recipients := newTestEnumerationHandlers(t, false)
+	_, err := repo.CreateUserWithEmail(context.Background(), "taken@example.com")
+	require.NoError(t, err)
+
+	rec := registerWithEmail(t, h, "taken@example.com")
+
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Contains(t, rec.Body.String(), "email already registered")
+	assert.Empty(t, recipients)
+}
@@ -0,0 +1,86 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/oliverandrich/go-webapp-template/internal/htmx"
+	"github.com/oliverandrich/go-webapp-template/internal/i18n"
+	"github.com/oliverandrich/go-webapp-template/internal/templates"
+)
+
+// errorMessageKeys maps status codes to i18n message IDs.
+var errorMessageKeys = map[int]string{
+	http.StatusBadRequest:       "error_bad_request",
+	http.StatusUnauthorized:     "error_unauthorized",
+	http.StatusForbidden:        "error_forbidden",
+	http.StatusNotFound:         "error_not_found",
+	http.StatusMethodNotAllowed: "error_method_not_allowed",
+	http.StatusTooManyRequests:  "error_too_many_requests",
+
+	http.StatusServiceUnavailable: "error_service_unavailable",
+}
+
+// HTTPErrorHandler renders errors as localized pages. htmx requests receive a
+// partial retargeted into the layout's error container, JSON clients a JSON
+// error and the JSON API problem details. Server errors are logged and always
+// shown with a generic message.
+func HTTPErrorHandler(err error, c echo.Context) {
+	if c.Response().Committed {
+		return
+	}
+
+	code := http.StatusInternalServerError
+	var he *echo.HTTPError
+	if errors.As(err, &he) {
+		code = he.Code
+	}
+
+	if code >= http.StatusInternalServerError {
+		slog.Error("request failed",
+			"method", c.Request().Method,
+			"path", c.Request().URL.Path,
+			"status", code,
+			"error", err,
+		)
+	}
+
+	message := errorMessage(c, code)
+
+	var renderErr error
+	switch {
+	case c.Request().Method == http.MethodHead:
+		renderErr = c.NoContent(code)
+	case wantsProblem(c):
+		renderErr = ProblemJSON(c, code, "", message)
+	case WantsJSON(c):
+		renderErr = c.JSON(code, map[string]string{"error": message})
+	case c.Request().Header.Get(htmx.HeaderRequest) == "true":
+		c.Response().Header().Set(htmx.HeaderRetarget, templates.ErrorTarget)
+		c.Response().Header().Set(htmx.HeaderReswap, "innerHTML")
+		renderErr = Render(c, code, templates.ErrorPartial(code, message))
+	default:
+		renderErr = Render(c, code, templates.ErrorPage(code, message))
+	}
+	if renderErr != nil {
+		slog.Error("failed to render error response", "error", renderErr)
+	}
+}
+
+// errorMessage returns the localized message for a status code. Server
+// errors without a dedicated message share a generic one.
+func errorMessage(c echo.Context, code int) string {
+	ctx := c.Request().Context()
+	if key, ok := errorMessageKeys[code]; ok {
+		return i18n.T(ctx, key)
+	}
+	if code >= http.StatusInternalServerError {
+		return i18n.T(ctx, "error_internal")
+	}
+	return http.StatusText(code)
+}
@@ -0,0 +1,138 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package handlers_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/oliverandrich/go-webapp-template/internal/handlers"
+	"github.com/oliverandrich/go-webapp-template/internal/htmx"
+	"github.com/oliverandrich/go-webapp-template/internal/i18n"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/text/language"
+)
+
+func newErrorTestContext(method string, headers map[string]string) (echo.Context, *httptest.ResponseRecorder) {
+	e := echo.New()
+	req := httptest.NewRequest(method, "/missing", nil)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	req = req.WithContext(i18n.WithLocale(req.Context(), language.English))
+	rec := httptest.NewRecorder()
+	return e.NewContext(req, rec), rec
+}
+
+func TestHTTPErrorHandler_NotFoundPage(t *testing.T) {
+	c, rec := newErrorTestContext(http.MethodGet, nil)
+
+	handlers.HTTPErrorHandler(echo.ErrNotFound, c)
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Contains(t, rec.Header().Get(echo.HeaderContentType), echo.MIMETextHTML)
+	assert.Contains(t, rec.Body.String(), "<!doctype html>")
+	assert.Contains(t, rec.Body.String(), "Page not found")
+	assert.Empty(t, rec.Header().Get(htmx.HeaderRetarget))
+}
+
+func TestHTTPErrorHandler_NotFoundHtmx(t *testing.T) {
+	c, rec := newErrorTestContext(http.MethodGet, map[string]string{htmx.HeaderRequest: "true"})
+
+	handlers.HTTPErrorHandler(echo.ErrNotFound, c)
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, "#htmx-error", rec.Header().Get(htmx.HeaderRetarget))
+	assert.Equal(t, "innerHTML", rec.Header().Get(htmx.HeaderReswap))
+	assert.Contains(t, rec.Body.String(), "Page not found")
+	assert.NotContains(t, rec.Body.String(), "<html")
+}
+
+func TestHTTPErrorHandler_InternalErrorIsGeneric(t *testing.T) {
+	c, rec := newErrorTestContext(http.MethodGet, nil)
+
+	handlers.HTTPErrorHandler(errors.New("database exploded: secret details"), c)
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Internal server error")
+	assert.NotContains(t, rec.Body.String(), "secret details")
+}
+
+func TestHTTPErrorHandler_LocalizedMessage(t *testing.T) {
+	c, rec := newErrorTestContext(http.MethodGet, nil)
+	c.SetRequest(c.Request().WithContext(i18n.WithLocale(c.Request().Context(), language.German)))
+
+	handlers.HTTPErrorHandler(echo.ErrNotFound, c)
+
+	assert.Contains(t, rec.Body.String(), "Seite nicht gefunden")
+}
+
+func TestHTTPErrorHandler_JSONClient(t *testing.T) {
+	c, rec := newErrorTestContext(http.MethodGet, map[string]string{echo.HeaderAccept: echo.MIMEApplicationJSON})
+
+	handlers.HTTPErrorHandler(echo.ErrForbidden, c)
+
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.JSONEq(t, `{"error":"You don't have permission to access this page"}`, rec.Body.String())
+}
+
+func TestHTTPErrorHandler_HeadRequest(t *testing.T) {
+	c, rec := newErrorTestContext(http.MethodHead, nil)
+
+	handlers.HTTPErrorHandler(echo.ErrNotFound, c)
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Empty(t, rec.Body.String())
+}
+
+func TestHTTPErrorHandler_APIProblem(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/missing", nil)
+	req = req.WithContext(i18n.WithLocale(req.Context(), language.English))
+	rec := httptest.NewRecorder()
+
+	handlers.HTTPErrorHandler(echo.ErrNotFound, e.NewContext(req, rec))
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, handlers.MIMEApplicationProblemJSON, rec.Header().Get(echo.HeaderContentType))
+	assert.JSONEq(t, `{
+		"type": "about:blank",
+		"title": "Not Found",
+		"status": 404,
+		"detail": "Page not found",
+		"instance": "/api/missing"
+	}`, rec.Body.String())
+}
+
+func TestHTTPErrorHandler_ProblemAccept(t *testing.T) {
+	c, rec := newErrorTestContext(http.MethodGet, map[string]string{echo.HeaderAccept: handlers.MIMEApplicationProblemJSON})
+
+	handlers.HTTPErrorHandler(echo.ErrForbidden, c)
+
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Equal(t, handlers.MIMEApplicationProblemJSON, rec.Header().Get(echo.HeaderContentType))
+	assert.Contains(t, rec.Body.String(), `"instance":"/missing"`)
+}
+
+func TestProblemJSON(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/api/things", nil)
+	rec := httptest.NewRecorder()
+
+	err := handlers.ProblemJSON(e.NewContext(req, rec), http.StatusBadRequest, "", "name is required")
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, handlers.MIMEApplicationProblemJSON, rec.Header().Get(echo.HeaderContentType))
+	assert.JSONEq(t, `{
+		"type": "about:blank",
+		"title": "Bad Request",
+		"status": 400,
+		"detail": "name is required",
+		"instance": "/api/things"
+	}`, rec.Body.String())
+}
@@ -0,0 +1,30 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package handlers
+
+import "context"
+
+// AuthErrorMessage exposes the translation of auth errors to the external tests.
+func AuthErrorMessage(ctx context.Context, status int, code APIErrorCode, messageID string) string {
+	return newAuthError(status, code, messageID).message(ctx)
+}
+
+// HomeCacheTTL exposes how long the home page content is cached.
+const HomeCacheTTL = homeCacheTTL
+
+// HomeCacheSize returns the number of cached renders of the home page.
+func HomeCacheSize(h *Handlers) int {
+	return h.home.len()
+}
+
+// ReplaceHomeCache overwrites every cached home page render with html, so
+// tests can tell a cached response from a fresh render.
+func ReplaceHomeCache(h *Handlers, html string) {
+	h.home.mu.Lock()
+	defer h.home.mu.Unlock()
+	for key, entry := range h.home.entries {
+		entry.html = html
+		h.home.entries[key] = entry
+	}
+}
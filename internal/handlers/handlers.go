@@ -5,35 +5,193 @@ package handlers
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/labstack/echo/v4"
+	"github.com/oliverandrich/go-webapp-template/internal/appcontext"
+	"github.com/oliverandrich/go-webapp-template/internal/clock"
+	"github.com/oliverandrich/go-webapp-template/internal/i18n"
 	"github.com/oliverandrich/go-webapp-template/internal/repository"
+	"github.com/oliverandrich/go-webapp-template/internal/services/auth"
 	"github.com/oliverandrich/go-webapp-template/internal/templates"
 )
 
 // Handlers contains all HTTP handlers.
 type Handlers struct {
-	repo *repository.Repository
+	repo    *repository.Repository
+	build   BuildInfo
+	started time.Time
+	pages   *Renderer
+	home    *pageCache
+}
+
+// BuildInfo describes the running binary. The values are set at build time
+// via -ldflags (see the justfile).
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"build_time"`
 }
 
 // New creates a new Handlers instance.
 func New(repo *repository.Repository) *Handlers {
-	return &Handlers{repo: repo}
+	return &Handlers{repo: repo, started: time.Now(), pages: defaultRenderer, home: newPageCache(homeCacheTTL)}
 }
 
-// Health returns the health status.
+// SetRenderer replaces the renderer used for the pages.
+func (h *Handlers) SetRenderer(r *Renderer) {
+	h.pages = r
+}
+
+// SetClock replaces the time source used for page cache expiry (for tests).
+func (h *Handlers) SetClock(c clock.Clock) {
+	h.home.clock = c
+}
+
+// SetBuildInfo sets the build information reported by the health endpoints.
+func (h *Handlers) SetBuildInfo(info BuildInfo) {
+	h.build = info
+}
+
+// HealthResponse is the JSON body of the health endpoints.
+type HealthResponse struct {
+	BuildInfo
+	Status        string `json:"status"`
+	UptimeSeconds int64  `json:"uptime_seconds"`
+	Error         string `json:"error,omitempty"`
+}
+
+// Health reports that the process is up. It answers with JSON when the
+// client accepts application/json and with a plain "ok" otherwise.
 func (h *Handlers) Health(c echo.Context) error {
-	return c.JSON(http.StatusOK, map[string]string{
-		"status": "ok",
+	return h.healthResponse(c, http.StatusOK, "")
+}
+
+// Ready reports whether the application can serve requests, i.e. the
+// database is reachable. It negotiates the format like Health.
+func (h *Handlers) Ready(c echo.Context) error {
+	if err := h.repo.Ping(c.Request().Context()); err != nil {
+		return h.healthResponse(c, http.StatusServiceUnavailable, "database unavailable")
+	}
+	return h.healthResponse(c, http.StatusOK, "")
+}
+
+func (h *Handlers) healthResponse(c echo.Context, status int, problem string) error {
+	text := "ok"
+	if problem != "" {
+		text = "unavailable"
+	}
+
+	if !WantsJSON(c) {
+		return c.String(status, text)
+	}
+	return c.JSON(status, HealthResponse{
+		BuildInfo:     h.build,
+		Status:        text,
+		UptimeSeconds: int64(time.Since(h.started).Seconds()),
+		Error:         problem,
 	})
 }
 
-// Home renders the home page.
+// Home renders the home page. Its content for anonymous visitors is cached
+// per locale for a short time; signed-in users always get a fresh render.
 func (h *Handlers) Home(c echo.Context) error {
-	return Render(c, http.StatusOK, templates.Home())
+	header := c.Response().Header()
+	header.Add(echo.HeaderVary, "Accept-Language")
+	header.Add(echo.HeaderVary, "Cookie")
+
+	content := templates.Home()
+	if cc, ok := c.(*appcontext.Context); !ok || !cc.IsAuthenticated() {
+		content = h.home.component(anonymousPageKey(pageContext(c), "home"), content)
+	}
+	return h.pages.Page(c, http.StatusOK, "app_name", content)
 }
 
-// Dashboard renders the protected dashboard page.
+// Dashboard renders the protected dashboard page with the user's account stats.
 func (h *Handlers) Dashboard(c echo.Context) error {
-	return Render(c, http.StatusOK, templates.Dashboard())
+	cc, ok := c.(*appcontext.Context)
+	if !ok || !cc.IsAuthenticated() {
+		return c.Redirect(http.StatusSeeOther, "/auth/login")
+	}
+
+	stats, err := h.repo.GetUserStats(c.Request().Context(), cc.GetUser().ID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to get account stats"})
+	}
+
+	return h.pages.Page(c, http.StatusOK, "dashboard_title", templates.Dashboard(stats))
+}
+
+// LanguageRequest is the request body for changing the preferred language.
+type LanguageRequest struct {
+	Language string `json:"language" form:"language"`
+}
+
+// SetLanguage stores the authenticated user's preferred language. An empty
+// language clears the preference.
+func (h *Handlers) SetLanguage(c echo.Context) error {
+	cc, ok := c.(*appcontext.Context)
+	if !ok || !cc.IsAuthenticated() {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "not authenticated"})
+	}
+	if cc.IsImpersonating() {
+		return impersonationForbidden(c)
+	}
+
+	var req LanguageRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid request"})
+	}
+
+	lang := ""
+	if req.Language != "" {
+		tag, supported := i18n.ParseSupported(req.Language)
+		if !supported {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "unsupported language"})
+		}
+		lang = tag.String()
+	}
+
+	if err := h.repo.SetUserLanguage(c.Request().Context(), cc.GetUser().ID, lang); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to update language"})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{"language": lang})
+}
+
+// DisplayNameRequest is the request body for changing the display name.
+type DisplayNameRequest struct {
+	DisplayName string `json:"display_name" form:"display_name"`
+}
+
+// SetDisplayName changes the name shown for the authenticated user, e.g. in
+// passkey prompts. An empty name resets it to the username.
+func (h *Handlers) SetDisplayName(c echo.Context) error {
+	cc, ok := c.(*appcontext.Context)
+	if !ok || !cc.IsAuthenticated() {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "not authenticated"})
+	}
+	if cc.IsImpersonating() {
+		return impersonationForbidden(c)
+	}
+
+	var req DisplayNameRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid request"})
+	}
+
+	name := auth.NormalizeDisplayName(req.DisplayName)
+	if !auth.ValidDisplayName(name) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid display name"})
+	}
+
+	user := cc.GetUser()
+	if err := h.repo.SetDisplayName(c.Request().Context(), user.ID, name); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to update display name"})
+	}
+	if name == "" {
+		name = user.Username
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{"display_name": name})
 }
@@ -4,13 +4,19 @@
 package handlers_test
 
 import (
+	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/labstack/echo/v4"
+	"github.com/oliverandrich/go-webapp-template/internal/clock"
 	"github.com/oliverandrich/go-webapp-template/internal/handlers"
 	"github.com/oliverandrich/go-webapp-template/internal/i18n"
+	"github.com/oliverandrich/go-webapp-template/internal/models"
 	"github.com/oliverandrich/go-webapp-template/internal/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -42,7 +48,83 @@ func TestHealth(t *testing.T) {
 
 	require.NoError(t, err)
 	assert.Equal(t, http.StatusOK, rec.Code)
-	assert.JSONEq(t, `{"status":"ok"}`, rec.Body.String())
+	assert.Equal(t, echo.MIMETextPlainCharsetUTF8, rec.Header().Get(echo.HeaderContentType))
+	assert.Equal(t, "ok", rec.Body.String())
+}
+
+func TestHealth_JSON(t *testing.T) {
+	h := handlers.New(nil)
+	h.SetBuildInfo(handlers.BuildInfo{Version: "v1.2.3", Commit: "abc1234", BuildTime: "2025-06-01T10:00:00Z"})
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/health", nil)
+	req.Header.Set(echo.HeaderAccept, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	err := h.Health(c)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, echo.MIMEApplicationJSON, rec.Header().Get(echo.HeaderContentType))
+	var body handlers.HealthResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, "ok", body.Status)
+	assert.Equal(t, "v1.2.3", body.Version)
+	assert.Equal(t, "abc1234", body.Commit)
+	assert.Equal(t, "2025-06-01T10:00:00Z", body.BuildTime)
+	assert.GreaterOrEqual(t, body.UptimeSeconds, int64(0))
+	assert.Contains(t, rec.Body.String(), `"uptime_seconds"`)
+}
+
+func TestReady(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	h := handlers.New(repo)
+
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	require.NoError(t, h.Ready(e.NewContext(httptest.NewRequest(http.MethodGet, "/ready", nil), rec)))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "ok", rec.Body.String())
+
+	req := httptest.NewRequest(http.MethodGet, "/ready", nil)
+	req.Header.Set(echo.HeaderAccept, echo.MIMEApplicationJSON)
+	rec = httptest.NewRecorder()
+	require.NoError(t, h.Ready(e.NewContext(req, rec)))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, echo.MIMEApplicationJSON, rec.Header().Get(echo.HeaderContentType))
+	assert.Contains(t, rec.Body.String(), `"status":"ok"`)
+}
+
+func TestReady_DatabaseUnavailable(t *testing.T) {
+	db, repo := testutil.NewTestDB(t)
+	h := handlers.New(repo)
+	require.NoError(t, db.Close())
+
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	require.NoError(t, h.Ready(e.NewContext(httptest.NewRequest(http.MethodGet, "/ready", nil), rec)))
+
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, "unavailable", rec.Body.String())
+
+	req := httptest.NewRequest(http.MethodGet, "/ready", nil)
+	req.Header.Set(echo.HeaderAccept, echo.MIMEApplicationJSON)
+	rec = httptest.NewRecorder()
+	require.NoError(t, h.Ready(e.NewContext(req, rec)))
+
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.JSONEq(t, `"unavailable"`, mustField(t, rec.Body.Bytes(), "status"))
+	assert.JSONEq(t, `"database unavailable"`, mustField(t, rec.Body.Bytes(), "error"))
+}
+
+func mustField(t *testing.T, body []byte, field string) string {
+	t.Helper()
+	var m map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(body, &m))
+	return string(m[field])
 }
 
 func TestHome(t *testing.T) {
@@ -64,21 +146,173 @@ func TestHome(t *testing.T) {
 	assert.Contains(t, rec.Body.String(), "<!doctype html>")
 }
 
+// getHome requests the home page in the given language, as user when set.
+func getHome(t *testing.T, h *handlers.Handlers, lang language.Tag, user *models.User) *httptest.ResponseRecorder {
+	t.Helper()
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req = req.WithContext(i18n.WithLocale(req.Context(), lang))
+	rec := httptest.NewRecorder()
+
+	require.NoError(t, h.Home(newTestContext(e, req, rec, user)))
+	require.Equal(t, http.StatusOK, rec.Code)
+	return rec
+}
+
+func TestHome_CachesAnonymousRender(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	h := handlers.New(repo)
+
+	first := getHome(t, h, language.English, nil)
+	second := getHome(t, h, language.English, nil)
+
+	assert.Equal(t, 1, handlers.HomeCacheSize(h))
+	assert.Equal(t, first.Body.String(), second.Body.String())
+	assert.ElementsMatch(t, []string{"Accept-Language", "Cookie", "HX-Request"}, second.Header().Values(echo.HeaderVary))
+}
+
+func TestHome_CacheExpires(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	h := handlers.New(repo)
+	clk := clock.NewFake(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+	h.SetClock(clk)
+
+	getHome(t, h, language.English, nil)
+	handlers.ReplaceHomeCache(h, "cached-marker")
+
+	clk.Advance(handlers.HomeCacheTTL)
+	assert.Contains(t, getHome(t, h, language.English, nil).Body.String(), "cached-marker")
+
+	clk.Advance(time.Second)
+	rec := getHome(t, h, language.English, nil)
+	assert.NotContains(t, rec.Body.String(), "cached-marker")
+	assert.Equal(t, 1, handlers.HomeCacheSize(h))
+}
+
+func TestHome_CachePerLocale(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	h := handlers.New(repo)
+
+	english := getHome(t, h, language.English, nil)
+	german := getHome(t, h, language.German, nil)
+
+	assert.Equal(t, 2, handlers.HomeCacheSize(h))
+	assert.Contains(t, english.Body.String(), `lang="en"`)
+	assert.Contains(t, german.Body.String(), `lang="de"`)
+	assert.NotEqual(t, english.Body.String(), german.Body.String())
+}
+
+func TestHome_AuthenticatedBypassesCache(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	h := handlers.New(repo)
+	user := testutil.NewTestUser(t, repo, "testuser")
+
+	getHome(t, h, language.English, nil)
+	rec := getHome(t, h, language.English, user)
+
+	assert.Equal(t, 1, handlers.HomeCacheSize(h))
+	assert.Contains(t, rec.Body.String(), "/auth/logout")
+	assert.Contains(t, rec.Header().Values(echo.HeaderVary), "Cookie")
+}
+
 func TestDashboard(t *testing.T) {
 	_, repo := testutil.NewTestDB(t)
 	h := handlers.New(repo)
 
+	user := testutil.NewTestUser(t, repo, "testuser")
+	testutil.NewTestCredential(t, repo, user.ID, "cred-1")
+	require.NoError(t, repo.CreateRecoveryCodes(context.Background(), user.ID, []string{"hash-1", "hash-2"}))
+
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodGet, "/dashboard", nil)
 	// Add i18n context
 	ctx := i18n.WithLocale(req.Context(), language.English)
 	req = req.WithContext(ctx)
 	rec := httptest.NewRecorder()
-	c := e.NewContext(req, rec)
+	c := newTestContext(e, req, rec, user)
 
 	err := h.Dashboard(c)
 
 	require.NoError(t, err)
 	assert.Equal(t, http.StatusOK, rec.Code)
-	assert.Contains(t, rec.Body.String(), "<!doctype html>")
+	body := rec.Body.String()
+	assert.Contains(t, body, "<!doctype html>")
+	assert.Contains(t, body, "1 passkey")
+	assert.Contains(t, body, "2 recovery codes left")
+	assert.Contains(t, body, "Never")
+}
+
+func TestDashboard_Unauthenticated(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	h := handlers.New(repo)
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/dashboard", nil)
+	rec := httptest.NewRecorder()
+	c := newTestContext(e, req, rec, nil)
+
+	err := h.Dashboard(c)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusSeeOther, rec.Code)
+	assert.Equal(t, "/auth/login", rec.Header().Get("Location"))
+}
+
+func setLanguage(t *testing.T, h *handlers.Handlers, user *models.User, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/settings/language", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	require.NoError(t, h.SetLanguage(newTestContext(e, req, rec, user)))
+	return rec
+}
+
+func TestSetLanguage(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	h := handlers.New(repo)
+	user := testutil.NewTestUser(t, repo, "testuser")
+
+	rec := setLanguage(t, h, user, `{"language":"de-DE"}`)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"language":"de"}`, rec.Body.String())
+	updated, err := repo.GetUserByID(context.Background(), user.ID)
+	require.NoError(t, err)
+	require.NotNil(t, updated.PreferredLanguage)
+	assert.Equal(t, "de", *updated.PreferredLanguage)
+}
+
+func TestSetLanguage_Clear(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	h := handlers.New(repo)
+	user := testutil.NewTestUser(t, repo, "testuser")
+	require.NoError(t, repo.SetUserLanguage(context.Background(), user.ID, "de"))
+
+	rec := setLanguage(t, h, user, `{"language":""}`)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	updated, err := repo.GetUserByID(context.Background(), user.ID)
+	require.NoError(t, err)
+	assert.Nil(t, updated.PreferredLanguage)
+}
+
+func TestSetLanguage_Unsupported(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	h := handlers.New(repo)
+	user := testutil.NewTestUser(t, repo, "testuser")
+
+	rec := setLanguage(t, h, user, `{"language":"fr"}`)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "unsupported language")
+}
+
+func TestSetLanguage_Unauthenticated(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	h := handlers.New(repo)
+
+	rec := setLanguage(t, h, nil, `{"language":"de"}`)
+
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
 }
@@ -4,6 +4,12 @@
 package handlers
 
 import (
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode"
+
 	"github.com/a-h/templ"
 	"github.com/labstack/echo/v4"
 )
@@ -19,3 +25,47 @@ func Render(c echo.Context, statusCode int, component templ.Component) error {
 
 	return c.HTML(statusCode, buf.String())
 }
+
+// WantsJSON reports whether the client explicitly asked for a JSON response.
+func WantsJSON(c echo.Context) bool {
+	return strings.Contains(c.Request().Header.Get(echo.HeaderAccept), echo.MIMEApplicationJSON)
+}
+
+// ServeAttachment streams r as a file download named filename. It sets the
+// Content-Disposition with a sanitized filename and X-Content-Type-Options:
+// nosniff, so browsers neither render nor reinterpret user-influenced
+// content. Other headers, e.g. Cache-Control, are left to the caller.
+func ServeAttachment(c echo.Context, filename, contentType string, r io.Reader) error {
+	header := c.Response().Header()
+	header.Set(echo.HeaderContentDisposition, contentDisposition(filename))
+	header.Set(echo.HeaderXContentTypeOptions, "nosniff")
+	return c.Stream(http.StatusOK, contentType, r)
+}
+
+// contentDisposition returns an attachment disposition for filename. Path
+// separators, quotes and control characters are dropped; names with non-ASCII
+// characters get an ASCII fallback plus the RFC 6266 filename* parameter.
+func contentDisposition(filename string) string {
+	name := strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == '"' || unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, filename)
+	name = strings.Trim(name, " .")
+	if name == "" {
+		name = "download"
+	}
+
+	ascii := strings.Map(func(r rune) rune {
+		if r > unicode.MaxASCII {
+			return '_'
+		}
+		return r
+	}, name)
+	disposition := `attachment; filename="` + ascii + `"`
+	if ascii != name {
+		disposition += "; filename*=UTF-8''" + url.PathEscape(name)
+	}
+	return disposition
+}
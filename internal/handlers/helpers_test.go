@@ -0,0 +1,58 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/oliverandrich/go-webapp-template/internal/handlers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServeAttachment(t *testing.T) {
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/export", nil), rec)
+
+	err := handlers.ServeAttachment(c, "export.csv", "text/csv", strings.NewReader("a,b\n1,2\n"))
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "text/csv", rec.Header().Get(echo.HeaderContentType))
+	assert.Equal(t, "nosniff", rec.Header().Get(echo.HeaderXContentTypeOptions))
+	assert.Equal(t, `attachment; filename="export.csv"`, rec.Header().Get(echo.HeaderContentDisposition))
+	assert.Equal(t, "a,b\n1,2\n", rec.Body.String())
+}
+
+func TestServeAttachment_SanitizesFilename(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		want     string
+	}{
+		{"path separators", "../../etc/passwd", `attachment; filename="etcpasswd"`},
+		{"backslashes", `..\..\boot.ini`, `attachment; filename="boot.ini"`},
+		{"quotes", `evil".txt"; filename="x.html`, `attachment; filename="evil.txt; filename=x.html"`},
+		{"control characters", "a\r\nSet-Cookie: x.txt", `attachment; filename="aSet-Cookie: x.txt"`},
+		{"empty after sanitizing", `/"/`, `attachment; filename="download"`},
+		{"non-ascii", "Übersicht.csv", `attachment; filename="_bersicht.csv"; filename*=UTF-8''%C3%9Cbersicht.csv`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			rec := httptest.NewRecorder()
+			c := e.NewContext(httptest.NewRequest(http.MethodGet, "/export", nil), rec)
+
+			require.NoError(t, handlers.ServeAttachment(c, tt.filename, "text/plain", strings.NewReader("")))
+
+			assert.Equal(t, tt.want, rec.Header().Get(echo.HeaderContentDisposition))
+		})
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/oliverandrich/go-webapp-template/internal/appcontext"
+	"github.com/oliverandrich/go-webapp-template/internal/models"
+	"github.com/oliverandrich/go-webapp-template/internal/services/session"
+)
+
+// impersonationDuration is the lifetime of a session issued for acting as
+// another user. It is deliberately short and never remembered.
+const impersonationDuration = time.Hour
+
+// ImpersonationResponse is returned when impersonation starts or stops.
+type ImpersonationResponse struct {
+	UserID       int64  `json:"user_id"`
+	Username     string `json:"username"`
+	Impersonator string `json:"impersonator,omitempty"`
+}
+
+// impersonationForbidden answers requests that would change the passkeys,
+// recovery codes, email address, sessions or profile of an impersonated
+// user. Those stay with the account owner.
+func impersonationForbidden(c echo.Context) error {
+	return c.JSON(http.StatusForbidden, map[string]string{"error": "not allowed while impersonating"})
+}
+
+// Impersonate replaces the administrator's session with one for the user in
+// the :id path parameter. The administrator's ID travels in the session, so
+// StopImpersonation can switch back, and the start is written to the audit
+// log.
+func (h *AdminHandlers) Impersonate(c echo.Context) error {
+	cc, ok := c.(*appcontext.Context)
+	if !ok || !cc.IsAuthenticated() {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "not authenticated"})
+	}
+	admin := cc.GetUser()
+	if cc.IsImpersonating() {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "already impersonating"})
+	}
+
+	targetID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid user id"})
+	}
+	if targetID == admin.ID {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "cannot impersonate yourself"})
+	}
+
+	ctx := c.Request().Context()
+	target, err := h.repo.GetUserByID(ctx, targetID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "user not found"})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "database error"})
+	}
+	// Acting as another administrator would grant their rights
+	if target.IsAdmin {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "cannot impersonate an administrator"})
+	}
+
+	// No impersonation without a trace
+	if err := h.repo.CreateAuditEvent(ctx, admin.ID, models.AuditImpersonationStart, target.ID); err != nil {
+		slog.Error("failed to record audit event", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to start impersonation"})
+	}
+
+	cookie, err := h.store.Create(ctx, session.Data{
+		UserID:              target.ID,
+		Username:            target.Username,
+		Version:             target.SessionVersion,
+		ImpersonatorID:      admin.ID,
+		ImpersonatorVersion: admin.SessionVersion,
+	}, impersonationDuration)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to create session"})
+	}
+	c.SetCookie(cookie)
+
+	slog.Warn("impersonation started", "admin_id", admin.ID, "user_id", target.ID)
+
+	return c.JSON(http.StatusOK, ImpersonationResponse{
+		UserID:       target.ID,
+		Username:     target.Username,
+		Impersonator: admin.Username,
+	})
+}
+
+// StopImpersonation ends an impersonation, revokes its session and signs the
+// administrator back in with a regular session of the default lifetime. JSON
+// clients get the administrator's identity, the banner form a redirect.
+func (h *AdminHandlers) StopImpersonation(c echo.Context) error {
+	cc, ok := c.(*appcontext.Context)
+	if !ok || !cc.IsAuthenticated() {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "not authenticated"})
+	}
+	if !cc.IsImpersonating() {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "not impersonating"})
+	}
+	admin, target := cc.Impersonator, cc.GetUser()
+
+	ctx := c.Request().Context()
+	if err := h.repo.CreateAuditEvent(ctx, admin.ID, models.AuditImpersonationStop, target.ID); err != nil {
+		slog.Error("failed to record audit event", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to stop impersonation"})
+	}
+
+	// Revoke the impersonation session itself, so a copy of its cookie
+	// stops working too; the removal cookie is superseded below
+	h.store.Clear(c.Request())
+	cookie, err := h.store.Create(ctx, session.Data{
+		UserID:   admin.ID,
+		Username: admin.Username,
+		Version:  admin.SessionVersion,
+	}, h.sessions.Duration())
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to create session"})
+	}
+	c.SetCookie(cookie)
+
+	slog.Info("impersonation stopped", "admin_id", admin.ID, "user_id", target.ID)
+
+	// The banner submits a plain form
+	if !WantsJSON(c) {
+		return c.Redirect(http.StatusSeeOther, "/dashboard")
+	}
+	return c.JSON(http.StatusOK, ImpersonationResponse{
+		UserID:   admin.ID,
+		Username: admin.Username,
+	})
+}
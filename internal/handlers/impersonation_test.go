@@ -0,0 +1,260 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package handlers_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/oliverandrich/go-webapp-template/internal/handlers"
+	"github.com/oliverandrich/go-webapp-template/internal/models"
+	"github.com/oliverandrich/go-webapp-template/internal/repository"
+	"github.com/oliverandrich/go-webapp-template/internal/services/session"
+	"github.com/oliverandrich/go-webapp-template/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestAdminHandlers(t *testing.T) (*handlers.AdminHandlers, *repository.Repository, *session.Manager) {
+	t.Helper()
+	_, repo := testutil.NewTestDB(t)
+	sessMgr := newTestSessionManager(t)
+	return handlers.NewAdmin(newTestSettings(t, repo), repo, sessMgr), repo, sessMgr
+}
+
+func newTestAdmin(t *testing.T, repo *repository.Repository) *models.User {
+	t.Helper()
+	admin := testutil.NewTestUser(t, repo, "admin")
+	require.NoError(t, repo.SetAdmin(context.Background(), admin.ID, true))
+	admin.IsAdmin = true
+	return admin
+}
+
+// impersonate calls Impersonate as admin for the given target ID.
+func impersonate(t *testing.T, h *handlers.AdminHandlers, admin *models.User, targetID int64) *httptest.ResponseRecorder {
+	t.Helper()
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/admin/users/"+strconv.FormatInt(targetID, 10)+"/impersonate", nil)
+	rec := httptest.NewRecorder()
+	c := newTestContext(e, req, rec, admin)
+	c.SetParamNames("id")
+	c.SetParamValues(strconv.FormatInt(targetID, 10))
+
+	require.NoError(t, h.Impersonate(c))
+	return rec
+}
+
+// parseSessionCookie decodes the session cookie set in rec.
+func parseSessionCookie(t *testing.T, sessMgr *session.Manager, rec *httptest.ResponseRecorder) *session.Data {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	for _, cookie := range rec.Result().Cookies() {
+		req.AddCookie(cookie)
+	}
+	data, err := sessMgr.Parse(req)
+	require.NoError(t, err)
+	return data
+}
+
+func TestImpersonate(t *testing.T) {
+	h, repo, sessMgr := newTestAdminHandlers(t)
+	admin := newTestAdmin(t, repo)
+	target := testutil.NewTestUser(t, repo, "customer")
+	version, err := repo.BumpSessionVersion(context.Background(), admin.ID)
+	require.NoError(t, err)
+	admin.SessionVersion = version
+
+	rec := impersonate(t, h, admin, target.ID)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"username":"customer"`)
+	assert.Contains(t, rec.Body.String(), `"impersonator":"admin"`)
+
+	data := parseSessionCookie(t, sessMgr, rec)
+	assert.Equal(t, target.ID, data.UserID)
+	assert.Equal(t, admin.ID, data.ImpersonatorID)
+	assert.Equal(t, version, data.ImpersonatorVersion)
+
+	events, err := repo.ListAuditEvents(context.Background(), 10)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, models.AuditImpersonationStart, events[0].Action)
+	assert.Equal(t, admin.ID, *events[0].ActorID)
+	assert.Equal(t, target.ID, *events[0].TargetID)
+}
+
+func TestImpersonate_Rejected(t *testing.T) {
+	h, repo, _ := newTestAdminHandlers(t)
+	admin := newTestAdmin(t, repo)
+
+	assert.Equal(t, http.StatusBadRequest, impersonate(t, h, admin, admin.ID).Code)
+	assert.Equal(t, http.StatusNotFound, impersonate(t, h, admin, 9999).Code)
+
+	events, err := repo.ListAuditEvents(context.Background(), 10)
+	require.NoError(t, err)
+	assert.Empty(t, events)
+}
+
+func TestImpersonate_RejectsAdministrators(t *testing.T) {
+	h, repo, _ := newTestAdminHandlers(t)
+	admin := newTestAdmin(t, repo)
+	other := testutil.NewTestUser(t, repo, "other-admin")
+	require.NoError(t, repo.SetAdmin(context.Background(), other.ID, true))
+
+	assert.Equal(t, http.StatusForbidden, impersonate(t, h, admin, other.ID).Code)
+
+	events, err := repo.ListAuditEvents(context.Background(), 10)
+	require.NoError(t, err)
+	assert.Empty(t, events)
+}
+
+func TestImpersonatedSession_CannotChangeAccount(t *testing.T) {
+	h, repo := newTestAuthHandlers(t)
+	admin := newTestAdmin(t, repo)
+	target := testutil.NewTestUser(t, repo, "customer")
+	testutil.NewTestCredential(t, repo, target.ID, "cred-1")
+	testutil.NewTestCredential(t, repo, target.ID, "cred-2")
+
+	tests := map[string]echo.HandlerFunc{
+		"add credential begin":      h.AddCredentialBegin,
+		"add credential finish":     h.AddCredentialFinish,
+		"delete credential":         h.DeleteCredential,
+		"revoke other credentials":  h.RevokeOtherCredentials,
+		"regenerate recovery codes": h.RegenerateRecoveryCodes,
+		"download recovery codes":   h.DownloadRecoveryCodes,
+		"change email":              h.ChangeEmailBegin,
+		"sign out everywhere":       h.SignOutEverywhere,
+	}
+	for name, handler := range tests {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		rec := httptest.NewRecorder()
+		c := newTestContext(e, req, rec, target)
+		c.Impersonator = admin
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+
+		require.NoError(t, handler(c), name)
+		assert.Equal(t, http.StatusForbidden, rec.Code, name)
+	}
+
+	count, err := repo.CountUserCredentials(context.Background(), target.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	updated, err := repo.GetUserByID(context.Background(), target.ID)
+	require.NoError(t, err)
+	assert.Equal(t, target.SessionVersion, updated.SessionVersion)
+}
+
+func TestImpersonatedSession_CannotChangeProfile(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	h := handlers.New(repo)
+	admin := newTestAdmin(t, repo)
+	target := testutil.NewTestUser(t, repo, "customer")
+
+	tests := map[string]struct {
+		handler echo.HandlerFunc
+		body    string
+	}{
+		"set language":     {h.SetLanguage, `{"language":"de"}`},
+		"set display name": {h.SetDisplayName, `{"display_name":"Mallory"}`},
+	}
+	for name, tt := range tests {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := newTestContext(e, req, rec, target)
+		c.Impersonator = admin
+
+		require.NoError(t, tt.handler(c), name)
+		assert.Equal(t, http.StatusForbidden, rec.Code, name)
+	}
+
+	updated, err := repo.GetUserByID(context.Background(), target.ID)
+	require.NoError(t, err)
+	assert.Nil(t, updated.PreferredLanguage)
+	assert.Equal(t, "customer", updated.WebAuthnDisplayName())
+}
+
+func TestImpersonate_AlreadyImpersonating(t *testing.T) {
+	h, repo, _ := newTestAdminHandlers(t)
+	admin := newTestAdmin(t, repo)
+	target := testutil.NewTestUser(t, repo, "customer")
+	other := testutil.NewTestUser(t, repo, "other")
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/admin/users/x/impersonate", nil)
+	rec := httptest.NewRecorder()
+	c := newTestContext(e, req, rec, target)
+	c.Impersonator = admin
+	c.SetParamNames("id")
+	c.SetParamValues(strconv.FormatInt(other.ID, 10))
+
+	require.NoError(t, h.Impersonate(c))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestStopImpersonation(t *testing.T) {
+	h, repo, sessMgr := newTestAdminHandlers(t)
+	admin := newTestAdmin(t, repo)
+	target := testutil.NewTestUser(t, repo, "customer")
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/auth/impersonation/stop", nil)
+	req.Header.Set(echo.HeaderAccept, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := newTestContext(e, req, rec, target)
+	c.Impersonator = admin
+
+	require.NoError(t, h.StopImpersonation(c))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"username":"admin"`)
+
+	data := parseSessionCookie(t, sessMgr, rec)
+	assert.Equal(t, admin.ID, data.UserID)
+	assert.Zero(t, data.ImpersonatorID)
+
+	events, err := repo.ListAuditEvents(context.Background(), 10)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, models.AuditImpersonationStop, events[0].Action)
+	assert.Equal(t, target.ID, *events[0].TargetID)
+}
+
+func TestStopImpersonation_FormRedirects(t *testing.T) {
+	h, repo, _ := newTestAdminHandlers(t)
+	admin := newTestAdmin(t, repo)
+	target := testutil.NewTestUser(t, repo, "customer")
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/auth/impersonation/stop", nil)
+	rec := httptest.NewRecorder()
+	c := newTestContext(e, req, rec, target)
+	c.Impersonator = admin
+
+	require.NoError(t, h.StopImpersonation(c))
+
+	assert.Equal(t, http.StatusSeeOther, rec.Code)
+	assert.Equal(t, "/dashboard", rec.Header().Get(echo.HeaderLocation))
+}
+
+func TestStopImpersonation_NotImpersonating(t *testing.T) {
+	h, repo, _ := newTestAdminHandlers(t)
+	admin := newTestAdmin(t, repo)
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/auth/impersonation/stop", nil)
+	rec := httptest.NewRecorder()
+
+	require.NoError(t, h.StopImpersonation(newTestContext(e, req, rec, admin)))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
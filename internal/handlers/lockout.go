@@ -0,0 +1,126 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxDecoyEntries bounds the memory used to track unknown usernames.
+const maxDecoyEntries = 10000
+
+// lockoutWindow returns the configured lockout window.
+func (h *AuthHandlers) lockoutWindow() time.Duration {
+	return time.Duration(h.authCfg.LockoutWindow) * time.Second
+}
+
+// lockoutEnabled reports whether failed logins can lock an account.
+func (h *AuthHandlers) lockoutEnabled() bool {
+	return h.authCfg != nil && h.authCfg.LockoutThreshold > 0
+}
+
+// isLockedOut reports whether the user has reached the failed login threshold
+// from client. Failures are counted per client, so attempts from one network
+// cannot lock the account owner out everywhere.
+func (h *AuthHandlers) isLockedOut(ctx context.Context, userID int64, client string) (bool, error) {
+	if !h.lockoutEnabled() {
+		return false, nil
+	}
+	count, err := h.repo.GetRecentFailedLoginCount(ctx, userID, client, h.lockoutWindow())
+	if err != nil {
+		return false, err
+	}
+	return count >= int64(h.authCfg.LockoutThreshold), nil
+}
+
+// recordFailedLogin stores a failed attempt for an existing user.
+func (h *AuthHandlers) recordFailedLogin(ctx context.Context, userID int64, client string) {
+	if !h.lockoutEnabled() {
+		return
+	}
+	_ = h.repo.RecordFailedLogin(ctx, userID, client)
+}
+
+// resetFailedLogins clears the failed attempts from client after a successful
+// login.
+func (h *AuthHandlers) resetFailedLogins(ctx context.Context, userID int64, client string) {
+	if !h.lockoutEnabled() {
+		return
+	}
+	_ = h.repo.ResetFailedLogins(ctx, userID, client)
+}
+
+// lockoutError is returned while an account is locked; clients are told to
+// retry after one lockout window.
+func (h *AuthHandlers) lockoutError() *authError {
+	aerr := newAuthError(http.StatusTooManyRequests, ErrCodeTooManyAttempts, "auth_error_too_many_attempts")
+	aerr.retryAfter = h.authCfg.LockoutWindow
+	aerr.data = map[string]any{"Minutes": (h.authCfg.LockoutWindow + 59) / 60}
+	return aerr
+}
+
+// decoyLockout counts failed attempts for usernames that don't exist, so
+// unknown accounts get locked out exactly like real ones and a 429 doesn't
+// reveal which usernames are registered.
+type decoyLockout struct {
+	mu       sync.Mutex
+	failures map[string][]time.Time
+}
+
+func newDecoyLockout() *decoyLockout {
+	return &decoyLockout{failures: make(map[string][]time.Time)}
+}
+
+// record adds a failed attempt and returns the number of attempts in the window.
+func (d *decoyLockout) record(username, client string, now time.Time, window time.Duration) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	key := decoyKey(username, client)
+	if _, ok := d.failures[key]; !ok && len(d.failures) >= maxDecoyEntries {
+		d.prune(now, window)
+		if len(d.failures) >= maxDecoyEntries {
+			clear(d.failures)
+		}
+	}
+
+	d.failures[key] = append(recent(d.failures[key], now, window), now)
+	return len(d.failures[key])
+}
+
+// count returns the number of failed attempts in the window.
+func (d *decoyLockout) count(username, client string, now time.Time, window time.Duration) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(recent(d.failures[decoyKey(username, client)], now, window))
+}
+
+// decoyKey counts unknown usernames per client, like real accounts.
+func decoyKey(username, client string) string {
+	return strings.ToLower(username) + " " + client
+}
+
+// prune drops all usernames without attempts in the window.
+func (d *decoyLockout) prune(now time.Time, window time.Duration) {
+	for key, times := range d.failures {
+		if len(recent(times, now, window)) == 0 {
+			delete(d.failures, key)
+		}
+	}
+}
+
+// recent returns the attempts that fall within the window ending at now.
+func recent(times []time.Time, now time.Time, window time.Duration) []time.Time {
+	cutoff := now.Add(-window)
+	for i, t := range times {
+		if t.After(cutoff) {
+			return times[i:]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package handlers_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/oliverandrich/go-webapp-template/internal/config"
+	"github.com/oliverandrich/go-webapp-template/internal/handlers"
+	"github.com/oliverandrich/go-webapp-template/internal/repository"
+	"github.com/oliverandrich/go-webapp-template/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestLockoutHandlers(t *testing.T) (*handlers.AuthHandlers, *repository.Repository) {
+	t.Helper()
+	return newTestAuthHandlersWithConfig(t, 0, &config.AuthConfig{LockoutThreshold: 3, LockoutWindow: 900})
+}
+
+// recoveryLogin posts a recovery login and returns the recorder.
+func recoveryLogin(t *testing.T, h *handlers.AuthHandlers, username, code string) *httptest.ResponseRecorder {
+	t.Helper()
+	return recoveryLoginFrom(t, h, "192.0.2.1:1234", username, code)
+}
+
+// recoveryLoginFrom posts a recovery login from remoteAddr.
+func recoveryLoginFrom(t *testing.T, h *handlers.AuthHandlers, remoteAddr, username, code string) *httptest.ResponseRecorder {
+	t.Helper()
+	e := echo.New()
+	body := strings.NewReader(`{"username":"` + username + `","code":"` + code + `"}`)
+	req := httptest.NewRequest(http.MethodPost, "/auth/recovery", body)
+	req.RemoteAddr = remoteAddr
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+
+	require.NoError(t, h.RecoveryLogin(e.NewContext(req, rec)))
+	return rec
+}
+
+// newTestRecoveryCodes stores fresh recovery codes for a user and returns them.
+func newTestRecoveryCodes(t *testing.T, repo *repository.Repository, userID int64) []string {
+	t.Helper()
+	return storeRecoveryCodes(t, repo, userID, 2)
+}
+
+func TestRecoveryLogin_LockoutAfterFailures(t *testing.T) {
+	h, repo := newTestLockoutHandlers(t)
+	user := testutil.NewTestUser(t, repo, "testuser")
+	codes := newTestRecoveryCodes(t, repo, user.ID)
+
+	for range 3 {
+		rec := recoveryLogin(t, h, "testuser", "wrong-code")
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	}
+
+	// Even the correct code is rejected while locked out
+	rec := recoveryLogin(t, h, "testuser", codes[0])
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "900", rec.Header().Get("Retry-After"))
+
+	// Once the failures are gone the code works again
+	require.NoError(t, repo.ResetFailedLogins(context.Background(), user.ID, "192.0.2.1"))
+	rec = recoveryLogin(t, h, "testuser", codes[0])
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestRecoveryLogin_LockoutIsPerClient(t *testing.T) {
+	h, repo := newTestLockoutHandlers(t)
+	user := testutil.NewTestUser(t, repo, "testuser")
+	codes := newTestRecoveryCodes(t, repo, user.ID)
+
+	for range 3 {
+		assert.Equal(t, http.StatusUnauthorized, recoveryLoginFrom(t, h, "198.51.100.7:1234", "testuser", "wrong-code").Code)
+	}
+	assert.Equal(t, http.StatusTooManyRequests, recoveryLoginFrom(t, h, "198.51.100.7:1234", "testuser", codes[0]).Code)
+
+	// The account owner on another network is not locked out
+	assert.Equal(t, http.StatusOK, recoveryLogin(t, h, "testuser", codes[0]).Code)
+}
+
+func TestRecoveryLogin_SuccessResetsFailures(t *testing.T) {
+	h, repo := newTestLockoutHandlers(t)
+	user := testutil.NewTestUser(t, repo, "testuser")
+	codes := newTestRecoveryCodes(t, repo, user.ID)
+
+	for range 2 {
+		assert.Equal(t, http.StatusUnauthorized, recoveryLogin(t, h, "testuser", "wrong-code").Code)
+	}
+	assert.Equal(t, http.StatusOK, recoveryLogin(t, h, "testuser", codes[0]).Code)
+
+	count, err := repo.GetRecentFailedLoginCount(context.Background(), user.ID, "192.0.2.1", time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), count)
+
+	// Two more failures stay below the threshold
+	for range 2 {
+		assert.Equal(t, http.StatusUnauthorized, recoveryLogin(t, h, "testuser", "wrong-code").Code)
+	}
+	assert.Equal(t, http.StatusOK, recoveryLogin(t, h, "testuser", codes[1]).Code)
+}
+
+func TestRecoveryLogin_RecordsLastLogin(t *testing.T) {
+	h, repo := newTestLockoutHandlers(t)
+	user := testutil.NewTestUser(t, repo, "testuser")
+	codes := newTestRecoveryCodes(t, repo, user.ID)
+
+	assert.Equal(t, http.StatusUnauthorized, recoveryLogin(t, h, "testuser", "wrong-code").Code)
+	stats, err := repo.GetUserStats(context.Background(), user.ID)
+	require.NoError(t, err)
+	assert.Nil(t, stats.LastLoginAt)
+
+	assert.Equal(t, http.StatusOK, recoveryLogin(t, h, "testuser", codes[0]).Code)
+	stats, err = repo.GetUserStats(context.Background(), user.ID)
+	require.NoError(t, err)
+	assert.NotNil(t, stats.LastLoginAt)
+}
+
+func TestRecoveryLogin_UnknownUserLockedOutLikeRealOne(t *testing.T) {
+	h, _ := newTestLockoutHandlers(t)
+
+	for range 3 {
+		assert.Equal(t, http.StatusUnauthorized, recoveryLogin(t, h, "ghost", "wrong-code").Code)
+	}
+
+	rec := recoveryLogin(t, h, "ghost", "wrong-code")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "900", rec.Header().Get("Retry-After"))
+}
+
+func TestRecoveryLogin_LockoutDisabled(t *testing.T) {
+	h, repo := newTestAuthHandlers(t)
+	user := testutil.NewTestUser(t, repo, "testuser")
+	codes := newTestRecoveryCodes(t, repo, user.ID)
+
+	for range 10 {
+		assert.Equal(t, http.StatusUnauthorized, recoveryLogin(t, h, "testuser", "wrong-code").Code)
+	}
+	assert.Equal(t, http.StatusOK, recoveryLogin(t, h, "testuser", codes[0]).Code)
+}
+
+func TestRecoveryLogin_LockedOutTriggersThrottleEvent(t *testing.T) {
+	h, repo := newTestLockoutHandlers(t)
+	user := testutil.NewTestUser(t, repo, "testuser")
+	newTestRecoveryCodes(t, repo, user.ID)
+
+	for range 3 {
+		rec := recoveryLogin(t, h, "testuser", "wrong-code")
+		assert.Empty(t, rec.Header().Get("HX-Trigger"))
+	}
+
+	rec := recoveryLogin(t, h, "testuser", "wrong-code")
+	require.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.JSONEq(t, `{"authThrottle":{"retryAfterSeconds":900}}`, rec.Header().Get("HX-Trigger"))
+}
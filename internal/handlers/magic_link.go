@@ -0,0 +1,230 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/oliverandrich/go-webapp-template/internal/models"
+	"github.com/oliverandrich/go-webapp-template/internal/services/email"
+	"github.com/oliverandrich/go-webapp-template/internal/signedurl"
+	authtpl "github.com/oliverandrich/go-webapp-template/internal/templates/auth"
+)
+
+// magicLinkPath is the route of MagicLinkPage; links are signed for it.
+const magicLinkPath = "/auth/magic-link"
+
+// magicLinkLimitWindow is the window AuthConfig.MagicLinkLimit applies to.
+const magicLinkLimitWindow = time.Hour
+
+// SetMagicLinks enables sign-in links by email, signed with signer. They
+// also need the email service, so they stay off without email mode.
+func (h *AuthHandlers) SetMagicLinks(signer *signedurl.Signer) {
+	h.magicLinks = signer
+}
+
+// magicLinksEnabled reports whether sign-in links can be requested and used.
+func (h *AuthHandlers) magicLinksEnabled() bool {
+	return h.magicLinks != nil && h.email != nil
+}
+
+// magicLinkTTL returns how long a sign-in link stays valid.
+func (h *AuthHandlers) magicLinkTTL() time.Duration {
+	return time.Duration(h.authCfg.MagicLinkTTL) * time.Second
+}
+
+// MagicLinkEmailRequest is the request body for requesting a sign-in link.
+type MagicLinkEmailRequest struct {
+	Email string `json:"email" form:"email"`
+}
+
+// MagicLinkRequest emails a single-use sign-in link, e.g. to users who lost
+// all their passkeys. The answer is the same whether or not the address
+// belongs to an account, and addresses over the hourly limit silently get
+// no further links, so neither reveals which addresses are registered. A
+// token is generated and a link signed for unknown addresses too, so the
+// answer takes about as long. Clients over the hourly IP limit get a 429
+// for any address.
+func (h *AuthHandlers) MagicLinkRequest(c echo.Context) error {
+	if !h.magicLinksEnabled() {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "magic links are not enabled"})
+	}
+
+	var req MagicLinkEmailRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid request"})
+	}
+	req.Email = h.normalizeEmail(req.Email)
+
+	if req.Email == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "email is required"})
+	}
+
+	ok := map[string]string{"status": "ok"}
+	ctx := c.Request().Context()
+
+	if aerr := h.countMagicLinkRequest(c); aerr != nil {
+		return writeAuthError(c, aerr)
+	}
+
+	user, err := h.repo.GetUserByEmail(ctx, req.Email)
+	if err != nil {
+		if plainToken, _, _, tokenErr := h.email.GenerateToken(); tokenErr == nil {
+			_ = h.magicLinks.Sign(magicLinkPath, map[string]string{"token": plainToken}, h.magicLinkTTL())
+		}
+		return c.JSON(http.StatusOK, ok)
+	}
+
+	if limit := h.authCfg.MagicLinkLimit; limit > 0 {
+		count, countErr := h.repo.CountRecentMagicLinkTokens(ctx, user.ID, magicLinkLimitWindow)
+		if countErr != nil {
+			slog.Error("failed to count magic links", "error", countErr)
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to send sign-in link"})
+		}
+		if count >= int64(limit) {
+			slog.Warn("magic link limit reached", "user_id", user.ID)
+			return c.JSON(http.StatusOK, ok)
+		}
+	}
+
+	plainToken, tokenHash, _, err := h.email.GenerateToken()
+	if err != nil {
+		slog.Error("failed to generate magic link token", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to send sign-in link"})
+	}
+
+	ttl := h.magicLinkTTL()
+	if err = h.repo.CreateMagicLinkToken(ctx, user.ID, tokenHash, h.clock.Now().Add(ttl)); err != nil {
+		slog.Error("failed to store magic link token", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to send sign-in link"})
+	}
+
+	link := h.magicLinks.Sign(magicLinkPath, map[string]string{"token": plainToken}, ttl)
+
+	// Send the link (async)
+	go func() {
+		if sendErr := h.email.SendMagicLink(context.WithoutCancel(ctx), *user.Email, link, ttl); sendErr != nil {
+			slog.Error("failed to send magic link", "error", sendErr, "email", *user.Email)
+		}
+	}()
+
+	return c.JSON(http.StatusOK, ok)
+}
+
+// countMagicLinkRequest counts a sign-in link request for the client IP and
+// returns an error once the IP has exceeded the hourly limit.
+func (h *AuthHandlers) countMagicLinkRequest(c echo.Context) *authError {
+	limit := h.authCfg.MagicLinkIPLimit
+	if limit <= 0 {
+		return nil
+	}
+	count, err := h.repo.RecordMagicLinkRequest(c.Request().Context(), clientKey(c.RealIP()), magicLinkLimitWindow)
+	if err != nil {
+		slog.Error("failed to count magic link request", "error", err)
+		return newAuthError(http.StatusInternalServerError, ErrCodeInternal, "auth_error_internal")
+	}
+	if count <= int64(limit) {
+		return nil
+	}
+	aerr := newAuthError(http.StatusTooManyRequests, ErrCodeTooManyAttempts, "auth_error_magic_link_limit")
+	aerr.retryAfter = int(magicLinkLimitWindow.Seconds())
+	return aerr
+}
+
+// MagicLinkPage answers the link sent by MagicLinkRequest with a page that
+// signs in on a click. Opening the link doesn't use it up, so mail filters
+// that fetch every URL in a message can't spend it before the user does.
+func (h *AuthHandlers) MagicLinkPage(c echo.Context) error {
+	if !h.magicLinksEnabled() {
+		return echo.ErrNotFound
+	}
+	link := c.Request().URL.RequestURI()
+	if _, errorType := h.verifyMagicLink(link); errorType != "" {
+		return h.pages.Page(c, http.StatusBadRequest, "verify_error_title", authtpl.VerifyError(errorType))
+	}
+	return h.pages.Page(c, http.StatusOK, "magic_link_confirm_title", authtpl.MagicLinkConfirm(link))
+}
+
+// verifyMagicLink checks the path and signature of a sign-in link and
+// returns its token, or the VerifyError type when the link is not usable.
+func (h *AuthHandlers) verifyMagicLink(link string) (token, errorType string) {
+	if u, err := url.Parse(link); err != nil || u.Path != magicLinkPath {
+		return "", "invalid_token"
+	}
+	params, err := h.magicLinks.Verify(link)
+	switch {
+	case errors.Is(err, signedurl.ErrExpired):
+		return "", "token_expired"
+	case err != nil:
+		return "", "invalid_token"
+	case params["token"] == "":
+		return "", "missing_token"
+	}
+	return params["token"], ""
+}
+
+// MagicLinkConsume signs the user in with the link posted from
+// MagicLinkPage. The signature guards against altered links, the stored
+// token hash makes every link work exactly once.
+func (h *AuthHandlers) MagicLinkConsume(c echo.Context) error {
+	if !h.magicLinksEnabled() {
+		return echo.ErrNotFound
+	}
+
+	plainToken, errorType := h.verifyMagicLink(c.FormValue("link"))
+	if errorType != "" {
+		return h.pages.Page(c, http.StatusBadRequest, "verify_error_title", authtpl.VerifyError(errorType))
+	}
+
+	ctx := c.Request().Context()
+
+	token, err := h.repo.ConsumeMagicLinkToken(ctx, email.HashToken(plainToken))
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return h.pages.Page(c, http.StatusBadRequest, "verify_error_title", authtpl.VerifyError("invalid_token"))
+	case err != nil:
+		slog.Error("failed to consume magic link token", "error", err)
+		return h.pages.Page(c, http.StatusInternalServerError, "verify_error_title", authtpl.VerifyError("verification_failed"))
+	case h.clock.Now().After(token.ExpiresAt):
+		return h.pages.Page(c, http.StatusBadRequest, "verify_error_title", authtpl.VerifyError("token_expired"))
+	}
+
+	user, err := h.repo.GetUserByID(ctx, token.UserID)
+	if err != nil {
+		// The account was deleted after the link was sent
+		return h.pages.Page(c, http.StatusBadRequest, "verify_error_title", authtpl.VerifyError("invalid_token"))
+	}
+
+	// A link is no way around the lockout of recovery codes
+	locked, err := h.isLockedOut(ctx, user.ID, clientKey(c.RealIP()))
+	if err != nil {
+		slog.Error("failed to check lockout", "error", err)
+		return h.pages.Page(c, http.StatusInternalServerError, "verify_error_title", authtpl.VerifyError("verification_failed"))
+	}
+	if locked {
+		return h.pages.Page(c, http.StatusTooManyRequests, "verify_error_title", authtpl.VerifyError("too_many_attempts"))
+	}
+
+	cookie, err := h.newSession(ctx, user, 0, h.sessions.Duration())
+	if err != nil {
+		slog.Error("failed to create session after magic link", "error", err)
+		return h.pages.Page(c, http.StatusInternalServerError, "verify_error_title", authtpl.VerifyError("verification_failed"))
+	}
+	c.SetCookie(cookie)
+	if err := h.repo.RecordLogin(ctx, user.ID); err != nil {
+		slog.Error("failed to record login", "error", err, "user_id", user.ID)
+	}
+	if err := h.repo.CreateAuditEvent(ctx, user.ID, models.AuditMagicLinkLogin, user.ID); err != nil {
+		slog.Error("failed to record audit event", "error", err)
+	}
+
+	return c.Redirect(http.StatusSeeOther, "/dashboard")
+}
@@ -0,0 +1,339 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package handlers_test
+
+import (
+	"context"
+	"html"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/oliverandrich/go-webapp-template/internal/clock"
+	"github.com/oliverandrich/go-webapp-template/internal/config"
+	"github.com/oliverandrich/go-webapp-template/internal/handlers"
+	"github.com/oliverandrich/go-webapp-template/internal/i18n"
+	"github.com/oliverandrich/go-webapp-template/internal/models"
+	"github.com/oliverandrich/go-webapp-template/internal/repository"
+	"github.com/oliverandrich/go-webapp-template/internal/services/email"
+	"github.com/oliverandrich/go-webapp-template/internal/services/session"
+	"github.com/oliverandrich/go-webapp-template/internal/services/webauthn"
+	"github.com/oliverandrich/go-webapp-template/internal/signedurl"
+	"github.com/oliverandrich/go-webapp-template/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wneessen/go-mail"
+	"golang.org/x/text/language"
+)
+
+var magicLinkPattern = regexp.MustCompile(`http://localhost:8080(/auth/magic-link\?\S+)`)
+
+// newTestMagicLinkHandlers returns email mode handlers with sign-in links
+// enabled. Sent links arrive on the returned channel as path and query.
+func newTestMagicLinkHandlers(t *testing.T, limit int) (*handlers.AuthHandlers, *repository.Repository, *clock.Fake, <-chan string) {
+	t.Helper()
+	return newTestMagicLinkHandlersWithConfig(t, &config.AuthConfig{MagicLinkLimit: limit})
+}
+
+// newTestMagicLinkHandlersWithConfig is newTestMagicLinkHandlers with the
+// limits and lockout settings of authCfg; email mode and sign-in links are
+// always enabled.
+func newTestMagicLinkHandlersWithConfig(t *testing.T, authCfg *config.AuthConfig) (*handlers.AuthHandlers, *repository.Repository, *clock.Fake, <-chan string) {
+	t.Helper()
+	_, repo := testutil.NewTestDB(t)
+
+	waSvc, err := webauthn.NewService(&config.WebAuthnConfig{
+		RPID:          "localhost",
+		RPOrigin:      "http://localhost:8080",
+		RPDisplayName: "Test App",
+	})
+	require.NoError(t, err)
+	sessMgr, err := session.NewManager(&config.SessionConfig{
+		CookieName: "_test_session",
+		MaxAge:     3600,
+		HashKey:    testHashKey,
+	}, false)
+	require.NoError(t, err)
+
+	emailSvc, err := email.NewService(&config.SMTPConfig{Host: "127.0.0.1", Port: 1, From: "noreply@example.com"}, "http://localhost:8080")
+	require.NoError(t, err)
+	links := make(chan string, 10)
+	emailSvc.SetSendFunc(func(msg *mail.Msg) error {
+		for _, part := range msg.GetParts() {
+			content, contentErr := part.GetContent()
+			require.NoError(t, contentErr)
+			if m := magicLinkPattern.FindSubmatch(content); m != nil {
+				links <- string(m[1])
+				return nil
+			}
+		}
+		t.Error("message contains no magic link")
+		return nil
+	})
+	authCfg.UseEmail = true
+	authCfg.MagicLink = true
+	authCfg.MagicLinkTTL = 900
+	h := handlers.NewAuth(repo, waSvc, sessMgr, emailSvc, authCfg)
+
+	fake := clock.NewFake(time.Now())
+	signer := signedurl.New([]byte("0123456789abcdef0123456789abcdef"))
+	signer.SetClock(fake)
+	h.SetClock(fake)
+	h.SetMagicLinks(signer)
+	return h, repo, fake, links
+}
+
+func requestMagicLink(t *testing.T, h *handlers.AuthHandlers, address string) *httptest.ResponseRecorder {
+	t.Helper()
+	return requestMagicLinkFrom(t, h, "192.0.2.1:1234", address)
+}
+
+// requestMagicLinkFrom requests a sign-in link for address from remoteAddr.
+func requestMagicLinkFrom(t *testing.T, h *handlers.AuthHandlers, remoteAddr, address string) *httptest.ResponseRecorder {
+	t.Helper()
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/auth/magic-link", strings.NewReader(`{"email":"`+address+`"}`))
+	req.RemoteAddr = remoteAddr
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	require.NoError(t, h.MagicLinkRequest(e.NewContext(req, rec)))
+	return rec
+}
+
+// openMagicLink opens link like a browser following it from the email.
+func openMagicLink(t *testing.T, h *handlers.AuthHandlers, link string) *httptest.ResponseRecorder {
+	t.Helper()
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, link, nil)
+	req = req.WithContext(i18n.WithLocale(req.Context(), language.English))
+	rec := httptest.NewRecorder()
+	require.NoError(t, h.MagicLinkPage(e.NewContext(req, rec)))
+	return rec
+}
+
+// consumeMagicLink submits link from the confirmation page.
+func consumeMagicLink(t *testing.T, h *handlers.AuthHandlers, link string) *httptest.ResponseRecorder {
+	t.Helper()
+	e := echo.New()
+	form := url.Values{"link": {link}}
+	req := httptest.NewRequest(http.MethodPost, "/auth/magic-link/consume", strings.NewReader(form.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	req = req.WithContext(i18n.WithLocale(req.Context(), language.English))
+	rec := httptest.NewRecorder()
+	require.NoError(t, h.MagicLinkConsume(e.NewContext(req, rec)))
+	return rec
+}
+
+func receiveLink(t *testing.T, links <-chan string) string {
+	t.Helper()
+	select {
+	case link := <-links:
+		return link
+	case <-time.After(5 * time.Second):
+		t.Fatal("no magic link sent")
+		return ""
+	}
+}
+
+func TestMagicLinkRequest_SendsLink(t *testing.T) {
+	h, repo, _, links := newTestMagicLinkHandlers(t, 3)
+	user, err := repo.CreateUserWithEmail(context.Background(), "user@example.com")
+	require.NoError(t, err)
+
+	rec := requestMagicLink(t, h, "User@Example.com")
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, receiveLink(t, links), "token=")
+	count, err := repo.CountRecentMagicLinkTokens(context.Background(), user.ID, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestMagicLinkRequest_UnknownEmailLooksTheSame(t *testing.T) {
+	h, _, _, links := newTestMagicLinkHandlers(t, 3)
+
+	rec := requestMagicLink(t, h, "nobody@example.com")
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"status":"ok"}`, rec.Body.String())
+	assert.Empty(t, links)
+}
+
+func TestMagicLinkRequest_RateLimited(t *testing.T) {
+	h, repo, fake, links := newTestMagicLinkHandlers(t, 1)
+	user, err := repo.CreateUserWithEmail(context.Background(), "user@example.com")
+	require.NoError(t, err)
+	repo.SetClock(fake)
+
+	require.Equal(t, http.StatusOK, requestMagicLink(t, h, "user@example.com").Code)
+	receiveLink(t, links)
+
+	// Over the limit the answer stays the same, but no link is issued
+	rec := requestMagicLink(t, h, "user@example.com")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	count, err := repo.CountRecentMagicLinkTokens(context.Background(), user.ID, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	// An hour later links can be requested again
+	fake.Advance(time.Hour + time.Second)
+	requestMagicLink(t, h, "user@example.com")
+	receiveLink(t, links)
+}
+
+func TestMagicLinkRequest_IPLimit(t *testing.T) {
+	h, repo, _, links := newTestMagicLinkHandlersWithConfig(t, &config.AuthConfig{MagicLinkIPLimit: 2})
+	_, err := repo.CreateUserWithEmail(context.Background(), "user@example.com")
+	require.NoError(t, err)
+
+	// Unknown addresses count as well, so probing many addresses is throttled
+	assert.Equal(t, http.StatusOK, requestMagicLink(t, h, "ghost@example.com").Code)
+	assert.Equal(t, http.StatusOK, requestMagicLink(t, h, "user@example.com").Code)
+	receiveLink(t, links)
+
+	rec := requestMagicLink(t, h, "user@example.com")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "3600", rec.Header().Get("Retry-After"))
+	assert.Empty(t, links)
+
+	// Other clients are not affected
+	assert.Equal(t, http.StatusOK, requestMagicLinkFrom(t, h, "198.51.100.7:1234", "user@example.com").Code)
+	receiveLink(t, links)
+}
+
+func TestMagicLinkRequest_UsedLinksCountTowardsLimit(t *testing.T) {
+	h, repo, _, links := newTestMagicLinkHandlers(t, 1)
+	_, err := repo.CreateUserWithEmail(context.Background(), "user@example.com")
+	require.NoError(t, err)
+
+	requestMagicLink(t, h, "user@example.com")
+	require.Equal(t, http.StatusSeeOther, consumeMagicLink(t, h, receiveLink(t, links)).Code)
+
+	requestMagicLink(t, h, "user@example.com")
+	select {
+	case <-links:
+		t.Fatal("a used link must still count towards the hourly limit")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestMagicLinkRequest_Disabled(t *testing.T) {
+	h, _ := newTestEmailAuthHandlers(t)
+
+	rec := requestMagicLink(t, h, "user@example.com")
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestMagicLinkPage_DoesNotUseLink(t *testing.T) {
+	h, repo, _, links := newTestMagicLinkHandlers(t, 3)
+	_, err := repo.CreateUserWithEmail(context.Background(), "user@example.com")
+	require.NoError(t, err)
+	requestMagicLink(t, h, "user@example.com")
+	link := receiveLink(t, links)
+
+	// Opening the link, e.g. by a mail scanner, only shows the confirmation
+	for range 2 {
+		rec := openMagicLink(t, h, link)
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Empty(t, rec.Header().Get("Set-Cookie"))
+		assert.Contains(t, rec.Body.String(), `action="/auth/magic-link/consume"`)
+		assert.Contains(t, rec.Body.String(), html.EscapeString(link))
+	}
+
+	assert.Equal(t, http.StatusSeeOther, consumeMagicLink(t, h, link).Code)
+}
+
+func TestMagicLinkPage_TamperedLink(t *testing.T) {
+	h, repo, _, links := newTestMagicLinkHandlers(t, 3)
+	_, err := repo.CreateUserWithEmail(context.Background(), "user@example.com")
+	require.NoError(t, err)
+	requestMagicLink(t, h, "user@example.com")
+	link := receiveLink(t, links)
+
+	rec := openMagicLink(t, h, strings.Replace(link, "token=", "token=0", 1))
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestMagicLinkConsume_SignsIn(t *testing.T) {
+	h, repo, _, links := newTestMagicLinkHandlers(t, 3)
+	user, err := repo.CreateUserWithEmail(context.Background(), "user@example.com")
+	require.NoError(t, err)
+	requestMagicLink(t, h, "user@example.com")
+
+	rec := consumeMagicLink(t, h, receiveLink(t, links))
+
+	assert.Equal(t, http.StatusSeeOther, rec.Code)
+	assert.Equal(t, "/dashboard", rec.Header().Get(echo.HeaderLocation))
+	assert.Contains(t, rec.Header().Get("Set-Cookie"), "_test_session=")
+
+	events, err := repo.ListAuditEvents(context.Background(), 10)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, models.AuditMagicLinkLogin, events[0].Action)
+	assert.Equal(t, user.ID, *events[0].ActorID)
+}
+
+func TestMagicLinkConsume_RejectsReuse(t *testing.T) {
+	h, repo, _, links := newTestMagicLinkHandlers(t, 3)
+	_, err := repo.CreateUserWithEmail(context.Background(), "user@example.com")
+	require.NoError(t, err)
+	requestMagicLink(t, h, "user@example.com")
+	link := receiveLink(t, links)
+
+	require.Equal(t, http.StatusSeeOther, consumeMagicLink(t, h, link).Code)
+	rec := consumeMagicLink(t, h, link)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Empty(t, rec.Header().Get("Set-Cookie"))
+}
+
+func TestMagicLinkConsume_Expired(t *testing.T) {
+	h, repo, fake, links := newTestMagicLinkHandlers(t, 3)
+	_, err := repo.CreateUserWithEmail(context.Background(), "user@example.com")
+	require.NoError(t, err)
+	requestMagicLink(t, h, "user@example.com")
+	link := receiveLink(t, links)
+
+	fake.Advance(15*time.Minute + time.Second)
+	rec := consumeMagicLink(t, h, link)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "expired")
+	assert.Empty(t, rec.Header().Get("Set-Cookie"))
+}
+
+func TestMagicLinkConsume_TamperedLink(t *testing.T) {
+	h, repo, _, links := newTestMagicLinkHandlers(t, 3)
+	_, err := repo.CreateUserWithEmail(context.Background(), "user@example.com")
+	require.NoError(t, err)
+	requestMagicLink(t, h, "user@example.com")
+	link := receiveLink(t, links)
+
+	rec := consumeMagicLink(t, h, strings.Replace(link, "token=", "token=0", 1))
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Empty(t, rec.Header().Get("Set-Cookie"))
+}
+
+func TestMagicLinkConsume_LockedOut(t *testing.T) {
+	h, repo, _, links := newTestMagicLinkHandlersWithConfig(t, &config.AuthConfig{LockoutThreshold: 1, LockoutWindow: 900})
+	user, err := repo.CreateUserWithEmail(context.Background(), "user@example.com")
+	require.NoError(t, err)
+	requestMagicLink(t, h, "user@example.com")
+	link := receiveLink(t, links)
+	require.NoError(t, repo.RecordFailedLogin(context.Background(), user.ID, "192.0.2.1"))
+
+	rec := consumeMagicLink(t, h, link)
+
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Too many failed sign-in attempts")
+	assert.Empty(t, rec.Header().Get("Set-Cookie"))
+}
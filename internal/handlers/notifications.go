@@ -0,0 +1,78 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/oliverandrich/go-webapp-template/internal/appcontext"
+	"github.com/oliverandrich/go-webapp-template/internal/models"
+)
+
+// maxUnreadNotifications bounds the notifications returned by ListNotifications.
+const maxUnreadNotifications = 50
+
+// NotificationListResponse is the JSON body returned by ListNotifications.
+type NotificationListResponse struct {
+	Notifications []models.Notification `json:"notifications"`
+}
+
+// ListNotifications returns the signed-in user's unread notifications,
+// newest first.
+func (h *Handlers) ListNotifications(c echo.Context) error {
+	cc, ok := c.(*appcontext.Context)
+	if !ok || !cc.IsAuthenticated() {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "not authenticated"})
+	}
+
+	notifications, err := h.repo.ListUnreadNotifications(c.Request().Context(), cc.GetUser().ID, maxUnreadNotifications)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to get notifications"})
+	}
+
+	return c.JSON(http.StatusOK, NotificationListResponse{Notifications: notifications})
+}
+
+// MarkNotificationRead marks one of the signed-in user's notifications as read.
+func (h *Handlers) MarkNotificationRead(c echo.Context) error {
+	cc, ok := c.(*appcontext.Context)
+	if !ok || !cc.IsAuthenticated() {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "not authenticated"})
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid notification id"})
+	}
+
+	err = h.repo.MarkNotificationRead(c.Request().Context(), id, cc.GetUser().ID)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "notification not found"})
+	case err != nil:
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to update notification"})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// MarkAllNotificationsRead marks all of the signed-in user's notifications as
+// read. Returns {"status": "ok", "updated": n}.
+func (h *Handlers) MarkAllNotificationsRead(c echo.Context) error {
+	cc, ok := c.(*appcontext.Context)
+	if !ok || !cc.IsAuthenticated() {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "not authenticated"})
+	}
+
+	updated, err := h.repo.MarkAllNotificationsRead(c.Request().Context(), cc.GetUser().ID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to update notifications"})
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{"status": "ok", "updated": updated})
+}
@@ -0,0 +1,106 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/oliverandrich/go-webapp-template/internal/handlers"
+	"github.com/oliverandrich/go-webapp-template/internal/models"
+	"github.com/oliverandrich/go-webapp-template/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func listNotifications(t *testing.T, h *handlers.Handlers, user *models.User) handlers.NotificationListResponse {
+	t.Helper()
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	require.NoError(t, h.ListNotifications(newTestContext(e, httptest.NewRequest(http.MethodGet, "/notifications", nil), rec, user)))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var resp handlers.NotificationListResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	return resp
+}
+
+func markNotificationRead(t *testing.T, h *handlers.Handlers, user *models.User, id string) *httptest.ResponseRecorder {
+	t.Helper()
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	c := newTestContext(e, httptest.NewRequest(http.MethodPost, "/notifications/"+id+"/read", nil), rec, user)
+	c.SetParamNames("id")
+	c.SetParamValues(id)
+	require.NoError(t, h.MarkNotificationRead(c))
+	return rec
+}
+
+func TestListNotifications(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	h := handlers.New(repo)
+	user := testutil.NewTestUser(t, repo, "testuser")
+	_, err := repo.CreateNotification(context.Background(), user.ID, "passkey.added", map[string]string{"name": "YubiKey"})
+	require.NoError(t, err)
+
+	resp := listNotifications(t, h, user)
+
+	require.Len(t, resp.Notifications, 1)
+	assert.Equal(t, "passkey.added", resp.Notifications[0].Type)
+	assert.JSONEq(t, `{"name":"YubiKey"}`, string(resp.Notifications[0].Payload))
+}
+
+func TestListNotifications_Unauthenticated(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	h := handlers.New(repo)
+
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	require.NoError(t, h.ListNotifications(newTestContext(e, httptest.NewRequest(http.MethodGet, "/notifications", nil), rec, nil)))
+
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestMarkNotificationRead(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	h := handlers.New(repo)
+	user := testutil.NewTestUser(t, repo, "testuser")
+	other := testutil.NewTestUser(t, repo, "other")
+	n, err := repo.CreateNotification(context.Background(), user.ID, "test", nil)
+	require.NoError(t, err)
+	id := strconv.FormatInt(n.ID, 10)
+
+	assert.Equal(t, http.StatusBadRequest, markNotificationRead(t, h, user, "abc").Code)
+	assert.Equal(t, http.StatusNotFound, markNotificationRead(t, h, other, id).Code)
+	require.Len(t, listNotifications(t, h, user).Notifications, 1)
+
+	assert.Equal(t, http.StatusOK, markNotificationRead(t, h, user, id).Code)
+	assert.Empty(t, listNotifications(t, h, user).Notifications)
+
+	// Marking it again is harmless
+	assert.Equal(t, http.StatusOK, markNotificationRead(t, h, user, id).Code)
+}
+
+func TestMarkAllNotificationsRead(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	h := handlers.New(repo)
+	user := testutil.NewTestUser(t, repo, "testuser")
+	for range 2 {
+		_, err := repo.CreateNotification(context.Background(), user.ID, "test", nil)
+		require.NoError(t, err)
+	}
+
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	require.NoError(t, h.MarkAllNotificationsRead(newTestContext(e, httptest.NewRequest(http.MethodPost, "/notifications/read", nil), rec, user)))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"status":"ok","updated":2}`, rec.Body.String())
+	assert.Empty(t, listNotifications(t, h, user).Notifications)
+}
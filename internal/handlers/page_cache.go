@@ -0,0 +1,104 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package handlers
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/a-h/templ"
+	"github.com/oliverandrich/go-webapp-template/internal/appcontext"
+	"github.com/oliverandrich/go-webapp-template/internal/clock"
+	"github.com/oliverandrich/go-webapp-template/internal/templates"
+)
+
+// homeCacheTTL is how long the rendered home page content is reused for
+// anonymous visitors.
+const homeCacheTTL = time.Minute
+
+// pageCache keeps rendered page content in memory for a short time. Only the
+// content is cached; the layout carries per-request values such as the CSP
+// nonce and is rendered every time.
+type pageCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	clock   clock.Clock
+	entries map[string]pageCacheEntry
+}
+
+type pageCacheEntry struct {
+	html      string
+	expiresAt time.Time
+}
+
+func newPageCache(ttl time.Duration) *pageCache {
+	return &pageCache{ttl: ttl, clock: clock.Real{}, entries: make(map[string]pageCacheEntry)}
+}
+
+// component returns content wrapped so that it is rendered at most once per
+// key and TTL. Content must not depend on anything but the key.
+func (p *pageCache) component(key string, content templ.Component) templ.Component {
+	return templ.ComponentFunc(func(ctx context.Context, w io.Writer) error {
+		now := p.clock.Now()
+		if html, ok := p.get(key, now); ok {
+			_, err := io.WriteString(w, html)
+			return err
+		}
+
+		buf := templ.GetBuffer()
+		defer templ.ReleaseBuffer(buf)
+		if err := content.Render(ctx, buf); err != nil {
+			return err
+		}
+		p.put(key, buf.String(), now)
+		_, err := w.Write(buf.Bytes())
+		return err
+	})
+}
+
+func (p *pageCache) get(key string, now time.Time) (string, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	entry, ok := p.entries[key]
+	if !ok || now.After(entry.expiresAt) {
+		return "", false
+	}
+	return entry.html, true
+}
+
+// put stores html under key and drops expired entries.
+func (p *pageCache) put(key, html string, now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for k, entry := range p.entries {
+		if now.After(entry.expiresAt) {
+			delete(p.entries, k)
+		}
+	}
+	p.entries[key] = pageCacheEntry{html: html, expiresAt: now.Add(p.ttl)}
+}
+
+func (p *pageCache) len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.entries)
+}
+
+// anonymousPageKey identifies the content of a page as seen by anonymous
+// visitors: it varies with the locale and the path prefix, and the asset
+// paths tie it to the deployed build, so a deploy starts with a fresh cache.
+func anonymousPageKey(ctx context.Context, page string) string {
+	return strings.Join([]string{
+		page,
+		templates.Locale(ctx),
+		appcontext.PathPrefixFrom(ctx),
+		templates.CSSPath(ctx),
+		templates.JSPath(ctx),
+	}, "\x00")
+}
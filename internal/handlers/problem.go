@@ -0,0 +1,53 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// MIMEApplicationProblemJSON is the media type of RFC 7807 error responses.
+const MIMEApplicationProblemJSON = "application/problem+json"
+
+// Problem is an RFC 7807 problem details object. Code is an extension member
+// carrying the stable error code of the JSON API.
+type Problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Code     APIErrorCode `json:"code,omitempty"`
+}
+
+// ProblemJSON writes an application/problem+json error for the request path.
+// An empty title defaults to the status text.
+func ProblemJSON(c echo.Context, status int, title, detail string) error {
+	return writeProblem(c, Problem{Status: status, Title: title, Detail: detail})
+}
+
+// writeProblem fills in the defaults of p and writes it.
+func writeProblem(c echo.Context, p Problem) error {
+	if p.Type == "" {
+		p.Type = "about:blank"
+	}
+	if p.Title == "" {
+		p.Title = http.StatusText(p.Status)
+	}
+	if p.Instance == "" {
+		p.Instance = c.Request().URL.Path
+	}
+	c.Response().Header().Set(echo.HeaderContentType, MIMEApplicationProblemJSON)
+	return c.JSON(p.Status, p)
+}
+
+// wantsProblem reports whether an error for this request should be sent as
+// problem details: requests to the JSON API and clients asking for them.
+func wantsProblem(c echo.Context) bool {
+	return strings.HasPrefix(c.Request().URL.Path, "/api/") ||
+		strings.Contains(c.Request().Header.Get(echo.HeaderAccept), MIMEApplicationProblemJSON)
+}
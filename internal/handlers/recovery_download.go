@@ -0,0 +1,125 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package handlers
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/oliverandrich/go-webapp-template/internal/appcontext"
+)
+
+// recoveryDownloadTTL is how long freshly generated recovery codes can be
+// downloaded. Afterwards only their hashes exist.
+const recoveryDownloadTTL = 5 * time.Minute
+
+// RecoveryCodesDownload is the JSON file offered by DownloadRecoveryCodes.
+type RecoveryCodesDownload struct {
+	Username    string    `json:"username"`
+	GeneratedAt time.Time `json:"generated_at"`
+	Codes       []string  `json:"codes"`
+}
+
+// DownloadRecoveryCodes sends recovery codes generated moments ago as an
+// attachment, as plain text (one code per line) or JSON (format=json). The
+// codes are held in memory behind a short-lived token bound to the user and
+// can't be fetched again once it expires.
+func (h *AuthHandlers) DownloadRecoveryCodes(c echo.Context) error {
+	cc, ok := c.(*appcontext.Context)
+	if !ok || !cc.IsAuthenticated() {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "not authenticated"})
+	}
+	if cc.IsImpersonating() {
+		return impersonationForbidden(c)
+	}
+	user := cc.GetUser()
+
+	format := c.FormValue("format")
+	if format != "" && format != "txt" && format != "json" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "format must be txt or json"})
+	}
+
+	download, ok := h.downloads.get(c.FormValue("token"), user.ID, h.clock.Now())
+	if !ok {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "download expired"})
+	}
+
+	c.Response().Header().Set("Cache-Control", "no-store")
+
+	if format == "json" {
+		var buf bytes.Buffer
+		if err := json.NewEncoder(&buf).Encode(RecoveryCodesDownload{
+			Username:    user.Username,
+			GeneratedAt: download.generatedAt.UTC(),
+			Codes:       download.codes,
+		}); err != nil {
+			return err
+		}
+		return ServeAttachment(c, "recovery-codes.json", echo.MIMEApplicationJSON, &buf)
+	}
+
+	body := strings.NewReader(strings.Join(download.codes, "\n") + "\n")
+	return ServeAttachment(c, "recovery-codes.txt", echo.MIMETextPlainCharsetUTF8, body)
+}
+
+// recoveryDownloads holds plaintext recovery codes in memory for a short
+// time after generation, keyed by a random token.
+type recoveryDownloads struct {
+	mu      sync.Mutex
+	entries map[string]recoveryDownload
+}
+
+type recoveryDownload struct {
+	userID      int64
+	codes       []string
+	generatedAt time.Time
+}
+
+func newRecoveryDownloads() *recoveryDownloads {
+	return &recoveryDownloads{entries: make(map[string]recoveryDownload)}
+}
+
+// put stores the codes and returns the download token. Earlier downloads of
+// the same user are dropped, since their codes are no longer valid.
+func (d *recoveryDownloads) put(userID int64, codes []string, now time.Time) (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(buf)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for key, entry := range d.entries {
+		if entry.userID == userID || now.Sub(entry.generatedAt) > recoveryDownloadTTL {
+			delete(d.entries, key)
+		}
+	}
+	d.entries[token] = recoveryDownload{userID: userID, codes: codes, generatedAt: now}
+	return token, nil
+}
+
+// get returns the download for token if it belongs to userID and hasn't expired.
+func (d *recoveryDownloads) get(token string, userID int64, now time.Time) (recoveryDownload, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	entry, ok := d.entries[token]
+	if !ok || entry.userID != userID {
+		return recoveryDownload{}, false
+	}
+	if now.Sub(entry.generatedAt) > recoveryDownloadTTL {
+		delete(d.entries, token)
+		return recoveryDownload{}, false
+	}
+	return entry, true
+}
@@ -0,0 +1,137 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/oliverandrich/go-webapp-template/internal/clock"
+	"github.com/oliverandrich/go-webapp-template/internal/handlers"
+	"github.com/oliverandrich/go-webapp-template/internal/models"
+	"github.com/oliverandrich/go-webapp-template/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// regenerateCodes regenerates the user's recovery codes through the JSON
+// API and returns the response including the download token.
+func regenerateCodes(t *testing.T, h *handlers.AuthHandlers, user *models.User) handlers.RecoveryCodesResponse {
+	t.Helper()
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/auth/credentials/recovery-codes", nil)
+	req.Header.Set(echo.HeaderAccept, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	require.NoError(t, h.RegenerateRecoveryCodes(newTestContext(e, req, rec, user)))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var resp handlers.RecoveryCodesResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.NotEmpty(t, resp.DownloadToken)
+	return resp
+}
+
+func downloadCodes(t *testing.T, h *handlers.AuthHandlers, user *models.User, token, format string) *httptest.ResponseRecorder {
+	t.Helper()
+	form := url.Values{"token": {token}, "format": {format}}
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/auth/recovery-codes/download", strings.NewReader(form.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	rec := httptest.NewRecorder()
+	require.NoError(t, h.DownloadRecoveryCodes(newTestContext(e, req, rec, user)))
+	return rec
+}
+
+func TestDownloadRecoveryCodes_Text(t *testing.T) {
+	h, repo := newTestAuthHandlers(t)
+	user := testutil.NewTestUser(t, repo, "testuser")
+	resp := regenerateCodes(t, h, user)
+
+	rec := downloadCodes(t, h, user, resp.DownloadToken, "txt")
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, echo.MIMETextPlainCharsetUTF8, rec.Header().Get(echo.HeaderContentType))
+	assert.Equal(t, `attachment; filename="recovery-codes.txt"`, rec.Header().Get(echo.HeaderContentDisposition))
+	assert.Equal(t, "no-store", rec.Header().Get("Cache-Control"))
+	assert.Equal(t, "nosniff", rec.Header().Get(echo.HeaderXContentTypeOptions))
+	assert.Equal(t, strings.Join(resp.Codes, "\n")+"\n", rec.Body.String())
+}
+
+func TestDownloadRecoveryCodes_JSON(t *testing.T) {
+	h, repo := newTestAuthHandlers(t)
+	user := testutil.NewTestUser(t, repo, "testuser")
+	resp := regenerateCodes(t, h, user)
+
+	rec := downloadCodes(t, h, user, resp.DownloadToken, "json")
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `attachment; filename="recovery-codes.json"`, rec.Header().Get(echo.HeaderContentDisposition))
+	assert.Equal(t, "nosniff", rec.Header().Get(echo.HeaderXContentTypeOptions))
+	assert.Equal(t, echo.MIMEApplicationJSON, rec.Header().Get(echo.HeaderContentType))
+	var download handlers.RecoveryCodesDownload
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &download))
+	assert.Equal(t, "testuser", download.Username)
+	assert.Equal(t, resp.Codes, download.Codes)
+	assert.False(t, download.GeneratedAt.IsZero())
+}
+
+func TestDownloadRecoveryCodes_Expired(t *testing.T) {
+	h, repo := newTestAuthHandlers(t)
+	fake := clock.NewFake(time.Now())
+	h.SetClock(fake)
+	user := testutil.NewTestUser(t, repo, "testuser")
+	resp := regenerateCodes(t, h, user)
+
+	fake.Advance(6 * time.Minute)
+	rec := downloadCodes(t, h, user, resp.DownloadToken, "txt")
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.NotContains(t, rec.Body.String(), resp.Codes[0])
+}
+
+func TestDownloadRecoveryCodes_ReplacedByNewCodes(t *testing.T) {
+	h, repo := newTestAuthHandlers(t)
+	user := testutil.NewTestUser(t, repo, "testuser")
+	first := regenerateCodes(t, h, user)
+	regenerateCodes(t, h, user)
+
+	rec := downloadCodes(t, h, user, first.DownloadToken, "txt")
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestDownloadRecoveryCodes_OtherUser(t *testing.T) {
+	h, repo := newTestAuthHandlers(t)
+	user := testutil.NewTestUser(t, repo, "testuser")
+	other := testutil.NewTestUser(t, repo, "other")
+	resp := regenerateCodes(t, h, user)
+
+	rec := downloadCodes(t, h, other, resp.DownloadToken, "txt")
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestDownloadRecoveryCodes_InvalidFormat(t *testing.T) {
+	h, repo := newTestAuthHandlers(t)
+	user := testutil.NewTestUser(t, repo, "testuser")
+	resp := regenerateCodes(t, h, user)
+
+	rec := downloadCodes(t, h, user, resp.DownloadToken, "csv")
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestDownloadRecoveryCodes_Unauthenticated(t *testing.T) {
+	h, _ := newTestAuthHandlers(t)
+
+	rec := downloadCodes(t, h, nil, "token", "txt")
+
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
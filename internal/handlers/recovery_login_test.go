@@ -0,0 +1,100 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/oliverandrich/go-webapp-template/internal/repository"
+	"github.com/oliverandrich/go-webapp-template/internal/services/recovery"
+	"github.com/oliverandrich/go-webapp-template/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recoveryLoginResponse is the success body of RecoveryLogin.
+type recoveryLoginResponse struct {
+	Status         string `json:"status"`
+	RemainingCodes int64  `json:"remaining_codes"`
+	LowCodes       bool   `json:"low_codes"`
+}
+
+// storeRecoveryCodes stores count fresh recovery codes for a user and
+// returns them.
+func storeRecoveryCodes(t *testing.T, repo *repository.Repository, userID int64, count int) []string {
+	t.Helper()
+	codes, hashes, err := recovery.NewService().GenerateCodes(count)
+	require.NoError(t, err)
+	require.NoError(t, repo.CreateRecoveryCodes(context.Background(), userID, hashes))
+	return codes
+}
+
+func decodeRecoveryLogin(t *testing.T, rec *httptest.ResponseRecorder) recoveryLoginResponse {
+	t.Helper()
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var resp recoveryLoginResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	return resp
+}
+
+func TestRecoveryLogin_ValidCodeSignsIn(t *testing.T) {
+	h, repo := newTestAuthHandlers(t)
+	user := testutil.NewTestUser(t, repo, "testuser")
+	codes := storeRecoveryCodes(t, repo, user.ID, recovery.CodeCount)
+
+	rec := recoveryLogin(t, h, "testuser", codes[0])
+
+	resp := decodeRecoveryLogin(t, rec)
+	assert.Equal(t, "ok", resp.Status)
+	assert.Equal(t, int64(recovery.CodeCount-1), resp.RemainingCodes)
+	assert.False(t, resp.LowCodes)
+	assert.Contains(t, rec.Header().Get("Set-Cookie"), "_test_session=")
+
+	// The code is used up
+	assert.Equal(t, http.StatusUnauthorized, recoveryLogin(t, h, "testuser", codes[0]).Code)
+}
+
+func TestRecoveryLogin_InvalidCodeFails(t *testing.T) {
+	h, repo := newTestAuthHandlers(t)
+	user := testutil.NewTestUser(t, repo, "testuser")
+	storeRecoveryCodes(t, repo, user.ID, recovery.CodeCount)
+
+	rec := recoveryLogin(t, h, "testuser", "wrong-code")
+
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Empty(t, rec.Header().Get("Set-Cookie"))
+	count, err := repo.GetUnusedRecoveryCodeCount(context.Background(), user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(recovery.CodeCount), count)
+}
+
+func TestRecoveryLogin_WarnsWhenFewCodesRemain(t *testing.T) {
+	tests := []struct {
+		stored int
+		low    bool
+	}{
+		{stored: recovery.LowCount + 2, low: false},
+		{stored: recovery.LowCount + 1, low: true},
+		{stored: 2, low: true},
+		{stored: 1, low: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d stored", tt.stored), func(t *testing.T) {
+			h, repo := newTestAuthHandlers(t)
+			user := testutil.NewTestUser(t, repo, "testuser")
+			codes := storeRecoveryCodes(t, repo, user.ID, tt.stored)
+
+			resp := decodeRecoveryLogin(t, recoveryLogin(t, h, "testuser", codes[0]))
+
+			assert.Equal(t, int64(tt.stored-1), resp.RemainingCodes)
+			assert.Equal(t, tt.low, resp.LowCodes)
+		})
+	}
+}
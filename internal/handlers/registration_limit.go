@@ -0,0 +1,71 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"net/netip"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// registrationLimitEnabled reports whether registrations per IP are capped.
+func (h *AuthHandlers) registrationLimitEnabled() bool {
+	return h.authCfg != nil && h.authCfg.RegistrationLimit > 0
+}
+
+// countRegistration counts a started registration for the client IP and
+// returns an error once the IP has exceeded the daily limit.
+func (h *AuthHandlers) countRegistration(c echo.Context) *authError {
+	if !h.registrationLimitEnabled() {
+		return nil
+	}
+	count, err := h.repo.IncrementRegistrationAttempts(c.Request().Context(), clientKey(c.RealIP()))
+	if err != nil {
+		slog.Error("failed to count registration attempt", "error", err)
+		return newAuthError(http.StatusInternalServerError, ErrCodeInternal, "auth_error_internal")
+	}
+	return h.registrationLimitError(count)
+}
+
+// checkRegistrationLimit returns an error if the client IP has exceeded the
+// daily limit, without counting another attempt.
+func (h *AuthHandlers) checkRegistrationLimit(c echo.Context) *authError {
+	if !h.registrationLimitEnabled() {
+		return nil
+	}
+	count, err := h.repo.GetRegistrationAttempts(c.Request().Context(), clientKey(c.RealIP()))
+	if err != nil {
+		slog.Error("failed to get registration attempts", "error", err)
+		return newAuthError(http.StatusInternalServerError, ErrCodeInternal, "auth_error_internal")
+	}
+	return h.registrationLimitError(count)
+}
+
+// clientKey identifies a client for per-IP limits. IPv6 clients are keyed by
+// their /64 prefix, since a single host usually controls a whole /64 and
+// could otherwise rotate addresses to evade the limit.
+func clientKey(ip string) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil || !addr.Is6() {
+		return ip
+	}
+	return netip.PrefixFrom(addr.WithZone(""), 64).Masked().String()
+}
+
+// registrationLimitError returns nil while count is within the limit, and a
+// 429 asking the client to retry after the next UTC midnight otherwise.
+func (h *AuthHandlers) registrationLimitError(count int64) *authError {
+	if count <= int64(h.authCfg.RegistrationLimit) {
+		return nil
+	}
+	now := h.clock.Now().UTC()
+	midnight := now.Truncate(24 * time.Hour).Add(24 * time.Hour)
+
+	aerr := newAuthError(http.StatusTooManyRequests, ErrCodeTooManyAttempts, "auth_error_registration_limit")
+	aerr.retryAfter = int(midnight.Sub(now).Seconds())
+	return aerr
+}
@@ -0,0 +1,102 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package handlers_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/oliverandrich/go-webapp-template/internal/clock"
+	"github.com/oliverandrich/go-webapp-template/internal/config"
+	"github.com/oliverandrich/go-webapp-template/internal/handlers"
+	"github.com/oliverandrich/go-webapp-template/internal/i18n"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/language"
+)
+
+// registerBeginFrom starts a registration for username from the given IP.
+func registerBeginFrom(t *testing.T, h *handlers.AuthHandlers, ip, username string) *httptest.ResponseRecorder {
+	t.Helper()
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/auth/register/begin", strings.NewReader(`{"username":"`+username+`"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.RemoteAddr = ip + ":1234"
+	rec := httptest.NewRecorder()
+
+	require.NoError(t, h.RegisterBegin(e.NewContext(req, rec)))
+	return rec
+}
+
+func TestRegisterBegin_RegistrationLimit(t *testing.T) {
+	h, repo := newTestAuthHandlersWithConfig(t, 0, &config.AuthConfig{RegistrationLimit: 2})
+	fake := clock.NewFake(time.Date(2025, 6, 1, 22, 0, 0, 0, time.UTC))
+	h.SetClock(fake)
+	repo.SetClock(fake)
+
+	for i := range 2 {
+		rec := registerBeginFrom(t, h, "192.0.2.1", fmt.Sprintf("user%d", i))
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+
+	rec := registerBeginFrom(t, h, "192.0.2.1", "user3")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "7200", rec.Header().Get("Retry-After"))
+	assert.Contains(t, rec.Body.String(), "Too many registrations")
+
+	// Other clients are not affected
+	assert.Equal(t, http.StatusOK, registerBeginFrom(t, h, "192.0.2.2", "other").Code)
+
+	// The limit resets with the next UTC day
+	fake.Advance(2 * time.Hour)
+	assert.Equal(t, http.StatusOK, registerBeginFrom(t, h, "192.0.2.1", "user3").Code)
+}
+
+func TestRegisterBegin_RegistrationLimitIPv6Prefix(t *testing.T) {
+	h, _ := newTestAuthHandlersWithConfig(t, 0, &config.AuthConfig{RegistrationLimit: 2})
+
+	// Addresses from one /64 share the limit
+	assert.Equal(t, http.StatusOK, registerBeginFrom(t, h, "[2001:db8:1:2::1]", "user1").Code)
+	assert.Equal(t, http.StatusOK, registerBeginFrom(t, h, "[2001:db8:1:2::2]", "user2").Code)
+	assert.Equal(t, http.StatusTooManyRequests, registerBeginFrom(t, h, "[2001:db8:1:2:ffff::3]", "user3").Code)
+
+	// Another /64 is a different client
+	assert.Equal(t, http.StatusOK, registerBeginFrom(t, h, "[2001:db8:1:3::1]", "user4").Code)
+}
+
+func TestRegisterBegin_RegistrationLimitLocalized(t *testing.T) {
+	h, _ := newTestAuthHandlersWithConfig(t, 0, &config.AuthConfig{RegistrationLimit: 1})
+	assert.Equal(t, http.StatusOK, registerBeginFrom(t, h, "192.0.2.1", "first").Code)
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/auth/register/begin", strings.NewReader(`{"username":"second"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.RemoteAddr = "192.0.2.1:1234"
+	req = req.WithContext(i18n.WithLocale(req.Context(), language.German))
+	rec := httptest.NewRecorder()
+	require.NoError(t, h.RegisterBegin(e.NewContext(req, rec)))
+
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Zu viele Registrierungen")
+}
+
+func TestRegisterFinish_RegistrationLimit(t *testing.T) {
+	h, _ := newTestAuthHandlersWithConfig(t, 0, &config.AuthConfig{RegistrationLimit: 1})
+	for _, name := range []string{"first", "second"} {
+		registerBeginFrom(t, h, "192.0.2.1", name)
+	}
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/auth/register/finish?user_id=1", nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+	rec := httptest.NewRecorder()
+	require.NoError(t, h.RegisterFinish(e.NewContext(req, rec)))
+
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+}
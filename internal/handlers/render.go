@@ -0,0 +1,86 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package handlers
+
+import (
+	"context"
+
+	"github.com/a-h/templ"
+	"github.com/labstack/echo/v4"
+	"github.com/oliverandrich/go-webapp-template/internal/appcontext"
+	"github.com/oliverandrich/go-webapp-template/internal/htmx"
+	"github.com/oliverandrich/go-webapp-template/internal/i18n"
+	"github.com/oliverandrich/go-webapp-template/internal/templates"
+)
+
+// Renderer renders page content either wrapped in a layout or, for htmx
+// requests that swap part of the page, as a bare fragment. The shared data
+// templates read from the request context (user, asset paths) is filled in
+// from the appcontext.Context, so handlers only pass the page content.
+type Renderer struct {
+	layout func(title string) templ.Component
+}
+
+// NewRenderer creates a Renderer that wraps full pages in layout.
+func NewRenderer(layout func(title string) templ.Component) *Renderer {
+	return &Renderer{layout: layout}
+}
+
+// defaultRenderer wraps pages in templates.Layout.
+var defaultRenderer = NewRenderer(templates.Layout)
+
+// Page renders content with the given status code. Full page loads get the
+// layout with the translated titleKey as title; htmx requests get content
+// only. Boosted and history-restore requests replace the whole body and
+// therefore get the full page as well.
+func (r *Renderer) Page(c echo.Context, statusCode int, titleKey string, content templ.Component) error {
+	ctx := pageContext(c)
+	c.Response().Header().Add(echo.HeaderVary, htmx.HeaderRequest)
+
+	component := content
+	if !wantsFragment(c) {
+		component = r.layout(i18n.T(ctx, titleKey))
+		ctx = templ.WithChildren(ctx, content)
+	}
+
+	buf := templ.GetBuffer()
+	defer templ.ReleaseBuffer(buf)
+
+	if err := component.Render(ctx, buf); err != nil {
+		return err
+	}
+	return c.HTML(statusCode, buf.String())
+}
+
+// wantsFragment reports whether the request only swaps a part of the page.
+func wantsFragment(c echo.Context) bool {
+	var req *htmx.Request
+	if cc, ok := c.(*appcontext.Context); ok && cc.Htmx != nil {
+		req = cc.Htmx
+	} else {
+		req = htmx.ParseRequest(c.Request())
+	}
+	return req.IsHtmx && !req.IsBoosted && !req.IsHistoryRestore
+}
+
+// pageContext returns the request context with the user, impersonator and
+// asset paths of the appcontext.Context added, so templates see them even
+// when a handler changed them after the middleware ran.
+func pageContext(c echo.Context) context.Context {
+	ctx := c.Request().Context()
+	cc, ok := c.(*appcontext.Context)
+	if !ok {
+		return ctx
+	}
+	if cc.User != nil {
+		ctx = appcontext.WithUser(ctx, cc.User)
+	}
+	if cc.Impersonator != nil {
+		ctx = appcontext.WithImpersonator(ctx, cc.Impersonator)
+	}
+	if _, found := appcontext.CSSPathFrom(ctx); !found && cc.Assets != nil {
+		ctx = appcontext.WithAssets(ctx, cc.Assets)
+	}
+	return ctx
+}
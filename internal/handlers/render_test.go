@@ -0,0 +1,115 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package handlers_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/a-h/templ"
+	"github.com/labstack/echo/v4"
+	"github.com/oliverandrich/go-webapp-template/internal/handlers"
+	"github.com/oliverandrich/go-webapp-template/internal/htmx"
+	"github.com/oliverandrich/go-webapp-template/internal/i18n"
+	"github.com/oliverandrich/go-webapp-template/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/language"
+)
+
+// renderHome calls the Home handler with the given extra request headers.
+func renderHome(t *testing.T, h *handlers.Handlers, headers map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req = req.WithContext(i18n.WithLocale(req.Context(), language.English))
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+
+	require.NoError(t, h.Home(e.NewContext(req, rec)))
+	return rec
+}
+
+func TestRenderer_FullPage(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	h := handlers.New(repo)
+
+	rec := renderHome(t, h, nil)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "<!doctype html>")
+	assert.Contains(t, rec.Body.String(), "<title>")
+	assert.Contains(t, rec.Header().Values(echo.HeaderVary), htmx.HeaderRequest)
+}
+
+func TestRenderer_HtmxFragment(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	h := handlers.New(repo)
+
+	rec := renderHome(t, h, map[string]string{htmx.HeaderRequest: "true"})
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	body := rec.Body.String()
+	assert.NotContains(t, body, "<!doctype html>")
+	assert.NotContains(t, body, "<title>")
+	assert.Contains(t, body, "<nav")
+}
+
+func TestRenderer_BoostedAndHistoryRestoreGetFullPage(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	h := handlers.New(repo)
+
+	for _, header := range []string{htmx.HeaderBoosted, htmx.HeaderHistoryRestore} {
+		t.Run(header, func(t *testing.T) {
+			rec := renderHome(t, h, map[string]string{htmx.HeaderRequest: "true", header: "true"})
+			assert.Contains(t, rec.Body.String(), "<!doctype html>")
+		})
+	}
+}
+
+func TestRenderer_CustomLayout(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	h := handlers.New(repo)
+	h.SetRenderer(handlers.NewRenderer(func(title string) templ.Component {
+		return templ.ComponentFunc(func(ctx context.Context, w io.Writer) error {
+			if _, err := io.WriteString(w, "<custom title=\""+title+"\">"); err != nil {
+				return err
+			}
+			if err := templ.GetChildren(ctx).Render(ctx, w); err != nil {
+				return err
+			}
+			_, err := io.WriteString(w, "</custom>")
+			return err
+		})
+	}))
+
+	rec := renderHome(t, h, nil)
+
+	body := rec.Body.String()
+	assert.Contains(t, body, `<custom title="Go Webapp Template">`)
+	assert.Contains(t, body, "<nav")
+	assert.NotContains(t, body, "<!doctype html>")
+}
+
+func TestRenderer_InjectsUserFromContext(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	h := handlers.New(repo)
+	user := testutil.NewTestUser(t, repo, "alice")
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/dashboard", nil)
+	req = req.WithContext(i18n.WithLocale(req.Context(), language.English))
+	rec := httptest.NewRecorder()
+
+	// The user is only set on the appcontext.Context, not in the request context
+	c := newTestContext(e, req, rec, user)
+	require.NoError(t, h.Dashboard(c))
+
+	assert.Contains(t, rec.Body.String(), "alice")
+}
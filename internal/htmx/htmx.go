@@ -5,7 +5,10 @@
 package htmx
 
 import (
+	"encoding/json"
+	"fmt"
 	"net/http"
+	"strings"
 )
 
 // Header constants for htmx request headers.
@@ -76,3 +79,52 @@ func ParseRequest(r *http.Request) *Request {
 		TriggerName:      r.Header.Get(HeaderTriggerName),
 	}
 }
+
+// TriggerEvent adds a client-side event to the HX-Trigger response header.
+// The detail is JSON-encoded, so htmx dispatches it as event.detail; events
+// already present in the header are kept.
+func TriggerEvent(h http.Header, name string, detail any) error {
+	return addEvent(h, HeaderTriggerResponse, name, detail)
+}
+
+// TriggerEventAfterSettle is like TriggerEvent for HX-Trigger-After-Settle.
+func TriggerEventAfterSettle(h http.Header, name string, detail any) error {
+	return addEvent(h, HeaderTriggerAfterSettle, name, detail)
+}
+
+// TriggerEventAfterSwap is like TriggerEvent for HX-Trigger-After-Swap.
+func TriggerEventAfterSwap(h http.Header, name string, detail any) error {
+	return addEvent(h, HeaderTriggerAfterSwap, name, detail)
+}
+
+// addEvent merges the event into the JSON object stored in header. A plain
+// comma-separated list of event names is converted to the object form.
+func addEvent(h http.Header, header, name string, detail any) error {
+	raw, err := json.Marshal(detail)
+	if err != nil {
+		return fmt.Errorf("encoding %s event %q: %w", header, name, err)
+	}
+
+	events := make(map[string]json.RawMessage)
+	if existing := strings.TrimSpace(h.Get(header)); existing != "" {
+		if strings.HasPrefix(existing, "{") {
+			if err := json.Unmarshal([]byte(existing), &events); err != nil {
+				return fmt.Errorf("parsing existing %s header: %w", header, err)
+			}
+		} else {
+			for event := range strings.SplitSeq(existing, ",") {
+				if event = strings.TrimSpace(event); event != "" {
+					events[event] = json.RawMessage("null")
+				}
+			}
+		}
+	}
+	events[name] = raw
+
+	encoded, err := json.Marshal(events)
+	if err != nil {
+		return fmt.Errorf("encoding %s header: %w", header, err)
+	}
+	h.Set(header, string(encoded))
+	return nil
+}
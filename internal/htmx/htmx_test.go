@@ -4,12 +4,14 @@
 package htmx_test
 
 import (
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
 	"github.com/oliverandrich/go-webapp-template/internal/htmx"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestParseRequest_HtmxRequest(t *testing.T) {
@@ -73,3 +75,60 @@ func TestParseRequest_FalseValues(t *testing.T) {
 	assert.False(t, parsed.IsHtmx)
 	assert.False(t, parsed.IsBoosted)
 }
+
+func TestTriggerEvent_EncodesDetail(t *testing.T) {
+	h := http.Header{}
+
+	err := htmx.TriggerEvent(h, "authThrottle", map[string]int{"retryAfterSeconds": 30})
+
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"authThrottle":{"retryAfterSeconds":30}}`, h.Get(htmx.HeaderTriggerResponse))
+}
+
+func TestTriggerEvent_MergesExistingEvents(t *testing.T) {
+	h := http.Header{}
+	require.NoError(t, htmx.TriggerEvent(h, "first", "a"))
+
+	require.NoError(t, htmx.TriggerEvent(h, "second", map[string]bool{"ok": true}))
+
+	assert.JSONEq(t, `{"first":"a","second":{"ok":true}}`, h.Get(htmx.HeaderTriggerResponse))
+}
+
+func TestTriggerEvent_ConvertsPlainEventList(t *testing.T) {
+	h := http.Header{}
+	h.Set(htmx.HeaderTriggerResponse, "refresh, closeModal")
+
+	require.NoError(t, htmx.TriggerEvent(h, "notify", "saved"))
+
+	assert.JSONEq(t, `{"refresh":null,"closeModal":null,"notify":"saved"}`, h.Get(htmx.HeaderTriggerResponse))
+}
+
+func TestTriggerEvent_EscapesValues(t *testing.T) {
+	h := http.Header{}
+
+	require.NoError(t, htmx.TriggerEvent(h, "message", "quote \" and <tag>\nnewline"))
+
+	var events map[string]string
+	require.NoError(t, json.Unmarshal([]byte(h.Get(htmx.HeaderTriggerResponse)), &events))
+	assert.Equal(t, "quote \" and <tag>\nnewline", events["message"])
+	assert.NotContains(t, h.Get(htmx.HeaderTriggerResponse), "\n", "header values must not contain raw newlines")
+}
+
+func TestTriggerEvent_UnencodableDetail(t *testing.T) {
+	h := http.Header{}
+
+	err := htmx.TriggerEvent(h, "bad", make(chan int))
+
+	require.Error(t, err)
+	assert.Empty(t, h.Get(htmx.HeaderTriggerResponse))
+}
+
+func TestTriggerEventAfterSettleAndSwap(t *testing.T) {
+	h := http.Header{}
+
+	require.NoError(t, htmx.TriggerEventAfterSettle(h, "settled", 1))
+	require.NoError(t, htmx.TriggerEventAfterSwap(h, "swapped", 2))
+
+	assert.JSONEq(t, `{"settled":1}`, h.Get(htmx.HeaderTriggerAfterSettle))
+	assert.JSONEq(t, `{"swapped":2}`, h.Get(htmx.HeaderTriggerAfterSwap))
+}
@@ -0,0 +1,87 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package i18n
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"golang.org/x/text/number"
+)
+
+// Date styles accepted by FormatTime.
+const (
+	DateShort     = "short"    // 01/02/2006, 02.01.2006
+	DateLong      = "long"     // January 2, 2006, 2. Januar 2006
+	DateTimeShort = "datetime" // short date plus 24h time
+)
+
+// dateLayouts holds the Go time layouts per base language and style.
+var dateLayouts = map[string]map[string]string{
+	"en": {
+		DateShort:     "01/02/2006",
+		DateLong:      "January 2, 2006",
+		DateTimeShort: "01/02/2006 15:04",
+	},
+	"de": {
+		DateShort:     "02.01.2006",
+		DateLong:      "2. January 2006",
+		DateTimeShort: "02.01.2006 15:04",
+	},
+}
+
+// monthNames translates English month names for locales whose long format
+// spells out the month. time.Format only knows English names.
+var monthNames = map[string]*strings.Replacer{
+	"de": strings.NewReplacer(
+		"January", "Januar", "February", "Februar", "March", "März",
+		"May", "Mai", "June", "Juni", "July", "Juli",
+		"October", "Oktober", "December", "Dezember",
+	),
+}
+
+// FormatTime formats t for the context locale. Unknown styles fall back to
+// the short date style, unknown locales to English.
+func FormatTime(ctx context.Context, t time.Time, style string) string {
+	base := baseLanguage(ctx)
+	layouts, ok := dateLayouts[base]
+	if !ok {
+		base = "en"
+		layouts = dateLayouts[base]
+	}
+
+	layout, ok := layouts[style]
+	if !ok {
+		layout = layouts[DateShort]
+	}
+
+	formatted := t.Format(layout)
+	if r, ok := monthNames[base]; ok && style == DateLong {
+		formatted = r.Replace(formatted)
+	}
+	return formatted
+}
+
+// FormatNumber formats n with the grouping and decimal separators of the
+// context locale, e.g. 1,234.5 in English and 1.234,5 in German.
+func FormatNumber(ctx context.Context, n float64) string {
+	p := message.NewPrinter(localeTag(ctx))
+	return p.Sprint(number.Decimal(n))
+}
+
+func localeTag(ctx context.Context) language.Tag {
+	tag, err := language.Parse(GetLocale(ctx))
+	if err != nil {
+		return DefaultLanguage
+	}
+	return tag
+}
+
+func baseLanguage(ctx context.Context) string {
+	base, _ := localeTag(ctx).Base()
+	return base.String()
+}
@@ -0,0 +1,55 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package i18n_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/oliverandrich/go-webapp-template/internal/i18n"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/language"
+)
+
+var formatTestTime = time.Date(2025, time.March, 7, 14, 5, 0, 0, time.UTC)
+
+func TestFormatTime_English(t *testing.T) {
+	require.NoError(t, i18n.Init())
+	ctx := i18n.WithLocale(context.Background(), language.English)
+
+	assert.Equal(t, "03/07/2025", i18n.FormatTime(ctx, formatTestTime, i18n.DateShort))
+	assert.Equal(t, "March 7, 2025", i18n.FormatTime(ctx, formatTestTime, i18n.DateLong))
+	assert.Equal(t, "03/07/2025 14:05", i18n.FormatTime(ctx, formatTestTime, i18n.DateTimeShort))
+}
+
+func TestFormatTime_German(t *testing.T) {
+	require.NoError(t, i18n.Init())
+	ctx := i18n.WithLocale(context.Background(), language.German)
+
+	// Day before month
+	assert.Equal(t, "07.03.2025", i18n.FormatTime(ctx, formatTestTime, i18n.DateShort))
+	assert.Equal(t, "7. März 2025", i18n.FormatTime(ctx, formatTestTime, i18n.DateLong))
+	assert.Equal(t, "07.03.2025 14:05", i18n.FormatTime(ctx, formatTestTime, i18n.DateTimeShort))
+}
+
+func TestFormatTime_UnknownStyleAndLocale(t *testing.T) {
+	require.NoError(t, i18n.Init())
+	ctx := i18n.WithLocale(context.Background(), language.French)
+
+	assert.Equal(t, "03/07/2025", i18n.FormatTime(ctx, formatTestTime, "bogus"))
+	assert.Equal(t, "03/07/2025", i18n.FormatTime(context.Background(), formatTestTime, i18n.DateShort))
+}
+
+func TestFormatNumber(t *testing.T) {
+	require.NoError(t, i18n.Init())
+	en := i18n.WithLocale(context.Background(), language.English)
+	de := i18n.WithLocale(context.Background(), language.German)
+
+	assert.Equal(t, "1,234,567.5", i18n.FormatNumber(en, 1234567.5))
+	assert.Equal(t, "1.234.567,5", i18n.FormatNumber(de, 1234567.5))
+	assert.Equal(t, "0,25", i18n.FormatNumber(de, 0.25))
+	assert.Equal(t, "42", i18n.FormatNumber(en, 42))
+}
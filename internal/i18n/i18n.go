@@ -6,6 +6,8 @@ package i18n
 import (
 	"context"
 	"embed"
+	"fmt"
+	"strings"
 
 	"github.com/BurntSushi/toml"
 	"github.com/nicksnyder/go-i18n/v2/i18n"
@@ -20,9 +22,10 @@ var bundle *i18n.Bundle
 type localeContextKey struct{}
 type localizerContextKey struct{}
 
-// Init initializes the i18n bundle with embedded translations.
+// Init initializes the i18n bundle with embedded translations. Messages
+// missing in a translation fall back to DefaultLanguage.
 func Init() error {
-	bundle = i18n.NewBundle(language.English)
+	bundle = i18n.NewBundle(DefaultLanguage)
 	bundle.RegisterUnmarshalFunc("toml", toml.Unmarshal)
 
 	files := []string{
@@ -47,12 +50,18 @@ func WithLocale(ctx context.Context, lang language.Tag) context.Context {
 	return context.WithValue(ctx, localizerContextKey{}, localizer)
 }
 
+// LocaleFrom returns the locale stored in ctx by WithLocale.
+func LocaleFrom(ctx context.Context) (string, bool) {
+	locale, ok := ctx.Value(localeContextKey{}).(string)
+	return locale, ok
+}
+
 // GetLocale returns the current locale from context.
 func GetLocale(ctx context.Context) string {
-	if locale, ok := ctx.Value(localeContextKey{}).(string); ok {
+	if locale, ok := LocaleFrom(ctx); ok {
 		return locale
 	}
-	return "en"
+	return DefaultLanguage.String()
 }
 
 // T translates a message by ID.
@@ -94,19 +103,89 @@ func TPlural(ctx context.Context, messageID string, count int) string {
 	return msg
 }
 
+// supportedLanguages lists the languages with translations, default first.
+var supportedLanguages = []language.Tag{
+	language.English,
+	language.German,
+}
+
+// DefaultLanguage is used when no supported language matches the client's
+// preferences. Change it with SetDefaultLanguage.
+var DefaultLanguage = supportedLanguages[0]
+
+// matcher falls back to the first of its languages, DefaultLanguage.
+var matcher = language.NewMatcher(supportedLanguages)
+
+// SetDefaultLanguage makes a supported language, e.g. "de", the fallback
+// for clients whose preferences match none of the translations. Call it
+// before Init.
+func SetDefaultLanguage(lang string) error {
+	tag, ok := ParseSupported(lang)
+	if !ok {
+		return fmt.Errorf("unsupported default language %q", lang)
+	}
+
+	tags := []language.Tag{tag}
+	for _, supported := range supportedLanguages {
+		if supported != tag {
+			tags = append(tags, supported)
+		}
+	}
+	DefaultLanguage = tag
+	matcher = language.NewMatcher(tags)
+	return nil
+}
+
+// Limits applied to Accept-Language headers before matching. Browsers send a
+// handful of short tags, anything beyond is cut off.
+const (
+	maxAcceptLanguageLength = 256
+	maxAcceptLanguageTags   = 16
+)
+
 // MatchLanguage matches the best language from Accept-Language header.
+// Oversized headers are truncated at the last complete entry, and malformed
+// ones fall back to DefaultLanguage.
 func MatchLanguage(acceptLanguage string) language.Tag {
-	matcher := language.NewMatcher([]language.Tag{
-		language.English,
-		language.German,
-	})
-	tag, _ := language.MatchStrings(matcher, acceptLanguage)
+	if len(acceptLanguage) > maxAcceptLanguageLength {
+		acceptLanguage = acceptLanguage[:maxAcceptLanguageLength]
+		if i := strings.LastIndexByte(acceptLanguage, ','); i >= 0 {
+			acceptLanguage = acceptLanguage[:i]
+		}
+	}
+
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(tags) == 0 {
+		return DefaultLanguage
+	}
+	// Tags are sorted by weight, so the least preferred ones are dropped
+	if len(tags) > maxAcceptLanguageTags {
+		tags = tags[:maxAcceptLanguageTags]
+	}
+
+	tag, _, _ := matcher.Match(tags...)
 	return tag
 }
 
+// ParseSupported parses a language code such as "de" and reports whether
+// translations exist for it.
+func ParseSupported(lang string) (language.Tag, bool) {
+	tag, err := language.Parse(lang)
+	if err != nil {
+		return language.Und, false
+	}
+	base, _ := tag.Base()
+	for _, supported := range supportedLanguages {
+		if supportedBase, _ := supported.Base(); supportedBase == base {
+			return supported, true
+		}
+	}
+	return language.Und, false
+}
+
 func getLocalizer(ctx context.Context) *i18n.Localizer {
 	if localizer, ok := ctx.Value(localizerContextKey{}).(*i18n.Localizer); ok {
 		return localizer
 	}
-	return i18n.NewLocalizer(bundle, "en")
+	return i18n.NewLocalizer(bundle, DefaultLanguage.String())
 }
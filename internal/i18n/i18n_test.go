@@ -5,6 +5,7 @@ package i18n_test
 
 import (
 	"context"
+	"strings"
 	"testing"
 
 	"github.com/oliverandrich/go-webapp-template/internal/i18n"
@@ -107,6 +108,60 @@ func TestMatchLanguage(t *testing.T) {
 	}
 }
 
+func TestMatchLanguage_OversizedHeader(t *testing.T) {
+	header := "de-DE," + strings.Repeat("x-private-tag,", 10000)
+
+	tag := i18n.MatchLanguage(header)
+
+	assert.Equal(t, "de", tag.String()[:2])
+}
+
+func TestMatchLanguage_LongSingleTag(t *testing.T) {
+	tag := i18n.MatchLanguage(strings.Repeat("a", 100000))
+
+	assert.Equal(t, i18n.DefaultLanguage, tag)
+}
+
+func TestMatchLanguage_Malformed(t *testing.T) {
+	for _, header := range []string{"!!!", "de;q=abc", ";;;,,,", "de;q=0.5;q=0.6", "\x00\xff"} {
+		t.Run(header, func(t *testing.T) {
+			assert.Equal(t, i18n.DefaultLanguage, i18n.MatchLanguage(header))
+		})
+	}
+}
+
+func TestMatchLanguage_ManyWeightedEntries(t *testing.T) {
+	// More tags than are matched, the preferred one listed last
+	header := strings.Repeat("fr;q=0.1,", 20) + "de;q=0.9"
+
+	tag := i18n.MatchLanguage(header)
+
+	assert.Equal(t, "de", tag.String()[:2])
+}
+
+func TestParseSupported(t *testing.T) {
+	tests := []struct {
+		lang      string
+		expected  language.Tag
+		supported bool
+	}{
+		{"en", language.English, true},
+		{"de", language.German, true},
+		{"de-AT", language.German, true},
+		{"fr", language.Und, false},
+		{"not a language", language.Und, false},
+		{"", language.Und, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.lang, func(t *testing.T) {
+			tag, supported := i18n.ParseSupported(tt.lang)
+			assert.Equal(t, tt.supported, supported)
+			assert.Equal(t, tt.expected, tag)
+		})
+	}
+}
+
 func TestWithLocale(t *testing.T) {
 	require.NoError(t, i18n.Init())
 
@@ -130,3 +185,44 @@ func TestGetLocale_Default(t *testing.T) {
 	// Without WithLocale, should return "en"
 	assert.Equal(t, "en", i18n.GetLocale(ctx))
 }
+
+func TestLocaleFrom(t *testing.T) {
+	require.NoError(t, i18n.Init())
+
+	locale, ok := i18n.LocaleFrom(i18n.WithLocale(context.Background(), language.German))
+	assert.True(t, ok)
+	assert.Equal(t, "de", locale)
+
+	_, ok = i18n.LocaleFrom(context.Background())
+	assert.False(t, ok)
+}
+
+// useDefaultLanguage switches the default language for one test.
+func useDefaultLanguage(t *testing.T, lang string) {
+	t.Helper()
+	require.NoError(t, i18n.SetDefaultLanguage(lang))
+	require.NoError(t, i18n.Init())
+	t.Cleanup(func() {
+		require.NoError(t, i18n.SetDefaultLanguage("en"))
+		require.NoError(t, i18n.Init())
+	})
+}
+
+func TestSetDefaultLanguage_German(t *testing.T) {
+	useDefaultLanguage(t, "de-DE")
+
+	assert.Equal(t, language.German, i18n.DefaultLanguage)
+	assert.Equal(t, "de", i18n.MatchLanguage("fr").String()[:2])
+	assert.Equal(t, "de", i18n.MatchLanguage("").String()[:2])
+	assert.Equal(t, "en", i18n.MatchLanguage("fr, en;q=0.5").String()[:2])
+	assert.Equal(t, "de", i18n.GetLocale(context.Background()))
+	assert.Equal(t, "Benutzername", i18n.T(context.Background(), "username"))
+}
+
+func TestSetDefaultLanguage_Unsupported(t *testing.T) {
+	err := i18n.SetDefaultLanguage("fr")
+
+	require.Error(t, err)
+	assert.Equal(t, language.English, i18n.DefaultLanguage)
+	assert.Equal(t, "en", i18n.MatchLanguage("fr").String()[:2])
+}
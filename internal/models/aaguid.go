@@ -0,0 +1,49 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package models
+
+import (
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+)
+
+// aaguidsJSON maps AAGUIDs of common passkey providers to display names.
+// Extend it as needed; unknown AAGUIDs simply show no authenticator name.
+//
+//go:embed aaguids.json
+var aaguidsJSON []byte
+
+var authenticatorNames = func() map[string]string {
+	names := make(map[string]string)
+	if err := json.Unmarshal(aaguidsJSON, &names); err != nil {
+		panic("models: invalid aaguids.json: " + err.Error())
+	}
+	return names
+}()
+
+// AuthenticatorName returns a human-friendly name for the authenticator with
+// the given AAGUID, or "" if it is unknown.
+func AuthenticatorName(aaguid []byte) string {
+	if len(aaguid) != 16 {
+		return ""
+	}
+	return authenticatorNames[formatUUID(aaguid)]
+}
+
+// formatUUID formats 16 bytes in canonical 8-4-4-4-12 UUID form.
+func formatUUID(b []byte) string {
+	s := hex.EncodeToString(b)
+	return s[0:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:32]
+}
+
+// AuthenticatorNameFromHex is AuthenticatorName for a hex-encoded AAGUID, as
+// returned by Repository.CountCredentialsByAAGUID.
+func AuthenticatorNameFromHex(aaguid string) string {
+	b, err := hex.DecodeString(aaguid)
+	if err != nil {
+		return ""
+	}
+	return AuthenticatorName(b)
+}
@@ -0,0 +1,26 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package models
+
+import "time"
+
+// Audit actions.
+const (
+	AuditImpersonationStart = "impersonation.start"
+	AuditImpersonationStop  = "impersonation.stop"
+	AuditSessionsRevoked    = "sessions.revoked"
+	AuditCredentialBackup   = "credential.backup_state_changed"
+	AuditMagicLinkLogin     = "login.magic_link"
+	AuditRecoveryCodesReset = "recovery_codes.reset"
+)
+
+// AuditEvent records a security-relevant action. ActorID and TargetID are
+// nil once the user involved has been deleted.
+type AuditEvent struct { //nolint:govet // fieldalignment: readability over optimization
+	ID        int64     `db:"id" json:"id"`
+	ActorID   *int64    `db:"actor_id" json:"actor_id"`
+	Action    string    `db:"action" json:"action"`
+	TargetID  *int64    `db:"target_id" json:"target_id"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
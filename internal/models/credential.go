@@ -13,18 +13,47 @@ import (
 
 // Credential stores a WebAuthn credential for a user.
 type Credential struct { //nolint:govet // fieldalignment: readability over optimization
-	ID              int64     `db:"id" json:"id"`
-	UserID          int64     `db:"user_id" json:"user_id"`
-	CredentialID    []byte    `db:"credential_id" json:"-"`
-	PublicKey       []byte    `db:"public_key" json:"-"`
-	AAGUID          []byte    `db:"aaguid" json:"-"`
-	SignCount       uint32    `db:"sign_count" json:"-"`
-	Transports      string    `db:"transports" json:"-"` // comma-separated
-	Name            string    `db:"name" json:"name"`
-	BackupEligible  bool      `db:"backup_eligible" json:"-"`
-	BackupState     bool      `db:"backup_state" json:"-"`
-	AttestationType string    `db:"attestation_type" json:"-"`
-	CreatedAt       time.Time `db:"created_at" json:"created_at"`
+	ID              int64      `db:"id" json:"id"`
+	UserID          int64      `db:"user_id" json:"user_id"`
+	CredentialID    []byte     `db:"credential_id" json:"-"`
+	PublicKey       []byte     `db:"public_key" json:"-"`
+	AAGUID          []byte     `db:"aaguid" json:"-"`
+	SignCount       uint32     `db:"sign_count" json:"-"`
+	Transports      string     `db:"transports" json:"-"` // comma-separated
+	Name            string     `db:"name" json:"name"`
+	BackupEligible  bool       `db:"backup_eligible" json:"-"`
+	BackupState     bool       `db:"backup_state" json:"-"`
+	AttestationType string     `db:"attestation_type" json:"-"`
+	CreatedAt       time.Time  `db:"created_at" json:"created_at"`
+	LastUsedAt      *time.Time `db:"last_used_at" json:"last_used_at,omitempty"`
+}
+
+// CredentialSummary is a credential with its WebAuthn metadata decoded for display.
+type CredentialSummary struct { //nolint:govet // fieldalignment: readability over optimization
+	ID             int64
+	Name           string
+	Authenticator  string // Provider name resolved from the AAGUID, "" if unknown
+	Transports     []string
+	BackupEligible bool // Synced passkey that survives device loss
+	CreatedAt      time.Time
+	LastUsedAt     *time.Time
+}
+
+// Summary returns the display summary of the credential.
+func (c *Credential) Summary() CredentialSummary {
+	var transports []string
+	if c.Transports != "" {
+		transports = strings.Split(c.Transports, ",")
+	}
+	return CredentialSummary{
+		ID:             c.ID,
+		Name:           c.Name,
+		Authenticator:  AuthenticatorName(c.AAGUID),
+		Transports:     transports,
+		BackupEligible: c.BackupEligible,
+		CreatedAt:      c.CreatedAt,
+		LastUsedAt:     c.LastUsedAt,
+	}
 }
 
 // ToWebAuthn converts the database credential to a webauthn.Credential.
@@ -85,3 +85,54 @@ func TestTransportsFromWebAuthn_Single(t *testing.T) {
 
 	assert.Equal(t, "internal", result)
 }
+
+func TestAuthenticatorName(t *testing.T) {
+	tests := []struct {
+		name   string
+		aaguid []byte
+		want   string
+	}{
+		{"known", []byte{0xfb, 0xfc, 0x30, 0x07, 0x15, 0x4e, 0x4e, 0xcc, 0x8c, 0x0b, 0x6e, 0x02, 0x05, 0x57, 0xd7, 0xbd}, "iCloud Keychain"},
+		{"unknown", []byte("test-aaguid-1234"), ""},
+		{"zero", make([]byte, 16), ""},
+		{"wrong length", []byte("short"), ""},
+		{"nil", nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, models.AuthenticatorName(tt.aaguid))
+		})
+	}
+}
+
+func TestAuthenticatorNameFromHex(t *testing.T) {
+	assert.Equal(t, "iCloud Keychain", models.AuthenticatorNameFromHex("fbfc3007154e4ecc8c0b6e020557d7bd"))
+	assert.Empty(t, models.AuthenticatorNameFromHex("unknown"))
+	assert.Empty(t, models.AuthenticatorNameFromHex(""))
+}
+
+func TestCredential_Summary(t *testing.T) {
+	cred := &models.Credential{
+		ID:             7,
+		Name:           "Laptop",
+		AAGUID:         []byte{0xea, 0x9b, 0x8d, 0x66, 0x4d, 0x01, 0x1d, 0x21, 0x3c, 0xe4, 0xb6, 0xb4, 0x8c, 0xb5, 0x75, 0xd4},
+		Transports:     "internal,hybrid",
+		BackupEligible: true,
+	}
+
+	summary := cred.Summary()
+
+	assert.Equal(t, int64(7), summary.ID)
+	assert.Equal(t, "Laptop", summary.Name)
+	assert.Equal(t, "Google Password Manager", summary.Authenticator)
+	assert.Equal(t, []string{"internal", "hybrid"}, summary.Transports)
+	assert.True(t, summary.BackupEligible)
+	assert.Nil(t, summary.LastUsedAt)
+}
+
+func TestCredential_Summary_NoTransports(t *testing.T) {
+	cred := &models.Credential{Name: "Key"}
+
+	assert.Empty(t, cred.Summary().Transports)
+}
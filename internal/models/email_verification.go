@@ -13,3 +13,24 @@ type EmailVerificationToken struct { //nolint:govet // fieldalignment: readabili
 	ExpiresAt time.Time `db:"expires_at" json:"expires_at"`
 	CreatedAt time.Time `db:"created_at" json:"created_at"`
 }
+
+// PendingEmailChange stores a requested email address change until the new
+// address has been confirmed.
+type PendingEmailChange struct { //nolint:govet // fieldalignment: readability over optimization
+	ID        int64     `db:"id" json:"id"`
+	UserID    int64     `db:"user_id" json:"user_id"`
+	NewEmail  string    `db:"new_email" json:"new_email"`
+	TokenHash string    `db:"token_hash" json:"-"` // SHA256 hash
+	ExpiresAt time.Time `db:"expires_at" json:"expires_at"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
+
+// MagicLinkToken stores a hashed single-use token of an email sign-in link.
+type MagicLinkToken struct { //nolint:govet // fieldalignment: readability over optimization
+	ID        int64      `db:"id" json:"id"`
+	UserID    int64      `db:"user_id" json:"user_id"`
+	TokenHash string     `db:"token_hash" json:"-"` // SHA256 hash
+	ExpiresAt time.Time  `db:"expires_at" json:"expires_at"`
+	UsedAt    *time.Time `db:"used_at" json:"used_at,omitempty"` // nil until the link is used
+	CreatedAt time.Time  `db:"created_at" json:"created_at"`
+}
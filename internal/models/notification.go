@@ -0,0 +1,53 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// Notification is an in-app message for a user. Type tells clients how to
+// interpret the JSON Payload.
+type Notification struct { //nolint:govet // fieldalignment: readability over optimization
+	ID        int64      `db:"id" json:"id"`
+	UserID    int64      `db:"user_id" json:"-"`
+	Type      string     `db:"type" json:"type"`
+	Payload   RawJSON    `db:"payload" json:"payload"`
+	ReadAt    *time.Time `db:"read_at" json:"read_at,omitempty"`
+	CreatedAt time.Time  `db:"created_at" json:"created_at"`
+}
+
+// RawJSON is a JSON document read from a TEXT column. It is embedded as is
+// when marshalled to JSON.
+type RawJSON []byte
+
+// Scan implements sql.Scanner.
+func (j *RawJSON) Scan(src any) error {
+	switch v := src.(type) {
+	case string:
+		*j = append((*j)[:0], v...)
+	case []byte:
+		*j = append((*j)[:0], v...)
+	case nil:
+		*j = nil
+	default:
+		return fmt.Errorf("cannot scan %T into RawJSON", src)
+	}
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler.
+func (j RawJSON) MarshalJSON() ([]byte, error) {
+	if len(j) == 0 {
+		return []byte("null"), nil
+	}
+	return j, nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (j *RawJSON) UnmarshalJSON(data []byte) error {
+	*j = append((*j)[:0], data...)
+	return nil
+}
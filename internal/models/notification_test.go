@@ -0,0 +1,38 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package models_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/oliverandrich/go-webapp-template/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRawJSON_Scan(t *testing.T) {
+	var j models.RawJSON
+
+	require.NoError(t, j.Scan(`{"a":1}`))
+	assert.JSONEq(t, `{"a":1}`, string(j))
+
+	require.NoError(t, j.Scan([]byte(`[1,2]`)))
+	assert.JSONEq(t, `[1,2]`, string(j))
+
+	require.NoError(t, j.Scan(nil))
+	assert.Nil(t, j)
+
+	assert.Error(t, j.Scan(42))
+}
+
+func TestRawJSON_Marshal(t *testing.T) {
+	data, err := json.Marshal(models.Notification{Type: "test", Payload: models.RawJSON(`{"name":"YubiKey"}`)})
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"payload":{"name":"YubiKey"}`)
+
+	data, err = json.Marshal(models.Notification{Type: "test"})
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"payload":null`)
+}
@@ -0,0 +1,17 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package models
+
+import "time"
+
+// Session is a server-side login session. Data holds the JSON-encoded
+// session.Data; the cookie only carries the token whose hash is ID.
+type Session struct { //nolint:govet // fieldalignment: readability over optimization
+	ID             string    `db:"id" json:"-"` // SHA256 hash of the cookie token
+	UserID         int64     `db:"user_id" json:"user_id"`
+	ImpersonatorID *int64    `db:"impersonator_id" json:"impersonator_id,omitempty"` // Administrator acting as UserID
+	Data           string    `db:"data" json:"-"`
+	ExpiresAt      time.Time `db:"expires_at" json:"expires_at"`
+	CreatedAt      time.Time `db:"created_at" json:"created_at"`
+}
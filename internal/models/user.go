@@ -12,14 +12,31 @@ import (
 
 // User represents an authenticated user with WebAuthn credentials.
 type User struct { //nolint:govet // fieldalignment: readability over optimization
-	ID              int64        `db:"id" json:"id"`
-	Username        string       `db:"username" json:"username"`
-	Email           *string      `db:"email" json:"email,omitempty"`
-	EmailVerified   bool         `db:"email_verified" json:"email_verified"`
-	EmailVerifiedAt *time.Time   `db:"email_verified_at" json:"email_verified_at,omitempty"`
-	CreatedAt       time.Time    `db:"created_at" json:"created_at"`
-	UpdatedAt       time.Time    `db:"updated_at" json:"updated_at"`
-	Credentials     []Credential `db:"-" json:"credentials,omitempty"`
+	ID                int64        `db:"id" json:"id"`
+	Username          string       `db:"username" json:"username"`
+	DisplayName       string       `db:"display_name" json:"display_name"`
+	Email             *string      `db:"email" json:"email,omitempty"`
+	EmailVerified     bool         `db:"email_verified" json:"email_verified"`
+	EmailVerifiedAt   *time.Time   `db:"email_verified_at" json:"email_verified_at,omitempty"`
+	IsAdmin           bool         `db:"is_admin" json:"is_admin"`
+	PreferredLanguage *string      `db:"preferred_language" json:"preferred_language,omitempty"`
+	SessionVersion    int          `db:"session_version" json:"-"`
+	LastLoginAt       *time.Time   `db:"last_login_at" json:"last_login_at,omitempty"`
+	PreviousLoginAt   *time.Time   `db:"previous_login_at" json:"previous_login_at,omitempty"`
+	DeletedAt         *time.Time   `db:"deleted_at" json:"deleted_at,omitempty"`
+	CreatedAt         time.Time    `db:"created_at" json:"created_at"`
+	UpdatedAt         time.Time    `db:"updated_at" json:"updated_at"`
+	Credentials       []Credential `db:"-" json:"credentials,omitempty"`
+}
+
+// UserStats aggregates account information shown on the dashboard.
+type UserStats struct { //nolint:govet // fieldalignment: readability over optimization
+	CredentialCount   int64         `db:"credential_count"`
+	RecoveryCodesLeft int64         `db:"recovery_codes_left"`
+	LastLoginAt       *time.Time    `db:"last_login_at"`     // nil until the first sign-in is recorded
+	PreviousLoginAt   *time.Time    `db:"previous_login_at"` // sign-in before LastLoginAt, nil until the second one
+	CreatedAt         time.Time     `db:"created_at"`
+	AccountAge        time.Duration `db:"-"`
 }
 
 // WebAuthnID returns the user's ID as a byte slice for WebAuthn.
@@ -34,9 +51,13 @@ func (u *User) WebAuthnName() string {
 	return u.Username
 }
 
-// WebAuthnDisplayName returns the user's display name (username).
+// WebAuthnDisplayName returns the user's display name, or the username if
+// none is set.
 func (u *User) WebAuthnDisplayName() string {
-	return u.Username
+	if u.DisplayName == "" {
+		return u.Username
+	}
+	return u.DisplayName
 }
 
 // WebAuthnCredentials returns the user's WebAuthn credentials.
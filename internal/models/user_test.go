@@ -39,6 +39,9 @@ func TestUser_WebAuthnDisplayName(t *testing.T) {
 	user := &models.User{Username: "testuser"}
 
 	assert.Equal(t, "testuser", user.WebAuthnDisplayName())
+
+	user.DisplayName = "Test User"
+	assert.Equal(t, "Test User", user.WebAuthnDisplayName())
 }
 
 func TestUser_WebAuthnIcon(t *testing.T) {
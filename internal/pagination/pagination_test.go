@@ -0,0 +1,67 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package pagination_test
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/oliverandrich/go-webapp-template/internal/pagination"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testKey = []byte("0123456789abcdef0123456789abcdef")
+
+func TestCodec_RoundTrip(t *testing.T) {
+	codec := pagination.New(testKey)
+	cursor := pagination.Cursor{CreatedAt: time.Date(2025, 6, 1, 12, 30, 45, 0, time.UTC), ID: 42}
+
+	encoded := codec.Encode(cursor)
+	decoded, err := codec.Decode(encoded)
+
+	require.NoError(t, err)
+	assert.True(t, cursor.CreatedAt.Equal(decoded.CreatedAt))
+	assert.Equal(t, int64(42), decoded.ID)
+	assert.NotContains(t, encoded, "=", "cursor must be URL-safe")
+}
+
+func TestCodec_RejectsTamperedCursor(t *testing.T) {
+	codec := pagination.New(testKey)
+	raw, err := base64.RawURLEncoding.DecodeString(codec.Encode(pagination.Cursor{CreatedAt: time.Now(), ID: 42}))
+	require.NoError(t, err)
+
+	raw[15]++ // change the ID
+	_, err = codec.Decode(base64.RawURLEncoding.EncodeToString(raw))
+
+	require.ErrorIs(t, err, pagination.ErrInvalidCursor)
+}
+
+func TestCodec_RejectsInvalidCursors(t *testing.T) {
+	codec := pagination.New(testKey)
+	foreign := pagination.New([]byte("another key, another signature.")).
+		Encode(pagination.Cursor{CreatedAt: time.Now(), ID: 1})
+
+	for _, s := range []string{"", "not base64!", "c2hvcnQ", foreign} {
+		_, err := codec.Decode(s)
+		assert.ErrorIs(t, err, pagination.ErrInvalidCursor, s)
+	}
+}
+
+func TestCursor_Where(t *testing.T) {
+	cursor := pagination.Cursor{CreatedAt: time.Date(2025, 6, 1, 14, 0, 0, 0, time.FixedZone("CEST", 2*60*60)), ID: 9}
+
+	where, args := cursor.Where("u.created_at", "u.id")
+
+	assert.Equal(t, "(u.created_at, u.id) < (?, ?)", where)
+	assert.Equal(t, []any{"2025-06-01 12:00:00", int64(9)}, args)
+}
+
+func TestLimit(t *testing.T) {
+	assert.Equal(t, pagination.DefaultLimit, pagination.Limit(0))
+	assert.Equal(t, pagination.DefaultLimit, pagination.Limit(-5))
+	assert.Equal(t, 7, pagination.Limit(7))
+	assert.Equal(t, pagination.MaxLimit, pagination.Limit(1000))
+}
@@ -0,0 +1,37 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+// accountNoticeRetention is how long sent account notices are kept, so
+// CountRecentAccountNotices still sees them.
+const accountNoticeRetention = 24 * time.Hour
+
+// RecordAccountNotice records that a user was told about a registration for
+// their address. Notices older than accountNoticeRetention are deleted first,
+// so the table stays small.
+func (r *Repository) RecordAccountNotice(ctx context.Context, userID int64) error {
+	now := r.clock.Now()
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM account_notices WHERE created_at < ?`, now.Add(-accountNoticeRetention)); err != nil {
+		return err
+	}
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO account_notices (user_id, created_at) VALUES (?, ?)`,
+		userID, now)
+	return err
+}
+
+// CountRecentAccountNotices counts the account notices sent to a user within
+// the given window up to now. window must not exceed one day.
+func (r *Repository) CountRecentAccountNotices(ctx context.Context, userID int64, window time.Duration) (int64, error) {
+	var count int64
+	err := r.db.GetContext(ctx, &count,
+		`SELECT COUNT(*) FROM account_notices WHERE user_id = ? AND created_at > ?`,
+		userID, r.clock.Now().Add(-window))
+	return count, err
+}
@@ -0,0 +1,53 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/oliverandrich/go-webapp-template/internal/clock"
+	"github.com/oliverandrich/go-webapp-template/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCountRecentAccountNotices(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	ctx := context.Background()
+	fake := clock.NewFake(time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC))
+	repo.SetClock(fake)
+	user := testutil.NewTestUser(t, repo, "testuser")
+	other := testutil.NewTestUser(t, repo, "other")
+
+	require.NoError(t, repo.RecordAccountNotice(ctx, user.ID))
+	fake.Advance(45 * time.Minute)
+	require.NoError(t, repo.RecordAccountNotice(ctx, user.ID))
+	require.NoError(t, repo.RecordAccountNotice(ctx, other.ID))
+
+	count, err := repo.CountRecentAccountNotices(ctx, user.ID, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	count, err = repo.CountRecentAccountNotices(ctx, user.ID, 30*time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestRecordAccountNotice_DeletesOldNotices(t *testing.T) {
+	db, repo := testutil.NewTestDB(t)
+	ctx := context.Background()
+	fake := clock.NewFake(time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC))
+	repo.SetClock(fake)
+	user := testutil.NewTestUser(t, repo, "testuser")
+
+	require.NoError(t, repo.RecordAccountNotice(ctx, user.ID))
+	fake.Advance(48 * time.Hour)
+	require.NoError(t, repo.RecordAccountNotice(ctx, user.ID))
+
+	var count int
+	require.NoError(t, db.Get(&count, `SELECT COUNT(*) FROM account_notices`))
+	assert.Equal(t, 1, count)
+}
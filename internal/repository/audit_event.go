@@ -0,0 +1,27 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package repository
+
+import (
+	"context"
+
+	"github.com/oliverandrich/go-webapp-template/internal/models"
+)
+
+// CreateAuditEvent records that actorID performed action on targetID.
+func (r *Repository) CreateAuditEvent(ctx context.Context, actorID int64, action string, targetID int64) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO audit_events (actor_id, action, target_id, created_at) VALUES (?, ?, ?, ?)`,
+		actorID, action, targetID, r.clock.Now())
+	return err
+}
+
+// ListAuditEvents returns the most recent audit events, newest first.
+func (r *Repository) ListAuditEvents(ctx context.Context, limit int) ([]models.AuditEvent, error) {
+	var events []models.AuditEvent
+	err := r.db.SelectContext(ctx, &events,
+		`SELECT * FROM audit_events ORDER BY created_at DESC, id DESC LIMIT ?`,
+		limit)
+	return events, err
+}
@@ -0,0 +1,34 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package repository_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/oliverandrich/go-webapp-template/internal/models"
+	"github.com/oliverandrich/go-webapp-template/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateAuditEvent(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	ctx := context.Background()
+	admin := testutil.NewTestUser(t, repo, "admin")
+	user := testutil.NewTestUser(t, repo, "user")
+
+	require.NoError(t, repo.CreateAuditEvent(ctx, admin.ID, models.AuditImpersonationStart, user.ID))
+	require.NoError(t, repo.CreateAuditEvent(ctx, admin.ID, models.AuditImpersonationStop, user.ID))
+
+	events, err := repo.ListAuditEvents(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, models.AuditImpersonationStop, events[0].Action)
+	assert.Equal(t, models.AuditImpersonationStart, events[1].Action)
+	require.NotNil(t, events[1].ActorID)
+	assert.Equal(t, admin.ID, *events[1].ActorID)
+	require.NotNil(t, events[1].TargetID)
+	assert.Equal(t, user.ID, *events[1].TargetID)
+}
@@ -5,6 +5,7 @@ package repository
 
 import (
 	"context"
+	"strings"
 
 	"github.com/oliverandrich/go-webapp-template/internal/models"
 )
@@ -37,11 +38,45 @@ func (r *Repository) GetCredentialsByUserID(ctx context.Context, userID int64) (
 	return creds, nil
 }
 
-// UpdateCredentialSignCount updates the sign count for a credential by credential_id bytes.
+// GetCredentialSummaries retrieves all credentials for a user with their
+// WebAuthn metadata decoded for display.
+func (r *Repository) GetCredentialSummaries(ctx context.Context, userID int64) ([]models.CredentialSummary, error) {
+	creds, err := r.GetCredentialsByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	summaries := make([]models.CredentialSummary, len(creds))
+	for i := range creds {
+		summaries[i] = creds[i].Summary()
+	}
+	return summaries, nil
+}
+
+// GetCredentialByCredentialID retrieves a credential by its WebAuthn credential ID.
+func (r *Repository) GetCredentialByCredentialID(ctx context.Context, credentialID []byte) (*models.Credential, error) {
+	var cred models.Credential
+	err := r.db.GetContext(ctx, &cred, `SELECT * FROM credentials WHERE credential_id = ?`, credentialID)
+	if err != nil {
+		return nil, err
+	}
+	return &cred, nil
+}
+
+// UpdateCredentialSignCount updates the sign count and last-used time for a
+// credential by credential_id bytes. It is called after every successful login.
 func (r *Repository) UpdateCredentialSignCount(ctx context.Context, credentialID []byte, signCount uint32) error {
 	_, err := r.db.ExecContext(ctx,
-		`UPDATE credentials SET sign_count = ? WHERE credential_id = ?`,
-		signCount, credentialID)
+		`UPDATE credentials SET sign_count = ?, last_used_at = ? WHERE credential_id = ?`,
+		signCount, r.clock.Now(), credentialID)
+	return err
+}
+
+// UpdateCredentialBackupState stores the backup flags reported by the
+// authenticator, e.g. when a device-bound passkey starts being synced.
+func (r *Repository) UpdateCredentialBackupState(ctx context.Context, credentialID []byte, eligible, state bool) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE credentials SET backup_eligible = ?, backup_state = ? WHERE credential_id = ?`,
+		eligible, state, credentialID)
 	return err
 }
 
@@ -53,9 +88,52 @@ func (r *Repository) DeleteCredential(ctx context.Context, credID, userID int64)
 	return err
 }
 
+// DeleteCredentialsExcept deletes all of the user's credentials except
+// keepCredID and returns how many were removed. Nothing is deleted unless
+// keepCredID belongs to the user, so the user always keeps a credential.
+func (r *Repository) DeleteCredentialsExcept(ctx context.Context, userID, keepCredID int64) (int64, error) {
+	result, err := r.db.ExecContext(ctx,
+		`DELETE FROM credentials WHERE user_id = ? AND id != ?
+		AND EXISTS (SELECT 1 FROM credentials WHERE id = ? AND user_id = ?)`,
+		userID, keepCredID, keepCredID, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 // CountUserCredentials counts the number of credentials for a user.
 func (r *Repository) CountUserCredentials(ctx context.Context, userID int64) (int64, error) {
 	var count int64
 	err := r.db.GetContext(ctx, &count, `SELECT COUNT(*) FROM credentials WHERE user_id = ?`, userID)
 	return count, err
 }
+
+// UnknownAAGUID is the CountCredentialsByAAGUID bucket for credentials whose
+// authenticator did not disclose its AAGUID (NULL, empty or all zeros).
+const UnknownAAGUID = "unknown"
+
+// CountCredentialsByAAGUID returns the number of credentials per
+// authenticator model, keyed by lower-case hex AAGUID. Credentials without a
+// usable AAGUID are counted under UnknownAAGUID.
+func (r *Repository) CountCredentialsByAAGUID(ctx context.Context) (map[string]int64, error) {
+	var rows []struct {
+		AAGUID string `db:"aaguid_hex"`
+		Count  int64  `db:"n"`
+	}
+	err := r.db.SelectContext(ctx, &rows,
+		`SELECT lower(hex(aaguid)) AS aaguid_hex, COUNT(*) AS n FROM credentials GROUP BY aaguid_hex`)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		key := row.AAGUID
+		if strings.Trim(key, "0") == "" {
+			key = UnknownAAGUID
+		}
+		counts[key] += row.Count
+	}
+	return counts, nil
+}
@@ -6,8 +6,11 @@ package repository_test
 import (
 	"context"
 	"testing"
+	"time"
 
+	"github.com/oliverandrich/go-webapp-template/internal/clock"
 	"github.com/oliverandrich/go-webapp-template/internal/models"
+	"github.com/oliverandrich/go-webapp-template/internal/repository"
 	"github.com/oliverandrich/go-webapp-template/internal/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -74,6 +77,75 @@ func TestUpdateCredentialSignCount(t *testing.T) {
 	assert.Equal(t, uint32(42), updated.SignCount)
 }
 
+func TestUpdateCredentialBackupState(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	ctx := context.Background()
+
+	user := testutil.NewTestUser(t, repo, "testuser")
+	cred := testutil.NewTestCredential(t, repo, user.ID, "my-cred")
+	require.False(t, cred.BackupState)
+
+	require.NoError(t, repo.UpdateCredentialBackupState(ctx, cred.CredentialID, true, true))
+
+	updated, err := repo.GetCredentialByCredentialID(ctx, cred.CredentialID)
+	require.NoError(t, err)
+	assert.True(t, updated.BackupEligible)
+	assert.True(t, updated.BackupState)
+	assert.Equal(t, cred.SignCount, updated.SignCount)
+}
+
+func TestGetCredentialSummaries(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	ctx := context.Background()
+
+	user := testutil.NewTestUser(t, repo, "testuser")
+	known := &models.Credential{
+		UserID:         user.ID,
+		CredentialID:   []byte("known-cred"),
+		PublicKey:      []byte("test-public-key"),
+		AAGUID:         []byte{0xba, 0xda, 0x55, 0x66, 0xa7, 0xaa, 0x40, 0x1f, 0xbd, 0x96, 0x45, 0x61, 0x9a, 0x55, 0x12, 0x0d},
+		Transports:     "internal,hybrid",
+		BackupEligible: true,
+		Name:           "Password manager",
+	}
+	require.NoError(t, repo.CreateCredential(ctx, known))
+	testutil.NewTestCredential(t, repo, user.ID, "security-key")
+
+	summaries, err := repo.GetCredentialSummaries(ctx, user.ID)
+
+	require.NoError(t, err)
+	require.Len(t, summaries, 2)
+	assert.Equal(t, "Password manager", summaries[0].Name)
+	assert.Equal(t, "1Password", summaries[0].Authenticator)
+	assert.Equal(t, []string{"internal", "hybrid"}, summaries[0].Transports)
+	assert.True(t, summaries[0].BackupEligible)
+	assert.False(t, summaries[0].CreatedAt.IsZero())
+	assert.Nil(t, summaries[0].LastUsedAt)
+
+	assert.Equal(t, "security-key", summaries[1].Name)
+	assert.Empty(t, summaries[1].Authenticator)
+	assert.Empty(t, summaries[1].Transports)
+	assert.False(t, summaries[1].BackupEligible)
+}
+
+func TestUpdateCredentialSignCount_SetsLastUsed(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	ctx := context.Background()
+	now := time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)
+	repo.SetClock(clock.NewFake(now))
+
+	user := testutil.NewTestUser(t, repo, "testuser")
+	cred := testutil.NewTestCredential(t, repo, user.ID, "my-cred")
+
+	require.NoError(t, repo.UpdateCredentialSignCount(ctx, cred.CredentialID, 1))
+
+	summaries, err := repo.GetCredentialSummaries(ctx, user.ID)
+	require.NoError(t, err)
+	require.Len(t, summaries, 1)
+	require.NotNil(t, summaries[0].LastUsedAt)
+	assert.True(t, now.Equal(*summaries[0].LastUsedAt))
+}
+
 func TestDeleteCredential(t *testing.T) {
 	db, repo := testutil.NewTestDB(t)
 	ctx := context.Background()
@@ -147,3 +219,101 @@ func TestCountUserCredentials_Zero(t *testing.T) {
 	require.NoError(t, err)
 	assert.Zero(t, count)
 }
+
+func TestGetCredentialByCredentialID(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	ctx := context.Background()
+
+	user := testutil.NewTestUser(t, repo, "testuser")
+	cred := testutil.NewTestCredential(t, repo, user.ID, "cred-1")
+
+	found, err := repo.GetCredentialByCredentialID(ctx, cred.CredentialID)
+
+	require.NoError(t, err)
+	assert.Equal(t, cred.ID, found.ID)
+
+	_, err = repo.GetCredentialByCredentialID(ctx, []byte("unknown"))
+	require.Error(t, err)
+}
+
+func TestDeleteCredentialsExcept(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	ctx := context.Background()
+
+	user := testutil.NewTestUser(t, repo, "testuser")
+	other := testutil.NewTestUser(t, repo, "other")
+	testutil.NewTestCredential(t, repo, user.ID, "cred-1")
+	keep := testutil.NewTestCredential(t, repo, user.ID, "cred-2")
+	testutil.NewTestCredential(t, repo, user.ID, "cred-3")
+	testutil.NewTestCredential(t, repo, other.ID, "other-cred")
+
+	removed, err := repo.DeleteCredentialsExcept(ctx, user.ID, keep.ID)
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), removed)
+
+	creds, err := repo.GetCredentialsByUserID(ctx, user.ID)
+	require.NoError(t, err)
+	require.Len(t, creds, 1)
+	assert.Equal(t, keep.ID, creds[0].ID)
+
+	// Other users are untouched
+	count, err := repo.CountUserCredentials(ctx, other.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestDeleteCredentialsExcept_ForeignCredential(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	ctx := context.Background()
+
+	user := testutil.NewTestUser(t, repo, "testuser")
+	other := testutil.NewTestUser(t, repo, "other")
+	testutil.NewTestCredential(t, repo, user.ID, "cred-1")
+	testutil.NewTestCredential(t, repo, user.ID, "cred-2")
+	foreign := testutil.NewTestCredential(t, repo, other.ID, "other-cred")
+
+	// Keeping a credential the user does not own must not delete everything
+	removed, err := repo.DeleteCredentialsExcept(ctx, user.ID, foreign.ID)
+
+	require.NoError(t, err)
+	assert.Zero(t, removed)
+	count, err := repo.CountUserCredentials(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+}
+
+func TestCountCredentialsByAAGUID(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	ctx := context.Background()
+	user := testutil.NewTestUser(t, repo, "testuser")
+
+	icloud := []byte{0xfb, 0xfc, 0x30, 0x07, 0x15, 0x4e, 0x4e, 0xcc, 0x8c, 0x0b, 0x6e, 0x02, 0x05, 0x57, 0xd7, 0xbd}
+	yubikey := []byte{0xee, 0x88, 0x28, 0x79, 0x72, 0x1c, 0x49, 0x13, 0x97, 0x75, 0x3d, 0xfc, 0xce, 0x97, 0x07, 0x2a}
+	for i, aaguid := range [][]byte{icloud, icloud, yubikey, nil, {}, make([]byte, 16)} {
+		require.NoError(t, repo.CreateCredential(ctx, &models.Credential{
+			UserID:       user.ID,
+			CredentialID: []byte{byte(i)},
+			PublicKey:    []byte("key"),
+			AAGUID:       aaguid,
+		}))
+	}
+
+	counts, err := repo.CountCredentialsByAAGUID(ctx)
+
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int64{
+		"fbfc3007154e4ecc8c0b6e020557d7bd": 2,
+		"ee882879721c491397753dfcce97072a": 1,
+		repository.UnknownAAGUID:           3,
+	}, counts)
+}
+
+func TestCountCredentialsByAAGUID_Empty(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+
+	counts, err := repo.CountCredentialsByAAGUID(context.Background())
+
+	require.NoError(t, err)
+	assert.Empty(t, counts)
+}
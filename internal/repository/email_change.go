@@ -0,0 +1,52 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/oliverandrich/go-webapp-template/internal/models"
+)
+
+// CreatePendingEmailChange stores a pending email change for a user.
+func (r *Repository) CreatePendingEmailChange(ctx context.Context, userID int64, newEmail, tokenHash string, expiresAt time.Time) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO pending_email_changes (user_id, new_email, token_hash, expires_at) VALUES (?, ?, ?, ?)`,
+		userID, newEmail, tokenHash, expiresAt)
+	return err
+}
+
+// GetPendingEmailChange retrieves a pending email change by token hash.
+func (r *Repository) GetPendingEmailChange(ctx context.Context, tokenHash string) (*models.PendingEmailChange, error) {
+	var change models.PendingEmailChange
+	err := r.db.GetContext(ctx, &change, `SELECT * FROM pending_email_changes WHERE token_hash = ?`, tokenHash)
+	if err != nil {
+		return nil, err
+	}
+	return &change, nil
+}
+
+// DeleteUserPendingEmailChanges deletes all pending email changes for a user.
+func (r *Repository) DeleteUserPendingEmailChanges(ctx context.Context, userID int64) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM pending_email_changes WHERE user_id = ?`, userID)
+	return err
+}
+
+// UpdateUserEmail sets a user's email address and marks it as verified.
+// In email mode the username mirrors the email, so it is updated as well, as
+// is a display name still left at the old address.
+func (r *Repository) UpdateUserEmail(ctx context.Context, userID int64, email string) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE users SET
+			username = CASE WHEN username = email THEN ?1 ELSE username END,
+			display_name = CASE WHEN display_name = email THEN ?1 ELSE display_name END,
+			email = ?1,
+			email_verified = 1,
+			email_verified_at = CURRENT_TIMESTAMP,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?2`,
+		email, userID)
+	return err
+}
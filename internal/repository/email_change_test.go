@@ -0,0 +1,90 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/oliverandrich/go-webapp-template/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreatePendingEmailChange(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	ctx := context.Background()
+
+	user := testutil.NewTestUser(t, repo, "testuser")
+	expiresAt := time.Now().Add(24 * time.Hour)
+
+	err := repo.CreatePendingEmailChange(ctx, user.ID, "new@example.com", "changehash", expiresAt)
+	require.NoError(t, err)
+
+	change, err := repo.GetPendingEmailChange(ctx, "changehash")
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, change.UserID)
+	assert.Equal(t, "new@example.com", change.NewEmail)
+	assert.WithinDuration(t, expiresAt, change.ExpiresAt, time.Second)
+}
+
+func TestGetPendingEmailChange_NotFound(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	ctx := context.Background()
+
+	_, err := repo.GetPendingEmailChange(ctx, "nonexistent")
+
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestDeleteUserPendingEmailChanges(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	ctx := context.Background()
+
+	user := testutil.NewTestUser(t, repo, "testuser")
+	expiresAt := time.Now().Add(time.Hour)
+	require.NoError(t, repo.CreatePendingEmailChange(ctx, user.ID, "a@example.com", "hash1", expiresAt))
+	require.NoError(t, repo.CreatePendingEmailChange(ctx, user.ID, "b@example.com", "hash2", expiresAt))
+
+	require.NoError(t, repo.DeleteUserPendingEmailChanges(ctx, user.ID))
+
+	_, err := repo.GetPendingEmailChange(ctx, "hash1")
+	require.ErrorIs(t, err, sql.ErrNoRows)
+	_, err = repo.GetPendingEmailChange(ctx, "hash2")
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestUpdateUserEmail_EmailMode(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	ctx := context.Background()
+
+	user, err := repo.CreateUserWithEmail(ctx, "old@example.com")
+	require.NoError(t, err)
+
+	require.NoError(t, repo.UpdateUserEmail(ctx, user.ID, "new@example.com"))
+
+	updated, err := repo.GetUserByID(ctx, user.ID)
+	require.NoError(t, err)
+	require.NotNil(t, updated.Email)
+	assert.Equal(t, "new@example.com", *updated.Email)
+	assert.Equal(t, "new@example.com", updated.Username)
+	assert.Equal(t, "new@example.com", updated.DisplayName)
+	assert.True(t, updated.EmailVerified)
+}
+
+func TestUpdateUserEmail_KeepsDistinctUsername(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	ctx := context.Background()
+
+	user := testutil.NewTestUser(t, repo, "alice")
+
+	require.NoError(t, repo.UpdateUserEmail(ctx, user.ID, "alice@example.com"))
+
+	updated, err := repo.GetUserByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", updated.Username)
+	assert.Equal(t, "alice@example.com", *updated.Email)
+}
@@ -28,6 +28,19 @@ func (r *Repository) GetEmailVerificationToken(ctx context.Context, tokenHash st
 	return &token, nil
 }
 
+// ConsumeEmailVerificationToken deletes the token with the given hash and
+// returns it. Deleting and reading in one statement makes the token single
+// use: of two concurrent callers only one gets it, the other sees
+// sql.ErrNoRows.
+func (r *Repository) ConsumeEmailVerificationToken(ctx context.Context, tokenHash string) (*models.EmailVerificationToken, error) {
+	var token models.EmailVerificationToken
+	err := r.db.GetContext(ctx, &token, `DELETE FROM email_verification_tokens WHERE token_hash = ? RETURNING *`, tokenHash)
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
 // DeleteEmailVerificationToken deletes a token by ID.
 func (r *Repository) DeleteEmailVerificationToken(ctx context.Context, tokenID int64) error {
 	_, err := r.db.ExecContext(ctx, `DELETE FROM email_verification_tokens WHERE id = ?`, tokenID)
@@ -42,6 +55,6 @@ func (r *Repository) DeleteUserEmailVerificationTokens(ctx context.Context, user
 
 // DeleteExpiredEmailVerificationTokens deletes expired tokens.
 func (r *Repository) DeleteExpiredEmailVerificationTokens(ctx context.Context) error {
-	_, err := r.db.ExecContext(ctx, `DELETE FROM email_verification_tokens WHERE expires_at < ?`, time.Now())
+	_, err := r.db.ExecContext(ctx, `DELETE FROM email_verification_tokens WHERE expires_at < ?`, r.clock.Now())
 	return err
 }
@@ -62,6 +62,22 @@ func TestGetEmailVerificationToken_NotFound(t *testing.T) {
 	assert.ErrorIs(t, err, sql.ErrNoRows)
 }
 
+func TestConsumeEmailVerificationToken(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	ctx := context.Background()
+
+	user := testutil.NewTestUser(t, repo, "testuser")
+	require.NoError(t, repo.CreateEmailVerificationToken(ctx, user.ID, "abc123hash", time.Now().Add(time.Hour)))
+
+	token, err := repo.ConsumeEmailVerificationToken(ctx, "abc123hash")
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, token.UserID)
+
+	// A token can only be consumed once
+	_, err = repo.ConsumeEmailVerificationToken(ctx, "abc123hash")
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
+
 func TestDeleteEmailVerificationToken(t *testing.T) {
 	_, repo := testutil.NewTestDB(t)
 	ctx := context.Background()
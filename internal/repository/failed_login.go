@@ -0,0 +1,34 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+// RecordFailedLogin records a failed login attempt for a user from a client.
+func (r *Repository) RecordFailedLogin(ctx context.Context, userID int64, client string) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO failed_logins (user_id, client, created_at) VALUES (?, ?, ?)`,
+		userID, client, r.clock.Now())
+	return err
+}
+
+// GetRecentFailedLoginCount counts a user's failed login attempts from a
+// client within the given window up to now.
+func (r *Repository) GetRecentFailedLoginCount(ctx context.Context, userID int64, client string, window time.Duration) (int64, error) {
+	var count int64
+	err := r.db.GetContext(ctx, &count,
+		`SELECT COUNT(*) FROM failed_logins WHERE user_id = ? AND client = ? AND created_at > ?`,
+		userID, client, r.clock.Now().Add(-window))
+	return count, err
+}
+
+// ResetFailedLogins deletes the recorded failed login attempts of a user from
+// a client.
+func (r *Repository) ResetFailedLogins(ctx context.Context, userID int64, client string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM failed_logins WHERE user_id = ? AND client = ?`, userID, client)
+	return err
+}
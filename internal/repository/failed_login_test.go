@@ -0,0 +1,96 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/oliverandrich/go-webapp-template/internal/clock"
+	"github.com/oliverandrich/go-webapp-template/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordFailedLogin(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	ctx := context.Background()
+	user := testutil.NewTestUser(t, repo, "testuser")
+	other := testutil.NewTestUser(t, repo, "other")
+
+	for range 3 {
+		require.NoError(t, repo.RecordFailedLogin(ctx, user.ID, "192.0.2.1"))
+	}
+	require.NoError(t, repo.RecordFailedLogin(ctx, other.ID, "192.0.2.1"))
+
+	count, err := repo.GetRecentFailedLoginCount(ctx, user.ID, "192.0.2.1", time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+}
+
+func TestGetRecentFailedLoginCount_OutsideWindow(t *testing.T) {
+	db, repo := testutil.NewTestDB(t)
+	ctx := context.Background()
+	user := testutil.NewTestUser(t, repo, "testuser")
+
+	_, err := db.ExecContext(ctx, `INSERT INTO failed_logins (user_id, client, created_at) VALUES (?, ?, ?)`,
+		user.ID, "192.0.2.1", time.Now().Add(-2*time.Hour))
+	require.NoError(t, err)
+	require.NoError(t, repo.RecordFailedLogin(ctx, user.ID, "192.0.2.1"))
+
+	count, err := repo.GetRecentFailedLoginCount(ctx, user.ID, "192.0.2.1", time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestResetFailedLogins(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	ctx := context.Background()
+	user := testutil.NewTestUser(t, repo, "testuser")
+	require.NoError(t, repo.RecordFailedLogin(ctx, user.ID, "192.0.2.1"))
+
+	require.NoError(t, repo.ResetFailedLogins(ctx, user.ID, "192.0.2.1"))
+
+	count, err := repo.GetRecentFailedLoginCount(ctx, user.ID, "192.0.2.1", time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), count)
+}
+
+func TestGetRecentFailedLoginCount_WindowFollowsClock(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	ctx := context.Background()
+	user := testutil.NewTestUser(t, repo, "testuser")
+
+	fake := clock.NewFake(time.Now())
+	repo.SetClock(fake)
+	require.NoError(t, repo.RecordFailedLogin(ctx, user.ID, "192.0.2.1"))
+
+	fake.Advance(59 * time.Minute)
+	count, err := repo.GetRecentFailedLoginCount(ctx, user.ID, "192.0.2.1", time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	fake.Advance(2 * time.Minute)
+	count, err = repo.GetRecentFailedLoginCount(ctx, user.ID, "192.0.2.1", time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), count)
+}
+
+func TestFailedLogins_PerClient(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	ctx := context.Background()
+	user := testutil.NewTestUser(t, repo, "testuser")
+	require.NoError(t, repo.RecordFailedLogin(ctx, user.ID, "192.0.2.1"))
+	require.NoError(t, repo.RecordFailedLogin(ctx, user.ID, "198.51.100.1"))
+
+	require.NoError(t, repo.ResetFailedLogins(ctx, user.ID, "192.0.2.1"))
+
+	count, err := repo.GetRecentFailedLoginCount(ctx, user.ID, "192.0.2.1", time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), count)
+	count, err = repo.GetRecentFailedLoginCount(ctx, user.ID, "198.51.100.1", time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+}
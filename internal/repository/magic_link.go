@@ -0,0 +1,76 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/oliverandrich/go-webapp-template/internal/models"
+)
+
+// magicLinkRetention is how long expired sign-in link tokens are kept, so
+// CountRecentMagicLinkTokens still sees them.
+const magicLinkRetention = 24 * time.Hour
+
+// CreateMagicLinkToken stores the hash of a new sign-in link token. Tokens
+// that expired more than magicLinkRetention ago are deleted first, so the
+// table stays small.
+func (r *Repository) CreateMagicLinkToken(ctx context.Context, userID int64, tokenHash string, expiresAt time.Time) error {
+	now := r.clock.Now()
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM magic_link_tokens WHERE expires_at < ?`, now.Add(-magicLinkRetention)); err != nil {
+		return err
+	}
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO magic_link_tokens (user_id, token_hash, expires_at, created_at) VALUES (?, ?, ?, ?)`,
+		userID, tokenHash, expiresAt, now)
+	return err
+}
+
+// CountRecentMagicLinkTokens counts the sign-in links issued to a user
+// within the given window up to now, used, expired or not. window must not
+// exceed one day.
+func (r *Repository) CountRecentMagicLinkTokens(ctx context.Context, userID int64, window time.Duration) (int64, error) {
+	var count int64
+	err := r.db.GetContext(ctx, &count,
+		`SELECT COUNT(*) FROM magic_link_tokens WHERE user_id = ? AND created_at > ?`,
+		userID, r.clock.Now().Add(-window))
+	return count, err
+}
+
+// RecordMagicLinkRequest counts a sign-in link request from client and
+// returns the number of requests from client within the given window up to
+// now, this one included. Requests older than magicLinkRetention are deleted
+// first. window must not exceed one day.
+func (r *Repository) RecordMagicLinkRequest(ctx context.Context, client string, window time.Duration) (int64, error) {
+	now := r.clock.Now()
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM magic_link_requests WHERE created_at < ?`, now.Add(-magicLinkRetention)); err != nil {
+		return 0, err
+	}
+	if _, err := r.db.ExecContext(ctx,
+		`INSERT INTO magic_link_requests (client, created_at) VALUES (?, ?)`,
+		client, now); err != nil {
+		return 0, err
+	}
+	var count int64
+	err := r.db.GetContext(ctx, &count,
+		`SELECT COUNT(*) FROM magic_link_requests WHERE client = ? AND created_at > ?`,
+		client, now.Add(-window))
+	return count, err
+}
+
+// ConsumeMagicLinkToken marks the token with the given hash as used and
+// returns it, so every link signs in at most once. The row stays until it is
+// cleaned up with the expired ones and keeps counting towards the limit.
+// Unknown or already used tokens yield sql.ErrNoRows.
+func (r *Repository) ConsumeMagicLinkToken(ctx context.Context, tokenHash string) (*models.MagicLinkToken, error) {
+	var token models.MagicLinkToken
+	err := r.db.GetContext(ctx, &token,
+		`UPDATE magic_link_tokens SET used_at = ? WHERE token_hash = ? AND used_at IS NULL RETURNING *`,
+		r.clock.Now(), tokenHash)
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
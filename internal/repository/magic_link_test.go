@@ -0,0 +1,102 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/oliverandrich/go-webapp-template/internal/clock"
+	"github.com/oliverandrich/go-webapp-template/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConsumeMagicLinkToken_SingleUse(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	ctx := context.Background()
+	user := testutil.NewTestUser(t, repo, "testuser")
+	expiresAt := time.Now().Add(15 * time.Minute)
+	require.NoError(t, repo.CreateMagicLinkToken(ctx, user.ID, "hash", expiresAt))
+
+	token, err := repo.ConsumeMagicLinkToken(ctx, "hash")
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, token.UserID)
+	assert.WithinDuration(t, expiresAt, token.ExpiresAt, time.Second)
+
+	_, err = repo.ConsumeMagicLinkToken(ctx, "hash")
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestCountRecentMagicLinkTokens(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	ctx := context.Background()
+	fake := clock.NewFake(time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC))
+	repo.SetClock(fake)
+	user := testutil.NewTestUser(t, repo, "testuser")
+	other := testutil.NewTestUser(t, repo, "other")
+
+	require.NoError(t, repo.CreateMagicLinkToken(ctx, user.ID, "old", fake.Now().Add(15*time.Minute)))
+	fake.Advance(45 * time.Minute)
+	require.NoError(t, repo.CreateMagicLinkToken(ctx, user.ID, "new", fake.Now().Add(15*time.Minute)))
+	require.NoError(t, repo.CreateMagicLinkToken(ctx, other.ID, "other", fake.Now().Add(15*time.Minute)))
+
+	// Expired tokens still count within the window
+	count, err := repo.CountRecentMagicLinkTokens(ctx, user.ID, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	count, err = repo.CountRecentMagicLinkTokens(ctx, user.ID, 30*time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	// Used links keep counting
+	_, err = repo.ConsumeMagicLinkToken(ctx, "new")
+	require.NoError(t, err)
+	count, err = repo.CountRecentMagicLinkTokens(ctx, user.ID, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+}
+
+func TestCreateMagicLinkToken_DeletesLongExpiredTokens(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	ctx := context.Background()
+	fake := clock.NewFake(time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC))
+	repo.SetClock(fake)
+	user := testutil.NewTestUser(t, repo, "testuser")
+
+	require.NoError(t, repo.CreateMagicLinkToken(ctx, user.ID, "stale", fake.Now().Add(15*time.Minute)))
+	fake.Advance(48 * time.Hour)
+	require.NoError(t, repo.CreateMagicLinkToken(ctx, user.ID, "fresh", fake.Now().Add(15*time.Minute)))
+
+	_, err := repo.ConsumeMagicLinkToken(ctx, "stale")
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	_, err = repo.ConsumeMagicLinkToken(ctx, "fresh")
+	assert.NoError(t, err)
+}
+
+func TestRecordMagicLinkRequest(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	ctx := context.Background()
+	fake := clock.NewFake(time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC))
+	repo.SetClock(fake)
+
+	count, err := repo.RecordMagicLinkRequest(ctx, "192.0.2.1", time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	fake.Advance(45 * time.Minute)
+	_, err = repo.RecordMagicLinkRequest(ctx, "198.51.100.1", time.Hour)
+	require.NoError(t, err)
+	count, err = repo.RecordMagicLinkRequest(ctx, "192.0.2.1", time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	fake.Advance(30 * time.Minute)
+	count, err = repo.RecordMagicLinkRequest(ctx, "192.0.2.1", time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+}
@@ -0,0 +1,74 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/oliverandrich/go-webapp-template/internal/models"
+)
+
+// CreateNotification stores a notification for a user. payload is encoded as
+// JSON; nil stores an empty object.
+func (r *Repository) CreateNotification(ctx context.Context, userID int64, notificationType string, payload any) (*models.Notification, error) {
+	data := []byte("{}")
+	if payload != nil {
+		var err error
+		if data, err = json.Marshal(payload); err != nil {
+			return nil, err
+		}
+	}
+
+	var n models.Notification
+	err := r.db.GetContext(ctx, &n,
+		`INSERT INTO notifications (user_id, type, payload, created_at) VALUES (?, ?, ?, ?) RETURNING *`,
+		userID, notificationType, string(data), r.clock.Now())
+	if err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+// ListUnreadNotifications returns a user's unread notifications, newest first.
+func (r *Repository) ListUnreadNotifications(ctx context.Context, userID int64, limit int) ([]models.Notification, error) {
+	notifications := []models.Notification{}
+	err := r.db.SelectContext(ctx, &notifications,
+		`SELECT * FROM notifications WHERE user_id = ? AND read_at IS NULL ORDER BY created_at DESC, id DESC LIMIT ?`,
+		userID, limit)
+	return notifications, err
+}
+
+// MarkNotificationRead marks one of the user's notifications as read. A
+// notification that is already read keeps its read time. Returns
+// sql.ErrNoRows if the user has no notification with that ID.
+func (r *Repository) MarkNotificationRead(ctx context.Context, id, userID int64) error {
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE notifications SET read_at = COALESCE(read_at, ?) WHERE id = ? AND user_id = ?`,
+		r.clock.Now(), id, userID)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// MarkAllNotificationsRead marks all unread notifications of a user as read
+// and returns how many were changed.
+func (r *Repository) MarkAllNotificationsRead(ctx context.Context, userID int64) (int64, error) {
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE notifications SET read_at = ? WHERE user_id = ? AND read_at IS NULL`,
+		r.clock.Now(), userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,131 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/oliverandrich/go-webapp-template/internal/clock"
+	"github.com/oliverandrich/go-webapp-template/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateNotification(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	ctx := context.Background()
+	now := time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)
+	repo.SetClock(clock.NewFake(now))
+	user := testutil.NewTestUser(t, repo, "testuser")
+
+	n, err := repo.CreateNotification(ctx, user.ID, "passkey.added", map[string]string{"name": "YubiKey"})
+
+	require.NoError(t, err)
+	assert.NotZero(t, n.ID)
+	assert.Equal(t, user.ID, n.UserID)
+	assert.Equal(t, "passkey.added", n.Type)
+	assert.JSONEq(t, `{"name":"YubiKey"}`, string(n.Payload))
+	assert.Nil(t, n.ReadAt)
+	assert.True(t, now.Equal(n.CreatedAt))
+}
+
+func TestCreateNotification_NilPayload(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	user := testutil.NewTestUser(t, repo, "testuser")
+
+	n, err := repo.CreateNotification(context.Background(), user.ID, "welcome", nil)
+
+	require.NoError(t, err)
+	assert.JSONEq(t, `{}`, string(n.Payload))
+}
+
+func TestListUnreadNotifications(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	ctx := context.Background()
+	fake := clock.NewFake(time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC))
+	repo.SetClock(fake)
+	user := testutil.NewTestUser(t, repo, "testuser")
+	other := testutil.NewTestUser(t, repo, "other")
+
+	first, err := repo.CreateNotification(ctx, user.ID, "first", nil)
+	require.NoError(t, err)
+	fake.Advance(time.Minute)
+	_, err = repo.CreateNotification(ctx, user.ID, "second", nil)
+	require.NoError(t, err)
+	_, err = repo.CreateNotification(ctx, other.ID, "foreign", nil)
+	require.NoError(t, err)
+
+	unread, err := repo.ListUnreadNotifications(ctx, user.ID, 10)
+	require.NoError(t, err)
+	require.Len(t, unread, 2)
+	assert.Equal(t, "second", unread[0].Type)
+	assert.Equal(t, "first", unread[1].Type)
+
+	require.NoError(t, repo.MarkNotificationRead(ctx, first.ID, user.ID))
+	unread, err = repo.ListUnreadNotifications(ctx, user.ID, 10)
+	require.NoError(t, err)
+	require.Len(t, unread, 1)
+	assert.Equal(t, "second", unread[0].Type)
+
+	unread, err = repo.ListUnreadNotifications(ctx, user.ID, 0)
+	require.NoError(t, err)
+	assert.Empty(t, unread)
+}
+
+func TestMarkNotificationRead(t *testing.T) {
+	db, repo := testutil.NewTestDB(t)
+	ctx := context.Background()
+	fake := clock.NewFake(time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC))
+	repo.SetClock(fake)
+	user := testutil.NewTestUser(t, repo, "testuser")
+	other := testutil.NewTestUser(t, repo, "other")
+	n, err := repo.CreateNotification(ctx, user.ID, "test", nil)
+	require.NoError(t, err)
+
+	// Other users can't mark it, unknown IDs are reported
+	assert.ErrorIs(t, repo.MarkNotificationRead(ctx, n.ID, other.ID), sql.ErrNoRows)
+	assert.ErrorIs(t, repo.MarkNotificationRead(ctx, 999, user.ID), sql.ErrNoRows)
+
+	readAt := fake.Now()
+	require.NoError(t, repo.MarkNotificationRead(ctx, n.ID, user.ID))
+
+	// Marking it again keeps the first read time
+	fake.Advance(time.Hour)
+	require.NoError(t, repo.MarkNotificationRead(ctx, n.ID, user.ID))
+
+	count, err := repo.MarkAllNotificationsRead(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Zero(t, count)
+
+	var got time.Time
+	require.NoError(t, db.Get(&got, `SELECT read_at FROM notifications WHERE id = ?`, n.ID))
+	assert.True(t, readAt.Equal(got))
+}
+
+func TestMarkAllNotificationsRead(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	ctx := context.Background()
+	user := testutil.NewTestUser(t, repo, "testuser")
+	other := testutil.NewTestUser(t, repo, "other")
+	for range 3 {
+		_, err := repo.CreateNotification(ctx, user.ID, "test", nil)
+		require.NoError(t, err)
+	}
+	_, err := repo.CreateNotification(ctx, other.ID, "test", nil)
+	require.NoError(t, err)
+
+	count, err := repo.MarkAllNotificationsRead(ctx, user.ID)
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+	unread, err := repo.ListUnreadNotifications(ctx, user.ID, 10)
+	require.NoError(t, err)
+	assert.Empty(t, unread)
+	unread, err = repo.ListUnreadNotifications(ctx, other.ID, 10)
+	require.NoError(t, err)
+	assert.Len(t, unread, 1)
+}
@@ -0,0 +1,73 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// queryLogger logs the queries run through db: those slower than slow at
+// warn level, all others at debug level. Arguments are never logged, as they
+// may hold secrets.
+type queryLogger struct {
+	db   dbtx
+	slow time.Duration
+}
+
+func (l *queryLogger) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	start := time.Now()
+	result, err := l.db.ExecContext(ctx, query, args...)
+	rows := int64(-1)
+	if err == nil {
+		rows, _ = result.RowsAffected()
+	}
+	l.log(ctx, query, time.Since(start), rows, err)
+	return result, err
+}
+
+func (l *queryLogger) GetContext(ctx context.Context, dest any, query string, args ...any) error {
+	start := time.Now()
+	err := l.db.GetContext(ctx, dest, query, args...)
+	rows := int64(1)
+	if err != nil {
+		rows = 0
+	}
+	l.log(ctx, query, time.Since(start), rows, err)
+	return err
+}
+
+func (l *queryLogger) SelectContext(ctx context.Context, dest any, query string, args ...any) error {
+	start := time.Now()
+	err := l.db.SelectContext(ctx, dest, query, args...)
+	rows := int64(0)
+	if v := reflect.ValueOf(dest); v.Kind() == reflect.Pointer && v.Elem().Kind() == reflect.Slice {
+		rows = int64(v.Elem().Len())
+	}
+	l.log(ctx, query, time.Since(start), rows, err)
+	return err
+}
+
+func (l *queryLogger) log(ctx context.Context, query string, d time.Duration, rows int64, err error) {
+	level, msg := slog.LevelDebug, "query"
+	if d >= l.slow {
+		level, msg = slog.LevelWarn, "slow query"
+	}
+	if !slog.Default().Enabled(ctx, level) {
+		return
+	}
+	attrs := []slog.Attr{
+		slog.String("sql", strings.Join(strings.Fields(query), " ")),
+		slog.Duration("duration", d),
+		slog.Int64("rows", rows),
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+	slog.LogAttrs(ctx, level, msg, attrs...)
+}
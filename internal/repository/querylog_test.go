@@ -0,0 +1,105 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package repository_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oliverandrich/go-webapp-template/internal/repository"
+	"github.com/oliverandrich/go-webapp-template/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// captureLogs routes the default slog logger into a buffer for the test.
+func captureLogs(t *testing.T, level slog.Level) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: level})))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+	return &buf
+}
+
+// logRecords decodes the JSON log records written to buf.
+func logRecords(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var record map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &record))
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestLogQueries_SlowQuery(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	buf := captureLogs(t, slog.LevelInfo)
+	repo.LogQueries(time.Nanosecond) // every query is slow
+
+	_, err := repo.CreateUser(context.Background(), "alice")
+	require.NoError(t, err)
+
+	records := logRecords(t, buf)
+	require.Len(t, records, 2)
+	assert.Equal(t, "WARN", records[0]["level"])
+	assert.Equal(t, "slow query", records[0]["msg"])
+	assert.Equal(t, "INSERT INTO users (username, display_name) VALUES (?, ?)", records[0]["sql"])
+	assert.InDelta(t, 1, records[0]["rows"], 0)
+	assert.NotZero(t, records[0]["duration"])
+	assert.NotContains(t, buf.String(), "alice", "arguments must not be logged")
+}
+
+func TestLogQueries_FastQueriesAtDebug(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	testutil.NewTestUser(t, repo, "alice")
+	testutil.NewTestUser(t, repo, "bob")
+
+	info := captureLogs(t, slog.LevelInfo)
+	repo.LogQueries(time.Hour)
+	_, err := repo.SearchUsers(context.Background(), "", 0)
+	require.NoError(t, err)
+	assert.Empty(t, info.String(), "fast queries stay below info level")
+
+	debug := captureLogs(t, slog.LevelDebug)
+	_, err = repo.SearchUsers(context.Background(), "", 0)
+	require.NoError(t, err)
+	records := logRecords(t, debug)
+	require.Len(t, records, 1)
+	assert.Equal(t, "query", records[0]["msg"])
+	assert.InDelta(t, 2, records[0]["rows"], 0)
+}
+
+func TestLogQueries_InsideTransaction(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	buf := captureLogs(t, slog.LevelDebug)
+	repo.LogQueries(time.Hour)
+
+	err := repo.WithTx(context.Background(), func(tx *repository.Repository) error {
+		_, err := tx.CreateUser(context.Background(), "alice")
+		return err
+	})
+	require.NoError(t, err)
+
+	assert.Len(t, logRecords(t, buf), 2)
+}
+
+func TestLogQueries_OffByDefault(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	buf := captureLogs(t, slog.LevelDebug)
+
+	testutil.NewTestUser(t, repo, "alice")
+
+	assert.Empty(t, buf.String())
+}
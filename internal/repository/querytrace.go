@@ -0,0 +1,74 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// queryTracer records a client span for the queries run through db. Queries
+// without an active span in their context (background jobs, startup) are
+// not traced, so every span belongs to a request. Arguments are never
+// recorded, as they may hold secrets.
+type queryTracer struct {
+	db     dbtx
+	tracer trace.Tracer
+}
+
+func (t *queryTracer) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	ctx, span := t.start(ctx, query)
+	result, err := t.db.ExecContext(ctx, query, args...)
+	endQuerySpan(span, err)
+	return result, err
+}
+
+func (t *queryTracer) GetContext(ctx context.Context, dest any, query string, args ...any) error {
+	ctx, span := t.start(ctx, query)
+	err := t.db.GetContext(ctx, dest, query, args...)
+	endQuerySpan(span, err)
+	return err
+}
+
+func (t *queryTracer) SelectContext(ctx context.Context, dest any, query string, args ...any) error {
+	ctx, span := t.start(ctx, query)
+	err := t.db.SelectContext(ctx, dest, query, args...)
+	endQuerySpan(span, err)
+	return err
+}
+
+// start begins a span named after the SQL operation, e.g. "SELECT". The
+// returned span is a no-op when ctx carries no active span.
+func (t *queryTracer) start(ctx context.Context, query string) (context.Context, trace.Span) {
+	parent := trace.SpanFromContext(ctx)
+	if !parent.SpanContext().IsValid() {
+		return ctx, parent
+	}
+	text := strings.Join(strings.Fields(query), " ")
+	operation, _, _ := strings.Cut(text, " ")
+	return t.tracer.Start(ctx, strings.ToUpper(operation),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName(strings.ToUpper(operation)),
+			semconv.DBQueryText(text),
+		),
+	)
+}
+
+// endQuerySpan marks span as failed for errors other than sql.ErrNoRows,
+// which lookups use to report a missing row, and ends it.
+func endQuerySpan(span trace.Span, err error) {
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,77 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package repository_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/oliverandrich/go-webapp-template/internal/repository"
+	"github.com/oliverandrich/go-webapp-template/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// newTracedRepo returns a repository tracing its queries into the returned
+// exporter, and a tracer for starting parent spans.
+func newTracedRepo(t *testing.T) (*repository.Repository, *tracetest.InMemoryExporter, trace.Tracer) {
+	t.Helper()
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	t.Cleanup(func() { _ = tp.Shutdown(context.Background()) })
+
+	_, repo := testutil.NewTestDB(t)
+	repo.TraceQueries(tp)
+	return repo, exporter, tp.Tracer("test")
+}
+
+func TestTraceQueries_ChildSpans(t *testing.T) {
+	repo, exporter, tracer := newTracedRepo(t)
+
+	ctx, parent := tracer.Start(context.Background(), "request")
+	_, err := repo.GetUserByID(ctx, 999)
+	parent.End()
+
+	require.Error(t, err)
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 2)
+	query := spans[0]
+	assert.Equal(t, "SELECT", query.Name)
+	assert.Equal(t, trace.SpanKindClient, query.SpanKind)
+	assert.Equal(t, parent.SpanContext().SpanID(), query.Parent.SpanID())
+	attrs := make(map[string]string)
+	for _, kv := range query.Attributes {
+		attrs[string(kv.Key)] = kv.Value.Emit()
+	}
+	assert.Equal(t, "sqlite", attrs["db.system.name"])
+	assert.Equal(t, "SELECT * FROM users WHERE id = ? AND deleted_at IS NULL", attrs["db.query.text"])
+	// A missing row is an answer, not a failure
+	assert.Empty(t, query.Events)
+}
+
+func TestTraceQueries_InTransaction(t *testing.T) {
+	repo, exporter, tracer := newTracedRepo(t)
+
+	ctx, parent := tracer.Start(context.Background(), "request")
+	err := repo.WithTx(ctx, func(tx *repository.Repository) error {
+		_, createErr := tx.CreateUser(ctx, "alice")
+		return createErr
+	})
+	parent.End()
+
+	require.NoError(t, err)
+	assert.Greater(t, len(exporter.GetSpans()), 1)
+}
+
+func TestTraceQueries_SkipsQueriesWithoutSpan(t *testing.T) {
+	repo, exporter, _ := newTracedRepo(t)
+
+	_, err := repo.CreateUser(context.Background(), "alice")
+
+	require.NoError(t, err)
+	assert.Empty(t, exporter.GetSpans())
+}
@@ -0,0 +1,42 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+// registrationDay returns the UTC day attempts made now are counted for.
+func (r *Repository) registrationDay() string {
+	return r.clock.Now().UTC().Format(time.DateOnly)
+}
+
+// IncrementRegistrationAttempts counts a registration attempt from ip for the
+// current UTC day and returns the day's total. Counts of earlier days are
+// deleted, so every IP starts the next day from zero.
+func (r *Repository) IncrementRegistrationAttempts(ctx context.Context, ip string) (int64, error) {
+	day := r.registrationDay()
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM registration_attempts WHERE day < ?`, day); err != nil {
+		return 0, err
+	}
+
+	var count int64
+	err := r.db.GetContext(ctx, &count,
+		`INSERT INTO registration_attempts (ip, day, count) VALUES (?, ?, 1)
+		ON CONFLICT (ip, day) DO UPDATE SET count = count + 1
+		RETURNING count`,
+		ip, day)
+	return count, err
+}
+
+// GetRegistrationAttempts returns the registration attempts counted for ip on
+// the current UTC day.
+func (r *Repository) GetRegistrationAttempts(ctx context.Context, ip string) (int64, error) {
+	var count int64
+	err := r.db.GetContext(ctx, &count,
+		`SELECT COALESCE(SUM(count), 0) FROM registration_attempts WHERE ip = ? AND day = ?`,
+		ip, r.registrationDay())
+	return count, err
+}
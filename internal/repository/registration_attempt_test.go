@@ -0,0 +1,63 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/oliverandrich/go-webapp-template/internal/clock"
+	"github.com/oliverandrich/go-webapp-template/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIncrementRegistrationAttempts(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	ctx := context.Background()
+	repo.SetClock(clock.NewFake(time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)))
+
+	for want := int64(1); want <= 3; want++ {
+		count, err := repo.IncrementRegistrationAttempts(ctx, "192.0.2.1")
+		require.NoError(t, err)
+		assert.Equal(t, want, count)
+	}
+
+	// Other IPs are counted separately
+	count, err := repo.IncrementRegistrationAttempts(ctx, "192.0.2.2")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	count, err = repo.GetRegistrationAttempts(ctx, "192.0.2.1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+}
+
+func TestIncrementRegistrationAttempts_ResetsNextDay(t *testing.T) {
+	db, repo := testutil.NewTestDB(t)
+	ctx := context.Background()
+	fake := clock.NewFake(time.Date(2025, 6, 1, 23, 59, 0, 0, time.UTC))
+	repo.SetClock(fake)
+
+	for range 3 {
+		_, err := repo.IncrementRegistrationAttempts(ctx, "192.0.2.1")
+		require.NoError(t, err)
+	}
+
+	fake.Advance(2 * time.Minute)
+
+	count, err := repo.GetRegistrationAttempts(ctx, "192.0.2.1")
+	require.NoError(t, err)
+	assert.Zero(t, count)
+
+	count, err = repo.IncrementRegistrationAttempts(ctx, "192.0.2.1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	// The previous day's rows are gone
+	var rows int
+	require.NoError(t, db.Get(&rows, `SELECT COUNT(*) FROM registration_attempts`))
+	assert.Equal(t, 1, rows)
+}
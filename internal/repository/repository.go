@@ -5,15 +5,110 @@
 package repository
 
 import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/oliverandrich/go-webapp-template/internal/clock"
+	"github.com/oliverandrich/go-webapp-template/internal/tracing"
 	"github.com/vinovest/sqlx"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// dbtx is the query interface shared by *sqlx.DB and *sqlx.Tx.
+type dbtx interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	GetContext(ctx context.Context, dest any, query string, args ...any) error
+	SelectContext(ctx context.Context, dest any, query string, args ...any) error
+}
+
 // Repository provides data access methods.
 type Repository struct {
-	db *sqlx.DB
+	db        dbtx
+	conn      *sqlx.DB // nil when the repository is bound to a transaction
+	clock     clock.Clock
+	slowQuery time.Duration // 0 = query logging off
+	tracer    trace.Tracer  // nil = query tracing off
 }
 
 // New creates a new Repository.
 func New(db *sqlx.DB) *Repository {
-	return &Repository{db: db}
+	return &Repository{db: db, conn: db, clock: clock.Real{}}
+}
+
+// SetClock replaces the time source used for expiry and time windows (for tests).
+func (r *Repository) SetClock(c clock.Clock) {
+	r.clock = c
+}
+
+// LogQueries routes all queries through slog: queries taking at least slow
+// are logged at warn level, the others at debug level. Zero turns logging
+// off, which is the default.
+func (r *Repository) LogQueries(slow time.Duration) {
+	r.slowQuery = slow
+	if r.conn != nil {
+		r.db = r.bind(r.conn)
+	}
+}
+
+// TraceQueries records a span for every query made while a span is active
+// in the query's context. A nil tp turns tracing off, which is the default.
+func (r *Repository) TraceQueries(tp trace.TracerProvider) {
+	r.tracer = nil
+	if tp != nil {
+		r.tracer = tp.Tracer(tracing.TracerName)
+	}
+	if r.conn != nil {
+		r.db = r.bind(r.conn)
+	}
+}
+
+// bind returns db, wrapped in a query tracer and logger when they are on.
+func (r *Repository) bind(db dbtx) dbtx {
+	if r.tracer != nil {
+		db = &queryTracer{db: db, tracer: r.tracer}
+	}
+	if r.slowQuery > 0 {
+		db = &queryLogger{db: db, slow: r.slowQuery}
+	}
+	return db
+}
+
+// Ping checks that the database answers queries.
+func (r *Repository) Ping(ctx context.Context) error {
+	var one int
+	return r.db.GetContext(ctx, &one, `SELECT 1`)
+}
+
+// WithTx runs fn with a repository bound to a single transaction.
+// The transaction is committed if fn returns nil and rolled back otherwise,
+// including when fn panics.
+// Calling WithTx on a repository that is already inside a transaction runs fn
+// in that same transaction.
+func (r *Repository) WithTx(ctx context.Context, fn func(*Repository) error) error {
+	if r.conn == nil {
+		return fn(r)
+	}
+
+	tx, err := r.conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	// Don't leave the transaction open when fn panics
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if fnErr := fn(&Repository{db: r.bind(tx), clock: r.clock, slowQuery: r.slowQuery, tracer: r.tracer}); fnErr != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return fmt.Errorf("%w (rollback failed: %v)", fnErr, rbErr)
+		}
+		return fnErr
+	}
+
+	return tx.Commit()
 }
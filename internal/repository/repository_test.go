@@ -4,10 +4,14 @@
 package repository_test
 
 import (
+	"context"
+	"errors"
 	"testing"
 
+	"github.com/oliverandrich/go-webapp-template/internal/repository"
 	"github.com/oliverandrich/go-webapp-template/internal/testutil"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNew(t *testing.T) {
@@ -15,3 +19,90 @@ func TestNew(t *testing.T) {
 
 	assert.NotNil(t, repo)
 }
+
+func TestWithTx_Commit(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	ctx := context.Background()
+	user := testutil.NewTestUser(t, repo, "txuser")
+	require.NoError(t, repo.CreateRecoveryCodes(ctx, user.ID, []string{"old1", "old2"}))
+
+	err := repo.WithTx(ctx, func(tx *repository.Repository) error {
+		if err := tx.DeleteRecoveryCodes(ctx, user.ID); err != nil {
+			return err
+		}
+		return tx.CreateRecoveryCodes(ctx, user.ID, []string{"new1", "new2", "new3"})
+	})
+
+	require.NoError(t, err)
+	codes, err := repo.GetUnusedRecoveryCodes(ctx, user.ID)
+	require.NoError(t, err)
+	require.Len(t, codes, 3)
+	assert.Equal(t, "new1", codes[0].CodeHash)
+}
+
+func TestWithTx_RollbackOnError(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	ctx := context.Background()
+	user := testutil.NewTestUser(t, repo, "txuser")
+	require.NoError(t, repo.CreateRecoveryCodes(ctx, user.ID, []string{"old1", "old2"}))
+
+	errBoom := errors.New("boom")
+	err := repo.WithTx(ctx, func(tx *repository.Repository) error {
+		if err := tx.DeleteRecoveryCodes(ctx, user.ID); err != nil {
+			return err
+		}
+		if err := tx.CreateRecoveryCodes(ctx, user.ID, []string{"new1"}); err != nil {
+			return err
+		}
+		return errBoom
+	})
+
+	require.ErrorIs(t, err, errBoom)
+	codes, err := repo.GetUnusedRecoveryCodes(ctx, user.ID)
+	require.NoError(t, err)
+	require.Len(t, codes, 2)
+	assert.Equal(t, "old1", codes[0].CodeHash)
+	assert.Equal(t, "old2", codes[1].CodeHash)
+}
+
+func TestWithTx_RollbackOnPanic(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	ctx := context.Background()
+
+	assert.PanicsWithValue(t, "boom", func() {
+		_ = repo.WithTx(ctx, func(tx *repository.Repository) error {
+			if _, err := tx.CreateUser(ctx, "panicky"); err != nil {
+				return err
+			}
+			panic("boom")
+		})
+	})
+
+	// The rollback released the write lock and discarded the user
+	exists, err := repo.UserExists(ctx, "panicky")
+	require.NoError(t, err)
+	assert.False(t, exists)
+	_, err = repo.CreateUser(ctx, "after-panic")
+	require.NoError(t, err)
+}
+
+func TestWithTx_Nested(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	ctx := context.Background()
+
+	errBoom := errors.New("boom")
+	err := repo.WithTx(ctx, func(tx *repository.Repository) error {
+		if _, err := tx.CreateUser(ctx, "nested"); err != nil {
+			return err
+		}
+		// Nested calls share the outer transaction
+		return tx.WithTx(ctx, func(_ *repository.Repository) error {
+			return errBoom
+		})
+	})
+
+	require.ErrorIs(t, err, errBoom)
+	exists, err := repo.UserExists(ctx, "nested")
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
@@ -0,0 +1,73 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/oliverandrich/go-webapp-template/internal/models"
+)
+
+// CreateSession stores a new server-side session.
+func (r *Repository) CreateSession(ctx context.Context, s *models.Session) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO sessions (id, user_id, impersonator_id, data, expires_at, created_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		s.ID, s.UserID, s.ImpersonatorID, s.Data, s.ExpiresAt, r.clock.Now())
+	return err
+}
+
+// UpdateSession replaces the data and expiry of an existing session. It
+// returns sql.ErrNoRows if the session was deleted in the meantime, so a
+// revoked session is never stored again.
+func (r *Repository) UpdateSession(ctx context.Context, s *models.Session) error {
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE sessions SET data = ?, expires_at = ? WHERE id = ?`,
+		s.Data, s.ExpiresAt, s.ID)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetSession returns the unexpired session with the given ID. Unknown and
+// expired sessions yield sql.ErrNoRows.
+func (r *Repository) GetSession(ctx context.Context, id string) (*models.Session, error) {
+	var s models.Session
+	err := r.db.GetContext(ctx, &s, `SELECT * FROM sessions WHERE id = ? AND expires_at > ?`, id, r.clock.Now())
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// DeleteSession deletes the session with the given ID, if any.
+func (r *Repository) DeleteSession(ctx context.Context, id string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM sessions WHERE id = ?`, id)
+	return err
+}
+
+// DeleteUserSessions deletes all sessions of a user, including those in which
+// they impersonate another user.
+func (r *Repository) DeleteUserSessions(ctx context.Context, userID int64) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM sessions WHERE user_id = ? OR impersonator_id = ?`, userID, userID)
+	return err
+}
+
+// DeleteExpiredSessions deletes expired sessions and returns how many were
+// removed.
+func (r *Repository) DeleteExpiredSessions(ctx context.Context) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM sessions WHERE expires_at < ?`, r.clock.Now())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
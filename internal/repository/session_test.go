@@ -0,0 +1,134 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/oliverandrich/go-webapp-template/internal/clock"
+	"github.com/oliverandrich/go-webapp-template/internal/models"
+	"github.com/oliverandrich/go-webapp-template/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateAndUpdateSession(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	ctx := context.Background()
+	user := testutil.NewTestUser(t, repo, "testuser")
+	expiresAt := time.Now().Add(time.Hour)
+
+	require.NoError(t, repo.CreateSession(ctx, &models.Session{ID: "abc", UserID: user.ID, Data: `{"a":1}`, ExpiresAt: expiresAt}))
+	require.NoError(t, repo.UpdateSession(ctx, &models.Session{ID: "abc", UserID: user.ID, Data: `{"a":2}`, ExpiresAt: expiresAt.Add(time.Hour)}))
+
+	s, err := repo.GetSession(ctx, "abc")
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, s.UserID)
+	assert.JSONEq(t, `{"a":2}`, s.Data)
+	assert.WithinDuration(t, expiresAt.Add(time.Hour), s.ExpiresAt, time.Second)
+}
+
+func TestUpdateSession_DeletedSession(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	ctx := context.Background()
+	user := testutil.NewTestUser(t, repo, "testuser")
+	expiresAt := time.Now().Add(time.Hour)
+	require.NoError(t, repo.CreateSession(ctx, &models.Session{ID: "abc", UserID: user.ID, Data: "{}", ExpiresAt: expiresAt}))
+	require.NoError(t, repo.DeleteUserSessions(ctx, user.ID))
+
+	err := repo.UpdateSession(ctx, &models.Session{ID: "abc", UserID: user.ID, Data: "{}", ExpiresAt: expiresAt})
+
+	require.ErrorIs(t, err, sql.ErrNoRows)
+	_, err = repo.GetSession(ctx, "abc")
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestGetSession_Expired(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	ctx := context.Background()
+	fake := clock.NewFake(time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC))
+	repo.SetClock(fake)
+	user := testutil.NewTestUser(t, repo, "testuser")
+	require.NoError(t, repo.CreateSession(ctx, &models.Session{ID: "abc", UserID: user.ID, Data: "{}", ExpiresAt: fake.Now().Add(time.Hour)}))
+
+	fake.Advance(2 * time.Hour)
+
+	_, err := repo.GetSession(ctx, "abc")
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestDeleteExpiredSessions(t *testing.T) {
+	db, repo := testutil.NewTestDB(t)
+	ctx := context.Background()
+	fake := clock.NewFake(time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC))
+	repo.SetClock(fake)
+	user := testutil.NewTestUser(t, repo, "testuser")
+	require.NoError(t, repo.CreateSession(ctx, &models.Session{ID: "old", UserID: user.ID, Data: "{}", ExpiresAt: fake.Now().Add(time.Hour)}))
+
+	fake.Advance(2 * time.Hour)
+	require.NoError(t, repo.CreateSession(ctx, &models.Session{ID: "new", UserID: user.ID, Data: "{}", ExpiresAt: fake.Now().Add(time.Hour)}))
+
+	n, err := repo.DeleteExpiredSessions(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	var count int
+	require.NoError(t, db.Get(&count, `SELECT COUNT(*) FROM sessions`))
+	assert.Equal(t, 1, count)
+}
+
+func TestDeleteSession(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	ctx := context.Background()
+	user := testutil.NewTestUser(t, repo, "testuser")
+	expiresAt := time.Now().Add(time.Hour)
+	require.NoError(t, repo.CreateSession(ctx, &models.Session{ID: "a", UserID: user.ID, Data: "{}", ExpiresAt: expiresAt}))
+	require.NoError(t, repo.CreateSession(ctx, &models.Session{ID: "b", UserID: user.ID, Data: "{}", ExpiresAt: expiresAt}))
+
+	require.NoError(t, repo.DeleteSession(ctx, "a"))
+
+	_, err := repo.GetSession(ctx, "a")
+	require.ErrorIs(t, err, sql.ErrNoRows)
+	_, err = repo.GetSession(ctx, "b")
+	assert.NoError(t, err)
+}
+
+func TestDeleteUserSessions(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	ctx := context.Background()
+	user := testutil.NewTestUser(t, repo, "testuser")
+	other := testutil.NewTestUser(t, repo, "other")
+	expiresAt := time.Now().Add(time.Hour)
+	require.NoError(t, repo.CreateSession(ctx, &models.Session{ID: "a", UserID: user.ID, Data: "{}", ExpiresAt: expiresAt}))
+	require.NoError(t, repo.CreateSession(ctx, &models.Session{ID: "b", UserID: user.ID, Data: "{}", ExpiresAt: expiresAt}))
+	require.NoError(t, repo.CreateSession(ctx, &models.Session{ID: "c", UserID: other.ID, Data: "{}", ExpiresAt: expiresAt}))
+
+	require.NoError(t, repo.DeleteUserSessions(ctx, user.ID))
+
+	_, err := repo.GetSession(ctx, "a")
+	require.ErrorIs(t, err, sql.ErrNoRows)
+	_, err = repo.GetSession(ctx, "b")
+	require.ErrorIs(t, err, sql.ErrNoRows)
+	_, err = repo.GetSession(ctx, "c")
+	assert.NoError(t, err)
+}
+
+func TestDeleteUserSessions_Impersonation(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	ctx := context.Background()
+	admin := testutil.NewTestUser(t, repo, "admin")
+	target := testutil.NewTestUser(t, repo, "customer")
+	expiresAt := time.Now().Add(time.Hour)
+	require.NoError(t, repo.CreateSession(ctx, &models.Session{ID: "a", UserID: target.ID, ImpersonatorID: &admin.ID, Data: "{}", ExpiresAt: expiresAt}))
+	require.NoError(t, repo.CreateSession(ctx, &models.Session{ID: "b", UserID: target.ID, Data: "{}", ExpiresAt: expiresAt}))
+
+	require.NoError(t, repo.DeleteUserSessions(ctx, admin.ID))
+
+	_, err := repo.GetSession(ctx, "a")
+	require.ErrorIs(t, err, sql.ErrNoRows)
+	_, err = repo.GetSession(ctx, "b")
+	assert.NoError(t, err)
+}
@@ -0,0 +1,25 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package repository
+
+import (
+	"context"
+)
+
+// GetSetting returns the value stored under key.
+// Returns sql.ErrNoRows if the setting has never been set.
+func (r *Repository) GetSetting(ctx context.Context, key string) (string, error) {
+	var value string
+	err := r.db.GetContext(ctx, &value, `SELECT value FROM settings WHERE key = ?`, key)
+	return value, err
+}
+
+// SetSetting stores value under key, replacing any previous value.
+func (r *Repository) SetSetting(ctx context.Context, key, value string) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO settings (key, value) VALUES (?, ?)
+		ON CONFLICT(key) DO UPDATE SET value = excluded.value, updated_at = CURRENT_TIMESTAMP`,
+		key, value)
+	return err
+}
@@ -0,0 +1,38 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/oliverandrich/go-webapp-template/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetSetting_NotFound(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+
+	_, err := repo.GetSetting(context.Background(), "missing")
+
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestSetSetting(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	ctx := context.Background()
+
+	require.NoError(t, repo.SetSetting(ctx, "registration", "closed"))
+	value, err := repo.GetSetting(ctx, "registration")
+	require.NoError(t, err)
+	assert.Equal(t, "closed", value)
+
+	// Setting it again replaces the value
+	require.NoError(t, repo.SetSetting(ctx, "registration", "open"))
+	value, err = repo.GetSetting(ctx, "registration")
+	require.NoError(t, err)
+	assert.Equal(t, "open", value)
+}
@@ -6,16 +6,30 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"encoding/binary"
 	"errors"
+	"fmt"
+	"math"
+	"strings"
 
 	"github.com/oliverandrich/go-webapp-template/internal/models"
+	"github.com/oliverandrich/go-webapp-template/internal/pagination"
 )
 
 // CreateUser creates a new user with only a username.
 func (r *Repository) CreateUser(ctx context.Context, username string) (*models.User, error) {
+	return r.CreateUserWithDisplayName(ctx, username, "")
+}
+
+// CreateUserWithDisplayName creates a new user with a display name. An empty
+// display name defaults to the username.
+func (r *Repository) CreateUserWithDisplayName(ctx context.Context, username, displayName string) (*models.User, error) {
+	if displayName == "" {
+		displayName = username
+	}
 	result, err := r.db.ExecContext(ctx,
-		`INSERT INTO users (username) VALUES (?)`,
-		username)
+		`INSERT INTO users (username, display_name) VALUES (?, ?)`,
+		username, displayName)
 	if err != nil {
 		return nil, err
 	}
@@ -28,9 +42,18 @@ func (r *Repository) CreateUser(ctx context.Context, username string) (*models.U
 
 // CreateUserWithEmail creates a new user with email.
 func (r *Repository) CreateUserWithEmail(ctx context.Context, email string) (*models.User, error) {
+	return r.CreateUserWithEmailAndDisplayName(ctx, email, "")
+}
+
+// CreateUserWithEmailAndDisplayName creates a new user with email and a
+// display name. An empty display name defaults to the email.
+func (r *Repository) CreateUserWithEmailAndDisplayName(ctx context.Context, email, displayName string) (*models.User, error) {
+	if displayName == "" {
+		displayName = email
+	}
 	result, err := r.db.ExecContext(ctx,
-		`INSERT INTO users (username, email) VALUES (?, ?)`,
-		email, email)
+		`INSERT INTO users (username, display_name, email) VALUES (?, ?, ?)`,
+		email, displayName, email)
 	if err != nil {
 		return nil, err
 	}
@@ -41,20 +64,61 @@ func (r *Repository) CreateUserWithEmail(ctx context.Context, email string) (*mo
 	return r.GetUserByID(ctx, id)
 }
 
-// GetUserByID retrieves a user by ID.
+// ReserveUserID consumes the next user ID without creating a user. IDs are
+// never reused, so the returned ID is the one a sign-up at this moment would
+// have received and never belongs to an account.
+func (r *Repository) ReserveUserID(ctx context.Context) (int64, error) {
+	var id int64
+	err := r.WithTx(ctx, func(tx *Repository) error {
+		result, err := tx.db.ExecContext(ctx,
+			`INSERT INTO users (username, display_name) VALUES ('reserved-' || hex(randomblob(16)), '')`)
+		if err != nil {
+			return err
+		}
+		if id, err = result.LastInsertId(); err != nil {
+			return err
+		}
+		_, err = tx.db.ExecContext(ctx, `DELETE FROM users WHERE id = ?`, id)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// GetUserByID retrieves a user by ID. Soft-deleted users are not found.
 func (r *Repository) GetUserByID(ctx context.Context, id int64) (*models.User, error) {
 	var user models.User
-	err := r.db.GetContext(ctx, &user, `SELECT * FROM users WHERE id = ?`, id)
+	err := r.db.GetContext(ctx, &user, `SELECT * FROM users WHERE id = ? AND deleted_at IS NULL`, id)
 	if err != nil {
 		return nil, err
 	}
 	return &user, nil
 }
 
+// ErrInvalidUserHandle is returned by GetUserByWebAuthnID when the handle was
+// not produced by models.User.WebAuthnID.
+var ErrInvalidUserHandle = errors.New("invalid webauthn user handle")
+
+// GetUserByWebAuthnID retrieves a user by the WebAuthn user handle returned by
+// an authenticator during discoverable login. The handle is the 8-byte
+// big-endian user ID written by models.User.WebAuthnID.
+func (r *Repository) GetUserByWebAuthnID(ctx context.Context, handle []byte) (*models.User, error) {
+	if len(handle) != 8 {
+		return nil, ErrInvalidUserHandle
+	}
+	id := binary.BigEndian.Uint64(handle)
+	if id == 0 || id > math.MaxInt64 {
+		return nil, ErrInvalidUserHandle
+	}
+	return r.GetUserByID(ctx, int64(id))
+}
+
 // GetUserByUsername retrieves a user by username.
 func (r *Repository) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
 	var user models.User
-	err := r.db.GetContext(ctx, &user, `SELECT * FROM users WHERE username = ?`, username)
+	err := r.db.GetContext(ctx, &user, `SELECT * FROM users WHERE username = ? AND deleted_at IS NULL`, username)
 	if err != nil {
 		return nil, err
 	}
@@ -64,7 +128,7 @@ func (r *Repository) GetUserByUsername(ctx context.Context, username string) (*m
 // GetUserByEmail retrieves a user by email.
 func (r *Repository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
 	var user models.User
-	err := r.db.GetContext(ctx, &user, `SELECT * FROM users WHERE email = ?`, email)
+	err := r.db.GetContext(ctx, &user, `SELECT * FROM users WHERE email = ? AND deleted_at IS NULL`, email)
 	if err != nil {
 		return nil, err
 	}
@@ -74,14 +138,107 @@ func (r *Repository) GetUserByEmail(ctx context.Context, email string) (*models.
 // UserExists checks if a user with the given username exists.
 func (r *Repository) UserExists(ctx context.Context, username string) (bool, error) {
 	var exists bool
-	err := r.db.GetContext(ctx, &exists, `SELECT EXISTS(SELECT 1 FROM users WHERE username = ?)`, username)
+	err := r.db.GetContext(ctx, &exists, `SELECT EXISTS(SELECT 1 FROM users WHERE username = ? AND deleted_at IS NULL)`, username)
 	return exists, err
 }
 
+// TakenUsernames returns which of names belong to existing users, in a
+// single query.
+func (r *Repository) TakenUsernames(ctx context.Context, names []string) (map[string]bool, error) {
+	taken := make(map[string]bool)
+	if len(names) == 0 {
+		return taken, nil
+	}
+
+	args := make([]any, len(names))
+	for i, name := range names {
+		args[i] = name
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(names)), ",")
+
+	var found []string
+	err := r.db.SelectContext(ctx, &found,
+		`SELECT username FROM users WHERE username IN (`+placeholders+`) AND deleted_at IS NULL`, args...)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range found {
+		taken[name] = true
+	}
+	return taken, nil
+}
+
+// Bounds for the number of results returned by SearchUsers.
+const (
+	DefaultUserSearchLimit = 20
+	MaxUserSearchLimit     = 100
+)
+
+// likeEscaper escapes the LIKE wildcards in user input (with \ as escape).
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// SearchUsers returns users whose username or email starts with query,
+// ignoring case. Exact matches come first, then username matches, then email
+// matches, newest first within each group. An empty query returns the newest
+// users. limit is clamped to MaxUserSearchLimit; zero or less selects
+// DefaultUserSearchLimit.
+func (r *Repository) SearchUsers(ctx context.Context, query string, limit int) ([]models.User, error) {
+	if limit <= 0 {
+		limit = DefaultUserSearchLimit
+	}
+	limit = min(limit, MaxUserSearchLimit)
+
+	query = strings.TrimSpace(query)
+	pattern := likeEscaper.Replace(query) + "%"
+
+	var users []models.User
+	err := r.db.SelectContext(ctx, &users,
+		`SELECT * FROM users
+		WHERE (username LIKE ?1 ESCAPE '\' OR email LIKE ?1 ESCAPE '\') AND deleted_at IS NULL
+		ORDER BY CASE
+			WHEN lower(username) = lower(?2) OR lower(email) = lower(?2) THEN 0
+			WHEN username LIKE ?1 ESCAPE '\' THEN 1
+			ELSE 2
+		END, created_at DESC, id DESC
+		LIMIT ?3`,
+		pattern, query, limit)
+	return users, err
+}
+
+// ListUsersAfter returns a page of users, newest first, following after, or
+// the first page when after is nil. next is the cursor for the following
+// page and nil on the last one. limit is clamped by pagination.Limit.
+func (r *Repository) ListUsersAfter(ctx context.Context, after *pagination.Cursor, limit int) (users []models.User, next *pagination.Cursor, err error) {
+	limit = pagination.Limit(limit)
+
+	where := "deleted_at IS NULL"
+	var args []any
+	if after != nil {
+		cond, condArgs := after.Where("created_at", "id")
+		where += " AND " + cond
+		args = condArgs
+	}
+	// One extra row tells whether another page follows
+	args = append(args, limit+1)
+
+	err = r.db.SelectContext(ctx, &users,
+		`SELECT * FROM users WHERE `+where+` ORDER BY created_at DESC, id DESC LIMIT ?`,
+		args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(users) > limit {
+		users = users[:limit]
+		last := users[limit-1]
+		next = &pagination.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}
+	}
+	return users, next, nil
+}
+
 // EmailExists checks if a user with the given email exists.
 func (r *Repository) EmailExists(ctx context.Context, email string) (bool, error) {
 	var exists bool
-	err := r.db.GetContext(ctx, &exists, `SELECT EXISTS(SELECT 1 FROM users WHERE email = ?)`, email)
+	err := r.db.GetContext(ctx, &exists, `SELECT EXISTS(SELECT 1 FROM users WHERE email = ? AND deleted_at IS NULL)`, email)
 	if errors.Is(err, sql.ErrNoRows) {
 		return false, nil
 	}
@@ -95,3 +252,174 @@ func (r *Repository) MarkEmailVerified(ctx context.Context, userID int64) error
 		userID)
 	return err
 }
+
+// SetAdmin grants or revokes administrator rights for a user.
+func (r *Repository) SetAdmin(ctx context.Context, userID int64, admin bool) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE users SET is_admin = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`,
+		admin, userID)
+	return err
+}
+
+// SetUserLanguage stores the user's preferred language. An empty lang clears
+// the preference so the Accept-Language header applies again.
+func (r *Repository) SetUserLanguage(ctx context.Context, userID int64, lang string) error {
+	var value *string
+	if lang != "" {
+		value = &lang
+	}
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE users SET preferred_language = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`,
+		value, userID)
+	return err
+}
+
+// SetDisplayName changes the user's display name. An empty name resets it
+// to the username.
+func (r *Repository) SetDisplayName(ctx context.Context, userID int64, displayName string) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE users SET display_name = COALESCE(NULLIF(?, ''), username), updated_at = CURRENT_TIMESTAMP WHERE id = ?`,
+		displayName, userID)
+	return err
+}
+
+// BumpSessionVersion increments the user's session version, invalidating all
+// session cookies issued before, and returns the new version.
+func (r *Repository) BumpSessionVersion(ctx context.Context, userID int64) (int, error) {
+	var version int
+	err := r.db.GetContext(ctx, &version,
+		`UPDATE users SET session_version = session_version + 1, updated_at = CURRENT_TIMESTAMP WHERE id = ? RETURNING session_version`,
+		userID)
+	return version, err
+}
+
+// RecordLogin stores the time of a successful sign-in and keeps the one
+// before it as previous_login_at.
+func (r *Repository) RecordLogin(ctx context.Context, userID int64) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE users SET previous_login_at = last_login_at, last_login_at = ? WHERE id = ?`,
+		r.clock.Now(), userID)
+	return err
+}
+
+// GetUserStats returns the credential and unused recovery code counts, the
+// last two sign-ins and the account age of a user in a single query.
+func (r *Repository) GetUserStats(ctx context.Context, userID int64) (models.UserStats, error) {
+	var stats models.UserStats
+	err := r.db.GetContext(ctx, &stats, `
+		SELECT
+			(SELECT COUNT(*) FROM credentials WHERE user_id = u.id) AS credential_count,
+			(SELECT COUNT(*) FROM recovery_codes WHERE user_id = u.id AND used = 0) AS recovery_codes_left,
+			u.last_login_at,
+			u.previous_login_at,
+			u.created_at
+		FROM users u WHERE u.id = ?`,
+		userID)
+	if err != nil {
+		return models.UserStats{}, err
+	}
+	stats.AccountAge = max(r.clock.Now().Sub(stats.CreatedAt), 0)
+	return stats, nil
+}
+
+// ErrIdentifierTaken is returned by RestoreUser when the username or email of
+// the deleted user has been registered by someone else in the meantime.
+var ErrIdentifierTaken = errors.New("username or email is taken by another user")
+
+// deletedSuffix returns the suffix appended to the username and email of a
+// soft-deleted user, keeping them unique while freeing the originals.
+func deletedSuffix(id int64) string {
+	return fmt.Sprintf(":deleted:%d", id)
+}
+
+// SoftDeleteUser marks a user as deleted. The row, credentials and history are
+// kept, but the user is no longer found by the lookups and can't sign in; the
+// username and email become available for new registrations. Returns
+// sql.ErrNoRows if no active user has that ID.
+func (r *Repository) SoftDeleteUser(ctx context.Context, userID int64) error {
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE users SET
+			deleted_at = ?,
+			username = username || ?2,
+			email = email || ?2,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?3 AND deleted_at IS NULL`,
+		r.clock.Now(), deletedSuffix(userID), userID)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// ListDeletedUsers returns the soft-deleted users with their original username
+// and email, most recently deleted first.
+func (r *Repository) ListDeletedUsers(ctx context.Context) ([]models.User, error) {
+	var users []models.User
+	err := r.db.SelectContext(ctx, &users,
+		`SELECT * FROM users WHERE deleted_at IS NOT NULL ORDER BY deleted_at DESC, id DESC`)
+	if err != nil {
+		return nil, err
+	}
+	for i := range users {
+		stripDeletedSuffix(&users[i])
+	}
+	return users, nil
+}
+
+// RestoreUser undoes SoftDeleteUser. Returns sql.ErrNoRows if no deleted user
+// has that ID and ErrIdentifierTaken if the original username or email is in
+// use again. The check and the update share a transaction, so a sign-up in
+// between cannot claim the identifiers.
+func (r *Repository) RestoreUser(ctx context.Context, userID int64) (*models.User, error) {
+	var restored *models.User
+	err := r.WithTx(ctx, func(tx *Repository) error {
+		var user models.User
+		err := tx.db.GetContext(ctx, &user,
+			`SELECT * FROM users WHERE id = ? AND deleted_at IS NOT NULL`, userID)
+		if err != nil {
+			return err
+		}
+		stripDeletedSuffix(&user)
+
+		var taken bool
+		err = tx.db.GetContext(ctx, &taken,
+			`SELECT EXISTS(SELECT 1 FROM users WHERE username = ?1 OR (?2 IS NOT NULL AND email = ?2))`,
+			user.Username, user.Email)
+		if err != nil {
+			return err
+		}
+		if taken {
+			return ErrIdentifierTaken
+		}
+
+		_, err = tx.db.ExecContext(ctx,
+			`UPDATE users SET deleted_at = NULL, username = ?, email = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`,
+			user.Username, user.Email, userID)
+		if err != nil {
+			return err
+		}
+		restored, err = tx.GetUserByID(ctx, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return restored, nil
+}
+
+// stripDeletedSuffix restores the original identifiers of a soft-deleted user.
+func stripDeletedSuffix(user *models.User) {
+	suffix := deletedSuffix(user.ID)
+	user.Username = strings.TrimSuffix(user.Username, suffix)
+	if user.Email != nil {
+		email := strings.TrimSuffix(*user.Email, suffix)
+		user.Email = &email
+	}
+}
@@ -6,8 +6,14 @@ package repository_test
 import (
 	"context"
 	"database/sql"
+	"strconv"
 	"testing"
+	"time"
 
+	"github.com/oliverandrich/go-webapp-template/internal/clock"
+	"github.com/oliverandrich/go-webapp-template/internal/models"
+	"github.com/oliverandrich/go-webapp-template/internal/pagination"
+	"github.com/oliverandrich/go-webapp-template/internal/repository"
 	"github.com/oliverandrich/go-webapp-template/internal/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -22,9 +28,71 @@ func TestCreateUser(t *testing.T) {
 	require.NoError(t, err)
 	assert.NotZero(t, user.ID)
 	assert.Equal(t, "testuser", user.Username)
+	assert.Equal(t, "testuser", user.DisplayName)
 	assert.NotZero(t, user.CreatedAt)
 }
 
+func TestCreateUserWithDisplayName(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+
+	user, err := repo.CreateUserWithDisplayName(context.Background(), "alice", "Alice Smith")
+
+	require.NoError(t, err)
+	assert.Equal(t, "alice", user.Username)
+	assert.Equal(t, "Alice Smith", user.DisplayName)
+}
+
+func TestCreateUserWithEmailAndDisplayName(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	ctx := context.Background()
+
+	user, err := repo.CreateUserWithEmailAndDisplayName(ctx, "alice@example.com", "Alice Smith")
+
+	require.NoError(t, err)
+	assert.Equal(t, "alice@example.com", user.Username)
+	require.NotNil(t, user.Email)
+	assert.Equal(t, "alice@example.com", *user.Email)
+	assert.Equal(t, "Alice Smith", user.DisplayName)
+
+	user, err = repo.CreateUserWithEmailAndDisplayName(ctx, "bob@example.com", "")
+	require.NoError(t, err)
+	assert.Equal(t, "bob@example.com", user.DisplayName)
+}
+
+func TestReserveUserID(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	ctx := context.Background()
+	first := testutil.NewTestUser(t, repo, "alice")
+
+	id, err := repo.ReserveUserID(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, first.ID+1, id)
+
+	_, err = repo.GetUserByID(ctx, id)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	// The reserved ID is skipped by the next sign-up
+	next := testutil.NewTestUser(t, repo, "bob")
+	assert.Equal(t, id+1, next.ID)
+}
+
+func TestSetDisplayName(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	ctx := context.Background()
+	user := testutil.NewTestUser(t, repo, "alice")
+
+	require.NoError(t, repo.SetDisplayName(ctx, user.ID, "Alice Smith"))
+	updated, err := repo.GetUserByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Alice Smith", updated.DisplayName)
+
+	// An empty name falls back to the username
+	require.NoError(t, repo.SetDisplayName(ctx, user.ID, ""))
+	updated, err = repo.GetUserByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", updated.DisplayName)
+}
+
 func TestCreateUser_DuplicateUsername(t *testing.T) {
 	_, repo := testutil.NewTestDB(t)
 	ctx := context.Background()
@@ -74,6 +142,48 @@ func TestGetUserByID_WithCredentials(t *testing.T) {
 	assert.Len(t, creds, 2)
 }
 
+func TestGetUserByWebAuthnID(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	ctx := context.Background()
+
+	testutil.NewTestUser(t, repo, "other")
+	user := testutil.NewTestUser(t, repo, "testuser")
+
+	retrieved, err := repo.GetUserByWebAuthnID(ctx, user.WebAuthnID())
+
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, retrieved.ID)
+	assert.Equal(t, "testuser", retrieved.Username)
+}
+
+func TestGetUserByWebAuthnID_NotFound(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+
+	_, err := repo.GetUserByWebAuthnID(context.Background(), (&models.User{ID: 999}).WebAuthnID())
+
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestGetUserByWebAuthnID_Malformed(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	user := testutil.NewTestUser(t, repo, "testuser")
+
+	tests := map[string][]byte{
+		"nil":       nil,
+		"empty":     {},
+		"too short": user.WebAuthnID()[:7],
+		"too long":  append(user.WebAuthnID(), 0),
+		"zero id":   make([]byte, 8),
+		"negative":  {0x80, 0, 0, 0, 0, 0, 0, 1},
+	}
+	for name, handle := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := repo.GetUserByWebAuthnID(context.Background(), handle)
+			assert.ErrorIs(t, err, repository.ErrInvalidUserHandle)
+		})
+	}
+}
+
 func TestGetUserByUsername(t *testing.T) {
 	_, repo := testutil.NewTestDB(t)
 	ctx := context.Background()
@@ -118,3 +228,447 @@ func TestUserExists_NotFound(t *testing.T) {
 	require.NoError(t, err)
 	assert.False(t, exists)
 }
+
+func TestSetAdmin(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	ctx := context.Background()
+	user := testutil.NewTestUser(t, repo, "testuser")
+	assert.False(t, user.IsAdmin)
+
+	require.NoError(t, repo.SetAdmin(ctx, user.ID, true))
+	found, err := repo.GetUserByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.True(t, found.IsAdmin)
+
+	require.NoError(t, repo.SetAdmin(ctx, user.ID, false))
+	found, err = repo.GetUserByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.False(t, found.IsAdmin)
+}
+
+func TestSetUserLanguage(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	ctx := context.Background()
+	user := testutil.NewTestUser(t, repo, "testuser")
+	assert.Nil(t, user.PreferredLanguage)
+
+	require.NoError(t, repo.SetUserLanguage(ctx, user.ID, "de"))
+
+	updated, err := repo.GetUserByID(ctx, user.ID)
+	require.NoError(t, err)
+	require.NotNil(t, updated.PreferredLanguage)
+	assert.Equal(t, "de", *updated.PreferredLanguage)
+
+	require.NoError(t, repo.SetUserLanguage(ctx, user.ID, ""))
+
+	cleared, err := repo.GetUserByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Nil(t, cleared.PreferredLanguage)
+}
+
+func TestBumpSessionVersion(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	ctx := context.Background()
+	user := testutil.NewTestUser(t, repo, "testuser")
+	assert.Zero(t, user.SessionVersion)
+
+	version, err := repo.BumpSessionVersion(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, version)
+
+	version, err = repo.BumpSessionVersion(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, version)
+
+	updated, err := repo.GetUserByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, updated.SessionVersion)
+}
+
+func TestTakenUsernames(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	ctx := context.Background()
+	testutil.NewTestUser(t, repo, "alice")
+	testutil.NewTestUser(t, repo, "alice_")
+	deleted := testutil.NewTestUser(t, repo, "alice3")
+	require.NoError(t, repo.SoftDeleteUser(ctx, deleted.ID))
+
+	taken, err := repo.TakenUsernames(ctx, []string{"alice", "alice2", "alice_", "alice3"})
+
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{"alice": true, "alice_": true}, taken)
+
+	taken, err = repo.TakenUsernames(ctx, nil)
+	require.NoError(t, err)
+	assert.Empty(t, taken)
+}
+
+// usernames returns the usernames of users in order.
+func usernames(users []models.User) []string {
+	names := make([]string, len(users))
+	for i, u := range users {
+		names[i] = u.Username
+	}
+	return names
+}
+
+func TestSearchUsers_PrefixMatch(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	ctx := context.Background()
+	testutil.NewTestUser(t, repo, "alice")
+	testutil.NewTestUser(t, repo, "alicia")
+	testutil.NewTestUser(t, repo, "bob")
+	testutil.NewTestUser(t, repo, "malice")
+
+	users, err := repo.SearchUsers(ctx, "ali", 10)
+
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"alice", "alicia"}, usernames(users))
+}
+
+func TestSearchUsers_CaseInsensitive(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	ctx := context.Background()
+	testutil.NewTestUser(t, repo, "Alice")
+	_, err := repo.CreateUserWithEmail(ctx, "Bob@Example.com")
+	require.NoError(t, err)
+
+	users, err := repo.SearchUsers(ctx, "ALI", 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Alice"}, usernames(users))
+
+	users, err = repo.SearchUsers(ctx, "bob@example", 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Bob@Example.com"}, usernames(users))
+}
+
+func TestSearchUsers_MatchesEmail(t *testing.T) {
+	db, repo := testutil.NewTestDB(t)
+	ctx := context.Background()
+	user := testutil.NewTestUser(t, repo, "carol")
+	_, err := db.ExecContext(ctx, `UPDATE users SET email = ? WHERE id = ?`, "c.smith@example.com", user.ID)
+	require.NoError(t, err)
+
+	users, err := repo.SearchUsers(ctx, "c.smith", 10)
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"carol"}, usernames(users))
+}
+
+func TestSearchUsers_ExactMatchFirst(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	ctx := context.Background()
+	testutil.NewTestUser(t, repo, "ann")
+	testutil.NewTestUser(t, repo, "anna")
+	testutil.NewTestUser(t, repo, "annabel")
+
+	users, err := repo.SearchUsers(ctx, "anna", 10)
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"anna", "annabel"}, usernames(users))
+}
+
+func TestSearchUsers_EscapesWildcards(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	ctx := context.Background()
+	testutil.NewTestUser(t, repo, "a_b")
+	testutil.NewTestUser(t, repo, "axb")
+	testutil.NewTestUser(t, repo, "50%off")
+	testutil.NewTestUser(t, repo, "500")
+	testutil.NewTestUser(t, repo, `back\slash`)
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"a_", []string{"a_b"}},
+		{"50%", []string{"50%off"}},
+		{"%", []string{}},
+		{`back\`, []string{`back\slash`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			users, err := repo.SearchUsers(ctx, tt.query, 10)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, usernames(users))
+		})
+	}
+}
+
+func TestSearchUsers_EmptyQueryIsCapped(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	ctx := context.Background()
+	for i := range repository.MaxUserSearchLimit + 5 {
+		testutil.NewTestUser(t, repo, "user"+strconv.Itoa(i))
+	}
+
+	users, err := repo.SearchUsers(ctx, "  ", 0)
+	require.NoError(t, err)
+	assert.Len(t, users, repository.DefaultUserSearchLimit)
+
+	users, err = repo.SearchUsers(ctx, "", 1000)
+	require.NoError(t, err)
+	assert.Len(t, users, repository.MaxUserSearchLimit)
+}
+
+func TestGetUserStats(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	ctx := context.Background()
+
+	user := testutil.NewTestUser(t, repo, "testuser")
+	testutil.NewTestCredential(t, repo, user.ID, "cred-1")
+	testutil.NewTestCredential(t, repo, user.ID, "cred-2")
+	require.NoError(t, repo.CreateRecoveryCodes(ctx, user.ID, []string{"hash-1", "hash-2", "hash-3"}))
+	codes, err := repo.GetUnusedRecoveryCodes(ctx, user.ID)
+	require.NoError(t, err)
+	require.NoError(t, repo.MarkRecoveryCodeUsed(ctx, codes[0].ID))
+
+	other := testutil.NewTestUser(t, repo, "other")
+	testutil.NewTestCredential(t, repo, other.ID, "other-cred")
+
+	now := user.CreatedAt.Add(48 * time.Hour)
+	repo.SetClock(clock.NewFake(now))
+	require.NoError(t, repo.RecordLogin(ctx, user.ID))
+
+	stats, err := repo.GetUserStats(ctx, user.ID)
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), stats.CredentialCount)
+	assert.Equal(t, int64(2), stats.RecoveryCodesLeft)
+	require.NotNil(t, stats.LastLoginAt)
+	assert.True(t, now.Equal(*stats.LastLoginAt))
+	assert.Nil(t, stats.PreviousLoginAt)
+	assert.True(t, user.CreatedAt.Equal(stats.CreatedAt))
+	assert.Equal(t, 48*time.Hour, stats.AccountAge)
+}
+
+func TestRecordLogin_KeepsPreviousLogin(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	ctx := context.Background()
+	user := testutil.NewTestUser(t, repo, "testuser")
+	fake := clock.NewFake(time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC))
+	repo.SetClock(fake)
+
+	first := fake.Now()
+	require.NoError(t, repo.RecordLogin(ctx, user.ID))
+	fake.Advance(time.Hour)
+	require.NoError(t, repo.RecordLogin(ctx, user.ID))
+
+	stats, err := repo.GetUserStats(ctx, user.ID)
+	require.NoError(t, err)
+	require.NotNil(t, stats.LastLoginAt)
+	require.NotNil(t, stats.PreviousLoginAt)
+	assert.True(t, fake.Now().Equal(*stats.LastLoginAt))
+	assert.True(t, first.Equal(*stats.PreviousLoginAt))
+}
+
+func TestGetUserStats_NewUser(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+
+	user := testutil.NewTestUser(t, repo, "testuser")
+
+	stats, err := repo.GetUserStats(context.Background(), user.ID)
+
+	require.NoError(t, err)
+	assert.Zero(t, stats.CredentialCount)
+	assert.Zero(t, stats.RecoveryCodesLeft)
+	assert.Nil(t, stats.LastLoginAt)
+}
+
+func TestGetUserStats_NotFound(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+
+	_, err := repo.GetUserStats(context.Background(), 999)
+
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestSoftDeleteUser_HidesUser(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	ctx := context.Background()
+
+	user, err := repo.CreateUserWithEmail(ctx, "alice@example.com")
+	require.NoError(t, err)
+
+	require.NoError(t, repo.SoftDeleteUser(ctx, user.ID))
+
+	_, err = repo.GetUserByID(ctx, user.ID)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+	_, err = repo.GetUserByEmail(ctx, "alice@example.com")
+	require.ErrorIs(t, err, sql.ErrNoRows)
+	exists, err := repo.UserExists(ctx, "alice@example.com")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	users, err := repo.SearchUsers(ctx, "alice", 0)
+	require.NoError(t, err)
+	assert.Empty(t, users)
+}
+
+func TestSoftDeleteUser_NotFound(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	ctx := context.Background()
+
+	user, err := repo.CreateUser(ctx, "alice")
+	require.NoError(t, err)
+	require.NoError(t, repo.SoftDeleteUser(ctx, user.ID))
+
+	require.ErrorIs(t, repo.SoftDeleteUser(ctx, user.ID), sql.ErrNoRows)
+	require.ErrorIs(t, repo.SoftDeleteUser(ctx, 999), sql.ErrNoRows)
+}
+
+func TestListDeletedUsers(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	ctx := context.Background()
+	fake := clock.NewFake(time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC))
+	repo.SetClock(fake)
+
+	alice, err := repo.CreateUserWithEmail(ctx, "alice@example.com")
+	require.NoError(t, err)
+	bob, err := repo.CreateUser(ctx, "bob")
+	require.NoError(t, err)
+	_, err = repo.CreateUser(ctx, "carol")
+	require.NoError(t, err)
+
+	require.NoError(t, repo.SoftDeleteUser(ctx, alice.ID))
+	fake.Advance(time.Hour)
+	require.NoError(t, repo.SoftDeleteUser(ctx, bob.ID))
+
+	users, err := repo.ListDeletedUsers(ctx)
+	require.NoError(t, err)
+	require.Len(t, users, 2)
+	assert.Equal(t, "bob", users[0].Username)
+	assert.Nil(t, users[0].Email)
+	assert.Equal(t, "alice@example.com", users[1].Username)
+	require.NotNil(t, users[1].Email)
+	assert.Equal(t, "alice@example.com", *users[1].Email)
+	require.NotNil(t, users[1].DeletedAt)
+	assert.True(t, users[1].DeletedAt.Equal(fake.Now().Add(-time.Hour)))
+}
+
+func TestRestoreUser(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	ctx := context.Background()
+
+	user, err := repo.CreateUserWithEmail(ctx, "alice@example.com")
+	require.NoError(t, err)
+	require.NoError(t, repo.SoftDeleteUser(ctx, user.ID))
+
+	restored, err := repo.RestoreUser(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "alice@example.com", restored.Username)
+	require.NotNil(t, restored.Email)
+	assert.Equal(t, "alice@example.com", *restored.Email)
+	assert.Nil(t, restored.DeletedAt)
+
+	found, err := repo.GetUserByEmail(ctx, "alice@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, found.ID)
+
+	_, err = repo.RestoreUser(ctx, user.ID)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestSoftDeleteUser_AllowsReregistration(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	ctx := context.Background()
+
+	old, err := repo.CreateUserWithEmail(ctx, "alice@example.com")
+	require.NoError(t, err)
+	require.NoError(t, repo.SoftDeleteUser(ctx, old.ID))
+
+	taken, err := repo.EmailExists(ctx, "alice@example.com")
+	require.NoError(t, err)
+	assert.False(t, taken)
+
+	fresh, err := repo.CreateUserWithEmail(ctx, "alice@example.com")
+	require.NoError(t, err)
+	assert.NotEqual(t, old.ID, fresh.ID)
+
+	// The identifier now belongs to the new account.
+	_, err = repo.RestoreUser(ctx, old.ID)
+	require.ErrorIs(t, err, repository.ErrIdentifierTaken)
+}
+
+func TestListUsersAfter_Pages(t *testing.T) {
+	db, repo := testutil.NewTestDB(t)
+	ctx := context.Background()
+	codec := pagination.New([]byte("0123456789abcdef0123456789abcdef"))
+
+	// 25 users over 13 seconds, so pairs share a created_at
+	start := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	var want []string
+	for i := range 25 {
+		user, err := repo.CreateUser(ctx, "user"+strconv.Itoa(i))
+		require.NoError(t, err)
+		createdAt := start.Add(time.Duration(i/2) * time.Second).Format(time.DateTime)
+		_, err = db.Exec(`UPDATE users SET created_at = ? WHERE id = ?`, createdAt, user.ID)
+		require.NoError(t, err)
+		want = append([]string{user.Username}, want...)
+	}
+
+	var got []string
+	var after *pagination.Cursor
+	pages := 0
+	for {
+		users, next, err := repo.ListUsersAfter(ctx, after, 10)
+		require.NoError(t, err)
+		pages++
+		for _, u := range users {
+			got = append(got, u.Username)
+		}
+		if next == nil {
+			break
+		}
+		// Cursors travel to the client and back in their opaque form
+		decoded, err := codec.Decode(codec.Encode(*next))
+		require.NoError(t, err)
+		after = &decoded
+	}
+
+	assert.Equal(t, 3, pages)
+	assert.Equal(t, want, got)
+}
+
+func TestListUsersAfter_StableWhileInserting(t *testing.T) {
+	db, repo := testutil.NewTestDB(t)
+	ctx := context.Background()
+
+	for i := range 4 {
+		user, err := repo.CreateUser(ctx, "user"+strconv.Itoa(i))
+		require.NoError(t, err)
+		_, err = db.Exec(`UPDATE users SET created_at = ? WHERE id = ?`, "2025-06-01 12:00:0"+strconv.Itoa(i), user.ID)
+		require.NoError(t, err)
+	}
+
+	first, next, err := repo.ListUsersAfter(ctx, nil, 2)
+	require.NoError(t, err)
+	require.NotNil(t, next)
+	assert.Equal(t, "user3", first[0].Username)
+
+	// A newer user doesn't shift the following page
+	_, err = repo.CreateUser(ctx, "newcomer")
+	require.NoError(t, err)
+
+	second, next, err := repo.ListUsersAfter(ctx, next, 2)
+	require.NoError(t, err)
+	assert.Nil(t, next)
+	require.Len(t, second, 2)
+	assert.Equal(t, "user1", second[0].Username)
+	assert.Equal(t, "user0", second[1].Username)
+}
+
+func TestListUsersAfter_SkipsDeletedUsers(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	ctx := context.Background()
+
+	alice := testutil.NewTestUser(t, repo, "alice")
+	testutil.NewTestUser(t, repo, "bob")
+	require.NoError(t, repo.SoftDeleteUser(ctx, alice.ID))
+
+	users, next, err := repo.ListUsersAfter(ctx, nil, 0)
+	require.NoError(t, err)
+	assert.Nil(t, next)
+	require.Len(t, users, 1)
+	assert.Equal(t, "bob", users[0].Username)
+}
@@ -0,0 +1,374 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package server
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/oliverandrich/go-webapp-template/internal/config"
+	"golang.org/x/crypto/acme"
+)
+
+const (
+	// dnsIssueTimeout bounds a single DNS-01 issuance, including the time
+	// the DNS provider needs to publish the records.
+	dnsIssueTimeout = 10 * time.Minute
+
+	// dnsRenewInterval is how often the DNS-01 certificate is checked for
+	// renewal. It is renewed once it expires within 30 days.
+	dnsRenewInterval = 12 * time.Hour
+
+	// dnsCleanupTimeout bounds the removal of challenge records, which also
+	// runs when issuance was cancelled.
+	dnsCleanupTimeout = time.Minute
+)
+
+// DNSProvider publishes the TXT records answering ACME DNS-01 challenges.
+// fqdn is the fully qualified record name including the trailing dot, e.g.
+// "_acme-challenge.example.com.". Present should only return once the record
+// is visible to the CA; for wildcard certificates several values may be
+// present for the same name at once.
+type DNSProvider interface {
+	Present(ctx context.Context, fqdn, value string) error
+	CleanUp(ctx context.Context, fqdn, value string) error
+}
+
+// newDNSProvider returns the DNS provider selected in the TLS config.
+func newDNSProvider(cfg *config.TLSConfig) (DNSProvider, error) {
+	switch strings.ToLower(cfg.ACMEDNSProvider) {
+	case "exec", "":
+		if cfg.ACMEDNSExec == "" {
+			return nil, errors.New("the exec DNS provider requires TLS_ACME_DNS_EXEC to be set")
+		}
+		return &execDNSProvider{command: cfg.ACMEDNSExec}, nil
+	default:
+		return nil, fmt.Errorf("unknown DNS provider: %s", cfg.ACMEDNSProvider)
+	}
+}
+
+// execDNSProvider hands record changes to an external hook, called as
+// "<command> present|cleanup <fqdn> <value>". The hook can talk to whatever
+// DNS API the deployment uses.
+type execDNSProvider struct {
+	command string
+}
+
+func (p *execDNSProvider) Present(ctx context.Context, fqdn, value string) error {
+	return p.run(ctx, "present", fqdn, value)
+}
+
+func (p *execDNSProvider) CleanUp(ctx context.Context, fqdn, value string) error {
+	return p.run(ctx, "cleanup", fqdn, value)
+}
+
+func (p *execDNSProvider) run(ctx context.Context, action, fqdn, value string) error {
+	cmd := exec.CommandContext(ctx, p.command, action, fqdn, value) //nolint:gosec // command comes from config
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("dns hook %s %s: %w: %s", action, fqdn, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// dnsCertManager obtains and renews a certificate through the ACME DNS-01
+// challenge. Unlike autocert it needs no inbound ports and can issue
+// wildcard names, at the cost of a DNSProvider.
+type dnsCertManager struct {
+	cert     atomic.Pointer[tls.Certificate]
+	client   *acme.Client
+	provider DNSProvider
+	domains  []string
+	email    string
+	certFile string
+	keyFile  string
+	hashFile string
+
+	mu         sync.Mutex // serializes issuance
+	registered bool
+}
+
+// newDNSCertManager prepares a manager that keeps its account key and
+// certificate in certDir.
+func newDNSCertManager(cfg *config.Config, certDir string) (*dnsCertManager, error) {
+	provider, err := newDNSProvider(&cfg.TLS)
+	if err != nil {
+		return nil, err
+	}
+
+	accountKey, err := loadOrCreateKey(filepath.Join(certDir, "account.key"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load ACME account key: %w", err)
+	}
+
+	return &dnsCertManager{
+		client: &acme.Client{
+			Key:          accountKey,
+			DirectoryURL: cfg.TLS.ACMEDirectoryURL, // empty means Let's Encrypt
+		},
+		provider: provider,
+		domains:  dnsDomains(cfg),
+		email:    cfg.TLS.Email,
+		certFile: filepath.Join(certDir, "cert.pem"),
+		keyFile:  filepath.Join(certDir, "key.pem"),
+		hashFile: filepath.Join(certDir, "domains.sha256"),
+	}, nil
+}
+
+// dnsDomains returns the names for the DNS-01 certificate: the configured
+// host and the extra SANs that are DNS names (e.g. "*.example.com").
+func dnsDomains(cfg *config.Config) []string {
+	var domains []string
+	for _, name := range append([]string{cfg.Server.Host}, cfg.TLS.ExtraSANs...) {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || net.ParseIP(name) != nil || slices.Contains(domains, name) {
+			continue
+		}
+		domains = append(domains, name)
+	}
+	return domains
+}
+
+// GetCertificate returns the current certificate. It is used as
+// tls.Config.GetCertificate.
+func (m *dnsCertManager) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return m.cert.Load(), nil
+}
+
+// ensure loads the cached certificate if it covers the configured domains
+// and is not expiring soon; otherwise it obtains a new one.
+func (m *dnsCertManager) ensure(ctx context.Context) error {
+	if m.cert.Load() == nil && certExists(m.certFile, m.keyFile) &&
+		readSANsHash(m.hashFile) == hashSANs(m.domains, nil) {
+		cert, err := tls.LoadX509KeyPair(m.certFile, m.keyFile)
+		if err != nil {
+			slog.Warn("cached ACME certificate invalid, obtaining new one", "error", err)
+		} else {
+			m.cert.Store(&cert)
+		}
+	}
+
+	if cert := m.cert.Load(); cert != nil && !isCertExpiringSoon(cert) {
+		return nil
+	}
+	return m.obtain(ctx)
+}
+
+// watch renews the certificate in the background until ctx is done. Failed
+// renewals are logged and retried on the next tick.
+func (m *dnsCertManager) watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			issueCtx, cancel := context.WithTimeout(ctx, dnsIssueTimeout)
+			if err := m.ensure(issueCtx); err != nil {
+				slog.Error("ACME certificate renewal failed", "error", err)
+			}
+			cancel()
+		}
+	}
+}
+
+// obtain runs a complete ACME order: all challenge records are published
+// first, then the challenges are accepted, and the records are removed again
+// once the order is done.
+func (m *dnsCertManager) obtain(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	slog.Info("Obtaining certificate via DNS-01", "domains", m.domains)
+
+	if err := m.register(ctx); err != nil {
+		return err
+	}
+
+	order, err := m.client.AuthorizeOrder(ctx, acme.DomainIDs(m.domains...))
+	if err != nil {
+		return fmt.Errorf("failed to create ACME order: %w", err)
+	}
+
+	type pendingChallenge struct {
+		challenge *acme.Challenge
+		authzURL  string
+		fqdn      string
+		value     string
+	}
+	var pending []pendingChallenge
+	defer func() {
+		cleanupCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), dnsCleanupTimeout)
+		defer cancel()
+		for _, p := range pending {
+			if cleanupErr := m.provider.CleanUp(cleanupCtx, p.fqdn, p.value); cleanupErr != nil {
+				slog.Warn("failed to remove ACME challenge record", "fqdn", p.fqdn, "error", cleanupErr)
+			}
+		}
+	}()
+
+	for _, authzURL := range order.AuthzURLs {
+		authz, authzErr := m.client.GetAuthorization(ctx, authzURL)
+		if authzErr != nil {
+			return fmt.Errorf("failed to fetch ACME authorization: %w", authzErr)
+		}
+		if authz.Status == acme.StatusValid {
+			continue
+		}
+
+		idx := slices.IndexFunc(authz.Challenges, func(c *acme.Challenge) bool { return c.Type == "dns-01" })
+		if idx < 0 {
+			return fmt.Errorf("CA offers no dns-01 challenge for %s", authz.Identifier.Value)
+		}
+		challenge := authz.Challenges[idx]
+
+		value, recordErr := m.client.DNS01ChallengeRecord(challenge.Token)
+		if recordErr != nil {
+			return fmt.Errorf("failed to compute challenge record: %w", recordErr)
+		}
+		fqdn := "_acme-challenge." + authz.Identifier.Value + "."
+		if presentErr := m.provider.Present(ctx, fqdn, value); presentErr != nil {
+			return presentErr
+		}
+		pending = append(pending, pendingChallenge{challenge: challenge, authzURL: authz.URI, fqdn: fqdn, value: value})
+	}
+
+	for _, p := range pending {
+		if _, acceptErr := m.client.Accept(ctx, p.challenge); acceptErr != nil {
+			return fmt.Errorf("failed to accept challenge for %s: %w", p.fqdn, acceptErr)
+		}
+		if _, waitErr := m.client.WaitAuthorization(ctx, p.authzURL); waitErr != nil {
+			return fmt.Errorf("authorization for %s failed: %w", p.fqdn, waitErr)
+		}
+	}
+
+	order, err = m.client.WaitOrder(ctx, order.URI)
+	if err != nil {
+		return fmt.Errorf("ACME order failed: %w", err)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return fmt.Errorf("failed to generate private key: %w", err)
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: m.domains[0]},
+		DNSNames: m.domains,
+	}, key)
+	if err != nil {
+		return fmt.Errorf("failed to create certificate request: %w", err)
+	}
+
+	chain, _, err := m.client.CreateOrderCert(ctx, order.FinalizeURL, csr, true)
+	if err != nil {
+		return fmt.Errorf("failed to finalize ACME order: %w", err)
+	}
+
+	cert, err := m.store(chain, key)
+	if err != nil {
+		return err
+	}
+	m.cert.Store(cert)
+
+	slog.Info("Obtained certificate via DNS-01", "domains", m.domains)
+	logCertFingerprint(cert)
+	return nil
+}
+
+// register creates the ACME account on first use. An existing account for
+// the key is fine.
+func (m *dnsCertManager) register(ctx context.Context) error {
+	if m.registered {
+		return nil
+	}
+	account := &acme.Account{}
+	if m.email != "" {
+		account.Contact = []string{"mailto:" + m.email}
+	}
+	if _, err := m.client.Register(ctx, account, acme.AcceptTOS); err != nil && !errors.Is(err, acme.ErrAccountAlreadyExists) {
+		return fmt.Errorf("failed to register ACME account: %w", err)
+	}
+	m.registered = true
+	return nil
+}
+
+// store writes the issued chain and key to disk and returns them loaded.
+func (m *dnsCertManager) store(chain [][]byte, key *ecdsa.PrivateKey) (*tls.Certificate, error) {
+	var certPEM []byte
+	for _, der := range chain {
+		certPEM = append(certPEM, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})...)
+	}
+	if err := os.WriteFile(m.certFile, certPEM, 0o600); err != nil {
+		return nil, fmt.Errorf("failed to write cert file: %w", err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal private key: %w", err)
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	if writeErr := os.WriteFile(m.keyFile, keyPEM, 0o600); writeErr != nil {
+		return nil, fmt.Errorf("failed to write key file: %w", writeErr)
+	}
+
+	if writeErr := os.WriteFile(m.hashFile, []byte(hashSANs(m.domains, nil)), 0o600); writeErr != nil {
+		return nil, fmt.Errorf("failed to write domain hash file: %w", writeErr)
+	}
+
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load issued cert: %w", err)
+	}
+	return &cert, nil
+}
+
+// loadOrCreateKey reads an EC private key from path, generating and saving
+// a new P-256 key if the file does not exist.
+func loadOrCreateKey(path string) (crypto.Signer, error) {
+	data, err := os.ReadFile(path) //nolint:gosec // path is derived from config
+	if err == nil {
+		block, _ := pem.Decode(data)
+		if block == nil {
+			return nil, fmt.Errorf("no PEM data in %s", path)
+		}
+		return x509.ParseECPrivateKey(block.Bytes)
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
@@ -0,0 +1,98 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/oliverandrich/go-webapp-template/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeHook creates an executable hook script that appends its arguments
+// to a log file, and returns the script and log paths.
+func writeHook(t *testing.T, exitCode int) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	logFile := filepath.Join(dir, "calls.log")
+	script := filepath.Join(dir, "hook.sh")
+	content := "#!/bin/sh\necho \"$@\" >> " + logFile + "\necho 'hook output'\nexit " + strconv.Itoa(exitCode) + "\n"
+	require.NoError(t, os.WriteFile(script, []byte(content), 0o700)) //nolint:gosec // test script must be executable
+	return script, logFile
+}
+
+func TestExecDNSProvider(t *testing.T) {
+	script, logFile := writeHook(t, 0)
+	provider, err := newDNSProvider(&config.TLSConfig{ACMEDNSProvider: "exec", ACMEDNSExec: script})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, provider.Present(ctx, "_acme-challenge.example.com.", "token-value"))
+	require.NoError(t, provider.CleanUp(ctx, "_acme-challenge.example.com.", "token-value"))
+
+	calls, err := os.ReadFile(logFile) //nolint:gosec // test file
+	require.NoError(t, err)
+	assert.Equal(t,
+		"present _acme-challenge.example.com. token-value\ncleanup _acme-challenge.example.com. token-value\n",
+		string(calls))
+}
+
+func TestExecDNSProvider_Failure(t *testing.T) {
+	script, _ := writeHook(t, 1)
+	provider, err := newDNSProvider(&config.TLSConfig{ACMEDNSExec: script})
+	require.NoError(t, err)
+
+	err = provider.Present(context.Background(), "_acme-challenge.example.com.", "v")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "hook output")
+}
+
+func TestNewDNSProvider_Invalid(t *testing.T) {
+	_, err := newDNSProvider(&config.TLSConfig{ACMEDNSProvider: "exec"})
+	require.Error(t, err)
+
+	_, err = newDNSProvider(&config.TLSConfig{ACMEDNSProvider: "route53", ACMEDNSExec: "/bin/true"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown DNS provider")
+}
+
+func TestDNSDomains(t *testing.T) {
+	cfg := &config.Config{
+		Server: config.ServerConfig{Host: "Example.com"},
+		TLS:    config.TLSConfig{ExtraSANs: []string{"*.example.com", "10.0.0.5", " ", "example.com"}},
+	}
+
+	assert.Equal(t, []string{"example.com", "*.example.com"}, dnsDomains(cfg))
+}
+
+func TestSetupACMEDNS_UsesCachedCertificate(t *testing.T) {
+	cfg := newACMEConfig(t)
+	cfg.TLS.ACMEChallenge = "dns-01"
+	cfg.TLS.ACMEDNSExec = "/bin/false"
+
+	// Seed the cache so no ACME server is contacted
+	certDir := filepath.Join(cfg.TLS.CertDir, "acme-dns")
+	require.NoError(t, os.MkdirAll(certDir, 0o700))
+	cached, err := generateSelfSignedCert(cfg, filepath.Join(certDir, "cert.pem"), filepath.Join(certDir, "key.pem"))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(certDir, "domains.sha256"), []byte(hashSANs([]string{"example.com"}, nil)), 0o600))
+
+	result, err := setupACME(cfg)
+	require.NoError(t, err)
+
+	assert.Equal(t, TLSModeACME, result.Mode)
+	assert.Nil(t, result.CertManager)
+	assert.Nil(t, result.HTTPHandler)
+	require.NotNil(t, result.DNSManager)
+
+	cert, err := result.TLSConfig.GetCertificate(nil)
+	require.NoError(t, err)
+	assert.Equal(t, cached.Certificate[0], cert.Certificate[0])
+	assert.FileExists(t, filepath.Join(certDir, "account.key"))
+}
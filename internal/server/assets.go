@@ -4,18 +4,45 @@
 package server
 
 import (
+	"io/fs"
 	"log/slog"
+	"strings"
 
 	"github.com/oliverandrich/go-webapp-template/internal/appcontext"
 	"github.com/oliverandrich/go-webapp-template/internal/assets"
 )
 
-// findAssets returns asset paths from the embedded manifest.
-func findAssets() *appcontext.Assets {
+// findAssets returns asset paths from the embedded manifest, below
+// pathPrefix. Missing files are only logged, see checkAssets.
+func findAssets(pathPrefix string) *appcontext.Assets {
 	a := &appcontext.Assets{
 		CSSPath: assets.CSSPath(),
 		JSPath:  assets.JSPath(),
 	}
+	checkAssets(assets.FS(), a)
+	a.CSSPath = pathPrefix + a.CSSPath
+	a.JSPath = pathPrefix + a.JSPath
 	slog.Debug("assets loaded", "css", a.CSSPath, "js", a.JSPath)
 	return a
 }
+
+// checkAssets warns when the static files the pages link to are missing,
+// typically because a development build runs outside the repository root or
+// the asset build step was skipped. The server still starts, pages just
+// render without styles and scripts. It reports whether all files exist.
+func checkAssets(static fs.FS, a *appcontext.Assets) bool {
+	if _, err := fs.Stat(static, "."); err != nil {
+		slog.Warn("static asset directory not found, pages will render without styles and scripts; "+
+			"run the server from the repository root and build the assets with `just css bundle`", "error", err)
+		return false
+	}
+
+	ok := true
+	for _, path := range []string{a.CSSPath, a.JSPath} {
+		if _, err := fs.Stat(static, strings.TrimPrefix(path, "/static/")); err != nil {
+			slog.Warn("static asset not found, build the assets with `just css bundle`", "path", path)
+			ok = false
+		}
+	}
+	return ok
+}
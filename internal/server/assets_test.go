@@ -4,14 +4,21 @@
 package server
 
 import (
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"testing/fstest"
 
+	"github.com/labstack/echo/v4"
+	"github.com/oliverandrich/go-webapp-template/internal/appcontext"
+	"github.com/oliverandrich/go-webapp-template/internal/config"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestFindAssets(t *testing.T) {
-	assets := findAssets()
+	assets := findAssets("")
 
 	// CSSPath should be in /static/dist/ and end with .css
 	assert.True(t, strings.HasPrefix(assets.CSSPath, "/static/dist/styles"), "CSSPath should start with /static/dist/styles")
@@ -21,3 +28,46 @@ func TestFindAssets(t *testing.T) {
 	assert.True(t, strings.HasPrefix(assets.JSPath, "/static/dist/app"), "JSPath should start with /static/dist/app")
 	assert.True(t, strings.HasSuffix(assets.JSPath, ".js"), "JSPath should end with .js")
 }
+
+func TestFindAssets_PathPrefix(t *testing.T) {
+	assets := findAssets("/app")
+
+	assert.True(t, strings.HasPrefix(assets.CSSPath, "/app/static/dist/styles"))
+	assert.True(t, strings.HasPrefix(assets.JSPath, "/app/static/dist/app"))
+}
+
+func TestCheckAssets_MissingDirectory(t *testing.T) {
+	logs := captureLogs(t)
+	a := &appcontext.Assets{CSSPath: "/static/dist/styles.css", JSPath: "/static/dist/app.js"}
+
+	ok := checkAssets(os.DirFS(filepath.Join(t.TempDir(), "static")), a)
+
+	assert.False(t, ok)
+	assert.Contains(t, logs.String(), "static asset directory not found")
+
+	// The server still starts without them
+	e := echo.New()
+	require.NoError(t, setupMiddleware(e, &config.Config{Server: config.ServerConfig{MaxBodySize: 1}}, a))
+}
+
+func TestCheckAssets_MissingFile(t *testing.T) {
+	logs := captureLogs(t)
+	static := fstest.MapFS{"dist/styles.css": {Data: []byte("body{}")}}
+
+	ok := checkAssets(static, &appcontext.Assets{CSSPath: "/static/dist/styles.css", JSPath: "/static/dist/app.js"})
+
+	assert.False(t, ok)
+	assert.Contains(t, logs.String(), `"path":"/static/dist/app.js"`)
+	assert.NotContains(t, logs.String(), "styles.css")
+}
+
+func TestCheckAssets_Complete(t *testing.T) {
+	logs := captureLogs(t)
+	static := fstest.MapFS{
+		"dist/styles.css": {Data: []byte("body{}")},
+		"dist/app.js":     {Data: []byte("")},
+	}
+
+	assert.True(t, checkAssets(static, &appcontext.Assets{CSSPath: "/static/dist/styles.css", JSPath: "/static/dist/app.js"}))
+	assert.Empty(t, logs.String())
+}
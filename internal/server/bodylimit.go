@@ -0,0 +1,53 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package server
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// bodyLimitsMiddleware limits request bodies by path prefix, as configured
+// with server.body_limits. When several prefixes match, the tightest limit
+// applies; paths without a matching prefix get defaultLimit. Prefixes match
+// whole path segments, so "/api" covers "/api/upload" but not "/apis".
+// Oversized bodies are answered with 413 Request Entity Too Large.
+func bodyLimitsMiddleware(limits map[string]int64, defaultLimit int64) echo.MiddlewareFunc {
+	// One limiter per configured size, built once
+	limiters := make(map[int64]echo.MiddlewareFunc, len(limits)+1)
+	for _, n := range limits {
+		limiters[n] = bodyLimit(fmt.Sprintf("%dB", n))
+	}
+	limiters[defaultLimit] = bodyLimit(fmt.Sprintf("%dB", defaultLimit))
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			limit := selectBodyLimit(c.Request().URL.Path, limits, defaultLimit)
+			return limiters[limit](next)(c)
+		}
+	}
+}
+
+// selectBodyLimit returns the smallest limit whose prefix matches path, or
+// defaultLimit when none does.
+func selectBodyLimit(path string, limits map[string]int64, defaultLimit int64) int64 {
+	limit := int64(-1)
+	for prefix, n := range limits {
+		if hasPathPrefix(path, prefix) && (limit < 0 || n < limit) {
+			limit = n
+		}
+	}
+	if limit < 0 {
+		return defaultLimit
+	}
+	return limit
+}
+
+// hasPathPrefix reports whether path is prefix or lies below it.
+func hasPathPrefix(path, prefix string) bool {
+	prefix = strings.TrimSuffix(prefix, "/")
+	return prefix == "" || path == prefix || strings.HasPrefix(path, prefix+"/")
+}
@@ -0,0 +1,133 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// certReloadInterval is how often the manual certificate files are checked for changes.
+const certReloadInterval = time.Minute
+
+// certReloader serves a certificate loaded from disk and swaps it for a new
+// one when the files change, so renewed certificates apply without a restart.
+type certReloader struct {
+	cert     atomic.Pointer[tls.Certificate]
+	certFile string
+	keyFile  string
+
+	mu      sync.Mutex // serializes reloads
+	modTime time.Time  // newest cert/key mtime seen by the last reload
+}
+
+// newCertReloader loads the initial certificate pair.
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	r := &certReloader{certFile: certFile, keyFile: keyFile}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetCertificate returns the current certificate. It is used as
+// tls.Config.GetCertificate.
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return r.cert.Load(), nil
+}
+
+// reload loads and validates the pair on disk. The current certificate is
+// only replaced if the new one is valid.
+func (r *certReloader) reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	modTime, err := r.filesModTime()
+	if err != nil {
+		return err
+	}
+	// Remember the attempt so a broken pair is not retried on every tick
+	r.modTime = modTime
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load certificate: %w", err)
+	}
+	if cert.Leaf == nil {
+		if cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+			return fmt.Errorf("failed to parse certificate: %w", err)
+		}
+	}
+	if time.Now().After(cert.Leaf.NotAfter) {
+		return fmt.Errorf("certificate expired at %s", cert.Leaf.NotAfter.Format(time.RFC3339))
+	}
+
+	r.cert.Store(&cert)
+	return nil
+}
+
+// changed reports whether the cert or key file was modified since the last
+// reload attempt.
+func (r *certReloader) changed() bool {
+	modTime, err := r.filesModTime()
+	if err != nil {
+		return false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return modTime.After(r.modTime)
+}
+
+// filesModTime returns the newer of the cert and key file mtimes.
+func (r *certReloader) filesModTime() (time.Time, error) {
+	certInfo, err := os.Stat(r.certFile)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("certificate file not found: %w", err)
+	}
+	keyInfo, err := os.Stat(r.keyFile)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("key file not found: %w", err)
+	}
+	if keyInfo.ModTime().After(certInfo.ModTime()) {
+		return keyInfo.ModTime(), nil
+	}
+	return certInfo.ModTime(), nil
+}
+
+// tryReload reloads the certificate and logs the outcome.
+func (r *certReloader) tryReload() {
+	if err := r.reload(); err != nil {
+		slog.Error("certificate reload failed, keeping current certificate", "error", err)
+		return
+	}
+	slog.Info("certificate reloaded", "cert", r.certFile)
+	logCertFingerprint(r.cert.Load())
+}
+
+// watch reloads the certificate when its files change or a value arrives on
+// trigger (e.g. SIGHUP), until ctx is cancelled.
+func (r *certReloader) watch(ctx context.Context, interval time.Duration, trigger <-chan os.Signal) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-trigger:
+			r.tryReload()
+		case <-ticker.C:
+			if r.changed() {
+				r.tryReload()
+			}
+		}
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeTestCert generates a self-signed pair into the given files and bumps
+// their mtime so the change is visible on coarse-grained filesystems.
+func writeTestCert(t *testing.T, certFile, keyFile string, extraSANs ...string) {
+	t.Helper()
+	cfg := newSelfSignedConfig(t, extraSANs...)
+	_, err := generateSelfSignedCert(cfg, certFile, keyFile)
+	require.NoError(t, err)
+
+	future := time.Now().Add(time.Duration(len(extraSANs)) * time.Second)
+	require.NoError(t, os.Chtimes(certFile, future, future))
+	require.NoError(t, os.Chtimes(keyFile, future, future))
+}
+
+func newTestCertFiles(t *testing.T) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	return filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+}
+
+func TestCertReloader_SwapsCertificateOnChange(t *testing.T) {
+	certFile, keyFile := newTestCertFiles(t)
+	writeTestCert(t, certFile, keyFile)
+
+	r, err := newCertReloader(certFile, keyFile)
+	require.NoError(t, err)
+	first, err := r.GetCertificate(nil)
+	require.NoError(t, err)
+	assert.False(t, r.changed())
+
+	writeTestCert(t, certFile, keyFile, "renewed.test")
+	require.True(t, r.changed())
+	require.NoError(t, r.reload())
+
+	second, err := r.GetCertificate(nil)
+	require.NoError(t, err)
+	assert.NotEqual(t, first.Leaf.SerialNumber, second.Leaf.SerialNumber)
+	assert.Contains(t, second.Leaf.DNSNames, "renewed.test")
+	assert.False(t, r.changed())
+}
+
+func TestCertReloader_KeepsCertificateWhenNewPairIsInvalid(t *testing.T) {
+	certFile, keyFile := newTestCertFiles(t)
+	writeTestCert(t, certFile, keyFile)
+
+	r, err := newCertReloader(certFile, keyFile)
+	require.NoError(t, err)
+	before, _ := r.GetCertificate(nil)
+
+	require.NoError(t, os.WriteFile(certFile, []byte("not a certificate"), 0o600))
+
+	require.Error(t, r.reload())
+	after, _ := r.GetCertificate(nil)
+	assert.Same(t, before, after)
+	assert.False(t, r.changed(), "a failed attempt must not be retried until the files change again")
+}
+
+func TestCertReloader_WatchReloadsOnTrigger(t *testing.T) {
+	certFile, keyFile := newTestCertFiles(t)
+	writeTestCert(t, certFile, keyFile)
+
+	r, err := newCertReloader(certFile, keyFile)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	trigger := make(chan os.Signal, 1)
+	go r.watch(ctx, time.Hour, trigger)
+
+	writeTestCert(t, certFile, keyFile, "signalled.test")
+	trigger <- os.Interrupt
+
+	assert.Eventually(t, func() bool {
+		cert, _ := r.GetCertificate(nil)
+		return slices.Contains(cert.Leaf.DNSNames, "signalled.test")
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestCertReloader_WatchReloadsOnFileChange(t *testing.T) {
+	certFile, keyFile := newTestCertFiles(t)
+	writeTestCert(t, certFile, keyFile)
+
+	r, err := newCertReloader(certFile, keyFile)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go r.watch(ctx, 10*time.Millisecond, nil)
+
+	writeTestCert(t, certFile, keyFile, "polled.test")
+
+	assert.Eventually(t, func() bool {
+		cert, _ := r.GetCertificate(nil)
+		return slices.Contains(cert.Leaf.DNSNames, "polled.test")
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestSetupManual_UsesReloader(t *testing.T) {
+	certFile, keyFile := newTestCertFiles(t)
+	writeTestCert(t, certFile, keyFile)
+	cfg := newSelfSignedConfig(t)
+	cfg.TLS.Mode = "manual"
+	cfg.TLS.CertFile = certFile
+	cfg.TLS.KeyFile = keyFile
+
+	result, err := setupManual(cfg)
+
+	require.NoError(t, err)
+	require.NotNil(t, result.Reloader)
+	assert.Empty(t, result.TLSConfig.Certificates)
+	cert, err := result.TLSConfig.GetCertificate(nil)
+	require.NoError(t, err)
+	assert.NotNil(t, cert.Leaf)
+}
@@ -0,0 +1,70 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package server
+
+import (
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// cleanPathMiddleware collapses repeated slashes and resolves "." and ".."
+// segments before routing, so "/auth//login" and "/auth/./login" reach the
+// same route as "/auth/login". GET and HEAD requests are redirected (301) to
+// the canonical path to keep caches and logs consistent; other methods are
+// rewritten in place because clients would drop the body on redirect. A
+// trailing slash is kept for RemoveTrailingSlash to handle.
+//
+// Cleaning works on the escaped path, so encoded slashes and dots (%2F,
+// %2E) are left alone.
+func cleanPathMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			escaped := req.URL.EscapedPath()
+			cleaned := cleanPath(escaped)
+			if cleaned == escaped {
+				return next(c)
+			}
+
+			if req.Method == http.MethodGet || req.Method == http.MethodHead {
+				target := cleaned
+				if req.URL.RawQuery != "" {
+					target += "?" + req.URL.RawQuery
+				}
+				return c.Redirect(http.StatusMovedPermanently, target)
+			}
+
+			unescaped, err := url.PathUnescape(cleaned)
+			if err != nil {
+				return echo.ErrBadRequest
+			}
+			// The router reads the path of the original request, so it is
+			// changed in place
+			req.URL.Path = unescaped
+			req.URL.RawPath = ""
+			if cleaned != req.URL.EscapedPath() {
+				req.URL.RawPath = cleaned
+			}
+			return next(c)
+		}
+	}
+}
+
+// cleanPath returns the canonical form of an escaped request path. The
+// result always starts with a single slash, so it is safe to use as a
+// same-host redirect target.
+func cleanPath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	cleaned := path.Clean("/" + p)
+	if strings.HasSuffix(p, "/") && cleaned != "/" {
+		cleaned += "/"
+	}
+	return cleaned
+}
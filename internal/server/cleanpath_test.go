@@ -0,0 +1,73 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/stretchr/testify/assert"
+)
+
+// newCleanPathEcho answers every route with the path it was routed on.
+func newCleanPathEcho() *echo.Echo {
+	e := echo.New()
+	e.Pre(cleanPathMiddleware())
+	e.Pre(middleware.RemoveTrailingSlash())
+	echoPath := func(c echo.Context) error { return c.String(http.StatusOK, c.Request().URL.Path) }
+	e.GET("/auth/login", echoPath)
+	e.POST("/auth/login", echoPath)
+	e.GET("/static/*", echoPath)
+	return e
+}
+
+func TestCleanPath_RedirectsGET(t *testing.T) {
+	e := newCleanPathEcho()
+
+	tests := map[string]string{
+		"/auth//login":          "/auth/login",
+		"//auth/login":          "/auth/login",
+		"/auth/./login":         "/auth/login",
+		"/static/../auth/login": "/auth/login",
+		"/../auth/login":        "/auth/login",
+		"/auth//login?next=/x":  "/auth/login?next=/x",
+		"/auth//login/":         "/auth/login/",
+	}
+	for path, want := range tests {
+		rec := serve(e, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, http.StatusMovedPermanently, rec.Code, path)
+		assert.Equal(t, want, rec.Header().Get(echo.HeaderLocation), path)
+	}
+}
+
+func TestCleanPath_RewritesOtherMethods(t *testing.T) {
+	e := newCleanPathEcho()
+
+	rec := serve(e, httptest.NewRequest(http.MethodPost, "/auth//./login", strings.NewReader("x")))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "/auth/login", rec.Body.String())
+}
+
+func TestCleanPath_PassesCanonicalPaths(t *testing.T) {
+	e := newCleanPathEcho()
+
+	for _, path := range []string{"/auth/login", "/auth/login/", "/static/css/app.css", "/static/a%2F..%2Fb"} {
+		rec := serve(e, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, http.StatusOK, rec.Code, path)
+	}
+}
+
+func TestCleanPath_NeverRedirectsOffHost(t *testing.T) {
+	e := newCleanPathEcho()
+
+	rec := serve(e, httptest.NewRequest(http.MethodGet, "///evil.example//x", nil))
+
+	assert.Equal(t, http.StatusMovedPermanently, rec.Code)
+	assert.Equal(t, "/evil.example/x", rec.Header().Get(echo.HeaderLocation))
+}
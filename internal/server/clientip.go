@@ -0,0 +1,97 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package server
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ClientIP returns the IP address of the client that sent the request.
+// Forwarded headers are only honoured when the immediate peer is a trusted
+// proxy: X-Forwarded-For is walked from the right, skipping trusted proxies,
+// and the first untrusted address is the client. Otherwise the peer address
+// from RemoteAddr is returned, so direct clients cannot spoof their IP.
+// A peer without an IP address, i.e. a client of the Unix socket listener,
+// counts as a trusted proxy: only local processes can reach the socket.
+func ClientIP(c echo.Context, trustedProxies []netip.Prefix) string {
+	return clientIP(c.Request(), trustedProxies)
+}
+
+// ipExtractor adapts ClientIP for echo.Echo.IPExtractor, so c.RealIP()
+// applies the same rules everywhere.
+func ipExtractor(trustedProxies []netip.Prefix) echo.IPExtractor {
+	return func(r *http.Request) string {
+		return clientIP(r, trustedProxies)
+	}
+}
+
+func clientIP(r *http.Request, trustedProxies []netip.Prefix) string {
+	fallback := r.RemoteAddr
+	if peer, ok := parseIP(r.RemoteAddr); ok {
+		if !isTrusted(peer, trustedProxies) {
+			return peer.String()
+		}
+		fallback = peer.String()
+	}
+
+	// Walk the chain from the closest hop outwards
+	hops := forwardedFor(r.Header)
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, valid := parseIP(hops[i])
+		if !valid {
+			// A malformed entry cannot be attributed; stop at the last good hop
+			break
+		}
+		if !isTrusted(addr, trustedProxies) {
+			return addr.String()
+		}
+		fallback = addr.String()
+	}
+	if len(hops) == 0 {
+		if addr, valid := parseIP(r.Header.Get(echo.HeaderXRealIP)); valid {
+			return addr.String()
+		}
+	}
+	return fallback
+}
+
+// forwardedFor returns all X-Forwarded-For entries in order, across repeated headers.
+func forwardedFor(h http.Header) []string {
+	var hops []string
+	for _, value := range h.Values(echo.HeaderXForwardedFor) {
+		for hop := range strings.SplitSeq(value, ",") {
+			if hop = strings.TrimSpace(hop); hop != "" {
+				hops = append(hops, hop)
+			}
+		}
+	}
+	return hops
+}
+
+// parseIP parses an address with or without port, including bracketed IPv6
+// and zones. IPv4-mapped IPv6 addresses are unmapped.
+func parseIP(s string) (netip.Addr, bool) {
+	s = strings.TrimSpace(s)
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		s = host
+	}
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "["), "]")
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.WithZone("").Unmap(), true
+}
+
+func isTrusted(addr netip.Addr, trustedProxies []netip.Prefix) bool {
+	return slices.ContainsFunc(trustedProxies, func(p netip.Prefix) bool {
+		return p.Contains(addr)
+	})
+}
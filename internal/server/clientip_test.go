@@ -0,0 +1,86 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientIP(t *testing.T) {
+	trusted := []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("fd00::/8"),
+		netip.MustParsePrefix("127.0.0.1/32"),
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		xff        []string
+		xRealIP    string
+		want       string
+	}{
+		{"direct client", "203.0.113.7:5555", nil, "", "203.0.113.7"},
+		{"untrusted client spoofing XFF", "203.0.113.7:5555", []string{"1.2.3.4"}, "", "203.0.113.7"},
+		{"untrusted client spoofing X-Real-IP", "203.0.113.7:5555", nil, "1.2.3.4", "203.0.113.7"},
+		{"trusted proxy", "10.0.0.2:443", []string{"198.51.100.20"}, "", "198.51.100.20"},
+		{"trusted proxy chain", "10.0.0.2:443", []string{"198.51.100.20, 10.1.1.1, 10.0.0.3"}, "", "198.51.100.20"},
+		{"spoofed entry left of real client", "10.0.0.2:443", []string{"1.2.3.4, 198.51.100.20"}, "", "198.51.100.20"},
+		{"repeated XFF headers", "10.0.0.2:443", []string{"198.51.100.20", "10.0.0.9"}, "", "198.51.100.20"},
+		{"only trusted hops", "10.0.0.2:443", []string{"10.0.0.5"}, "", "10.0.0.5"},
+		{"trusted proxy with X-Real-IP", "127.0.0.1:80", nil, "198.51.100.20", "198.51.100.20"},
+		{"trusted proxy without headers", "127.0.0.1:80", nil, "", "127.0.0.1"},
+		{"malformed hop", "10.0.0.2:443", []string{"garbage"}, "", "10.0.0.2"},
+		{"IPv6 direct client", "[2001:db8::1]:5555", []string{"1.2.3.4"}, "", "2001:db8::1"},
+		{"IPv6 trusted proxy", "[fd00::2]:443", []string{"2001:db8::42"}, "", "2001:db8::42"},
+		{"IPv6 client with brackets and port", "[fd00::2]:443", []string{"[2001:db8::42]:1234"}, "", "2001:db8::42"},
+		{"IPv4-mapped peer", "[::ffff:10.0.0.2]:443", []string{"198.51.100.20"}, "", "198.51.100.20"},
+		{"unix socket proxy", "@", []string{"198.51.100.20"}, "", "198.51.100.20"},
+		{"unix socket proxy chain", "@", []string{"198.51.100.20, 10.0.0.3"}, "", "198.51.100.20"},
+		{"unix socket proxy with X-Real-IP", "@", nil, "198.51.100.20", "198.51.100.20"},
+		{"unix socket without headers", "@", nil, "", "@"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for _, v := range tt.xff {
+				req.Header.Add(echo.HeaderXForwardedFor, v)
+			}
+			if tt.xRealIP != "" {
+				req.Header.Set(echo.HeaderXRealIP, tt.xRealIP)
+			}
+			c := echo.New().NewContext(req, httptest.NewRecorder())
+
+			assert.Equal(t, tt.want, ClientIP(c, trusted))
+		})
+	}
+}
+
+func TestClientIP_NoTrustedProxies(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "127.0.0.1:80"
+	req.Header.Set(echo.HeaderXForwardedFor, "1.2.3.4")
+	c := echo.New().NewContext(req, httptest.NewRecorder())
+
+	assert.Equal(t, "127.0.0.1", ClientIP(c, nil))
+}
+
+func TestIPExtractor_UsedByRealIP(t *testing.T) {
+	e := echo.New()
+	e.IPExtractor = ipExtractor([]netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "10.0.0.2:443"
+	req.Header.Set(echo.HeaderXForwardedFor, "198.51.100.20")
+
+	assert.Equal(t, "198.51.100.20", e.NewContext(req, httptest.NewRecorder()).RealIP())
+}
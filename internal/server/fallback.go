@@ -0,0 +1,112 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package server
+
+import (
+	"errors"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/oliverandrich/go-webapp-template/internal/config"
+)
+
+// spaIndex is the page served for unknown paths when the SPA fallback is on.
+const spaIndex = "index.html"
+
+// webManifest is the file name of the web app manifest in the static assets.
+const webManifest = "manifest.webmanifest"
+
+// mimeApplicationManifestJSON is the media type of web app manifests.
+const mimeApplicationManifestJSON = "application/manifest+json"
+
+// spaExcludedPrefixes never fall back to the SPA index page, so API clients
+// and missing assets still get a 404.
+var spaExcludedPrefixes = []string{"/api/", "/auth/", "/static/"}
+
+// faviconHandler serves /favicon.ico from the configured file, or from
+// favicon.ico in the static assets when none is configured.
+func faviconHandler(cfg *config.ServerConfig, static fs.FS) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		c.Response().Header().Set(echo.HeaderCacheControl, iconCacheControl(cfg.IconMaxAge))
+		if cfg.Favicon != "" {
+			return c.File(cfg.Favicon)
+		}
+		return echo.StaticFileHandler("favicon.ico", static)(c)
+	}
+}
+
+// manifestHandler serves the web app manifest from the configured file, or
+// from manifest.webmanifest in the static assets when none is configured.
+func manifestHandler(cfg *config.ServerConfig, static fs.FS) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		var manifest []byte
+		var err error
+		if cfg.Manifest != "" {
+			manifest, err = os.ReadFile(cfg.Manifest)
+		} else {
+			manifest, err = fs.ReadFile(static, webManifest)
+		}
+		if errors.Is(err, fs.ErrNotExist) {
+			return echo.ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		c.Response().Header().Set(echo.HeaderCacheControl, iconCacheControl(cfg.IconMaxAge))
+		return c.Blob(http.StatusOK, mimeApplicationManifestJSON, manifest)
+	}
+}
+
+// iconCacheControl returns the Cache-Control value for icons and the
+// manifest. They keep stable URLs, so they are cached briefly instead of
+// forever like content-hashed assets.
+func iconCacheControl(maxAge int) string {
+	if maxAge <= 0 {
+		return "no-cache"
+	}
+	return "public, max-age=" + strconv.Itoa(maxAge)
+}
+
+// isIconAsset reports whether a static path is an icon or manifest, which
+// browsers request under a fixed name: anything below /static/icons/ and
+// .ico and .webmanifest files.
+func isIconAsset(p string) bool {
+	ext := path.Ext(p)
+	return strings.HasPrefix(p, "/static/icons/") || ext == ".ico" || ext == ".webmanifest"
+}
+
+// spaFallback answers GET and HEAD requests for unknown paths with the
+// index.html of the static assets, so client-side routes survive a reload.
+// It returns nil when the fallback is disabled.
+func spaFallback(cfg *config.ServerConfig, static fs.FS) echo.HandlerFunc {
+	if !cfg.SPAFallback {
+		return nil
+	}
+	return func(c echo.Context) error {
+		req := c.Request()
+		if req.Method != http.MethodGet && req.Method != http.MethodHead {
+			return echo.ErrNotFound
+		}
+		for _, prefix := range spaExcludedPrefixes {
+			if strings.HasPrefix(req.URL.Path, prefix) || req.URL.Path == strings.TrimSuffix(prefix, "/") {
+				return echo.ErrNotFound
+			}
+		}
+
+		index, err := fs.ReadFile(static, spaIndex)
+		if errors.Is(err, fs.ErrNotExist) {
+			return echo.ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		c.Response().Header().Set(echo.HeaderCacheControl, "no-cache")
+		return c.HTMLBlob(http.StatusOK, index)
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/labstack/echo/v4"
+	"github.com/oliverandrich/go-webapp-template/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testStatic = fstest.MapFS{
+	"favicon.ico":          {Data: []byte("embedded-icon")},
+	"index.html":           {Data: []byte("<!doctype html><div id=app></div>")},
+	"manifest.webmanifest": {Data: []byte(`{"name":"App"}`)},
+}
+
+// newFallbackEcho routes a page, a group, the favicon and the manifest like
+// setupRoutes.
+func newFallbackEcho(cfg *config.ServerConfig) *echo.Echo {
+	e := echo.New()
+	ok := func(c echo.Context) error { return c.String(http.StatusOK, "route") }
+	e.GET("/dashboard", ok)
+	auth := e.Group("/auth", func(next echo.HandlerFunc) echo.HandlerFunc { return next })
+	auth.POST("/logout", ok)
+	e.Match([]string{http.MethodGet, http.MethodHead}, "/favicon.ico", faviconHandler(cfg, testStatic))
+	e.Match([]string{http.MethodGet, http.MethodHead}, "/manifest.webmanifest", manifestHandler(cfg, testStatic))
+	setupMethodNotAllowed(e, spaFallback(cfg, testStatic), map[string]*echo.Group{"/auth": auth})
+	return e
+}
+
+func TestFavicon_FromStaticAssets(t *testing.T) {
+	e := newFallbackEcho(&config.ServerConfig{IconMaxAge: 86400})
+
+	rec := serve(e, httptest.NewRequest(http.MethodGet, "/favicon.ico", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "embedded-icon", rec.Body.String())
+	assert.Equal(t, "public, max-age=86400", rec.Header().Get(echo.HeaderCacheControl))
+}
+
+func TestFavicon_ConfiguredFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "icon.ico")
+	require.NoError(t, os.WriteFile(path, []byte("custom-icon"), 0o600))
+	e := newFallbackEcho(&config.ServerConfig{Favicon: path})
+
+	rec := serve(e, httptest.NewRequest(http.MethodGet, "/favicon.ico", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "custom-icon", rec.Body.String())
+}
+
+func TestFavicon_MissingFile(t *testing.T) {
+	e := newFallbackEcho(&config.ServerConfig{Favicon: filepath.Join(t.TempDir(), "missing.ico")})
+
+	assert.Equal(t, http.StatusNotFound, serve(e, httptest.NewRequest(http.MethodGet, "/favicon.ico", nil)).Code)
+}
+
+func TestManifest_FromStaticAssets(t *testing.T) {
+	e := newFallbackEcho(&config.ServerConfig{IconMaxAge: 3600})
+
+	rec := serve(e, httptest.NewRequest(http.MethodGet, "/manifest.webmanifest", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"name":"App"}`, rec.Body.String())
+	assert.Equal(t, "application/manifest+json", rec.Header().Get(echo.HeaderContentType))
+	assert.Equal(t, "public, max-age=3600", rec.Header().Get(echo.HeaderCacheControl))
+}
+
+func TestManifest_ConfiguredFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.webmanifest")
+	require.NoError(t, os.WriteFile(path, []byte(`{"name":"Custom"}`), 0o600))
+	e := newFallbackEcho(&config.ServerConfig{Manifest: path})
+
+	rec := serve(e, httptest.NewRequest(http.MethodGet, "/manifest.webmanifest", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"name":"Custom"}`, rec.Body.String())
+	assert.Equal(t, "application/manifest+json", rec.Header().Get(echo.HeaderContentType))
+	assert.Equal(t, "no-cache", rec.Header().Get(echo.HeaderCacheControl))
+}
+
+func TestManifest_MissingFile(t *testing.T) {
+	e := newFallbackEcho(&config.ServerConfig{Manifest: filepath.Join(t.TempDir(), "missing.webmanifest")})
+
+	assert.Equal(t, http.StatusNotFound, serve(e, httptest.NewRequest(http.MethodGet, "/manifest.webmanifest", nil)).Code)
+}
+
+func TestSPAFallback(t *testing.T) {
+	e := newFallbackEcho(&config.ServerConfig{SPAFallback: true})
+
+	tests := []struct {
+		method, path string
+		wantStatus   int
+		wantBody     string
+	}{
+		{http.MethodGet, "/settings/profile", http.StatusOK, "<!doctype html><div id=app></div>"},
+		{http.MethodGet, "/", http.StatusOK, "<!doctype html><div id=app></div>"},
+		{http.MethodGet, "/dashboard", http.StatusOK, "route"},
+		{http.MethodPost, "/dashboard", http.StatusMethodNotAllowed, ""},
+		{http.MethodGet, "/auth/logout", http.StatusMethodNotAllowed, ""},
+		{http.MethodPost, "/settings/profile", http.StatusNotFound, ""},
+		{http.MethodGet, "/api/users", http.StatusNotFound, ""},
+		{http.MethodGet, "/api", http.StatusNotFound, ""},
+		{http.MethodGet, "/auth/unknown", http.StatusNotFound, ""},
+		{http.MethodGet, "/static/missing.js", http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			rec := serve(e, httptest.NewRequest(tt.method, tt.path, nil))
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			if tt.wantBody != "" {
+				assert.Equal(t, tt.wantBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestSPAFallback_IndexIsNotCached(t *testing.T) {
+	e := newFallbackEcho(&config.ServerConfig{SPAFallback: true})
+
+	rec := serve(e, httptest.NewRequest(http.MethodGet, "/settings/profile", nil))
+
+	assert.Equal(t, "no-cache", rec.Header().Get(echo.HeaderCacheControl))
+	assert.Contains(t, rec.Header().Get(echo.HeaderContentType), "text/html")
+}
+
+func TestSPAFallback_Disabled(t *testing.T) {
+	e := newFallbackEcho(&config.ServerConfig{})
+
+	assert.Equal(t, http.StatusNotFound, serve(e, httptest.NewRequest(http.MethodGet, "/settings/profile", nil)).Code)
+	assert.Equal(t, http.StatusMethodNotAllowed, serve(e, httptest.NewRequest(http.MethodPost, "/dashboard", nil)).Code)
+}
@@ -0,0 +1,137 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package server
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/oliverandrich/go-webapp-template/internal/config"
+)
+
+// compressibleTypes lists the media types worth compressing. Everything else
+// (images, fonts, archives, video) is already compressed and sent as is.
+var compressibleTypes = []string{
+	"text/",
+	"application/json",
+	"application/javascript",
+	"application/xml",
+	"application/manifest+json",
+	"application/wasm",
+	"image/svg+xml",
+}
+
+// isCompressible reports whether a response with the given Content-Type
+// should be compressed.
+func isCompressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, t := range compressibleTypes {
+		if (strings.HasSuffix(t, "/") && strings.HasPrefix(mediaType, t)) || mediaType == t {
+			return true
+		}
+	}
+	return false
+}
+
+// acceptsGzip reports whether the Accept-Encoding header allows gzip,
+// honouring q=0 for both "gzip" and the "*" wildcard.
+func acceptsGzip(header string) bool {
+	wildcard := false
+	for part := range strings.SplitSeq(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding != "gzip" && coding != "*" {
+			continue
+		}
+		accepted := true
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if v, err := strconv.ParseFloat(q, 64); err == nil && v == 0 {
+				accepted = false
+			}
+		}
+		if coding == "gzip" {
+			return accepted
+		}
+		wildcard = accepted
+	}
+	return wildcard
+}
+
+// gzipMiddleware compresses responses with the configured level once they
+// exceed the minimum size. Clients that do not accept gzip and responses
+// with an incompressible Content-Type are passed through unchanged.
+func gzipMiddleware(cfg *config.ServerConfig) echo.MiddlewareFunc {
+	if cfg.GzipLevel == 0 {
+		return func(next echo.HandlerFunc) echo.HandlerFunc { return next }
+	}
+
+	gzip := middleware.GzipWithConfig(middleware.GzipConfig{
+		Level:     cfg.GzipLevel,
+		MinLength: cfg.GzipMinSize,
+		Skipper: func(c echo.Context) bool {
+			return !acceptsGzip(c.Request().Header.Get(echo.HeaderAcceptEncoding))
+		},
+	})
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return gzip(func(c echo.Context) error {
+			res := c.Response()
+			if gz, ok := res.Writer.(interface{ Unwrap() http.ResponseWriter }); ok {
+				res.Writer = &contentTypeSwitch{gzip: res.Writer, plain: gz.Unwrap()}
+			}
+			return next(c)
+		})
+	}
+}
+
+// contentTypeSwitch sits in front of the gzip writer and routes the response
+// around it once the Content-Type turns out to be incompressible.
+type contentTypeSwitch struct {
+	gzip   http.ResponseWriter
+	plain  http.ResponseWriter
+	target http.ResponseWriter // nil until the first WriteHeader or Write
+}
+
+func (w *contentTypeSwitch) Header() http.Header {
+	return w.plain.Header()
+}
+
+func (w *contentTypeSwitch) choose(body []byte) http.ResponseWriter {
+	if w.target == nil {
+		contentType := w.Header().Get(echo.HeaderContentType)
+		if contentType == "" && body != nil {
+			contentType = http.DetectContentType(body)
+			w.Header().Set(echo.HeaderContentType, contentType)
+		}
+		if contentType == "" || isCompressible(contentType) {
+			w.target = w.gzip
+		} else {
+			w.target = w.plain
+		}
+	}
+	return w.target
+}
+
+func (w *contentTypeSwitch) WriteHeader(code int) {
+	w.choose(nil).WriteHeader(code)
+}
+
+func (w *contentTypeSwitch) Write(b []byte) (int, error) {
+	return w.choose(b).Write(b)
+}
+
+func (w *contentTypeSwitch) Flush() {
+	_ = http.NewResponseController(w.choose(nil)).Flush()
+}
+
+func (w *contentTypeSwitch) Unwrap() http.ResponseWriter {
+	return w.choose(nil)
+}
@@ -0,0 +1,112 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/oliverandrich/go-webapp-template/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// serveGzip runs a single request through gzipMiddleware.
+func serveGzip(t *testing.T, cfg *config.ServerConfig, acceptEncoding string, handler echo.HandlerFunc) *httptest.ResponseRecorder {
+	t.Helper()
+	e := echo.New()
+	e.Use(gzipMiddleware(cfg))
+	e.GET("/", handler)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if acceptEncoding != "" {
+		req.Header.Set(echo.HeaderAcceptEncoding, acceptEncoding)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestGzipMiddleware_CompressesLargeHTML(t *testing.T) {
+	body := "<html>" + strings.Repeat("hello world ", 500) + "</html>"
+
+	rec := serveGzip(t, &config.ServerConfig{GzipLevel: 9, GzipMinSize: 1024}, "gzip, deflate", func(c echo.Context) error {
+		return c.HTML(http.StatusOK, body)
+	})
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "gzip", rec.Header().Get(echo.HeaderContentEncoding))
+	assert.Less(t, rec.Body.Len(), len(body))
+
+	zr, err := gzip.NewReader(rec.Body)
+	require.NoError(t, err)
+	decoded, err := io.ReadAll(zr)
+	require.NoError(t, err)
+	assert.Equal(t, body, string(decoded))
+}
+
+func TestGzipMiddleware_SkipsBelowThreshold(t *testing.T) {
+	rec := serveGzip(t, &config.ServerConfig{GzipLevel: -1, GzipMinSize: 1024}, "gzip", func(c echo.Context) error {
+		return c.HTML(http.StatusOK, "<p>tiny</p>")
+	})
+
+	assert.Empty(t, rec.Header().Get(echo.HeaderContentEncoding))
+	assert.Equal(t, "<p>tiny</p>", rec.Body.String())
+}
+
+func TestGzipMiddleware_SkipsImages(t *testing.T) {
+	png := bytes.Repeat([]byte{0x89, 'P', 'N', 'G'}, 1024)
+
+	rec := serveGzip(t, &config.ServerConfig{GzipLevel: -1, GzipMinSize: 0}, "gzip", func(c echo.Context) error {
+		return c.Blob(http.StatusOK, "image/png", png)
+	})
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Header().Get(echo.HeaderContentEncoding))
+	assert.Equal(t, png, rec.Body.Bytes())
+}
+
+func TestGzipMiddleware_RespectsAcceptEncoding(t *testing.T) {
+	body := strings.Repeat("a", 4096)
+	handler := func(c echo.Context) error { return c.String(http.StatusOK, body) }
+	cfg := &config.ServerConfig{GzipLevel: -1, GzipMinSize: 0}
+
+	for _, accept := range []string{"", "br", "gzip;q=0", "*;q=0", "br, *;q=0"} {
+		rec := serveGzip(t, cfg, accept, handler)
+		assert.Empty(t, rec.Header().Get(echo.HeaderContentEncoding), "Accept-Encoding %q", accept)
+		assert.Equal(t, body, rec.Body.String())
+	}
+
+	for _, accept := range []string{"gzip", "br;q=1.0, gzip;q=0.8"} {
+		rec := serveGzip(t, cfg, accept, handler)
+		assert.Equal(t, "gzip", rec.Header().Get(echo.HeaderContentEncoding), "Accept-Encoding %q", accept)
+	}
+}
+
+func TestGzipMiddleware_Disabled(t *testing.T) {
+	body := strings.Repeat("a", 4096)
+
+	rec := serveGzip(t, &config.ServerConfig{GzipLevel: 0}, "gzip", func(c echo.Context) error {
+		return c.String(http.StatusOK, body)
+	})
+
+	assert.Empty(t, rec.Header().Get(echo.HeaderContentEncoding))
+	assert.Equal(t, body, rec.Body.String())
+}
+
+func TestIsCompressible(t *testing.T) {
+	assert.True(t, isCompressible("text/html; charset=UTF-8"))
+	assert.True(t, isCompressible("application/json"))
+	assert.True(t, isCompressible("image/svg+xml"))
+	assert.False(t, isCompressible("image/png"))
+	assert.False(t, isCompressible("font/woff2"))
+	assert.False(t, isCompressible("application/zip"))
+	assert.False(t, isCompressible(""))
+}
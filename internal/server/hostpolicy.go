@@ -0,0 +1,73 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// maxPatternHosts bounds the number of distinct hosts a "*.<domain>" entry
+// admits per process. Anyone pointing a name below the domain at us can make
+// autocert order a certificate, so the patterns must not allow unbounded
+// issuance (and with it exhausting the CA's rate limits).
+const maxPatternHosts = 100
+
+// acmeHostPolicy returns the autocert host policy for the server host and
+// the configured allowed hosts. Entries of the form "*.example.com" admit
+// exactly one additional label ("shop.example.com", not "a.b.example.com"),
+// up to maxPatternHosts distinct names.
+func acmeHostPolicy(host string, allowed []string) autocert.HostPolicy {
+	exact := map[string]bool{normalizeHost(host): true}
+	var suffixes []string
+	for _, entry := range allowed {
+		entry = normalizeHost(entry)
+		if apex, ok := strings.CutPrefix(entry, "*."); ok {
+			suffixes = append(suffixes, "."+apex)
+		} else if entry != "" {
+			exact[entry] = true
+		}
+	}
+
+	var mu sync.Mutex
+	admitted := make(map[string]bool)
+
+	return func(_ context.Context, name string) error {
+		name = normalizeHost(name)
+		if exact[name] {
+			return nil
+		}
+		if !matchesPattern(name, suffixes) {
+			return fmt.Errorf("acme: host %q not allowed", name)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		if !admitted[name] && len(admitted) >= maxPatternHosts {
+			return fmt.Errorf("acme: host %q rejected, pattern limit of %d hosts reached", name, maxPatternHosts)
+		}
+		admitted[name] = true
+		return nil
+	}
+}
+
+// matchesPattern reports whether name is a single label followed by one of
+// the suffixes.
+func matchesPattern(name string, suffixes []string) bool {
+	for _, suffix := range suffixes {
+		label, ok := strings.CutSuffix(name, suffix)
+		if ok && label != "" && !strings.Contains(label, ".") {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeHost(host string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(host)), ".")
+}
@@ -0,0 +1,69 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package server
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestACMEHostPolicy(t *testing.T) {
+	policy := acmeHostPolicy("example.com", []string{"www.example.com", "*.apps.example.org"})
+	ctx := context.Background()
+
+	tests := []struct {
+		host    string
+		allowed bool
+	}{
+		{"example.com", true},
+		{"EXAMPLE.com.", true},
+		{"www.example.com", true},
+		{"shop.apps.example.org", true},
+		{"apps.example.org", false},
+		{"a.b.apps.example.org", false},
+		{"evil.com", false},
+		{"mail.example.com", false},
+		{"xapps.example.org", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			err := policy(ctx, tt.host)
+			if tt.allowed {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestACMEHostPolicy_PatternLimit(t *testing.T) {
+	policy := acmeHostPolicy("example.com", []string{"*.example.com"})
+	ctx := context.Background()
+
+	for i := range maxPatternHosts {
+		require.NoError(t, policy(ctx, "h"+strconv.Itoa(i)+".example.com"))
+	}
+
+	assert.Error(t, policy(ctx, "one-too-many.example.com"))
+	assert.NoError(t, policy(ctx, "h0.example.com"), "already admitted hosts stay allowed")
+	assert.NoError(t, policy(ctx, "example.com"), "exact hosts are not limited")
+}
+
+func TestSetupACME_AllowedHosts(t *testing.T) {
+	cfg := newACMEConfig(t)
+	cfg.TLS.AllowedHosts = []string{"www.example.com"}
+
+	result, err := setupACME(cfg)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	assert.NoError(t, result.CertManager.HostPolicy(ctx, "www.example.com"))
+	assert.Error(t, result.CertManager.HostPolicy(ctx, "other.example.com"))
+}
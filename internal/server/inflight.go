@@ -0,0 +1,55 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package server
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"golang.org/x/sync/semaphore"
+)
+
+// maxInFlightRetryAfter is the Retry-After value, in seconds, sent with
+// requests rejected by maxInFlightMiddleware.
+const maxInFlightRetryAfter = "1"
+
+// maxInFlightMiddleware sheds load by answering requests beyond n
+// concurrently running ones with 503 and a Retry-After header instead of
+// queueing them. Requests below one of excludePrefixes, such as long-lived
+// event streams that would hold a slot each, are not counted. n <= 0
+// disables the limit.
+func maxInFlightMiddleware(n int, excludePrefixes []string) echo.MiddlewareFunc {
+	exclude := make([]string, 0, len(excludePrefixes))
+	for _, prefix := range excludePrefixes {
+		if prefix = strings.TrimSpace(prefix); prefix != "" {
+			exclude = append(exclude, prefix)
+		}
+	}
+
+	// Echo wraps handlers in Use middleware per request, so the semaphore
+	// has to be shared outside the wrapper
+	sem := semaphore.NewWeighted(int64(max(n, 1)))
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		if n <= 0 {
+			return next
+		}
+		return func(c echo.Context) error {
+			path := c.Request().URL.Path
+			for _, prefix := range exclude {
+				if strings.HasPrefix(path, prefix) {
+					return next(c)
+				}
+			}
+			if !sem.TryAcquire(1) {
+				slog.Warn("too many requests in flight", "method", c.Request().Method, "path", path, "limit", n)
+				c.Response().Header().Set("Retry-After", maxInFlightRetryAfter)
+				return echo.NewHTTPError(http.StatusServiceUnavailable, "server is busy")
+			}
+			defer sem.Release(1)
+			return next(c)
+		}
+	}
+}
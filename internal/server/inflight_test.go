@@ -0,0 +1,111 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newInFlightEcho serves /block and /events/block behind
+// maxInFlightMiddleware, with /events excluded from the limit. Each request
+// to them signals on the returned started channel and waits for a value on
+// release before it answers.
+func newInFlightEcho(n int) (e *echo.Echo, started <-chan struct{}, release chan<- struct{}) {
+	startedCh := make(chan struct{})
+	releaseCh := make(chan struct{})
+	block := func(c echo.Context) error {
+		startedCh <- struct{}{}
+		<-releaseCh
+		return c.NoContent(http.StatusOK)
+	}
+	e = echo.New()
+	e.Use(maxInFlightMiddleware(n, []string{" /events "}))
+	e.GET("/block", block)
+	e.GET("/events/block", block)
+	e.GET("/fast", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+	return e, startedCh, releaseCh
+}
+
+// serveAsync serves req in the background and delivers the recorder once
+// the handler returned.
+func serveAsync(e *echo.Echo, req *http.Request) <-chan *httptest.ResponseRecorder {
+	done := make(chan *httptest.ResponseRecorder, 1)
+	go func() {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		done <- rec
+	}()
+	return done
+}
+
+func awaitStarted(t *testing.T, started <-chan struct{}) {
+	t.Helper()
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("handler did not start")
+	}
+}
+
+func TestMaxInFlightMiddleware_RejectsWhenSaturated(t *testing.T) {
+	e, started, release := newInFlightEcho(2)
+	first := serveAsync(e, httptest.NewRequest(http.MethodGet, "/block", nil))
+	awaitStarted(t, started)
+	second := serveAsync(e, httptest.NewRequest(http.MethodGet, "/block", nil))
+	awaitStarted(t, started)
+
+	rec := serve(e, httptest.NewRequest(http.MethodGet, "/fast", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, "1", rec.Header().Get("Retry-After"))
+
+	// Once a request completes, its slot is free again
+	release <- struct{}{}
+	require.Equal(t, http.StatusOK, (<-first).Code)
+	assert.Equal(t, http.StatusOK, serve(e, httptest.NewRequest(http.MethodGet, "/fast", nil)).Code)
+
+	release <- struct{}{}
+	require.Equal(t, http.StatusOK, (<-second).Code)
+}
+
+func TestMaxInFlightMiddleware_ExcludedPrefixNotCounted(t *testing.T) {
+	e, started, release := newInFlightEcho(1)
+	done := serveAsync(e, httptest.NewRequest(http.MethodGet, "/events/block", nil))
+	awaitStarted(t, started)
+
+	assert.Equal(t, http.StatusOK, serve(e, httptest.NewRequest(http.MethodGet, "/fast", nil)).Code)
+
+	release <- struct{}{}
+	require.Equal(t, http.StatusOK, (<-done).Code)
+}
+
+func TestMaxInFlightMiddleware_AcceptHeaderDoesNotBypass(t *testing.T) {
+	e, started, release := newInFlightEcho(1)
+	done := serveAsync(e, httptest.NewRequest(http.MethodGet, "/block", nil))
+	awaitStarted(t, started)
+
+	req := httptest.NewRequest(http.MethodGet, "/fast", nil)
+	req.Header.Set(echo.HeaderAccept, "text/event-stream")
+	assert.Equal(t, http.StatusServiceUnavailable, serve(e, req).Code)
+
+	release <- struct{}{}
+	require.Equal(t, http.StatusOK, (<-done).Code)
+}
+
+func TestMaxInFlightMiddleware_Disabled(t *testing.T) {
+	e, started, release := newInFlightEcho(0)
+	done := serveAsync(e, httptest.NewRequest(http.MethodGet, "/block", nil))
+	awaitStarted(t, started)
+
+	assert.Equal(t, http.StatusOK, serve(e, httptest.NewRequest(http.MethodGet, "/fast", nil)).Code)
+
+	release <- struct{}{}
+	require.Equal(t, http.StatusOK, (<-done).Code)
+}
@@ -0,0 +1,72 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package server
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// shutdownTimeout bounds the graceful shutdown of the HTTP servers and the
+// shutdown hooks that run afterwards.
+const shutdownTimeout = 10 * time.Second
+
+// webhookDrainTimeout bounds how long shutdown waits for pending webhook
+// deliveries, so their retries cannot use up the whole shutdown timeout.
+const webhookDrainTimeout = shutdownTimeout / 2
+
+// Lifecycle collects cleanup callbacks of subsystems started during setup
+// (webhook deliveries, WAL checkpoints, certificate reloads, ...). They run
+// after the HTTP servers have stopped.
+type Lifecycle struct {
+	mu    sync.Mutex
+	hooks []func(context.Context) error
+}
+
+// NewLifecycle creates an empty Lifecycle.
+func NewLifecycle() *Lifecycle {
+	return &Lifecycle{}
+}
+
+// OnShutdown registers fn to run on shutdown. Hooks run in reverse order of
+// registration, so subsystems stop before the ones they depend on.
+func (l *Lifecycle) OnShutdown(fn func(context.Context) error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.hooks = append(l.hooks, fn)
+}
+
+// Shutdown runs the registered hooks in reverse order and logs their errors.
+// Hooks are expected to honour ctx; once it is done, a hook still running is
+// abandoned and the remaining hooks are skipped. Hooks that may wait on
+// outside systems should therefore bound themselves to a share of ctx. All
+// errors are returned joined. The hooks are cleared, so a second call does
+// nothing.
+func (l *Lifecycle) Shutdown(ctx context.Context) error {
+	l.mu.Lock()
+	hooks := l.hooks
+	l.hooks = nil
+	l.mu.Unlock()
+
+	var errs []error
+	for i := len(hooks) - 1; i >= 0; i-- {
+		done := make(chan error, 1)
+		go func() { done <- hooks[i](ctx) }()
+
+		select {
+		case err := <-done:
+			if err != nil {
+				slog.Error("shutdown hook failed", "error", err)
+				errs = append(errs, err)
+			}
+		case <-ctx.Done():
+			slog.Error("shutdown hooks timed out", "remaining", i+1)
+			return errors.Join(append(errs, ctx.Err())...)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,110 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package server
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLifecycle_ReverseOrder(t *testing.T) {
+	l := NewLifecycle()
+	var order []string
+	l.OnShutdown(func(context.Context) error {
+		order = append(order, "first")
+		return nil
+	})
+	l.OnShutdown(func(context.Context) error {
+		order = append(order, "second")
+		return nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	require.NoError(t, l.Shutdown(ctx))
+	assert.Equal(t, []string{"second", "first"}, order)
+}
+
+func TestLifecycle_CollectsErrors(t *testing.T) {
+	l := NewLifecycle()
+	errFirst := errors.New("first failed")
+	ran := false
+	l.OnShutdown(func(context.Context) error { return errFirst })
+	l.OnShutdown(func(context.Context) error {
+		ran = true
+		return nil
+	})
+
+	err := l.Shutdown(context.Background())
+
+	require.ErrorIs(t, err, errFirst)
+	assert.True(t, ran, "a failing hook must not stop the others")
+}
+
+func TestLifecycle_Timeout(t *testing.T) {
+	l := NewLifecycle()
+	skipped := true
+	l.OnShutdown(func(context.Context) error {
+		skipped = false
+		return nil
+	})
+	l.OnShutdown(func(context.Context) error {
+		// Ignores ctx and never returns in time
+		time.Sleep(time.Second)
+		return nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := l.Shutdown(ctx)
+
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+	assert.True(t, skipped)
+}
+
+func TestLifecycle_BoundedHookLeavesTimeForOthers(t *testing.T) {
+	l := NewLifecycle()
+	flushed := false
+	l.OnShutdown(func(context.Context) error {
+		flushed = true
+		return nil
+	})
+	l.OnShutdown(func(ctx context.Context) error {
+		// Blocks like a stuck webhook delivery, but only for its own share
+		ctx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+		defer cancel()
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	err := l.Shutdown(ctx)
+
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.True(t, flushed, "hooks registered earlier must still run")
+}
+
+func TestLifecycle_ShutdownTwice(t *testing.T) {
+	l := NewLifecycle()
+	calls := 0
+	l.OnShutdown(func(context.Context) error {
+		calls++
+		return nil
+	})
+
+	require.NoError(t, l.Shutdown(context.Background()))
+	require.NoError(t, l.Shutdown(context.Background()))
+	assert.Equal(t, 1, calls)
+}
@@ -4,16 +4,19 @@
 package server
 
 import (
+	"context"
 	"log/slog"
 	"os"
 
 	"github.com/lmittmann/tint"
+	"github.com/oliverandrich/go-webapp-template/internal/appcontext"
+	"github.com/oliverandrich/go-webapp-template/internal/config"
 )
 
 // setupLogger configures the global slog logger.
-func setupLogger(level, format string) {
+func setupLogger(cfg *config.LogConfig) {
 	var logLevel slog.Level
-	switch level {
+	switch cfg.Level {
 	case "debug":
 		logLevel = slog.LevelDebug
 	case "warn":
@@ -25,11 +28,42 @@ func setupLogger(level, format string) {
 	}
 
 	var handler slog.Handler
-	if format == "json" {
+	if cfg.Format == "json" {
 		handler = slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: logLevel})
 	} else {
 		handler = tint.NewHandler(os.Stdout, &tint.Options{Level: logLevel})
 	}
 
-	slog.SetDefault(slog.New(handler))
+	slog.SetDefault(slog.New(&requestIDHandler{newRedactHandler(handler, cfg.RedactKeys)}))
+}
+
+// requestIDHandler adds a request_id attribute to records logged with a
+// request context, unless the record already carries one.
+type requestIDHandler struct {
+	slog.Handler
+}
+
+func (h *requestIDHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id, ok := appcontext.RequestIDFrom(ctx); ok && id != "" && !hasAttr(r, "request_id") {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *requestIDHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &requestIDHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h *requestIDHandler) WithGroup(name string) slog.Handler {
+	return &requestIDHandler{h.Handler.WithGroup(name)}
+}
+
+// hasAttr reports whether the record has a top-level attribute with the given key.
+func hasAttr(r slog.Record, key string) bool {
+	found := false
+	r.Attrs(func(a slog.Attr) bool {
+		found = a.Key == key
+		return !found
+	})
+	return found
 }
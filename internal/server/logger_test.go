@@ -0,0 +1,88 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/oliverandrich/go-webapp-template/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// captureLogs routes the default slog logger into a buffer for the test.
+func captureLogs(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(&requestIDHandler{slog.NewJSONHandler(&buf, nil)}))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+	return &buf
+}
+
+// logLines decodes the JSON log records written to buf.
+func logLines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var lines []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var record map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &record))
+		lines = append(lines, record)
+	}
+	return lines
+}
+
+func TestRequestLogger_IncludesRequestID(t *testing.T) {
+	buf := captureLogs(t)
+
+	e := echo.New()
+	e.Use(requestID())
+	e.Use(requestLogger(&config.LogConfig{}))
+	e.GET("/", func(c echo.Context) error {
+		slog.InfoContext(c.Request().Context(), "inside handler")
+		return c.NoContent(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	id := rec.Header().Get(echo.HeaderXRequestID)
+	require.NotEmpty(t, id)
+
+	lines := logLines(t, buf)
+	require.Len(t, lines, 2)
+	assert.Equal(t, "inside handler", lines[0]["msg"])
+	assert.Equal(t, id, lines[0]["request_id"])
+	assert.Equal(t, "request", lines[1]["msg"])
+	assert.Equal(t, id, lines[1]["request_id"])
+}
+
+func TestRequestID_KeepsIncomingHeader(t *testing.T) {
+	buf := captureLogs(t)
+
+	e := echo.New()
+	e.Use(requestID())
+	e.Use(requestLogger(&config.LogConfig{}))
+	e.GET("/", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(echo.HeaderXRequestID, "upstream-id")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, "upstream-id", rec.Header().Get(echo.HeaderXRequestID))
+	lines := logLines(t, buf)
+	require.Len(t, lines, 1)
+	assert.Equal(t, "upstream-id", lines[0]["request_id"])
+}
@@ -0,0 +1,65 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/oliverandrich/go-webapp-template/internal/appcontext"
+	"github.com/oliverandrich/go-webapp-template/internal/handlers"
+	"github.com/oliverandrich/go-webapp-template/internal/htmx"
+	"github.com/oliverandrich/go-webapp-template/internal/i18n"
+	"github.com/oliverandrich/go-webapp-template/internal/services/settings"
+	"github.com/oliverandrich/go-webapp-template/internal/templates"
+)
+
+// maintenanceExemptPaths stay reachable in maintenance mode: health checks
+// for the orchestrator and the login flow, so administrators can sign in.
+var maintenanceExemptPaths = map[string]bool{
+	"/health":                true,
+	"/healthz":               true,
+	"/ready":                 true,
+	"/auth/login":            true,
+	"/auth/login/begin":      true,
+	"/auth/login/finish":     true,
+	"/auth/logout":           true,
+	"/api/auth/login/begin":  true,
+	"/api/auth/login/finish": true,
+}
+
+// maintenanceMiddleware answers with 503 and a maintenance page while
+// maintenance mode is on, either forced by configuration or switched on at
+// runtime. Administrators keep full access. htmx requests are told to
+// refresh, so the whole page is replaced by the maintenance page. It must
+// run after AuthMiddleware.
+func maintenanceMiddleware(forced bool, settingsSvc *settings.Service) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if !forced && (settingsSvc == nil || !settingsSvc.MaintenanceEnabled()) {
+				return next(c)
+			}
+			path := c.Request().URL.Path
+			if maintenanceExemptPaths[path] || strings.HasPrefix(path, "/static/") {
+				return next(c)
+			}
+			if cc, ok := c.(*appcontext.Context); ok && cc.IsAuthenticated() && cc.GetUser().IsAdmin {
+				return next(c)
+			}
+
+			switch {
+			case handlers.WantsJSON(c):
+				return c.JSON(http.StatusServiceUnavailable, map[string]string{
+					"error": i18n.T(c.Request().Context(), "maintenance_message"),
+				})
+			case c.Request().Header.Get(htmx.HeaderRequest) == "true":
+				c.Response().Header().Set(htmx.HeaderRefresh, "true")
+				return c.NoContent(http.StatusServiceUnavailable)
+			default:
+				return handlers.Render(c, http.StatusServiceUnavailable, templates.MaintenancePage())
+			}
+		}
+	}
+}
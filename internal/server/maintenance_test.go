@@ -0,0 +1,127 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/oliverandrich/go-webapp-template/internal/appcontext"
+	"github.com/oliverandrich/go-webapp-template/internal/htmx"
+	"github.com/oliverandrich/go-webapp-template/internal/i18n"
+	"github.com/oliverandrich/go-webapp-template/internal/models"
+	"github.com/oliverandrich/go-webapp-template/internal/services/settings"
+	"github.com/oliverandrich/go-webapp-template/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/language"
+)
+
+// newMaintenanceEcho returns an Echo instance that serves a few routes behind
+// the maintenance middleware, signed in as user (nil = anonymous).
+func newMaintenanceEcho(t *testing.T, forced bool, settingsSvc *settings.Service, user *models.User) *echo.Echo {
+	t.Helper()
+	require.NoError(t, i18n.Init())
+
+	e := echo.New()
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			ctx := i18n.WithLocale(c.Request().Context(), language.English)
+			c.SetRequest(c.Request().WithContext(ctx))
+			return next(&appcontext.Context{Context: c, User: user})
+		}
+	})
+	e.Use(maintenanceMiddleware(forced, settingsSvc))
+
+	ok := func(c echo.Context) error { return c.String(http.StatusOK, "ok") }
+	e.GET("/", ok)
+	e.GET("/health", ok)
+	e.GET("/healthz", ok)
+	e.GET("/static/css/styles.css", ok)
+	e.GET("/auth/login", ok)
+	return e
+}
+
+func newMaintenanceSettings(t *testing.T, enabled bool) *settings.Service {
+	t.Helper()
+	_, repo := testutil.NewTestDB(t)
+	svc, err := settings.NewService(context.Background(), repo)
+	require.NoError(t, err)
+	require.NoError(t, svc.SetMaintenance(context.Background(), enabled))
+	return svc
+}
+
+func serve(e *echo.Echo, req *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestMaintenanceMiddleware_Anonymous(t *testing.T) {
+	e := newMaintenanceEcho(t, false, newMaintenanceSettings(t, true), nil)
+
+	rec := serve(e, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Down for maintenance")
+}
+
+func TestMaintenanceMiddleware_ExemptPaths(t *testing.T) {
+	e := newMaintenanceEcho(t, false, newMaintenanceSettings(t, true), nil)
+
+	for _, path := range []string{"/health", "/healthz", "/static/css/styles.css", "/auth/login"} {
+		t.Run(path, func(t *testing.T) {
+			rec := serve(e, httptest.NewRequest(http.MethodGet, path, nil))
+			assert.Equal(t, http.StatusOK, rec.Code)
+		})
+	}
+}
+
+func TestMaintenanceMiddleware_AdminBypass(t *testing.T) {
+	svc := newMaintenanceSettings(t, true)
+
+	admin := newMaintenanceEcho(t, false, svc, &models.User{ID: 1, Username: "admin", IsAdmin: true})
+	assert.Equal(t, http.StatusOK, serve(admin, httptest.NewRequest(http.MethodGet, "/", nil)).Code)
+
+	user := newMaintenanceEcho(t, false, svc, &models.User{ID: 2, Username: "user"})
+	assert.Equal(t, http.StatusServiceUnavailable, serve(user, httptest.NewRequest(http.MethodGet, "/", nil)).Code)
+}
+
+func TestMaintenanceMiddleware_Htmx(t *testing.T) {
+	e := newMaintenanceEcho(t, false, newMaintenanceSettings(t, true), nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(htmx.HeaderRequest, "true")
+	rec := serve(e, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, "true", rec.Header().Get(htmx.HeaderRefresh))
+	assert.Empty(t, rec.Body.String())
+}
+
+func TestMaintenanceMiddleware_JSON(t *testing.T) {
+	e := newMaintenanceEcho(t, false, newMaintenanceSettings(t, true), nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(echo.HeaderAccept, echo.MIMEApplicationJSON)
+	rec := serve(e, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"error"`)
+}
+
+func TestMaintenanceMiddleware_Off(t *testing.T) {
+	e := newMaintenanceEcho(t, false, newMaintenanceSettings(t, false), nil)
+
+	assert.Equal(t, http.StatusOK, serve(e, httptest.NewRequest(http.MethodGet, "/", nil)).Code)
+}
+
+func TestMaintenanceMiddleware_ForcedByConfig(t *testing.T) {
+	e := newMaintenanceEcho(t, true, newMaintenanceSettings(t, false), nil)
+
+	assert.Equal(t, http.StatusServiceUnavailable, serve(e, httptest.NewRequest(http.MethodGet, "/", nil)).Code)
+}
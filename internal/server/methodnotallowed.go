@@ -0,0 +1,98 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package server
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// setupMethodNotAllowed makes wrong methods on known paths answer 405 with an
+// Allow header. Echo does this on its own, except below route groups with
+// middleware: those register a catch-all not-found route that runs the group
+// middleware, so e.g. GET /auth/logout ended up as a 404 or a login redirect.
+// The catch-alls are replaced by a handler that looks up the methods routed
+// for the path. Catch-alls below a prefix in groups are registered on that
+// group, so its middleware keeps running before the 404 or 405. It must be
+// called after all routes have been registered. A non-nil notFound answers
+// paths without any route, including those outside the groups; nil leaves
+// them a plain 404.
+func setupMethodNotAllowed(e *echo.Echo, notFound echo.HandlerFunc, groups map[string]*echo.Group) {
+	routes := make(map[string]bool) // "METHOD path" of every registered route
+	var methods, catchAlls []string
+	for _, r := range e.Routes() {
+		if r.Method == echo.RouteNotFound {
+			catchAlls = append(catchAlls, r.Path)
+			continue
+		}
+		routes[r.Method+" "+r.Path] = true
+		if !slices.Contains(methods, r.Method) {
+			methods = append(methods, r.Method)
+		}
+	}
+	slices.Sort(methods)
+	if notFound != nil && !slices.Contains(catchAlls, "/*") {
+		catchAlls = append(catchAlls, "/*")
+	}
+
+	h := methodNotAllowedHandler(e, methods, routes, notFound)
+	for _, path := range catchAlls {
+		if g, rel, ok := catchAllGroup(groups, path); ok {
+			g.RouteNotFound(rel, h)
+			continue
+		}
+		e.RouteNotFound(path, h)
+	}
+}
+
+// catchAllGroup returns the group whose prefix the catch-all path belongs to
+// and the path relative to that prefix.
+func catchAllGroup(groups map[string]*echo.Group, path string) (*echo.Group, string, bool) {
+	for prefix, g := range groups {
+		switch path {
+		case prefix:
+			return g, "", true
+		case prefix + "/*":
+			return g, "/*", true
+		}
+	}
+	return nil, "", false
+}
+
+// methodNotAllowedHandler answers 405 with the allowed methods when another
+// method is routed for the request path, OPTIONS with 204, and otherwise
+// passes on to notFound or answers 404.
+func methodNotAllowedHandler(e *echo.Echo, methods []string, routes map[string]bool, notFound echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		path := echo.GetPath(c.Request())
+
+		// Find reports the path of a real route only when the method is
+		// registered for it, otherwise the matching catch-all or node path.
+		var allowed []string
+		for _, method := range methods {
+			ctx := e.NewContext(c.Request(), nil)
+			e.Router().Find(method, path, ctx)
+			if routes[method+" "+ctx.Path()] {
+				allowed = append(allowed, method)
+			}
+		}
+		if len(allowed) == 0 {
+			if notFound != nil {
+				return notFound(c)
+			}
+			return echo.ErrNotFound
+		}
+
+		// OPTIONS first, like the Allow header Echo sends itself
+		allowed = append([]string{http.MethodOptions}, allowed...)
+		c.Response().Header().Set(echo.HeaderAllow, strings.Join(allowed, ", "))
+		if c.Request().Method == http.MethodOptions {
+			return c.NoContent(http.StatusNoContent)
+		}
+		return echo.ErrMethodNotAllowed
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/oliverandrich/go-webapp-template/internal/config"
+	"github.com/oliverandrich/go-webapp-template/internal/i18n"
+	"github.com/oliverandrich/go-webapp-template/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMethodNotAllowed(t *testing.T) {
+	require.NoError(t, i18n.Init())
+	e := newTestRoutes(t, &config.Config{Server: config.ServerConfig{MaxBodySize: 1}})
+
+	tests := []struct {
+		method, path string
+		wantStatus   int
+		wantAllow    string
+	}{
+		{http.MethodGet, "/auth/logout", http.StatusMethodNotAllowed, "OPTIONS, POST"},
+		{http.MethodGet, "/api/auth/logout", http.StatusMethodNotAllowed, "OPTIONS, POST"},
+		{http.MethodPut, "/auth/credentials", http.StatusMethodNotAllowed, "OPTIONS, GET"},
+		{http.MethodGet, "/auth/credentials/42", http.StatusMethodNotAllowed, "OPTIONS, DELETE"},
+		{http.MethodPost, "/dashboard", http.StatusMethodNotAllowed, "OPTIONS, GET"},
+		{http.MethodOptions, "/auth/logout", http.StatusNoContent, "OPTIONS, POST"},
+		{http.MethodGet, "/auth/does-not-exist", http.StatusNotFound, ""},
+		{http.MethodGet, "/does-not-exist", http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			assert.Equal(t, tt.wantAllow, rec.Header().Get("Allow"))
+		})
+	}
+}
+
+func TestMethodNotAllowed_RunsGroupMiddleware(t *testing.T) {
+	require.NoError(t, i18n.Init())
+	cfg := &config.Config{Server: config.ServerConfig{MaxBodySize: 1, AuthBodySize: 1}}
+
+	tests := []struct {
+		name       string
+		user       *models.User
+		method     string
+		path       string
+		body       string
+		wantStatus int
+		wantAllow  string
+	}{
+		{"admin routes need a login", nil, http.MethodDelete, "/admin/users", "", http.StatusSeeOther, ""},
+		{"admin routes need an admin", &models.User{ID: 1}, http.MethodDelete, "/admin/users", "", http.StatusForbidden, ""},
+		{"unknown admin path needs an admin", &models.User{ID: 1}, http.MethodGet, "/admin/nope", "", http.StatusForbidden, ""},
+		{"admin gets 405", &models.User{ID: 1, IsAdmin: true}, http.MethodDelete, "/admin/users", "", http.StatusMethodNotAllowed, "OPTIONS, GET"},
+		{"auth body limit", nil, http.MethodGet, "/auth/logout", strings.Repeat("x", 2048), http.StatusRequestEntityTooLarge, ""},
+		{"api auth body limit", nil, http.MethodGet, "/api/auth/logout", strings.Repeat("x", 2048), http.StatusRequestEntityTooLarge, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestRoutesAs(t, cfg, tt.user)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body)))
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			assert.Equal(t, tt.wantAllow, rec.Header().Get("Allow"))
+		})
+	}
+}
+
+func TestMethodNotAllowed_RoutedMethodStillWorks(t *testing.T) {
+	require.NoError(t, i18n.Init())
+	e := newTestRoutes(t, &config.Config{Server: config.ServerConfig{MaxBodySize: 1}})
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/auth/logout", nil))
+
+	assert.Equal(t, http.StatusSeeOther, rec.Code)
+	assert.Empty(t, rec.Header().Get("Allow"))
+}
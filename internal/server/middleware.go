@@ -4,11 +4,15 @@
 package server
 
 import (
-	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
@@ -16,37 +20,163 @@ import (
 	"github.com/oliverandrich/go-webapp-template/internal/config"
 	"github.com/oliverandrich/go-webapp-template/internal/htmx"
 	"github.com/oliverandrich/go-webapp-template/internal/i18n"
+	"github.com/oliverandrich/go-webapp-template/internal/models"
 	"github.com/oliverandrich/go-webapp-template/internal/repository"
 	"github.com/oliverandrich/go-webapp-template/internal/services/session"
 )
 
-func setupMiddleware(e *echo.Echo, cfg *config.Config, assets *appcontext.Assets) {
+func setupMiddleware(e *echo.Echo, cfg *config.Config, assets *appcontext.Assets) error {
+	csrf, err := csrfMiddleware(cfg)
+	if err != nil {
+		return err
+	}
+	trustedProxies, err := cfg.Server.TrustedProxyPrefixes()
+	if err != nil {
+		return err
+	}
+	bodyLimits, err := cfg.Server.BodyLimitBytes()
+	if err != nil {
+		return err
+	}
+	e.IPExtractor = ipExtractor(trustedProxies)
+
+	e.Pre(cleanPathMiddleware())
+	e.Pre(pathPrefixMiddleware(cfg.Server.PathPrefix))
 	e.Pre(middleware.RemoveTrailingSlash())
 	e.Use(middleware.Recover())
-	e.Use(middleware.RequestID())
-	e.Use(requestLogger())
-	e.Use(middleware.Secure())
-	e.Use(middleware.Gzip())
-	e.Use(middleware.BodyLimit(fmt.Sprintf("%dM", cfg.Server.MaxBodySize)))
-	e.Use(staticCacheHeaders())
-	e.Use(csrfMiddleware(cfg))
+	e.Use(requestID())
+	e.Use(requestLogger(&cfg.Log))
+	e.Use(maxInFlightMiddleware(cfg.Server.MaxInFlight, cfg.Server.MaxInFlightExclude))
+	e.Use(timeoutMiddleware(time.Duration(cfg.Server.RequestTimeout)*time.Second, cfg.Server.RequestTimeoutExclude))
+	e.Use(secureMiddleware(cfg))
+	e.Use(cspMiddleware(&cfg.CSP))
+	e.Use(gzipMiddleware(&cfg.Server))
+	e.Use(bodyLimitsMiddleware(bodyLimits, int64(cfg.Server.MaxBodySize)<<20))
+	e.Use(staticCacheHeaders(cfg.Server.IconMaxAge))
+	e.Use(csrf)
 	e.Use(csrfToContext())
 	e.Use(i18nMiddleware())
 	e.Use(customContext(assets))
+	return nil
+}
+
+// bodyLimit rejects requests whose body exceeds limit (e.g. "16K", "1M")
+// with 413 Request Entity Too Large. An empty limit disables the check.
+// Route groups use it to tighten the global limit.
+func bodyLimit(limit string) echo.MiddlewareFunc {
+	if limit == "" {
+		return func(next echo.HandlerFunc) echo.HandlerFunc { return next }
+	}
+	return middleware.BodyLimit(limit)
 }
 
+// authBodyLimit returns the body limit for the auth routes, which only
+// accept small JSON and form payloads.
+func authBodyLimit(cfg *config.ServerConfig) echo.MiddlewareFunc {
+	if cfg.AuthBodySize <= 0 {
+		return bodyLimit("")
+	}
+	return bodyLimit(fmt.Sprintf("%dK", cfg.AuthBodySize))
+}
+
+// Default CSRF settings used when the corresponding config values are empty.
+const (
+	defaultCSRFCookieName = "_csrf"
+	defaultCSRFHeaderName = "X-CSRF-Token"
+)
+
 // csrfMiddleware configures CSRF protection.
-func csrfMiddleware(cfg *config.Config) echo.MiddlewareFunc {
+func csrfMiddleware(cfg *config.Config) (echo.MiddlewareFunc, error) {
+	csrfCfg, err := csrfConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return middleware.CSRFWithConfig(csrfCfg), nil
+}
+
+// csrfConfig builds the Echo CSRF configuration from the app config.
+// The built-in pages always send X-CSRF-Token, so that header stays accepted
+// alongside a custom header name.
+func csrfConfig(cfg *config.Config) (middleware.CSRFConfig, error) {
 	secure := strings.HasPrefix(cfg.Server.BaseURL, "https://")
 
-	return middleware.CSRFWithConfig(middleware.CSRFConfig{
-		TokenLookup:    "form:csrf_token,header:X-CSRF-Token",
-		CookieName:     "_csrf",
+	sameSite, err := parseSameSite(cfg.CSRF.SameSite)
+	if err != nil {
+		return middleware.CSRFConfig{}, err
+	}
+	if sameSite == http.SameSiteNoneMode && !secure {
+		return middleware.CSRFConfig{}, errors.New("csrf same-site none requires an https base URL")
+	}
+
+	cookieName := cfg.CSRF.CookieName
+	if cookieName == "" {
+		cookieName = defaultCSRFCookieName
+	}
+
+	tokenLookup := "form:csrf_token,header:" + defaultCSRFHeaderName
+	customHeader := false
+	if h := cfg.CSRF.HeaderName; h != "" && !strings.EqualFold(h, defaultCSRFHeaderName) {
+		tokenLookup += ",header:" + h
+		customHeader = true
+	}
+
+	// A custom cookie or header is meant for SPA frameworks that copy the
+	// token from the cookie into the header, so scripts must be able to read
+	// it. The default setup renders the token into the page instead.
+	readable := cookieName != defaultCSRFCookieName || customHeader
+
+	return middleware.CSRFConfig{
+		Skipper:        csrfSkipper(cfg.CSRF.ExemptPrefixes),
+		TokenLookup:    tokenLookup,
+		CookieName:     cookieName,
 		CookiePath:     "/",
 		CookieSecure:   secure,
-		CookieHTTPOnly: true,
-		CookieSameSite: http.SameSiteLaxMode,
-	})
+		CookieHTTPOnly: !readable,
+		CookieSameSite: sameSite,
+	}, nil
+}
+
+// csrfExemptPaths are machine endpoints that never need a CSRF token: the
+// health probes and the server-sent event stream.
+var csrfExemptPaths = []string{"/health", "/ready", "/events"}
+
+// csrfSkipper exempts csrfExemptPaths and paths below one of the configured
+// prefixes from CSRF checks. Those prefixes must only cover endpoints that
+// do not authenticate with cookies, e.g. an API using bearer tokens.
+func csrfSkipper(exemptPrefixes []string) middleware.Skipper {
+	prefixes := make([]string, 0, len(exemptPrefixes))
+	for _, prefix := range exemptPrefixes {
+		if prefix = strings.TrimSpace(prefix); prefix != "" {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+
+	return func(c echo.Context) bool {
+		path := c.Request().URL.Path
+		if slices.Contains(csrfExemptPaths, path) {
+			return true
+		}
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(path, prefix) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// parseSameSite maps a config value to an http.SameSite mode. Empty means lax.
+func parseSameSite(value string) (http.SameSite, error) {
+	switch strings.ToLower(value) {
+	case "", "lax":
+		return http.SameSiteLaxMode, nil
+	case "strict":
+		return http.SameSiteStrictMode, nil
+	case "none":
+		return http.SameSiteNoneMode, nil
+	default:
+		return 0, fmt.Errorf("invalid csrf same-site mode %q (expected lax, strict or none)", value)
+	}
 }
 
 // csrfToContext copies the CSRF token to the request context.
@@ -54,7 +184,7 @@ func csrfToContext() echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
 			if token, ok := c.Get("csrf").(string); ok {
-				ctx := context.WithValue(c.Request().Context(), appcontext.CSRFToken{}, token)
+				ctx := appcontext.WithCSRFToken(c.Request().Context(), token)
 				c.SetRequest(c.Request().WithContext(ctx))
 			}
 			return next(c)
@@ -62,8 +192,89 @@ func csrfToContext() echo.MiddlewareFunc {
 	}
 }
 
-// requestLogger returns middleware that logs requests using slog.
-func requestLogger() echo.MiddlewareFunc {
+// secureMiddleware sets Echo's default security headers and, when the server
+// terminates TLS itself, the configured Strict-Transport-Security header.
+// Plain-HTTP setups such as localhost development never get HSTS, so a
+// browser cannot be pinned to an HTTPS origin that does not exist.
+func secureMiddleware(cfg *config.Config) echo.MiddlewareFunc {
+	sc := middleware.DefaultSecureConfig
+	if resolveTLSMode(cfg) != TLSModeOff {
+		sc.HSTSMaxAge = cfg.TLS.HSTSMaxAge
+		sc.HSTSExcludeSubdomains = !cfg.TLS.HSTSIncludeSubdomains
+		sc.HSTSPreloadEnabled = cfg.TLS.HSTSPreload
+	}
+	return middleware.SecureWithConfig(sc)
+}
+
+// cspMiddleware sets a nonce-based Content-Security-Policy header and exposes
+// the per-request nonce to templates via the request context.
+func cspMiddleware(cfg *config.CSPConfig) echo.MiddlewareFunc {
+	header := "Content-Security-Policy"
+	if cfg.ReportOnly {
+		header = "Content-Security-Policy-Report-Only"
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			nonce, err := generateNonce()
+			if err != nil {
+				return err
+			}
+
+			c.Response().Header().Set(header, buildCSP(nonce, cfg.ReportURI))
+
+			ctx := appcontext.WithCSPNonce(c.Request().Context(), nonce)
+			c.SetRequest(c.Request().WithContext(ctx))
+			return next(c)
+		}
+	}
+}
+
+// buildCSP assembles the policy, allowing scripts and styles only from our own
+// origin or when carrying the request nonce.
+func buildCSP(nonce, reportURI string) string {
+	directives := []string{
+		"default-src 'self'",
+		fmt.Sprintf("script-src 'self' 'nonce-%s'", nonce),
+		fmt.Sprintf("style-src 'self' 'nonce-%s'", nonce),
+		"img-src 'self' data:",
+		"object-src 'none'",
+		"base-uri 'self'",
+		"form-action 'self'",
+		"frame-ancestors 'none'",
+	}
+	if reportURI != "" {
+		directives = append(directives, "report-uri "+reportURI)
+	}
+	return strings.Join(directives, "; ")
+}
+
+// generateNonce returns a random base64-encoded nonce.
+func generateNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+// requestID assigns each request an ID (or keeps the incoming X-Request-ID),
+// echoes it in the X-Request-ID response header and stores it in the request
+// context, so slog calls made with that context are tagged with it.
+func requestID() echo.MiddlewareFunc {
+	return middleware.RequestIDWithConfig(middleware.RequestIDConfig{
+		RequestIDHandler: func(c echo.Context, id string) {
+			ctx := appcontext.WithRequestID(c.Request().Context(), id)
+			c.SetRequest(c.Request().WithContext(ctx))
+		},
+	})
+}
+
+// requestLogger returns middleware that logs requests using slog. Fast
+// successful requests are sampled according to cfg; errors, non-2xx
+// responses and slow requests are always logged.
+func requestLogger(cfg *config.LogConfig) echo.MiddlewareFunc {
+	sampler := newAccessLogSampler(cfg.SampleRate, time.Duration(cfg.SlowThreshold)*time.Millisecond)
 	return middleware.RequestLoggerWithConfig(middleware.RequestLoggerConfig{
 		LogStatus:   true,
 		LogURI:      true,
@@ -72,9 +283,14 @@ func requestLogger() echo.MiddlewareFunc {
 		LogError:    true,
 		HandleError: true,
 		LogValuesFunc: func(c echo.Context, v middleware.RequestLoggerValues) error {
+			if !sampler.keep(&v) {
+				return nil
+			}
+
 			attrs := []slog.Attr{
 				slog.String("method", v.Method),
 				slog.String("uri", v.URI),
+				slog.String("ip", c.RealIP()),
 				slog.Int("status", v.Status),
 				slog.Duration("latency", v.Latency),
 			}
@@ -104,8 +320,9 @@ func i18nMiddleware() echo.MiddlewareFunc {
 	}
 }
 
-// staticCacheHeaders adds cache headers for static assets.
-func staticCacheHeaders() echo.MiddlewareFunc {
+// staticCacheHeaders adds cache headers for static assets. Unhashed icons
+// are cached for iconMaxAge seconds, see iconCacheControl.
+func staticCacheHeaders(iconMaxAge int) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
 			path := c.Request().URL.Path
@@ -113,6 +330,8 @@ func staticCacheHeaders() echo.MiddlewareFunc {
 				if isHashedAsset(path) {
 					// Hashed assets get immutable caching (1 year)
 					c.Response().Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+				} else if isIconAsset(path) {
+					c.Response().Header().Set("Cache-Control", iconCacheControl(iconMaxAge))
 				} else {
 					// Unhashed assets (development mode) never cache
 					c.Response().Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
@@ -149,9 +368,7 @@ func customContext(assets *appcontext.Assets) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
 			// Add asset paths to request context (for templates)
-			ctx := c.Request().Context()
-			ctx = context.WithValue(ctx, appcontext.CSSPath{}, assets.CSSPath)
-			ctx = context.WithValue(ctx, appcontext.JSPath{}, assets.JSPath)
+			ctx := appcontext.WithAssets(c.Request().Context(), assets)
 			c.SetRequest(c.Request().WithContext(ctx))
 
 			// Wrap with custom context (for handlers)
@@ -165,8 +382,8 @@ func customContext(assets *appcontext.Assets) echo.MiddlewareFunc {
 	}
 }
 
-// AuthMiddleware loads the user from the session cookie and sets it in the context.
-func AuthMiddleware(sessions *session.Manager, repo *repository.Repository) echo.MiddlewareFunc {
+// AuthMiddleware loads the user from the session and sets it in the context.
+func AuthMiddleware(store session.Store, repo *repository.Repository) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
 			cc, ok := c.(*appcontext.Context)
@@ -174,8 +391,8 @@ func AuthMiddleware(sessions *session.Manager, repo *repository.Repository) echo
 				return next(c)
 			}
 
-			// Parse session cookie
-			sessionData, err := sessions.Parse(c.Request())
+			// Resolve the session
+			sessionData, err := store.Parse(c.Request())
 			if err != nil || sessionData == nil {
 				return next(c) // Not logged in, continue
 			}
@@ -186,11 +403,32 @@ func AuthMiddleware(sessions *session.Manager, repo *repository.Repository) echo
 				return next(c) // User not found, continue without auth
 			}
 
+			// Sessions issued before the last "sign out everywhere" are void
+			if sessionData.Version != user.SessionVersion {
+				c.SetCookie(store.Clear(c.Request()))
+				return next(c)
+			}
+
+			// Impersonation ends once the administrator loses their rights or
+			// their own sessions are invalidated
+			var impersonator *models.User
+			if sessionData.ImpersonatorID != 0 {
+				impersonator, err = repo.GetUserByID(c.Request().Context(), sessionData.ImpersonatorID)
+				if err != nil || !impersonator.IsAdmin || sessionData.ImpersonatorVersion != impersonator.SessionVersion {
+					c.SetCookie(store.Clear(c.Request()))
+					return next(c)
+				}
+			}
+
 			// Set user in Context struct
 			cc.User = user
+			cc.Impersonator = impersonator
 
 			// Also set in request context for templates
-			ctx := context.WithValue(c.Request().Context(), appcontext.User{}, user)
+			ctx := appcontext.WithUser(c.Request().Context(), user)
+			if impersonator != nil {
+				ctx = appcontext.WithImpersonator(ctx, impersonator)
+			}
 			c.SetRequest(c.Request().WithContext(ctx))
 
 			return next(c)
@@ -198,7 +436,27 @@ func AuthMiddleware(sessions *session.Manager, repo *repository.Repository) echo
 	}
 }
 
+// userLanguage switches the locale to the authenticated user's preferred
+// language, overriding Accept-Language. It must run after AuthMiddleware.
+func userLanguage() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			cc, ok := c.(*appcontext.Context)
+			if !ok || !cc.IsAuthenticated() || cc.GetUser().PreferredLanguage == nil {
+				return next(c)
+			}
+			if tag, supported := i18n.ParseSupported(*cc.GetUser().PreferredLanguage); supported {
+				ctx := i18n.WithLocale(c.Request().Context(), tag)
+				c.SetRequest(c.Request().WithContext(ctx))
+			}
+			return next(c)
+		}
+	}
+}
+
 // RequireAuth returns middleware that redirects to login if not authenticated.
+// Responses to impersonated requests carry an X-Impersonator header with the
+// administrator's username, so clients can tell whose session they are in.
 func RequireAuth() echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
@@ -206,6 +464,23 @@ func RequireAuth() echo.MiddlewareFunc {
 			if !ok || !cc.IsAuthenticated() {
 				return c.Redirect(http.StatusSeeOther, "/auth/login")
 			}
+			if cc.IsImpersonating() {
+				c.Response().Header().Set("X-Impersonator", cc.Impersonator.Username)
+			}
+			return next(c)
+		}
+	}
+}
+
+// RequireAdmin returns middleware that rejects users without administrator
+// rights. It must run after RequireAuth.
+func RequireAdmin() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			cc, ok := c.(*appcontext.Context)
+			if !ok || !cc.IsAuthenticated() || !cc.GetUser().IsAdmin {
+				return echo.NewHTTPError(http.StatusForbidden)
+			}
 			return next(c)
 		}
 	}
@@ -4,17 +4,24 @@
 package server
 
 import (
+	"context"
+	"crypto/tls"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/oliverandrich/go-webapp-template/internal/appcontext"
 	"github.com/oliverandrich/go-webapp-template/internal/config"
+	"github.com/oliverandrich/go-webapp-template/internal/handlers"
 	"github.com/oliverandrich/go-webapp-template/internal/i18n"
 	"github.com/oliverandrich/go-webapp-template/internal/models"
+	"github.com/oliverandrich/go-webapp-template/internal/repository"
 	"github.com/oliverandrich/go-webapp-template/internal/services/session"
+	"github.com/oliverandrich/go-webapp-template/internal/services/settings"
 	"github.com/oliverandrich/go-webapp-template/internal/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -43,7 +50,7 @@ func TestIsHashedAsset(t *testing.T) {
 
 func TestStaticCacheHeaders(t *testing.T) {
 	e := echo.New()
-	e.Use(staticCacheHeaders())
+	e.Use(staticCacheHeaders(3600))
 	e.GET("/static/*", func(c echo.Context) error {
 		return c.String(http.StatusOK, "ok")
 	})
@@ -63,6 +70,16 @@ func TestStaticCacheHeaders(t *testing.T) {
 
 		assert.Equal(t, "no-cache, no-store, must-revalidate", rec.Header().Get("Cache-Control"))
 	})
+
+	t.Run("unhashed icons get a short max-age", func(t *testing.T) {
+		for _, path := range []string{"/static/icons/icon-192.png", "/static/favicon.ico", "/static/site.webmanifest"} {
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, "public, max-age=3600", rec.Header().Get("Cache-Control"), path)
+		}
+	})
 }
 
 func TestI18nMiddleware(t *testing.T) {
@@ -97,6 +114,87 @@ func TestI18nMiddleware(t *testing.T) {
 	})
 }
 
+func TestI18nMiddleware_DefaultLanguage(t *testing.T) {
+	require.NoError(t, i18n.SetDefaultLanguage("de"))
+	require.NoError(t, i18n.Init())
+	t.Cleanup(func() {
+		require.NoError(t, i18n.SetDefaultLanguage("en"))
+		require.NoError(t, i18n.Init())
+	})
+
+	e := echo.New()
+	e.Use(i18nMiddleware())
+	e.GET("/", func(c echo.Context) error {
+		return c.String(http.StatusOK, i18n.T(c.Request().Context(), "username"))
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Language", "fr")
+	rec := serve(e, req)
+
+	assert.Equal(t, "Benutzername", rec.Body.String())
+}
+
+func TestUserLanguage_OverridesAcceptLanguage(t *testing.T) {
+	require.NoError(t, i18n.Init())
+	_, repo := testutil.NewTestDB(t)
+	german := testutil.NewTestUser(t, repo, "german")
+	require.NoError(t, repo.SetUserLanguage(context.Background(), german.ID, "de"))
+	plain := testutil.NewTestUser(t, repo, "plain")
+
+	sessMgr, err := session.NewManager(&config.SessionConfig{
+		CookieName: "_session",
+		MaxAge:     3600,
+		HashKey:    "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef",
+	}, false)
+	require.NoError(t, err)
+
+	e := echo.New()
+	e.Use(i18nMiddleware())
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			return next(&appcontext.Context{Context: c})
+		}
+	})
+	e.Use(AuthMiddleware(session.NewCookieStore(sessMgr, repo), repo))
+	e.Use(userLanguage())
+
+	var locale string
+	e.GET("/", func(c echo.Context) error {
+		locale = i18n.GetLocale(c.Request().Context())
+		return c.NoContent(http.StatusOK)
+	})
+
+	request := func(t *testing.T, cookie *http.Cookie) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept-Language", "en-US")
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		e.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	t.Run("user with German preference", func(t *testing.T) {
+		cookie, err := sessMgr.Create(german.ID, german.Username)
+		require.NoError(t, err)
+		request(t, cookie)
+		assert.Equal(t, "de", locale)
+	})
+
+	t.Run("user without preference", func(t *testing.T) {
+		cookie, err := sessMgr.Create(plain.ID, plain.Username)
+		require.NoError(t, err)
+		request(t, cookie)
+		assert.True(t, strings.HasPrefix(locale, "en"), "expected English, got %s", locale)
+	})
+
+	t.Run("anonymous", func(t *testing.T) {
+		request(t, nil)
+		assert.True(t, strings.HasPrefix(locale, "en"), "expected English, got %s", locale)
+	})
+}
+
 func TestAuthMiddleware_NoSession(t *testing.T) {
 	_, repo := testutil.NewTestDB(t)
 	sessMgr, err := session.NewManager(&config.SessionConfig{
@@ -114,7 +212,7 @@ func TestAuthMiddleware_NoSession(t *testing.T) {
 			return next(cc)
 		}
 	})
-	e.Use(AuthMiddleware(sessMgr, repo))
+	e.Use(AuthMiddleware(session.NewCookieStore(sessMgr, repo), repo))
 
 	var contextUser *models.User
 	e.GET("/", func(c echo.Context) error {
@@ -155,7 +253,7 @@ func TestAuthMiddleware_WithSession(t *testing.T) {
 			return next(cc)
 		}
 	})
-	e.Use(AuthMiddleware(sessMgr, repo))
+	e.Use(AuthMiddleware(session.NewCookieStore(sessMgr, repo), repo))
 
 	var contextUser *models.User
 	e.GET("/", func(c echo.Context) error {
@@ -175,6 +273,103 @@ func TestAuthMiddleware_WithSession(t *testing.T) {
 	assert.Equal(t, user.ID, contextUser.ID)
 }
 
+// newImpersonationEcho serves GET / behind AuthMiddleware and RequireAuth and
+// records the user and impersonator seen by the handler.
+func newImpersonationEcho(sessMgr *session.Manager, repo *repository.Repository, user, impersonator **models.User) *echo.Echo {
+	e := echo.New()
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			return next(&appcontext.Context{Context: c})
+		}
+	})
+	e.Use(AuthMiddleware(session.NewCookieStore(sessMgr, repo), repo))
+	e.GET("/", func(c echo.Context) error {
+		cc := c.(*appcontext.Context)
+		*user, *impersonator = cc.User, cc.Impersonator
+		return c.NoContent(http.StatusOK)
+	}, RequireAuth())
+	return e
+}
+
+func TestAuthMiddleware_Impersonation(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	ctx := context.Background()
+	admin := testutil.NewTestUser(t, repo, "admin")
+	require.NoError(t, repo.SetAdmin(ctx, admin.ID, true))
+	target := testutil.NewTestUser(t, repo, "customer")
+
+	sessMgr, err := session.NewManager(&config.SessionConfig{
+		CookieName: "_session",
+		MaxAge:     3600,
+		HashKey:    "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef",
+	}, false)
+	require.NoError(t, err)
+	cookie, err := sessMgr.Issue(session.Data{UserID: target.ID, Username: target.Username, ImpersonatorID: admin.ID}, time.Hour)
+	require.NoError(t, err)
+
+	var user, impersonator *models.User
+	e := newImpersonationEcho(sessMgr, repo, &user, &impersonator)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(cookie)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NotNil(t, user)
+	assert.Equal(t, target.ID, user.ID)
+	require.NotNil(t, impersonator)
+	assert.Equal(t, admin.ID, impersonator.ID)
+	assert.Equal(t, "admin", rec.Header().Get("X-Impersonator"))
+
+	// Revoking the administrator's rights ends the impersonation
+	require.NoError(t, repo.SetAdmin(ctx, admin.ID, false))
+	user, impersonator = nil, nil
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusSeeOther, rec.Code)
+	assert.Nil(t, user)
+	assert.Contains(t, rec.Header().Get("Set-Cookie"), "Max-Age=0")
+}
+
+func TestAuthMiddleware_ImpersonationEndsWithAdminSessions(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	ctx := context.Background()
+	admin := testutil.NewTestUser(t, repo, "admin")
+	require.NoError(t, repo.SetAdmin(ctx, admin.ID, true))
+	target := testutil.NewTestUser(t, repo, "customer")
+
+	sessMgr, err := session.NewManager(&config.SessionConfig{
+		CookieName: "_session",
+		MaxAge:     3600,
+		HashKey:    "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef",
+	}, false)
+	require.NoError(t, err)
+	cookie, err := sessMgr.Issue(session.Data{
+		UserID:              target.ID,
+		Username:            target.Username,
+		ImpersonatorID:      admin.ID,
+		ImpersonatorVersion: admin.SessionVersion,
+	}, time.Hour)
+	require.NoError(t, err)
+
+	var user, impersonator *models.User
+	e := newImpersonationEcho(sessMgr, repo, &user, &impersonator)
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(cookie)
+
+	// "Sign out everywhere" by the administrator ends the impersonation too
+	_, err = repo.BumpSessionVersion(ctx, admin.ID)
+	require.NoError(t, err)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusSeeOther, rec.Code)
+	assert.Nil(t, user)
+	assert.Contains(t, rec.Header().Get("Set-Cookie"), "Max-Age=0")
+}
+
 func TestRequireAuth_NotAuthenticated(t *testing.T) {
 	e := echo.New()
 	// Create custom context middleware
@@ -224,6 +419,39 @@ func TestRequireAuth_Authenticated(t *testing.T) {
 	assert.Equal(t, "protected content", rec.Body.String())
 }
 
+func TestRequireAdmin(t *testing.T) {
+	tests := []struct {
+		name string
+		user *models.User
+		want int
+	}{
+		{"anonymous", nil, http.StatusForbidden},
+		{"regular user", &models.User{ID: 1, Username: "user"}, http.StatusForbidden},
+		{"admin", &models.User{ID: 2, Username: "admin", IsAdmin: true}, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+				return func(c echo.Context) error {
+					return next(&appcontext.Context{Context: c, User: tt.user})
+				}
+			})
+			e.Use(RequireAdmin())
+			e.GET("/admin", func(c echo.Context) error {
+				return c.NoContent(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.want, rec.Code)
+		})
+	}
+}
+
 func TestCsrfMiddleware(t *testing.T) {
 	cfg := &config.Config{
 		Server: config.ServerConfig{
@@ -231,8 +459,9 @@ func TestCsrfMiddleware(t *testing.T) {
 		},
 	}
 
-	mw := csrfMiddleware(cfg)
+	mw, err := csrfMiddleware(cfg)
 
+	require.NoError(t, err)
 	assert.NotNil(t, mw)
 }
 
@@ -243,11 +472,208 @@ func TestCsrfMiddleware_HTTPS(t *testing.T) {
 		},
 	}
 
-	mw := csrfMiddleware(cfg)
+	mw, err := csrfMiddleware(cfg)
 
+	require.NoError(t, err)
 	assert.NotNil(t, mw)
 }
 
+func TestCsrfConfig_Defaults(t *testing.T) {
+	cfg := &config.Config{
+		Server: config.ServerConfig{BaseURL: "http://localhost:8080"},
+	}
+
+	csrfCfg, err := csrfConfig(cfg)
+
+	require.NoError(t, err)
+	assert.Equal(t, "form:csrf_token,header:X-CSRF-Token", csrfCfg.TokenLookup)
+	assert.Equal(t, "_csrf", csrfCfg.CookieName)
+	assert.Equal(t, http.SameSiteLaxMode, csrfCfg.CookieSameSite)
+	assert.False(t, csrfCfg.CookieSecure)
+	assert.True(t, csrfCfg.CookieHTTPOnly)
+}
+
+func TestCsrfConfig_Custom(t *testing.T) {
+	cfg := &config.Config{
+		Server: config.ServerConfig{BaseURL: "https://example.com"},
+		CSRF: config.CSRFConfig{
+			CookieName: "XSRF-TOKEN",
+			HeaderName: "X-XSRF-TOKEN",
+			SameSite:   "none",
+		},
+	}
+
+	csrfCfg, err := csrfConfig(cfg)
+
+	require.NoError(t, err)
+	assert.Equal(t, "form:csrf_token,header:X-CSRF-Token,header:X-XSRF-TOKEN", csrfCfg.TokenLookup)
+	assert.Equal(t, "XSRF-TOKEN", csrfCfg.CookieName)
+	assert.Equal(t, http.SameSiteNoneMode, csrfCfg.CookieSameSite)
+	assert.True(t, csrfCfg.CookieSecure)
+	assert.False(t, csrfCfg.CookieHTTPOnly)
+}
+
+func TestCsrfConfig_CustomHeaderOnlyIsReadable(t *testing.T) {
+	cfg := &config.Config{
+		Server: config.ServerConfig{BaseURL: "http://localhost:8080"},
+		CSRF:   config.CSRFConfig{CookieName: "_csrf", HeaderName: "X-XSRF-TOKEN"},
+	}
+
+	csrfCfg, err := csrfConfig(cfg)
+
+	require.NoError(t, err)
+	assert.False(t, csrfCfg.CookieHTTPOnly)
+}
+
+func TestCsrfMiddleware_CustomCookieReadableByScripts(t *testing.T) {
+	cfg := &config.Config{
+		Server: config.ServerConfig{BaseURL: "http://localhost:8080"},
+		CSRF:   config.CSRFConfig{CookieName: "XSRF-TOKEN", HeaderName: "X-XSRF-TOKEN"},
+	}
+	mw, err := csrfMiddleware(cfg)
+	require.NoError(t, err)
+
+	e := echo.New()
+	e.Use(mw)
+	e.GET("/", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+	e.POST("/", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+
+	// Like an SPA: read the token from the cookie of a GET and echo it in
+	// the header of the next POST
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	var token *http.Cookie
+	for _, cookie := range rec.Result().Cookies() {
+		if cookie.Name == "XSRF-TOKEN" {
+			token = cookie
+		}
+	}
+	require.NotNil(t, token)
+	assert.False(t, token.HttpOnly)
+
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req.AddCookie(&http.Cookie{Name: token.Name, Value: token.Value})
+	req.Header.Set("X-XSRF-TOKEN", token.Value)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestCsrfConfig_Strict(t *testing.T) {
+	cfg := &config.Config{
+		Server: config.ServerConfig{BaseURL: "http://localhost:8080"},
+		CSRF:   config.CSRFConfig{SameSite: "Strict"},
+	}
+
+	csrfCfg, err := csrfConfig(cfg)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.SameSiteStrictMode, csrfCfg.CookieSameSite)
+}
+
+func TestCsrfConfig_SameSiteNoneRequiresHTTPS(t *testing.T) {
+	cfg := &config.Config{
+		Server: config.ServerConfig{BaseURL: "http://localhost:8080"},
+		CSRF:   config.CSRFConfig{SameSite: "none"},
+	}
+
+	_, err := csrfConfig(cfg)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "requires an https base URL")
+}
+
+func TestCsrfConfig_InvalidSameSite(t *testing.T) {
+	cfg := &config.Config{
+		Server: config.ServerConfig{BaseURL: "http://localhost:8080"},
+		CSRF:   config.CSRFConfig{SameSite: "sometimes"},
+	}
+
+	_, err := csrfConfig(cfg)
+
+	require.Error(t, err)
+}
+
+func TestCsrfMiddleware_CustomHeaderAccepted(t *testing.T) {
+	cfg := &config.Config{
+		Server: config.ServerConfig{BaseURL: "http://localhost:8080"},
+		CSRF:   config.CSRFConfig{CookieName: "XSRF-TOKEN", HeaderName: "X-XSRF-TOKEN"},
+	}
+	mw, err := csrfMiddleware(cfg)
+	require.NoError(t, err)
+
+	e := echo.New()
+	e.Use(mw)
+	e.POST("/", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req.AddCookie(&http.Cookie{Name: "XSRF-TOKEN", Value: "token123"})
+	req.Header.Set("X-XSRF-TOKEN", "token123")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+// newCSRFEcho accepts POSTs to a form route, an API route and the health
+// endpoint behind the CSRF middleware.
+func newCSRFEcho(t *testing.T, exemptPrefixes ...string) *echo.Echo {
+	t.Helper()
+	cfg := &config.Config{
+		Server: config.ServerConfig{BaseURL: "http://localhost:8080"},
+		CSRF:   config.CSRFConfig{ExemptPrefixes: exemptPrefixes},
+	}
+	mw, err := csrfMiddleware(cfg)
+	require.NoError(t, err)
+
+	e := echo.New()
+	e.Use(mw)
+	ok := func(c echo.Context) error { return c.NoContent(http.StatusOK) }
+	e.POST("/settings/language", ok)
+	e.POST("/api/items", ok)
+	e.POST("/health", ok)
+	return e
+}
+
+// forgedPost is a POST carrying a CSRF header that does not match the cookie.
+func forgedPost(path string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, path, nil)
+	req.AddCookie(&http.Cookie{Name: "_csrf", Value: "token123"})
+	req.Header.Set("X-CSRF-Token", "forged")
+	return req
+}
+
+func TestCsrfMiddleware_ExemptPrefix(t *testing.T) {
+	e := newCSRFEcho(t, " ", "/api/")
+
+	rec := serve(e, httptest.NewRequest(http.MethodPost, "/api/items", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, http.StatusOK, serve(e, forgedPost("/api/items")).Code)
+
+	// Browser form routes still need a valid token
+	rec = serve(e, httptest.NewRequest(http.MethodPost, "/settings/language", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, http.StatusForbidden, serve(e, forgedPost("/settings/language")).Code)
+}
+
+func TestCsrfMiddleware_MachineEndpointsExempt(t *testing.T) {
+	e := newCSRFEcho(t)
+
+	rec := serve(e, httptest.NewRequest(http.MethodPost, "/health", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Header().Get("Set-Cookie"))
+
+	// Without configured prefixes the API is protected as well
+	assert.Equal(t, http.StatusForbidden, serve(e, forgedPost("/api/items")).Code)
+}
+
 func TestCsrfToContext(t *testing.T) {
 	e := echo.New()
 	mw := csrfToContext()
@@ -315,7 +741,7 @@ func TestAuthMiddleware_InvalidSession(t *testing.T) {
 			return next(cc)
 		}
 	})
-	e.Use(AuthMiddleware(sessMgr, repo))
+	e.Use(AuthMiddleware(session.NewCookieStore(sessMgr, repo), repo))
 
 	var contextUser *models.User
 	e.GET("/", func(c echo.Context) error {
@@ -358,7 +784,7 @@ func TestAuthMiddleware_UserNotFound(t *testing.T) {
 			return next(cc)
 		}
 	})
-	e.Use(AuthMiddleware(sessMgr, repo))
+	e.Use(AuthMiddleware(session.NewCookieStore(sessMgr, repo), repo))
 
 	var contextUser *models.User
 	e.GET("/", func(c echo.Context) error {
@@ -388,7 +814,7 @@ func TestAuthMiddleware_NotCustomContext(t *testing.T) {
 
 	e := echo.New()
 	// Don't add custom context middleware - use standard echo.Context
-	e.Use(AuthMiddleware(sessMgr, repo))
+	e.Use(AuthMiddleware(session.NewCookieStore(sessMgr, repo), repo))
 
 	e.GET("/", func(c echo.Context) error {
 		return c.NoContent(http.StatusOK)
@@ -421,7 +847,7 @@ func TestRequireAuth_NotCustomContext(t *testing.T) {
 
 func TestStaticCacheHeaders_NonStaticPath(t *testing.T) {
 	e := echo.New()
-	e.Use(staticCacheHeaders())
+	e.Use(staticCacheHeaders(0))
 	e.GET("/api/data", func(c echo.Context) error {
 		return c.String(http.StatusOK, "ok")
 	})
@@ -621,3 +1047,214 @@ func TestCustomContext_PreservesOriginalContext(t *testing.T) {
 	assert.Equal(t, "/api/test", capturedContext.Request().URL.Path)
 	assert.Equal(t, "application/json", capturedContext.Request().Header.Get("Content-Type"))
 }
+
+func TestCSPMiddleware_NonceInHeaderAndContext(t *testing.T) {
+	e := echo.New()
+	e.Use(cspMiddleware(&config.CSPConfig{}))
+
+	var nonces []string
+	e.GET("/", func(c echo.Context) error {
+		nonce, _ := c.Request().Context().Value(appcontext.CSPNonce{}).(string)
+		nonces = append(nonces, nonce)
+		return c.NoContent(http.StatusOK)
+	})
+
+	var headers []string
+	for range 2 {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		headers = append(headers, rec.Header().Get("Content-Security-Policy"))
+		assert.Empty(t, rec.Header().Get("Content-Security-Policy-Report-Only"))
+	}
+
+	require.Len(t, nonces, 2)
+	assert.NotEmpty(t, nonces[0])
+	assert.NotEqual(t, nonces[0], nonces[1], "nonce should be unique per request")
+	for i, header := range headers {
+		assert.Contains(t, header, "script-src 'self' 'nonce-"+nonces[i]+"'")
+		assert.Contains(t, header, "style-src 'self' 'nonce-"+nonces[i]+"'")
+		assert.NotContains(t, header, "report-uri")
+	}
+}
+
+func TestCSPMiddleware_ReportOnly(t *testing.T) {
+	e := echo.New()
+	e.Use(cspMiddleware(&config.CSPConfig{ReportOnly: true, ReportURI: "/csp-report"}))
+	e.GET("/", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Empty(t, rec.Header().Get("Content-Security-Policy"))
+	header := rec.Header().Get("Content-Security-Policy-Report-Only")
+	assert.Contains(t, header, "'nonce-")
+	assert.True(t, strings.HasSuffix(header, "; report-uri /csp-report"))
+}
+
+func serveSecure(cfg *config.Config, req *http.Request) *httptest.ResponseRecorder {
+	e := echo.New()
+	e.Use(secureMiddleware(cfg))
+	e.GET("/", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestSecureMiddleware_HSTS(t *testing.T) {
+	cfg := &config.Config{TLS: config.TLSConfig{
+		Mode:                  "manual",
+		HSTSMaxAge:            63072000,
+		HSTSIncludeSubdomains: true,
+		HSTSPreload:           true,
+	}}
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.TLS = &tls.ConnectionState{}
+
+	rec := serveSecure(cfg, req)
+
+	assert.Equal(t, "max-age=63072000; includeSubdomains; preload", rec.Header().Get("Strict-Transport-Security"))
+	assert.Equal(t, "nosniff", rec.Header().Get("X-Content-Type-Options"))
+}
+
+func TestSecureMiddleware_HSTSWithoutSubdomains(t *testing.T) {
+	cfg := &config.Config{TLS: config.TLSConfig{Mode: "acme", HSTSMaxAge: 86400}}
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.TLS = &tls.ConnectionState{}
+
+	rec := serveSecure(cfg, req)
+
+	assert.Equal(t, "max-age=86400", rec.Header().Get("Strict-Transport-Security"))
+}
+
+func TestSecureMiddleware_NoHSTSWhenTLSOff(t *testing.T) {
+	cfg := &config.Config{
+		Server: config.ServerConfig{Host: "localhost"},
+		TLS:    config.TLSConfig{Mode: "off", HSTSMaxAge: 63072000, HSTSIncludeSubdomains: true},
+	}
+	// Even a proxy claiming HTTPS must not pin a plain-HTTP deployment.
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(echo.HeaderXForwardedProto, "https")
+
+	rec := serveSecure(cfg, req)
+
+	assert.Empty(t, rec.Header().Get("Strict-Transport-Security"))
+	assert.Equal(t, "nosniff", rec.Header().Get("X-Content-Type-Options"))
+}
+
+func TestAuthMiddleware_SessionVersionMismatch(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	user := testutil.NewTestUser(t, repo, "testuser")
+
+	sessMgr, err := session.NewManager(&config.SessionConfig{
+		CookieName: "_session",
+		MaxAge:     3600,
+		HashKey:    "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef",
+	}, false)
+	require.NoError(t, err)
+
+	stale, err := sessMgr.Create(user.ID, user.Username)
+	require.NoError(t, err)
+
+	// Sign out everywhere
+	version, err := repo.BumpSessionVersion(context.Background(), user.ID)
+	require.NoError(t, err)
+	current, err := sessMgr.Issue(session.Data{UserID: user.ID, Username: user.Username, Version: version}, sessMgr.Duration())
+	require.NoError(t, err)
+
+	e := echo.New()
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			return next(&appcontext.Context{Context: c})
+		}
+	})
+	e.Use(AuthMiddleware(session.NewCookieStore(sessMgr, repo), repo))
+
+	var contextUser *models.User
+	e.GET("/", func(c echo.Context) error {
+		contextUser = c.(*appcontext.Context).User
+		return c.NoContent(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(stale)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Nil(t, contextUser, "stale session must require a new login")
+	require.Len(t, rec.Result().Cookies(), 1)
+	assert.Equal(t, -1, rec.Result().Cookies()[0].MaxAge)
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(current)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	require.NotNil(t, contextUser)
+	assert.Equal(t, user.ID, contextUser.ID)
+}
+
+func TestAdminLogoutAll_InvalidatesSessions(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	admin := testutil.NewTestUser(t, repo, "admin")
+	require.NoError(t, repo.SetAdmin(context.Background(), admin.ID, true))
+	user := testutil.NewTestUser(t, repo, "testuser")
+
+	sessMgr, err := session.NewManager(&config.SessionConfig{
+		CookieName: "_session",
+		MaxAge:     3600,
+		HashKey:    "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef",
+	}, false)
+	require.NoError(t, err)
+	settingsSvc, err := settings.NewService(context.Background(), repo)
+	require.NoError(t, err)
+
+	e := echo.New()
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			return next(&appcontext.Context{Context: c})
+		}
+	})
+	e.Use(AuthMiddleware(session.NewCookieStore(sessMgr, repo), repo))
+	e.POST("/admin/users/:id/logout-all", handlers.NewAdmin(settingsSvc, repo, sessMgr).LogoutAll, RequireAuth(), RequireAdmin())
+	e.GET("/whoami", func(c echo.Context) error {
+		if u := c.(*appcontext.Context).User; u != nil {
+			return c.String(http.StatusOK, u.Username)
+		}
+		return c.NoContent(http.StatusUnauthorized)
+	})
+
+	get := func(cookie *http.Cookie) int {
+		req := httptest.NewRequest(http.MethodGet, "/whoami", nil)
+		req.AddCookie(cookie)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	old, err := sessMgr.Create(user.ID, user.Username)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, get(old))
+
+	adminCookie, err := sessMgr.Create(admin.ID, admin.Username)
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodPost, "/admin/users/"+strconv.FormatInt(user.ID, 10)+"/logout-all", nil)
+	req.AddCookie(adminCookie)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	assert.Equal(t, http.StatusUnauthorized, get(old), "old session must stop authenticating")
+
+	// A session issued after the bump carries the new version
+	updated, err := repo.GetUserByID(context.Background(), user.ID)
+	require.NoError(t, err)
+	fresh, err := sessMgr.Issue(session.Data{UserID: user.ID, Username: user.Username, Version: updated.SessionVersion}, sessMgr.Duration())
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, get(fresh))
+}
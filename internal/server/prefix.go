@@ -0,0 +1,60 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package server
+
+import (
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/oliverandrich/go-webapp-template/internal/appcontext"
+	"github.com/oliverandrich/go-webapp-template/internal/htmx"
+)
+
+// pathPrefixMiddleware mounts the application below prefix, e.g. "/app"
+// behind a reverse proxy that forwards the full path. It runs before
+// routing: the prefix is stripped so routes stay declared at the root, and
+// requests outside the prefix get 404. Handlers keep redirecting to root
+// paths; Location, HX-Location and HX-Redirect headers are prefixed on the
+// way out. Templates build links with templates.Path, which reads the
+// prefix stored in the request context. An empty prefix disables it.
+func pathPrefixMiddleware(prefix string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		if prefix == "" {
+			return next
+		}
+		return func(c echo.Context) error {
+			req := c.Request()
+			rest, ok := strings.CutPrefix(req.URL.Path, prefix)
+			if !ok || (rest != "" && rest[0] != '/') {
+				return echo.ErrNotFound
+			}
+			if rest == "" {
+				rest = "/"
+			}
+			// The router reads the path of the original request, so it is
+			// changed in place
+			req.URL.Path = rest
+			if raw, found := strings.CutPrefix(req.URL.RawPath, prefix); found {
+				req.URL.RawPath = raw
+			}
+			c.SetRequest(req.WithContext(appcontext.WithPathPrefix(req.Context(), prefix)))
+
+			res := c.Response()
+			res.Before(func() {
+				for _, name := range []string{echo.HeaderLocation, htmx.HeaderLocation, htmx.HeaderRedirect} {
+					if target := res.Header().Get(name); isRootPath(target) {
+						res.Header().Set(name, prefix+target)
+					}
+				}
+			})
+			return next(c)
+		}
+	}
+}
+
+// isRootPath reports whether target is a path on this host, as opposed to
+// an absolute or protocol-relative URL.
+func isRootPath(target string) bool {
+	return strings.HasPrefix(target, "/") && !strings.HasPrefix(target, "//")
+}
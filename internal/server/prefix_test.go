@@ -0,0 +1,89 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/oliverandrich/go-webapp-template/internal/config"
+	"github.com/oliverandrich/go-webapp-template/internal/htmx"
+	"github.com/oliverandrich/go-webapp-template/internal/i18n"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newPrefixedRoutes serves the application routes below /app.
+func newPrefixedRoutes(t *testing.T) *echo.Echo {
+	t.Helper()
+	require.NoError(t, i18n.Init())
+	e := newTestRoutes(t, &config.Config{Server: config.ServerConfig{MaxBodySize: 1, PathPrefix: "/app"}})
+	e.Pre(pathPrefixMiddleware("/app"))
+	return e
+}
+
+func TestPathPrefix_ServesRoutesBelowPrefix(t *testing.T) {
+	e := newPrefixedRoutes(t)
+
+	rec := serve(e, httptest.NewRequest(http.MethodGet, "/app/health", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestPathPrefix_RejectsPathsOutsidePrefix(t *testing.T) {
+	e := newPrefixedRoutes(t)
+
+	for _, path := range []string{"/health", "/application/health"} {
+		rec := serve(e, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, http.StatusNotFound, rec.Code, path)
+	}
+}
+
+func TestPathPrefix_PrefixesRedirects(t *testing.T) {
+	e := newPrefixedRoutes(t)
+
+	rec := serve(e, httptest.NewRequest(http.MethodGet, "/app/dashboard", nil))
+
+	assert.Equal(t, http.StatusSeeOther, rec.Code)
+	assert.Equal(t, "/app/auth/login", rec.Header().Get(echo.HeaderLocation))
+}
+
+func TestPathPrefix_PrefixesLinks(t *testing.T) {
+	e := newPrefixedRoutes(t)
+
+	rec := serve(e, httptest.NewRequest(http.MethodGet, "/app/auth/login", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `href="/app/auth/register"`)
+	assert.Contains(t, rec.Body.String(), `data-base-path="/app"`)
+}
+
+func TestPathPrefixMiddleware_HeaderRewrites(t *testing.T) {
+	e := echo.New()
+	e.Pre(pathPrefixMiddleware("/app"))
+	e.GET("/", func(c echo.Context) error {
+		c.Response().Header().Set(htmx.HeaderRedirect, "/auth/login")
+		c.Response().Header().Set(htmx.HeaderLocation, "https://example.com/elsewhere")
+		return c.Redirect(http.StatusSeeOther, "//example.com/")
+	})
+
+	rec := serve(e, httptest.NewRequest(http.MethodGet, "/app", nil))
+
+	assert.Equal(t, http.StatusSeeOther, rec.Code)
+	assert.Equal(t, "/app/auth/login", rec.Header().Get(htmx.HeaderRedirect))
+	assert.Equal(t, "https://example.com/elsewhere", rec.Header().Get(htmx.HeaderLocation))
+	assert.Equal(t, "//example.com/", rec.Header().Get(echo.HeaderLocation))
+}
+
+func TestPathPrefixMiddleware_Disabled(t *testing.T) {
+	e := echo.New()
+	e.Pre(pathPrefixMiddleware(""))
+	e.GET("/health", func(c echo.Context) error { return c.Redirect(http.StatusFound, "/") })
+
+	rec := serve(e, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	assert.Equal(t, "/", rec.Header().Get(echo.HeaderLocation))
+}
@@ -0,0 +1,83 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package server
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// redactedValue replaces the value of sensitive attributes.
+	redactedValue = "[REDACTED]"
+	// maxLogValueLen is the length after which string values are truncated.
+	maxLogValueLen = 1024
+)
+
+// redactHandler replaces the values of sensitive attributes, matched by key
+// without regard to case, and truncates very long string values, so secrets
+// added to a log call by accident never reach the output.
+type redactHandler struct {
+	slog.Handler
+	keys map[string]bool
+}
+
+func newRedactHandler(next slog.Handler, keys []string) *redactHandler {
+	set := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		set[strings.ToLower(k)] = true
+	}
+	return &redactHandler{Handler: next, keys: set}
+}
+
+func (h *redactHandler) Handle(ctx context.Context, r slog.Record) error {
+	clean := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		clean.AddAttrs(h.redact(a))
+		return true
+	})
+	return h.Handler.Handle(ctx, clean)
+}
+
+func (h *redactHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clean := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		clean[i] = h.redact(a)
+	}
+	return &redactHandler{Handler: h.Handler.WithAttrs(clean), keys: h.keys}
+}
+
+func (h *redactHandler) WithGroup(name string) slog.Handler {
+	return &redactHandler{Handler: h.Handler.WithGroup(name), keys: h.keys}
+}
+
+// redact returns the attribute with a sensitive or oversized value replaced,
+// descending into groups.
+func (h *redactHandler) redact(a slog.Attr) slog.Attr {
+	if h.keys[strings.ToLower(a.Key)] {
+		return slog.String(a.Key, redactedValue)
+	}
+	v := a.Value.Resolve()
+	switch v.Kind() {
+	case slog.KindGroup:
+		group := v.Group()
+		clean := make([]slog.Attr, len(group))
+		for i, g := range group {
+			clean[i] = h.redact(g)
+		}
+		return slog.Attr{Key: a.Key, Value: slog.GroupValue(clean...)}
+	case slog.KindString:
+		if s := v.String(); len(s) > maxLogValueLen {
+			// Cut at a rune boundary, so the output stays valid UTF-8
+			end := maxLogValueLen
+			for end > 0 && !utf8.RuneStart(s[end]) {
+				end--
+			}
+			return slog.String(a.Key, s[:end]+"…")
+		}
+	}
+	return slog.Attr{Key: a.Key, Value: v}
+}
@@ -0,0 +1,80 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newRedactLogger returns a logger writing JSON records through a redactHandler.
+func newRedactLogger(keys ...string) (*slog.Logger, *bytes.Buffer) {
+	var buf bytes.Buffer
+	return slog.New(newRedactHandler(slog.NewJSONHandler(&buf, nil), keys)), &buf
+}
+
+func decodeRecord(t *testing.T, buf *bytes.Buffer) map[string]any {
+	t.Helper()
+	var record map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	return record
+}
+
+func TestRedactHandler_RedactsSensitiveKeys(t *testing.T) {
+	logger, buf := newRedactLogger("token", "password")
+
+	logger.Info("login", "token", "s3cr3t-value", "user", "alice")
+
+	record := decodeRecord(t, buf)
+	assert.Equal(t, redactedValue, record["token"])
+	assert.Equal(t, "alice", record["user"])
+	assert.NotContains(t, buf.String(), "s3cr3t-value")
+}
+
+func TestRedactHandler_MatchesKeysWithoutCase(t *testing.T) {
+	logger, buf := newRedactLogger("authorization")
+
+	logger.Info("request", "Authorization", "Bearer abc")
+
+	assert.Equal(t, redactedValue, decodeRecord(t, buf)["Authorization"])
+}
+
+func TestRedactHandler_RedactsGroupsAndWithAttrs(t *testing.T) {
+	logger, buf := newRedactLogger("password")
+
+	logger.With("password", "hunter2").Info("signup", slog.Group("form", "password", "hunter2", "name", "bob"))
+
+	record := decodeRecord(t, buf)
+	assert.Equal(t, redactedValue, record["password"])
+	form := record["form"].(map[string]any)
+	assert.Equal(t, redactedValue, form["password"])
+	assert.Equal(t, "bob", form["name"])
+	assert.NotContains(t, buf.String(), "hunter2")
+}
+
+func TestRedactHandler_TruncatesLongValues(t *testing.T) {
+	logger, buf := newRedactLogger()
+
+	logger.Info("payload", "body", strings.Repeat("x", maxLogValueLen+500))
+
+	body := decodeRecord(t, buf)["body"].(string)
+	assert.Equal(t, strings.Repeat("x", maxLogValueLen)+"…", body)
+}
+
+func TestRedactHandler_TruncatesAtRuneBoundary(t *testing.T) {
+	logger, buf := newRedactLogger()
+
+	// The two-byte "é" straddles the truncation point
+	logger.Info("payload", "body", strings.Repeat("x", maxLogValueLen-1)+"é tail")
+
+	body := decodeRecord(t, buf)["body"].(string)
+	assert.Equal(t, strings.Repeat("x", maxLogValueLen-1)+"…", body)
+	assert.NotContains(t, buf.String(), `\ufffd`)
+}
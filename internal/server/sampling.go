@@ -0,0 +1,37 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package server
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/labstack/echo/v4/middleware"
+)
+
+// accessLogSampler decides which requests requestLogger writes. Requests
+// that failed, returned a non-2xx status or took longer than slow are always
+// kept; of the remaining ones only every rate-th is.
+type accessLogSampler struct {
+	count atomic.Uint64
+	rate  uint64
+	slow  time.Duration
+}
+
+// newAccessLogSampler creates a sampler keeping 1 in rate fast successful
+// requests. A rate of 0 or 1 keeps everything, a slow threshold of 0 treats
+// no request as slow.
+func newAccessLogSampler(rate int, slow time.Duration) *accessLogSampler {
+	return &accessLogSampler{rate: uint64(max(rate, 1)), slow: slow}
+}
+
+func (s *accessLogSampler) keep(v *middleware.RequestLoggerValues) bool {
+	if s.rate == 1 || v.Error != nil || v.Status < 200 || v.Status > 299 {
+		return true
+	}
+	if s.slow > 0 && v.Latency > s.slow {
+		return true
+	}
+	return s.count.Add(1)%s.rate == 1
+}
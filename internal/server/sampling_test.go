@@ -0,0 +1,86 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package server
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/oliverandrich/go-webapp-template/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func countKept(s *accessLogSampler, n int, v middleware.RequestLoggerValues) int {
+	kept := 0
+	for range n {
+		if s.keep(&v) {
+			kept++
+		}
+	}
+	return kept
+}
+
+func TestAccessLogSampler_SamplesFastSuccess(t *testing.T) {
+	s := newAccessLogSampler(10, time.Second)
+
+	kept := countKept(s, 1000, middleware.RequestLoggerValues{Status: http.StatusOK, Latency: time.Millisecond})
+
+	assert.Equal(t, 100, kept)
+}
+
+func TestAccessLogSampler_AlwaysKeepsErrorsAndSlowRequests(t *testing.T) {
+	tests := []struct {
+		name string
+		v    middleware.RequestLoggerValues
+	}{
+		{"server error", middleware.RequestLoggerValues{Status: http.StatusInternalServerError}},
+		{"client error", middleware.RequestLoggerValues{Status: http.StatusNotFound}},
+		{"redirect", middleware.RequestLoggerValues{Status: http.StatusSeeOther}},
+		{"handler error", middleware.RequestLoggerValues{Status: http.StatusOK, Error: errors.New("boom")}},
+		{"slow", middleware.RequestLoggerValues{Status: http.StatusOK, Latency: 2 * time.Second}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newAccessLogSampler(100, time.Second)
+			assert.Equal(t, 500, countKept(s, 500, tt.v))
+		})
+	}
+}
+
+func TestAccessLogSampler_DisabledKeepsEverything(t *testing.T) {
+	for _, rate := range []int{0, 1} {
+		s := newAccessLogSampler(rate, 0)
+		assert.Equal(t, 50, countKept(s, 50, middleware.RequestLoggerValues{Status: http.StatusOK, Latency: time.Hour}))
+	}
+}
+
+func TestRequestLogger_Sampling(t *testing.T) {
+	buf := captureLogs(t)
+
+	e := echo.New()
+	e.Use(requestLogger(&config.LogConfig{SampleRate: 5, SlowThreshold: 1000}))
+	e.GET("/ok", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+	e.GET("/fail", func(c echo.Context) error { return errors.New("boom") })
+
+	for range 10 {
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/ok", nil))
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/fail", nil))
+	}
+
+	counts := map[string]int{}
+	for _, line := range logLines(t, buf) {
+		uri, ok := line["uri"].(string)
+		require.True(t, ok)
+		counts[uri]++
+	}
+	assert.Equal(t, 2, counts["/ok"])
+	assert.Equal(t, 10, counts["/fail"])
+}
@@ -26,14 +26,34 @@ import (
 	"github.com/oliverandrich/go-webapp-template/internal/repository"
 	"github.com/oliverandrich/go-webapp-template/internal/services/email"
 	"github.com/oliverandrich/go-webapp-template/internal/services/session"
+	"github.com/oliverandrich/go-webapp-template/internal/services/settings"
 	"github.com/oliverandrich/go-webapp-template/internal/services/webauthn"
+	"github.com/oliverandrich/go-webapp-template/internal/services/webhook"
+	"github.com/oliverandrich/go-webapp-template/internal/signedurl"
+	"github.com/oliverandrich/go-webapp-template/internal/tracing"
 	"github.com/urfave/cli/v3"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 )
 
+// buildInfo is reported by the health endpoints; see SetBuildInfo.
+var buildInfo = handlers.BuildInfo{Version: "dev"}
+
+// SetBuildInfo records the version information injected into main at build
+// time. It must be called before Run.
+func SetBuildInfo(info handlers.BuildInfo) {
+	buildInfo = info
+}
+
+// sessionCleanupInterval is how often expired database sessions are deleted.
+const sessionCleanupInterval = 15 * time.Minute
+
 // Run starts the server with the given CLI command.
 func Run(ctx context.Context, cmd *cli.Command) error {
 	cfg := config.NewFromCLI(cmd)
-	setupLogger(cfg.Log.Level, cfg.Log.Format)
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	setupLogger(&cfg.Log)
 
 	slog.Info("starting server",
 		"host", cfg.Server.Host,
@@ -52,13 +72,50 @@ func Run(ctx context.Context, cmd *cli.Command) error {
 		}
 	}()
 
+	// Cleanup of subsystems, run after the HTTP servers have stopped
+	lifecycle := NewLifecycle()
+
+	// Periodic WAL checkpoints (stopped before the database is closed)
+	checkpointCtx, stopCheckpoints := context.WithCancel(ctx)
+	defer stopCheckpoints()
+	checkpointsDone := database.StartCheckpointer(checkpointCtx, db, cfg.Database.DSN, time.Duration(cfg.Database.CheckpointInterval)*time.Second)
+	lifecycle.OnShutdown(func(ctx context.Context) error {
+		stopCheckpoints()
+		select {
+		case <-checkpointsDone:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+
 	// i18n
+	if lang := cfg.I18n.DefaultLanguage; lang != "" {
+		if langErr := i18n.SetDefaultLanguage(lang); langErr != nil {
+			return langErr
+		}
+	}
 	if initErr := i18n.Init(); initErr != nil {
 		return fmt.Errorf("failed to init i18n: %w", initErr)
 	}
 
 	// Repository
 	repo := repository.New(db)
+	repo.LogQueries(time.Duration(cfg.Database.SlowQueryThreshold) * time.Millisecond)
+
+	// Tracing (optional; without it requests and queries carry no
+	// instrumentation)
+	var tracerProvider *sdktrace.TracerProvider
+	if cfg.Tracing.Enabled {
+		tracerProvider, err = tracing.NewProvider(ctx, &cfg.Tracing, buildInfo.Version)
+		if err != nil {
+			return fmt.Errorf("failed to set up tracing: %w", err)
+		}
+		repo.TraceQueries(tracerProvider)
+		slog.Info("tracing enabled", "endpoint", cfg.Tracing.Endpoint)
+		// Flush spans of the last requests
+		lifecycle.OnShutdown(tracerProvider.Shutdown)
+	}
 
 	// Session Manager
 	secure := strings.HasPrefix(cfg.Server.BaseURL, "https://")
@@ -66,6 +123,25 @@ func Run(ctx context.Context, cmd *cli.Command) error {
 	if err != nil {
 		return fmt.Errorf("failed to create session manager: %w", err)
 	}
+	store, err := session.NewStore(cfg.Session.Backend, sessions, repo)
+	if err != nil {
+		return fmt.Errorf("failed to create session store: %w", err)
+	}
+	if dbStore, ok := store.(*session.DBStore); ok {
+		// Expired rows are never read again; delete them periodically
+		cleanupCtx, stopCleanup := context.WithCancel(ctx)
+		defer stopCleanup()
+		cleanupDone := dbStore.StartCleanup(cleanupCtx, sessionCleanupInterval)
+		lifecycle.OnShutdown(func(ctx context.Context) error {
+			stopCleanup()
+			select {
+			case <-cleanupDone:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+	}
 
 	// WebAuthn Service
 	wa, err := webauthn.NewService(&cfg.WebAuthn)
@@ -81,76 +157,194 @@ func Run(ctx context.Context, cmd *cli.Command) error {
 			return fmt.Errorf("failed to create email service: %w", err)
 		}
 		slog.Info("email authentication enabled")
+		// Quit the pooled SMTP connection
+		lifecycle.OnShutdown(func(context.Context) error {
+			return emailSvc.Close()
+		})
+	}
+
+	// Sign-in links by email (optional, need the email service)
+	var magicLinks *signedurl.Signer
+	if cfg.Auth.MagicLink && emailSvc != nil {
+		magicLinks, err = signedurl.NewFromConfig(&cfg.Session)
+		if err != nil {
+			return fmt.Errorf("failed to create magic link signer: %w", err)
+		}
+		slog.Info("magic link sign-in enabled")
 	}
 
+	// Runtime settings (registration mode etc.)
+	settingsSvc, err := settings.NewService(ctx, repo)
+	if err != nil {
+		return fmt.Errorf("failed to load settings: %w", err)
+	}
+
+	// Webhook Notifier (no-op when no URL is configured)
+	webhooks := webhook.NewNotifier(&cfg.Webhook)
+	if webhooks.Enabled() {
+		slog.Info("webhook notifications enabled", "url", cfg.Webhook.URL)
+	}
+	// Let pending webhook deliveries finish, but leave the rest of the
+	// shutdown budget to the hooks that run after this one
+	lifecycle.OnShutdown(func(ctx context.Context) error {
+		ctx, cancel := context.WithTimeout(ctx, webhookDrainTimeout)
+		defer cancel()
+		if err := webhooks.Wait(ctx); err != nil {
+			return fmt.Errorf("webhook deliveries: %w", err)
+		}
+		return nil
+	})
+
 	// Echo
 	e := echo.New()
 	e.HideBanner = true
 	e.HidePort = true
+	e.HTTPErrorHandler = handlers.HTTPErrorHandler
 
 	// Assets
-	assets := findAssets()
+	assets := findAssets(cfg.Server.PathPrefix)
 
-	// Middleware
-	setupMiddleware(e, cfg, assets)
+	// Middleware (tracing first, so its span covers the whole chain)
+	if tracerProvider != nil {
+		e.Use(tracingMiddleware(tracerProvider))
+	}
+	if mwErr := setupMiddleware(e, cfg, assets); mwErr != nil {
+		return fmt.Errorf("failed to set up middleware: %w", mwErr)
+	}
 
 	// Auth Middleware (after customContext, which sets up *Context)
-	e.Use(AuthMiddleware(sessions, repo))
+	e.Use(AuthMiddleware(store, repo))
+	e.Use(userLanguage())
+	e.Use(maintenanceMiddleware(cfg.Server.Maintenance, settingsSvc))
 
 	// Routes
-	setupRoutes(e, repo, wa, sessions, emailSvc, &cfg.Auth)
+	setupRoutes(e, repo, wa, sessions, store, emailSvc, magicLinks, webhooks, settingsSvc, cfg)
 
 	// Start server
-	return startWithGracefulShutdown(e, cfg)
+	return startWithGracefulShutdown(e, cfg, lifecycle)
 }
 
-func setupRoutes(e *echo.Echo, repo *repository.Repository, wa *webauthn.Service, sessions *session.Manager, emailSvc *email.Service, authCfg *config.AuthConfig) {
+func setupRoutes(e *echo.Echo, repo *repository.Repository, wa *webauthn.Service, sessions *session.Manager, store session.Store, emailSvc *email.Service, magicLinks *signedurl.Signer, webhooks *webhook.Notifier, settingsSvc *settings.Service, cfg *config.Config) {
 	h := handlers.New(repo)
-	auth := handlers.NewAuth(repo, wa, sessions, emailSvc, authCfg)
+	h.SetBuildInfo(buildInfo)
+	auth := handlers.NewAuth(repo, wa, sessions, emailSvc, &cfg.Auth)
+	auth.SetWebhooks(webhooks)
+	auth.SetSettings(settingsSvc)
+	auth.SetMagicLinks(magicLinks)
+	auth.SetSessionStore(store)
+	admin := handlers.NewAdmin(settingsSvc, repo, sessions)
+	admin.SetSessionStore(store)
+	api := handlers.NewAPIAuth(auth)
 
 	// Static files (served from embedded filesystem)
-	e.GET("/static/*", echo.WrapHandler(http.StripPrefix("/static/", assets.FileServer())))
+	e.Match([]string{http.MethodGet, http.MethodHead}, "/static/*",
+		echo.WrapHandler(http.StripPrefix("/static/", assets.FileServer())))
+	e.Match([]string{http.MethodGet, http.MethodHead}, "/favicon.ico", faviconHandler(&cfg.Server, assets.FS()))
+	e.Match([]string{http.MethodGet, http.MethodHead}, "/manifest.webmanifest", manifestHandler(&cfg.Server, assets.FS()))
+	e.Match([]string{http.MethodGet, http.MethodHead}, "/robots.txt", robotsHandler(&cfg.Robots))
+	if len(cfg.SecurityTxt.Contact) > 0 {
+		e.Match([]string{http.MethodGet, http.MethodHead}, securityTxtPath, securityTxtHandler(&cfg.SecurityTxt, cfg.Server.BaseURL))
+	}
 
 	// Public routes
 	e.GET("/health", h.Health)
+	e.GET("/ready", h.Ready)
 	e.GET("/", h.Home)
+	e.GET("/.well-known/change-password", auth.ChangePassword)
 
 	// Protected routes
 	e.GET("/dashboard", h.Dashboard, RequireAuth())
-
-	// Auth routes
-	e.GET("/auth/register", auth.RegisterPage)
-	e.POST("/auth/register/begin", auth.RegisterBegin)
-	e.POST("/auth/register/finish", auth.RegisterFinish)
-	e.GET("/auth/login", auth.LoginPage)
-	e.POST("/auth/login/begin", auth.LoginBegin)
-	e.POST("/auth/login/finish", auth.LoginFinish)
-	e.POST("/auth/logout", auth.Logout)
-	e.GET("/auth/recovery", auth.RecoveryPage)
-	e.POST("/auth/recovery", auth.RecoveryLogin)
-	e.GET("/auth/recovery-codes", auth.RecoveryCodesPage)
+	e.POST("/settings/language", h.SetLanguage, RequireAuth())
+	e.POST("/settings/display-name", h.SetDisplayName, RequireAuth())
+	e.GET("/notifications", h.ListNotifications, RequireAuth())
+	e.POST("/notifications/read", h.MarkAllNotificationsRead, RequireAuth())
+	e.POST("/notifications/:id/read", h.MarkNotificationRead, RequireAuth())
+
+	// Auth routes only accept small payloads
+	authLimit := authBodyLimit(&cfg.Server)
+	public := e.Group("/auth", authLimit)
+	public.GET("/register", auth.RegisterPage)
+	public.POST("/register/begin", auth.RegisterBegin)
+	public.POST("/register/finish", auth.RegisterFinish)
+	public.GET("/login", auth.LoginPage)
+	public.POST("/login/begin", auth.LoginBegin)
+	public.POST("/login/finish", auth.LoginFinish)
+	public.POST("/logout", auth.Logout)
+	public.GET("/recovery", auth.RecoveryPage)
+	public.POST("/recovery", auth.RecoveryLogin)
+	public.GET("/recovery-codes", auth.RecoveryCodesPage)
 
 	// Email verification routes (only functional when email auth is enabled)
-	e.GET("/auth/verify-email", auth.VerifyEmail)
-	e.GET("/auth/verify-pending", auth.VerifyPendingPage)
-	e.POST("/auth/resend-verification", auth.ResendVerification)
+	public.GET("/verify-email", auth.VerifyEmail)
+	public.GET("/verify-pending", auth.VerifyPendingPage)
+	public.POST("/resend-verification", auth.ResendVerification)
+	public.GET("/email/confirm", auth.ChangeEmailConfirm)
+	public.POST("/magic-link", auth.MagicLinkRequest)
+	public.GET("/magic-link", auth.MagicLinkPage)
+	public.POST("/magic-link/consume", auth.MagicLinkConsume)
 
 	// Protected auth routes
-	protected := e.Group("/auth", RequireAuth())
+	protected := e.Group("/auth", authLimit, RequireAuth())
 	protected.GET("/credentials", auth.CredentialsPage)
 	protected.POST("/credentials/begin", auth.AddCredentialBegin)
 	protected.POST("/credentials/finish", auth.AddCredentialFinish)
 	protected.DELETE("/credentials/:id", auth.DeleteCredential)
+	protected.POST("/credentials/revoke-others", auth.RevokeOtherCredentials)
+	protected.POST("/sessions/revoke", auth.SignOutEverywhere)
 	protected.POST("/credentials/recovery-codes", auth.RegenerateRecoveryCodes)
+	protected.POST("/recovery-codes/download", auth.DownloadRecoveryCodes)
+	protected.POST("/email/change", auth.ChangeEmailBegin)
+	protected.POST("/impersonation/stop", admin.StopImpersonation)
+
+	// JSON auth API for single-page applications (CSRF protected like the rest)
+	apiAuth := e.Group("/api/auth", authLimit)
+	apiAuth.POST("/login/begin", api.LoginBegin)
+	apiAuth.POST("/login/finish", api.LoginFinish)
+	apiAuth.POST("/recovery", api.RecoveryLogin)
+	apiAuth.POST("/logout", api.Logout)
+	apiAuth.GET("/me", api.Me)
+
+	// Admin routes
+	adminGroup := e.Group("/admin", RequireAuth(), RequireAdmin())
+	adminGroup.POST("/settings/registration", admin.SetRegistration)
+	adminGroup.POST("/settings/maintenance", admin.SetMaintenance)
+	adminGroup.GET("/users", admin.ListUsers)
+	adminGroup.GET("/analytics/authenticators", admin.AuthenticatorStats)
+	adminGroup.POST("/users/:id/impersonate", admin.Impersonate)
+	adminGroup.POST("/users/:id/logout-all", admin.LogoutAll)
+	adminGroup.POST("/users/:id/recovery-codes", admin.ResetRecoveryCodes)
+
+	// The /auth catch-all goes with the public group: it only answers 404 or
+	// 405, and requiring a login there would redirect wrong methods instead
+	setupMethodNotAllowed(e, spaFallback(&cfg.Server, assets.FS()), map[string]*echo.Group{
+		"/auth":     public,
+		"/api/auth": apiAuth,
+		"/admin":    adminGroup,
+	})
 }
 
-func startWithGracefulShutdown(e *echo.Echo, cfg *config.Config) error {
+func startWithGracefulShutdown(e *echo.Echo, cfg *config.Config, lifecycle *Lifecycle) error {
 	// Setup TLS
 	tlsResult, err := SetupTLS(cfg)
 	if err != nil {
 		return fmt.Errorf("TLS setup failed: %w", err)
 	}
 
+	// Unix socket (plain HTTP behind a reverse proxy)
+	socket := unixSocketPath(&cfg.Server)
+	if socket != "" {
+		if tlsResult.Mode != TLSModeOff {
+			return errors.New("listening on a unix socket requires tls mode off")
+		}
+		ln, listenErr := listenUnix(socket)
+		if listenErr != nil {
+			return fmt.Errorf("failed to listen on unix socket: %w", listenErr)
+		}
+		e.Listener = ln
+		defer removeSocket(socket)
+		slog.Info("listening on unix socket", "path", socket)
+	}
+
 	// Channel for server errors
 	errChan := make(chan error, 2)
 
@@ -159,7 +353,7 @@ func startWithGracefulShutdown(e *echo.Echo, cfg *config.Config) error {
 
 	switch tlsResult.Mode {
 	case TLSModeOff:
-		// Plain HTTP on configured port
+		// Plain HTTP on configured port (or on the Unix socket listener set above)
 		addr := fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port)
 		go func() {
 			slog.Info("Server running", "url", cfg.Server.BaseURL)
@@ -169,6 +363,25 @@ func startWithGracefulShutdown(e *echo.Echo, cfg *config.Config) error {
 		}()
 
 	case TLSModeACME:
+		if tlsResult.DNSManager != nil {
+			// DNS-01: renew in the background and serve HTTPS on the configured port
+			renewCtx, cancelRenew := context.WithCancel(context.Background())
+			go tlsResult.DNSManager.watch(renewCtx, dnsRenewInterval)
+			lifecycle.OnShutdown(func(context.Context) error {
+				cancelRenew()
+				return nil
+			})
+
+			addr := fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port)
+			go func() {
+				slog.Info("Server running", "url", cfg.Server.BaseURL)
+				if err := startTLSServer(e, addr, tlsResult.TLSConfig); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					errChan <- err
+				}
+			}()
+			break
+		}
+
 		// HTTPS on :443
 		go func() {
 			slog.Info("Server running", "url", cfg.Server.BaseURL)
@@ -191,6 +404,19 @@ func startWithGracefulShutdown(e *echo.Echo, cfg *config.Config) error {
 		}()
 
 	case TLSModeSelfSigned, TLSModeManual:
+		// Pick up renewed manual certificates on file change or SIGHUP
+		if tlsResult.Reloader != nil {
+			reloadCtx, cancelReload := context.WithCancel(context.Background())
+			hup := make(chan os.Signal, 1)
+			signal.Notify(hup, syscall.SIGHUP)
+			go tlsResult.Reloader.watch(reloadCtx, certReloadInterval, hup)
+			lifecycle.OnShutdown(func(context.Context) error {
+				signal.Stop(hup)
+				cancelReload()
+				return nil
+			})
+		}
+
 		// HTTPS on configured port
 		addr := fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port)
 		go func() {
@@ -210,11 +436,14 @@ func startWithGracefulShutdown(e *echo.Echo, cfg *config.Config) error {
 		slog.Info("shutting down server")
 	case err := <-errChan:
 		slog.Error("server error", "error", err)
+		hookCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		_ = lifecycle.Shutdown(hookCtx)
 		return err
 	}
 
 	// Graceful shutdown
-	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
 	defer cancel()
 
 	// Shutdown main server
@@ -229,10 +458,57 @@ func startWithGracefulShutdown(e *echo.Echo, cfg *config.Config) error {
 		}
 	}
 
+	// Stop the remaining subsystems; errors are logged by Shutdown
+	_ = lifecycle.Shutdown(shutdownCtx)
+
 	slog.Info("server stopped")
 	return nil
 }
 
+// unixSocketPath returns the Unix socket to listen on, or "" to use TCP.
+// The socket can be set explicitly or via a host of the form "unix:/path".
+func unixSocketPath(cfg *config.ServerConfig) string {
+	if cfg.Socket != "" {
+		return cfg.Socket
+	}
+	if path, ok := strings.CutPrefix(cfg.Host, "unix:"); ok {
+		return path
+	}
+	return ""
+}
+
+// listenUnix listens on a Unix socket, replacing a stale socket file left
+// behind by a previous run. The socket is made group-writable so a reverse
+// proxy in the same group can connect.
+func listenUnix(path string) (net.Listener, error) {
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s exists and is not a socket", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("failed to remove stale socket: %w", err)
+		}
+	}
+
+	lc := &net.ListenConfig{}
+	ln, err := lc.Listen(context.Background(), "unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0o660); err != nil {
+		_ = ln.Close()
+		return nil, err
+	}
+	return ln, nil
+}
+
+// removeSocket deletes the socket file after shutdown.
+func removeSocket(path string) {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		slog.Error("failed to remove unix socket", "path", path, "error", err)
+	}
+}
+
 // startTLSServer starts the Echo server with a custom TLS configuration.
 func startTLSServer(e *echo.Echo, addr string, tlsConfig *tls.Config) error {
 	lc := &net.ListenConfig{}
@@ -0,0 +1,206 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/oliverandrich/go-webapp-template/internal/appcontext"
+	"github.com/oliverandrich/go-webapp-template/internal/config"
+	"github.com/oliverandrich/go-webapp-template/internal/handlers"
+	"github.com/oliverandrich/go-webapp-template/internal/models"
+	"github.com/oliverandrich/go-webapp-template/internal/services/session"
+	"github.com/oliverandrich/go-webapp-template/internal/services/settings"
+	"github.com/oliverandrich/go-webapp-template/internal/services/webauthn"
+	"github.com/oliverandrich/go-webapp-template/internal/services/webhook"
+	"github.com/oliverandrich/go-webapp-template/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnixSocketPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      config.ServerConfig
+		expected string
+	}{
+		{"tcp", config.ServerConfig{Host: "localhost"}, ""},
+		{"socket option", config.ServerConfig{Host: "localhost", Socket: "/run/app.sock"}, "/run/app.sock"},
+		{"unix host", config.ServerConfig{Host: "unix:/run/app.sock"}, "/run/app.sock"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, unixSocketPath(&tt.cfg))
+		})
+	}
+}
+
+func TestListenUnix_ServesRequests(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.sock")
+
+	ln, err := listenUnix(path)
+	require.NoError(t, err)
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o660), info.Mode().Perm())
+
+	e := echo.New()
+	e.HideBanner = true
+	e.HidePort = true
+	e.Listener = ln
+	e.GET("/health", func(c echo.Context) error {
+		return c.String(http.StatusOK, "ok")
+	})
+
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- e.Start("")
+	}()
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", path)
+			},
+		},
+		Timeout: 5 * time.Second,
+	}
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, "http://unix/health", nil)
+	require.NoError(t, err)
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "ok", string(body))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, e.Shutdown(ctx))
+	assert.ErrorIs(t, <-errChan, http.ErrServerClosed)
+
+	removeSocket(path)
+	_, err = os.Stat(path)
+	assert.True(t, errors.Is(err, os.ErrNotExist), "socket file should be removed")
+}
+
+func TestListenUnix_ReplacesStaleSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.sock")
+
+	// Leave a socket file behind without unlinking it
+	stale, err := net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+	require.NoError(t, err)
+	stale.SetUnlinkOnClose(false)
+	require.NoError(t, stale.Close())
+
+	ln, err := listenUnix(path)
+
+	require.NoError(t, err)
+	_ = ln.Close()
+}
+
+func TestListenUnix_RefusesRegularFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.sock")
+	require.NoError(t, os.WriteFile(path, []byte("data"), 0o600))
+
+	_, err := listenUnix(path)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not a socket")
+}
+
+// newTestRoutes returns an Echo instance with all application routes, but
+// without the global middleware stack.
+func newTestRoutes(t *testing.T, cfg *config.Config) *echo.Echo {
+	t.Helper()
+	return newTestRoutesAs(t, cfg, nil)
+}
+
+// newTestRoutesAs is newTestRoutes with every request authenticated as user
+// (anonymous when nil).
+func newTestRoutesAs(t *testing.T, cfg *config.Config, user *models.User) *echo.Echo {
+	t.Helper()
+	_, repo := testutil.NewTestDB(t)
+
+	wa, err := webauthn.NewService(&config.WebAuthnConfig{
+		RPID:          "localhost",
+		RPOrigin:      "http://localhost:8080",
+		RPDisplayName: "Test App",
+	})
+	require.NoError(t, err)
+	sessions, err := session.NewManager(&config.SessionConfig{
+		CookieName: "_session",
+		MaxAge:     3600,
+		HashKey:    "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef",
+	}, false)
+	require.NoError(t, err)
+	settingsSvc, err := settings.NewService(context.Background(), repo)
+	require.NoError(t, err)
+
+	e := echo.New()
+	e.HTTPErrorHandler = handlers.HTTPErrorHandler
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			return next(&appcontext.Context{Context: c, User: user})
+		}
+	})
+	setupRoutes(e, repo, wa, sessions, session.NewCookieStore(sessions, repo), nil, nil, webhook.NewNotifier(&cfg.Webhook), settingsSvc, cfg)
+	return e
+}
+
+func TestSetupRoutes_AuthBodyLimit(t *testing.T) {
+	cfg := &config.Config{Server: config.ServerConfig{MaxBodySize: 1, AuthBodySize: 16}}
+	e := newTestRoutes(t, cfg)
+
+	oversized := `{"username":"someone","code":"` + strings.Repeat("x", 17*1024) + `"}`
+	normal := `{"username":"someone","code":"abcd-efgh-jkmn"}`
+
+	for _, path := range []string{"/auth/recovery", "/api/auth/recovery"} {
+		t.Run(path, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(oversized))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			require.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+
+			// A normal body reaches the handler, which rejects the unknown user
+			req = httptest.NewRequest(http.MethodPost, path, strings.NewReader(normal))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec = httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			require.Equal(t, http.StatusUnauthorized, rec.Code)
+		})
+	}
+}
+
+func TestSetupRoutes_AuthBodyLimitDisabled(t *testing.T) {
+	cfg := &config.Config{Server: config.ServerConfig{MaxBodySize: 1}}
+	e := newTestRoutes(t, cfg)
+
+	body := `{"username":"someone","code":"` + strings.Repeat("x", 17*1024) + `"}`
+	req := httptest.NewRequest(http.MethodPost, "/auth/recovery", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+}
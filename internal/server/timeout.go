@@ -0,0 +1,56 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package server
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// timeoutMiddleware bounds each request with a context deadline. Database
+// calls and other context-aware work give up once it passes, and a request
+// that ran out of time without writing a response is answered with 503. A
+// handler that ignores its context still runs to the end, so the deadline
+// only helps for code that honours it. Paths starting with one of the
+// configured prefixes, e.g. long-lived streams, are not limited.
+func timeoutMiddleware(d time.Duration, excludePrefixes []string) echo.MiddlewareFunc {
+	exclude := make([]string, 0, len(excludePrefixes))
+	for _, prefix := range excludePrefixes {
+		if prefix = strings.TrimSpace(prefix); prefix != "" {
+			exclude = append(exclude, prefix)
+		}
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		if d <= 0 {
+			return next
+		}
+		return func(c echo.Context) error {
+			path := c.Request().URL.Path
+			for _, prefix := range exclude {
+				if strings.HasPrefix(path, prefix) {
+					return next(c)
+				}
+			}
+
+			ctx, cancel := context.WithTimeout(c.Request().Context(), d)
+			defer cancel()
+			c.SetRequest(c.Request().WithContext(ctx))
+
+			err := next(c)
+			if !errors.Is(ctx.Err(), context.DeadlineExceeded) || c.Response().Committed {
+				return err
+			}
+
+			slog.Warn("request timed out", "method", c.Request().Method, "path", path, "timeout", d)
+			return echo.NewHTTPError(http.StatusServiceUnavailable, "request timed out").SetInternal(context.DeadlineExceeded)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/oliverandrich/go-webapp-template/internal/handlers"
+	"github.com/oliverandrich/go-webapp-template/internal/i18n"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// slowHandler waits for the request context to end, or for a second.
+func slowHandler(c echo.Context) error {
+	select {
+	case <-c.Request().Context().Done():
+		return c.Request().Context().Err()
+	case <-time.After(time.Second):
+		return c.String(http.StatusOK, "slow")
+	}
+}
+
+// newTimeoutEcho serves a fast route and two slow ones behind timeoutMiddleware.
+func newTimeoutEcho(t *testing.T, d time.Duration, exclude ...string) *echo.Echo {
+	t.Helper()
+	require.NoError(t, i18n.Init())
+
+	e := echo.New()
+	e.HTTPErrorHandler = handlers.HTTPErrorHandler
+	e.Use(timeoutMiddleware(d, exclude))
+	e.GET("/fast", func(c echo.Context) error { return c.String(http.StatusOK, "fast") })
+	e.GET("/slow", slowHandler)
+	e.GET("/events/stream", slowHandler)
+	return e
+}
+
+func getWithTimeout(e *echo.Echo, path string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set(echo.HeaderAccept, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestTimeoutMiddleware_FastHandlerPasses(t *testing.T) {
+	rec := getWithTimeout(newTimeoutEcho(t, 50*time.Millisecond), "/fast")
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "fast", rec.Body.String())
+}
+
+func TestTimeoutMiddleware_SlowHandlerTimesOut(t *testing.T) {
+	start := time.Now()
+	rec := getWithTimeout(newTimeoutEcho(t, 50*time.Millisecond), "/slow")
+
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Contains(t, rec.Body.String(), "too long to respond")
+}
+
+func TestTimeoutMiddleware_ExcludedPrefix(t *testing.T) {
+	rec := getWithTimeout(newTimeoutEcho(t, 50*time.Millisecond, " ", "/events"), "/events/stream")
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "slow", rec.Body.String())
+}
+
+func TestTimeoutMiddleware_Disabled(t *testing.T) {
+	e := echo.New()
+	e.Use(timeoutMiddleware(0, nil))
+	e.GET("/", func(c echo.Context) error {
+		_, hasDeadline := c.Request().Context().Deadline()
+		assert.False(t, hasDeadline)
+		return c.NoContent(http.StatusOK)
+	})
+
+	assert.Equal(t, http.StatusOK, getWithTimeout(e, "/").Code)
+}
@@ -12,6 +12,7 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"crypto/x509/pkix"
+	"encoding/hex"
 	"encoding/pem"
 	"fmt"
 	"log/slog"
@@ -20,10 +21,12 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/oliverandrich/go-webapp-template/internal/config"
+	"golang.org/x/crypto/acme"
 	"golang.org/x/crypto/acme/autocert"
 )
 
@@ -40,8 +43,10 @@ const (
 // TLSResult contains the resolved TLS configuration.
 type TLSResult struct {
 	TLSConfig   *tls.Config
-	CertManager *autocert.Manager // nil unless ACME mode
-	HTTPHandler http.Handler      // For HTTP→HTTPS redirect (ACME only)
+	CertManager *autocert.Manager // nil unless ACME mode with HTTP-01
+	DNSManager  *dnsCertManager   // nil unless ACME mode with DNS-01
+	Reloader    *certReloader     // nil unless manual mode
+	HTTPHandler http.Handler      // For HTTP→HTTPS redirect (ACME with HTTP-01 only)
 	Mode        TLSMode
 }
 
@@ -62,6 +67,7 @@ func SetupTLS(cfg *config.Config) (*TLSResult, error) {
 		slog.Info("TLS mode: acme (Let's Encrypt)",
 			"host", cfg.Server.Host,
 			"email", cfg.TLS.Email,
+			"challenge", acmeChallenge(cfg),
 		)
 		return setupACME(cfg)
 
@@ -119,8 +125,29 @@ func resolveTLSMode(cfg *config.Config) TLSMode {
 	return TLSModeSelfSigned
 }
 
+// acmeChallenge returns the ACME challenge type in use.
+func acmeChallenge(cfg *config.Config) string {
+	if cfg.TLS.UsesDNSChallenge() {
+		return "dns-01"
+	}
+	return "http-01"
+}
+
+// portAvailable is isPortAvailable, replaceable in tests.
+var portAvailable = isPortAvailable
+
 // validateACME checks requirements when ACME mode is explicitly selected.
 func validateACME(cfg *config.Config) error {
+	// Validate email is provided
+	if cfg.TLS.Email == "" {
+		return fmt.Errorf("ACME mode requires TLS_EMAIL to be set")
+	}
+
+	// DNS-01 needs no inbound connections, so the configured port is used
+	if cfg.TLS.UsesDNSChallenge() {
+		return nil
+	}
+
 	// Warn if configured port is not 443
 	if cfg.Server.Port != 443 {
 		slog.Warn("ACME mode uses port 443, configured port will be ignored",
@@ -128,18 +155,13 @@ func validateACME(cfg *config.Config) error {
 		)
 	}
 
-	// Validate email is provided
-	if cfg.TLS.Email == "" {
-		return fmt.Errorf("ACME mode requires TLS_EMAIL to be set")
-	}
-
 	// Check if port 80 is available (required for HTTP-01 challenge)
-	if !isPortAvailable(80) {
+	if !portAvailable(80) {
 		return fmt.Errorf("ACME mode requires port 80 for HTTP-01 challenge (port in use)")
 	}
 
 	// Check if port 443 is available
-	if !isPortAvailable(443) {
+	if !portAvailable(443) {
 		return fmt.Errorf("ACME mode requires port 443 for HTTPS (port in use)")
 	}
 
@@ -167,14 +189,19 @@ func canUseACME(cfg *config.Config) bool {
 		return false
 	}
 
+	// DNS-01 works without ports 80 and 443
+	if cfg.TLS.UsesDNSChallenge() {
+		return true
+	}
+
 	// Check if port 80 is available (required for HTTP-01 challenge)
-	if !isPortAvailable(80) {
+	if !portAvailable(80) {
 		slog.Debug("ACME disabled: port 80 not available")
 		return false
 	}
 
 	// Check if port 443 is available
-	if !isPortAvailable(443) {
+	if !portAvailable(443) {
 		slog.Debug("ACME disabled: port 443 not available")
 		return false
 	}
@@ -194,8 +221,13 @@ func isPortAvailable(port int) bool {
 	return true
 }
 
-// setupACME configures Let's Encrypt with autocert.
+// setupACME configures Let's Encrypt with autocert, or with dnsCertManager
+// when the DNS-01 challenge is selected.
 func setupACME(cfg *config.Config) (*TLSResult, error) {
+	if cfg.TLS.UsesDNSChallenge() {
+		return setupACMEDNS(cfg)
+	}
+
 	certDir := filepath.Join(cfg.TLS.CertDir, "acme")
 	if err := os.MkdirAll(certDir, 0o700); err != nil {
 		return nil, fmt.Errorf("failed to create ACME cert directory: %w", err)
@@ -205,13 +237,16 @@ func setupACME(cfg *config.Config) (*TLSResult, error) {
 		Prompt:     autocert.AcceptTOS,
 		Email:      cfg.TLS.Email,
 		Cache:      autocert.DirCache(certDir),
-		HostPolicy: autocert.HostWhitelist(cfg.Server.Host),
+		HostPolicy: acmeHostPolicy(cfg.Server.Host, cfg.TLS.AllowedHosts),
+	}
+	if cfg.TLS.ACMEDirectoryURL != "" {
+		manager.Client = &acme.Client{DirectoryURL: cfg.TLS.ACMEDirectoryURL}
 	}
 
 	tlsConfig := manager.TLSConfig()
 	tlsConfig.MinVersion = tls.VersionTLS12
 
-	slog.Info("Using Let's Encrypt for domain", "host", cfg.Server.Host)
+	slog.Info("Using Let's Encrypt for domain", "host", cfg.Server.Host, "allowed_hosts", cfg.TLS.AllowedHosts)
 
 	return &TLSResult{
 		Mode:        TLSModeACME,
@@ -221,6 +256,37 @@ func setupACME(cfg *config.Config) (*TLSResult, error) {
 	}, nil
 }
 
+// setupACMEDNS obtains the certificate via the DNS-01 challenge before the
+// server starts, reusing a cached certificate when it is still valid.
+func setupACMEDNS(cfg *config.Config) (*TLSResult, error) {
+	certDir := filepath.Join(cfg.TLS.CertDir, "acme-dns")
+	if err := os.MkdirAll(certDir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create ACME cert directory: %w", err)
+	}
+
+	manager, err := newDNSCertManager(cfg, certDir)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), dnsIssueTimeout)
+	defer cancel()
+	if err := manager.ensure(ctx); err != nil {
+		return nil, fmt.Errorf("failed to obtain certificate via DNS-01: %w", err)
+	}
+
+	slog.Info("Using ACME certificate for domains", "domains", manager.domains)
+
+	return &TLSResult{
+		Mode: TLSModeACME,
+		TLSConfig: &tls.Config{
+			GetCertificate: manager.GetCertificate,
+			MinVersion:     tls.VersionTLS12,
+		},
+		DNSManager: manager,
+	}, nil
+}
+
 // setupSelfSigned generates or loads a self-signed certificate.
 func setupSelfSigned(cfg *config.Config) (*TLSResult, error) {
 	certDir := filepath.Join(cfg.TLS.CertDir, "selfsigned")
@@ -230,11 +296,16 @@ func setupSelfSigned(cfg *config.Config) (*TLSResult, error) {
 
 	certFile := filepath.Join(certDir, "cert.pem")
 	keyFile := filepath.Join(certDir, "key.pem")
+	sansFile := filepath.Join(certDir, "sans.sha256")
 
-	// Check if cert exists and is valid
+	dnsNames, ipAddresses := selfSignedSANs(cfg)
+	sansHash := hashSANs(dnsNames, ipAddresses)
+
+	// Check if cert exists, is valid and covers the requested SANs
 	if certExists(certFile, keyFile) {
 		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
-		if err == nil && !isCertExpiringSoon(&cert) {
+		sansChanged := readSANsHash(sansFile) != sansHash
+		if err == nil && !isCertExpiringSoon(&cert) && !sansChanged {
 			slog.Info("Using existing self-signed certificate")
 			logCertFingerprint(&cert)
 			logSelfSignedWarning()
@@ -243,19 +314,25 @@ func setupSelfSigned(cfg *config.Config) (*TLSResult, error) {
 				TLSConfig: createTLSConfig(&cert),
 			}, nil
 		}
-		if err != nil {
+		switch {
+		case err != nil:
 			slog.Warn("existing certificate invalid, generating new one", "error", err)
-		} else {
+		case sansChanged:
+			slog.Info("certificate SANs changed, generating new one")
+		default:
 			slog.Info("existing certificate expiring soon, generating new one")
 		}
 	}
 
 	// Generate new certificate
-	slog.Info("Generating new self-signed certificate")
+	slog.Info("Generating new self-signed certificate", "dns", dnsNames, "ips", ipAddresses)
 	cert, err := generateSelfSignedCert(cfg, certFile, keyFile)
 	if err != nil {
 		return nil, err
 	}
+	if writeErr := os.WriteFile(sansFile, []byte(sansHash), 0o600); writeErr != nil {
+		return nil, fmt.Errorf("failed to write SAN hash file: %w", writeErr)
+	}
 
 	logCertFingerprint(cert)
 	logSelfSignedWarning()
@@ -284,18 +361,22 @@ func setupManual(cfg *config.Config) (*TLSResult, error) {
 		return nil, fmt.Errorf("key file not found: %w", err)
 	}
 
-	// Load certificate
-	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	// Load certificate; it is reloaded when the files change (see certReloader)
+	reloader, err := newCertReloader(certFile, keyFile)
 	if err != nil {
-		return nil, fmt.Errorf("failed to load certificate: %w", err)
+		return nil, err
 	}
 
 	slog.Info("Using manual certificate", "cert", certFile, "key", keyFile)
-	logCertFingerprint(&cert)
+	logCertFingerprint(reloader.cert.Load())
 
 	return &TLSResult{
-		Mode:      TLSModeManual,
-		TLSConfig: createTLSConfig(&cert),
+		Mode: TLSModeManual,
+		TLSConfig: &tls.Config{
+			GetCertificate: reloader.GetCertificate,
+			MinVersion:     tls.VersionTLS12,
+		},
+		Reloader: reloader,
 	}, nil
 }
 
@@ -328,16 +409,7 @@ func generateSelfSignedCert(cfg *config.Config, certFile, keyFile string) (*tls.
 	}
 
 	// Add SANs (Subject Alternative Names)
-	host := cfg.Server.Host
-	if ip := net.ParseIP(host); ip != nil {
-		template.IPAddresses = []net.IP{ip}
-	} else {
-		template.DNSNames = []string{host}
-	}
-
-	// Also add localhost for local access
-	template.DNSNames = append(template.DNSNames, "localhost")
-	template.IPAddresses = append(template.IPAddresses, net.ParseIP("127.0.0.1"), net.ParseIP("::1"))
+	template.DNSNames, template.IPAddresses = selfSignedSANs(cfg)
 
 	// Generate certificate
 	certDER, err := x509.CreateCertificate(rand.Reader, &template, &template, &privateKey.PublicKey, privateKey)
@@ -370,6 +442,66 @@ func generateSelfSignedCert(cfg *config.Config, certFile, keyFile string) (*tls.
 	return &cert, nil
 }
 
+// selfSignedSANs returns the DNS names and IPs for the self-signed certificate:
+// the configured host, localhost, and any extra SANs from the TLS config.
+func selfSignedSANs(cfg *config.Config) ([]string, []net.IP) {
+	var dnsNames []string
+	var ipAddresses []net.IP
+
+	add := func(name string) {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return
+		}
+		if ip := net.ParseIP(name); ip != nil {
+			if !slices.ContainsFunc(ipAddresses, ip.Equal) {
+				ipAddresses = append(ipAddresses, ip)
+			}
+			return
+		}
+		if !slices.Contains(dnsNames, name) {
+			dnsNames = append(dnsNames, name)
+		}
+	}
+
+	add(cfg.Server.Host)
+
+	// Also add localhost for local access
+	add("localhost")
+	add("127.0.0.1")
+	add("::1")
+
+	for _, san := range cfg.TLS.ExtraSANs {
+		add(san)
+	}
+
+	return dnsNames, ipAddresses
+}
+
+// hashSANs returns a stable hash of the SAN set, used to detect changes.
+func hashSANs(dnsNames []string, ipAddresses []net.IP) string {
+	entries := make([]string, 0, len(dnsNames)+len(ipAddresses))
+	for _, name := range dnsNames {
+		entries = append(entries, "dns:"+strings.ToLower(name))
+	}
+	for _, ip := range ipAddresses {
+		entries = append(entries, "ip:"+ip.String())
+	}
+	slices.Sort(entries)
+
+	sum := sha256.Sum256([]byte(strings.Join(entries, "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+// readSANsHash reads the stored SAN hash, returning "" if it is missing.
+func readSANsHash(path string) string {
+	data, err := os.ReadFile(path) //nolint:gosec // path is derived from config
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
 // certExists checks if both cert and key files exist.
 func certExists(certFile, keyFile string) bool {
 	_, certErr := os.Stat(certFile)
@@ -0,0 +1,163 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package server
+
+import (
+	"net"
+	"testing"
+
+	"github.com/oliverandrich/go-webapp-template/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newSelfSignedConfig(t *testing.T, extraSANs ...string) *config.Config {
+	t.Helper()
+	return &config.Config{
+		Server: config.ServerConfig{Host: "localhost"},
+		TLS: config.TLSConfig{
+			Mode:      "selfsigned",
+			CertDir:   t.TempDir(),
+			ExtraSANs: extraSANs,
+		},
+	}
+}
+
+func TestSelfSignedSANs(t *testing.T) {
+	cfg := newSelfSignedConfig(t, "myapp.test", "10.0.0.5", " ", "localhost")
+	cfg.Server.Host = "192.168.1.50"
+
+	dnsNames, ips := selfSignedSANs(cfg)
+
+	assert.Equal(t, []string{"localhost", "myapp.test"}, dnsNames)
+	require.Len(t, ips, 4)
+	assert.True(t, ips[0].Equal(net.ParseIP("192.168.1.50")))
+	assert.True(t, ips[3].Equal(net.ParseIP("10.0.0.5")))
+}
+
+func TestSetupSelfSigned_IncludesExtraSANs(t *testing.T) {
+	cfg := newSelfSignedConfig(t, "myapp.test", "10.0.0.5")
+
+	result, err := setupSelfSigned(cfg)
+	require.NoError(t, err)
+
+	leaf := result.TLSConfig.Certificates[0].Leaf
+	require.NotNil(t, leaf)
+	assert.Contains(t, leaf.DNSNames, "myapp.test")
+	assert.Contains(t, leaf.DNSNames, "localhost")
+	assert.True(t, containsIP(leaf.IPAddresses, "10.0.0.5"))
+	assert.True(t, containsIP(leaf.IPAddresses, "127.0.0.1"))
+}
+
+func TestSetupSelfSigned_ReusesCertWhenSANsUnchanged(t *testing.T) {
+	cfg := newSelfSignedConfig(t, "myapp.test")
+
+	first, err := setupSelfSigned(cfg)
+	require.NoError(t, err)
+	second, err := setupSelfSigned(cfg)
+	require.NoError(t, err)
+
+	assert.Equal(t, first.TLSConfig.Certificates[0].Leaf.SerialNumber, second.TLSConfig.Certificates[0].Leaf.SerialNumber)
+}
+
+func TestSetupSelfSigned_RegeneratesWhenSANsChange(t *testing.T) {
+	cfg := newSelfSignedConfig(t, "myapp.test")
+
+	first, err := setupSelfSigned(cfg)
+	require.NoError(t, err)
+
+	cfg.TLS.ExtraSANs = []string{"myapp.test", "other.test"}
+	second, err := setupSelfSigned(cfg)
+	require.NoError(t, err)
+
+	leaf := second.TLSConfig.Certificates[0].Leaf
+	assert.NotEqual(t, first.TLSConfig.Certificates[0].Leaf.SerialNumber, leaf.SerialNumber)
+	assert.Contains(t, leaf.DNSNames, "other.test")
+}
+
+func TestHashSANs_OrderIndependent(t *testing.T) {
+	ips := []net.IP{net.ParseIP("127.0.0.1"), net.ParseIP("::1")}
+
+	a := hashSANs([]string{"localhost", "myapp.test"}, ips)
+	b := hashSANs([]string{"myapp.test", "localhost"}, []net.IP{ips[1], ips[0]})
+
+	assert.Equal(t, a, b)
+	assert.NotEqual(t, a, hashSANs([]string{"localhost"}, ips))
+}
+
+func containsIP(ips []net.IP, want string) bool {
+	target := net.ParseIP(want)
+	for _, ip := range ips {
+		if ip.Equal(target) {
+			return true
+		}
+	}
+	return false
+}
+
+func newACMEConfig(t *testing.T) *config.Config {
+	t.Helper()
+	return &config.Config{
+		Server: config.ServerConfig{Host: "example.com", Port: 8443},
+		TLS: config.TLSConfig{
+			Mode:    "acme",
+			CertDir: t.TempDir(),
+			Email:   "admin@example.com",
+		},
+	}
+}
+
+// stubPortsInUse makes every port look taken for the duration of the test.
+func stubPortsInUse(t *testing.T) {
+	t.Helper()
+	orig := portAvailable
+	portAvailable = func(int) bool { return false }
+	t.Cleanup(func() { portAvailable = orig })
+}
+
+func TestSetupACME_DirectoryURL(t *testing.T) {
+	cfg := newACMEConfig(t)
+	cfg.TLS.ACMEDirectoryURL = "https://acme-staging-v02.api.letsencrypt.org/directory"
+
+	result, err := setupACME(cfg)
+	require.NoError(t, err)
+
+	require.NotNil(t, result.CertManager.Client)
+	assert.Equal(t, cfg.TLS.ACMEDirectoryURL, result.CertManager.Client.DirectoryURL)
+	assert.NotNil(t, result.HTTPHandler)
+}
+
+func TestSetupACME_DefaultDirectory(t *testing.T) {
+	result, err := setupACME(newACMEConfig(t))
+	require.NoError(t, err)
+
+	// autocert falls back to Let's Encrypt production
+	assert.Nil(t, result.CertManager.Client)
+}
+
+func TestValidateACME_HTTPChallengeRequiresPorts(t *testing.T) {
+	stubPortsInUse(t)
+
+	err := validateACME(newACMEConfig(t))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "port 80")
+}
+
+func TestValidateACME_DNSChallengeSkipsPortCheck(t *testing.T) {
+	stubPortsInUse(t)
+	cfg := newACMEConfig(t)
+	cfg.TLS.ACMEChallenge = "dns-01"
+
+	assert.NoError(t, validateACME(cfg))
+}
+
+func TestCanUseACME_DNSChallengeSkipsPortCheck(t *testing.T) {
+	stubPortsInUse(t)
+	cfg := newACMEConfig(t)
+
+	assert.False(t, canUseACME(cfg))
+
+	cfg.TLS.ACMEChallenge = "dns-01"
+	assert.True(t, canUseACME(cfg))
+}
@@ -0,0 +1,74 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/oliverandrich/go-webapp-template/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracingMiddleware starts a server span for every request, continuing a
+// trace passed in W3C traceparent headers. The span is named after the
+// matched route and carries the method, route, status and request ID. It is
+// stored in the request context, so repository queries become child spans.
+// It is only installed when tracing is enabled.
+func tracingMiddleware(tp trace.TracerProvider) echo.MiddlewareFunc {
+	tracer := tp.Tracer(tracing.TracerName)
+	propagator := propagation.TraceContext{}
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			ctx := propagator.Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+			ctx, span := tracer.Start(ctx, req.Method,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					semconv.HTTPRequestMethodKey.String(req.Method),
+					semconv.URLPath(req.URL.Path),
+				),
+			)
+			defer span.End()
+			c.SetRequest(req.WithContext(ctx))
+
+			err := next(c)
+
+			if route := c.Path(); route != "" {
+				span.SetName(req.Method + " " + route)
+				span.SetAttributes(semconv.HTTPRoute(route))
+			}
+			if id := c.Response().Header().Get(echo.HeaderXRequestID); id != "" {
+				span.SetAttributes(attribute.String("request.id", id))
+			}
+			status := responseStatus(c, err)
+			span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+			if status >= http.StatusInternalServerError {
+				if err != nil {
+					span.RecordError(err)
+				}
+				span.SetStatus(codes.Error, http.StatusText(status))
+			}
+			return err
+		}
+	}
+}
+
+// responseStatus returns the status code the client receives for a request
+// whose handler chain returned err.
+func responseStatus(c echo.Context, err error) int {
+	if err == nil || c.Response().Committed {
+		return c.Response().Status
+	}
+	var he *echo.HTTPError
+	if errors.As(err, &he) {
+		return he.Code
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,95 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package server
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// newTracedEcho serves a few routes behind tracingMiddleware and records
+// the finished spans in the returned exporter.
+func newTracedEcho(t *testing.T) (*echo.Echo, *tracetest.InMemoryExporter) {
+	t.Helper()
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	t.Cleanup(func() { _ = tp.Shutdown(t.Context()) })
+
+	e := echo.New()
+	e.Use(tracingMiddleware(tp))
+	e.Use(requestID())
+	e.GET("/users/:id", func(c echo.Context) error {
+		return c.String(http.StatusOK, "ok")
+	})
+	e.GET("/fail", func(echo.Context) error {
+		return errors.New("boom")
+	})
+	return e, exporter
+}
+
+// spanAttributes returns the attributes of span keyed by name.
+func spanAttributes(span tracetest.SpanStub) map[attribute.Key]attribute.Value {
+	attrs := make(map[attribute.Key]attribute.Value)
+	for _, kv := range span.Attributes {
+		attrs[kv.Key] = kv.Value
+	}
+	return attrs
+}
+
+func TestTracingMiddleware_RecordsServerSpan(t *testing.T) {
+	e, exporter := newTracedEcho(t)
+
+	rec := serve(e, httptest.NewRequest(http.MethodGet, "/users/42", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	span := spans[0]
+	assert.Equal(t, "GET /users/:id", span.Name)
+	assert.Equal(t, trace.SpanKindServer, span.SpanKind)
+	attrs := spanAttributes(span)
+	assert.Equal(t, "GET", attrs["http.request.method"].AsString())
+	assert.Equal(t, "/users/:id", attrs["http.route"].AsString())
+	assert.Equal(t, "/users/42", attrs["url.path"].AsString())
+	assert.Equal(t, int64(http.StatusOK), attrs["http.response.status_code"].AsInt64())
+	assert.Equal(t, rec.Header().Get(echo.HeaderXRequestID), attrs["request.id"].AsString())
+	assert.NotEmpty(t, attrs["request.id"].AsString())
+	assert.Equal(t, codes.Unset, span.Status.Code)
+}
+
+func TestTracingMiddleware_MarksServerErrors(t *testing.T) {
+	e, exporter := newTracedEcho(t)
+
+	rec := serve(e, httptest.NewRequest(http.MethodGet, "/fail", nil))
+
+	require.Equal(t, http.StatusInternalServerError, rec.Code)
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, int64(http.StatusInternalServerError), spanAttributes(spans[0])["http.response.status_code"].AsInt64())
+	assert.Equal(t, codes.Error, spans[0].Status.Code)
+}
+
+func TestTracingMiddleware_ContinuesUpstreamTrace(t *testing.T) {
+	e, exporter := newTracedEcho(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	serve(e, req)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", spans[0].SpanContext.TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", spans[0].Parent.SpanID().String())
+}
@@ -0,0 +1,81 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package server
+
+import (
+	"errors"
+	"io/fs"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/oliverandrich/go-webapp-template/internal/config"
+)
+
+// defaultRobots lets crawlers index the site, except for endpoints without
+// public content.
+const defaultRobots = `User-agent: *
+Disallow: /admin/
+Disallow: /api/
+Disallow: /auth/
+`
+
+// noIndexRobots keeps all crawlers out.
+const noIndexRobots = `User-agent: *
+Disallow: /
+`
+
+// securityTxtPath is where RFC 9116 clients look for security.txt.
+const securityTxtPath = "/.well-known/security.txt"
+
+// robotsHandler serves /robots.txt: everything disallowed with NoIndex,
+// otherwise the configured file or the built-in default.
+func robotsHandler(cfg *config.RobotsConfig) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if cfg.NoIndex {
+			return c.String(http.StatusOK, noIndexRobots)
+		}
+		if cfg.File == "" {
+			return c.String(http.StatusOK, defaultRobots)
+		}
+		robots, err := os.ReadFile(cfg.File)
+		if errors.Is(err, fs.ErrNotExist) {
+			return echo.ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		return c.Blob(http.StatusOK, echo.MIMETextPlainCharsetUTF8, robots)
+	}
+}
+
+// securityTxtHandler serves /.well-known/security.txt built from cfg. Without
+// an Expires value the file expires one year after the request, the maximum
+// RFC 9116 recommends. It must only be routed when cfg.Contact is set.
+func securityTxtHandler(cfg *config.SecurityTxtConfig, baseURL string) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		var b strings.Builder
+		for _, contact := range cfg.Contact {
+			b.WriteString("Contact: " + contact + "\n")
+		}
+		expires := cfg.Expires
+		if expires == "" {
+			expires = time.Now().UTC().AddDate(1, 0, 0).Truncate(24 * time.Hour).Format(time.RFC3339)
+		}
+		b.WriteString("Expires: " + expires + "\n")
+		if cfg.Encryption != "" {
+			b.WriteString("Encryption: " + cfg.Encryption + "\n")
+		}
+		if cfg.Policy != "" {
+			b.WriteString("Policy: " + cfg.Policy + "\n")
+		}
+		if cfg.PreferredLanguages != "" {
+			b.WriteString("Preferred-Languages: " + cfg.PreferredLanguages + "\n")
+		}
+		b.WriteString("Canonical: " + strings.TrimSuffix(baseURL, "/") + securityTxtPath + "\n")
+		return c.String(http.StatusOK, b.String())
+	}
+}
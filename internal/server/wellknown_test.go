@@ -0,0 +1,110 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/oliverandrich/go-webapp-template/internal/config"
+	"github.com/oliverandrich/go-webapp-template/internal/i18n"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func getRobots(t *testing.T, cfg *config.RobotsConfig) *httptest.ResponseRecorder {
+	t.Helper()
+	e := echo.New()
+	e.GET("/robots.txt", robotsHandler(cfg))
+	return serve(e, httptest.NewRequest(http.MethodGet, "/robots.txt", nil))
+}
+
+func TestRobots_Default(t *testing.T) {
+	rec := getRobots(t, &config.RobotsConfig{})
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, echo.MIMETextPlainCharsetUTF8, rec.Header().Get(echo.HeaderContentType))
+	assert.Equal(t, defaultRobots, rec.Body.String())
+	assert.NotContains(t, rec.Body.String(), "Disallow: /\n")
+}
+
+func TestRobots_NoIndex(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "robots.txt")
+	require.NoError(t, os.WriteFile(path, []byte("User-agent: *\nAllow: /\n"), 0o600))
+
+	// NoIndex wins over a configured file
+	rec := getRobots(t, &config.RobotsConfig{NoIndex: true, File: path})
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "User-agent: *\nDisallow: /\n", rec.Body.String())
+}
+
+func TestRobots_ConfiguredFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "robots.txt")
+	require.NoError(t, os.WriteFile(path, []byte("User-agent: *\nAllow: /\n"), 0o600))
+
+	rec := getRobots(t, &config.RobotsConfig{File: path})
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, echo.MIMETextPlainCharsetUTF8, rec.Header().Get(echo.HeaderContentType))
+	assert.Equal(t, "User-agent: *\nAllow: /\n", rec.Body.String())
+}
+
+func TestSecurityTxt_Fields(t *testing.T) {
+	e := echo.New()
+	e.GET(securityTxtPath, securityTxtHandler(&config.SecurityTxtConfig{
+		Contact:            []string{"mailto:security@example.com", "https://example.com/security"},
+		Expires:            "2030-01-01T00:00:00Z",
+		Encryption:         "https://example.com/pgp.asc",
+		Policy:             "https://example.com/disclosure",
+		PreferredLanguages: "en, de",
+	}, "https://example.com/"))
+
+	rec := serve(e, httptest.NewRequest(http.MethodGet, securityTxtPath, nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, echo.MIMETextPlainCharsetUTF8, rec.Header().Get(echo.HeaderContentType))
+	assert.Equal(t, `Contact: mailto:security@example.com
+Contact: https://example.com/security
+Expires: 2030-01-01T00:00:00Z
+Encryption: https://example.com/pgp.asc
+Policy: https://example.com/disclosure
+Preferred-Languages: en, de
+Canonical: https://example.com/.well-known/security.txt
+`, rec.Body.String())
+}
+
+func TestSecurityTxt_DefaultExpiry(t *testing.T) {
+	e := echo.New()
+	e.GET(securityTxtPath, securityTxtHandler(&config.SecurityTxtConfig{
+		Contact: []string{"mailto:security@example.com"},
+	}, "https://example.com"))
+
+	rec := serve(e, httptest.NewRequest(http.MethodGet, securityTxtPath, nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	var expires string
+	for line := range strings.Lines(rec.Body.String()) {
+		if v, ok := strings.CutPrefix(line, "Expires: "); ok {
+			expires = strings.TrimSpace(v)
+		}
+	}
+	at, err := time.Parse(time.RFC3339, expires)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().AddDate(1, 0, 0), at, 25*time.Hour)
+	assert.NotContains(t, rec.Body.String(), "Policy:")
+}
+
+func TestSecurityTxt_NotRoutedWithoutContact(t *testing.T) {
+	require.NoError(t, i18n.Init())
+	e := newTestRoutes(t, &config.Config{Server: config.ServerConfig{MaxBodySize: 1}})
+
+	assert.Equal(t, http.StatusNotFound, serve(e, httptest.NewRequest(http.MethodGet, securityTxtPath, nil)).Code)
+}
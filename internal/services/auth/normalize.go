@@ -0,0 +1,144 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+// Package auth canonicalizes the identifiers users register and sign in with,
+// so that lookups and uniqueness checks don't depend on how they were typed.
+package auth
+
+import (
+	"errors"
+	"net/mail"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxDisplayNameLength is the maximum length of a display name in characters.
+const MaxDisplayNameLength = 64
+
+// MaxEmailLength is the maximum length of an email address in bytes, the
+// limit for a forward path in RFC 5321.
+const MaxEmailLength = 254
+
+// MaxUsernameLength is the maximum length of a username in characters, as
+// limited by the users table.
+const MaxUsernameLength = 64
+
+// Errors returned by ValidateUsername.
+var (
+	ErrUsernameEmpty      = errors.New("username is required")
+	ErrUsernameTooLong    = errors.New("username is too long")
+	ErrUsernameWhitespace = errors.New("username has leading or trailing whitespace")
+	ErrUsernameCharacters = errors.New("username contains invalid characters")
+)
+
+// NormalizeEmail returns the canonical form of an email address: trimmed and
+// lowercased. With collapseGmail, dots and "+tag" suffixes are removed from
+// Gmail addresses and googlemail.com becomes gmail.com, since Gmail delivers
+// all of these variants to the same mailbox.
+func NormalizeEmail(email string, collapseGmail bool) string {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if !collapseGmail {
+		return email
+	}
+
+	at := strings.LastIndexByte(email, '@')
+	if at < 0 {
+		return email
+	}
+	local, domain := email[:at], email[at+1:]
+	if domain != "gmail.com" && domain != "googlemail.com" {
+		return email
+	}
+
+	if plus := strings.IndexByte(local, '+'); plus >= 0 {
+		local = local[:plus]
+	}
+	local = strings.ReplaceAll(local, ".", "")
+	if local == "" {
+		return email
+	}
+	return local + "@gmail.com"
+}
+
+// NormalizeUsername trims surrounding whitespace. Case is preserved, as
+// usernames are shown as entered.
+func NormalizeUsername(username string) string {
+	return strings.TrimSpace(username)
+}
+
+// NormalizeDisplayName trims surrounding whitespace and collapses inner runs
+// of whitespace into single spaces.
+func NormalizeDisplayName(name string) string {
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// ValidEmail reports whether a normalized email address is a plain
+// addr-spec such as "user@example.com", without a display name or angle
+// brackets, and at most MaxEmailLength bytes long.
+func ValidEmail(email string) bool {
+	if email == "" || len(email) > MaxEmailLength {
+		return false
+	}
+	addr, err := mail.ParseAddress(email)
+	return err == nil && addr.Name == "" && addr.Address == email
+}
+
+// ValidDisplayName reports whether a normalized display name is at most
+// MaxDisplayNameLength characters long and free of control characters.
+// The empty name is valid; it stands for the username.
+func ValidDisplayName(name string) bool {
+	if !utf8.ValidString(name) || utf8.RuneCountInString(name) > MaxDisplayNameLength {
+		return false
+	}
+	return !strings.ContainsFunc(name, unicode.IsControl)
+}
+
+// ValidateUsername checks a username for registration: 1 to maxLength
+// characters (MaxUsernameLength when maxLength is 0 or larger), no leading
+// or trailing whitespace, and only letters, digits, ".", "_" and "-".
+func ValidateUsername(name string, maxLength int) error {
+	if maxLength <= 0 || maxLength > MaxUsernameLength {
+		maxLength = MaxUsernameLength
+	}
+
+	switch {
+	case name == "":
+		return ErrUsernameEmpty
+	case !utf8.ValidString(name):
+		return ErrUsernameCharacters
+	case utf8.RuneCountInString(name) > maxLength:
+		return ErrUsernameTooLong
+	case strings.TrimSpace(name) != name:
+		return ErrUsernameWhitespace
+	case strings.ContainsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '.' && r != '_' && r != '-'
+	}):
+		return ErrUsernameCharacters
+	}
+	return nil
+}
+
+// MaxUsernameCandidates is the number of variants UsernameCandidates tries.
+const MaxUsernameCandidates = 20
+
+// UsernameCandidates returns variants of base to suggest when it is taken,
+// such as "base2", "base_" and "base_2", in order of preference. Of the
+// first MaxUsernameCandidates variants, only those that pass ValidateUsername
+// with maxLength are returned.
+func UsernameCandidates(base string, maxLength int) []string {
+	variants := make([]string, 0, MaxUsernameCandidates)
+	variants = append(variants, base+"2", base+"_")
+	for i := 3; len(variants) < MaxUsernameCandidates; i++ {
+		variants = append(variants, base+strconv.Itoa(i), base+"_"+strconv.Itoa(i-1))
+	}
+
+	candidates := variants[:0]
+	for _, variant := range variants[:MaxUsernameCandidates] {
+		if ValidateUsername(variant, maxLength) == nil {
+			candidates = append(candidates, variant)
+		}
+	}
+	return candidates
+}
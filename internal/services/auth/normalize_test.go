@@ -0,0 +1,114 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package auth_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/oliverandrich/go-webapp-template/internal/services/auth"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeEmail(t *testing.T) {
+	tests := []struct {
+		name          string
+		email         string
+		collapseGmail bool
+		want          string
+	}{
+		{"lowercases and trims", "  Test@Example.COM ", false, "test@example.com"},
+		{"keeps gmail aliases by default", "John.Doe+news@Gmail.com", false, "john.doe+news@gmail.com"},
+		{"collapses gmail dots and tags", "John.Doe+news@Gmail.com", true, "johndoe@gmail.com"},
+		{"maps googlemail to gmail", "john.doe@googlemail.com", true, "johndoe@gmail.com"},
+		{"leaves other domains alone", "john.doe+news@example.com", true, "john.doe+news@example.com"},
+		{"leaves empty local part alone", "+news@gmail.com", true, "+news@gmail.com"},
+		{"no at sign", " Not-An-Email ", true, "not-an-email"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, auth.NormalizeEmail(tt.email, tt.collapseGmail))
+		})
+	}
+}
+
+func TestValidEmail(t *testing.T) {
+	assert.True(t, auth.ValidEmail("user@example.com"))
+	assert.True(t, auth.ValidEmail("first.last+tag@sub.example.org"))
+
+	assert.False(t, auth.ValidEmail(""))
+	assert.False(t, auth.ValidEmail("not-an-email"))
+	assert.False(t, auth.ValidEmail("user@"))
+	assert.False(t, auth.ValidEmail("Alice <alice@example.com>"))
+	assert.False(t, auth.ValidEmail("a b@example.com"))
+	assert.False(t, auth.ValidEmail(strings.Repeat("a", auth.MaxEmailLength)+"@example.com"))
+}
+
+func TestNormalizeUsername(t *testing.T) {
+	assert.Equal(t, "Alice", auth.NormalizeUsername("  Alice\t"))
+}
+
+func TestNormalizeDisplayName(t *testing.T) {
+	assert.Equal(t, "Alice Smith", auth.NormalizeDisplayName("  Alice \t Smith\n"))
+	assert.Empty(t, auth.NormalizeDisplayName(" \t "))
+}
+
+func TestValidDisplayName(t *testing.T) {
+	assert.True(t, auth.ValidDisplayName(""))
+	assert.True(t, auth.ValidDisplayName("Jürgen Müller"))
+	assert.True(t, auth.ValidDisplayName(strings.Repeat("ä", auth.MaxDisplayNameLength)))
+	assert.False(t, auth.ValidDisplayName(strings.Repeat("ä", auth.MaxDisplayNameLength+1)))
+	assert.False(t, auth.ValidDisplayName("Alice\u0000"))
+	assert.False(t, auth.ValidDisplayName("\xff"))
+}
+
+func TestValidateUsername(t *testing.T) {
+	tests := []struct {
+		name      string
+		username  string
+		maxLength int
+		want      error
+	}{
+		{"valid", "alice", 0, nil},
+		{"punctuation", "alice.smith_2-b", 0, nil},
+		{"unicode letters", "jürgen", 0, nil},
+		{"empty", "", 0, auth.ErrUsernameEmpty},
+		{"max length", strings.Repeat("a", auth.MaxUsernameLength), 0, nil},
+		{"max length multibyte", strings.Repeat("ä", auth.MaxUsernameLength), 0, nil},
+		{"too long", strings.Repeat("a", auth.MaxUsernameLength+1), 0, auth.ErrUsernameTooLong},
+		{"configured max length", strings.Repeat("a", 16), 16, nil},
+		{"over configured max length", strings.Repeat("a", 17), 16, auth.ErrUsernameTooLong},
+		{"max length above limit", strings.Repeat("a", auth.MaxUsernameLength+1), 100, auth.ErrUsernameTooLong},
+		{"leading space", " alice", 0, auth.ErrUsernameWhitespace},
+		{"trailing tab", "alice\t", 0, auth.ErrUsernameWhitespace},
+		{"inner space", "alice smith", 0, auth.ErrUsernameCharacters},
+		{"control character", "ali\x00ce", 0, auth.ErrUsernameCharacters},
+		{"newline", "ali\nce", 0, auth.ErrUsernameCharacters},
+		{"symbol", "alice@example.com", 0, auth.ErrUsernameCharacters},
+		{"invalid utf8", "al\xffice", 0, auth.ErrUsernameCharacters},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorIs(t, auth.ValidateUsername(tt.username, tt.maxLength), tt.want)
+		})
+	}
+}
+
+func TestUsernameCandidates(t *testing.T) {
+	candidates := auth.UsernameCandidates("bob", 0)
+
+	assert.Len(t, candidates, auth.MaxUsernameCandidates)
+	assert.Equal(t, []string{"bob2", "bob_", "bob3", "bob_2"}, candidates[:4])
+}
+
+func TestUsernameCandidates_RespectsMaxLength(t *testing.T) {
+	// "carol2" and "carol_" fit into 6 characters, "carol_2" does not
+	assert.Equal(t,
+		[]string{"carol2", "carol_", "carol3", "carol4", "carol5", "carol6", "carol7", "carol8", "carol9"},
+		auth.UsernameCandidates("carol", 6))
+
+	// A base at the limit leaves no room for any variant
+	assert.Empty(t, auth.UsernameCandidates("carol", 5))
+}
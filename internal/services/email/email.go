@@ -7,27 +7,42 @@ import (
 	"context"
 	"crypto/rand"
 	"crypto/sha256"
+	"crypto/tls"
 	"encoding/hex"
 	"fmt"
+	"io/fs"
+	"net"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/oliverandrich/go-webapp-template/internal/clock"
 	"github.com/oliverandrich/go-webapp-template/internal/config"
 	"github.com/oliverandrich/go-webapp-template/internal/i18n"
 	"github.com/wneessen/go-mail"
+	"golang.org/x/text/language"
 )
 
 const (
 	// TokenLength is the number of random bytes for verification tokens.
 	TokenLength = 32
-	// TokenExpiry is how long verification tokens are valid.
+	// TokenExpiry is how long verification tokens are valid when
+	// config.SMTPConfig.TokenTTL is not set.
 	TokenExpiry = 24 * time.Hour
 )
 
 // Service handles email sending and verification token management.
+// It is safe for concurrent use; one idle SMTP connection is kept for reuse.
 type Service struct {
-	cfg     *config.SMTPConfig
-	baseURL string
+	cfg       *config.SMTPConfig
+	clock     clock.Clock
+	templates *templates
+	sendFunc  func(context.Context, *mail.Msg) error
+	baseURL   string
+
+	mu       sync.Mutex   // guards conn and lastUsed, never held during SMTP I/O
+	conn     *mail.Client // idle SMTP connection, nil when none is kept
+	lastUsed time.Time
 }
 
 // NewService creates a new email service.
@@ -39,10 +54,49 @@ func NewService(cfg *config.SMTPConfig, baseURL string) (*Service, error) {
 		return nil, fmt.Errorf("SMTP from address is required")
 	}
 
-	return &Service{
-		cfg:     cfg,
-		baseURL: strings.TrimSuffix(baseURL, "/"),
-	}, nil
+	sub, err := fs.Sub(defaultTemplates, "templates")
+	if err != nil {
+		return nil, err
+	}
+	tmpl, err := parseTemplates(sub)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Service{
+		cfg:       cfg,
+		clock:     clock.Real{},
+		templates: tmpl,
+		baseURL:   strings.TrimSuffix(baseURL, "/"),
+	}
+	s.sendFunc = s.send
+	return s, nil
+}
+
+// SetTemplates replaces the built-in email templates. fsys must contain a
+// "<name>.txt.tmpl" and "<name>.html.tmpl" pair for every message
+// ("verification", "email_change", "magic_link", "account_exists") at its
+// root.
+func (s *Service) SetTemplates(fsys fs.FS) error {
+	tmpl, err := parseTemplates(fsys)
+	if err != nil {
+		return err
+	}
+	s.templates = tmpl
+	return nil
+}
+
+// SetSendFunc replaces SMTP delivery with fn (for tests).
+func (s *Service) SetSendFunc(fn func(*mail.Msg) error) {
+	s.sendFunc = func(_ context.Context, msg *mail.Msg) error {
+		return fn(msg)
+	}
+}
+
+// SetClock replaces the time source used for token expiry and connection
+// idle tracking (for tests).
+func (s *Service) SetClock(c clock.Clock) {
+	s.clock = c
 }
 
 // GenerateToken generates a new verification token.
@@ -55,11 +109,29 @@ func (s *Service) GenerateToken() (string, string, time.Time, error) {
 
 	plaintext := hex.EncodeToString(bytes)
 	hash := HashToken(plaintext)
-	expiresAt := time.Now().Add(TokenExpiry)
+	expiresAt := s.clock.Now().Add(s.TokenTTL())
 
 	return plaintext, hash, expiresAt, nil
 }
 
+// TokenTTL returns how long tokens from GenerateToken stay valid.
+func (s *Service) TokenTTL() time.Duration {
+	if s.cfg.TokenTTL > 0 {
+		return time.Duration(s.cfg.TokenTTL) * time.Second
+	}
+	return TokenExpiry
+}
+
+// expiryNote describes the lifetime of tokens from GenerateToken, in hours
+// when it is a whole number of them and in minutes otherwise.
+func (s *Service) expiryNote(ctx context.Context) string {
+	ttl := s.TokenTTL()
+	if ttl%time.Hour == 0 {
+		return i18n.TData(ctx, "email_link_expiry", map[string]any{"Hours": int(ttl.Hours())})
+	}
+	return i18n.TData(ctx, "email_link_expiry_minutes", map[string]any{"Minutes": int(ttl.Minutes())})
+}
+
 // HashToken computes the SHA256 hash of a token.
 func HashToken(token string) string {
 	hash := sha256.Sum256([]byte(token))
@@ -70,20 +142,72 @@ func HashToken(token string) string {
 func (s *Service) SendVerification(ctx context.Context, toEmail, token string) error {
 	verifyURL := fmt.Sprintf("%s/auth/verify-email?token=%s", s.baseURL, token)
 
-	subject := i18n.T(ctx, "email_verification_subject")
-	body := i18n.TData(ctx, "email_verification_body", map[string]any{
-		"VerifyURL": verifyURL,
+	return s.sendMessage(ctx, toEmail, "verification", messageData{
+		Subject: i18n.T(ctx, "email_verification_subject"),
+		Intro:   i18n.T(ctx, "email_verification_intro"),
+		Action:  i18n.T(ctx, "email_verification_action"),
+		URL:     verifyURL,
+		Expiry:  s.expiryNote(ctx),
+		Ignore:  i18n.T(ctx, "email_verification_ignore"),
 	})
+}
+
+// SendEmailChange sends a confirmation link for a pending email change to the
+// new address.
+func (s *Service) SendEmailChange(ctx context.Context, toEmail, token string) error {
+	confirmURL := fmt.Sprintf("%s/auth/email/confirm?token=%s", s.baseURL, token)
 
-	return s.send(toEmail, subject, body)
+	return s.sendMessage(ctx, toEmail, "email_change", messageData{
+		Subject: i18n.T(ctx, "email_change_subject"),
+		Intro:   i18n.TData(ctx, "email_change_intro", map[string]any{"Email": toEmail}),
+		Action:  i18n.T(ctx, "email_change_action"),
+		URL:     confirmURL,
+		Expiry:  s.expiryNote(ctx),
+		Ignore:  i18n.T(ctx, "email_change_ignore"),
+	})
 }
 
-// send sends an email via SMTP using go-mail.
-func (s *Service) send(to, subject, body string) error {
+// SendAccountExists tells the owner of toEmail that someone tried to register
+// a new account with it. It replaces the "email already registered" error
+// when auth.enumeration_safe is enabled.
+func (s *Service) SendAccountExists(ctx context.Context, toEmail string) error {
+	return s.sendMessage(ctx, toEmail, "account_exists", messageData{
+		Subject: i18n.T(ctx, "email_account_exists_subject"),
+		Intro:   i18n.T(ctx, "email_account_exists_intro"),
+		Action:  i18n.T(ctx, "email_account_exists_action"),
+		URL:     s.baseURL + "/auth/login",
+		Ignore:  i18n.T(ctx, "email_account_exists_ignore"),
+	})
+}
+
+// SendMagicLink sends a sign-in link. link is the signed path and query of
+// the link, ttl how long it stays valid.
+func (s *Service) SendMagicLink(ctx context.Context, toEmail, link string, ttl time.Duration) error {
+	return s.sendMessage(ctx, toEmail, "magic_link", messageData{
+		Subject: i18n.T(ctx, "email_magic_link_subject"),
+		Intro:   i18n.T(ctx, "email_magic_link_intro"),
+		Action:  i18n.T(ctx, "email_magic_link_action"),
+		URL:     s.baseURL + link,
+		Expiry:  i18n.TData(ctx, "email_magic_link_expiry", map[string]any{"Minutes": int(ttl.Minutes())}),
+		Ignore:  i18n.T(ctx, "email_magic_link_ignore"),
+	})
+}
+
+// sendMessage renders the named templates and sends them as a multipart
+// message with text/plain and text/html alternatives.
+func (s *Service) sendMessage(ctx context.Context, to, name string, data messageData) error {
+	data.Lang = i18n.GetLocale(ctx)
+	data.AppName = i18n.T(ctx, "app_name")
+
+	text, html, err := s.templates.render(name, data)
+	if err != nil {
+		return err
+	}
+
 	msg := mail.NewMsg()
 
-	if s.cfg.FromName != "" {
-		if err := msg.FromFormat(s.cfg.FromName, s.cfg.From); err != nil {
+	if fromName := s.fromName(ctx); fromName != "" {
+		if err := msg.FromFormat(fromName, s.cfg.From); err != nil {
 			return fmt.Errorf("setting from address: %w", err)
 		}
 	} else {
@@ -96,12 +220,145 @@ func (s *Service) send(to, subject, body string) error {
 		return fmt.Errorf("setting to address: %w", err)
 	}
 
-	msg.Subject(subject)
-	msg.SetBodyString(mail.TypeTextPlain, body)
+	msg.Subject(data.Subject)
+	msg.SetBodyString(mail.TypeTextPlain, text)
+	msg.AddAlternativeString(mail.TypeTextHTML, html)
+
+	return s.sendFunc(ctx, msg)
+}
+
+// fromName returns the sender display name for the locale in ctx. A name for
+// the exact locale wins over one for its base language, which wins over the
+// default FromName.
+func (s *Service) fromName(ctx context.Context) string {
+	locale := i18n.GetLocale(ctx)
+	if name, ok := s.cfg.FromNames[locale]; ok {
+		return name
+	}
+	if tag, err := language.Parse(locale); err == nil {
+		base, _ := tag.Base()
+		if name, ok := s.cfg.FromNames[base.String()]; ok {
+			return name
+		}
+	}
+	return s.cfg.FromName
+}
+
+// send delivers msg via SMTP using go-mail. The connection stays open for
+// IdleTimeout seconds, so bursts of messages don't dial for each one. Before
+// reuse it is checked with RSET, and replaced if the server has dropped it.
+// A send takes the idle connection for itself or dials a new one with ctx, so
+// a hanging server only holds up the sends that wait on it.
+func (s *Service) send(ctx context.Context, msg *mail.Msg) error {
+	idleTimeout := time.Duration(s.cfg.IdleTimeout) * time.Second
+
+	client := s.takeConn(idleTimeout)
+	if client != nil && client.Reset() != nil {
+		_ = client.Close()
+		client = nil
+	}
+
+	if client == nil {
+		var err error
+		if client, err = s.newClient(); err != nil {
+			return err
+		}
+		if err = client.DialWithContext(ctx); err != nil {
+			return fmt.Errorf("connecting to SMTP server: %w", err)
+		}
+	}
+
+	if err := client.Send(msg); err != nil {
+		_ = client.Close()
+		return fmt.Errorf("sending email: %w", err)
+	}
+
+	if idleTimeout <= 0 {
+		_ = client.Close()
+		return nil
+	}
+	s.putConn(client)
+	return nil
+}
 
+// takeConn removes the idle connection from the service and returns it, or
+// nil when there is none or it has been idle for longer than idleTimeout.
+func (s *Service) takeConn(idleTimeout time.Duration) *mail.Client {
+	s.mu.Lock()
+	client, lastUsed := s.conn, s.lastUsed
+	s.conn = nil
+	s.mu.Unlock()
+
+	if client != nil && s.clock.Now().Sub(lastUsed) > idleTimeout {
+		_ = client.Close()
+		return nil
+	}
+	return client
+}
+
+// putConn keeps client as the idle connection. If another send already
+// returned one, client is closed instead.
+func (s *Service) putConn(client *mail.Client) {
+	s.mu.Lock()
+	if s.conn == nil {
+		s.conn, s.lastUsed, client = client, s.clock.Now(), nil
+	}
+	s.mu.Unlock()
+
+	if client != nil {
+		_ = client.Close()
+	}
+}
+
+// Close closes the idle SMTP connection, if any. Errors are irrelevant at
+// this point, the connection is discarded either way.
+func (s *Service) Close() error {
+	s.mu.Lock()
+	client := s.conn
+	s.conn = nil
+	s.mu.Unlock()
+
+	if client != nil {
+		_ = client.Close()
+	}
+	return nil
+}
+
+// dialContext connects to the SMTP server. go-mail reads the greeting
+// without a deadline, so the connection gets the deadline of ctx, which
+// go-mail derives from the caller's context and its connect timeout.
+func (s *Service) dialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	dialer := &net.Dialer{}
+	var conn net.Conn
+	var err error
+	if s.cfg.TLS && s.cfg.Port == 465 {
+		tlsDialer := &tls.Dialer{
+			NetDialer: dialer,
+			Config:    &tls.Config{ServerName: s.cfg.Host, MinVersion: tls.VersionTLS12},
+		}
+		conn, err = tlsDialer.DialContext(ctx, network, address)
+	} else {
+		conn, err = dialer.DialContext(ctx, network, address)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		if err = conn.SetDeadline(deadline); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+// newClient creates a go-mail client for the configured server.
+func (s *Service) newClient() (*mail.Client, error) {
 	// Build client options
 	opts := []mail.Option{
 		mail.WithPort(s.cfg.Port),
+		mail.WithDialContextFunc(s.dialContext),
 	}
 
 	// Configure TLS based on config and port
@@ -126,12 +383,7 @@ func (s *Service) send(to, subject, body string) error {
 
 	client, err := mail.NewClient(s.cfg.Host, opts...)
 	if err != nil {
-		return fmt.Errorf("creating mail client: %w", err)
+		return nil, fmt.Errorf("creating mail client: %w", err)
 	}
-
-	if err := client.DialAndSend(msg); err != nil {
-		return fmt.Errorf("sending email: %w", err)
-	}
-
-	return nil
+	return client, nil
 }
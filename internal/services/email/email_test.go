@@ -7,6 +7,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/oliverandrich/go-webapp-template/internal/clock"
 	"github.com/oliverandrich/go-webapp-template/internal/config"
 	"github.com/oliverandrich/go-webapp-template/internal/services/email"
 	"github.com/stretchr/testify/assert"
@@ -87,6 +88,36 @@ func TestGenerateToken(t *testing.T) {
 	assert.WithinDuration(t, expectedExpiry, expiresAt, time.Minute)
 }
 
+func TestGenerateToken_UsesClock(t *testing.T) {
+	cfg := validSMTPConfig()
+	svc, err := email.NewService(cfg, "https://example.com")
+	require.NoError(t, err)
+
+	now := time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)
+	svc.SetClock(clock.NewFake(now))
+
+	_, _, expiresAt, err := svc.GenerateToken()
+
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(email.TokenExpiry), expiresAt)
+}
+
+func TestGenerateToken_CustomTTL(t *testing.T) {
+	cfg := validSMTPConfig()
+	cfg.TokenTTL = 900
+	svc, err := email.NewService(cfg, "https://example.com")
+	require.NoError(t, err)
+
+	now := time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)
+	svc.SetClock(clock.NewFake(now))
+
+	_, _, expiresAt, err := svc.GenerateToken()
+
+	require.NoError(t, err)
+	assert.Equal(t, 15*time.Minute, svc.TokenTTL())
+	assert.Equal(t, now.Add(15*time.Minute), expiresAt)
+}
+
 func TestGenerateToken_Unique(t *testing.T) {
 	cfg := validSMTPConfig()
 	svc, err := email.NewService(cfg, "https://example.com")
@@ -0,0 +1,247 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package email_test
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/oliverandrich/go-webapp-template/internal/clock"
+	"github.com/oliverandrich/go-webapp-template/internal/config"
+	"github.com/oliverandrich/go-webapp-template/internal/i18n"
+	"github.com/oliverandrich/go-webapp-template/internal/services/email"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/language"
+)
+
+// fakeSMTP is a minimal SMTP server that counts connections and messages.
+type fakeSMTP struct {
+	ln       net.Listener
+	dials    atomic.Int32
+	messages atomic.Int32
+	wg       sync.WaitGroup
+
+	mu    sync.Mutex
+	conns []net.Conn
+}
+
+func newFakeSMTP(t *testing.T) *fakeSMTP {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	f := &fakeSMTP{ln: ln}
+	f.wg.Add(1)
+	go f.serve()
+	t.Cleanup(func() {
+		_ = ln.Close()
+		f.wg.Wait()
+	})
+	return f
+}
+
+func (f *fakeSMTP) port() int {
+	return f.ln.Addr().(*net.TCPAddr).Port
+}
+
+func (f *fakeSMTP) serve() {
+	defer f.wg.Done()
+	for {
+		conn, err := f.ln.Accept()
+		if err != nil {
+			return
+		}
+		f.dials.Add(1)
+		f.mu.Lock()
+		f.conns = append(f.conns, conn)
+		f.mu.Unlock()
+		f.wg.Add(1)
+		go f.handle(conn)
+	}
+}
+
+// drop closes all open connections, as a server restart would.
+func (f *fakeSMTP) drop() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, conn := range f.conns {
+		_ = conn.Close()
+	}
+	f.conns = nil
+}
+
+func (f *fakeSMTP) handle(conn net.Conn) {
+	defer f.wg.Done()
+	defer func() { _ = conn.Close() }()
+
+	r := bufio.NewReader(conn)
+	reply := func(line string) { _, _ = conn.Write([]byte(line + "\r\n")) }
+	reply("220 fake ESMTP")
+
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(cmd, "DATA"):
+			reply("354 go ahead")
+			for {
+				body, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if body == ".\r\n" {
+					break
+				}
+			}
+			f.messages.Add(1)
+			reply("250 queued")
+		case strings.HasPrefix(cmd, "QUIT"):
+			reply("221 bye")
+			return
+		default: // EHLO, MAIL, RCPT, RSET, NOOP
+			reply("250 OK")
+		}
+	}
+}
+
+func newPooledService(t *testing.T, f *fakeSMTP, idleTimeout int) *email.Service {
+	t.Helper()
+	require.NoError(t, i18n.Init())
+	svc, err := email.NewService(&config.SMTPConfig{
+		Host:        "127.0.0.1",
+		Port:        f.port(),
+		From:        "noreply@example.com",
+		IdleTimeout: idleTimeout,
+	}, "http://localhost:8080")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = svc.Close() })
+	return svc
+}
+
+func sendVerification(t *testing.T, svc *email.Service, n int) {
+	t.Helper()
+	ctx := i18n.WithLocale(context.Background(), language.English)
+	require.NoError(t, svc.SendVerification(ctx, "user"+strconv.Itoa(n)+"@example.com", "token"))
+}
+
+func TestSend_ReusesConnection(t *testing.T) {
+	f := newFakeSMTP(t)
+	svc := newPooledService(t, f, 30)
+
+	for i := range 3 {
+		sendVerification(t, svc, i)
+	}
+
+	assert.Equal(t, int32(1), f.dials.Load())
+	assert.Equal(t, int32(3), f.messages.Load())
+}
+
+func TestSend_ConcurrentSendsKeepOneConnection(t *testing.T) {
+	f := newFakeSMTP(t)
+	svc := newPooledService(t, f, 30)
+
+	var wg sync.WaitGroup
+	for i := range 5 {
+		wg.Go(func() { sendVerification(t, svc, i) })
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(5), f.messages.Load())
+
+	// One of the connections is kept and reused
+	dials := f.dials.Load()
+	sendVerification(t, svc, 6)
+	assert.Equal(t, dials, f.dials.Load())
+}
+
+func TestSend_DialHonoursContext(t *testing.T) {
+	// A server that accepts connections but never greets
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	var mu sync.Mutex
+	var conns []net.Conn
+	t.Cleanup(func() {
+		_ = ln.Close()
+		mu.Lock()
+		defer mu.Unlock()
+		for _, conn := range conns {
+			_ = conn.Close()
+		}
+	})
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			mu.Lock()
+			conns = append(conns, conn)
+			mu.Unlock()
+		}
+	}()
+
+	require.NoError(t, i18n.Init())
+	svc, err := email.NewService(&config.SMTPConfig{
+		Host:        "127.0.0.1",
+		Port:        ln.Addr().(*net.TCPAddr).Port,
+		From:        "noreply@example.com",
+		IdleTimeout: 30,
+	}, "http://localhost:8080")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(i18n.WithLocale(context.Background(), language.English), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err = svc.SendVerification(ctx, "user@example.com", "token")
+
+	require.Error(t, err)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestSend_RedialsAfterIdleTimeout(t *testing.T) {
+	f := newFakeSMTP(t)
+	svc := newPooledService(t, f, 30)
+	fake := clock.NewFake(time.Now())
+	svc.SetClock(fake)
+
+	sendVerification(t, svc, 1)
+	fake.Advance(31 * time.Second)
+	sendVerification(t, svc, 2)
+
+	assert.Equal(t, int32(2), f.dials.Load())
+}
+
+func TestSend_NoReuseWhenDisabled(t *testing.T) {
+	f := newFakeSMTP(t)
+	svc := newPooledService(t, f, 0)
+
+	sendVerification(t, svc, 1)
+	sendVerification(t, svc, 2)
+
+	assert.Equal(t, int32(2), f.dials.Load())
+}
+
+func TestSend_ReconnectsAfterServerDrop(t *testing.T) {
+	f := newFakeSMTP(t)
+	svc := newPooledService(t, f, 30)
+
+	sendVerification(t, svc, 1)
+	f.drop()
+	sendVerification(t, svc, 2)
+
+	assert.Equal(t, int32(2), f.dials.Load())
+	assert.Equal(t, int32(2), f.messages.Load())
+}
@@ -0,0 +1,64 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package email
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	texttemplate "text/template"
+)
+
+// defaultTemplates holds the built-in email templates. Each message is
+// rendered from "<name>.txt.tmpl" and "<name>.html.tmpl"; edit the files in
+// templates/ to restyle them, or call SetTemplates with a replacement FS.
+//
+//go:embed templates/*.tmpl
+var defaultTemplates embed.FS
+
+// messageData is passed to the email templates. All text is already
+// localized for the recipient.
+type messageData struct {
+	Lang    string // Locale of the message, e.g. "de"
+	AppName string // Application name
+	Subject string // Message subject
+	Intro   string // Explanation shown above the link
+	Action  string // Label of the call-to-action button
+	URL     string // Link the recipient should open
+	Expiry  string // Note about how long the link is valid (empty if it does not expire)
+	Ignore  string // Note for recipients who did not request the message
+}
+
+// templates holds the parsed text and HTML variants of all messages.
+type templates struct {
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+// parseTemplates parses all *.txt.tmpl and *.html.tmpl files at the root of fsys.
+func parseTemplates(fsys fs.FS) (*templates, error) {
+	text, err := texttemplate.ParseFS(fsys, "*.txt.tmpl")
+	if err != nil {
+		return nil, fmt.Errorf("parsing text email templates: %w", err)
+	}
+	html, err := htmltemplate.ParseFS(fsys, "*.html.tmpl")
+	if err != nil {
+		return nil, fmt.Errorf("parsing HTML email templates: %w", err)
+	}
+	return &templates{text: text, html: html}, nil
+}
+
+// render executes the text and HTML templates for the named message.
+func (t *templates) render(name string, data messageData) (string, string, error) {
+	var text, html bytes.Buffer
+	if err := t.text.ExecuteTemplate(&text, name+".txt.tmpl", data); err != nil {
+		return "", "", fmt.Errorf("rendering %s text template: %w", name, err)
+	}
+	if err := t.html.ExecuteTemplate(&html, name+".html.tmpl", data); err != nil {
+		return "", "", fmt.Errorf("rendering %s HTML template: %w", name, err)
+	}
+	return text.String(), html.String(), nil
+}
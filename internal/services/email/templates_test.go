@@ -0,0 +1,227 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package email_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	netmail "net/mail"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/oliverandrich/go-webapp-template/internal/i18n"
+	"github.com/oliverandrich/go-webapp-template/internal/services/email"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wneessen/go-mail"
+	"golang.org/x/text/language"
+)
+
+// sentMessage is a decoded multipart message captured by captureSend.
+type sentMessage struct {
+	header netmail.Header
+	parts  map[string]string // body by media type
+}
+
+// captureSend replaces SMTP delivery and returns a pointer that receives the
+// decoded message.
+func captureSend(t *testing.T, svc *email.Service) *sentMessage {
+	t.Helper()
+	sent := &sentMessage{}
+	svc.SetSendFunc(func(msg *mail.Msg) error {
+		var buf bytes.Buffer
+		if _, err := msg.WriteTo(&buf); err != nil {
+			return err
+		}
+		*sent = decodeMessage(t, buf.Bytes())
+		return nil
+	})
+	return sent
+}
+
+func decodeMessage(t *testing.T, raw []byte) sentMessage {
+	t.Helper()
+	m, err := netmail.ReadMessage(bytes.NewReader(raw))
+	require.NoError(t, err)
+
+	mediaType, params, err := mime.ParseMediaType(m.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	require.Equal(t, "multipart/alternative", mediaType)
+
+	parts := make(map[string]string)
+	mr := multipart.NewReader(m.Body, params["boundary"])
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		partType, _, err := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		require.NoError(t, err)
+		body, err := io.ReadAll(part)
+		require.NoError(t, err)
+		parts[partType] = string(body)
+	}
+	return sentMessage{header: m.Header, parts: parts}
+}
+
+func TestSendVerification_Multipart(t *testing.T) {
+	require.NoError(t, i18n.Init())
+	svc, err := email.NewService(validSMTPConfig(), "https://example.com/")
+	require.NoError(t, err)
+	sent := captureSend(t, svc)
+
+	ctx := i18n.WithLocale(context.Background(), language.English)
+	require.NoError(t, svc.SendVerification(ctx, "user@example.com", "abc123"))
+
+	verifyURL := "https://example.com/auth/verify-email?token=abc123"
+	require.Contains(t, sent.parts, "text/plain")
+	require.Contains(t, sent.parts, "text/html")
+	assert.Contains(t, sent.parts["text/plain"], verifyURL)
+	assert.Contains(t, sent.parts["text/html"], `href="`+verifyURL+`"`)
+	assert.Contains(t, sent.parts["text/html"], "Verify email address")
+	assert.Equal(t, "Verify your email address", sent.header.Get("Subject"))
+}
+
+func TestSendVerification_Localized(t *testing.T) {
+	require.NoError(t, i18n.Init())
+	svc, err := email.NewService(validSMTPConfig(), "https://example.com")
+	require.NoError(t, err)
+	sent := captureSend(t, svc)
+
+	ctx := i18n.WithLocale(context.Background(), language.German)
+	require.NoError(t, svc.SendVerification(ctx, "user@example.com", "abc123"))
+
+	assert.Contains(t, sent.parts["text/plain"], "Bitte öffne den folgenden Link")
+	assert.Contains(t, sent.parts["text/html"], `lang="de"`)
+}
+
+func TestSendVerification_ExpiryFollowsTokenTTL(t *testing.T) {
+	require.NoError(t, i18n.Init())
+	ctx := i18n.WithLocale(context.Background(), language.English)
+
+	for ttl, want := range map[int]string{
+		0:    "expire in 24 hours",
+		7200: "expire in 2 hours",
+		1800: "expire in 30 minutes",
+	} {
+		cfg := validSMTPConfig()
+		cfg.TokenTTL = ttl
+		svc, err := email.NewService(cfg, "https://example.com")
+		require.NoError(t, err)
+		sent := captureSend(t, svc)
+
+		require.NoError(t, svc.SendVerification(ctx, "user@example.com", "abc123"))
+		assert.Contains(t, sent.parts["text/plain"], want)
+	}
+}
+
+func TestSendEmailChange_Multipart(t *testing.T) {
+	require.NoError(t, i18n.Init())
+	svc, err := email.NewService(validSMTPConfig(), "https://example.com")
+	require.NoError(t, err)
+	sent := captureSend(t, svc)
+
+	ctx := i18n.WithLocale(context.Background(), language.English)
+	require.NoError(t, svc.SendEmailChange(ctx, "new@example.com", "xyz"))
+
+	confirmURL := "https://example.com/auth/email/confirm?token=xyz"
+	assert.Contains(t, sent.parts["text/plain"], confirmURL)
+	assert.Contains(t, sent.parts["text/plain"], "new@example.com")
+	assert.Contains(t, sent.parts["text/html"], confirmURL)
+}
+
+func TestSendMagicLink_Multipart(t *testing.T) {
+	require.NoError(t, i18n.Init())
+	svc, err := email.NewService(validSMTPConfig(), "https://example.com")
+	require.NoError(t, err)
+	sent := captureSend(t, svc)
+
+	ctx := i18n.WithLocale(context.Background(), language.English)
+	require.NoError(t, svc.SendMagicLink(ctx, "user@example.com", "/auth/magic-link?exp=1&sig=s&token=xyz", 15*time.Minute))
+
+	assert.Contains(t, sent.parts["text/plain"], "https://example.com/auth/magic-link?exp=1&sig=s&token=xyz")
+	assert.Contains(t, sent.parts["text/plain"], "expire in 15 minutes")
+	assert.Contains(t, sent.parts["text/html"], "https://example.com/auth/magic-link?exp=1&amp;sig=s&amp;token=xyz")
+	assert.Equal(t, "Your sign-in link", sent.header.Get("Subject"))
+}
+
+func TestSendAccountExists_Multipart(t *testing.T) {
+	require.NoError(t, i18n.Init())
+	svc, err := email.NewService(validSMTPConfig(), "https://example.com")
+	require.NoError(t, err)
+	sent := captureSend(t, svc)
+
+	ctx := i18n.WithLocale(context.Background(), language.English)
+	require.NoError(t, svc.SendAccountExists(ctx, "user@example.com"))
+
+	assert.Contains(t, sent.parts["text/plain"], "https://example.com/auth/login")
+	assert.NotContains(t, sent.parts["text/plain"], "expire")
+	assert.NotContains(t, sent.parts["text/html"], "expire")
+	assert.Equal(t, "Sign-up attempt with your email address", sent.header.Get("Subject"))
+}
+
+func TestSendVerification_FromNamePerLocale(t *testing.T) {
+	require.NoError(t, i18n.Init())
+	cfg := validSMTPConfig()
+	cfg.FromNames = map[string]string{"de": "Meine App"}
+	svc, err := email.NewService(cfg, "https://example.com")
+	require.NoError(t, err)
+	sent := captureSend(t, svc)
+
+	tests := []struct {
+		lang language.Tag
+		want string
+	}{
+		{language.German, "Meine App"},
+		{language.MustParse("de-AT"), "Meine App"},
+		{language.English, "Test App"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.lang.String(), func(t *testing.T) {
+			ctx := i18n.WithLocale(context.Background(), tt.lang)
+			require.NoError(t, svc.SendVerification(ctx, "user@example.com", "abc123"))
+
+			from, err := netmail.ParseAddress(sent.header.Get("From"))
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, from.Name)
+			assert.Equal(t, "noreply@example.com", from.Address)
+		})
+	}
+}
+
+func TestSetTemplates(t *testing.T) {
+	require.NoError(t, i18n.Init())
+	svc, err := email.NewService(validSMTPConfig(), "https://example.com")
+	require.NoError(t, err)
+	sent := captureSend(t, svc)
+
+	require.NoError(t, svc.SetTemplates(fstest.MapFS{
+		"verification.txt.tmpl":  {Data: []byte("custom text {{.URL}}")},
+		"verification.html.tmpl": {Data: []byte(`<p>custom <a href="{{.URL}}">{{.Action}}</a></p>`)},
+	}))
+
+	ctx := i18n.WithLocale(context.Background(), language.English)
+	require.NoError(t, svc.SendVerification(ctx, "user@example.com", "abc123"))
+
+	assert.True(t, strings.HasPrefix(sent.parts["text/plain"], "custom text https://example.com/auth/verify-email?token=abc123"))
+	assert.Contains(t, sent.parts["text/html"], "<p>custom ")
+}
+
+func TestSetTemplates_Invalid(t *testing.T) {
+	svc, err := email.NewService(validSMTPConfig(), "https://example.com")
+	require.NoError(t, err)
+
+	err = svc.SetTemplates(fstest.MapFS{
+		"verification.txt.tmpl": {Data: []byte("{{.URL")},
+	})
+
+	require.Error(t, err)
+}
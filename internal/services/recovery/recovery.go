@@ -16,6 +16,9 @@ const (
 	CodeLength = 12
 	// CodeCount is the default number of recovery codes to generate.
 	CodeCount = 8
+	// LowCount is the number of unused codes at or below which users are
+	// told to generate new ones.
+	LowCount = 2
 	// bcryptCost is the cost factor for bcrypt hashing.
 	bcryptCost = 10
 )
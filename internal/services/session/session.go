@@ -4,7 +4,9 @@
 package session
 
 import (
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"errors"
 	"log/slog"
@@ -12,6 +14,7 @@ import (
 	"time"
 
 	"github.com/gorilla/securecookie"
+	"github.com/oliverandrich/go-webapp-template/internal/clock"
 	"github.com/oliverandrich/go-webapp-template/internal/config"
 )
 
@@ -20,64 +23,128 @@ type Data struct { //nolint:govet // fieldalignment not critical
 	UserID    int64     `json:"u"`
 	Username  string    `json:"n"`
 	ExpiresAt time.Time `json:"e"`
+	AuthAt    time.Time `json:"a"`           // Time of the last successful passkey assertion
+	MaxAge    int       `json:"m,omitempty"` // Lifetime in seconds chosen at creation (0 = manager default)
+
+	Version      int   `json:"v,omitempty"` // User's session version at issue; a mismatch invalidates the session
+	CredentialID int64 `json:"c,omitempty"` // Database ID of the passkey used to sign in (0 = none, e.g. recovery code)
+
+	ImpersonatorID      int64 `json:"i,omitempty"`  // Administrator acting as UserID (0 = not impersonated)
+	ImpersonatorVersion int   `json:"iv,omitempty"` // Administrator's session version at issue; a mismatch ends the impersonation
+
+	token string // Cookie token of a DBStore session; never part of the payload
 }
 
 // Manager handles session cookie creation and parsing.
 type Manager struct {
-	sc         *securecookie.SecureCookie
-	cookieName string
-	maxAge     int
-	secure     bool
+	sc             *securecookie.SecureCookie // current keys, used for encoding
+	hashKey        []byte                     // current hash key, see DeriveKey
+	codecs         []securecookie.Codec       // sc followed by the previous keys, tried in order when decoding
+	cookieName     string
+	maxAge         int
+	rememberMaxAge int
+	clock          clock.Clock
+	secure         bool
+	extendOnReauth bool
 }
 
 // NewManager creates a new session manager.
 func NewManager(cfg *config.SessionConfig, secure bool) (*Manager, error) {
-	hashKey, err := resolveKey(cfg.HashKey, "hash")
+	hashKey, err := resolveHashKey(cfg.HashKey, cfg.AllowEphemeralKey)
 	if err != nil {
 		return nil, err
 	}
+	blockKey, err := decodeBlockKey(cfg.BlockKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(cfg.PreviousBlockKeys) > len(cfg.PreviousHashKeys) {
+		return nil, errors.New("invalid previous session block keys: more block keys than hash keys")
+	}
+
+	rememberMaxAge := cfg.RememberMeMaxAge
+	if rememberMaxAge <= 0 {
+		rememberMaxAge = cfg.MaxAge
+	}
+	// securecookie rejects values older than its max age, so it has to accept
+	// the longest lifetime we hand out; ExpiresAt enforces the actual deadline.
+	cookieMaxAge := max(cfg.MaxAge, rememberMaxAge)
 
-	var blockKey []byte
-	if cfg.BlockKey != "" {
-		blockKey, err = hex.DecodeString(cfg.BlockKey)
+	sc := securecookie.New(hashKey, blockKey)
+	sc.MaxAge(cookieMaxAge)
+	codecs := []securecookie.Codec{sc}
+
+	// Cookies signed before a key rotation keep working with the old keys
+	for i, keyHex := range cfg.PreviousHashKeys {
+		prevHash, err := decodeKey(keyHex, "previous hash")
 		if err != nil {
-			return nil, errors.New("invalid session block key: must be hex encoded")
+			return nil, err
 		}
-		if len(blockKey) != 32 {
-			return nil, errors.New("invalid session block key: must be 32 bytes")
+		var prevBlock []byte
+		if i < len(cfg.PreviousBlockKeys) {
+			if prevBlock, err = decodeBlockKey(cfg.PreviousBlockKeys[i]); err != nil {
+				return nil, err
+			}
 		}
+		prev := securecookie.New(prevHash, prevBlock)
+		prev.MaxAge(cookieMaxAge)
+		codecs = append(codecs, prev)
 	}
 
-	sc := securecookie.New(hashKey, blockKey)
-	sc.MaxAge(cfg.MaxAge)
-
 	return &Manager{
-		sc:         sc,
-		cookieName: cfg.CookieName,
-		maxAge:     cfg.MaxAge,
-		secure:     secure,
+		sc:             sc,
+		hashKey:        hashKey,
+		codecs:         codecs,
+		cookieName:     cfg.CookieName,
+		maxAge:         cfg.MaxAge,
+		rememberMaxAge: rememberMaxAge,
+		clock:          clock.Real{},
+		secure:         secure,
+		extendOnReauth: cfg.ExtendOnReauth,
 	}, nil
 }
 
-// resolveKey resolves the key from config or generates one for development.
-func resolveKey(keyHex, keyType string) ([]byte, error) {
+// SetClock replaces the time source used for expiry (for tests).
+func (m *Manager) SetClock(c clock.Clock) {
+	m.clock = c
+}
+
+// decodeKey decodes a 32-byte hex key.
+func decodeKey(keyHex, keyType string) ([]byte, error) {
+	key, err := hex.DecodeString(keyHex)
+	if err != nil {
+		return nil, errors.New("invalid session " + keyType + " key: must be hex encoded")
+	}
+	if len(key) != 32 {
+		return nil, errors.New("invalid session " + keyType + " key: must be 32 bytes")
+	}
+	return key, nil
+}
+
+// decodeBlockKey decodes an optional block key; empty means no encryption.
+func decodeBlockKey(keyHex string) ([]byte, error) {
+	if keyHex == "" {
+		return nil, nil
+	}
+	return decodeKey(keyHex, "block")
+}
+
+// resolveHashKey decodes the configured hash key. Without one, a random key
+// is generated if allowEphemeral is set and an error is returned otherwise.
+func resolveHashKey(keyHex string, allowEphemeral bool) ([]byte, error) {
 	if keyHex != "" {
-		key, err := hex.DecodeString(keyHex)
-		if err != nil {
-			return nil, errors.New("invalid session " + keyType + " key: must be hex encoded")
-		}
-		if len(key) != 32 {
-			return nil, errors.New("invalid session " + keyType + " key: must be 32 bytes")
-		}
-		return key, nil
+		return decodeKey(keyHex, "hash")
+	}
+	if !allowEphemeral {
+		return nil, errors.New("no session hash key configured: set session.hash_key, or session.allow_ephemeral_key for development")
 	}
 
 	// Generate random key for development
 	key := make([]byte, 32)
 	if _, err := rand.Read(key); err != nil {
-		return nil, errors.New("failed to generate session " + keyType + " key")
+		return nil, errors.New("failed to generate session hash key")
 	}
-	slog.Warn("No session "+keyType+" key configured, using random key (sessions will not persist across restarts)",
+	slog.Warn("No session hash key configured, using an EPHEMERAL random key: all sessions end when the server restarts. Set session.hash_key in production.",
 		"generated_key", hex.EncodeToString(key),
 	)
 	return key, nil
@@ -85,26 +152,115 @@ func resolveKey(keyHex, keyType string) ([]byte, error) {
 
 // Create creates a new session cookie for the given user.
 func (m *Manager) Create(userID int64, username string) (*http.Cookie, error) {
-	data := Data{
-		UserID:    userID,
-		Username:  username,
-		ExpiresAt: time.Now().Add(time.Duration(m.maxAge) * time.Second),
+	return m.CreateWithDuration(userID, username, m.Duration())
+}
+
+// DeriveKey returns a key for purpose derived from the session hash key, so
+// other signed values follow the same key policy without being replayable as
+// session cookies.
+func (m *Manager) DeriveKey(purpose string) []byte {
+	mac := hmac.New(sha256.New, m.hashKey)
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil)
+}
+
+// Duration returns the default session lifetime.
+func (m *Manager) Duration() time.Duration {
+	return time.Duration(m.maxAge) * time.Second
+}
+
+// RememberMeDuration returns the session lifetime used when the user asks to
+// stay signed in.
+func (m *Manager) RememberMeDuration() time.Duration {
+	return time.Duration(m.rememberMaxAge) * time.Second
+}
+
+// CreateWithDuration creates a new session cookie that is valid for d.
+// Both the payload deadline and the cookie MaxAge reflect d.
+func (m *Manager) CreateWithDuration(userID int64, username string, d time.Duration) (*http.Cookie, error) {
+	return m.Issue(Data{UserID: userID, Username: username}, d)
+}
+
+// Issue creates a new session cookie from data that is valid for d. The user,
+// version and credential fields are taken from data; expiry and last-auth
+// time are set here.
+func (m *Manager) Issue(data Data, d time.Duration) (*http.Cookie, error) {
+	maxAge := m.start(&data, d)
+	return m.encode(&data, maxAge)
+}
+
+// start sets the expiry and last-auth time of a new session valid for d and
+// returns the cookie max age in seconds.
+func (m *Manager) start(data *Data, d time.Duration) int {
+	now := m.clock.Now()
+	maxAge := int(d.Seconds())
+	data.ExpiresAt = now.Add(d)
+	data.AuthAt = now
+	data.MaxAge = 0
+	if maxAge != m.maxAge {
+		data.MaxAge = maxAge
 	}
+	return maxAge
+}
 
+// Reauthenticate refreshes an existing session after the user has re-asserted
+// a passkey. The last-auth time is always updated; the expiry is only pushed
+// out when extend-on-reauth is enabled.
+func (m *Manager) Reauthenticate(data *Data) (*http.Cookie, error) {
+	refreshed, maxAge := m.reauthenticated(data)
+	return m.encode(refreshed, maxAge)
+}
+
+// Reauthenticated returns a copy of data refreshed like Reauthenticate does,
+// to be saved with Store.Save.
+func (m *Manager) Reauthenticated(data *Data) *Data {
+	refreshed, _ := m.reauthenticated(data)
+	return refreshed
+}
+
+func (m *Manager) reauthenticated(data *Data) (*Data, int) {
+	now := m.clock.Now()
+	refreshed := *data
+	refreshed.AuthAt = now
+
+	maxAge := int(refreshed.ExpiresAt.Sub(now).Seconds())
+	if m.extendOnReauth {
+		// Keep the lifetime the session was created with (e.g. remember me)
+		maxAge = m.maxAge
+		if refreshed.MaxAge > 0 {
+			maxAge = refreshed.MaxAge
+		}
+		refreshed.ExpiresAt = now.Add(time.Duration(maxAge) * time.Second)
+	}
+	return &refreshed, maxAge
+}
+
+// Reissue re-encodes changed session data without touching its expiry or
+// last-auth time, e.g. after the session version was bumped.
+func (m *Manager) Reissue(data *Data) (*http.Cookie, error) {
+	return m.encode(data, int(data.ExpiresAt.Sub(m.clock.Now()).Seconds()))
+}
+
+// encode signs the session data and wraps it in a session cookie.
+func (m *Manager) encode(data *Data, maxAge int) (*http.Cookie, error) {
 	encoded, err := m.sc.Encode(m.cookieName, data)
 	if err != nil {
 		return nil, err
 	}
+	return m.cookie(encoded, maxAge), nil
+}
 
+// cookie returns a session cookie holding value.
+func (m *Manager) cookie(value string, maxAge int) *http.Cookie {
 	return &http.Cookie{
 		Name:     m.cookieName,
-		Value:    encoded,
+		Value:    value,
 		Path:     "/",
-		MaxAge:   m.maxAge,
+		MaxAge:   maxAge,
 		HttpOnly: true,
 		Secure:   m.secure,
 		SameSite: http.SameSiteLaxMode,
-	}, nil
+	}
 }
 
 // Parse parses the session cookie from the request.
@@ -119,12 +275,12 @@ func (m *Manager) Parse(r *http.Request) (*Data, error) {
 	}
 
 	var data Data
-	if err := m.sc.Decode(m.cookieName, cookie.Value, &data); err != nil {
+	if err := securecookie.DecodeMulti(m.cookieName, cookie.Value, &data, m.codecs...); err != nil {
 		return nil, nil //nolint:nilerr // Invalid cookie is treated as no session
 	}
 
 	// Check expiration
-	if time.Now().After(data.ExpiresAt) {
+	if m.clock.Now().After(data.ExpiresAt) {
 		return nil, nil
 	}
 
@@ -133,15 +289,7 @@ func (m *Manager) Parse(r *http.Request) (*Data, error) {
 
 // Clear returns a cookie that clears the session.
 func (m *Manager) Clear() *http.Cookie {
-	return &http.Cookie{
-		Name:     m.cookieName,
-		Value:    "",
-		Path:     "/",
-		MaxAge:   -1,
-		HttpOnly: true,
-		Secure:   m.secure,
-		SameSite: http.SameSiteLaxMode,
-	}
+	return m.cookie("", -1)
 }
 
 // Flash cookie name.
@@ -150,6 +298,7 @@ const flashCookieName = "flash"
 // FlashData contains temporary data that is cleared after reading.
 type FlashData struct {
 	RecoveryCodes []string `json:"rc,omitempty"`
+	DownloadToken string   `json:"dt,omitempty"` // Token for downloading RecoveryCodes
 }
 
 // SetFlash creates a flash cookie with temporary data.
@@ -179,7 +328,7 @@ func (m *Manager) GetFlash(r *http.Request) *FlashData {
 	}
 
 	var data FlashData
-	if err := m.sc.Decode(flashCookieName, cookie.Value, &data); err != nil {
+	if err := securecookie.DecodeMulti(flashCookieName, cookie.Value, &data, m.codecs...); err != nil {
 		return nil
 	}
 
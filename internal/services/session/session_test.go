@@ -9,6 +9,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/oliverandrich/go-webapp-template/internal/clock"
 	"github.com/oliverandrich/go-webapp-template/internal/config"
 	"github.com/oliverandrich/go-webapp-template/internal/services/session"
 	"github.com/stretchr/testify/assert"
@@ -89,16 +90,61 @@ func TestNewManager_InvalidBlockKey_WrongLength(t *testing.T) {
 }
 
 func TestNewManager_DevMode_GeneratesKey(t *testing.T) {
+	cfg := &config.SessionConfig{
+		CookieName:        "_session",
+		MaxAge:            3600,
+		HashKey:           "", // empty - should auto-generate
+		AllowEphemeralKey: true,
+	}
+
+	mgr, err := session.NewManager(cfg, false)
+
+	require.NoError(t, err)
+	assert.NotNil(t, mgr)
+	cookie, err := mgr.Create(1, "testuser")
+	require.NoError(t, err)
+	assert.NotEmpty(t, cookie.Value)
+}
+
+func TestNewManager_MissingKeyWithoutEphemeral(t *testing.T) {
 	cfg := &config.SessionConfig{
 		CookieName: "_session",
 		MaxAge:     3600,
-		HashKey:    "", // empty - should auto-generate
 	}
 
 	mgr, err := session.NewManager(cfg, false)
 
+	require.Error(t, err)
+	assert.Nil(t, mgr)
+	assert.Contains(t, err.Error(), "no session hash key configured")
+}
+
+func TestNewManager_SuppliedKeyIgnoresEphemeralSetting(t *testing.T) {
+	a, err := session.NewManager(&config.SessionConfig{CookieName: "_session", MaxAge: 3600, HashKey: validHashKey}, false)
 	require.NoError(t, err)
-	assert.NotNil(t, mgr)
+	b, err := session.NewManager(&config.SessionConfig{CookieName: "_session", MaxAge: 3600, HashKey: validHashKey, AllowEphemeralKey: true}, false)
+	require.NoError(t, err)
+
+	// Both managers use the supplied key, so they accept each other's cookies
+	cookie, err := a.Create(1, "testuser")
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(cookie)
+	data, err := b.Parse(req)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), data.UserID)
+}
+
+func TestDeriveKey(t *testing.T) {
+	a, err := session.NewManager(newTestConfig(), false)
+	require.NoError(t, err)
+	b, err := session.NewManager(newTestConfig(), false)
+	require.NoError(t, err)
+
+	// Stable across restarts with the same hash key, distinct per purpose
+	assert.Equal(t, a.DeriveKey("pagination"), b.DeriveKey("pagination"))
+	assert.NotEqual(t, a.DeriveKey("pagination"), a.DeriveKey("other"))
+	assert.Len(t, a.DeriveKey("pagination"), 32)
 }
 
 func TestCreate(t *testing.T) {
@@ -204,30 +250,106 @@ func TestParse_TamperedCookie(t *testing.T) {
 }
 
 func TestParse_ExpiredSession(t *testing.T) {
-	cfg := &config.SessionConfig{
-		CookieName: "_test_session",
-		MaxAge:     1, // 1 second
-		HashKey:    validHashKey,
-	}
+	cfg := newTestConfig()
 	mgr, err := session.NewManager(cfg, false)
 	require.NoError(t, err)
 
+	fake := clock.NewFake(time.Now())
+	mgr.SetClock(fake)
+
 	// Create a session
 	cookie, err := mgr.Create(123, "testuser")
 	require.NoError(t, err)
 
-	// Wait for expiration
-	time.Sleep(2 * time.Second)
-
 	req := httptest.NewRequest(http.MethodGet, "/", nil)
 	req.AddCookie(cookie)
 
+	// Just before expiry the session is still valid
+	fake.Advance(time.Hour - time.Second)
 	data, err := mgr.Parse(req)
+	require.NoError(t, err)
+	assert.NotNil(t, data)
+
+	// Past expiry it is rejected
+	fake.Advance(2 * time.Second)
+	data, err = mgr.Parse(req)
 
 	require.NoError(t, err)
 	assert.Nil(t, data)
 }
 
+func TestCreateWithDuration_RememberMeOutlivesNormalSession(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.RememberMeMaxAge = 86400 // 1 day
+	mgr, err := session.NewManager(cfg, false)
+	require.NoError(t, err)
+
+	fake := clock.NewFake(time.Now())
+	mgr.SetClock(fake)
+
+	normal, err := mgr.Create(123, "testuser")
+	require.NoError(t, err)
+	remembered, err := mgr.CreateWithDuration(123, "testuser", mgr.RememberMeDuration())
+	require.NoError(t, err)
+
+	assert.Equal(t, 3600, normal.MaxAge)
+	assert.Equal(t, 86400, remembered.MaxAge)
+
+	parse := func(cookie *http.Cookie) *session.Data {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.AddCookie(cookie)
+		data, parseErr := mgr.Parse(req)
+		require.NoError(t, parseErr)
+		return data
+	}
+
+	// Both sessions parse right after creation with their own deadlines
+	data := parse(normal)
+	require.NotNil(t, data)
+	assert.True(t, fake.Now().Add(time.Hour).Equal(data.ExpiresAt))
+	data = parse(remembered)
+	require.NotNil(t, data)
+	assert.Equal(t, int64(123), data.UserID)
+	assert.True(t, fake.Now().Add(24*time.Hour).Equal(data.ExpiresAt))
+
+	// After the normal lifetime only the remembered session is still valid
+	fake.Advance(2 * time.Hour)
+	assert.Nil(t, parse(normal))
+	assert.NotNil(t, parse(remembered))
+
+	// After the remember-me lifetime it expires as well
+	fake.Advance(23 * time.Hour)
+	assert.Nil(t, parse(remembered))
+}
+
+func TestRememberMeDuration_DefaultsToMaxAge(t *testing.T) {
+	mgr, err := session.NewManager(newTestConfig(), false)
+	require.NoError(t, err)
+
+	assert.Equal(t, time.Hour, mgr.RememberMeDuration())
+}
+
+func TestReauthenticate_KeepsRememberMeLifetime(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.RememberMeMaxAge = 86400
+	cfg.ExtendOnReauth = true
+	mgr, err := session.NewManager(cfg, false)
+	require.NoError(t, err)
+
+	cookie, err := mgr.CreateWithDuration(123, "testuser", mgr.RememberMeDuration())
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(cookie)
+	data, err := mgr.Parse(req)
+	require.NoError(t, err)
+	require.NotNil(t, data)
+
+	refreshed, err := mgr.Reauthenticate(data)
+
+	require.NoError(t, err)
+	assert.Equal(t, 86400, refreshed.MaxAge)
+}
+
 func TestParse_DifferentManager(t *testing.T) {
 	cfg1 := newTestConfig()
 	mgr1, err := session.NewManager(cfg1, false)
@@ -256,6 +378,98 @@ func TestParse_DifferentManager(t *testing.T) {
 	assert.Nil(t, data) // Should not be able to decode
 }
 
+func TestReauthenticate_ExtendsSession(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.ExtendOnReauth = true
+	mgr, err := session.NewManager(cfg, false)
+	require.NoError(t, err)
+
+	// Simulate a session that was authenticated a while ago and is about to expire
+	old := &session.Data{
+		UserID:    123,
+		Username:  "testuser",
+		AuthAt:    time.Now().Add(-time.Hour),
+		ExpiresAt: time.Now().Add(5 * time.Minute),
+	}
+
+	cookie, err := mgr.Reauthenticate(old)
+	require.NoError(t, err)
+	assert.Equal(t, 3600, cookie.MaxAge)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(cookie)
+
+	data, err := mgr.Parse(req)
+
+	require.NoError(t, err)
+	require.NotNil(t, data)
+	assert.Equal(t, int64(123), data.UserID)
+	assert.True(t, data.AuthAt.After(old.AuthAt), "last-auth time should be updated")
+	assert.WithinDuration(t, time.Now(), data.AuthAt, 5*time.Second)
+	assert.True(t, data.ExpiresAt.After(old.ExpiresAt), "deadline should be extended")
+	assert.WithinDuration(t, time.Now().Add(time.Hour), data.ExpiresAt, 5*time.Second)
+}
+
+func TestReauthenticate_WithoutExtension(t *testing.T) {
+	cfg := newTestConfig()
+	mgr, err := session.NewManager(cfg, false)
+	require.NoError(t, err)
+
+	old := &session.Data{
+		UserID:    123,
+		Username:  "testuser",
+		AuthAt:    time.Now().Add(-time.Hour),
+		ExpiresAt: time.Now().Add(5 * time.Minute),
+	}
+
+	cookie, err := mgr.Reauthenticate(old)
+	require.NoError(t, err)
+	assert.LessOrEqual(t, cookie.MaxAge, 300)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(cookie)
+
+	data, err := mgr.Parse(req)
+
+	require.NoError(t, err)
+	require.NotNil(t, data)
+	assert.True(t, data.AuthAt.After(old.AuthAt), "last-auth time should be updated")
+	assert.WithinDuration(t, old.ExpiresAt, data.ExpiresAt, time.Second)
+}
+
+func TestReauthenticate_WithoutExtension_ExpiresAtOriginalDeadline(t *testing.T) {
+	cfg := newTestConfig()
+	mgr, err := session.NewManager(cfg, false)
+	require.NoError(t, err)
+
+	fake := clock.NewFake(time.Now())
+	mgr.SetClock(fake)
+
+	cookie, err := mgr.Create(123, "testuser")
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(cookie)
+	data, err := mgr.Parse(req)
+	require.NoError(t, err)
+	require.NotNil(t, data)
+
+	// Re-authenticate half-way through the session
+	fake.Advance(30 * time.Minute)
+	cookie, err = mgr.Reauthenticate(data)
+	require.NoError(t, err)
+	assert.Equal(t, 1800, cookie.MaxAge)
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(cookie)
+
+	// The original deadline still applies
+	fake.Advance(31 * time.Minute)
+	data, err = mgr.Parse(req)
+
+	require.NoError(t, err)
+	assert.Nil(t, data)
+}
+
 func TestClear(t *testing.T) {
 	cfg := newTestConfig()
 	mgr, err := session.NewManager(cfg, false)
@@ -368,3 +582,151 @@ func TestClearFlash_SecureMode(t *testing.T) {
 
 	assert.True(t, cookie.Secure)
 }
+
+func TestIssue_KeepsVersionAndCredential(t *testing.T) {
+	mgr, err := session.NewManager(newTestConfig(), false)
+	require.NoError(t, err)
+
+	cookie, err := mgr.Issue(session.Data{UserID: 123, Username: "testuser", Version: 3, CredentialID: 7}, mgr.Duration())
+	require.NoError(t, err)
+	assert.Equal(t, 3600, cookie.MaxAge)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(cookie)
+	data, err := mgr.Parse(req)
+
+	require.NoError(t, err)
+	require.NotNil(t, data)
+	assert.Equal(t, int64(123), data.UserID)
+	assert.Equal(t, 3, data.Version)
+	assert.Equal(t, int64(7), data.CredentialID)
+}
+
+func TestReissue_KeepsExpiry(t *testing.T) {
+	mgr, err := session.NewManager(newTestConfig(), false)
+	require.NoError(t, err)
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	clk := clock.NewFake(now)
+	mgr.SetClock(clk)
+
+	cookie, err := mgr.Create(123, "testuser")
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(cookie)
+	data, err := mgr.Parse(req)
+	require.NoError(t, err)
+
+	clk.Advance(10 * time.Minute)
+	data.Version = 1
+	reissued, err := mgr.Reissue(data)
+	require.NoError(t, err)
+	assert.Equal(t, 3000, reissued.MaxAge)
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(reissued)
+	parsed, err := mgr.Parse(req)
+	require.NoError(t, err)
+	require.NotNil(t, parsed)
+	assert.Equal(t, 1, parsed.Version)
+	assert.True(t, now.Add(time.Hour).Equal(parsed.ExpiresAt))
+	assert.True(t, now.Equal(parsed.AuthAt))
+}
+
+// rotatedHashKey is the primary key after rotating away from validHashKey.
+const rotatedHashKey = "00112233445566778899aabbccddeeff00112233445566778899aabbccddeeff"
+
+// parseCookie parses cookie with mgr as part of a fresh request.
+func parseCookie(t *testing.T, mgr *session.Manager, cookie *http.Cookie) *session.Data {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(cookie)
+	data, err := mgr.Parse(req)
+	require.NoError(t, err)
+	return data
+}
+
+func TestParse_PreviousKeyAfterRotation(t *testing.T) {
+	old, err := session.NewManager(newTestConfig(), false)
+	require.NoError(t, err)
+	oldCookie, err := old.Create(123, "testuser")
+	require.NoError(t, err)
+
+	cfg := newTestConfig()
+	cfg.HashKey = rotatedHashKey
+	cfg.PreviousHashKeys = []string{validHashKey}
+	rotated, err := session.NewManager(cfg, false)
+	require.NoError(t, err)
+
+	data := parseCookie(t, rotated, oldCookie)
+	require.NotNil(t, data)
+	assert.Equal(t, int64(123), data.UserID)
+
+	// New cookies are signed with the new primary key only
+	newCookie, err := rotated.Create(456, "other")
+	require.NoError(t, err)
+	assert.Nil(t, parseCookie(t, old, newCookie))
+}
+
+func TestParse_PreviousKeyWithBlockKey(t *testing.T) {
+	oldCfg := newTestConfig()
+	oldCfg.BlockKey = validBlockKey
+	old, err := session.NewManager(oldCfg, false)
+	require.NoError(t, err)
+	oldCookie, err := old.Create(123, "testuser")
+	require.NoError(t, err)
+
+	cfg := newTestConfig()
+	cfg.HashKey = rotatedHashKey
+	cfg.PreviousHashKeys = []string{validHashKey}
+	cfg.PreviousBlockKeys = []string{validBlockKey}
+	rotated, err := session.NewManager(cfg, false)
+	require.NoError(t, err)
+
+	data := parseCookie(t, rotated, oldCookie)
+	require.NotNil(t, data)
+	assert.Equal(t, int64(123), data.UserID)
+}
+
+func TestParse_UnknownKeyAfterRotation(t *testing.T) {
+	other := newTestConfig()
+	other.HashKey = validBlockKey
+	unknown, err := session.NewManager(other, false)
+	require.NoError(t, err)
+	cookie, err := unknown.Create(123, "testuser")
+	require.NoError(t, err)
+
+	cfg := newTestConfig()
+	cfg.HashKey = rotatedHashKey
+	cfg.PreviousHashKeys = []string{validHashKey}
+	rotated, err := session.NewManager(cfg, false)
+	require.NoError(t, err)
+
+	assert.Nil(t, parseCookie(t, rotated, cookie))
+}
+
+func TestNewManager_InvalidPreviousKeys(t *testing.T) {
+	tests := []struct {
+		name   string
+		hash   []string
+		block  []string
+		errMsg string
+	}{
+		{"hash not hex", []string{"zz"}, nil, "invalid session previous hash key"},
+		{"hash wrong length", []string{"abcd"}, nil, "invalid session previous hash key"},
+		{"block wrong length", []string{validHashKey}, []string{"abcd"}, "invalid session block key"},
+		{"more block keys", []string{validHashKey}, []string{validBlockKey, validBlockKey}, "more block keys than hash keys"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newTestConfig()
+			cfg.PreviousHashKeys = tt.hash
+			cfg.PreviousBlockKeys = tt.block
+
+			_, err := session.NewManager(cfg, false)
+
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errMsg)
+		})
+	}
+}
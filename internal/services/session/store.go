@@ -0,0 +1,281 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package session
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/oliverandrich/go-webapp-template/internal/models"
+	"github.com/oliverandrich/go-webapp-template/internal/repository"
+)
+
+// ErrSessionRevoked is returned by Store.Save for a session that was ended
+// after it was loaded.
+var ErrSessionRevoked = errors.New("session was revoked")
+
+// Backends selectable with session.backend.
+const (
+	BackendCookie   = "cookie"   // Whole session in a signed cookie (default)
+	BackendDatabase = "database" // Session in the database, cookie holds a random token
+)
+
+// Store creates, resolves and ends login sessions. Both implementations use
+// the cookie settings and lifetimes of a Manager.
+type Store interface {
+	// Create starts a session for data that is valid for d and returns its
+	// cookie. The user, version and credential fields are taken from data.
+	Create(ctx context.Context, data Data, d time.Duration) (*http.Cookie, error)
+	// Parse returns the session of r, or nil, nil if there is none or it
+	// expired.
+	Parse(r *http.Request) (*Data, error)
+	// Save stores changes to a session returned by Parse, keeping its expiry
+	// unless data changed it, and returns the updated cookie. It returns
+	// ErrSessionRevoked if the session was ended in the meantime.
+	Save(ctx context.Context, data *Data) (*http.Cookie, error)
+	// Renew stores a session returned by Parse as a new session with its
+	// expiry and last-auth time, e.g. to keep the caller signed in after
+	// Invalidate, and returns its cookie.
+	Renew(ctx context.Context, data *Data) (*http.Cookie, error)
+	// Clear ends the session of r and returns a cookie removing it.
+	Clear(r *http.Request) *http.Cookie
+	// Invalidate ends every session of the user.
+	Invalidate(ctx context.Context, userID int64) error
+}
+
+// NewStore returns the Store for the configured backend.
+func NewStore(backend string, m *Manager, repo *repository.Repository) (Store, error) {
+	switch backend {
+	case "", BackendCookie:
+		return NewCookieStore(m, repo), nil
+	case BackendDatabase:
+		return NewDBStore(m, repo), nil
+	default:
+		return nil, fmt.Errorf("unknown session backend %q", backend)
+	}
+}
+
+// CookieStore keeps the whole session in the signed cookie. Sessions cannot
+// be revoked one by one; Invalidate bumps the user's session version, which
+// AuthMiddleware compares with the version in the cookie.
+type CookieStore struct {
+	m    *Manager
+	repo *repository.Repository
+}
+
+// NewCookieStore creates a cookie-backed Store.
+func NewCookieStore(m *Manager, repo *repository.Repository) *CookieStore {
+	return &CookieStore{m: m, repo: repo}
+}
+
+// Create implements Store.
+func (s *CookieStore) Create(_ context.Context, data Data, d time.Duration) (*http.Cookie, error) {
+	return s.m.Issue(data, d)
+}
+
+// Parse implements Store.
+func (s *CookieStore) Parse(r *http.Request) (*Data, error) {
+	return s.m.Parse(r)
+}
+
+// Save implements Store.
+func (s *CookieStore) Save(_ context.Context, data *Data) (*http.Cookie, error) {
+	return s.m.Reissue(data)
+}
+
+// Renew implements Store.
+func (s *CookieStore) Renew(_ context.Context, data *Data) (*http.Cookie, error) {
+	return s.m.Reissue(data)
+}
+
+// Clear implements Store.
+func (s *CookieStore) Clear(*http.Request) *http.Cookie {
+	return s.m.Clear()
+}
+
+// Invalidate implements Store.
+func (s *CookieStore) Invalidate(ctx context.Context, userID int64) error {
+	_, err := s.repo.BumpSessionVersion(ctx, userID)
+	return err
+}
+
+// DBStore keeps sessions in the sessions table; the cookie only carries a
+// random token whose SHA256 hash is the row ID. Signing out deletes the row,
+// so a copied cookie stops working immediately.
+type DBStore struct {
+	m    *Manager
+	repo *repository.Repository
+}
+
+// NewDBStore creates a database-backed Store.
+func NewDBStore(m *Manager, repo *repository.Repository) *DBStore {
+	return &DBStore{m: m, repo: repo}
+}
+
+// tokenLength is the number of random bytes in a DBStore cookie token.
+const tokenLength = 32
+
+// Create implements Store.
+func (s *DBStore) Create(ctx context.Context, data Data, d time.Duration) (*http.Cookie, error) {
+	maxAge := s.m.start(&data, d)
+	if err := s.insert(ctx, &data); err != nil {
+		return nil, err
+	}
+	return s.m.cookie(data.token, maxAge), nil
+}
+
+// Parse implements Store.
+func (s *DBStore) Parse(r *http.Request) (*Data, error) {
+	cookie, err := r.Cookie(s.m.cookieName)
+	if err != nil {
+		if errors.Is(err, http.ErrNoCookie) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	row, err := s.repo.GetSession(r.Context(), hashToken(cookie.Value))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var data Data
+	if err := json.Unmarshal([]byte(row.Data), &data); err != nil {
+		return nil, fmt.Errorf("failed to decode session: %w", err)
+	}
+	if s.m.clock.Now().After(data.ExpiresAt) {
+		return nil, nil
+	}
+	data.token = cookie.Value
+	return &data, nil
+}
+
+// Save implements Store. The row is only updated, never inserted, so a
+// session deleted by Clear or Invalidate in the meantime stays revoked.
+func (s *DBStore) Save(ctx context.Context, data *Data) (*http.Cookie, error) {
+	if data.token == "" {
+		return nil, errors.New("session was not loaded from the database")
+	}
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	err = s.repo.UpdateSession(ctx, &models.Session{
+		ID:        hashToken(data.token),
+		UserID:    data.UserID,
+		Data:      string(encoded),
+		ExpiresAt: data.ExpiresAt,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrSessionRevoked
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.m.cookie(data.token, s.remaining(data)), nil
+}
+
+// Renew implements Store. The session gets a new token; the old one is not
+// touched.
+func (s *DBStore) Renew(ctx context.Context, data *Data) (*http.Cookie, error) {
+	renewed := *data
+	if err := s.insert(ctx, &renewed); err != nil {
+		return nil, err
+	}
+	return s.m.cookie(renewed.token, s.remaining(&renewed)), nil
+}
+
+// Clear implements Store. Failing to delete the row is not reported: the
+// cookie is removed either way and the row expires on its own.
+func (s *DBStore) Clear(r *http.Request) *http.Cookie {
+	if cookie, err := r.Cookie(s.m.cookieName); err == nil && cookie.Value != "" {
+		_ = s.repo.DeleteSession(r.Context(), hashToken(cookie.Value))
+	}
+	return s.m.Clear()
+}
+
+// Invalidate implements Store. The session version is bumped as well, so
+// sessions stored concurrently with an old version are rejected too.
+// Sessions in which the user impersonates someone else are deleted with
+// their own.
+func (s *DBStore) Invalidate(ctx context.Context, userID int64) error {
+	if _, err := s.repo.BumpSessionVersion(ctx, userID); err != nil {
+		return err
+	}
+	return s.repo.DeleteUserSessions(ctx, userID)
+}
+
+// insert stores data as a new row under a fresh random token.
+func (s *DBStore) insert(ctx context.Context, data *Data) error {
+	raw := make([]byte, tokenLength)
+	if _, err := rand.Read(raw); err != nil {
+		return fmt.Errorf("failed to generate session token: %w", err)
+	}
+	data.token = hex.EncodeToString(raw)
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	row := &models.Session{
+		ID:        hashToken(data.token),
+		UserID:    data.UserID,
+		Data:      string(encoded),
+		ExpiresAt: data.ExpiresAt,
+	}
+	if data.ImpersonatorID != 0 {
+		row.ImpersonatorID = &data.ImpersonatorID
+	}
+	return s.repo.CreateSession(ctx, row)
+}
+
+// remaining returns the cookie max age in seconds for data.
+func (s *DBStore) remaining(data *Data) int {
+	return int(data.ExpiresAt.Sub(s.m.clock.Now()).Seconds())
+}
+
+// StartCleanup deletes expired sessions every interval until ctx is
+// cancelled. The returned channel is closed once the cleanup has stopped.
+func (s *DBStore) StartCleanup(ctx context.Context, interval time.Duration) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				n, err := s.repo.DeleteExpiredSessions(ctx)
+				if err != nil {
+					if ctx.Err() == nil {
+						slog.Error("session cleanup failed", "error", err)
+					}
+					continue
+				}
+				slog.Debug("session cleanup", "deleted", n)
+			}
+		}
+	}()
+	return done
+}
+
+// hashToken returns the row ID of a DBStore cookie token.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
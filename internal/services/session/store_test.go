@@ -0,0 +1,207 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package session_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/oliverandrich/go-webapp-template/internal/models"
+	"github.com/oliverandrich/go-webapp-template/internal/repository"
+	"github.com/oliverandrich/go-webapp-template/internal/services/session"
+	"github.com/oliverandrich/go-webapp-template/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestStore(t *testing.T, backend string) (session.Store, *repository.Repository, *models.User) {
+	t.Helper()
+	mgr, err := session.NewManager(newTestConfig(), false)
+	require.NoError(t, err)
+	_, repo := testutil.NewTestDB(t)
+	store, err := session.NewStore(backend, mgr, repo)
+	require.NoError(t, err)
+	return store, repo, testutil.NewTestUser(t, repo, "testuser")
+}
+
+func requestWithCookie(cookie *http.Cookie) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(cookie)
+	return req
+}
+
+func TestNewStore_UnknownBackend(t *testing.T) {
+	mgr, err := session.NewManager(newTestConfig(), false)
+	require.NoError(t, err)
+
+	_, err = session.NewStore("redis", mgr, nil)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown session backend")
+}
+
+func TestStore_CreateParseSave(t *testing.T) {
+	for _, backend := range []string{session.BackendCookie, session.BackendDatabase} {
+		t.Run(backend, func(t *testing.T) {
+			store, _, user := newTestStore(t, backend)
+			ctx := context.Background()
+
+			cookie, err := store.Create(ctx, session.Data{UserID: user.ID, CredentialID: 7}, time.Hour)
+			require.NoError(t, err)
+			assert.Equal(t, 3600, cookie.MaxAge)
+
+			data, err := store.Parse(requestWithCookie(cookie))
+			require.NoError(t, err)
+			require.NotNil(t, data)
+			assert.Equal(t, user.ID, data.UserID)
+			assert.Equal(t, int64(7), data.CredentialID)
+
+			data.CredentialID = 8
+			cookie, err = store.Save(ctx, data)
+			require.NoError(t, err)
+
+			data, err = store.Parse(requestWithCookie(cookie))
+			require.NoError(t, err)
+			require.NotNil(t, data)
+			assert.Equal(t, int64(8), data.CredentialID)
+		})
+	}
+}
+
+func TestStore_ParseWithoutCookie(t *testing.T) {
+	for _, backend := range []string{session.BackendCookie, session.BackendDatabase} {
+		t.Run(backend, func(t *testing.T) {
+			store, _, _ := newTestStore(t, backend)
+
+			data, err := store.Parse(httptest.NewRequest(http.MethodGet, "/", nil))
+
+			require.NoError(t, err)
+			assert.Nil(t, data)
+		})
+	}
+}
+
+func TestDBStore_ClearRevokesSession(t *testing.T) {
+	store, _, user := newTestStore(t, session.BackendDatabase)
+	cookie, err := store.Create(context.Background(), session.Data{UserID: user.ID}, time.Hour)
+	require.NoError(t, err)
+
+	cleared := store.Clear(requestWithCookie(cookie))
+	assert.Equal(t, -1, cleared.MaxAge)
+
+	// A copy of the old cookie no longer resolves
+	data, err := store.Parse(requestWithCookie(cookie))
+	require.NoError(t, err)
+	assert.Nil(t, data)
+}
+
+func TestDBStore_UnknownToken(t *testing.T) {
+	store, _, _ := newTestStore(t, session.BackendDatabase)
+
+	data, err := store.Parse(requestWithCookie(&http.Cookie{Name: "_test_session", Value: "forged"}))
+
+	require.NoError(t, err)
+	assert.Nil(t, data)
+}
+
+func TestDBStore_InvalidateDeletesSessions(t *testing.T) {
+	store, repo, user := newTestStore(t, session.BackendDatabase)
+	ctx := context.Background()
+	cookie, err := store.Create(ctx, session.Data{UserID: user.ID}, time.Hour)
+	require.NoError(t, err)
+
+	require.NoError(t, store.Invalidate(ctx, user.ID))
+
+	data, err := store.Parse(requestWithCookie(cookie))
+	require.NoError(t, err)
+	assert.Nil(t, data)
+	updated, err := repo.GetUserByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, user.SessionVersion+1, updated.SessionVersion)
+}
+
+func TestDBStore_InvalidateEndsImpersonation(t *testing.T) {
+	store, repo, target := newTestStore(t, session.BackendDatabase)
+	ctx := context.Background()
+	admin := testutil.NewTestUser(t, repo, "admin")
+	cookie, err := store.Create(ctx, session.Data{UserID: target.ID, ImpersonatorID: admin.ID}, time.Hour)
+	require.NoError(t, err)
+
+	require.NoError(t, store.Invalidate(ctx, admin.ID))
+
+	data, err := store.Parse(requestWithCookie(cookie))
+	require.NoError(t, err)
+	assert.Nil(t, data)
+}
+
+func TestCookieStore_InvalidateBumpsVersion(t *testing.T) {
+	store, repo, user := newTestStore(t, session.BackendCookie)
+	ctx := context.Background()
+
+	require.NoError(t, store.Invalidate(ctx, user.ID))
+
+	updated, err := repo.GetUserByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, user.SessionVersion+1, updated.SessionVersion)
+}
+
+func TestDBStore_SaveDoesNotReviveRevokedSession(t *testing.T) {
+	store, _, user := newTestStore(t, session.BackendDatabase)
+	ctx := context.Background()
+	cookie, err := store.Create(ctx, session.Data{UserID: user.ID}, time.Hour)
+	require.NoError(t, err)
+	data, err := store.Parse(requestWithCookie(cookie))
+	require.NoError(t, err)
+	require.NotNil(t, data)
+
+	require.NoError(t, store.Invalidate(ctx, user.ID))
+	_, err = store.Save(ctx, data)
+
+	require.ErrorIs(t, err, session.ErrSessionRevoked)
+	data, err = store.Parse(requestWithCookie(cookie))
+	require.NoError(t, err)
+	assert.Nil(t, data)
+}
+
+func TestStore_RenewKeepsExpiry(t *testing.T) {
+	for _, backend := range []string{session.BackendCookie, session.BackendDatabase} {
+		t.Run(backend, func(t *testing.T) {
+			store, _, user := newTestStore(t, backend)
+			ctx := context.Background()
+			cookie, err := store.Create(ctx, session.Data{UserID: user.ID}, time.Hour)
+			require.NoError(t, err)
+			data, err := store.Parse(requestWithCookie(cookie))
+			require.NoError(t, err)
+			require.NotNil(t, data)
+
+			require.NoError(t, store.Invalidate(ctx, user.ID))
+			renewed, err := store.Renew(ctx, data)
+			require.NoError(t, err)
+
+			got, err := store.Parse(requestWithCookie(renewed))
+			require.NoError(t, err)
+			require.NotNil(t, got)
+			assert.Equal(t, user.ID, got.UserID)
+			assert.True(t, data.ExpiresAt.Equal(got.ExpiresAt))
+		})
+	}
+}
+
+func TestDBStore_StartCleanupStopsOnCancel(t *testing.T) {
+	store, _, _ := newTestStore(t, session.BackendDatabase)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := store.(*session.DBStore).StartCleanup(ctx, time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("cleanup did not stop")
+	}
+}
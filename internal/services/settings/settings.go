@@ -0,0 +1,108 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+// Package settings provides runtime settings persisted in the database and
+// cached in memory.
+package settings
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/oliverandrich/go-webapp-template/internal/repository"
+)
+
+// Settings keys.
+const (
+	KeyRegistration = "registration" // Registration mode
+	KeyMaintenance  = "maintenance"  // "on" while the site is in maintenance mode
+)
+
+// Registration modes.
+const (
+	RegistrationOpen   = "open"
+	RegistrationClosed = "closed"
+)
+
+// ErrInvalidRegistrationMode is returned for unknown registration modes.
+var ErrInvalidRegistrationMode = errors.New("invalid registration mode")
+
+// Service reads settings from a cache and writes them through to the database.
+type Service struct {
+	repo         *repository.Repository
+	mu           sync.RWMutex
+	registration string
+	maintenance  bool
+}
+
+// NewService loads the current settings from the database.
+// Settings that were never stored fall back to their defaults.
+func NewService(ctx context.Context, repo *repository.Repository) (*Service, error) {
+	registration, err := repo.GetSetting(ctx, KeyRegistration)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		registration = RegistrationOpen
+	case err != nil:
+		return nil, fmt.Errorf("failed to load registration mode: %w", err)
+	}
+
+	maintenance, err := repo.GetSetting(ctx, KeyMaintenance)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to load maintenance mode: %w", err)
+	}
+
+	return &Service{repo: repo, registration: registration, maintenance: maintenance == "on"}, nil
+}
+
+// RegistrationMode returns the effective registration mode.
+func (s *Service) RegistrationMode() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.registration
+}
+
+// IsRegistrationEnabled reports whether new users may register.
+func (s *Service) IsRegistrationEnabled() bool {
+	return s.RegistrationMode() != RegistrationClosed
+}
+
+// SetRegistrationMode persists a new registration mode and updates the cache.
+func (s *Service) SetRegistrationMode(ctx context.Context, mode string) error {
+	if mode != RegistrationOpen && mode != RegistrationClosed {
+		return fmt.Errorf("%w: %q", ErrInvalidRegistrationMode, mode)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.repo.SetSetting(ctx, KeyRegistration, mode); err != nil {
+		return err
+	}
+	s.registration = mode
+	return nil
+}
+
+// MaintenanceEnabled reports whether maintenance mode was switched on at runtime.
+func (s *Service) MaintenanceEnabled() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.maintenance
+}
+
+// SetMaintenance persists the maintenance mode and updates the cache.
+func (s *Service) SetMaintenance(ctx context.Context, enabled bool) error {
+	value := "off"
+	if enabled {
+		value = "on"
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.repo.SetSetting(ctx, KeyMaintenance, value); err != nil {
+		return err
+	}
+	s.maintenance = enabled
+	return nil
+}
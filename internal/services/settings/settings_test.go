@@ -0,0 +1,73 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package settings_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/oliverandrich/go-webapp-template/internal/services/settings"
+	"github.com/oliverandrich/go-webapp-template/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewService_DefaultsToOpen(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+
+	svc, err := settings.NewService(context.Background(), repo)
+
+	require.NoError(t, err)
+	assert.Equal(t, settings.RegistrationOpen, svc.RegistrationMode())
+	assert.True(t, svc.IsRegistrationEnabled())
+}
+
+func TestSetRegistrationMode(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	ctx := context.Background()
+	svc, err := settings.NewService(ctx, repo)
+	require.NoError(t, err)
+
+	require.NoError(t, svc.SetRegistrationMode(ctx, settings.RegistrationClosed))
+	assert.False(t, svc.IsRegistrationEnabled())
+
+	// A new service picks up the persisted mode
+	reloaded, err := settings.NewService(ctx, repo)
+	require.NoError(t, err)
+	assert.Equal(t, settings.RegistrationClosed, reloaded.RegistrationMode())
+
+	require.NoError(t, svc.SetRegistrationMode(ctx, settings.RegistrationOpen))
+	assert.True(t, svc.IsRegistrationEnabled())
+}
+
+func TestSetRegistrationMode_Invalid(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	ctx := context.Background()
+	svc, err := settings.NewService(ctx, repo)
+	require.NoError(t, err)
+
+	err = svc.SetRegistrationMode(ctx, "invite-only")
+
+	require.ErrorIs(t, err, settings.ErrInvalidRegistrationMode)
+	assert.Equal(t, settings.RegistrationOpen, svc.RegistrationMode())
+}
+
+func TestSetMaintenance(t *testing.T) {
+	_, repo := testutil.NewTestDB(t)
+	ctx := context.Background()
+	svc, err := settings.NewService(ctx, repo)
+	require.NoError(t, err)
+	assert.False(t, svc.MaintenanceEnabled())
+
+	require.NoError(t, svc.SetMaintenance(ctx, true))
+	assert.True(t, svc.MaintenanceEnabled())
+
+	// A new service picks up the persisted state
+	reloaded, err := settings.NewService(ctx, repo)
+	require.NoError(t, err)
+	assert.True(t, reloaded.MaintenanceEnabled())
+
+	require.NoError(t, svc.SetMaintenance(ctx, false))
+	assert.False(t, svc.MaintenanceEnabled())
+}
@@ -9,24 +9,42 @@ import (
 	"sync"
 	"time"
 
+	"github.com/go-webauthn/webauthn/protocol"
 	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/oliverandrich/go-webapp-template/internal/clock"
 	"github.com/oliverandrich/go-webapp-template/internal/config"
 )
 
-const sessionTTL = 2 * time.Minute
+// defaultTimeout bounds a ceremony when no timeout is configured. Ceremony
+// sessions are kept exactly as long as the browser is told to wait.
+const defaultTimeout = 2 * time.Minute
 
 // Service provides WebAuthn functionality.
 type Service struct {
-	wa       *webauthn.WebAuthn
-	sessions *sessionStore
+	wa             *webauthn.WebAuthn
+	sessions       *sessionStore
+	maxCredentials int
 }
 
-// NewService creates a new WebAuthn service.
+// NewService creates a new WebAuthn service. The configured timeout, user
+// verification and authenticator attachment apply to both registration and
+// login ceremonies.
 func NewService(cfg *config.WebAuthnConfig) (*Service, error) {
+	timeout := defaultTimeout
+	if cfg.Timeout > 0 {
+		timeout = time.Duration(cfg.Timeout) * time.Millisecond
+	}
+	ceremony := webauthn.TimeoutConfig{Enforce: true, Timeout: timeout, TimeoutUVD: timeout}
+
 	wconfig := &webauthn.Config{
 		RPDisplayName: cfg.RPDisplayName,
 		RPID:          cfg.RPID,
 		RPOrigins:     []string{cfg.RPOrigin},
+		AuthenticatorSelection: protocol.AuthenticatorSelection{
+			AuthenticatorAttachment: authenticatorAttachment(cfg.AuthenticatorAttachment),
+			UserVerification:        protocol.UserVerificationRequirement(cfg.UserVerification),
+		},
+		Timeouts: webauthn.TimeoutsConfig{Login: ceremony, Registration: ceremony},
 	}
 
 	wa, err := webauthn.New(wconfig)
@@ -35,11 +53,31 @@ func NewService(cfg *config.WebAuthnConfig) (*Service, error) {
 	}
 
 	return &Service{
-		wa:       wa,
-		sessions: newSessionStore(),
+		wa:             wa,
+		sessions:       newSessionStore(timeout),
+		maxCredentials: cfg.MaxCredentialsPerUser,
 	}, nil
 }
 
+// authenticatorAttachment maps the configured attachment to the protocol
+// value; "any" and "" leave the choice to the browser.
+func authenticatorAttachment(attachment string) protocol.AuthenticatorAttachment {
+	if attachment == "any" {
+		return ""
+	}
+	return protocol.AuthenticatorAttachment(attachment)
+}
+
+// SetClock replaces the time source used for session expiry (for tests).
+func (s *Service) SetClock(c clock.Clock) {
+	s.sessions.setClock(c)
+}
+
+// MaxCredentialsPerUser returns the passkey limit per user (0 = unlimited).
+func (s *Service) MaxCredentialsPerUser() int {
+	return s.maxCredentials
+}
+
 // WebAuthn returns the underlying webauthn.WebAuthn instance.
 func (s *Service) WebAuthn() *webauthn.WebAuthn {
 	return s.wa
@@ -87,6 +125,8 @@ func loginKey(userID int64) string {
 type sessionStore struct { //nolint:govet // fieldalignment not critical
 	mu       sync.RWMutex
 	sessions map[string]*sessionEntry
+	clock    clock.Clock
+	ttl      time.Duration
 }
 
 type sessionEntry struct {
@@ -94,20 +134,28 @@ type sessionEntry struct {
 	expiresAt time.Time
 }
 
-func newSessionStore() *sessionStore {
+func newSessionStore(ttl time.Duration) *sessionStore {
 	ss := &sessionStore{
 		sessions: make(map[string]*sessionEntry),
+		clock:    clock.Real{},
+		ttl:      ttl,
 	}
 	go ss.cleanup()
 	return ss
 }
 
+func (s *sessionStore) setClock(c clock.Clock) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clock = c
+}
+
 func (s *sessionStore) store(key string, data *webauthn.SessionData) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.sessions[key] = &sessionEntry{
 		data:      data,
-		expiresAt: time.Now().Add(sessionTTL),
+		expiresAt: s.clock.Now().Add(s.ttl),
 	}
 }
 
@@ -122,7 +170,7 @@ func (s *sessionStore) get(key string) (*webauthn.SessionData, error) {
 
 	delete(s.sessions, key)
 
-	if time.Now().After(entry.expiresAt) {
+	if s.clock.Now().After(entry.expiresAt) {
 		return nil, errors.New("session expired")
 	}
 
@@ -135,7 +183,7 @@ func (s *sessionStore) cleanup() {
 
 	for range ticker.C {
 		s.mu.Lock()
-		now := time.Now()
+		now := s.clock.Now()
 		for key, entry := range s.sessions {
 			if now.After(entry.expiresAt) {
 				delete(s.sessions, key)
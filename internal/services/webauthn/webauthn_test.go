@@ -6,9 +6,13 @@ package webauthn_test
 import (
 	"sync"
 	"testing"
+	"time"
 
+	"github.com/go-webauthn/webauthn/protocol"
 	gowebauthn "github.com/go-webauthn/webauthn/webauthn"
+	"github.com/oliverandrich/go-webapp-template/internal/clock"
 	"github.com/oliverandrich/go-webapp-template/internal/config"
+	"github.com/oliverandrich/go-webapp-template/internal/models"
 	"github.com/oliverandrich/go-webapp-template/internal/services/webauthn"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -238,3 +242,80 @@ func TestOverwriteSession(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, "second", retrieved.Challenge)
 }
+
+func TestGetRegistrationSession_Expired(t *testing.T) {
+	cfg := newTestConfig()
+	svc, err := webauthn.NewService(cfg)
+	require.NoError(t, err)
+
+	start := time.Now()
+	fake := clock.NewFake(start)
+	svc.SetClock(fake)
+
+	svc.StoreRegistrationSession(123, &gowebauthn.SessionData{Challenge: "stale"})
+	svc.StoreLoginSession(123, &gowebauthn.SessionData{Challenge: "fresh"})
+
+	// Past the TTL
+	fake.Advance(3 * time.Minute)
+	_, err = svc.GetRegistrationSession(123)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "session expired")
+
+	// Still within the TTL
+	fake.Set(start.Add(time.Minute))
+	login, err := svc.GetLoginSession(123)
+	require.NoError(t, err)
+	assert.Equal(t, "fresh", login.Challenge)
+}
+
+func TestNewService_CeremonyOptions(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.Timeout = 90000
+	cfg.UserVerification = "required"
+	cfg.AuthenticatorAttachment = "platform"
+	svc, err := webauthn.NewService(cfg)
+	require.NoError(t, err)
+
+	creation, _, err := svc.WebAuthn().BeginRegistration(&models.User{ID: 1, Username: "testuser"})
+	require.NoError(t, err)
+	assert.Equal(t, 90000, creation.Response.Timeout)
+	assert.Equal(t, protocol.VerificationRequired, creation.Response.AuthenticatorSelection.UserVerification)
+	assert.Equal(t, protocol.Platform, creation.Response.AuthenticatorSelection.AuthenticatorAttachment)
+
+	assertion, _, err := svc.WebAuthn().BeginDiscoverableLogin()
+	require.NoError(t, err)
+	assert.Equal(t, 90000, assertion.Response.Timeout)
+	assert.Equal(t, protocol.VerificationRequired, assertion.Response.UserVerification)
+}
+
+func TestNewService_CeremonyDefaults(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.UserVerification = "preferred"
+	cfg.AuthenticatorAttachment = "any"
+	svc, err := webauthn.NewService(cfg)
+	require.NoError(t, err)
+
+	creation, _, err := svc.WebAuthn().BeginRegistration(&models.User{ID: 1, Username: "testuser"})
+	require.NoError(t, err)
+	assert.Equal(t, 120000, creation.Response.Timeout)
+	assert.Equal(t, protocol.VerificationPreferred, creation.Response.AuthenticatorSelection.UserVerification)
+	assert.Empty(t, creation.Response.AuthenticatorSelection.AuthenticatorAttachment)
+}
+
+func TestSessionTTL_FollowsTimeout(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.Timeout = 300000
+	svc, err := webauthn.NewService(cfg)
+	require.NoError(t, err)
+
+	start := time.Now()
+	fake := clock.NewFake(start)
+	svc.SetClock(fake)
+
+	svc.StoreRegistrationSession(123, &gowebauthn.SessionData{Challenge: "slow"})
+	fake.Advance(4 * time.Minute)
+
+	session, err := svc.GetRegistrationSession(123)
+	require.NoError(t, err)
+	assert.Equal(t, "slow", session.Challenge)
+}
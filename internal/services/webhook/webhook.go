@@ -0,0 +1,186 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+// Package webhook delivers signed notifications about security events to an external URL.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/oliverandrich/go-webapp-template/internal/config"
+)
+
+// Event names for security-relevant notifications.
+const (
+	EventCredentialAdded          = "credential.added"
+	EventRecoveryCodesRegenerated = "recovery_codes.regenerated"
+	EventEmailChanged             = "email.changed"
+	EventAdminGranted             = "admin.granted"
+)
+
+// Header names set on every delivery.
+const (
+	SignatureHeader = "X-Webhook-Signature"
+	EventHeader     = "X-Webhook-Event"
+)
+
+const (
+	maxAttempts    = 3
+	retryDelay     = time.Second
+	requestTimeout = 10 * time.Second
+)
+
+// Payload is the JSON body posted to the webhook endpoint.
+type Payload struct { //nolint:govet // fieldalignment not critical
+	Event     string         `json:"event"`
+	Timestamp time.Time      `json:"timestamp"`
+	Data      map[string]any `json:"data,omitempty"`
+}
+
+// Notifier posts signed event payloads asynchronously with retry.
+// A nil Notifier or one without a URL is a no-op.
+type Notifier struct { //nolint:govet // fieldalignment not critical
+	url         string
+	secret      []byte
+	client      *http.Client
+	maxAttempts int
+	retryDelay  time.Duration
+	wg          sync.WaitGroup
+
+	// ctx is cancelled when Wait gives up, so pending retries stop.
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewNotifier creates a new webhook notifier from the configuration.
+func NewNotifier(cfg *config.WebhookConfig) *Notifier {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Notifier{
+		url:         cfg.URL,
+		secret:      []byte(cfg.Secret),
+		client:      &http.Client{Timeout: requestTimeout},
+		maxAttempts: maxAttempts,
+		retryDelay:  retryDelay,
+		ctx:         ctx,
+		cancel:      cancel,
+	}
+}
+
+// Enabled returns true if a webhook URL is configured.
+func (n *Notifier) Enabled() bool {
+	return n != nil && n.url != ""
+}
+
+// Notify delivers an event in the background. It never blocks the caller.
+func (n *Notifier) Notify(event string, data map[string]any) {
+	if !n.Enabled() {
+		return
+	}
+
+	payload := Payload{
+		Event:     event,
+		Timestamp: time.Now().UTC(),
+		Data:      data,
+	}
+
+	n.wg.Add(1)
+	go func() {
+		defer n.wg.Done()
+		if err := n.deliver(&payload); err != nil {
+			slog.Error("failed to deliver webhook", "event", event, "error", err)
+		}
+	}()
+}
+
+// Wait blocks until all in-flight deliveries have finished or ctx is done.
+// In the latter case the pending deliveries are cancelled and ctx.Err() is
+// returned.
+func (n *Notifier) Wait(ctx context.Context) error {
+	if n == nil {
+		return nil
+	}
+
+	done := make(chan struct{})
+	go func() {
+		n.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		n.cancel()
+		return ctx.Err()
+	}
+}
+
+// Sign computes the signature header value for a request body.
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// deliver posts the payload, retrying with exponential backoff on failure.
+// It stops early once the notifier is cancelled by Wait.
+func (n *Notifier) deliver(payload *Payload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("encoding payload: %w", err)
+	}
+
+	delay := n.retryDelay
+	for attempt := 1; ; attempt++ {
+		err = n.post(n.ctx, payload.Event, body)
+		if err == nil {
+			return nil
+		}
+		if attempt >= n.maxAttempts {
+			return fmt.Errorf("giving up after %d attempts: %w", attempt, err)
+		}
+		slog.Warn("webhook delivery failed, retrying", "event", payload.Event, "attempt", attempt, "error", err)
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-n.ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("cancelled after %d attempts: %w", attempt, err)
+		}
+		delay *= 2
+	}
+}
+
+// post performs a single delivery attempt.
+func (n *Notifier) post(ctx context.Context, event string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, event)
+	if len(n.secret) > 0 {
+		req.Header.Set(SignatureHeader, Sign(n.secret, body))
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
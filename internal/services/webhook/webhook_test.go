@@ -0,0 +1,167 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/oliverandrich/go-webapp-template/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type receivedRequest struct {
+	header http.Header
+	body   []byte
+}
+
+func newTestServer(t *testing.T, status int) (*httptest.Server, *[]receivedRequest, *sync.Mutex) {
+	t.Helper()
+	var mu sync.Mutex
+	var received []receivedRequest
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		received = append(received, receivedRequest{header: r.Header.Clone(), body: body})
+		mu.Unlock()
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &received, &mu
+}
+
+func TestNotify_SignsPayload(t *testing.T) {
+	srv, received, mu := newTestServer(t, http.StatusOK)
+
+	n := NewNotifier(&config.WebhookConfig{URL: srv.URL, Secret: "s3cret"})
+	n.Notify(EventCredentialAdded, map[string]any{"user_id": 42})
+	require.NoError(t, n.Wait(context.Background()))
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, *received, 1)
+	req := (*received)[0]
+
+	assert.Equal(t, "application/json", req.header.Get("Content-Type"))
+	assert.Equal(t, EventCredentialAdded, req.header.Get(EventHeader))
+	assert.Equal(t, Sign([]byte("s3cret"), req.body), req.header.Get(SignatureHeader))
+	assert.Contains(t, req.header.Get(SignatureHeader), "sha256=")
+
+	var payload map[string]any
+	require.NoError(t, json.Unmarshal(req.body, &payload))
+	assert.Equal(t, EventCredentialAdded, payload["event"])
+	assert.NotEmpty(t, payload["timestamp"])
+	data, ok := payload["data"].(map[string]any)
+	require.True(t, ok)
+	assert.InDelta(t, 42, data["user_id"], 0)
+}
+
+func TestNotify_WrongSecretDoesNotVerify(t *testing.T) {
+	srv, received, mu := newTestServer(t, http.StatusOK)
+
+	n := NewNotifier(&config.WebhookConfig{URL: srv.URL, Secret: "s3cret"})
+	n.Notify(EventRecoveryCodesRegenerated, nil)
+	require.NoError(t, n.Wait(context.Background()))
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, *received, 1)
+	req := (*received)[0]
+	assert.NotEqual(t, Sign([]byte("other"), req.body), req.header.Get(SignatureHeader))
+}
+
+func TestNotify_NoSecretOmitsSignature(t *testing.T) {
+	srv, received, mu := newTestServer(t, http.StatusOK)
+
+	n := NewNotifier(&config.WebhookConfig{URL: srv.URL})
+	n.Notify(EventCredentialAdded, nil)
+	require.NoError(t, n.Wait(context.Background()))
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, *received, 1)
+	assert.Empty(t, (*received)[0].header.Get(SignatureHeader))
+}
+
+func TestNotify_RetriesOnFailure(t *testing.T) {
+	var attempts atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if attempts.Add(1) < 3 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(srv.Close)
+
+	n := NewNotifier(&config.WebhookConfig{URL: srv.URL, Secret: "s3cret"})
+	n.retryDelay = time.Millisecond
+	n.Notify(EventCredentialAdded, nil)
+	require.NoError(t, n.Wait(context.Background()))
+
+	assert.Equal(t, int32(3), attempts.Load())
+}
+
+func TestNotify_GivesUpAfterMaxAttempts(t *testing.T) {
+	var attempts atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	t.Cleanup(srv.Close)
+
+	n := NewNotifier(&config.WebhookConfig{URL: srv.URL})
+	n.retryDelay = time.Millisecond
+	n.Notify(EventCredentialAdded, nil)
+	require.NoError(t, n.Wait(context.Background()))
+
+	assert.Equal(t, int32(maxAttempts), attempts.Load())
+}
+
+func TestNotify_DisabledIsNoop(t *testing.T) {
+	n := NewNotifier(&config.WebhookConfig{})
+	assert.False(t, n.Enabled())
+	n.Notify(EventCredentialAdded, nil)
+	require.NoError(t, n.Wait(context.Background()))
+
+	var nilNotifier *Notifier
+	assert.False(t, nilNotifier.Enabled())
+	nilNotifier.Notify(EventCredentialAdded, nil)
+	require.NoError(t, nilNotifier.Wait(context.Background()))
+}
+
+func TestWait_CancelsPendingRetries(t *testing.T) {
+	var attempts atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	t.Cleanup(srv.Close)
+
+	n := NewNotifier(&config.WebhookConfig{URL: srv.URL})
+	n.retryDelay = time.Hour
+	n.Notify(EventCredentialAdded, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := n.Wait(ctx)
+
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+
+	// The delivery goroutine stops instead of sleeping through its backoff
+	require.NoError(t, n.Wait(context.Background()))
+	assert.Equal(t, int32(1), attempts.Load())
+}
@@ -0,0 +1,153 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+// Package signedurl creates and verifies URLs that carry an HMAC signature
+// and an expiry time, e.g. for download or share links that must not be
+// guessable or altered.
+package signedurl
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/oliverandrich/go-webapp-template/internal/clock"
+	"github.com/oliverandrich/go-webapp-template/internal/config"
+)
+
+// Query parameters added by Sign. They must not be used in params.
+const (
+	ParamExpires   = "exp"
+	ParamSignature = "sig"
+)
+
+// Errors returned by Verify.
+var (
+	ErrInvalidSignature = errors.New("invalid url signature")
+	ErrExpired          = errors.New("url expired")
+)
+
+// Signer signs and verifies URLs with a secret key.
+type Signer struct {
+	key   []byte
+	clock clock.Clock
+}
+
+// New creates a Signer using key for the HMAC.
+func New(key []byte) *Signer {
+	return &Signer{key: key, clock: clock.Real{}}
+}
+
+// NewFromConfig creates a Signer from the session configuration. The key is
+// URLSigningKey, or derived from HashKey when it is empty, so signed URLs
+// work without extra configuration. Without either key a random key is
+// generated for development and URLs don't survive restarts.
+func NewFromConfig(cfg *config.SessionConfig) (*Signer, error) {
+	if cfg.URLSigningKey != "" {
+		key, err := hex.DecodeString(cfg.URLSigningKey)
+		if err != nil || len(key) != 32 {
+			return nil, errors.New("invalid url signing key: must be 32 bytes hex encoded")
+		}
+		return New(key), nil
+	}
+
+	if cfg.HashKey != "" {
+		hashKey, err := hex.DecodeString(cfg.HashKey)
+		if err != nil {
+			return nil, errors.New("invalid session hash key: must be hex encoded")
+		}
+		// Use a derived key so signed URLs can't be replayed as cookies
+		mac := hmac.New(sha256.New, hashKey)
+		mac.Write([]byte("signedurl"))
+		return New(mac.Sum(nil)), nil
+	}
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, errors.New("failed to generate url signing key")
+	}
+	slog.Warn("No url signing key configured, using random key (signed URLs will not survive restarts)")
+	return New(key), nil
+}
+
+// SetClock replaces the time source used for expiry (for tests).
+func (s *Signer) SetClock(c clock.Clock) {
+	s.clock = c
+}
+
+// Sign returns path with params, an expiry ttl from now and a signature as
+// query parameters. path must not contain a query string; it may contain
+// escaped characters and is returned in escaped form.
+func (s *Signer) Sign(path string, params map[string]string, ttl time.Duration) string {
+	path = escapedPath(path)
+	query := make(url.Values, len(params)+2)
+	for k, v := range params {
+		query.Set(k, v)
+	}
+	query.Set(ParamExpires, strconv.FormatInt(s.clock.Now().Add(ttl).Unix(), 10))
+	query.Set(ParamSignature, s.signature(path, query))
+	return path + "?" + query.Encode()
+}
+
+// Verify checks the signature and expiry of a URL created by Sign and
+// returns its parameters, without the expiry and signature. rawURL may be
+// absolute or just path and query.
+func (s *Signer) Verify(rawURL string) (map[string]string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, ErrInvalidSignature
+	}
+	query := u.Query()
+	signature := query.Get(ParamSignature)
+	query.Del(ParamSignature)
+
+	if !hmac.Equal([]byte(signature), []byte(s.signature(u.EscapedPath(), query))) {
+		return nil, ErrInvalidSignature
+	}
+
+	expires, err := strconv.ParseInt(query.Get(ParamExpires), 10, 64)
+	if err != nil {
+		return nil, ErrInvalidSignature
+	}
+	if !s.clock.Now().Before(time.Unix(expires, 0)) {
+		return nil, ErrExpired
+	}
+
+	query.Del(ParamExpires)
+	params := make(map[string]string, len(query))
+	for k := range query {
+		params[k] = query.Get(k)
+	}
+	return params, nil
+}
+
+// escapedPath returns path the way Verify sees it in a parsed URL, so both
+// sides sign the same string.
+func escapedPath(path string) string {
+	u, err := url.Parse(path)
+	if err != nil {
+		return (&url.URL{Path: path}).EscapedPath()
+	}
+	return u.EscapedPath()
+}
+
+// signature computes the HMAC over the path and the sorted query without
+// the signature itself.
+func (s *Signer) signature(path string, query url.Values) string {
+	unsigned := make(url.Values, len(query))
+	for k, v := range query {
+		if k != ParamSignature {
+			unsigned[k] = v
+		}
+	}
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(path + "?" + unsigned.Encode()))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
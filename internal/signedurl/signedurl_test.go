@@ -0,0 +1,158 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+package signedurl_test
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oliverandrich/go-webapp-template/internal/clock"
+	"github.com/oliverandrich/go-webapp-template/internal/config"
+	"github.com/oliverandrich/go-webapp-template/internal/signedurl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testKey = "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+func newTestSigner(t *testing.T) (*signedurl.Signer, *clock.Fake) {
+	t.Helper()
+	s, err := signedurl.NewFromConfig(&config.SessionConfig{URLSigningKey: testKey})
+	require.NoError(t, err)
+	fake := clock.NewFake(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+	s.SetClock(fake)
+	return s, fake
+}
+
+func TestSignVerify_RoundTrip(t *testing.T) {
+	s, _ := newTestSigner(t)
+
+	signed := s.Sign("/exports/download", map[string]string{"id": "42", "name": "a b&c"}, time.Hour)
+
+	assert.True(t, strings.HasPrefix(signed, "/exports/download?"))
+	params, err := s.Verify(signed)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"id": "42", "name": "a b&c"}, params)
+
+	// Absolute URLs verify as well
+	params, err = s.Verify("https://example.com" + signed)
+	require.NoError(t, err)
+	assert.Equal(t, "42", params["id"])
+}
+
+func TestSignVerify_EscapedPath(t *testing.T) {
+	s, _ := newTestSigner(t)
+
+	for _, path := range []string{"/files/a%20b.txt", "/files/a%2Fb", "/files/résumé.pdf"} {
+		signed := s.Sign(path, map[string]string{"id": "42"}, time.Hour)
+
+		params, err := s.Verify(signed)
+		require.NoError(t, err, path)
+		assert.Equal(t, "42", params["id"], path)
+	}
+
+	// An escaped slash is a different path than a real one
+	signed := s.Sign("/files/a%2Fb", nil, time.Hour)
+	_, err := s.Verify(strings.Replace(signed, "%2F", "/", 1))
+	assert.ErrorIs(t, err, signedurl.ErrInvalidSignature)
+}
+
+func TestVerify_Tampered(t *testing.T) {
+	s, _ := newTestSigner(t)
+	signed := s.Sign("/exports/download", map[string]string{"id": "42"}, time.Hour)
+
+	tests := map[string]func(u *url.URL){
+		"changed param": func(u *url.URL) {
+			q := u.Query()
+			q.Set("id", "43")
+			u.RawQuery = q.Encode()
+		},
+		"added param": func(u *url.URL) {
+			q := u.Query()
+			q.Set("admin", "1")
+			u.RawQuery = q.Encode()
+		},
+		"extended expiry": func(u *url.URL) {
+			q := u.Query()
+			q.Set(signedurl.ParamExpires, "9999999999")
+			u.RawQuery = q.Encode()
+		},
+		"changed path": func(u *url.URL) {
+			u.Path = "/exports/other"
+		},
+		"changed signature": func(u *url.URL) {
+			q := u.Query()
+			q.Set(signedurl.ParamSignature, "x"+q.Get(signedurl.ParamSignature)[1:])
+			u.RawQuery = q.Encode()
+		},
+		"missing signature": func(u *url.URL) {
+			q := u.Query()
+			q.Del(signedurl.ParamSignature)
+			u.RawQuery = q.Encode()
+		},
+	}
+
+	for name, tamper := range tests {
+		t.Run(name, func(t *testing.T) {
+			u, err := url.Parse(signed)
+			require.NoError(t, err)
+			tamper(u)
+
+			_, err = s.Verify(u.String())
+
+			require.ErrorIs(t, err, signedurl.ErrInvalidSignature)
+		})
+	}
+}
+
+func TestVerify_Expired(t *testing.T) {
+	s, fake := newTestSigner(t)
+	signed := s.Sign("/exports/download", map[string]string{"id": "42"}, time.Hour)
+
+	fake.Advance(59 * time.Minute)
+	_, err := s.Verify(signed)
+	require.NoError(t, err)
+
+	fake.Advance(time.Minute)
+	_, err = s.Verify(signed)
+	require.ErrorIs(t, err, signedurl.ErrExpired)
+}
+
+func TestVerify_OtherKey(t *testing.T) {
+	s, _ := newTestSigner(t)
+	signed := s.Sign("/exports/download", nil, time.Hour)
+
+	other := signedurl.New([]byte("another key"))
+
+	_, err := other.Verify(signed)
+	require.ErrorIs(t, err, signedurl.ErrInvalidSignature)
+}
+
+func TestNewFromConfig_DerivesFromHashKey(t *testing.T) {
+	a, err := signedurl.NewFromConfig(&config.SessionConfig{HashKey: testKey})
+	require.NoError(t, err)
+	b, err := signedurl.NewFromConfig(&config.SessionConfig{HashKey: testKey})
+	require.NoError(t, err)
+
+	// The same session key yields the same signing key
+	params, err := b.Verify(a.Sign("/share", map[string]string{"id": "1"}, time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, "1", params["id"])
+
+	// ... which differs from using the hash key directly
+	raw, err := signedurl.NewFromConfig(&config.SessionConfig{URLSigningKey: testKey})
+	require.NoError(t, err)
+	_, err = raw.Verify(a.Sign("/share", nil, time.Hour))
+	require.ErrorIs(t, err, signedurl.ErrInvalidSignature)
+}
+
+func TestNewFromConfig_InvalidKey(t *testing.T) {
+	_, err := signedurl.NewFromConfig(&config.SessionConfig{URLSigningKey: "abcd"})
+	require.Error(t, err)
+
+	_, err = signedurl.NewFromConfig(&config.SessionConfig{HashKey: "not hex"})
+	require.Error(t, err)
+}
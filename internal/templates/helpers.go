@@ -5,6 +5,8 @@ package templates
 
 import (
 	"context"
+	"encoding/json"
+	"time"
 
 	"github.com/oliverandrich/go-webapp-template/internal/appcontext"
 	"github.com/oliverandrich/go-webapp-template/internal/i18n"
@@ -13,12 +15,55 @@ import (
 
 // CSRFToken returns the CSRF token from the context.
 func CSRFToken(ctx context.Context) string {
-	if token, ok := ctx.Value(appcontext.CSRFToken{}).(string); ok {
+	if token, ok := appcontext.CSRFTokenFrom(ctx); ok {
 		return token
 	}
 	return ""
 }
 
+// CSPNonce returns the Content-Security-Policy nonce from the context.
+func CSPNonce(ctx context.Context) string {
+	if nonce, ok := appcontext.CSPNonceFrom(ctx); ok {
+		return nonce
+	}
+	return ""
+}
+
+// Path returns the URL of an application path, e.g. "/auth/login", taking
+// a configured path prefix into account.
+func Path(ctx context.Context, path string) string {
+	return appcontext.PathPrefixFrom(ctx) + path
+}
+
+// PathPrefix returns the path the app is mounted below, for scripts that
+// build URLs. It is empty when the app is mounted at the root.
+func PathPrefix(ctx context.Context) string {
+	return appcontext.PathPrefixFrom(ctx)
+}
+
+// htmxResponseHandling swaps 4xx/5xx responses (flagged as errors) so the
+// partials rendered by the error handler reach the page.
+var htmxResponseHandling = []map[string]any{
+	{"code": "204", "swap": false},
+	{"code": "[23]..", "swap": true},
+	{"code": "[45]..", "swap": true, "error": true},
+}
+
+// HtmxConfig returns the htmx configuration meta content, passing the CSP
+// nonce so htmx can inject its indicator styles and inline scripts.
+func HtmxConfig(ctx context.Context) string {
+	nonce := CSPNonce(ctx)
+	cfg, err := json.Marshal(map[string]any{
+		"inlineScriptNonce": nonce,
+		"inlineStyleNonce":  nonce,
+		"responseHandling":  htmxResponseHandling,
+	})
+	if err != nil {
+		return "{}"
+	}
+	return string(cfg)
+}
+
 // T translates a message by ID.
 func T(ctx context.Context, messageID string) string {
 	return i18n.T(ctx, messageID)
@@ -29,6 +74,21 @@ func TData(ctx context.Context, messageID string, data map[string]any) string {
 	return i18n.TData(ctx, messageID, data)
 }
 
+// TPlural translates a message with plural forms for count.
+func TPlural(ctx context.Context, messageID string, count int) string {
+	return i18n.TPlural(ctx, messageID, count)
+}
+
+// FormatTime formats a timestamp for the current locale (i18n.DateShort, DateLong, DateTimeShort).
+func FormatTime(ctx context.Context, t time.Time, style string) string {
+	return i18n.FormatTime(ctx, t, style)
+}
+
+// FormatNumber formats a number with the current locale's separators.
+func FormatNumber(ctx context.Context, n float64) string {
+	return i18n.FormatNumber(ctx, n)
+}
+
 // Locale returns the current locale.
 func Locale(ctx context.Context) string {
 	return i18n.GetLocale(ctx)
@@ -36,7 +96,7 @@ func Locale(ctx context.Context) string {
 
 // CSSPath returns the path to the hashed CSS file.
 func CSSPath(ctx context.Context) string {
-	if path, ok := ctx.Value(appcontext.CSSPath{}).(string); ok {
+	if path, ok := appcontext.CSSPathFrom(ctx); ok {
 		return path
 	}
 	return "/static/css/styles.css"
@@ -44,7 +104,7 @@ func CSSPath(ctx context.Context) string {
 
 // JSPath returns the path to the hashed htmx JS file.
 func JSPath(ctx context.Context) string {
-	if path, ok := ctx.Value(appcontext.JSPath{}).(string); ok {
+	if path, ok := appcontext.JSPathFrom(ctx); ok {
 		return path
 	}
 	return "/static/js/htmx.js"
@@ -52,7 +112,16 @@ func JSPath(ctx context.Context) string {
 
 // GetUser returns the authenticated user from context, or nil if not logged in.
 func GetUser(ctx context.Context) *models.User {
-	if user, ok := ctx.Value(appcontext.User{}).(*models.User); ok {
+	if user, ok := appcontext.UserFrom(ctx); ok {
+		return user
+	}
+	return nil
+}
+
+// GetImpersonator returns the administrator acting as the current user, or
+// nil if the session is not impersonated.
+func GetImpersonator(ctx context.Context) *models.User {
+	if user, ok := appcontext.ImpersonatorFrom(ctx); ok {
 		return user
 	}
 	return nil
@@ -9,6 +9,7 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
 	"testing"
 
 	"github.com/labstack/echo/v4"
@@ -32,6 +33,19 @@ func NewTestDB(t *testing.T) (*sqlx.DB, *repository.Repository) {
 	return db, repo
 }
 
+// NewTestFileDB creates a file-backed SQLite database for tests. Unlike
+// in-memory databases it is shared by all pooled connections, which tests of
+// concurrent access need.
+func NewTestFileDB(t *testing.T) (*sqlx.DB, *repository.Repository) {
+	t.Helper()
+	db, err := database.Open(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+	return db, repository.New(db)
+}
+
 // NewTestUser creates a test user in the database.
 func NewTestUser(t *testing.T, repo *repository.Repository, username string) *models.User {
 	t.Helper()
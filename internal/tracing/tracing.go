@@ -0,0 +1,48 @@
+// Copyright 2025 Oliver Andrich
+// Licensed under the EUPL-1.2
+
+// Package tracing exports OpenTelemetry spans over OTLP/HTTP. Requests and
+// database queries are only instrumented when the server is given a
+// provider from NewProvider; without one no tracing code runs at all.
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/oliverandrich/go-webapp-template/internal/config"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+)
+
+// TracerName identifies the spans created by this application.
+const TracerName = "github.com/oliverandrich/go-webapp-template"
+
+// NewProvider returns a tracer provider that batches spans to the OTLP/HTTP
+// endpoint in cfg. Call Shutdown on it to flush pending spans.
+func NewProvider(ctx context.Context, cfg *config.TracingConfig, version string) (*sdktrace.TracerProvider, error) {
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+	if err != nil {
+		return nil, fmt.Errorf("create OTLP exporter: %w", err)
+	}
+	res := resource.NewSchemaless(
+		semconv.ServiceName(cfg.ServiceName),
+		semconv.ServiceVersion(version),
+	)
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(Sampler(cfg.SampleRate)),
+	), nil
+}
+
+// Sampler records 1 in rate new traces (0 or 1 = all). Traces continued
+// from an upstream service follow the caller's sampling decision.
+func Sampler(rate int) sdktrace.Sampler {
+	if rate <= 1 {
+		return sdktrace.ParentBased(sdktrace.AlwaysSample())
+	}
+	return sdktrace.ParentBased(sdktrace.TraceIDRatioBased(1 / float64(rate)))
+}